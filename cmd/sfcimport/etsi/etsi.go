@@ -0,0 +1,286 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package etsi maps the VNF forwarding graphs of an ETSI NFV TOSCA
+// descriptor onto sfc entities, so the chains a MANO orchestrator designed
+// can be provisioned by the controller.  The subset of the descriptor read
+// is the one the forwarding graph descriptors of the MANO projects share:
+// the tosca.groups.nfv.VNFFG groups of the topology template, their member
+// forwarding paths, ie node templates of a tosca.nodes.nfv.FP type, and the
+// connection points, tosca.nodes.nfv.CP types, the paths go through.
+//
+// Each forwarding path becomes an east-west l2 xconnect chain, the
+// forwarders of its path are the containers of the elements and their
+// connection points the port labels.  A descriptor does not place the vnfs
+// on hosts, the placement is given with the options.  The classification
+// policy of a forwarding path is not imported.
+package etsi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// the tosca types of the node templates and groups that are read
+const (
+	VNFFGType           = "tosca.groups.nfv.VNFFG"
+	ForwardingPathType  = "tosca.nodes.nfv.FP"
+	ConnectionPointType = "tosca.nodes.nfv.CP"
+)
+
+// ServiceTemplate is the part of a TOSCA service template the forwarding graphs are read from
+type ServiceTemplate struct {
+	DefinitionsVersion string           `json:"tosca_definitions_version"`
+	Description        string           `json:"description"`
+	TopologyTemplate   TopologyTemplate `json:"topology_template"`
+}
+
+// TopologyTemplate holds the node templates and the groups of a descriptor
+type TopologyTemplate struct {
+	NodeTemplates map[string]NodeTemplate `json:"node_templates"`
+	Groups        map[string]Group        `json:"groups"`
+}
+
+// NodeTemplate is a node of the topology, the properties are decoded according to its type
+type NodeTemplate struct {
+	Type       string          `json:"type"`
+	Properties json.RawMessage `json:"properties"`
+}
+
+// Group is a group of the topology, a VNFFG group's members are its forwarding paths
+type Group struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Members     []string `json:"members"`
+}
+
+// PathElement is a hop of a forwarding path: the vnf and the connection point the traffic is sent to
+type PathElement struct {
+	Forwarder  string `json:"forwarder"`
+	Capability string `json:"capability"`
+}
+
+// ForwardingPath are the properties of a forwarding path node template
+type ForwardingPath struct {
+	ID     interface{}     `json:"id"`
+	Policy json.RawMessage `json:"policy"`
+	Path   []PathElement   `json:"path"`
+}
+
+// ConnectionPoint are the properties of a connection point node template the converter uses
+type ConnectionPoint struct {
+	MacAddress string `json:"mac_address"`
+	IPAddress  string `json:"ip_address"`
+}
+
+// Options are the settings of the mapping
+type Options struct {
+	Placement   map[string]string         // the host of each vnf, by forwarder name
+	DefaultHost string                    // the host of the vnfs not in the placement
+	ElementType controller.SfcElementType // the type of the vnf ports, VPP_CONTAINER_MEMIF if not set
+	NamePrefix  string                    // prepended to the names of the chains
+}
+
+// Read parses a TOSCA descriptor, in yaml or json
+func Read(r io.Reader) (*ServiceTemplate, error) {
+
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	st := &ServiceTemplate{}
+	if err := yaml.Unmarshal(b, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// isType tells whether the tosca type is the base type or derived from it by name, ie
+// tosca.nodes.nfv.FP.TackerV2 for tosca.nodes.nfv.FP
+func isType(toscaType string, baseType string) bool {
+	return toscaType == baseType || strings.HasPrefix(toscaType, baseType+".")
+}
+
+// Convert maps each forwarding path of the VNFFG groups of the descriptor onto an sfc entity, the warnings
+// are the parts of the graphs the controller's model cannot express
+func Convert(st *ServiceTemplate, opts Options) ([]*controller.SfcEntity, []string, error) {
+
+	if opts.ElementType == controller.SfcElementType_ELEMENT_UNKNOWN {
+		opts.ElementType = controller.SfcElementType_VPP_CONTAINER_MEMIF
+	}
+	topology := &st.TopologyTemplate
+
+	var groupNames []string
+	for groupName, group := range topology.Groups {
+		if isType(group.Type, VNFFGType) {
+			groupNames = append(groupNames, groupName)
+		}
+	}
+	sort.Strings(groupNames)
+	if len(groupNames) == 0 {
+		return nil, nil, fmt.Errorf("the descriptor has no group of type: '%s'", VNFFGType)
+	}
+
+	var sfcs []*controller.SfcEntity
+	var warnings []string
+	names := make(map[string]bool)
+	for _, groupName := range groupNames {
+		group := topology.Groups[groupName]
+		for _, member := range group.Members {
+			name := groupName
+			if len(group.Members) > 1 {
+				name = groupName + "-" + member
+			}
+			name = opts.NamePrefix + name
+			if names[name] {
+				return nil, nil, fmt.Errorf("vnffg: '%s': more than one chain is named: '%s'", groupName, name)
+			}
+			names[name] = true
+
+			sfc, warning, err := convertPath(topology, member, opts)
+			if err != nil {
+				return nil, nil, fmt.Errorf("vnffg: '%s': %s", groupName, err)
+			}
+			sfc.Name = name
+			sfc.Description = group.Description
+			sfc.Labels = map[string]string{"etsi-vnffg": groupName, "etsi-nfp": member}
+			if warning != "" {
+				warnings = append(warnings, fmt.Sprintf("vnffg: '%s': %s", groupName, warning))
+			}
+			sfcs = append(sfcs, sfc)
+		}
+	}
+
+	return sfcs, warnings, nil
+}
+
+// convertPath maps the forwarding path node template onto a chain
+func convertPath(topology *TopologyTemplate, member string, opts Options) (*controller.SfcEntity, string,
+	error) {
+
+	node, exists := topology.NodeTemplates[member]
+	if !exists {
+		return nil, "", fmt.Errorf("member: '%s' is not a node template", member)
+	}
+	if !isType(node.Type, ForwardingPathType) {
+		return nil, "", fmt.Errorf("member: '%s' has type: '%s', not: '%s'", member, node.Type, ForwardingPathType)
+	}
+	fp := &ForwardingPath{}
+	if err := json.Unmarshal(node.Properties, fp); err != nil {
+		return nil, "", fmt.Errorf("forwarding path: '%s': %s", member, err)
+	}
+	if len(fp.Path) < 2 {
+		return nil, "", fmt.Errorf("forwarding path: '%s' has %d hops, an l2 xconnect chain needs at least 2",
+			member, len(fp.Path))
+	}
+
+	sfc := &controller.SfcEntity{Type: controller.SfcType_SFC_EW_L2XCONN}
+	for i, hop := range fp.Path {
+		if hop.Forwarder == "" || hop.Capability == "" {
+			return nil, "", fmt.Errorf("forwarding path: '%s': hop %d needs a forwarder and a capability", member, i)
+		}
+		host, placed := opts.Placement[hop.Forwarder]
+		if !placed {
+			host = opts.DefaultHost
+		}
+		if host == "" {
+			return nil, "", fmt.Errorf("forwarding path: '%s': vnf: '%s' is not placed on a host", member,
+				hop.Forwarder)
+		}
+		sfcElement := &controller.SfcEntity_SfcElement{
+			Container:        hop.Forwarder,
+			PortLabel:        hop.Capability,
+			EtcdVppSwitchKey: host,
+			Type:             opts.ElementType,
+		}
+		if err := connectionPointAddresses(topology, hop.Capability, sfcElement); err != nil {
+			return nil, "", fmt.Errorf("forwarding path: '%s': %s", member, err)
+		}
+		sfc.Elements = append(sfc.Elements, sfcElement)
+	}
+
+	var warning string
+	if len(fp.Policy) != 0 && string(fp.Policy) != "null" {
+		warning = fmt.Sprintf("the classification policy of forwarding path: '%s' is not imported, all the "+
+			"traffic of its first hop is steered through the chain", member)
+	}
+	return sfc, warning, nil
+}
+
+// connectionPointAddresses copies the addresses of the connection point's node template, if it has one, to
+// the element
+func connectionPointAddresses(topology *TopologyTemplate, cpName string,
+	sfcElement *controller.SfcEntity_SfcElement) error {
+
+	node, exists := topology.NodeTemplates[cpName]
+	if !exists || !isType(node.Type, ConnectionPointType) || len(node.Properties) == 0 {
+		return nil
+	}
+	cp := &ConnectionPoint{}
+	if err := json.Unmarshal(node.Properties, cp); err != nil {
+		return fmt.Errorf("connection point: '%s': %s", cpName, err)
+	}
+	sfcElement.MacAddr = cp.MacAddress
+	if cp.IPAddress == "" {
+		return nil
+	}
+
+	addr := cp.IPAddress
+	if !strings.Contains(addr, "/") {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return fmt.Errorf("connection point: '%s' has an invalid ip_address: '%s'", cpName, cp.IPAddress)
+		}
+		if ip.To4() != nil {
+			addr += "/32"
+		} else {
+			addr += "/128"
+		}
+	}
+	ip, _, err := net.ParseCIDR(addr)
+	if err != nil {
+		return fmt.Errorf("connection point: '%s' has an invalid ip_address: '%s'", cpName, cp.IPAddress)
+	}
+	if ip.To4() != nil {
+		sfcElement.Ipv4Addr = addr
+	} else {
+		sfcElement.Ipv6Addr = addr
+	}
+	return nil
+}
+
+// ParsePlacement parses a placement of the form vnf1=host1,vnf2=host2
+func ParsePlacement(s string) (map[string]string, error) {
+
+	placement := make(map[string]string)
+	if s == "" {
+		return placement, nil
+	}
+	for _, assignment := range strings.Split(s, ",") {
+		kv := strings.SplitN(assignment, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid placement: '%s', expected vnf=host", assignment)
+		}
+		placement[kv[0]] = kv[1]
+	}
+	return placement, nil
+}
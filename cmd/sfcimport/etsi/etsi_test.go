@@ -0,0 +1,133 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package etsi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+const vnffgd = `
+tosca_definitions_version: tosca_simple_profile_for_nfv_1_0_0
+description: web traffic through a firewall and a nat
+topology_template:
+  node_templates:
+    Forwarding_path1:
+      type: tosca.nodes.nfv.FP.TackerV2
+      properties:
+        id: 51
+        policy:
+          type: ACL
+          criteria:
+            - name: block_tcp
+              classifier:
+                ip_proto: 6
+        path:
+          - forwarder: fw
+            capability: CP11
+          - forwarder: fw
+            capability: CP12
+          - forwarder: nat
+            capability: CP21
+    CP11:
+      type: tosca.nodes.nfv.CP.Tacker
+      properties:
+        mac_address: "02:00:00:00:00:11"
+        ip_address: 10.1.1.1/24
+    CP21:
+      type: tosca.nodes.nfv.CP.Tacker
+      properties:
+        ip_address: 10.1.2.1
+  groups:
+    VNFFG1:
+      type: tosca.groups.nfv.VNFFG
+      description: web chain
+      properties:
+        vendor: tacker
+        version: 1.0
+        constituent_vnfs: [fw, nat]
+      members: [Forwarding_path1]
+`
+
+func TestConvertVNFFG(t *testing.T) {
+
+	st, err := Read(strings.NewReader(vnffgd))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	placement, err := ParsePlacement("nat=host2")
+	if err != nil {
+		t.Fatalf("ParsePlacement: %s", err)
+	}
+
+	sfcs, warnings, err := Convert(st, Options{Placement: placement, DefaultHost: "host1"})
+	if err != nil {
+		t.Fatalf("Convert: %s", err)
+	}
+	if len(sfcs) != 1 {
+		t.Fatalf("converted %d chains, expected 1", len(sfcs))
+	}
+
+	sfc := sfcs[0]
+	if sfc.Name != "VNFFG1" || sfc.Description != "web chain" || sfc.Type != controller.SfcType_SFC_EW_L2XCONN ||
+		sfc.Labels["etsi-nfp"] != "Forwarding_path1" {
+		t.Errorf("unexpected chain: %v", sfc)
+	}
+
+	expected := []string{"fw/CP11@host1", "fw/CP12@host1", "nat/CP21@host2"}
+	if len(sfc.Elements) != len(expected) {
+		t.Fatalf("chain has %d elements, expected %d", len(sfc.Elements), len(expected))
+	}
+	for i, sfcElement := range sfc.Elements {
+		got := sfcElement.Container + "/" + sfcElement.PortLabel + "@" + sfcElement.EtcdVppSwitchKey
+		if got != expected[i] {
+			t.Errorf("element %d is: '%s', expected: '%s'", i, got, expected[i])
+		}
+	}
+	if e := sfc.Elements[0]; e.MacAddr != "02:00:00:00:00:11" || e.Ipv4Addr != "10.1.1.1/24" {
+		t.Errorf("unexpected addresses of the first element: %v", e)
+	}
+	if e := sfc.Elements[2]; e.Ipv4Addr != "10.1.2.1/32" {
+		t.Errorf("unexpected address of the last element: '%s'", e.Ipv4Addr)
+	}
+
+	// the acl policy of the path is not imported
+	if len(warnings) != 1 {
+		t.Errorf("expected 1 warning, got: %v", warnings)
+	}
+}
+
+func TestConvertUnplacedVnf(t *testing.T) {
+
+	st, err := Read(strings.NewReader(vnffgd))
+	if err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	if _, _, err := Convert(st, Options{Placement: map[string]string{"fw": "host1"}}); err == nil ||
+		!strings.Contains(err.Error(), "'nat' is not placed") {
+		t.Errorf("expected an error for the unplaced vnf, got: %v", err)
+	}
+}
+
+func TestParsePlacement(t *testing.T) {
+
+	for _, s := range []string{"fw", "fw=", "=host1", "fw=host1,,nat=host2"} {
+		if _, err := ParsePlacement(s); err == nil {
+			t.Errorf("placement: '%s' should be invalid", s)
+		}
+	}
+}
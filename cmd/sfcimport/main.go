@@ -0,0 +1,181 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// sfcimport is a command-line tool for importing the chain definitions of
+// other systems as sfc entities:
+//
+//	sfcimport openstack -in chains.json > sfcs.yaml                  # print the sfc entities
+//	sfcimport openstack -in chains.json -url http://localhost:9191   # bulk apply them to a controller
+//	sfcimport etsi -in vnffgd.yaml -placement fw=host1,nat=host2 > sfcs.yaml
+//
+// The input of openstack is the json of the Neutron API's port_chains,
+// port_pair_groups, port_pairs, flow_classifiers, ports and subnets lists,
+// in one or more documents.  The input of etsi is a TOSCA descriptor with
+// VNFFG groups, the descriptor does not place the vnfs so the hosts are
+// given with -placement and -host.  The hosts the chains are placed on
+// must be host entities of the controller.  What could not be imported is
+// printed on stderr.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/ligato/sfc-controller/cmd/sfcimport/etsi"
+	"github.com/ligato/sfc-controller/cmd/sfcimport/openstack"
+	"github.com/ligato/sfc-controller/cmd/sfctopogen/topogen"
+	"github.com/ligato/sfc-controller/controller/client"
+	"github.com/ligato/sfc-controller/controller/core"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: sfcimport openstack|etsi [flags], -h after the command lists its flags")
+	os.Exit(2)
+}
+
+func main() {
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "openstack":
+		err = importOpenstack(os.Args[2:])
+	case "etsi":
+		err = importEtsi(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sfcimport:", err)
+		os.Exit(1)
+	}
+}
+
+func importOpenstack(args []string) error {
+
+	var opts openstack.Options
+	fs := flag.NewFlagSet("openstack", flag.ExitOnError)
+	in := fs.String("in", "-", "json file of the Neutron resources, - for stdin")
+	elementType := fs.String("element-type", "VPP_CONTAINER_MEMIF", "sfc element type of the vm ports")
+	fs.StringVar(&opts.NamePrefix, "name-prefix", "", "prepended to the names of the imported chains")
+	baseURL := fs.String("url", "", "controller REST API to bulk apply the chains to, prints them if not set")
+	fs.Parse(args)
+
+	var err error
+	if opts.ElementType, err = parseElementType(*elementType); err != nil {
+		return err
+	}
+	r, err := openInput(*in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	dump := &openstack.Dump{}
+	if err := openstack.Read(r, dump); err != nil {
+		return err
+	}
+
+	sfcs, warnings, err := openstack.Convert(dump, opts)
+	if err != nil {
+		return err
+	}
+	return output(sfcs, warnings, *baseURL)
+}
+
+func importEtsi(args []string) error {
+
+	var opts etsi.Options
+	fs := flag.NewFlagSet("etsi", flag.ExitOnError)
+	in := fs.String("in", "-", "TOSCA descriptor with the VNFFG groups, yaml or json, - for stdin")
+	placement := fs.String("placement", "", "the hosts of the vnfs: vnf1=host1,vnf2=host2")
+	fs.StringVar(&opts.DefaultHost, "host", "", "the host of the vnfs not in -placement")
+	elementType := fs.String("element-type", "VPP_CONTAINER_MEMIF", "sfc element type of the vnf ports")
+	fs.StringVar(&opts.NamePrefix, "name-prefix", "", "prepended to the names of the imported chains")
+	baseURL := fs.String("url", "", "controller REST API to bulk apply the chains to, prints them if not set")
+	fs.Parse(args)
+
+	var err error
+	if opts.ElementType, err = parseElementType(*elementType); err != nil {
+		return err
+	}
+	if opts.Placement, err = etsi.ParsePlacement(*placement); err != nil {
+		return err
+	}
+	r, err := openInput(*in)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	st, err := etsi.Read(r)
+	if err != nil {
+		return err
+	}
+
+	sfcs, warnings, err := etsi.Convert(st, opts)
+	if err != nil {
+		return err
+	}
+	return output(sfcs, warnings, *baseURL)
+}
+
+func parseElementType(s string) (controller.SfcElementType, error) {
+	t, exists := controller.SfcElementType_value[s]
+	if !exists {
+		return 0, fmt.Errorf("invalid -element-type: '%s'", s)
+	}
+	return controller.SfcElementType(t), nil
+}
+
+func openInput(in string) (io.ReadCloser, error) {
+	if in == "-" {
+		return ioutil.NopCloser(os.Stdin), nil
+	}
+	return os.Open(in)
+}
+
+// output prints the chains as an -sfc-config yaml file, or bulk applies them to the controller at baseURL
+func output(sfcs []*controller.SfcEntity, warnings []string, baseURL string) error {
+
+	for _, warning := range warnings {
+		fmt.Fprintln(os.Stderr, "sfcimport: warning:", warning)
+	}
+
+	if baseURL == "" {
+		cfg := &core.YamlConfig{Version: 1, Description: "imported by sfcimport"}
+		for _, sfc := range sfcs {
+			cfg.SFCs = append(cfg.SFCs, *sfc)
+		}
+		return topogen.WriteConfig(cfg, os.Stdout)
+	}
+
+	c := client.New(baseURL, client.WithChangedBy("sfcimport"))
+	result, err := c.BulkApply(&client.BulkApply{SfcEntities: sfcs})
+	if result != nil {
+		for _, r := range result.Results {
+			if r.Error != "" {
+				fmt.Fprintf(os.Stderr, "sfcimport: %s: '%s': %s: %s\n", r.Kind, r.Name, r.Result, r.Error)
+			} else {
+				fmt.Fprintf(os.Stderr, "sfcimport: %s: '%s': %s\n", r.Kind, r.Name, r.Result)
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,348 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package openstack converts the port chains of OpenStack's networking-sfc
+// into sfc entities.  The input is the json the Neutron API returns for the
+// port chains, port pair groups, port pairs, flow classifiers and ports, ie
+// the output of GET /v2.0/sfc/port_chains etc, either in one document or in
+// one document per resource.  A port chain becomes an east-west l2 xconnect
+// chain: the logical source port of its flow classifier, the ingress and the
+// egress port of each port pair group in order, and the logical destination
+// port.  The vm of a port is the element's container, the port is the port
+// label and the port's binding host is the vswitch of the element.
+//
+// The controller has no traffic classification and no load balancing, so
+// the match fields of a flow classifier are not imported, and a port pair
+// group with several port pairs is imported with its first pair only; a
+// warning is returned for each.
+package openstack
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// PortChain is a networking-sfc port chain
+type PortChain struct {
+	ID              string   `json:"id"`
+	Name            string   `json:"name"`
+	Description     string   `json:"description"`
+	PortPairGroups  []string `json:"port_pair_groups"`
+	FlowClassifiers []string `json:"flow_classifiers"`
+}
+
+// PortPairGroup is a networking-sfc port pair group, the port pairs are the instances of a vnf
+type PortPairGroup struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	PortPairs []string `json:"port_pairs"`
+}
+
+// PortPair is the ingress and the egress port of a vnf instance, they are the same port for a one-armed vnf
+type PortPair struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Ingress string `json:"ingress"`
+	Egress  string `json:"egress"`
+}
+
+// FlowClassifier selects the traffic of a port chain
+type FlowClassifier struct {
+	ID                      string `json:"id"`
+	Name                    string `json:"name"`
+	Ethertype               string `json:"ethertype"`
+	Protocol                string `json:"protocol"`
+	SourceIPPrefix          string `json:"source_ip_prefix"`
+	DestinationIPPrefix     string `json:"destination_ip_prefix"`
+	SourcePortRangeMin      *int   `json:"source_port_range_min"`
+	DestinationPortRangeMin *int   `json:"destination_port_range_min"`
+	LogicalSourcePort       string `json:"logical_source_port"`
+	LogicalDestinationPort  string `json:"logical_destination_port"`
+}
+
+// FixedIP is an address of a Neutron port
+type FixedIP struct {
+	SubnetID  string `json:"subnet_id"`
+	IPAddress string `json:"ip_address"`
+}
+
+// Subnet is a Neutron subnet, its cidr gives the prefix length of the addresses of the ports
+type Subnet struct {
+	ID   string `json:"id"`
+	Cidr string `json:"cidr"`
+}
+
+// Port is a Neutron port
+type Port struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	DeviceID   string    `json:"device_id"`
+	MacAddress string    `json:"mac_address"`
+	FixedIPs   []FixedIP `json:"fixed_ips"`
+	HostID     string    `json:"binding:host_id"`
+}
+
+// Dump is the networking-sfc and port resources, in the layout of the Neutron API's list responses
+type Dump struct {
+	PortChains      []PortChain      `json:"port_chains"`
+	PortPairGroups  []PortPairGroup  `json:"port_pair_groups"`
+	PortPairs       []PortPair       `json:"port_pairs"`
+	FlowClassifiers []FlowClassifier `json:"flow_classifiers"`
+	Ports           []Port           `json:"ports"`
+	Subnets         []Subnet         `json:"subnets"`
+}
+
+// Options are the settings of the conversion
+type Options struct {
+	ElementType controller.SfcElementType // the type of the vm ports, VPP_CONTAINER_MEMIF if not set
+	NamePrefix  string                    // prepended to the names of the chains
+}
+
+// Read decodes the json documents of r, each one or more of the Neutron API's list responses, into dump
+func Read(r io.Reader, dump *Dump) error {
+
+	dec := json.NewDecoder(r)
+	for {
+		var d Dump
+		if err := dec.Decode(&d); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		dump.PortChains = append(dump.PortChains, d.PortChains...)
+		dump.PortPairGroups = append(dump.PortPairGroups, d.PortPairGroups...)
+		dump.PortPairs = append(dump.PortPairs, d.PortPairs...)
+		dump.FlowClassifiers = append(dump.FlowClassifiers, d.FlowClassifiers...)
+		dump.Ports = append(dump.Ports, d.Ports...)
+		dump.Subnets = append(dump.Subnets, d.Subnets...)
+	}
+}
+
+type converter struct {
+	opts     Options
+	ppgs     map[string]*PortPairGroup
+	pps      map[string]*PortPair
+	fcs      map[string]*FlowClassifier
+	ports    map[string]*Port
+	subnets  map[string]*Subnet
+	warnings []string
+}
+
+func (c *converter) warnf(format string, args ...interface{}) {
+	c.warnings = append(c.warnings, fmt.Sprintf(format, args...))
+}
+
+// Convert converts each port chain of the dump into an sfc entity, the warnings are the parts of the chains
+// that could not be expressed in the controller's model
+func Convert(dump *Dump, opts Options) ([]*controller.SfcEntity, []string, error) {
+
+	if opts.ElementType == controller.SfcElementType_ELEMENT_UNKNOWN {
+		opts.ElementType = controller.SfcElementType_VPP_CONTAINER_MEMIF
+	}
+	c := &converter{
+		opts:    opts,
+		ppgs:    make(map[string]*PortPairGroup),
+		pps:     make(map[string]*PortPair),
+		fcs:     make(map[string]*FlowClassifier),
+		ports:   make(map[string]*Port),
+		subnets: make(map[string]*Subnet),
+	}
+	for i := range dump.PortPairGroups {
+		c.ppgs[dump.PortPairGroups[i].ID] = &dump.PortPairGroups[i]
+	}
+	for i := range dump.PortPairs {
+		c.pps[dump.PortPairs[i].ID] = &dump.PortPairs[i]
+	}
+	for i := range dump.FlowClassifiers {
+		c.fcs[dump.FlowClassifiers[i].ID] = &dump.FlowClassifiers[i]
+	}
+	for i := range dump.Ports {
+		c.ports[dump.Ports[i].ID] = &dump.Ports[i]
+	}
+	for i := range dump.Subnets {
+		c.subnets[dump.Subnets[i].ID] = &dump.Subnets[i]
+	}
+
+	var sfcs []*controller.SfcEntity
+	names := make(map[string]string)
+	for i := range dump.PortChains {
+		sfc, err := c.convertPortChain(&dump.PortChains[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if id, exists := names[sfc.Name]; exists {
+			return nil, nil, fmt.Errorf("port chains: '%s' and '%s' are both named: '%s'", id,
+				dump.PortChains[i].ID, sfc.Name)
+		}
+		names[sfc.Name] = dump.PortChains[i].ID
+		sfcs = append(sfcs, sfc)
+	}
+
+	return sfcs, c.warnings, nil
+}
+
+func (c *converter) convertPortChain(pc *PortChain) (*controller.SfcEntity, error) {
+
+	name := pc.Name
+	if name == "" {
+		name = pc.ID
+	}
+	sfc := &controller.SfcEntity{
+		Name:        c.opts.NamePrefix + name,
+		Description: pc.Description,
+		Type:        controller.SfcType_SFC_EW_L2XCONN,
+		Labels: map[string]string{
+			"openstack-port-chain": pc.ID,
+		},
+	}
+
+	var fc *FlowClassifier
+	if len(pc.FlowClassifiers) != 0 {
+		var exists bool
+		if fc, exists = c.fcs[pc.FlowClassifiers[0]]; !exists {
+			return nil, fmt.Errorf("port chain: '%s': flow classifier: '%s' not found", name, pc.FlowClassifiers[0])
+		}
+		if len(pc.FlowClassifiers) > 1 {
+			c.warnf("port chain: '%s': only flow classifier: '%s' of %d is imported", name, fc.ID,
+				len(pc.FlowClassifiers))
+		}
+		if fc.Protocol != "" || fc.SourceIPPrefix != "" || fc.DestinationIPPrefix != "" ||
+			fc.SourcePortRangeMin != nil || fc.DestinationPortRangeMin != nil {
+			c.warnf("port chain: '%s': the match fields of flow classifier: '%s' are not imported, all the "+
+				"traffic of the source port is steered through the chain", name, fc.ID)
+		}
+	}
+
+	if fc != nil && fc.LogicalSourcePort != "" {
+		if err := c.appendPort(sfc, fc.LogicalSourcePort); err != nil {
+			return nil, fmt.Errorf("port chain: '%s': logical source port: %s", name, err)
+		}
+	}
+	for _, ppgID := range pc.PortPairGroups {
+		ppg, exists := c.ppgs[ppgID]
+		if !exists {
+			return nil, fmt.Errorf("port chain: '%s': port pair group: '%s' not found", name, ppgID)
+		}
+		if len(ppg.PortPairs) == 0 {
+			return nil, fmt.Errorf("port chain: '%s': port pair group: '%s' has no port pairs", name, ppgID)
+		}
+		if len(ppg.PortPairs) > 1 {
+			c.warnf("port chain: '%s': port pair group: '%s' is imported with the first of its %d port pairs",
+				name, ppgID, len(ppg.PortPairs))
+		}
+		pp, exists := c.pps[ppg.PortPairs[0]]
+		if !exists {
+			return nil, fmt.Errorf("port chain: '%s': port pair: '%s' not found", name, ppg.PortPairs[0])
+		}
+		if err := c.appendPort(sfc, pp.Ingress); err != nil {
+			return nil, fmt.Errorf("port chain: '%s': port pair: '%s' ingress: %s", name, pp.ID, err)
+		}
+		if pp.Egress != pp.Ingress {
+			if err := c.appendPort(sfc, pp.Egress); err != nil {
+				return nil, fmt.Errorf("port chain: '%s': port pair: '%s' egress: %s", name, pp.ID, err)
+			}
+		}
+	}
+	if fc != nil && fc.LogicalDestinationPort != "" {
+		if err := c.appendPort(sfc, fc.LogicalDestinationPort); err != nil {
+			return nil, fmt.Errorf("port chain: '%s': logical destination port: %s", name, err)
+		}
+	}
+
+	if len(sfc.Elements) < 2 {
+		return nil, fmt.Errorf("port chain: '%s': has %d ports, an l2 xconnect chain needs at least 2", name,
+			len(sfc.Elements))
+	}
+
+	return sfc, nil
+}
+
+// appendPort appends the element of the port to the chain
+func (c *converter) appendPort(sfc *controller.SfcEntity, portID string) error {
+
+	port, exists := c.ports[portID]
+	if !exists {
+		return fmt.Errorf("port: '%s' not found", portID)
+	}
+	if port.DeviceID == "" {
+		return fmt.Errorf("port: '%s' is not attached to a vm", portID)
+	}
+	if port.HostID == "" {
+		return fmt.Errorf("port: '%s' is not bound to a host", portID)
+	}
+
+	label := port.Name
+	if label == "" {
+		label = port.ID
+	}
+	element := &controller.SfcEntity_SfcElement{
+		Container:        port.DeviceID,
+		PortLabel:        label,
+		EtcdVppSwitchKey: port.HostID,
+		MacAddr:          port.MacAddress,
+		Type:             c.opts.ElementType,
+	}
+	for _, fixedIP := range port.FixedIPs {
+		ip := net.ParseIP(fixedIP.IPAddress)
+		if ip == nil {
+			return fmt.Errorf("port: '%s' has an invalid address: '%s'", portID, fixedIP.IPAddress)
+		}
+		addr := c.addrWithPrefix(portID, ip, fixedIP.SubnetID)
+		if ip.To4() != nil && element.Ipv4Addr == "" {
+			element.Ipv4Addr = addr
+		} else if ip.To4() == nil && element.Ipv6Addr == "" {
+			element.Ipv6Addr = addr
+		}
+	}
+
+	sfc.Elements = append(sfc.Elements, element)
+	return nil
+}
+
+// addrWithPrefix returns the address with the prefix length of its subnet, a host prefix if the subnet is
+// not in the dump
+func (c *converter) addrWithPrefix(portID string, ip net.IP, subnetID string) string {
+
+	if subnet, exists := c.subnets[subnetID]; exists {
+		if _, ipNet, err := net.ParseCIDR(subnet.Cidr); err == nil {
+			ones, _ := ipNet.Mask.Size()
+			return fmt.Sprintf("%s/%d", ip, ones)
+		}
+	}
+	c.warnf("port: '%s': subnet: '%s' of address: '%s' not found, a host prefix is used", portID, subnetID, ip)
+	if ip.To4() != nil {
+		return ip.String() + "/32"
+	}
+	return ip.String() + "/128"
+}
+
+// Hosts returns the names of the hosts the chains have elements on, in order of first use
+func Hosts(sfcs []*controller.SfcEntity) []string {
+
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, sfc := range sfcs {
+		for _, sfcElement := range sfc.Elements {
+			if !seen[sfcElement.EtcdVppSwitchKey] {
+				seen[sfcElement.EtcdVppSwitchKey] = true
+				hosts = append(hosts, sfcElement.EtcdVppSwitchKey)
+			}
+		}
+	}
+	return hosts
+}
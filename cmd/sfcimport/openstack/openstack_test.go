@@ -0,0 +1,141 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package openstack
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ligato/sfc-controller/controller/core"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+func init() {
+	logs.SetLogConfig(&logs.LogConfig{Levels: map[string]string{logs.AllLoggers: "error"}})
+}
+
+// the port chain and the ports are in separate documents, as listed by the Neutron API one resource at a time
+const neutronDump = `
+{"port_chains": [{"id": "pc-1", "name": "web-chain", "port_pair_groups": ["ppg-fw", "ppg-lb"],
+  "flow_classifiers": ["fc-1"]}]}
+{"port_pair_groups": [{"id": "ppg-fw", "port_pairs": ["pp-fw"]}, {"id": "ppg-lb", "port_pairs": ["pp-lb1", "pp-lb2"]}]}
+{"port_pairs": [{"id": "pp-fw", "ingress": "p-fw-in", "egress": "p-fw-out"},
+  {"id": "pp-lb1", "ingress": "p-lb1", "egress": "p-lb1"}, {"id": "pp-lb2", "ingress": "p-lb2", "egress": "p-lb2"}]}
+{"flow_classifiers": [{"id": "fc-1", "protocol": "tcp", "logical_source_port": "p-src",
+  "logical_destination_port": "p-dst"}]}
+{"subnets": [{"id": "sn-1", "cidr": "10.1.0.0/24"}]}
+{"ports": [
+  {"id": "p-src", "name": "src", "device_id": "vm-src", "mac_address": "fa:16:3e:00:00:01",
+   "fixed_ips": [{"subnet_id": "sn-1", "ip_address": "10.1.0.1"}], "binding:host_id": "compute-1"},
+  {"id": "p-fw-in", "name": "fw-in", "device_id": "vm-fw", "binding:host_id": "compute-1"},
+  {"id": "p-fw-out", "name": "fw-out", "device_id": "vm-fw", "binding:host_id": "compute-1"},
+  {"id": "p-lb1", "device_id": "vm-lb1", "binding:host_id": "compute-2"},
+  {"id": "p-lb2", "device_id": "vm-lb2", "binding:host_id": "compute-2"},
+  {"id": "p-dst", "name": "dst", "device_id": "vm-dst", "mac_address": "fa:16:3e:00:00:02",
+   "fixed_ips": [{"subnet_id": "sn-1", "ip_address": "10.1.0.2"}], "binding:host_id": "compute-2"}
+]}
+`
+
+func readDump(t *testing.T) *Dump {
+	dump := &Dump{}
+	if err := Read(strings.NewReader(neutronDump), dump); err != nil {
+		t.Fatalf("Read: %s", err)
+	}
+	return dump
+}
+
+func TestConvertPortChain(t *testing.T) {
+
+	sfcs, warnings, err := Convert(readDump(t), Options{NamePrefix: "os-"})
+	if err != nil {
+		t.Fatalf("Convert: %s", err)
+	}
+	if len(sfcs) != 1 {
+		t.Fatalf("converted %d chains, expected 1", len(sfcs))
+	}
+
+	sfc := sfcs[0]
+	if sfc.Name != "os-web-chain" || sfc.Type != controller.SfcType_SFC_EW_L2XCONN ||
+		sfc.Labels["openstack-port-chain"] != "pc-1" {
+		t.Errorf("unexpected chain: %v", sfc)
+	}
+
+	expected := []string{"vm-src/src", "vm-fw/fw-in", "vm-fw/fw-out", "vm-lb1/p-lb1", "vm-dst/dst"}
+	if len(sfc.Elements) != len(expected) {
+		t.Fatalf("chain has %d elements, expected %d: %v", len(sfc.Elements), len(expected), sfc.Elements)
+	}
+	for i, sfcElement := range sfc.Elements {
+		if got := sfcElement.Container + "/" + sfcElement.PortLabel; got != expected[i] {
+			t.Errorf("element %d is: '%s', expected: '%s'", i, got, expected[i])
+		}
+		if sfcElement.Type != controller.SfcElementType_VPP_CONTAINER_MEMIF {
+			t.Errorf("element %d has type %s", i, sfcElement.Type)
+		}
+	}
+	if e := sfc.Elements[0]; e.Ipv4Addr != "10.1.0.1/24" || e.MacAddr != "fa:16:3e:00:00:01" ||
+		e.EtcdVppSwitchKey != "compute-1" {
+		t.Errorf("unexpected source element: %v", e)
+	}
+
+	// the tcp match of the classifier and the second lb instance could not be imported
+	if len(warnings) != 2 {
+		t.Errorf("expected 2 warnings, got: %v", warnings)
+	}
+
+	if hosts := Hosts(sfcs); strings.Join(hosts, ",") != "compute-1,compute-2" {
+		t.Errorf("unexpected hosts: %v", hosts)
+	}
+}
+
+func TestConvertMissingPort(t *testing.T) {
+
+	dump := readDump(t)
+	dump.Ports = dump.Ports[:len(dump.Ports)-1]
+
+	if _, _, err := Convert(dump, Options{}); err == nil || !strings.Contains(err.Error(), "p-dst") {
+		t.Errorf("expected an error for the missing destination port, got: %v", err)
+	}
+}
+
+// TestImportedChainRenders renders the imported chain with the hosts it is placed on
+func TestImportedChainRenders(t *testing.T) {
+
+	sfcs, _, err := Convert(readDump(t), Options{})
+	if err != nil {
+		t.Fatalf("Convert: %s", err)
+	}
+
+	cfg := &core.YamlConfig{Version: 1}
+	for i, host := range Hosts(sfcs) {
+		cfg.HEs = append(cfg.HEs, controller.HostEntity{
+			Name:                   host,
+			EthIfName:              "GigabitEthernet13/0/0",
+			EthIpv4:                fmt.Sprintf("10.100.0.%d/24", i+1),
+			VxlanTunnelIpv4:        fmt.Sprintf("10.200.0.%d/24", i+1),
+			CreateVxlanStaticRoute: true,
+		})
+	}
+	for _, sfc := range sfcs {
+		cfg.SFCs = append(cfg.SFCs, *sfc)
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatalf("RenderConfig: %s", err)
+	}
+}
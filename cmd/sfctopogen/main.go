@@ -0,0 +1,103 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// sfctopogen is a command-line tool for generating randomized topologies
+// and replaying them, for scale runs and for reproducing bug reports:
+//
+//	sfctopogen gen -hosts 10 -ees 2 -chains 100 -seed 42 > topo.yaml
+//	sfctopogen replay -config topo.yaml                      # render offline, print the agent keys
+//	sfctopogen replay -config topo.yaml -url http://localhost:9191  # post it to a controller
+//
+// The description of a generated topology records the parameters it was
+// generated with, so it can be regenerated from a bug report.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ligato/sfc-controller/cmd/sfctopogen/topogen"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: sfctopogen gen|replay [flags], -h after the command lists its flags")
+	os.Exit(2)
+}
+
+func main() {
+
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "gen":
+		err = gen(os.Args[2:])
+	case "replay":
+		err = replay(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "sfctopogen:", err)
+		os.Exit(1)
+	}
+}
+
+func gen(args []string) error {
+
+	var p topogen.Params
+	fs := flag.NewFlagSet("gen", flag.ExitOnError)
+	fs.IntVar(&p.Hosts, "hosts", 2, "number of host entities")
+	fs.IntVar(&p.EEs, "ees", 1, "number of external entities")
+	fs.IntVar(&p.Chains, "chains", 10, "number of sfc chains")
+	fs.IntVar(&p.MaxElements, "max-elements", 4, "most vnf elements in a chain")
+	fs.Int64Var(&p.Seed, "seed", 1, "random seed, the same seed and sizes generate the same topology")
+	fs.Parse(args)
+
+	cfg, err := topogen.Generate(p)
+	if err != nil {
+		return err
+	}
+	return topogen.WriteConfig(cfg, os.Stdout)
+}
+
+func replay(args []string) error {
+
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	configFile := fs.String("config", "", "topology yaml file, in the format of the controller's -sfc-config")
+	baseURL := fs.String("url", "", "controller REST API to post the topology to, renders offline if not set")
+	logLevel := fs.String("log-level", "error", "controller log level while rendering offline")
+	fs.Parse(args)
+
+	if *configFile == "" {
+		return fmt.Errorf("missing -config")
+	}
+	cfg, err := topogen.ReadConfig(*configFile)
+	if err != nil {
+		return err
+	}
+
+	if *baseURL != "" {
+		return topogen.Post(cfg, *baseURL)
+	}
+
+	if err := logs.SetLogConfig(&logs.LogConfig{Levels: map[string]string{logs.AllLoggers: *logLevel}}); err != nil {
+		return err
+	}
+	return topogen.Render(cfg, os.Stdout)
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package topogen generates randomized, valid sfc controller topologies and
+// replays a topology, either by rendering it offline or by posting it to a
+// running controller.  The same seed always generates the same topology, so
+// a scale run or a bug report can be reproduced from its parameters alone.
+package topogen
+
+import (
+	"fmt"
+	"math/rand"
+
+	"github.com/ligato/sfc-controller/controller/core"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// the hosts' uplink addresses are allocated from a /16, which limits the number of entities
+const maxEntities = 250 * 250
+
+// Params sizes the generated topology
+type Params struct {
+	Hosts       int   // host entities, at least 1
+	EEs         int   // external entities
+	Chains      int   // sfc entities
+	MaxElements int   // most vnf elements in a chain, default 4
+	Seed        int64 // the same seed and sizes always generate the same topology
+}
+
+// the kinds of chains generated, n/s vxlan chains are only generated if there is a peer to tunnel to
+type chainKind int
+
+const (
+	chainEWBD chainKind = iota
+	chainEWL2XConn
+	chainEWMemif
+	chainNSNicBD
+	chainNSNicL2XConn
+	chainNSVxlanHost
+	chainNSVxlanEE
+)
+
+var chainKinds = []chainKind{chainEWBD, chainEWL2XConn, chainEWMemif, chainNSNicBD, chainNSNicL2XConn,
+	chainNSVxlanHost, chainNSVxlanEE}
+
+var (
+	memifElementTypes = []controller.SfcElementType{
+		controller.SfcElementType_VPP_CONTAINER_MEMIF,
+		controller.SfcElementType_NON_VPP_CONTAINER_MEMIF,
+	}
+	vnfElementTypes = []controller.SfcElementType{
+		controller.SfcElementType_VPP_CONTAINER_MEMIF,
+		controller.SfcElementType_NON_VPP_CONTAINER_AFP,
+		controller.SfcElementType_NON_VPP_CONTAINER_MEMIF,
+		controller.SfcElementType_VPP_CONTAINER_AFP,
+	}
+)
+
+type generator struct {
+	p       Params
+	rnd     *rand.Rand
+	cfg     *core.YamlConfig
+	vnfs    int            // vnf containers generated so far, each element is its own container
+	nicPort map[string]int // next free nic port on each host
+}
+
+// Generate returns a topology of p.Hosts hosts meshed with vxlan, p.EEs external entities and p.Chains
+// chains of mixed types and element types
+func Generate(p Params) (*core.YamlConfig, error) {
+
+	if p.Hosts < 1 || p.Hosts > maxEntities {
+		return nil, fmt.Errorf("number of hosts %d is not between 1 and %d", p.Hosts, maxEntities)
+	}
+	if p.EEs < 0 || p.EEs > maxEntities {
+		return nil, fmt.Errorf("number of external entities %d is not between 0 and %d", p.EEs, maxEntities)
+	}
+	if p.Chains < 0 {
+		return nil, fmt.Errorf("number of chains %d is negative", p.Chains)
+	}
+	if p.MaxElements == 0 {
+		p.MaxElements = 4
+	}
+	if p.MaxElements < 2 {
+		return nil, fmt.Errorf("max elements %d is less than 2", p.MaxElements)
+	}
+
+	g := &generator{
+		p:       p,
+		rnd:     rand.New(rand.NewSource(p.Seed)),
+		nicPort: make(map[string]int),
+		cfg: &core.YamlConfig{
+			Version: 1,
+			Description: fmt.Sprintf("generated topology: hosts=%d ees=%d chains=%d max-elements=%d seed=%d",
+				p.Hosts, p.EEs, p.Chains, p.MaxElements, p.Seed),
+			SysParms: controller.SystemParameters{Mtu: 1500},
+		},
+	}
+
+	for i := 0; i < p.Hosts; i++ {
+		g.cfg.HEs = append(g.cfg.HEs, g.host(i))
+	}
+	for i := 0; i < p.EEs; i++ {
+		g.cfg.EEs = append(g.cfg.EEs, g.externalEntity(i))
+	}
+	for i := 0; i < p.Chains; i++ {
+		g.cfg.SFCs = append(g.cfg.SFCs, g.chain(i))
+	}
+
+	return g.cfg, nil
+}
+
+// ipv4Addr returns the i'th address of the 10.net.0.0/16 subnet, skipping .0 and .255
+func ipv4Addr(net int, i int) string {
+	return fmt.Sprintf("10.%d.%d.%d", net, i/250, i%250+1)
+}
+
+func (g *generator) host(i int) controller.HostEntity {
+	return controller.HostEntity{
+		Name:                   fmt.Sprintf("vswitch%d", i+1),
+		EthIfName:              "GigabitEthernet13/0/0",
+		EthIpv4:                ipv4Addr(10, i) + "/15",
+		VxlanTunnelIpv4:        ipv4Addr(20, i) + "/16",
+		CreateVxlanStaticRoute: true,
+	}
+}
+
+// externalEntity has no driver, so rendering it never reaches out to a router, its host interface shares the
+// 10.10.0.0/15 subnet of the hosts' nics
+func (g *generator) externalEntity(i int) controller.ExternalEntity {
+	return controller.ExternalEntity{
+		Name:           fmt.Sprintf("router%d", i+1),
+		MgmntIpAddress: ipv4Addr(30, i),
+		HostInterface: &controller.ExternalEntity_HostInterface{
+			IfName:   "GigabitEthernet1",
+			Ipv4Addr: ipv4Addr(11, i) + "/15",
+		},
+		HostVxlan: &controller.ExternalEntity_HostVxlan{
+			IfName:     "Loopback0",
+			SourceIpv4: ipv4Addr(21, i),
+		},
+	}
+}
+
+func (g *generator) randomHost() string {
+	return g.cfg.HEs[g.rnd.Intn(len(g.cfg.HEs))].Name
+}
+
+// vnf returns an element for a new container on the host, of one of the types
+func (g *generator) vnf(host string, types []controller.SfcElementType) *controller.SfcEntity_SfcElement {
+	g.vnfs++
+	return &controller.SfcEntity_SfcElement{
+		Container:        fmt.Sprintf("vnf%d", g.vnfs),
+		PortLabel:        "port1",
+		EtcdVppSwitchKey: host,
+		Type:             types[g.rnd.Intn(len(types))],
+	}
+}
+
+// vnfElements returns between min and max elements for new containers on the host
+func (g *generator) vnfElements(host string, min int, max int,
+	types []controller.SfcElementType) []*controller.SfcEntity_SfcElement {
+
+	n := min
+	if max > min {
+		n += g.rnd.Intn(max - min + 1)
+	}
+	elements := make([]*controller.SfcEntity_SfcElement, 0, n)
+	for i := 0; i < n; i++ {
+		elements = append(elements, g.vnf(host, types))
+	}
+	return elements
+}
+
+// nic returns the host's element for its next free nic port
+func (g *generator) nic(host string) *controller.SfcEntity_SfcElement {
+	g.nicPort[host]++
+	return &controller.SfcEntity_SfcElement{
+		Container:        host,
+		PortLabel:        fmt.Sprintf("GigabitEthernet13/0/%d", g.nicPort[host]),
+		EtcdVppSwitchKey: host,
+		Type:             controller.SfcElementType_HOST_ENTITY,
+	}
+}
+
+func (g *generator) chain(i int) controller.SfcEntity {
+
+	var kinds []chainKind
+	for _, kind := range chainKinds {
+		if (kind == chainNSVxlanHost && len(g.cfg.HEs) < 2) || (kind == chainNSVxlanEE && len(g.cfg.EEs) == 0) {
+			continue
+		}
+		kinds = append(kinds, kind)
+	}
+
+	host := g.randomHost()
+	sfc := controller.SfcEntity{}
+
+	switch kinds[g.rnd.Intn(len(kinds))] {
+
+	case chainEWBD:
+		sfc.Type = controller.SfcType_SFC_EW_BD
+		sfc.Elements = g.vnfElements(host, 2, g.p.MaxElements, vnfElementTypes)
+
+	case chainEWL2XConn:
+		sfc.Type = controller.SfcType_SFC_EW_L2XCONN
+		sfc.Elements = g.vnfElements(host, 2, 2, vnfElementTypes)
+
+	case chainEWMemif:
+		sfc.Type = controller.SfcType_SFC_EW_MEMIF
+		sfc.Elements = g.vnfElements(host, 2, 2, memifElementTypes)
+
+	case chainNSNicBD:
+		sfc.Type = controller.SfcType_SFC_NS_NIC_BD
+		sfc.Elements = append([]*controller.SfcEntity_SfcElement{g.nic(host)},
+			g.vnfElements(host, 1, g.p.MaxElements-1, vnfElementTypes)...)
+
+	case chainNSNicL2XConn:
+		sfc.Type = controller.SfcType_SFC_NS_NIC_L2XCONN
+		sfc.Elements = []*controller.SfcEntity_SfcElement{g.nic(host), g.vnf(host, vnfElementTypes)}
+
+	case chainNSVxlanHost:
+		dest := g.randomHost()
+		for dest == host {
+			dest = g.randomHost()
+		}
+		sfc.Type = controller.SfcType_SFC_NS_VXLAN
+		sfc.Elements = append([]*controller.SfcEntity_SfcElement{{
+			Container:        dest,
+			EtcdVppSwitchKey: host,
+			Type:             controller.SfcElementType_HOST_ENTITY,
+		}}, g.vnfElements(host, 1, g.p.MaxElements-1, vnfElementTypes)...)
+
+	case chainNSVxlanEE:
+		ee := g.cfg.EEs[g.rnd.Intn(len(g.cfg.EEs))].Name
+		sfc.Type = controller.SfcType_SFC_NS_VXLAN
+		sfc.Elements = append([]*controller.SfcEntity_SfcElement{{
+			Container: ee,
+			Type:      controller.SfcElementType_EXTERNAL_ENTITY,
+		}}, g.vnfElements(host, 1, g.p.MaxElements-1, vnfElementTypes)...)
+	}
+
+	sfc.Name = fmt.Sprintf("chain%d-%s", i+1, sfc.Type)
+	sfc.Description = fmt.Sprintf("%s chain on host %s", sfc.Type, host)
+
+	return sfc
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topogen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/core"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+// WriteConfig writes the topology in the format of the controller's -sfc-config yaml file
+func WriteConfig(cfg *core.YamlConfig, w io.Writer) error {
+
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// ReadConfig reads a topology in the format of the controller's -sfc-config yaml file
+func ReadConfig(fpath string) (*core.YamlConfig, error) {
+
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return core.ParseYamlConfig(b)
+}
+
+// Render renders the topology offline and writes the vpp-agent keys it produced, one json line per key
+// sorted by key: {"key": "/vnf-agent/...", "value": {...}}, the layout of the golden files in tests/gotests
+func Render(cfg *core.YamlConfig, w io.Writer) error {
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		return err
+	}
+
+	dump := broker.Dump(utils.GetVppAgentPrefix())
+	keys := make([]string, 0, len(dump))
+	for key := range dump {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	enc := json.NewEncoder(w)
+	for _, key := range keys {
+		entry := struct {
+			Key   string          `json:"key"`
+			Value json.RawMessage `json:"value"`
+		}{key, dump[key]}
+		if err := enc.Encode(&entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Post replays the topology to the controller's REST API at baseURL, ie http://localhost:9191, the
+// system parameters first then the external entities, hosts and chains in the order of the topology
+func Post(cfg *core.YamlConfig, baseURL string) error {
+
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	if err := post(baseURL+controller.SystemParametersKey(), &cfg.SysParms); err != nil {
+		return err
+	}
+	for i := range cfg.EEs {
+		ee := &cfg.EEs[i]
+		if err := post(baseURL+controller.ExternalEntityNameKey(url.PathEscape(ee.Name)), ee); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.HEs {
+		he := &cfg.HEs[i]
+		if err := post(baseURL+controller.HostEntityNameKey(url.PathEscape(he.Name)), he); err != nil {
+			return err
+		}
+	}
+	for i := range cfg.SFCs {
+		sfc := &cfg.SFCs[i]
+		if err := post(baseURL+controller.SfcEntityNameKey(url.PathEscape(sfc.Name)), sfc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func post(u string, entity proto.Message) error {
+
+	body, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Changed-By", "sfctopogen")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("POST %s: %s: %s", u, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package topogen
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/ligato/sfc-controller/controller/core"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
+)
+
+func init() {
+	logs.SetLogConfig(&logs.LogConfig{Levels: map[string]string{logs.AllLoggers: "error"}})
+}
+
+func TestGeneratedTopologiesRender(t *testing.T) {
+
+	sizes := []Params{
+		{Hosts: 1, Chains: 20},
+		{Hosts: 3, EEs: 2, Chains: 40},
+		{Hosts: 5, EEs: 1, Chains: 40, MaxElements: 6},
+	}
+	for _, p := range sizes {
+		for seed := int64(1); seed <= 10; seed++ {
+			p.Seed = seed
+			cfg, err := Generate(p)
+			if err != nil {
+				t.Fatalf("%+v: error generating: %s", p, err)
+			}
+			var rendered bytes.Buffer
+			if err := Render(cfg, &rendered); err != nil {
+				t.Fatalf("%+v: error rendering: %s", p, err)
+			}
+			if rendered.Len() == 0 {
+				t.Fatalf("%+v: no keys rendered", p)
+			}
+		}
+	}
+}
+
+func TestGenerateIsRepeatable(t *testing.T) {
+
+	p := Params{Hosts: 4, EEs: 2, Chains: 30, Seed: 42}
+	first, err := generateYaml(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := generateYaml(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("the same params generated different topologies")
+	}
+
+	// the written topology must replay to the same topology
+	cfg := &core.YamlConfig{}
+	if err := yaml.Unmarshal(first, cfg); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := WriteConfig(cfg, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, buf.Bytes()) {
+		t.Fatalf("the written topology changed when read back")
+	}
+}
+
+func generateYaml(p Params) ([]byte, error) {
+	cfg, err := Generate(p)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := WriteConfig(cfg, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package client is the Go client of the sfc controller's northbound api.
+// Entities are posted, and the operations are run, via the controller's
+// REST api so they are validated, rendered and recorded in the config
+// versions like any other change.  The render status of each entity is read
+// from, and watched in, ETCD where the controller records it.  The urls and
+// keys are built with the model's key functions, so a Go service embedding
+// the client does not re-implement the controller's conventions.
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ligato/cn-infra/db/keyval"
+)
+
+// DefaultTimeout bounds each REST call, unless another http client is set
+const DefaultTimeout = 60 * time.Second
+
+// Client is a client of one sfc controller
+type Client struct {
+	baseURL   string // ie http://localhost:9191
+	http      *http.Client
+	db        keyval.ProtoBroker
+	watcher   keyval.ProtoWatcher
+	changedBy string
+}
+
+// Option customizes a client
+type Option func(*Client)
+
+// WithHTTPClient sets the http client of the REST calls, ie for tls or another timeout
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.http = hc
+	}
+}
+
+// WithChangedBy sets who the changes are recorded as made by in the entities' history
+func WithChangedBy(changedBy string) Option {
+	return func(c *Client) {
+		c.changedBy = changedBy
+	}
+}
+
+// WithDatastore sets the ETCD broker the statuses are read from, and the watcher they are watched with
+func WithDatastore(db keyval.ProtoBroker, watcher keyval.ProtoWatcher) Option {
+	return func(c *Client) {
+		c.db = db
+		c.watcher = watcher
+	}
+}
+
+// New returns a client of the controller serving its REST api at the base url
+func New(baseURL string, opts ...Option) *Client {
+
+	c := &Client{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: DefaultTimeout},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// APIError is an error answered by the controller
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("sfc controller: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// IsNotFound returns whether the error is the controller not finding the entity
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == http.StatusNotFound
+}
+
+// do sends in as the json body of the request, and decodes the json answered into out, in and out may be nil
+func (c *Client) do(method string, path string, in interface{}, out interface{}) error {
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	resp, err := c.send(method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// send sends the request, the response is only returned if its status is ok
+func (c *Client) send(method string, path string, body io.Reader) (*http.Response, error) {
+
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.changedBy != "" {
+		req.Header.Set("X-Changed-By", c.changedBy)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	data, _ := ioutil.ReadAll(resp.Body)
+	return nil, &APIError{StatusCode: resp.StatusCode, Message: errorMessage(data)}
+}
+
+// errorMessage returns the message of an error body, the controller answers {"Error": "..."} or a json string
+func errorMessage(data []byte) string {
+
+	var structured struct{ Error string }
+	if err := json.Unmarshal(data, &structured); err == nil && structured.Error != "" {
+		return structured.Error
+	}
+	var message string
+	if err := json.Unmarshal(data, &message); err == nil {
+		return message
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+func TestPutSfcEntity(t *testing.T) {
+
+	var method, path, changedBy string
+	var posted controller.SfcEntity
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		method, path, changedBy = req.Method, req.URL.Path, req.Header.Get("X-Changed-By")
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &posted)
+		json.NewEncoder(w).Encode("OK")
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/", WithChangedBy("ops"))
+	if err := c.PutSfcEntity(&controller.SfcEntity{Name: "sfc1", Description: "d"}); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPost || path != controller.SfcEntityNameKey("sfc1") {
+		t.Fatalf("sent %s %s", method, path)
+	}
+	if changedBy != "ops" || posted.Name != "sfc1" || posted.Description != "d" {
+		t.Fatalf("posted %v changed by: '%s'", posted, changedBy)
+	}
+}
+
+func TestPatchSfcEntity(t *testing.T) {
+
+	var method, path string
+	var patch map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		method, path = req.Method, req.URL.Path
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &patch)
+		json.NewEncoder(w).Encode("OK")
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	if err := c.PatchSfcEntity("sfc1", map[string]interface{}{"description": nil, "mtu": 9000}); err != nil {
+		t.Fatal(err)
+	}
+	if method != http.MethodPatch || path != controller.SfcEntityNameKey("sfc1") {
+		t.Fatalf("sent %s %s", method, path)
+	}
+	if value, exists := patch["description"]; !exists || value != nil || patch["mtu"] != float64(9000) {
+		t.Fatalf("patched %v", patch)
+	}
+}
+
+func TestListOptions(t *testing.T) {
+
+	var query url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		query = req.URL.Query()
+		json.NewEncoder(w).Encode([]controller.SfcEntity{{Name: "sfc1"}})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	sfcs, err := c.ListSfcEntities(&ListOptions{Host: "HOST1", Labels: map[string]string{"app": "fw", "tier": "1"},
+		Selector: "tier=gold,!legacy", Limit: 10})
+	if err != nil || len(sfcs) != 1 {
+		t.Fatalf("sfcs: %v, err: %v", sfcs, err)
+	}
+	if query.Get("host") != "HOST1" || len(query["label"]) != 2 || query.Get("selector") != "tier=gold,!legacy" ||
+		query.Get("limit") != "10" || query.Get("offset") != "" {
+		t.Fatalf("query: %v", query)
+	}
+	if _, err := c.ListSfcEntities(nil); err != nil || len(query) != 0 {
+		t.Fatalf("query without options: %v, err: %v", query, err)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.URL.Path {
+		case controller.HostEntityNameKey("missing"):
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode("host entity does not found:missing")
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(struct{ Error string }{"Invalid he"})
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	if _, err := c.GetHostEntity("missing"); !IsNotFound(err) {
+		t.Fatalf("expected not found, got: %v", err)
+	}
+	err := c.PutHostEntity(&controller.HostEntity{Name: "bad"})
+	if apiErr, ok := err.(*APIError); !ok || apiErr.StatusCode != http.StatusBadRequest ||
+		apiErr.Message != "Invalid he" {
+		t.Fatalf("expected the controller's error, got: %v", err)
+	}
+}
+
+func TestBulkApplyNotApplied(t *testing.T) {
+
+	var posted BulkApply
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &posted)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(&BulkApplyResult{Results: []*BulkEntityResult{
+			{Kind: controller.HostEntityKind, Name: "he1", Result: "not_applied"},
+			{Kind: controller.SfcEntityKind, Name: "sfc1", Result: "invalid", Error: "Invalid element"},
+		}})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	result, err := c.BulkApply(&BulkApply{
+		HostEntities: []*controller.HostEntity{{Name: "he1"}},
+		SfcEntities:  []*controller.SfcEntity{{Name: "sfc1"}},
+	})
+	if apiErr, ok := err.(*APIError); !ok || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected the controller's error, got: %v", err)
+	}
+	if len(posted.HostEntities) != 1 || len(posted.SfcEntities) != 1 {
+		t.Fatalf("posted %v", posted)
+	}
+	if result.Applied || len(result.Results) != 2 || result.Results[1].Error != "Invalid element" {
+		t.Fatalf("unexpected result: %v", result)
+	}
+}
+
+func TestScheduleChange(t *testing.T) {
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var sc controller.ScheduledChange
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &sc)
+		sc.State, sc.SubmittedBy = "scheduled", "ops"
+		json.NewEncoder(w).Encode(&sc)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL)
+	sc := &controller.ScheduledChange{Name: "mw1", WindowStart: 1767225600,
+		SfcEntities: []*controller.SfcEntity{{Name: "sfc1"}}}
+	if _, err := c.ScheduleChange(sc); err != nil {
+		t.Fatal(err)
+	}
+	if sc.State != "scheduled" || sc.SubmittedBy != "ops" || len(sc.SfcEntities) != 1 {
+		t.Fatalf("scheduled change not updated from the answer: %v", sc)
+	}
+}
+
+func TestEntityStatuses(t *testing.T) {
+
+	db := membroker.New()
+	db.Put(controller.EntityStatusKey(controller.SfcEntityKind, "sfc1"),
+		&controller.EntityStatus{Name: "sfc1", State: controller.RenderStateType_RENDERED})
+	db.Put(controller.EntityStatusKey(controller.HostEntityKind, "HOST1"),
+		&controller.EntityStatus{Name: "HOST1", State: controller.RenderStateType_RENDER_ERROR})
+
+	c := New("http://localhost:9191", WithDatastore(db, nil))
+
+	status, err := c.GetEntityStatus(controller.SfcEntityKind, "sfc1")
+	if err != nil || status == nil || status.State != controller.RenderStateType_RENDERED {
+		t.Fatalf("status: %v, err: %v", status, err)
+	}
+	if status, err := c.GetEntityStatus(controller.SfcEntityKind, "sfc2"); err != nil || status != nil {
+		t.Fatalf("status of an unrendered sfc: %v, err: %v", status, err)
+	}
+
+	statuses, err := c.ListEntityStatuses(controller.HostEntityKind)
+	if err != nil || len(statuses) != 1 || statuses[0].Kind != controller.HostEntityKind ||
+		statuses[0].Name != "HOST1" {
+		t.Fatalf("statuses: %v, err: %v", statuses, err)
+	}
+	if statuses, _ := c.ListEntityStatuses(""); len(statuses) != 2 {
+		t.Fatalf("statuses of every kind: %v", statuses)
+	}
+
+	if _, err := New("http://localhost:9191").GetEntityStatus(controller.SfcEntityKind, "sfc1"); err == nil {
+		t.Fatal("status read without a datastore")
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// ListOptions are the filters and the page of a list, each list accepts the filters that apply to its
+// entities, the controller answers an error for the others
+type ListOptions struct {
+	Host     string            // entities on the host
+	Tenant   string            // entities of the tenant
+	Labels   map[string]string // entities whose elements have the labels in their metadata
+	Selector string            // entities whose labels match the selector, ie tier=gold,rack!=A3
+	Status   string            // entities in the render state, ie RENDER_ERROR
+	Offset   int
+	Limit    int // 0 lists every entity from the offset
+}
+
+// query returns the url query of the options
+func (opts *ListOptions) query() string {
+
+	if opts == nil {
+		return ""
+	}
+	values := url.Values{}
+	if opts.Host != "" {
+		values.Set("host", opts.Host)
+	}
+	if opts.Tenant != "" {
+		values.Set("tenant", opts.Tenant)
+	}
+	var keys []string
+	for key := range opts.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		values.Add("label", key+"="+opts.Labels[key])
+	}
+	if opts.Selector != "" {
+		values.Set("selector", opts.Selector)
+	}
+	if opts.Status != "" {
+		values.Set("status", opts.Status)
+	}
+	if opts.Offset != 0 {
+		values.Set("offset", strconv.Itoa(opts.Offset))
+	}
+	if opts.Limit != 0 {
+		values.Set("limit", strconv.Itoa(opts.Limit))
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// GetSystemParameters returns the system parameters
+func (c *Client) GetSystemParameters() (*controller.SystemParameters, error) {
+	sp := &controller.SystemParameters{}
+	return sp, c.do(http.MethodGet, controller.SystemParametersKey(), nil, sp)
+}
+
+// PutSystemParameters sets the system parameters
+func (c *Client) PutSystemParameters(sp *controller.SystemParameters) error {
+	return c.do(http.MethodPost, controller.SystemParametersKey(), sp, nil)
+}
+
+// GetExternalEntity returns the ee, IsNotFound(err) if it is not configured
+func (c *Client) GetExternalEntity(name string) (*controller.ExternalEntity, error) {
+	ee := &controller.ExternalEntity{}
+	return ee, c.do(http.MethodGet, controller.ExternalEntityNameKey(name), nil, ee)
+}
+
+// ListExternalEntities returns the ee's matching the options, every ee if they are nil
+func (c *Client) ListExternalEntities(opts *ListOptions) ([]controller.ExternalEntity, error) {
+	var ees []controller.ExternalEntity
+	return ees, c.do(http.MethodGet, controller.ExternalEntitiesHTTPPrefix()+opts.query(), nil, &ees)
+}
+
+// PutExternalEntity creates or updates the ee, and wires it
+func (c *Client) PutExternalEntity(ee *controller.ExternalEntity) error {
+	return c.do(http.MethodPost, controller.ExternalEntityNameKey(ee.Name), ee, nil)
+}
+
+// PatchExternalEntity applies the json merge patch to the ee, a field set to nil in the patch is removed
+func (c *Client) PatchExternalEntity(name string, patch interface{}) error {
+	return c.do(http.MethodPatch, controller.ExternalEntityNameKey(name), patch, nil)
+}
+
+// GetHostEntity returns the he, IsNotFound(err) if it is not configured
+func (c *Client) GetHostEntity(name string) (*controller.HostEntity, error) {
+	he := &controller.HostEntity{}
+	return he, c.do(http.MethodGet, controller.HostEntityNameKey(name), nil, he)
+}
+
+// ListHostEntities returns the he's matching the options, every he if they are nil
+func (c *Client) ListHostEntities(opts *ListOptions) ([]controller.HostEntity, error) {
+	var hes []controller.HostEntity
+	return hes, c.do(http.MethodGet, controller.HostEntitiesHTTPPrefix()+opts.query(), nil, &hes)
+}
+
+// PutHostEntity creates or updates the he, and wires it
+func (c *Client) PutHostEntity(he *controller.HostEntity) error {
+	return c.do(http.MethodPost, controller.HostEntityNameKey(he.Name), he, nil)
+}
+
+// PatchHostEntity applies the json merge patch to the he, ie map[string]interface{}{"mtu": 9000}
+func (c *Client) PatchHostEntity(name string, patch interface{}) error {
+	return c.do(http.MethodPatch, controller.HostEntityNameKey(name), patch, nil)
+}
+
+// GetSfcEntity returns the sfc, IsNotFound(err) if it is not configured
+func (c *Client) GetSfcEntity(name string) (*controller.SfcEntity, error) {
+	sfc := &controller.SfcEntity{}
+	return sfc, c.do(http.MethodGet, controller.SfcEntityNameKey(name), nil, sfc)
+}
+
+// ListSfcEntities returns the sfc's matching the options, the chains of network services and template
+// instances included
+func (c *Client) ListSfcEntities(opts *ListOptions) ([]controller.SfcEntity, error) {
+	var sfcs []controller.SfcEntity
+	return sfcs, c.do(http.MethodGet, controller.SfcEntityHTTPPrefix()+opts.query(), nil, &sfcs)
+}
+
+// PutSfcEntity creates or updates the sfc, and wires it
+func (c *Client) PutSfcEntity(sfc *controller.SfcEntity) error {
+	return c.do(http.MethodPost, controller.SfcEntityNameKey(sfc.Name), sfc, nil)
+}
+
+// PatchSfcEntity applies the json merge patch to the sfc, the patch replaces arrays as a whole, so an element
+// is added by patching the complete list of elements
+func (c *Client) PatchSfcEntity(name string, patch interface{}) error {
+	return c.do(http.MethodPatch, controller.SfcEntityNameKey(name), patch, nil)
+}
+
+// GetNetworkService returns the network service, IsNotFound(err) if it is not configured
+func (c *Client) GetNetworkService(name string) (*controller.NetworkService, error) {
+	ns := &controller.NetworkService{}
+	return ns, c.do(http.MethodGet, controller.NetworkServiceNameKey(name), nil, ns)
+}
+
+// ListNetworkServices returns the network services matching the options
+func (c *Client) ListNetworkServices(opts *ListOptions) ([]controller.NetworkService, error) {
+	var nss []controller.NetworkService
+	return nss, c.do(http.MethodGet, controller.NetworkServicesHTTPPrefix()+opts.query(), nil, &nss)
+}
+
+// PutNetworkService creates or updates the network service, and wires its chains
+func (c *Client) PutNetworkService(ns *controller.NetworkService) error {
+	return c.do(http.MethodPost, controller.NetworkServiceNameKey(ns.Name), ns, nil)
+}
+
+// PatchNetworkService applies the json merge patch to the network service, its chains are re-wired
+func (c *Client) PatchNetworkService(name string, patch interface{}) error {
+	return c.do(http.MethodPatch, controller.NetworkServiceNameKey(name), patch, nil)
+}
+
+// GetSfcTemplate returns the sfc template, IsNotFound(err) if it is not configured
+func (c *Client) GetSfcTemplate(name string) (*controller.SfcTemplate, error) {
+	tpl := &controller.SfcTemplate{}
+	return tpl, c.do(http.MethodGet, controller.SfcTemplateNameKey(name), nil, tpl)
+}
+
+// ListSfcTemplates returns the page of sfc templates of the options
+func (c *Client) ListSfcTemplates(opts *ListOptions) ([]controller.SfcTemplate, error) {
+	var tpls []controller.SfcTemplate
+	return tpls, c.do(http.MethodGet, controller.SfcTemplatesHTTPPrefix()+opts.query(), nil, &tpls)
+}
+
+// PutSfcTemplate creates or updates the sfc template
+func (c *Client) PutSfcTemplate(tpl *controller.SfcTemplate) error {
+	return c.do(http.MethodPost, controller.SfcTemplateNameKey(tpl.Name), tpl, nil)
+}
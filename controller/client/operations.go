@@ -0,0 +1,215 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// VersionDiff is the answer of a rollback, the entities are "kind/name"
+type VersionDiff struct {
+	FromVersion uint32   `json:"from_version"`
+	ToVersion   uint32   `json:"to_version"`
+	Added       []string `json:"added,omitempty"`
+	Removed     []string `json:"removed,omitempty"`
+	Changed     []string `json:"changed,omitempty"`
+}
+
+// Inconsistency is a disagreement found by a consistency check, see ConsistencyReport
+type Inconsistency struct {
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+	Detail string `json:"detail"`
+}
+
+// ConsistencyReport is the answer of a consistency check
+type ConsistencyReport struct {
+	Consistent      bool            `json:"consistent"`
+	Inconsistencies []Inconsistency `json:"inconsistencies,omitempty"`
+}
+
+// BulkApply is a batch of entities the controller applies all or nothing
+type BulkApply struct {
+	ExternalEntities []*controller.ExternalEntity `json:"external_entities,omitempty"`
+	HostEntities     []*controller.HostEntity     `json:"host_entities,omitempty"`
+	SfcEntities      []*controller.SfcEntity      `json:"sfc_entities,omitempty"`
+}
+
+// BulkEntityResult is the result of an entity of a batch: created, updated, unchanged, invalid, render_error
+// or not_applied
+type BulkEntityResult struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkApplyResult is the answer of a bulk apply, with the result of each entity of the batch
+type BulkApplyResult struct {
+	Applied bool                `json:"applied"`
+	Version uint32              `json:"version,omitempty"`
+	Results []*BulkEntityResult `json:"results"`
+}
+
+// SelectorOperationResult is the answer of an operation on the entities matching a selector, the result of
+// each entity is reconciled, migrated or failed
+type SelectorOperationResult struct {
+	Selector string              `json:"selector"`
+	Results  []*BulkEntityResult `json:"results"`
+}
+
+// InstantiateSfcTemplate expands the template into its instances and wires them
+func (c *Client) InstantiateSfcTemplate(inst *controller.SfcTemplateInstantiation) error {
+	return c.do(http.MethodPost, controller.SfcTemplateInstantiateHTTPPrefix()+inst.Template, inst, nil)
+}
+
+// MigrateSfc moves the chain's elements to another host, the chain stays where it is if the move fails
+func (c *Client) MigrateSfc(m *controller.SfcMigration) error {
+	return c.do(http.MethodPost, controller.SfcMigrateHTTPPrefix()+m.Sfc, m, nil)
+}
+
+// ListConfigVersions returns the headers of the stored config versions, without their entities
+func (c *Client) ListConfigVersions() ([]controller.ConfigVersion, error) {
+	var cvs []controller.ConfigVersion
+	return cvs, c.do(http.MethodGet, controller.ConfigVersionsHTTPPrefix(), nil, &cvs)
+}
+
+// GetConfigVersion returns the stored config version
+func (c *Client) GetConfigVersion(version uint32) (*controller.ConfigVersion, error) {
+	cv := &controller.ConfigVersion{}
+	return cv, c.do(http.MethodGet, fmt.Sprintf("%s%d", controller.ConfigVersionKeyPrefix(), version), nil, cv)
+}
+
+// Rollback re-renders the config of the stored version
+func (c *Client) Rollback(version uint32) (*VersionDiff, error) {
+	diff := &VersionDiff{}
+	return diff, c.do(http.MethodPost, fmt.Sprintf("%s%d", controller.ConfigRollbackHTTPPrefix(), version), nil, diff)
+}
+
+// BulkApply wires all the entities of the batch or none of them, when the batch is not applied the results
+// tell which of its entities failed, along with the error
+func (c *Client) BulkApply(batch *BulkApply) (*BulkApplyResult, error) {
+
+	result := &BulkApplyResult{}
+	err := c.do(http.MethodPost, controller.BulkHTTPPrefix(), batch, result)
+	if apiErr, ok := err.(*APIError); ok && apiErr.StatusCode == http.StatusBadRequest {
+		json.Unmarshal([]byte(apiErr.Message), result)
+	}
+	return result, err
+}
+
+// ReconcileSfcs renders the chains whose labels match the selector again, the empty selector matches every chain
+func (c *Client) ReconcileSfcs(selector string) (*SelectorOperationResult, error) {
+	result := &SelectorOperationResult{}
+	return result, c.do(http.MethodPost, controller.ReconcileHTTPPrefix()+"?"+url.Values{
+		"selector": {selector}}.Encode(), nil, result)
+}
+
+// DrainHosts migrates the containers of the chains on the hosts whose labels match the selector to toHost
+func (c *Client) DrainHosts(selector string, toHost string) (*SelectorOperationResult, error) {
+	result := &SelectorOperationResult{}
+	return result, c.do(http.MethodPost, controller.DrainHTTPPrefix()+"?"+url.Values{
+		"selector": {selector}, "to_host": {toHost}}.Encode(), nil, result)
+}
+
+// ScheduleChange validates the change's entities against the running config and schedules them into the
+// change's window, when they are not valid the results tell which of them failed, along with the error
+func (c *Client) ScheduleChange(sc *controller.ScheduledChange) (*BulkApplyResult, error) {
+
+	err := c.do(http.MethodPost, controller.ScheduledChangeKey(sc.Name), sc, sc)
+	if apiErr, ok := err.(*APIError); ok && apiErr.StatusCode == http.StatusBadRequest {
+		result := &BulkApplyResult{}
+		if json.Unmarshal([]byte(apiErr.Message), result) == nil && result.Results != nil {
+			return result, err
+		}
+	}
+	return nil, err
+}
+
+// GetScheduledChange returns the scheduled change with its state, IsNotFound(err) if there is none
+func (c *Client) GetScheduledChange(name string) (*controller.ScheduledChange, error) {
+	sc := &controller.ScheduledChange{}
+	return sc, c.do(http.MethodGet, controller.ScheduledChangeKey(name), nil, sc)
+}
+
+// ListScheduledChanges returns the scheduled changes, the applied, failed and expired ones included
+func (c *Client) ListScheduledChanges() ([]*controller.ScheduledChange, error) {
+	var scs []*controller.ScheduledChange
+	return scs, c.do(http.MethodGet, controller.ScheduledChangeKeyPrefix(), nil, &scs)
+}
+
+// DeleteScheduledChange cancels the change if its window has not started, else it removes its record
+func (c *Client) DeleteScheduledChange(name string) error {
+	return c.do(http.MethodDelete, controller.ScheduledChangeKey(name), nil, nil)
+}
+
+// Backup writes the archive of the controller's tree in ETCD to w
+func (c *Client) Backup(w io.Writer) error {
+
+	resp, err := c.send(http.MethodGet, controller.BackupHTTPPrefix(), nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Restore replaces the controller's tree in ETCD with the archive read from r, and re-renders the config,
+// the number of keys restored is returned
+func (c *Client) Restore(r io.Reader) (int, error) {
+
+	resp, err := c.send(http.MethodPost, controller.RestoreHTTPPrefix(), r)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var restored struct{ Restored int }
+	err = json.NewDecoder(resp.Body).Decode(&restored)
+	return restored.Restored, err
+}
+
+// VerifyConsistency cross checks the controller's caches, id records and the keys of the agents
+func (c *Client) VerifyConsistency() (*ConsistencyReport, error) {
+	report := &ConsistencyReport{}
+	return report, c.do(http.MethodGet, controller.VerifyHTTPPrefix(), nil, report)
+}
+
+// GetEntityKeys returns the vpp-agent keys rendered for the entity
+func (c *Client) GetEntityKeys(kind string, name string) (*controller.EntityKeys, error) {
+	entityKeys := &controller.EntityKeys{}
+	return entityKeys, c.do(http.MethodGet, controller.EntityKeysKey(kind, name), nil, entityKeys)
+}
+
+// GetEntityHistory returns the changes of the entity, oldest first
+func (c *Client) GetEntityHistory(kind string, name string) ([]*controller.EntityChange, error) {
+	var changes []*controller.EntityChange
+	return changes, c.do(http.MethodGet, controller.HistoryKeyPrefix()+kind+"/"+name, nil, &changes)
+}
+
+// GetHistory returns the changes of every entity made since the unix time, in time order
+func (c *Client) GetHistory(since int64) ([]*controller.EntityChange, error) {
+	var changes []*controller.EntityChange
+	return changes, c.do(http.MethodGet, fmt.Sprintf("%s?since=%d", controller.HistoryKeyPrefix(), since), nil,
+		&changes)
+}
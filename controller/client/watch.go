@@ -0,0 +1,153 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package client
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+var errNoDatastore = errors.New("sfc controller client: no datastore, see WithDatastore")
+
+// StatusEvent is a change of an entity's render status
+type StatusEvent struct {
+	Kind    string // EE, HE or SFC
+	Name    string
+	Deleted bool
+	Status  *controller.EntityStatus // nil when deleted
+}
+
+// ChangeEvent is a change made to an entity, as recorded in its history
+type ChangeEvent struct {
+	Kind   string // EE, HE, SFC or SP
+	Name   string
+	Change *controller.EntityChange
+}
+
+// GetEntityStatus returns the render status of the entity, nil if it was not rendered
+func (c *Client) GetEntityStatus(kind string, name string) (*controller.EntityStatus, error) {
+
+	if c.db == nil {
+		return nil, errNoDatastore
+	}
+	status := &controller.EntityStatus{}
+	found, _, err := c.db.GetValue(controller.EntityStatusKey(kind, name), status)
+	if err != nil || !found {
+		return nil, err
+	}
+	return status, nil
+}
+
+// ListEntityStatuses returns the render statuses of the entities of the kind, of every kind if it is empty
+func (c *Client) ListEntityStatuses(kind string) ([]StatusEvent, error) {
+
+	if c.db == nil {
+		return nil, errNoDatastore
+	}
+	prefix := controller.StatusKeyPrefix()
+	if kind != "" {
+		prefix += kind + "/"
+	}
+	kvi, err := c.db.ListValues(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []StatusEvent
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return statuses, nil
+		}
+		status := &controller.EntityStatus{}
+		if err := kv.GetValue(status); err != nil {
+			return nil, err
+		}
+		kind, name := entityOfKey(controller.StatusKeyPrefix(), kv.GetKey())
+		statuses = append(statuses, StatusEvent{Kind: kind, Name: name, Status: status})
+	}
+}
+
+// WatchEntityStatuses sends the status changes of every entity on the channel returned until done is closed
+func (c *Client) WatchEntityStatuses(done <-chan struct{}) (<-chan StatusEvent, error) {
+
+	events := make(chan StatusEvent)
+	err := c.watch(controller.StatusKeyPrefix(), done, func(resp keyval.ProtoWatchResp) {
+		kind, name := entityOfKey(controller.StatusKeyPrefix(), resp.GetKey())
+		event := StatusEvent{Kind: kind, Name: name, Deleted: resp.GetChangeType() == datasync.Delete}
+		if !event.Deleted {
+			event.Status = &controller.EntityStatus{}
+			if err := resp.GetValue(event.Status); err != nil {
+				return
+			}
+		}
+		select {
+		case events <- event:
+		case <-done:
+		}
+	})
+	return events, err
+}
+
+// WatchEntityChanges sends the changes made to every entity on the channel returned until done is closed
+func (c *Client) WatchEntityChanges(done <-chan struct{}) (<-chan ChangeEvent, error) {
+
+	events := make(chan ChangeEvent)
+	err := c.watch(controller.HistoryKeyPrefix(), done, func(resp keyval.ProtoWatchResp) {
+		if resp.GetChangeType() == datasync.Delete {
+			return // the history is pruned
+		}
+		change := &controller.EntityChange{}
+		if err := resp.GetValue(change); err != nil {
+			return
+		}
+		select {
+		case events <- ChangeEvent{Kind: change.Kind, Name: change.Name, Change: change}:
+		case <-done:
+		}
+	})
+	return events, err
+}
+
+// watch calls handle with each change under the prefix until done is closed
+func (c *Client) watch(prefix string, done <-chan struct{}, handle func(resp keyval.ProtoWatchResp)) error {
+
+	if c.watcher == nil {
+		return errNoDatastore
+	}
+	closeCh := make(chan string)
+	if err := c.watcher.Watch(handle, closeCh, prefix); err != nil {
+		return err
+	}
+	go func() {
+		<-done
+		close(closeCh)
+	}()
+	return nil
+}
+
+// entityOfKey returns the kind and the name of the entity of a key in the tree of the prefix
+func entityOfKey(prefix string, key string) (string, string) {
+
+	kindName := strings.SplitN(strings.TrimPrefix(key, prefix), "/", 2)
+	if len(kindName) != 2 {
+		return kindName[0], ""
+	}
+	return kindName[0], kindName[1]
+}
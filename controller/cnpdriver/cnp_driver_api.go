@@ -25,17 +25,18 @@ package cnpdriver
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/ligato/cn-infra/db/keyval"
-	"github.com/ligato/cn-infra/logging/logrus"
 	"github.com/ligato/sfc-controller/controller/cnpdriver/l2driver"
 	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
 )
 
 var (
 	cnpDriverRegistered = false
 	cnpDriverName       string
-	log                 = logrus.DefaultLogger()
+	log                 = logs.Logger(logs.Driver)
 )
 
 // SfcControllerCNPDriverAPI is interface that is implemented by each Container Networking Policy (CNP) Driver.  As n/b api's
@@ -50,6 +51,9 @@ type SfcControllerCNPDriverAPI interface {
 	GetName() string
 	ReconcileStart(vppEtcdLabels map[string]struct{}) error
 	ReconcileEnd() error
+	ReconcileEndBlueGreen(acceptTimeout time.Duration) error
+	ReconcileEndCanary(canaryLabel string, verifyTimeout time.Duration) error
+	ReconcileEndForLabel(vppLabel string) error
 	DatastoreReInitialize() error
 	WireHostEntityToDestinationHostEntity(sh *controller.HostEntity, dh *controller.HostEntity) error
 	WireHostEntityToExternalEntity(he *controller.HostEntity, ee *controller.ExternalEntity) error
@@ -57,7 +61,21 @@ type SfcControllerCNPDriverAPI interface {
 	WireInternalsForExternalEntity(ee *controller.ExternalEntity) error
 	WireSfcEntity(sfc *controller.SfcEntity) error
 	SetSystemParameters(sp *controller.SystemParameters) error
-	GetSfcInterfaceIPAndMac(container string, port string) (string, string, error)
+	SetConfigVersion(version uint32)
+	GetSfcInterfaceIPAndMac(container string, port string) (string, string, string, error)
+	GetRenderedKeyCount() uint32
+	GetRenderedKeys(count uint32) []string
+	ResetRenderedKeys()
+	WaitForAgents(timeout time.Duration) error
+	EvictEntity(kind string, name string)
+	GetCacheSizes() map[string]int
+	GetExhaustedIDSpaces() []string
+	VerifyConsistency(checkLabel func(vppLabel string) bool) ([]l2driver.Inconsistency, error)
+	OwnsAgentKey(key string) bool
+	DeleteOwnedAgentKeys(selected func(key string) bool) (int, error)
+	GetState(entityName string) (*l2driver.EntityState, error)
+	GetAllocatedIDs(heName string, sfcName string) (*l2driver.AllocatedIDs, error)
+	ForensicLookup(query *l2driver.ForensicQuery) ([]*l2driver.ForensicMatch, error)
 	Dump()
 }
 
@@ -74,14 +92,13 @@ func RegisterCNPDriverPlugin(name string, dbFactory func(string) keyval.ProtoBro
 		//return nil, errors.New(errMsg)
 	}
 
-	switch name {
-	case "sfcctlrl2":
-		cnpDriverAPI = l2driver.NewSfcCtlrL2CNPDriver(name, dbFactory)
-	default:
+	registration, exists := cnpDrivers[name]
+	if !exists {
 		errMsg := fmt.Sprintf("RegisterCNPDriverPlugin: CNPDriver '%s' not recognized", name)
 		log.Error(errMsg)
 		return nil, errors.New(errMsg)
 	}
+	cnpDriverAPI = registration.factory(name, dbFactory)
 
 	cnpDriverName = name
 	cnpDriverRegistered = true
@@ -0,0 +1,245 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The registry of the cnp drivers is implemented in this file.  Each driver
+// registers its factory with the capabilities it has, and the controller
+// validates the entities against the capabilities of the driver that renders
+// them before anything is rendered, instead of finding out from a failed
+// render half way through a chain.  A chain is rendered by the driver it
+// names in its cnp_driver if the registered driver can render chains of that
+// driver next to its own, else by the registered driver.
+
+package cnpdriver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/cnpdriver/l2driver"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// Capabilities are what a cnp driver can render
+type Capabilities struct {
+	// ipv6 addresses, routes and neighbors of the hosts and elements
+	IPv6 bool `json:"ipv6"`
+	// east-west chains with elements on more than one host
+	MultiHostEastWest bool `json:"multi_host_east_west"`
+	// the wiring of a removed chain is removed from the hosts
+	Delete bool `json:"delete"`
+	// the chain types it wires, all of them if empty
+	SfcTypes []controller.SfcType `json:"sfc_types,omitempty"`
+	// the drivers whose chains it also renders when a chain names one
+	SfcDrivers []string `json:"sfc_drivers"`
+}
+
+// CNPDriverFactory creates an instance of a cnp driver
+type CNPDriverFactory func(name string, dbFactory func(string) keyval.ProtoBroker) SfcControllerCNPDriverAPI
+
+type cnpDriverRegistration struct {
+	factory      CNPDriverFactory
+	capabilities Capabilities
+}
+
+var cnpDrivers = make(map[string]*cnpDriverRegistration)
+
+func init() {
+	l2Family := []string{l2driver.L2DriverName, l2driver.XConnectDriverName, l2driver.SteeringDriverName}
+	pairTypes := []controller.SfcType{controller.SfcType_SFC_EW_L2XCONN, controller.SfcType_SFC_EW_MEMIF,
+		controller.SfcType_SFC_NS_NIC_L2XCONN}
+
+	RegisterCNPDriver(l2driver.L2DriverName, Capabilities{
+		IPv6:              true,
+		MultiHostEastWest: true,
+		Delete:            true,
+		SfcDrivers:        l2Family,
+	}, func(name string, dbFactory func(string) keyval.ProtoBroker) SfcControllerCNPDriverAPI {
+		return l2driver.NewSfcCtlrL2CNPDriver(name, dbFactory)
+	})
+	// the hosts of the xconnect and steering drivers get no bridges, so they cannot render bridged chains
+	RegisterCNPDriver(l2driver.XConnectDriverName, Capabilities{
+		IPv6:              true,
+		MultiHostEastWest: true,
+		Delete:            true,
+		SfcTypes:          pairTypes,
+		SfcDrivers:        l2Family[1:],
+	}, func(name string, dbFactory func(string) keyval.ProtoBroker) SfcControllerCNPDriverAPI {
+		return l2driver.NewSfcCtlrXConnCNPDriver(name, dbFactory)
+	})
+	// the steered hops are classified xconnects of containers, and the acls are not reconciled away
+	RegisterCNPDriver(l2driver.SteeringDriverName, Capabilities{
+		IPv6:              true,
+		MultiHostEastWest: true,
+		SfcTypes:          []controller.SfcType{controller.SfcType_SFC_EW_L2XCONN, controller.SfcType_SFC_EW_MEMIF},
+		SfcDrivers:        l2Family[1:],
+	}, func(name string, dbFactory func(string) keyval.ProtoBroker) SfcControllerCNPDriverAPI {
+		return l2driver.NewSfcCtlrSteeringCNPDriver(name, dbFactory)
+	})
+}
+
+// RegisterCNPDriver adds a driver to the registry, it can then be selected with -cnp-driver
+func RegisterCNPDriver(name string, capabilities Capabilities, factory CNPDriverFactory) error {
+
+	if name == "" || factory == nil {
+		err := fmt.Errorf("RegisterCNPDriver: missing name or factory for driver: '%s'", name)
+		log.Error(err.Error())
+		return err
+	}
+	if _, exists := cnpDrivers[name]; exists {
+		err := fmt.Errorf("RegisterCNPDriver: driver '%s' is already registered", name)
+		log.Error(err.Error())
+		return err
+	}
+	cnpDrivers[name] = &cnpDriverRegistration{
+		factory:      factory,
+		capabilities: capabilities,
+	}
+	return nil
+}
+
+// CNPDriverNames returns the names of the registered drivers, sorted
+func CNPDriverNames() []string {
+	names := make([]string, 0, len(cnpDrivers))
+	for name := range cnpDrivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupCNPDriverCapabilities returns the capabilities of the named driver
+func LookupCNPDriverCapabilities(name string) (Capabilities, error) {
+	registration, exists := cnpDrivers[name]
+	if !exists {
+		return Capabilities{}, fmt.Errorf("cnp driver '%s' not recognized, the drivers are: %s", name,
+			strings.Join(CNPDriverNames(), ", "))
+	}
+	return registration.capabilities, nil
+}
+
+// SfcEntityDriver returns the driver that renders the chain next to the registered driver
+func SfcEntityDriver(registered string, sfc *controller.SfcEntity) (string, error) {
+
+	if sfc.CnpDriver == "" || sfc.CnpDriver == registered {
+		return registered, nil
+	}
+	if _, exists := cnpDrivers[sfc.CnpDriver]; !exists {
+		return "", fmt.Errorf("Invalid cnp_driver: '%s' for sfc: '%s', the drivers are: %s", sfc.CnpDriver,
+			sfc.Name, strings.Join(CNPDriverNames(), ", "))
+	}
+	capabilities, err := LookupCNPDriverCapabilities(registered)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range capabilities.SfcDrivers {
+		if name == sfc.CnpDriver {
+			return sfc.CnpDriver, nil
+		}
+	}
+	return "", fmt.Errorf("Invalid cnp_driver: '%s' for sfc: '%s', the %s driver cannot render its chains",
+		sfc.CnpDriver, sfc.Name, registered)
+}
+
+// ValidateSfcEntity checks the driver that renders the chain has the capabilities the chain needs
+func ValidateSfcEntity(registered string, sfc *controller.SfcEntity) error {
+
+	driver, err := SfcEntityDriver(registered, sfc)
+	if err != nil {
+		return err
+	}
+	capabilities, err := LookupCNPDriverCapabilities(driver)
+	if err != nil {
+		return err
+	}
+
+	if len(capabilities.SfcTypes) != 0 {
+		supported := false
+		for _, sfcType := range capabilities.SfcTypes {
+			supported = supported || sfcType == sfc.Type
+		}
+		if !supported {
+			return fmt.Errorf("Invalid type: '%s' for sfc: '%s', the %s driver does not wire it", sfc.Type,
+				sfc.Name, driver)
+		}
+	}
+
+	if !capabilities.IPv6 {
+		for _, sfcElement := range sfc.GetElements() {
+			if sfcElement.Ipv6Addr != "" || len(sfcElement.GetL3VrfIpv6Routes()) != 0 ||
+				len(sfcElement.GetL3Ipv6Neighbors()) != 0 {
+				return fmt.Errorf("Invalid ipv6 for element: '%s/%s', sfc: '%s', the %s driver has no ipv6",
+					sfcElement.Container, sfcElement.PortLabel, sfc.Name, driver)
+			}
+		}
+	}
+
+	if !capabilities.MultiHostEastWest && strings.HasPrefix(sfc.Type.String(), "SFC_EW_") {
+		hosts := make(map[string]struct{})
+		for _, sfcElement := range sfc.GetElements() {
+			hosts[sfcElement.EtcdVppSwitchKey] = struct{}{}
+		}
+		if len(hosts) > 1 {
+			return fmt.Errorf("Invalid elements for sfc: '%s', they are on %d hosts and the %s driver "+
+				"wires east-west chains on one host only", sfc.Name, len(hosts), driver)
+		}
+	}
+
+	return nil
+}
+
+// ValidateHostEntity checks the registered driver has the capabilities the host needs
+func ValidateHostEntity(registered string, he *controller.HostEntity) error {
+
+	capabilities, err := LookupCNPDriverCapabilities(registered)
+	if err != nil {
+		return err
+	}
+	if capabilities.IPv6 {
+		return nil
+	}
+
+	addrs := []string{he.EthIpv6, he.LoopbackIpv6}
+	for _, uplink := range he.GetUplinks() {
+		addrs = append(addrs, uplink.EthIpv6)
+	}
+	for _, loopback := range he.GetLoopbacks() {
+		addrs = append(addrs, loopback.Ipv6)
+	}
+	for _, addr := range addrs {
+		if addr != "" {
+			return fmt.Errorf("Invalid ipv6: '%s' for he: '%s', the %s driver has no ipv6", addr, he.Name,
+				registered)
+		}
+	}
+	return nil
+}
+
+// ValidateSfcEntityDelete checks the driver that renders the chain can remove its wiring
+func ValidateSfcEntityDelete(registered string, sfc *controller.SfcEntity) error {
+
+	driver, err := SfcEntityDriver(registered, sfc)
+	if err != nil {
+		return err
+	}
+	capabilities, err := LookupCNPDriverCapabilities(driver)
+	if err != nil {
+		return err
+	}
+	if !capabilities.Delete {
+		return fmt.Errorf("Invalid delete of sfc: '%s', the %s driver cannot remove its wiring", sfc.Name, driver)
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The vpp-agent model adapters are implemented in this file.  The wiring logic
+// and the reconcile caches only deal with the vpp-agent defaultplugins models,
+// an adapter decides under which key, and in which form, each object is written
+// for the agents that use it.  The adapter is chosen per vpp label: the host's
+// agent_api, else the system agent_api, else defaultplugins.
+
+package l2driver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/utils/addrs"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
+	linuxIntf "github.com/ligato/vpp-agent/plugins/linuxplugin/ifplugin/model/interfaces"
+	linuxL3 "github.com/ligato/vpp-agent/plugins/linuxplugin/l3plugin/model/l3"
+)
+
+// DefaultPluginsAgentAPI is the name of the adapter for agents using the defaultplugins models
+const DefaultPluginsAgentAPI = "defaultplugins"
+
+// AgentAdapter renders the driver's objects, built with the defaultplugins models, for one vpp-agent API
+type AgentAdapter interface {
+	// GetName returns the agent_api name hosts use to select the adapter
+	GetName() string
+	// Key returns the ETCD key of obj for the agent with the vpp label
+	Key(vppLabel string, obj proto.Message) (string, error)
+	// KeyPrefix returns the ETCD prefix under which the agent stores objects of the same type as obj
+	KeyPrefix(vppLabel string, obj proto.Message) (string, error)
+	// Encode converts obj into the value the agent expects under its key
+	Encode(obj proto.Message) (proto.Message, error)
+	// Decode converts a value loaded from the agent's tree back into obj
+	Decode(kv keyval.ProtoKeyVal, obj proto.Message) error
+}
+
+var agentAdapters = map[string]AgentAdapter{
+	DefaultPluginsAgentAPI: &defaultPluginsAgentAdapter{},
+}
+
+// RegisterAgentAdapter makes an adapter selectable via the agent_api of the system parameters and hosts
+func RegisterAgentAdapter(adapter AgentAdapter) error {
+	if _, exists := agentAdapters[adapter.GetName()]; exists {
+		return fmt.Errorf("RegisterAgentAdapter: agent api '%s' is already registered", adapter.GetName())
+	}
+	agentAdapters[adapter.GetName()] = adapter
+	return nil
+}
+
+// LookupAgentAdapter returns the adapter registered for the agent_api, "" is defaultplugins
+func LookupAgentAdapter(agentAPI string) (AgentAdapter, error) {
+	if agentAPI == "" {
+		agentAPI = DefaultPluginsAgentAPI
+	}
+	adapter, exists := agentAdapters[agentAPI]
+	if !exists {
+		return nil, fmt.Errorf("unknown agent api: '%s'", agentAPI)
+	}
+	return adapter, nil
+}
+
+// agentAdapterFor picks the adapter of the vpp label, vnf containers use the system wide one
+func (cnpd *sfcCtlrL2CNPDriver) agentAdapterFor(vppLabel string) AgentAdapter {
+
+	agentAPI := cnpd.l2CNPEntityCache.SysParms.AgentApi
+	if he, exists := cnpd.l2CNPEntityCache.HEs[vppLabel]; exists && he.AgentApi != "" {
+		agentAPI = he.AgentApi
+	}
+	adapter, err := LookupAgentAdapter(agentAPI)
+	if err != nil {
+		// agent_api is validated with the rest of the config so this is not expected
+		log.Errorf("agentAdapterFor: vpp label: '%s': %s, using %s", vppLabel, err, DefaultPluginsAgentAPI)
+		return agentAdapters[DefaultPluginsAgentAPI]
+	}
+	return adapter
+}
+
+// agentKey returns the key of obj for the vpp label's agent
+func (cnpd *sfcCtlrL2CNPDriver) agentKey(vppLabel string, obj proto.Message) string {
+
+	key, err := cnpd.agentAdapterFor(vppLabel).Key(vppLabel, obj)
+	if err != nil {
+		log.Error("agentKey: ", err)
+	}
+	return key
+}
+
+// agentValue converts obj for the agent owning the key, stamped for the audit, see audit.go, the controller's own
+// keys are left as is
+func (cnpd *sfcCtlrL2CNPDriver) agentValue(key string, obj proto.Message) (proto.Message, error) {
+	if !strings.HasPrefix(key, utils.GetVppAgentPrefix()) {
+		return obj, nil
+	}
+	return cnpd.agentAdapterFor(utils.GetVppEtcdlabel(key)).Encode(cnpd.auditStamp(key, obj))
+}
+
+// agentPutKey writes obj under a key that was built by agentKey
+func (cnpd *sfcCtlrL2CNPDriver) agentPutKey(key string, obj proto.Message) error {
+
+	value, err := cnpd.agentValue(key, obj)
+	if err != nil {
+		log.Error("agentPutKey: ", key, err)
+		return err
+	}
+	if err := cnpd.agentKeyOwn(key); err != nil {
+		return err
+	}
+	return cnpd.db.Put(key, value)
+}
+
+// agentDeleteKey removes a key the driver wrote, and the record that it owns it
+func (cnpd *sfcCtlrL2CNPDriver) agentDeleteKey(key string) error {
+
+	defer cnpd.agentLocks.Lock(utils.GetVppEtcdlabel(key))()
+	if _, err := cnpd.db.Delete(key); err != nil {
+		log.Errorf("agentDeleteKey: error removing key: '%s': %s", key, err)
+		return err
+	}
+	cnpd.agentKeyDisown(key)
+	return nil
+}
+
+// agentCommit writes the puts and deletes in a single ETCD transaction, the caller holds the locks of their agents
+func (cnpd *sfcCtlrL2CNPDriver) agentCommit(puts []string, msgs map[string]proto.Message, deletes []string) error {
+
+	txn := cnpd.db.NewTxn()
+	for _, key := range puts {
+		log.Debugf("agentCommit: put key: '%s'", key)
+		value, err := cnpd.agentValue(key, msgs[key])
+		if err != nil {
+			log.Error("agentCommit: ", key, err)
+			return err
+		}
+		if err := cnpd.agentKeyOwn(key); err != nil {
+			return err
+		}
+		txn.Put(key, value)
+	}
+	for _, key := range deletes {
+		log.Debugf("agentCommit: delete key: '%s'", key)
+		txn.Delete(key)
+	}
+	if err := txn.Commit(); err != nil {
+		log.Error("agentCommit: databroker txn commit: ", err)
+		return err
+	}
+	for _, key := range deletes {
+		cnpd.agentKeyDisown(key)
+	}
+
+	return nil
+}
+
+// agentPut writes obj to the vpp label's agent
+func (cnpd *sfcCtlrL2CNPDriver) agentPut(vppLabel string, obj proto.Message) error {
+
+	adapter := cnpd.agentAdapterFor(vppLabel)
+
+	key, err := adapter.Key(vppLabel, obj)
+	if err != nil {
+		log.Error("agentPut: ", err)
+		return err
+	}
+	value, err := adapter.Encode(cnpd.auditStamp(key, obj))
+	if err != nil {
+		log.Error("agentPut: ", key, err)
+		return err
+	}
+
+	log.Debugf("agentPut: key: '%s'", key)
+
+	cnpd.renderedKeys = append(cnpd.renderedKeys, key)
+	cnpd.agentConfirmTrack(vppLabel, obj)
+
+	if err := cnpd.agentKeyOwn(key); err != nil {
+		return err
+	}
+	defer cnpd.agentLocks.Lock(vppLabel)()
+	return cnpd.db.Put(key, value)
+}
+
+// agentLoad lists every object of the same type as obj in the vpp label's tree, in the layout of each
+// registered adapter, so reconcile also finds the entries written before a host switched agent_api, the
+// objects the driver did not write are skipped, see ownership.go
+func (cnpd *sfcCtlrL2CNPDriver) agentLoad(vppLabel string, obj proto.Message,
+	actionFunc func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter)) error {
+
+	return cnpd.agentList(vppLabel, obj, func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter) {
+		if !cnpd.agentKeyOwned(key) {
+			log.Infof("agentLoad: key: '%s' was not written by the controller, it is left alone", key)
+			return
+		}
+		actionFunc(key, kv, adapter)
+	})
+}
+
+// agentList is agentLoad with the objects the driver did not write
+func (cnpd *sfcCtlrL2CNPDriver) agentList(vppLabel string, obj proto.Message,
+	actionFunc func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter)) error {
+
+	prefixes := make(map[string]struct{})
+	for _, adapter := range agentAdapters {
+		prefix, err := adapter.KeyPrefix(vppLabel, obj)
+		if err != nil {
+			continue
+		}
+		if _, exists := prefixes[prefix]; exists {
+			continue
+		}
+		prefixes[prefix] = struct{}{}
+
+		kvi, err := cnpd.db.ListValues(prefix)
+		if err != nil {
+			return err
+		}
+		for {
+			kv, allReceived := kvi.GetNext()
+			if allReceived {
+				break
+			}
+			actionFunc(kv.GetKey(), kv, adapter)
+		}
+	}
+
+	return nil
+}
+
+// defaultPluginsAgentAdapter writes the defaultplugins models as is, in the vpp-agent's key layout
+type defaultPluginsAgentAdapter struct{}
+
+func (a *defaultPluginsAgentAdapter) GetName() string {
+	return DefaultPluginsAgentAPI
+}
+
+func (a *defaultPluginsAgentAdapter) Key(vppLabel string, obj proto.Message) (string, error) {
+
+	switch o := obj.(type) {
+	case *interfaces.Interfaces_Interface:
+		return utils.InterfaceKey(vppLabel, o.Name), nil
+	case *linuxIntf.LinuxInterfaces_Interface:
+		return utils.LinuxInterfaceKey(vppLabel, o.Name), nil
+	case *l2.BridgeDomains_BridgeDomain:
+		return utils.L2BridgeDomainKey(vppLabel, o.Name), nil
+	case *l2.XConnectPairs_XConnectPair:
+		return utils.L2XConnectKey(vppLabel, o.ReceiveInterface), nil
+	case *l2.FibTableEntries_FibTableEntry:
+		return utils.L2FibKey(vppLabel, o.BridgeDomain, o.PhysAddress), nil
+	case *l3.StaticRoutes_Route:
+		destIPAddr, _, err := addrs.ParseIPWithPrefix(o.DstIpAddr)
+		if err != nil {
+			return "", err
+		}
+		return utils.L3RouteKey(vppLabel, o.VrfId, destIPAddr, o.NextHopAddr), nil
+	case *l3.ArpTable_ArpTableEntry:
+		return utils.ArpEntryKey(vppLabel, o.Interface, o.IpAddress), nil
+	case *linuxL3.LinuxStaticRoutes_Route:
+		return utils.LinuxStaticRouteKey(vppLabel, o.Name), nil
+	case *linuxL3.LinuxStaticArpEntries_ArpEntry:
+		return utils.LinuxStaticArpKey(vppLabel, o.Name), nil
+	case *acl.AccessLists_Acl:
+		return utils.AclKey(vppLabel, o.AclName), nil
+	}
+
+	return "", fmt.Errorf("%s: no key for type: %T", DefaultPluginsAgentAPI, obj)
+}
+
+func (a *defaultPluginsAgentAdapter) KeyPrefix(vppLabel string, obj proto.Message) (string, error) {
+
+	switch obj.(type) {
+	case *interfaces.Interfaces_Interface:
+		return utils.InterfacePrefixKey(vppLabel), nil
+	case *linuxIntf.LinuxInterfaces_Interface:
+		return utils.LinuxInterfacePrefixKey(vppLabel), nil
+	case *l2.BridgeDomains_BridgeDomain:
+		return utils.L2BridgeDomainKeyPrefix(vppLabel), nil
+	case *l2.XConnectPairs_XConnectPair:
+		return utils.L2XConnectKeyPrefix(vppLabel), nil
+	case *l2.FibTableEntries_FibTableEntry:
+		// the entries are kept under their bridge domains
+		return utils.L2BridgeDomainKeyPrefix(vppLabel), nil
+	case *l3.StaticRoutes_Route:
+		return utils.L3RouteKeyPrefix(vppLabel), nil
+	case *l3.ArpTable_ArpTableEntry:
+		return utils.ArpEntryKeyPrefix(vppLabel), nil
+	}
+
+	return "", fmt.Errorf("%s: no key prefix for type: %T", DefaultPluginsAgentAPI, obj)
+}
+
+func (a *defaultPluginsAgentAdapter) Encode(obj proto.Message) (proto.Message, error) {
+	return obj, nil
+}
+
+func (a *defaultPluginsAgentAdapter) Decode(kv keyval.ProtoKeyVal, obj proto.Message) error {
+	return kv.GetValue(obj)
+}
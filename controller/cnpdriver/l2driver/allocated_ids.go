@@ -0,0 +1,111 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The reporting of the allocated ids is implemented in this file.  The id
+// records in the datastore, ie the loopback macs of the hosts, the vni's of
+// the host to ee and host to host vxlans, and the addresses, macs, memif and
+// veth ids of the chains' elements, are returned as they are stored,
+// optionally only the ones of a host or of a chain.  The host of an element
+// is the vswitch the chain places it on, the records of a chain's elements
+// are reported under the chain's current placement.
+
+package l2driver
+
+import (
+	"github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// AllocatedIDs is what the driver allocated from its id spaces, as recorded in its datastore
+type AllocatedIDs struct {
+	Hosts       []*l2.HEIDs     `json:"hosts"`
+	HostToEEs   []*l2.HE2EEIDs  `json:"host_to_ees"`   // vlan_id is the vni of the vxlan
+	HostToHosts []*l2.HE2HEIDs  `json:"host_to_hosts"` // vlan_id is the vni of the vxlan
+	SfcElements []SfcElementIDs `json:"sfc_elements"`
+}
+
+// SfcElementIDs are the ids of a chain's element with the host it is placed on, if the chain still holds it
+type SfcElementIDs struct {
+	Host string `json:"host,omitempty"`
+	*l2.SFCIDs
+}
+
+// GetAllocatedIDs returns the id records of the host and of the chain, all of them if both are "".  With a
+// chain only its own records, ie its elements, are returned, not the host ones it shares.
+func (cnpd *sfcCtlrL2CNPDriver) GetAllocatedIDs(heName string, sfcName string) (*AllocatedIDs, error) {
+
+	ids := &AllocatedIDs{
+		Hosts:       make([]*l2.HEIDs, 0),
+		HostToEEs:   make([]*l2.HE2EEIDs, 0),
+		HostToHosts: make([]*l2.HE2HEIDs, 0),
+		SfcElements: make([]SfcElementIDs, 0),
+	}
+
+	if sfcName == "" {
+		if err := cnpd.DatastoreHEIDsIterate(func(key string, heIDs *l2.HEIDs) {
+			if heName == "" || heIDs.Name == heName {
+				ids.Hosts = append(ids.Hosts, heIDs)
+			}
+		}); err != nil {
+			return nil, err
+		}
+		if err := cnpd.DatastoreHE2EEIDsIterate(func(key string, he2ee *l2.HE2EEIDs) {
+			if heName == "" || he2ee.HeName == heName {
+				ids.HostToEEs = append(ids.HostToEEs, he2ee)
+			}
+		}); err != nil {
+			return nil, err
+		}
+		if err := cnpd.DatastoreHE2HEIDsIterate(func(key string, he2he *l2.HE2HEIDs) {
+			if heName == "" || he2he.ShName == heName || he2he.DhName == heName {
+				ids.HostToHosts = append(ids.HostToHosts, he2he)
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cnpd.DatastoreSFCIDsIterate(func(key string, sfcIDs *l2.SFCIDs) {
+		if sfcName != "" && sfcIDs.SfcName != sfcName {
+			return
+		}
+		host := cnpd.sfcElementHost(sfcIDs.SfcName, sfcIDs.Container, sfcIDs.Port)
+		if heName == "" || host == heName {
+			ids.SfcElements = append(ids.SfcElements, SfcElementIDs{Host: host, SFCIDs: sfcIDs})
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// sfcElementHost returns the host the chain places the element on, "" if the chain no longer holds it
+func (cnpd *sfcCtlrL2CNPDriver) sfcElementHost(sfcName string, container string, port string) string {
+
+	sfc, exists := cnpd.l2CNPEntityCache.SFCs[sfcName]
+	if !exists {
+		return ""
+	}
+	for _, sfcElement := range sfc.GetElements() {
+		if sfcElement.Container != container || sfcElement.PortLabel != port {
+			continue
+		}
+		if sfcElement.Type == controller.SfcElementType_HOST_ENTITY {
+			return sfcElement.Container
+		}
+		return sfcElement.EtcdVppSwitchKey
+	}
+	return ""
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The audit of the rendered objects is implemented in this file.  With the
+// system audit_descriptions set, the i/f's and static routes written to the
+// agents carry a stamp at the end of their description: the entity they
+// were first rendered for, the config version the controller was at, and
+// the time they were written, ie
+//
+//   sfc=s1 container=c1 port=p1 sfc-controller: entity=SFC/s1 version=4 rendered=2017-11-02T10:00:00Z
+//
+// The stamp is removed from the objects read back before they are compared
+// with the rendered ones, so an object that is rendered the same keeps the
+// stamp of when it was written.  While reconciling, the objects in the
+// agents' trees that the driver does not own, see ownership.go, and does
+// not render are reported: those with a stamp are stale, left behind by a
+// controller that lost the record of them, the others are foreign, ie
+// configured by hand.  Neither is touched, the findings of the last
+// reconcile are part of VerifyConsistency.
+
+package l2driver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
+	linuxIntf "github.com/ligato/vpp-agent/plugins/linuxplugin/ifplugin/model/interfaces"
+)
+
+// auditMarker starts the stamp in a description
+const auditMarker = "sfc-controller:"
+
+type auditStampType struct {
+	entity   string
+	version  uint32
+	rendered string
+}
+
+// SetConfigVersion sets the config version the objects written from now on are stamped with
+func (cnpd *sfcCtlrL2CNPDriver) SetConfigVersion(version uint32) {
+	cnpd.auditVersion = version
+}
+
+// auditDescriptionOf returns the description of the i/f's and routes, nil for the objects that have none
+func auditDescriptionOf(obj proto.Message) *string {
+	switch o := obj.(type) {
+	case *interfaces.Interfaces_Interface:
+		return &o.Description
+	case *linuxIntf.LinuxInterfaces_Interface:
+		return &o.Description
+	case *l3.StaticRoutes_Route:
+		return &o.Description
+	}
+	return nil
+}
+
+// auditRecord remembers the entity being wired as the one the key was rendered for, unless another entity
+// rendered it first, the reconcile caches are written once all entities are wired
+func (cnpd *sfcCtlrL2CNPDriver) auditRecord(key string) {
+	if _, exists := cnpd.auditEntities[key]; !exists && cnpd.auditEntities != nil {
+		cnpd.auditEntities[key] = cnpd.auditEntity
+	}
+}
+
+// auditStamp returns a copy of obj with the stamp in its description, obj itself is cached so it is left as is
+func (cnpd *sfcCtlrL2CNPDriver) auditStamp(key string, obj proto.Message) proto.Message {
+
+	if !cnpd.l2CNPEntityCache.SysParms.AuditDescriptions || auditDescriptionOf(obj) == nil {
+		return obj
+	}
+
+	entity, exists := cnpd.auditEntities[key]
+	if !exists {
+		entity = cnpd.auditEntity
+	}
+	stamp := fmt.Sprintf("%s entity=%s version=%d rendered=%s", auditMarker, entity, cnpd.auditVersion,
+		time.Now().UTC().Format(time.RFC3339))
+
+	stamped := proto.Clone(obj)
+	description := auditDescriptionOf(stamped)
+	*description = strings.TrimSpace(auditStripDescription(*description) + " " + stamp)
+	return stamped
+}
+
+// auditStripDescription returns the description without its stamp
+func auditStripDescription(description string) string {
+	if i := strings.Index(description, auditMarker); i >= 0 {
+		return strings.TrimSpace(description[:i])
+	}
+	return description
+}
+
+// auditStrip removes the stamp from the description of an object read from an agent's tree
+func auditStrip(obj proto.Message) {
+	if description := auditDescriptionOf(obj); description != nil {
+		*description = auditStripDescription(*description)
+	}
+}
+
+// auditParse returns the stamp in the description
+func auditParse(description string) (*auditStampType, bool) {
+
+	i := strings.Index(description, auditMarker)
+	if i < 0 {
+		return nil, false
+	}
+	stamp := &auditStampType{}
+	for _, field := range strings.Fields(description[i+len(auditMarker):]) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "entity":
+			stamp.entity = kv[1]
+		case "version":
+			fmt.Sscanf(kv[1], "%d", &stamp.version)
+		case "rendered":
+			stamp.rendered = kv[1]
+		}
+	}
+	return stamp, true
+}
+
+// reconcileAudit reports the i/f's and routes of the reconciled labels that the driver neither owns nor
+// renders, the caller holds the reconcile mutex and the after cache is not yet written back
+func (cnpd *sfcCtlrL2CNPDriver) reconcileAudit() error {
+
+	cnpd.auditFindings = nil
+	if !cnpd.l2CNPEntityCache.SysParms.AuditDescriptions {
+		return nil
+	}
+
+	var vppLabels []string
+	for vppLabel := range cnpd.reconcileLabels {
+		vppLabels = append(vppLabels, vppLabel)
+	}
+	sort.Strings(vppLabels)
+
+	rendered := func(key string) bool {
+		_, isIf := cnpd.reconcileAfter.ifs[key]
+		_, isLinuxIf := cnpd.reconcileAfter.lifs[key]
+		_, isRoute := cnpd.reconcileAfter.l3Routes[key]
+		return isIf || isLinuxIf || isRoute
+	}
+
+	for _, vppLabel := range vppLabels {
+		for _, obj := range []proto.Message{&interfaces.Interfaces_Interface{},
+			&linuxIntf.LinuxInterfaces_Interface{}, &l3.StaticRoutes_Route{}} {
+
+			obj := obj
+			err := cnpd.agentList(vppLabel, obj, func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter) {
+				if rendered(key) || cnpd.agentKeyOwned(key) {
+					return
+				}
+				entry := proto.Clone(obj)
+				if err := adapter.Decode(kv, entry); err != nil {
+					log.Errorf("reconcileAudit: error decoding key: '%s': %s", key, err)
+					return
+				}
+				finding := Inconsistency{Kind: InconsistencyForeignKey, Key: key,
+					Detail: "not written by the controller"}
+				if stamp, stamped := auditParse(*auditDescriptionOf(entry)); stamped {
+					finding.Kind = InconsistencyStaleKey
+					finding.Detail = fmt.Sprintf("rendered for: '%s' at config version %d, %s, but no longer owned",
+						stamp.entity, stamp.version, stamp.rendered)
+				}
+				reconcileLog.Warnf("reconcileAudit: %s: '%s': %s", finding.Kind, key, finding.Detail)
+				cnpd.auditFindings = append(cnpd.auditFindings, finding)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
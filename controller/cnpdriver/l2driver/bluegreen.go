@@ -0,0 +1,295 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The blue/green cutover of a changed config is implemented in this file.
+
+package l2driver
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+)
+
+const blueGreenPollInterval = 250 * time.Millisecond
+
+type blueGreenCacheType struct {
+	before     map[string]string        // running entries indexed by ETCD key, as comparable strings
+	after      map[string]string        // rendered entries indexed by ETCD key, as comparable strings
+	beforeMsgs map[string]proto.Message // running entries indexed by ETCD key
+	msgs       map[string]proto.Message // rendered entries indexed by ETCD key
+	ifNames    map[string]string        // vpp i/f names of the rendered entries that are vpp i/f's
+	bdNames    map[string]string        // BD names of the rendered entries that are BD's
+}
+
+// ReconcileEndBlueGreen is the blue/green alternative to ReconcileEnd.  Instead of writing the differences
+// between the before and after caches in one pass, they are written in phases so the running (blue) config
+// keeps forwarding until the changed (green) config is in place:
+//  1. the new entries, ie new memifs, bridges, tunnels, are added next to the blue ones
+//  2. the agents are given acceptTimeout to report the new vpp i/f's in their status tree, if they
+//     do not, or report an error for one of them, the green entries are removed and blue is untouched
+//  3. the changed entries, ie xconnects and bridge membership, are swapped in one ETCD transaction
+//  4. the blue entries that are no longer needed are removed in one ETCD transaction
+//
+// Entries are keyed by name, so an entry that keeps its name but changes its config is swapped in 3).
+func (cnpd *sfcCtlrL2CNPDriver) ReconcileEndBlueGreen(acceptTimeout time.Duration) error {
+
+	log.Info("ReconcileEndBlueGreen: begin ...")
+	defer cnpd.reconcileStateSet(false)
+	defer log.Info("ReconcileEndBlueGreen: exit ...")
+
+	bg := cnpd.blueGreenCollect()
+	// the host i/f name registry is reloaded without the names removed below
+	cnpd.hostIfNames = nil
+
+	var green, changed, stale []string
+	for key, afterStr := range bg.after {
+		if beforeStr, exists := bg.before[key]; !exists {
+			green = append(green, key)
+		} else if beforeStr != afterStr {
+			changed = append(changed, key)
+		}
+	}
+	for key := range bg.before {
+		if _, exists := bg.after[key]; !exists {
+			stale = append(stale, key)
+		}
+	}
+	sort.Strings(green)
+	sort.Strings(changed)
+	sort.Strings(stale)
+
+	log.Infof("ReconcileEndBlueGreen: green: %d, changed: %d, stale: %d", len(green), len(changed), len(stale))
+
+	if err := cnpd.blueGreenCommit(green, bg.msgs, nil); err != nil {
+		return err
+	}
+
+	if err := cnpd.blueGreenWaitForAgents(green, bg.ifNames, acceptTimeout); err != nil {
+		log.Errorf("ReconcileEndBlueGreen: green config not accepted, removing it: %s", err)
+		if err := cnpd.blueGreenCommit(nil, nil, green); err != nil {
+			log.Errorf("ReconcileEndBlueGreen: error removing green config: %s", err)
+		}
+		return err
+	}
+
+	if err := cnpd.blueGreenCommit(changed, bg.msgs, nil); err != nil {
+		return err
+	}
+
+	return cnpd.blueGreenCommit(nil, nil, stale)
+}
+
+// blueGreenCollect flattens the typed reconcile caches, the keys of the different types never overlap
+func (cnpd *sfcCtlrL2CNPDriver) blueGreenCollect() *blueGreenCacheType {
+
+	cnpd.reconcileMutex.Lock()
+	defer cnpd.reconcileMutex.Unlock()
+
+	cnpd.reconcileStreamLoad()
+	if err := cnpd.reconcileAudit(); err != nil {
+		log.Errorf("blueGreenCollect: audit: %s", err)
+	}
+
+	bg := &blueGreenCacheType{
+		before:     make(map[string]string),
+		after:      make(map[string]string),
+		beforeMsgs: make(map[string]proto.Message),
+		msgs:       make(map[string]proto.Message),
+		ifNames:    make(map[string]string),
+		bdNames:    make(map[string]string),
+	}
+
+	for key := range cnpd.reconcileBefore.ifs {
+		entry := cnpd.reconcileBefore.ifs[key]
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.ifs {
+		entry := cnpd.reconcileAfter.ifs[key]
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+		bg.ifNames[key] = entry.Name
+	}
+	for key := range cnpd.reconcileBefore.lifs {
+		entry := cnpd.reconcileBefore.lifs[key]
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.lifs {
+		entry := cnpd.reconcileAfter.lifs[key]
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+	}
+	for key := range cnpd.reconcileBefore.bds {
+		entry := cnpd.reconcileBefore.bds[key]
+		cnpd.sortBridgedInterfaces(entry.Interfaces)
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.bds {
+		entry := cnpd.reconcileAfter.bds[key]
+		cnpd.sortBridgedInterfaces(entry.Interfaces)
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+		bg.bdNames[key] = entry.Name
+	}
+	for key := range cnpd.reconcileBefore.l3Routes {
+		entry := cnpd.reconcileBefore.l3Routes[key]
+		bg.before[key] = reconcileStaticRouteString(&entry)
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.l3Routes {
+		entry := cnpd.reconcileAfter.l3Routes[key]
+		bg.after[key] = reconcileStaticRouteString(&entry)
+		bg.msgs[key] = &entry
+	}
+	for key := range cnpd.reconcileBefore.xconns {
+		entry := cnpd.reconcileBefore.xconns[key]
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.xconns {
+		entry := cnpd.reconcileAfter.xconns[key]
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+	}
+	for key := range cnpd.reconcileBefore.l2fibs {
+		entry := cnpd.reconcileBefore.l2fibs[key]
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.l2fibs {
+		entry := cnpd.reconcileAfter.l2fibs[key]
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+	}
+	for key := range cnpd.reconcileBefore.arps {
+		entry := cnpd.reconcileBefore.arps[key]
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.arps {
+		entry := cnpd.reconcileAfter.arps[key]
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+	}
+	for key := range cnpd.reconcileBefore.heIDs {
+		entry := cnpd.reconcileBefore.heIDs[key]
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.heIDs {
+		entry := cnpd.reconcileAfter.heIDs[key]
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+	}
+	for key := range cnpd.reconcileBefore.he2eeIDs {
+		entry := cnpd.reconcileBefore.he2eeIDs[key]
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.he2eeIDs {
+		entry := cnpd.reconcileAfter.he2eeIDs[key]
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+	}
+	for key := range cnpd.reconcileBefore.he2heIDs {
+		entry := cnpd.reconcileBefore.he2heIDs[key]
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.he2heIDs {
+		entry := cnpd.reconcileAfter.he2heIDs[key]
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+	}
+	for key := range cnpd.reconcileBefore.sfcIDs {
+		entry := cnpd.reconcileBefore.sfcIDs[key]
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.sfcIDs {
+		entry := cnpd.reconcileAfter.sfcIDs[key]
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+	}
+	for key := range cnpd.reconcileBefore.hostIfNames {
+		entry := cnpd.reconcileBefore.hostIfNames[key]
+		bg.before[key] = entry.String()
+		bg.beforeMsgs[key] = &entry
+	}
+	for key := range cnpd.reconcileAfter.hostIfNames {
+		entry := cnpd.reconcileAfter.hostIfNames[key]
+		bg.after[key] = entry.String()
+		bg.msgs[key] = &entry
+	}
+
+	return bg
+}
+
+// blueGreenCommit writes the puts and deletes in a single ETCD transaction
+func (cnpd *sfcCtlrL2CNPDriver) blueGreenCommit(puts []string, msgs map[string]proto.Message,
+	deletes []string) error {
+
+	if len(puts) == 0 && len(deletes) == 0 {
+		return nil
+	}
+
+	defer cnpd.lockAgents(puts, deletes)()
+
+	return cnpd.agentCommit(puts, msgs, deletes)
+}
+
+// blueGreenWaitForAgents polls the agents' i/f status and error trees until every green vpp i/f is reported
+func (cnpd *sfcCtlrL2CNPDriver) blueGreenWaitForAgents(keys []string, ifNames map[string]string,
+	timeout time.Duration) error {
+
+	// state key -> error key of each green vpp i/f that has not been reported yet
+	pending := make(map[string]string)
+	for _, key := range keys {
+		if ifName, isIf := ifNames[key]; isIf {
+			vppLabel := utils.GetVppEtcdlabel(key)
+			pending[utils.InterfaceStateKey(vppLabel, ifName)] = utils.InterfaceErrorKey(vppLabel, ifName)
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		for stateKey, errorKey := range pending {
+			ifErrors := &interfaces.InterfaceErrors_Interface{}
+			found, _, err := cnpd.db.GetValue(errorKey, ifErrors)
+			if err == nil && found && len(ifErrors.GetErrorData()) != 0 {
+				return fmt.Errorf("agent rejected i/f: '%s': %s", errorKey,
+					ifErrors.GetErrorData()[0].ErrorMessage)
+			}
+			ifState := &interfaces.InterfacesState_Interface{}
+			found, _, err = cnpd.db.GetValue(stateKey, ifState)
+			if err == nil && found {
+				delete(pending, stateKey)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d i/f's not accepted by the agents within %s", len(pending), timeout)
+		}
+		time.Sleep(blueGreenPollInterval)
+	}
+}
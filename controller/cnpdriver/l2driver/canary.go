@@ -0,0 +1,193 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The canary apply of a reconciled config is implemented in this file.  The
+// changes for one host are written first, and the host's agent is checked for
+// the i/f's and BD's it was given before the other hosts are touched.
+
+package l2driver
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
+)
+
+// ReconcileEndCanary is the canary alternative to ReconcileEnd.  The differences between the before and after
+// caches for the canary vpp label are written first, then the canary's agent must report, within verifyTimeout,
+// every rendered vpp i/f as admin and oper up (admin only for disabled i/f's), and every rendered BD, without
+// errors.  If it does, the differences for the remaining labels are written, if it does not, the canary's
+// entries are put back as they were and the remaining labels are left untouched.
+func (cnpd *sfcCtlrL2CNPDriver) ReconcileEndCanary(canaryLabel string, verifyTimeout time.Duration) error {
+
+	log.Infof("ReconcileEndCanary: begin, canary: '%s' ...", canaryLabel)
+	defer cnpd.reconcileStateSet(false)
+	defer log.Info("ReconcileEndCanary: exit ...")
+
+	bg := cnpd.blueGreenCollect()
+
+	canaryPrefix := utils.GetVppAgentPrefix() + canaryLabel + "/"
+
+	var canaryAdded, canaryChanged, canaryStale, puts, deletes []string
+	for key, afterStr := range bg.after {
+		beforeStr, exists := bg.before[key]
+		if exists && beforeStr == afterStr {
+			continue
+		}
+		if !strings.HasPrefix(key, canaryPrefix) {
+			puts = append(puts, key)
+		} else if exists {
+			canaryChanged = append(canaryChanged, key)
+		} else {
+			canaryAdded = append(canaryAdded, key)
+		}
+	}
+	for key := range bg.before {
+		if _, exists := bg.after[key]; exists {
+			continue
+		}
+		if strings.HasPrefix(key, canaryPrefix) {
+			canaryStale = append(canaryStale, key)
+		} else {
+			deletes = append(deletes, key)
+		}
+	}
+	sort.Strings(canaryAdded)
+	sort.Strings(canaryChanged)
+	sort.Strings(canaryStale)
+	sort.Strings(puts)
+	sort.Strings(deletes)
+
+	log.Infof("ReconcileEndCanary: canary puts: %d, canary deletes: %d, remaining puts: %d, remaining deletes: %d",
+		len(canaryAdded)+len(canaryChanged), len(canaryStale), len(puts), len(deletes))
+
+	canaryPuts := append(append([]string{}, canaryAdded...), canaryChanged...)
+	if err := cnpd.canaryApply(canaryPuts, bg.msgs, canaryStale); err != nil {
+		return err
+	}
+
+	if err := cnpd.canaryVerify(canaryPuts, bg, verifyTimeout); err != nil {
+		log.Errorf("ReconcileEndCanary: canary '%s' failed verification, halting: %s", canaryLabel, err)
+		// put back the canary's running entries, remove the ones it did not have
+		restores := append(append([]string{}, canaryChanged...), canaryStale...)
+		if err := cnpd.canaryApply(restores, bg.beforeMsgs, canaryAdded); err != nil {
+			log.Errorf("ReconcileEndCanary: error restoring canary '%s': %s", canaryLabel, err)
+		}
+		return fmt.Errorf("canary host '%s' failed verification, remaining hosts untouched: %s",
+			canaryLabel, err)
+	}
+
+	log.Infof("ReconcileEndCanary: canary '%s' verified, continuing with remaining hosts", canaryLabel)
+
+	return cnpd.canaryApply(puts, bg.msgs, deletes)
+}
+
+// canaryApply writes the puts then the deletes one key at a time like ReconcileEnd, a canary apply is
+// meant for large changes which may not fit in one ETCD transaction
+func (cnpd *sfcCtlrL2CNPDriver) canaryApply(puts []string, msgs map[string]proto.Message, deletes []string) error {
+
+	defer cnpd.lockAgents(puts, deletes)()
+
+	for _, key := range puts {
+		log.Info("canaryApply: put key: ", key)
+		value, err := cnpd.agentValue(key, msgs[key])
+		if err != nil {
+			log.Error("canaryApply: ", key, err)
+			return err
+		}
+		if err := cnpd.agentKeyOwn(key); err != nil {
+			return err
+		}
+		if err := cnpd.db.Put(key, value); err != nil {
+			log.Error("canaryApply: databroker put: ", key, err)
+			return err
+		}
+	}
+	for _, key := range deletes {
+		log.Info("canaryApply: delete key: ", key)
+		if _, err := cnpd.db.Delete(key); err != nil {
+			log.Error("canaryApply: databroker delete: ", key, err)
+			return err
+		}
+		cnpd.agentKeyDisown(key)
+	}
+
+	return nil
+}
+
+// canaryVerify polls the canary's status and error trees until every vpp i/f and BD that was put is
+// reported as expected
+func (cnpd *sfcCtlrL2CNPDriver) canaryVerify(keys []string, bg *blueGreenCacheType,
+	timeout time.Duration) error {
+
+	pendingIfs := make(map[string]*interfaces.Interfaces_Interface) // i/f state key -> rendered i/f
+	pendingBDs := make(map[string]string)                           // BD state key -> BD error key
+	for _, key := range keys {
+		vppLabel := utils.GetVppEtcdlabel(key)
+		if ifName, isIf := bg.ifNames[key]; isIf {
+			pendingIfs[utils.InterfaceStateKey(vppLabel, ifName)] = bg.msgs[key].(*interfaces.Interfaces_Interface)
+		} else if bdName, isBD := bg.bdNames[key]; isBD {
+			pendingBDs[utils.L2BridgeDomainStateKey(vppLabel, bdName)] = utils.L2BridgeDomainErrorKey(vppLabel, bdName)
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		for stateKey, iface := range pendingIfs {
+			errorKey := utils.InterfaceErrorKey(utils.GetVppEtcdlabel(stateKey), iface.Name)
+			ifErrors := &interfaces.InterfaceErrors_Interface{}
+			found, _, err := cnpd.db.GetValue(errorKey, ifErrors)
+			if err == nil && found && len(ifErrors.GetErrorData()) != 0 {
+				return fmt.Errorf("agent rejected i/f: '%s': %s", iface.Name,
+					ifErrors.GetErrorData()[0].ErrorMessage)
+			}
+			ifState := &interfaces.InterfacesState_Interface{}
+			found, _, err = cnpd.db.GetValue(stateKey, ifState)
+			if err != nil || !found {
+				continue
+			}
+			if !iface.Enabled || (ifState.AdminStatus == interfaces.InterfacesState_Interface_UP &&
+				ifState.OperStatus == interfaces.InterfacesState_Interface_UP) {
+				delete(pendingIfs, stateKey)
+			}
+		}
+		for stateKey, errorKey := range pendingBDs {
+			bdErrors := &l2.BridgeDomainErrors_BridgeDomain{}
+			found, _, err := cnpd.db.GetValue(errorKey, bdErrors)
+			if err == nil && found && len(bdErrors.GetErrorData()) != 0 {
+				return fmt.Errorf("agent rejected BD: '%s': %s", bdErrors.BdName,
+					bdErrors.GetErrorData()[0].ErrorMessage)
+			}
+			bdState := &l2.BridgeDomainState_BridgeDomain{}
+			found, _, err = cnpd.db.GetValue(stateKey, bdState)
+			if err == nil && found {
+				delete(pendingBDs, stateKey)
+			}
+		}
+		if len(pendingIfs) == 0 && len(pendingBDs) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d i/f's not up and %d BD's not created within %s", len(pendingIfs),
+				len(pendingBDs), timeout)
+		}
+		time.Sleep(blueGreenPollInterval)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The wait for agent confirmation mode is implemented in this file.  When the
+// system parameters ask for it, the vpp i/f's and BD's written to the agents
+// are remembered, and the controller waits for the agents to report each of
+// them in their status tree, or reject it, before an entity counts as wired.
+
+package l2driver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
+)
+
+// agentConfirmTrack remembers an i/f or BD that was written to the vpp label's agent, only when the
+// system parameters ask to wait for the agents
+func (cnpd *sfcCtlrL2CNPDriver) agentConfirmTrack(vppLabel string, obj proto.Message) {
+
+	if !cnpd.l2CNPEntityCache.SysParms.WaitForAgent {
+		return
+	}
+
+	switch o := obj.(type) {
+	case *interfaces.Interfaces_Interface:
+		cnpd.unconfirmedIfs[utils.InterfaceStateKey(vppLabel, o.Name)] =
+			utils.InterfaceErrorKey(vppLabel, o.Name)
+	case *l2.BridgeDomains_BridgeDomain:
+		cnpd.unconfirmedBDs[utils.L2BridgeDomainStateKey(vppLabel, o.Name)] =
+			utils.L2BridgeDomainErrorKey(vppLabel, o.Name)
+	}
+}
+
+// WaitForAgents polls the agents' status and error trees until every i/f and BD written since the previous
+// call is reported, or rejected, or the timeout expires.  During a reconcile nothing has been written to
+// the agents yet so there is nothing to wait for.
+func (cnpd *sfcCtlrL2CNPDriver) WaitForAgents(timeout time.Duration) error {
+
+	if cnpd.reconcileInProgress || (len(cnpd.unconfirmedIfs) == 0 && len(cnpd.unconfirmedBDs) == 0) {
+		return nil
+	}
+
+	pendingIfs := cnpd.unconfirmedIfs
+	pendingBDs := cnpd.unconfirmedBDs
+	cnpd.unconfirmedIfs = make(map[string]string)
+	cnpd.unconfirmedBDs = make(map[string]string)
+
+	log.Infof("WaitForAgents: waiting for %d i/f's and %d BD's", len(pendingIfs), len(pendingBDs))
+
+	deadline := time.Now().Add(timeout)
+	for {
+		for stateKey, errorKey := range pendingIfs {
+			ifErrors := &interfaces.InterfaceErrors_Interface{}
+			found, _, err := cnpd.db.GetValue(errorKey, ifErrors)
+			if err == nil && found && len(ifErrors.GetErrorData()) != 0 {
+				return fmt.Errorf("agent rejected i/f: '%s': %s", errorKey,
+					ifErrors.GetErrorData()[0].ErrorMessage)
+			}
+			ifState := &interfaces.InterfacesState_Interface{}
+			found, _, err = cnpd.db.GetValue(stateKey, ifState)
+			if err == nil && found {
+				delete(pendingIfs, stateKey)
+			}
+		}
+		for stateKey, errorKey := range pendingBDs {
+			bdErrors := &l2.BridgeDomainErrors_BridgeDomain{}
+			found, _, err := cnpd.db.GetValue(errorKey, bdErrors)
+			if err == nil && found && len(bdErrors.GetErrorData()) != 0 {
+				return fmt.Errorf("agent rejected BD: '%s': %s", bdErrors.BdName,
+					bdErrors.GetErrorData()[0].ErrorMessage)
+			}
+			bdState := &l2.BridgeDomainState_BridgeDomain{}
+			found, _, err = cnpd.db.GetValue(stateKey, bdState)
+			if err == nil && found {
+				delete(pendingBDs, stateKey)
+			}
+		}
+		if len(pendingIfs) == 0 && len(pendingBDs) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d i/f's and %d BD's not confirmed by the agents within %s", len(pendingIfs),
+				len(pendingBDs), timeout)
+		}
+		time.Sleep(blueGreenPollInterval)
+	}
+}
@@ -27,6 +27,7 @@ package l2driver
 import (
 	"github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
 	"fmt"
+	"github.com/gogo/protobuf/proto"
 )
 
 // DatastoreReInitialize clears the sfc tree in etcd
@@ -49,18 +50,25 @@ func (cnpd *sfcCtlrL2CNPDriver) DatastoreReInitialize() error {
 		log.Error("DatastoreReInitialize: DatastoreSFCIDsDeleteAll: ", err)
 		return err
 	}
+	if err := cnpd.DatastoreHostIfNamesDeleteAll(); err != nil {
+		log.Error("DatastoreReInitialize: DatastoreHostIfNamesDeleteAll: ", err)
+		return err
+	}
 
 	return nil
 }
 
 // DatastoreHEIDsCreate creates the specified entity in the sfc db in etcd
 func (cnpd *sfcCtlrL2CNPDriver) DatastoreHEIDsCreate(heName string,
-	macAddrID uint32) (string, *l2.HEIDs, error) {
+	macAddrID uint32, loopbackMacAddrIDs map[string]uint32) (string, *l2.HEIDs, error) {
 
 	he := &l2.HEIDs{
 		Name: heName,
 		LoopbackMacAddrId: macAddrID,
 	}
+	if len(loopbackMacAddrIDs) != 0 {
+		he.LoopbackMacAddrIds = loopbackMacAddrIDs
+	}
 
 	key := l2.HEIDsNameKey(he.Name)
 
@@ -254,7 +262,16 @@ func (cnpd *sfcCtlrL2CNPDriver) DatastoreHE2HEIDsCreate(shName string, dhName st
 
 	log.Infof("DatastoreHE2HEIDsCreate: setting key: '%s'", key)
 
-	err := cnpd.db.Put(key, sh2dh)
+	// the record is shared by the controllers of a sharded topology, one must not replace the vni another
+	// recorded in the meantime
+	stored := &l2.HE2HEIDs{}
+	err := cnpd.readModifyWrite(key, stored, decodeValue, func(found bool) (proto.Message, error) {
+		if found && stored.VlanId != 0 && stored.VlanId != vlanID && cnpd.l2CNPEntityCache.SysParms.ShardCount > 1 {
+			return nil, fmt.Errorf("DatastoreHE2HEIDsCreate: key: '%s' records vni %d, not %d", key,
+				stored.VlanId, vlanID)
+		}
+		return sh2dh, nil
+	})
 	if err != nil {
 		log.Errorf("DatastoreHE2HEIDsCreate: error storing key: '%s'", key)
 		log.Error("DatastoreHE2HEIDsCreate: databroker put: ", err)
@@ -336,13 +353,15 @@ func (cnpd *sfcCtlrL2CNPDriver) DatastoreHE2HEIDsDelete(ee *l2.HE2HEIDs) error {
 
 // DatastoreSFCIDsCreate creates the specified entity in the sfc db in etcd
 func (cnpd *sfcCtlrL2CNPDriver) DatastoreSFCIDsCreate(sfcName string, container string,
-	port string, ipID uint32, macAddrID uint32, memifID uint32, vethID uint32) (string, *l2.SFCIDs, error) {
+	port string, ipID uint32, ipv6ID uint32, macAddrID uint32, memifID uint32, vethID uint32) (string, *l2.SFCIDs,
+	error) {
 
 	sfc := &l2.SFCIDs{
 		SfcName: sfcName,
 		Container: container,
 		Port: port,
 		IpId: ipID,
+		Ipv6Id: ipv6ID,
 		MacAddrId: macAddrID,
 		MemifId: memifID,
 		VethId: vethID,
@@ -361,6 +380,29 @@ func (cnpd *sfcCtlrL2CNPDriver) DatastoreSFCIDsCreate(sfcName string, container
 	return key, sfc, nil
 }
 
+// DatastoreSFCIDsSetXConnectVni records the vni of the tunnel of the xconnect hop leaving the container port
+func (cnpd *sfcCtlrL2CNPDriver) DatastoreSFCIDsSetXConnectVni(sfcName string, container string,
+	port string, vni uint32) (string, *l2.SFCIDs, error) {
+
+	key := l2.SFCContainerPortIDsNameKey(sfcName, container, port)
+
+	log.Infof("DatastoreSFCIDsSetXConnectVni: setting key: '%s'", key)
+
+	sfc := &l2.SFCIDs{}
+	err := cnpd.readModifyWrite(key, sfc, decodeValue, func(found bool) (proto.Message, error) {
+		if !found {
+			return nil, fmt.Errorf("DatastoreSFCIDsSetXConnectVni: not found: %s", key)
+		}
+		sfc.XconnectVni = vni
+		return sfc, nil
+	})
+	if err != nil {
+		log.Error("DatastoreSFCIDsSetXConnectVni: databroker put: ", err)
+		return "", nil, err
+	}
+	return key, sfc, nil
+}
+
 // DatastoreSFCIDsRetrieve gets the specified entity from the sfc db in etcd
 func (cnpd *sfcCtlrL2CNPDriver) DatastoreSFCIDsRetrieve(sfcName string, container string,
 	port string) (*l2.SFCIDs, error) {
@@ -431,3 +473,61 @@ func (cnpd *sfcCtlrL2CNPDriver) DatastoreSFCIDsDelete(ee *l2.HE2EEIDs) error {
 
 	return nil
 }
+
+// DatastoreHostIfNameCreate registers the host interface name of the owner in the sfc db in etcd
+func (cnpd *sfcCtlrL2CNPDriver) DatastoreHostIfNameCreate(host string, name string,
+	owner string) (string, *l2.HostIfName, error) {
+
+	hifName := &l2.HostIfName{
+		Host:  host,
+		Name:  name,
+		Owner: owner,
+	}
+
+	key := l2.HostIfNameKey(host, name)
+
+	log.Infof("DatastoreHostIfNameCreate: setting key: '%s'", key)
+
+	err := cnpd.db.Put(key, hifName)
+	if err != nil {
+		log.Errorf("DatastoreHostIfNameCreate: error storing key: '%s': %s", key, err)
+		return "", nil, err
+	}
+	return key, hifName, nil
+}
+
+// DatastoreHostIfNamesDeleteAll removes the registered host interface names from the sfc db in etcd
+func (cnpd *sfcCtlrL2CNPDriver) DatastoreHostIfNamesDeleteAll() error {
+
+	log.Info("DatastoreHostIfNamesDeleteAll: begin ...")
+	defer log.Info("DatastoreHostIfNamesDeleteAll: exit ...")
+
+	return cnpd.DatastoreHostIfNamesIterate(l2.HostIfNamesKeyPrefix(), func(key string, hifName *l2.HostIfName) {
+		log.Infof("DatastoreHostIfNamesDeleteAll: deleting host i/f name: '%s'", key)
+		cnpd.db.Delete(key)
+	})
+}
+
+// DatastoreHostIfNamesIterate iterates over the host interface names registered under the prefix in etcd
+func (cnpd *sfcCtlrL2CNPDriver) DatastoreHostIfNamesIterate(prefix string, actionFunc func(key string,
+	hifName *l2.HostIfName)) error {
+
+	kvi, err := cnpd.db.ListValues(prefix)
+	if err != nil {
+		log.Errorf("DatastoreHostIfNamesIterate: error listing '%s': %s", prefix, err)
+		return err
+	}
+
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return nil
+		}
+		hifName := &l2.HostIfName{}
+		if err := kv.GetValue(hifName); err != nil {
+			log.Errorf("DatastoreHostIfNamesIterate: error decoding '%s': %s", kv.GetKey(), err)
+			return err
+		}
+		actionFunc(kv.GetKey(), hifName)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The eviction of the driver's caches is implemented in this file.  The
+// entity and state caches are filled as entities are wired, and an entity
+// removed from the config, ie by a rollback or a restore, used to stay in
+// them for the life of the controller.  The controller evicts each entity
+// it removes, which drops the entity, the wiring state kept for it and the
+// hashed id's it was allocated, so the caches only hold the entities that
+// are configured.
+
+package l2driver
+
+import (
+	"strings"
+
+	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// EvictEntity removes the entity of the kind, and the state cached for it, from the driver's caches
+func (cnpd *sfcCtlrL2CNPDriver) EvictEntity(kind string, name string) {
+
+	log.Infof("EvictEntity: evicting %s: '%s'", kind, name)
+
+	switch kind {
+	case controller.ExternalEntityKind:
+		cnpd.evictEE(name)
+	case controller.HostEntityKind:
+		cnpd.evictHE(name)
+	case controller.SfcEntityKind:
+		cnpd.evictSFC(name)
+	}
+}
+
+func (cnpd *sfcCtlrL2CNPDriver) evictEE(eeName string) {
+
+	delete(cnpd.l2CNPEntityCache.EEs, eeName)
+
+	for heName, heToEEMap := range cnpd.l2CNPStateCache.HEToEEs {
+		if _, exists := heToEEMap[eeName]; exists {
+			cnpd.releaseHashedIDs(l2driver.HE2EEIDsNameKey(heName, eeName))
+			delete(heToEEMap, eeName)
+		}
+	}
+}
+
+func (cnpd *sfcCtlrL2CNPDriver) evictHE(heName string) {
+
+	delete(cnpd.l2CNPEntityCache.HEs, heName)
+	delete(cnpd.l2CNPStateCache.HE, heName)
+	delete(cnpd.hostIfNames, heName)
+
+	for eeName := range cnpd.l2CNPStateCache.HEToEEs[heName] {
+		cnpd.releaseHashedIDs(l2driver.HE2EEIDsNameKey(heName, eeName))
+	}
+	delete(cnpd.l2CNPStateCache.HEToEEs, heName)
+
+	for dhName := range cnpd.l2CNPStateCache.HEToHEs[heName] {
+		cnpd.releaseHashedIDs(l2driver.HE2HEIDsNameKey(heName, dhName))
+	}
+	delete(cnpd.l2CNPStateCache.HEToHEs, heName)
+	for shName, heToHEMap := range cnpd.l2CNPStateCache.HEToHEs {
+		if _, exists := heToHEMap[heName]; exists {
+			cnpd.releaseHashedIDs(l2driver.HE2HEIDsNameKey(shName, heName))
+			delete(heToHEMap, heName)
+		}
+	}
+
+	for _, sfcToHEMap := range cnpd.l2CNPStateCache.SFCToHEs {
+		delete(sfcToHEMap, heName)
+	}
+
+	cnpd.releaseHashedIDs(l2driver.HEIDsNameKey(heName))
+	cnpd.releaseHashedIDsWithPrefix(l2driver.HEIDsNameKey(heName) + "/")
+}
+
+func (cnpd *sfcCtlrL2CNPDriver) evictSFC(sfcName string) {
+
+	sfc, exists := cnpd.l2CNPEntityCache.SFCs[sfcName]
+	delete(cnpd.l2CNPEntityCache.SFCs, sfcName)
+	delete(cnpd.l2CNPStateCache.SFCToHEs, sfcName)
+	delete(cnpd.l2CNPStateCache.SFCXConns, sfcName)
+	cnpd.releaseHashedIDsWithPrefix(l2driver.SFCIDsNameKey(sfcName) + "/")
+
+	if !exists {
+		return
+	}
+
+	// the addresses of a container port are shared by the sfc's it is an element of
+	for _, sfcElement := range sfc.GetElements() {
+		if !cnpd.containerPortInCachedSFCs(sfcElement.Container, sfcElement.PortLabel) {
+			delete(cnpd.l2CNPStateCache.SFCIFAddr, sfcElement.Container+"/"+sfcElement.PortLabel)
+		}
+	}
+}
+
+func (cnpd *sfcCtlrL2CNPDriver) containerPortInCachedSFCs(container string, port string) bool {
+	for _, sfc := range cnpd.l2CNPEntityCache.SFCs {
+		for _, sfcElement := range sfc.GetElements() {
+			if sfcElement.Container == container && sfcElement.PortLabel == port {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// releaseHashedIDs frees the hashed id's of the owner in every id space
+func (cnpd *sfcCtlrL2CNPDriver) releaseHashedIDs(owner string) {
+	for _, ids := range cnpd.seq.hashed {
+		if id, exists := ids.ids[owner]; exists {
+			delete(ids.ids, owner)
+			delete(ids.owners, id)
+		}
+	}
+}
+
+// releaseHashedIDsWithPrefix frees the hashed id's of the owners starting with the prefix
+func (cnpd *sfcCtlrL2CNPDriver) releaseHashedIDsWithPrefix(prefix string) {
+	for _, ids := range cnpd.seq.hashed {
+		for owner, id := range ids.ids {
+			if strings.HasPrefix(owner, prefix) {
+				delete(ids.ids, owner)
+				delete(ids.owners, id)
+			}
+		}
+	}
+}
+
+// GetCacheSizes returns the number of entries of each of the driver's caches
+func (cnpd *sfcCtlrL2CNPDriver) GetCacheSizes() map[string]int {
+
+	sizes := map[string]int{
+		"ees":                len(cnpd.l2CNPEntityCache.EEs),
+		"hes":                len(cnpd.l2CNPEntityCache.HEs),
+		"sfcs":               len(cnpd.l2CNPEntityCache.SFCs),
+		"he_state":           len(cnpd.l2CNPStateCache.HE),
+		"sfc_if_addrs":       len(cnpd.l2CNPStateCache.SFCIFAddr),
+		"host_if_name_hosts": len(cnpd.hostIfNames),
+		"he_to_ee_state":     0,
+		"he_to_he_state":     0,
+		"sfc_to_he_state":    0,
+		"hashed_ids":         0,
+	}
+	for _, heToEEMap := range cnpd.l2CNPStateCache.HEToEEs {
+		sizes["he_to_ee_state"] += len(heToEEMap)
+	}
+	for _, heToHEMap := range cnpd.l2CNPStateCache.HEToHEs {
+		sizes["he_to_he_state"] += len(heToHEMap)
+	}
+	for _, sfcToHEMap := range cnpd.l2CNPStateCache.SFCToHEs {
+		sizes["sfc_to_he_state"] += len(sfcToHEMap)
+	}
+	for _, ids := range cnpd.seq.hashed {
+		sizes["hashed_ids"] += len(ids.ids)
+	}
+
+	return sizes
+}
@@ -0,0 +1,329 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The forensic lookup is implemented in this file.  Given a vni, a mac or an
+// ip seen in a packet capture, the owners the driver rendered it for are
+// returned: the chain, element, container and host of an element's address,
+// mac or xconnect vni, the host of a loopback or uplink address, the hosts
+// and ee of a vxlan's vni, with the chains the vxlan carries, and the ee of
+// an ee address.  The vni's and the generated macs come from the id records
+// in the datastore, the addresses from what the chains were rendered with.
+
+package l2driver
+
+import (
+	"net"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// the owners of a forensic match
+const (
+	ForensicOwnerSfcElement    = "sfc_element"
+	ForensicOwnerXConnectVxlan = "xconnect_vxlan"
+	ForensicOwnerHostToHost    = "host_to_host_vxlan"
+	ForensicOwnerHostToEE      = "host_to_ee_vxlan"
+	ForensicOwnerHost          = "host"
+	ForensicOwnerEE            = "external_entity"
+)
+
+// ForensicQuery is what was observed, the fields that are not set are not looked up
+type ForensicQuery struct {
+	Vni uint32
+	Mac string
+	IP  string
+}
+
+// ForensicMatch is an owner of the observed vni, mac or ip
+type ForensicMatch struct {
+	Owner     string   `json:"owner"`
+	Matched   string   `json:"matched"` // vni, mac or ip
+	Sfc       string   `json:"sfc,omitempty"`
+	Container string   `json:"container,omitempty"`
+	Port      string   `json:"port,omitempty"`
+	Host      string   `json:"host,omitempty"`
+	PeerHost  string   `json:"peer_host,omitempty"`
+	EE        string   `json:"ee,omitempty"`
+	Interface string   `json:"interface,omitempty"` // the host's loopback or uplink the address is on
+	Sfcs      []string `json:"sfcs,omitempty"`      // the chains a vxlan between hosts, or to an ee, carries
+}
+
+// forensicIfAddrs are the addresses of a host's interface, the vxlan tunnel sources are on its uplinks
+type forensicIfAddrs struct {
+	ifName string
+	addrs  []string
+}
+
+// ForensicLookup returns the owners of the query's vni, mac and ip
+func (cnpd *sfcCtlrL2CNPDriver) ForensicLookup(query *ForensicQuery) ([]*ForensicMatch, error) {
+
+	matches := make([]*ForensicMatch, 0)
+
+	if query.Vni != 0 {
+		vniMatches, err := cnpd.forensicLookupVni(query.Vni)
+		if err != nil {
+			return nil, err
+		}
+		matches = append(matches, vniMatches...)
+	}
+	if query.Mac != "" {
+		if mac, err := net.ParseMAC(query.Mac); err == nil {
+			macMatches, err := cnpd.forensicLookupMac(mac)
+			if err != nil {
+				return nil, err
+			}
+			matches = append(matches, macMatches...)
+		}
+	}
+	if query.IP != "" {
+		if ip := net.ParseIP(strings.Split(query.IP, "/")[0]); ip != nil {
+			matches = append(matches, cnpd.forensicLookupIP(ip)...)
+		}
+	}
+
+	return matches, nil
+}
+
+// forensicLookupVni returns the xconnects and the vxlans the vni was allocated to
+func (cnpd *sfcCtlrL2CNPDriver) forensicLookupVni(vni uint32) ([]*ForensicMatch, error) {
+
+	var matches []*ForensicMatch
+
+	if err := cnpd.DatastoreSFCIDsIterate(func(key string, sfcIDs *l2.SFCIDs) {
+		if sfcIDs.XconnectVni == vni {
+			matches = append(matches, &ForensicMatch{
+				Owner:     ForensicOwnerXConnectVxlan,
+				Matched:   "vni",
+				Sfc:       sfcIDs.SfcName,
+				Container: sfcIDs.Container,
+				Port:      sfcIDs.Port,
+				Host:      cnpd.sfcElementHost(sfcIDs.SfcName, sfcIDs.Container, sfcIDs.Port),
+			})
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if err := cnpd.DatastoreHE2HEIDsIterate(func(key string, he2he *l2.HE2HEIDs) {
+		if he2he.VlanId == vni {
+			matches = append(matches, &ForensicMatch{
+				Owner:    ForensicOwnerHostToHost,
+				Matched:  "vni",
+				Host:     he2he.ShName,
+				PeerHost: he2he.DhName,
+				Sfcs:     cnpd.forensicSfcsBetween(he2he.ShName, he2he.DhName, ""),
+			})
+		}
+	}); err != nil {
+		return nil, err
+	}
+	if err := cnpd.DatastoreHE2EEIDsIterate(func(key string, he2ee *l2.HE2EEIDs) {
+		if he2ee.VlanId == vni {
+			matches = append(matches, &ForensicMatch{
+				Owner:   ForensicOwnerHostToEE,
+				Matched: "vni",
+				Host:    he2ee.HeName,
+				EE:      he2ee.EeName,
+				Sfcs:    cnpd.forensicSfcsBetween(he2ee.HeName, "", he2ee.EeName),
+			})
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// forensicLookupMac returns the elements and the host loopbacks the mac was rendered for
+func (cnpd *sfcCtlrL2CNPDriver) forensicLookupMac(mac net.HardwareAddr) ([]*ForensicMatch, error) {
+
+	elements := make(map[string]bool) // sfc/container/port of the elements already matched
+	matches := cnpd.forensicLookupElements("mac", func(addr sfcInterfaceAddressStateType) bool {
+		return forensicMacEqual(addr.macAddress, mac)
+	})
+	for _, match := range matches {
+		elements[match.Sfc+"/"+match.Container+"/"+match.Port] = true
+	}
+
+	// the generated macs of the elements whose address is not cached, ie of a chain that failed to render
+	if err := cnpd.DatastoreSFCIDsIterate(func(key string, sfcIDs *l2.SFCIDs) {
+		if sfcIDs.MacAddrId == 0 || !forensicMacEqual(formatMacAddress(sfcIDs.MacAddrId), mac) ||
+			elements[sfcIDs.SfcName+"/"+sfcIDs.Container+"/"+sfcIDs.Port] {
+			return
+		}
+		matches = append(matches, &ForensicMatch{
+			Owner:     ForensicOwnerSfcElement,
+			Matched:   "mac",
+			Sfc:       sfcIDs.SfcName,
+			Container: sfcIDs.Container,
+			Port:      sfcIDs.Port,
+			Host:      cnpd.sfcElementHost(sfcIDs.SfcName, sfcIDs.Container, sfcIDs.Port),
+		})
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := cnpd.DatastoreHEIDsIterate(func(key string, heIDs *l2.HEIDs) {
+		he := cnpd.l2CNPEntityCache.HEs[heIDs.Name]
+		if he.LoopbackMacAddr == "" && heIDs.LoopbackMacAddrId != 0 &&
+			forensicMacEqual(formatMacAddress(heIDs.LoopbackMacAddrId), mac) {
+			matches = append(matches, &ForensicMatch{Owner: ForensicOwnerHost, Matched: "mac", Host: heIDs.Name,
+				Interface: "IF_LOOPBACK_H_" + heIDs.Name})
+		}
+		for loopbackName, macAddrID := range heIDs.LoopbackMacAddrIds {
+			if forensicMacEqual(formatMacAddress(macAddrID), mac) {
+				matches = append(matches, &ForensicMatch{Owner: ForensicOwnerHost, Matched: "mac", Host: heIDs.Name,
+					Interface: "IF_LOOPBACK_H_" + heIDs.Name + "_" + loopbackName})
+			}
+		}
+	}); err != nil {
+		return nil, err
+	}
+	for _, heName := range sortedStateKeys(cnpd.l2CNPEntityCache.HEs) {
+		he := cnpd.l2CNPEntityCache.HEs[heName]
+		if forensicMacEqual(he.LoopbackMacAddr, mac) {
+			matches = append(matches, &ForensicMatch{Owner: ForensicOwnerHost, Matched: "mac", Host: he.Name,
+				Interface: "IF_LOOPBACK_H_" + he.Name})
+		}
+		for _, loopback := range he.GetLoopbacks() {
+			if forensicMacEqual(loopback.MacAddr, mac) {
+				matches = append(matches, &ForensicMatch{Owner: ForensicOwnerHost, Matched: "mac", Host: he.Name,
+					Interface: "IF_LOOPBACK_H_" + he.Name + "_" + loopback.Name})
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// forensicLookupIP returns the elements, the hosts and the ee's the ip was rendered for
+func (cnpd *sfcCtlrL2CNPDriver) forensicLookupIP(ip net.IP) []*ForensicMatch {
+
+	matches := cnpd.forensicLookupElements("ip", func(addr sfcInterfaceAddressStateType) bool {
+		return forensicIPEqual(addr.ipAddress, ip) || forensicIPEqual(addr.ipv6Address, ip)
+	})
+
+	for _, heName := range sortedStateKeys(cnpd.l2CNPEntityCache.HEs) {
+		he := cnpd.l2CNPEntityCache.HEs[heName]
+		ifAddrs := []forensicIfAddrs{
+			{he.EthIfName, []string{he.EthIpv4, he.EthIpv6, he.VxlanTunnelIpv4, he.VxlanTunnelIpv6}},
+			{"IF_LOOPBACK_H_" + he.Name, []string{he.LoopbackIpv4, he.LoopbackIpv6}},
+		}
+		for _, uplink := range he.GetUplinks() {
+			ifAddrs = append(ifAddrs, forensicIfAddrs{uplink.EthIfName, []string{uplink.EthIpv4, uplink.EthIpv6,
+				uplink.VxlanTunnelIpv4, uplink.VxlanTunnelIpv6}})
+		}
+		for _, loopback := range he.GetLoopbacks() {
+			ifAddrs = append(ifAddrs, forensicIfAddrs{"IF_LOOPBACK_H_" + he.Name + "_" + loopback.Name,
+				[]string{loopback.Ipv4, loopback.Ipv6}})
+		}
+		for _, ifAddr := range ifAddrs {
+			for _, addr := range ifAddr.addrs {
+				if forensicIPEqual(addr, ip) {
+					matches = append(matches, &ForensicMatch{Owner: ForensicOwnerHost, Matched: "ip",
+						Host: he.Name, Interface: ifAddr.ifName})
+				}
+			}
+		}
+	}
+
+	for _, eeName := range sortedStateKeys(cnpd.l2CNPEntityCache.EEs) {
+		ee := cnpd.l2CNPEntityCache.EEs[eeName]
+		addrs := []string{ee.MgmntIpAddress}
+		if ee.HostInterface != nil {
+			addrs = append(addrs, ee.HostInterface.Ipv4Addr)
+		}
+		if ee.HostVxlan != nil {
+			addrs = append(addrs, ee.HostVxlan.SourceIpv4)
+		}
+		for _, addr := range addrs {
+			if forensicIPEqual(addr, ip) {
+				matches = append(matches, &ForensicMatch{Owner: ForensicOwnerEE, Matched: "ip", EE: ee.Name})
+				break
+			}
+		}
+	}
+
+	return matches
+}
+
+// forensicLookupElements returns the elements of the chains whose rendered address matches
+func (cnpd *sfcCtlrL2CNPDriver) forensicLookupElements(matched string,
+	match func(addr sfcInterfaceAddressStateType) bool) []*ForensicMatch {
+
+	var matches []*ForensicMatch
+	for _, sfcName := range sortedStateKeys(cnpd.l2CNPEntityCache.SFCs) {
+		sfc := cnpd.l2CNPEntityCache.SFCs[sfcName]
+		for _, sfcElement := range sfc.GetElements() {
+			addr, exists := cnpd.l2CNPStateCache.SFCIFAddr[sfcElement.Container+"/"+sfcElement.PortLabel]
+			if !exists || !match(addr) {
+				continue
+			}
+			matches = append(matches, &ForensicMatch{
+				Owner:     ForensicOwnerSfcElement,
+				Matched:   matched,
+				Sfc:       sfc.Name,
+				Container: sfcElement.Container,
+				Port:      sfcElement.PortLabel,
+				Host:      cnpd.sfcElementHost(sfc.Name, sfcElement.Container, sfcElement.PortLabel),
+			})
+		}
+	}
+	return matches
+}
+
+// forensicSfcsBetween returns the chains with elements on the host and on the peer host, or the ee
+func (cnpd *sfcCtlrL2CNPDriver) forensicSfcsBetween(heName string, peerHeName string, eeName string) []string {
+
+	var sfcs []string
+	for _, sfcName := range sortedStateKeys(cnpd.l2CNPEntityCache.SFCs) {
+		sfc := cnpd.l2CNPEntityCache.SFCs[sfcName]
+		onHost, onPeer := false, false
+		for _, sfcElement := range sfc.GetElements() {
+			switch {
+			case sfcElement.Type == controller.SfcElementType_EXTERNAL_ENTITY:
+				onPeer = onPeer || (eeName != "" && sfcElement.Container == eeName)
+			case sfcElement.Type == controller.SfcElementType_HOST_ENTITY:
+				onHost = onHost || sfcElement.Container == heName
+				onPeer = onPeer || (peerHeName != "" && sfcElement.Container == peerHeName)
+			default:
+				onHost = onHost || sfcElement.EtcdVppSwitchKey == heName
+				onPeer = onPeer || (peerHeName != "" && sfcElement.EtcdVppSwitchKey == peerHeName)
+			}
+		}
+		if onHost && onPeer {
+			sfcs = append(sfcs, sfc.Name)
+		}
+	}
+	return sfcs
+}
+
+// forensicIPEqual is whether the address, with or without a prefix length, is the ip
+func forensicIPEqual(addr string, ip net.IP) bool {
+	if addr == "" {
+		return false
+	}
+	addrIP := net.ParseIP(strings.Split(addr, "/")[0])
+	return addrIP != nil && addrIP.Equal(ip)
+}
+
+// forensicMacEqual is whether the address is the mac, whatever its case and separators
+func forensicMacEqual(addr string, mac net.HardwareAddr) bool {
+	if addr == "" {
+		return false
+	}
+	addrMac, err := net.ParseMAC(addr)
+	return err == nil && addrMac.String() == mac.String()
+}
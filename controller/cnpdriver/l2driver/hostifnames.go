@@ -0,0 +1,121 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The registry of the linux interface names the controller renders on a
+// host is implemented in this file.  A linux interface name is limited to
+// 15 chars so it is shortened from the container and port names, and two
+// similarly named containers can shorten to the same name.  Every name is
+// registered per host in the datastore with the container port it was
+// given to, so a name is never handed out twice on a host, and the same
+// container port gets back its name after a restart or a reconcile.
+
+package l2driver
+
+import (
+	"fmt"
+	"strconv"
+
+	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+)
+
+// maxHostIfNameLen is IFNAMSIZ less the terminating NULL
+const maxHostIfNameLen = 15
+
+// hostIfNamesType are the interface names registered on a host, by name and by owner
+type hostIfNamesType struct {
+	owners map[string]string // name -> owner
+	names  map[string]string // owner -> name
+}
+
+// hostIfNamesOfHost returns the registry of the host, loading it from the datastore the first time
+func (cnpd *sfcCtlrL2CNPDriver) hostIfNamesOfHost(host string) (*hostIfNamesType, error) {
+
+	if cnpd.hostIfNames == nil {
+		cnpd.hostIfNames = make(map[string]*hostIfNamesType)
+	}
+	if hifNames, exists := cnpd.hostIfNames[host]; exists {
+		return hifNames, nil
+	}
+
+	hifNames := &hostIfNamesType{
+		owners: make(map[string]string),
+		names:  make(map[string]string),
+	}
+	err := cnpd.DatastoreHostIfNamesIterate(l2driver.HostIfNamesHostKeyPrefix(host),
+		func(key string, hifName *l2driver.HostIfName) {
+			hifNames.owners[hifName.Name] = hifName.Owner
+			hifNames.names[hifName.Owner] = hifName.Name
+		})
+	if err != nil {
+		return nil, err
+	}
+	cnpd.hostIfNames[host] = hifNames
+
+	return hifNames, nil
+}
+
+// allocateHostIfName returns the name of the owner's interface on the host.  The name registered for the
+// owner is kept, a new owner gets the shortened container and port names with the base 36 veth id, or if
+// that is taken on the host, the shortened names with the first free base 36 suffix.
+func (cnpd *sfcCtlrL2CNPDriver) allocateHostIfName(host string, owner string, container string, port string,
+	vethID uint32) (string, error) {
+
+	hifNames, err := cnpd.hostIfNamesOfHost(host)
+	if err != nil {
+		return "", err
+	}
+
+	name, exists := hifNames.names[owner]
+	if !exists {
+		vethIDStr := strconv.FormatUint(uint64(vethID), 36)
+		name = constructBaseHostName(container, port, vethIDStr) + "_" + vethIDStr
+		if _, taken := hifNames.owners[name]; taken || len(name) > maxHostIfNameLen {
+			if name, err = hifNames.firstFreeName(constructBaseHostName(container, port, "")); err != nil {
+				log.Errorf("allocateHostIfName: host: '%s', owner: '%s': %s", host, owner, err)
+				return "", err
+			}
+		}
+		hifNames.owners[name] = owner
+		hifNames.names[owner] = name
+	}
+
+	// the name is written on every render so a reconcile keeps it
+	key, hifName, err := cnpd.DatastoreHostIfNameCreate(host, name, owner)
+	if err != nil {
+		return "", err
+	}
+	if cnpd.reconcileInProgress {
+		cnpd.reconcileAfter.hostIfNames[key] = *hifName
+	}
+
+	return name, nil
+}
+
+// firstFreeName returns the base with the first base 36 suffix not taken on the host, the base is
+// truncated to make room for the suffix
+func (hifNames *hostIfNamesType) firstFreeName(base string) (string, error) {
+
+	for n := uint64(1); n < 36*36*36*36; n++ {
+		suffix := "_" + strconv.FormatUint(n, 36)
+		name := base
+		if len(name)+len(suffix) > maxHostIfNameLen {
+			name = name[:maxHostIfNameLen-len(suffix)]
+		}
+		name += suffix
+		if _, taken := hifNames.owners[name]; !taken {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no free host i/f name for '%s'", base)
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The allocation of the sequencer's id's is implemented in this file.  By
+// default each id space is a counter, so an id depends on the order the
+// entities were rendered in.  With deterministic_ids set in the system
+// parameters, an id is hashed from the name of its owner, ie the key of the
+// id's in the datastore, into the id space.  Only a collision moves an id to
+// the next free one, so the rendering of a topology does not change with the
+// order of the events that built it.
+
+package l2driver
+
+import (
+	"hash/fnv"
+	"sort"
+
+	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/utils/ipam"
+)
+
+type idSpace int
+
+const (
+	idSpaceVLan idSpace = iota
+	idSpaceMemIf
+	idSpaceMacInstance
+	idSpaceVeth
+)
+
+// idSpaceNames name the spaces in the logs and the alarms
+var idSpaceNames = map[idSpace]string{
+	idSpaceVLan:        "vlan",
+	idSpaceMemIf:       "memif",
+	idSpaceMacInstance: "mac-instance",
+	idSpaceVeth:        "veth",
+}
+
+// hashedIDs are the id's of a space allocated in deterministic mode, by owner and by id
+type hashedIDs struct {
+	ids    map[string]uint32
+	owners map[uint32]string
+}
+
+// counter returns the sequencer's counter for the space
+func (seq *sequencer) counter(space idSpace) *uint32 {
+	switch space {
+	case idSpaceVLan:
+		return &seq.VLanID
+	case idSpaceMemIf:
+		return &seq.MemIfID
+	case idSpaceMacInstance:
+		return &seq.MacInstanceID
+	default:
+		return &seq.VethID
+	}
+}
+
+// idRange returns the first and last id of the space
+func (cnpd *sfcCtlrL2CNPDriver) idRange(space idSpace) (uint32, uint32) {
+
+	first, last := uint32(1), uint32(1<<24-1)
+
+	switch space {
+	case idSpaceVLan:
+		first = cnpd.l2CNPEntityCache.SysParms.StartingVlanId // vni's are 24 bits
+	case idSpaceVeth:
+		last = 36*36*36 - 1 // the id is 3 base 36 chrs of the veth names
+	}
+	if first == 0 || first > last {
+		first = 1
+	}
+	return first, last
+}
+
+// deterministicIDs is whether id's are hashed from their owner's names
+func (cnpd *sfcCtlrL2CNPDriver) deterministicIDs() bool {
+	return cnpd.l2CNPEntityCache.SysParms.DeterministicIds
+}
+
+// nextID allocates an id of the space for the owner, the next value of the sequencer, or in deterministic
+// mode the id hashed from the owner's name
+func (cnpd *sfcCtlrL2CNPDriver) nextID(space idSpace, owner string) uint32 {
+
+	if !cnpd.deterministicIDs() {
+		counter := cnpd.seq.counter(space)
+		*counter++
+		if _, last := cnpd.idRange(space); *counter > last {
+			cnpd.idSpaceExhausted(space, owner)
+		}
+		return *counter
+	}
+
+	ids := cnpd.seq.hashedIDs(space)
+	if id, exists := ids.ids[owner]; exists {
+		return id
+	}
+
+	first, last := cnpd.idRange(space)
+	size := last - first + 1
+
+	h := fnv.New32a()
+	h.Write([]byte(owner))
+	offset := h.Sum32() % size
+
+	for n := uint32(0); n < size; n++ {
+		id := first + (offset+n)%size
+		if _, taken := ids.owners[id]; !taken {
+			ids.ids[owner] = id
+			ids.owners[id] = owner
+			return id
+		}
+	}
+
+	cnpd.idSpaceExhausted(space, owner)
+	counter := cnpd.seq.counter(space)
+	*counter++
+	return *counter
+}
+
+// idSpaceExhausted records that the space ran out allocating the owner's id
+func (cnpd *sfcCtlrL2CNPDriver) idSpaceExhausted(space idSpace, owner string) {
+	log.Errorf("nextID: id space '%s' is full, allocating out of its range for: '%s'", idSpaceNames[space],
+		owner)
+	if cnpd.seq.exhausted == nil {
+		cnpd.seq.exhausted = make(map[idSpace]struct{})
+	}
+	cnpd.seq.exhausted[space] = struct{}{}
+}
+
+// GetExhaustedIDSpaces returns the names of the id spaces that ran out since it was last called
+func (cnpd *sfcCtlrL2CNPDriver) GetExhaustedIDSpaces() []string {
+	var names []string
+	for space := range cnpd.seq.exhausted {
+		names = append(names, idSpaceNames[space])
+	}
+	cnpd.seq.exhausted = nil
+	sort.Strings(names)
+	return names
+}
+
+// shardedHE2HEVlanID returns the vni of the tunnel between the hosts when they are sharded across
+// controllers, the controllers rendering either end of the tunnel agree on it through the id records they
+// share: the vni recorded in either direction, else the vni hashed from the hosts in the same order
+func (cnpd *sfcCtlrL2CNPDriver) shardedHE2HEVlanID(shName string, dhName string) uint32 {
+
+	for _, hosts := range [][2]string{{shName, dhName}, {dhName, shName}} {
+		if sh2dhID, _ := cnpd.DatastoreHE2HEIDsRetrieve(hosts[0], hosts[1]); sh2dhID != nil && sh2dhID.VlanId != 0 {
+			return sh2dhID.VlanId
+		}
+	}
+	if dhName < shName {
+		shName, dhName = dhName, shName
+	}
+	return cnpd.nextID(idSpaceVLan, l2driver.HE2HEIDsNameKey(shName, dhName))
+}
+
+// reserveID marks an id allocated before, ie loaded from the datastore, as taken by its owner so a hashed
+// id is never allocated on top of it
+func (cnpd *sfcCtlrL2CNPDriver) reserveID(space idSpace, owner string, id uint32) {
+	if id == 0 {
+		return
+	}
+	ids := cnpd.seq.hashedIDs(space)
+	ids.ids[owner] = id
+	ids.owners[id] = owner
+}
+
+func (seq *sequencer) hashedIDs(space idSpace) *hashedIDs {
+	if seq.hashed == nil {
+		seq.hashed = make(map[idSpace]*hashedIDs)
+	}
+	ids, exists := seq.hashed[space]
+	if !exists {
+		ids = &hashedIDs{
+			ids:    make(map[string]uint32),
+			owners: make(map[uint32]string),
+		}
+		seq.hashed[space] = ids
+	}
+	return ids
+}
+
+// allocateIPAddress allocates the sfc element's address from the subnet, the first free address, or in
+// deterministic mode the address hashed from the element's name
+func (cnpd *sfcCtlrL2CNPDriver) allocateIPAddress(subnet string, sfcName string, container string,
+	port string) (string, uint32, error) {
+
+	if !cnpd.deterministicIDs() {
+		return ipam.AllocateFromSubnet(subnet)
+	}
+	return ipam.AllocateHashedFromSubnet(subnet, l2driver.SFCContainerPortIDsNameKey(sfcName, container, port))
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package l2driver
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+func TestNextIDCounter(t *testing.T) {
+
+	cnpd := NewSfcCtlrL2CNPDriver("test", membroker.New().NewBroker)
+	cnpd.SetSystemParameters(&controller.SystemParameters{StartingVlanId: 5000})
+
+	if id := cnpd.nextID(idSpaceVLan, "a"); id != 5000 {
+		t.Errorf("vlan id: %d, expected: 5000", id)
+	}
+	if id := cnpd.nextID(idSpaceMemIf, "a"); id != 1 {
+		t.Errorf("memif id: %d, expected: 1", id)
+	}
+	if id := cnpd.nextID(idSpaceMemIf, "a"); id != 2 {
+		t.Errorf("memif id: %d, expected: 2, the counter hands out a new id each time", id)
+	}
+
+	cnpd.seq.VethID = 36*36*36 - 1
+	cnpd.nextID(idSpaceVeth, "a")
+	if exhausted := cnpd.GetExhaustedIDSpaces(); !reflect.DeepEqual(exhausted, []string{"veth"}) {
+		t.Errorf("exhausted id spaces: %v, expected: [veth]", exhausted)
+	}
+	if exhausted := cnpd.GetExhaustedIDSpaces(); len(exhausted) != 0 {
+		t.Errorf("exhausted id spaces: %v, expected none once they were returned", exhausted)
+	}
+}
+
+// a hashed id depends on its owner only, not on the order the ids were allocated in
+func TestNextIDDeterministic(t *testing.T) {
+
+	allocate := func(owners ...string) map[string]uint32 {
+		cnpd := NewSfcCtlrL2CNPDriver("test", membroker.New().NewBroker)
+		cnpd.SetSystemParameters(&controller.SystemParameters{DeterministicIds: true})
+		ids := make(map[string]uint32)
+		for _, owner := range owners {
+			ids[owner] = cnpd.nextID(idSpaceMemIf, owner)
+			if again := cnpd.nextID(idSpaceMemIf, owner); again != ids[owner] {
+				t.Errorf("owner: '%s' allocated: %d, then: %d", owner, ids[owner], again)
+			}
+		}
+		return ids
+	}
+
+	forward, backward := allocate("a", "b", "c"), allocate("c", "b", "a")
+	if !reflect.DeepEqual(forward, backward) {
+		t.Errorf("ids: %v in one order, %v in the other", forward, backward)
+	}
+}
+
+// an id loaded from the datastore is never hashed to another owner
+func TestReserveID(t *testing.T) {
+
+	cnpd := NewSfcCtlrL2CNPDriver("test", membroker.New().NewBroker)
+	cnpd.SetSystemParameters(&controller.SystemParameters{DeterministicIds: true})
+
+	hashed := cnpd.nextID(idSpaceMemIf, "a")
+
+	cnpd = NewSfcCtlrL2CNPDriver("test", membroker.New().NewBroker)
+	cnpd.SetSystemParameters(&controller.SystemParameters{DeterministicIds: true})
+	cnpd.reserveID(idSpaceMemIf, "b", hashed)
+
+	if id := cnpd.nextID(idSpaceMemIf, "a"); id == hashed {
+		t.Errorf("owner: 'a' allocated id: %d reserved by 'b'", id)
+	}
+	if id := cnpd.nextID(idSpaceMemIf, "b"); id != hashed {
+		t.Errorf("owner: 'b' allocated id: %d instead of its reserved id: %d", id, hashed)
+	}
+}
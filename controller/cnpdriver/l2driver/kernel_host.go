@@ -0,0 +1,113 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The wiring of the kernel hosts is implemented in this file.  A kernel
+// host runs the agent without vpp, so none of the vswitch wiring of a host
+// is rendered for it: it has no ethernet, loopbacks, bridges or tunnels and
+// the ee's are not wired to it.  The elements a chain places on a kernel
+// host are non vpp containers, each is terminated into a veth pair rendered
+// with the linux models only.  The container end carries the element's
+// addresses and linux routes like it does on a vpp host, the host end is
+// left in the host's namespace with a link scoped route to each of the
+// element's addresses, so the host's kernel forwards to the container.  The
+// rest of the chain, on the vpp hosts, is wired without those elements.
+
+package l2driver
+
+import (
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	linuxL3 "github.com/ligato/vpp-agent/plugins/linuxplugin/l3plugin/model/l3"
+)
+
+// kernelHost is whether the host is a linux only one, without vpp
+func (cnpd *sfcCtlrL2CNPDriver) kernelHost(heName string) bool {
+	he, exists := cnpd.l2CNPEntityCache.HEs[heName]
+	return exists && he.HostType == controller.HostType_HOST_KERNEL
+}
+
+// wireSfcKernelHostElements terminates the chain's elements placed on kernel hosts into their veths, it returns
+// the chain without them for the vpp hosts to be wired
+func (cnpd *sfcCtlrL2CNPDriver) wireSfcKernelHostElements(sfc *controller.SfcEntity) (*controller.SfcEntity,
+	error) {
+
+	var vppElements []*controller.SfcEntity_SfcElement
+	for _, sfcEntityElement := range sfc.GetElements() {
+		if sfcEntityElement.Type == controller.SfcElementType_EXTERNAL_ENTITY ||
+			!cnpd.kernelHost(sfcEntityElement.EtcdVppSwitchKey) {
+			vppElements = append(vppElements, sfcEntityElement)
+			continue
+		}
+		if _, err := cnpd.createAFPacketVEthPair(sfc, sfcEntityElement); err != nil {
+			log.Errorf("wireSfcKernelHostElements: error creating veth pair: sfc: '%s', Container: '%s'",
+				sfc.Name, sfcEntityElement.Container)
+			return nil, err
+		}
+	}
+	if len(vppElements) == len(sfc.GetElements()) {
+		return sfc, nil
+	}
+
+	vppSfc := *sfc
+	vppSfc.Elements = vppElements
+	return &vppSfc, nil
+}
+
+// sfcHasVppElements is whether any of the chain's elements is wired on a vpp host, an ee alone is not
+func sfcHasVppElements(sfc *controller.SfcEntity) bool {
+	for _, sfcEntityElement := range sfc.GetElements() {
+		if sfcEntityElement.Type != controller.SfcElementType_EXTERNAL_ENTITY {
+			return true
+		}
+	}
+	return false
+}
+
+// createKernelHostRoutes routes the element's addresses onto the host end of its veth in the kernel host's
+// namespace
+func (cnpd *sfcCtlrL2CNPDriver) createKernelHostRoutes(vnfChainElement *controller.SfcEntity_SfcElement,
+	vethName string, ipv4 string, ipv6 string, description string) error {
+
+	for _, addr := range []string{ipv4, ipv6} {
+		if addr == "" {
+			continue
+		}
+		dstIPAddr := strings.Split(addr, "/")[0] + "/32"
+		if strings.Contains(addr, ":") {
+			dstIPAddr = strings.Split(addr, "/")[0] + "/128"
+		}
+		route := &linuxL3.LinuxStaticRoutes_Route{
+			Name: "ROUTE_KERNEL_" + vnfChainElement.Container + "_" + vnfChainElement.PortLabel + "_" +
+				replaceSlashesWithUScores(dstIPAddr),
+			Namespace: &linuxL3.LinuxStaticRoutes_Route_Namespace{
+				Type:         linuxL3.LinuxStaticRoutes_Route_Namespace_MICROSERVICE_REF_NS,
+				Microservice: vnfChainElement.EtcdVppSwitchKey,
+			},
+			Interface:   vethName,
+			Description: description,
+			Scope: &linuxL3.LinuxStaticRoutes_Route_Scope{
+				Type: linuxL3.LinuxStaticRoutes_Route_Scope_LINK,
+			},
+			DstIpAddr: dstIPAddr,
+		}
+		log.Info("createKernelHostRoutes: route: ", route)
+		if err := cnpd.agentPut(vnfChainElement.EtcdVppSwitchKey, route); err != nil {
+			log.Error("createKernelHostRoutes: databroker.Store: ", err)
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The locking of the driver's shared state is implemented in this file.
+// Reconcile, the repair of an agent that restarted, and the wiring triggered
+// by the northbound api can run at the same time.  The reconcile caches are
+// shared by all of them, so they are only read or updated holding the
+// reconcile mutex.  The writes to an agent are done holding the agent's host
+// lock: a wiring change writes one object at a time, and a reconcile holds
+// the locks of every agent it touches for its whole write back, so a wiring
+// change to one of those agents lands before or after the write back, never
+// in between its deletes and puts.  The reconcile mutex is always taken
+// before the host locks.
+
+package l2driver
+
+import (
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/utils"
+)
+
+// lockAgents locks the agents of the vpp agent keys, the other keys, ie the id records, are not locked
+func (cnpd *sfcCtlrL2CNPDriver) lockAgents(keySets ...[]string) (unlock func()) {
+
+	var vppLabels []string
+	for _, keys := range keySets {
+		for _, key := range keys {
+			if strings.HasPrefix(key, utils.GetVppAgentPrefix()) {
+				vppLabels = append(vppLabels, utils.GetVppEtcdlabel(key))
+			}
+		}
+	}
+	return cnpd.agentLocks.Lock(vppLabels...)
+}
+
+// reconcileAgentKeys returns the agent keys of the reconcile caches
+func (cnpd *sfcCtlrL2CNPDriver) reconcileAgentKeys() []string {
+
+	var keys []string
+	for _, cache := range []*reconcileCacheType{&cnpd.reconcileBefore, &cnpd.reconcileAfter} {
+		for key := range cache.ifs {
+			keys = append(keys, key)
+		}
+		for key := range cache.lifs {
+			keys = append(keys, key)
+		}
+		for key := range cache.bds {
+			keys = append(keys, key)
+		}
+		for key := range cache.l3Routes {
+			keys = append(keys, key)
+		}
+		for key := range cache.xconns {
+			keys = append(keys, key)
+		}
+		for key := range cache.l2fibs {
+			keys = append(keys, key)
+		}
+		for key := range cache.arps {
+			keys = append(keys, key)
+		}
+	}
+	for vppLabel := range cnpd.streamedLabels {
+		keys = append(keys, utils.GetVppAgentPrefix()+vppLabel+"/")
+	}
+	return keys
+}
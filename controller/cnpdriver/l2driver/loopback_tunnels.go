@@ -0,0 +1,35 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The loopback tunnel endpoints are implemented in this file.  A host with
+// loopback_tunnel_endpoints sources its vxlan tunnels from its loopback,
+// unnumbered, instead of from an address of one of its nics, so a tunnel
+// does not go down with the nic it was sourced from.  The loopback is not on
+// a subnet of the peers, so each host it is wired to gets a static route to
+// it via the address of the host's nic, whether or not the peer has
+// create_vxlan_static_route set.
+
+package l2driver
+
+import (
+	"strings"
+)
+
+// hostPrefix returns the address as a host prefix, unless it already has a prefix length
+func hostPrefix(addr string, hostLen string) string {
+	if strings.Contains(addr, "/") {
+		return addr
+	}
+	return addr + hostLen
+}
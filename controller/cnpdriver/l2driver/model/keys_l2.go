@@ -15,6 +15,8 @@
 package l2
 
 import (
+	"strings"
+
 	"github.com/ligato/sfc-controller/controller/model/controller"
 )
 
@@ -42,6 +44,22 @@ func SFCIDsKeyPrefix() string {
 	return sfcControllerIDsKeyPrefix() + "SFC/"
 }
 
+// HostIfNamesKeyPrefix returns the ETCD prefix
+func HostIfNamesKeyPrefix() string {
+	return sfcControllerIDsKeyPrefix() + "HIF/"
+}
+
+// HostIfNamesHostKeyPrefix returns the ETCD prefix of the host interface names registered on the host
+func HostIfNamesHostKeyPrefix(host string) string {
+	return HostIfNamesKeyPrefix() + host + "/"
+}
+
+// OwnedKeysKeyPrefix returns the ETCD prefix of the records of the agent keys the controller wrote, they are
+// not id's so -clean keeps them
+func OwnedKeysKeyPrefix() string {
+	return controller.SfcControllerPrefix() + "owned/"
+}
+
 // HEIDsNameKey returns the ETCD key
 func HEIDsNameKey(name string) string {
 	return HEIDsKeyPrefix() + name
@@ -66,3 +84,13 @@ func SFCIDsNameKey(name string) string {
 func SFCContainerPortIDsNameKey(sfcName string, container string, port string) string {
 	return SFCIDsNameKey(sfcName) + "/" + container + "_" + port
 }
+
+// HostIfNameKey returns the ETCD key
+func HostIfNameKey(host string, name string) string {
+	return HostIfNamesHostKeyPrefix(host) + name
+}
+
+// OwnedKeyKey returns the ETCD key
+func OwnedKeyKey(agentKey string) string {
+	return OwnedKeysKeyPrefix() + strings.TrimPrefix(agentKey, "/")
+}
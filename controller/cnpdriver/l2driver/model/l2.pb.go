@@ -13,6 +13,8 @@ It has these top-level messages:
 	HE2EEIDs
 	HE2HEIDs
 	SFCIDs
+	HostIfName
+	OwnedKey
 */
 package l2
 
@@ -22,14 +24,22 @@ import proto "github.com/gogo/protobuf/proto"
 var _ = proto.Marshal
 
 type HEIDs struct {
-	Name              string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	LoopbackMacAddrId uint32 `protobuf:"varint,2,opt,name=loopback_mac_addr_id,proto3" json:"loopback_mac_addr_id,omitempty"`
+	Name               string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	LoopbackMacAddrId  uint32            `protobuf:"varint,2,opt,name=loopback_mac_addr_id,proto3" json:"loopback_mac_addr_id,omitempty"`
+	LoopbackMacAddrIds map[string]uint32 `protobuf:"bytes,3,rep,name=loopback_mac_addr_ids" json:"loopback_mac_addr_ids,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
 }
 
 func (m *HEIDs) Reset()         { *m = HEIDs{} }
 func (m *HEIDs) String() string { return proto.CompactTextString(m) }
 func (*HEIDs) ProtoMessage()    {}
 
+func (m *HEIDs) GetLoopbackMacAddrIds() map[string]uint32 {
+	if m != nil {
+		return m.LoopbackMacAddrIds
+	}
+	return nil
+}
+
 type HE2EEIDs struct {
 	HeName string `protobuf:"bytes,1,opt,name=he_name,proto3" json:"he_name,omitempty"`
 	EeName string `protobuf:"bytes,2,opt,name=ee_name,proto3" json:"ee_name,omitempty"`
@@ -51,15 +61,35 @@ func (m *HE2HEIDs) String() string { return proto.CompactTextString(m) }
 func (*HE2HEIDs) ProtoMessage()    {}
 
 type SFCIDs struct {
-	SfcName   string `protobuf:"bytes,1,opt,name=sfc_name,proto3" json:"sfc_name,omitempty"`
-	Container string `protobuf:"bytes,2,opt,name=container,proto3" json:"container,omitempty"`
-	Port      string `protobuf:"bytes,3,opt,name=port,proto3" json:"port,omitempty"`
-	IpId      uint32 `protobuf:"varint,4,opt,name=ip_id,proto3" json:"ip_id,omitempty"`
-	MacAddrId uint32 `protobuf:"varint,5,opt,name=mac_addr_id,proto3" json:"mac_addr_id,omitempty"`
-	MemifId   uint32 `protobuf:"varint,6,opt,name=memif_id,proto3" json:"memif_id,omitempty"`
-	VethId    uint32 `protobuf:"varint,7,opt,name=veth_id,proto3" json:"veth_id,omitempty"`
+	SfcName     string `protobuf:"bytes,1,opt,name=sfc_name,proto3" json:"sfc_name,omitempty"`
+	Container   string `protobuf:"bytes,2,opt,name=container,proto3" json:"container,omitempty"`
+	Port        string `protobuf:"bytes,3,opt,name=port,proto3" json:"port,omitempty"`
+	IpId        uint32 `protobuf:"varint,4,opt,name=ip_id,proto3" json:"ip_id,omitempty"`
+	MacAddrId   uint32 `protobuf:"varint,5,opt,name=mac_addr_id,proto3" json:"mac_addr_id,omitempty"`
+	MemifId     uint32 `protobuf:"varint,6,opt,name=memif_id,proto3" json:"memif_id,omitempty"`
+	VethId      uint32 `protobuf:"varint,7,opt,name=veth_id,proto3" json:"veth_id,omitempty"`
+	XconnectVni uint32 `protobuf:"varint,9,opt,name=xconnect_vni,proto3" json:"xconnect_vni,omitempty"`
+	Ipv6Id      uint32 `protobuf:"varint,10,opt,name=ipv6_id,proto3" json:"ipv6_id,omitempty"`
 }
 
 func (m *SFCIDs) Reset()         { *m = SFCIDs{} }
 func (m *SFCIDs) String() string { return proto.CompactTextString(m) }
 func (*SFCIDs) ProtoMessage()    {}
+
+type HostIfName struct {
+	Host  string `protobuf:"bytes,1,opt,name=host,proto3" json:"host,omitempty"`
+	Name  string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Owner string `protobuf:"bytes,3,opt,name=owner,proto3" json:"owner,omitempty"`
+}
+
+func (m *HostIfName) Reset()         { *m = HostIfName{} }
+func (m *HostIfName) String() string { return proto.CompactTextString(m) }
+func (*HostIfName) ProtoMessage()    {}
+
+type OwnedKey struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+}
+
+func (m *OwnedKey) Reset()         { *m = OwnedKey{} }
+func (m *OwnedKey) String() string { return proto.CompactTextString(m) }
+func (*OwnedKey) ProtoMessage()    {}
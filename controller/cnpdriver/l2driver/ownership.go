@@ -0,0 +1,149 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The ownership of the agents' keys is implemented in this file.  An agent
+// may also be configured by hand, or by another controller, so every key the
+// driver writes to an agent is recorded in the controller's tree, and a
+// reconcile only loads the keys that are recorded: the objects of the others
+// are neither compared nor deleted.  A key the driver renders is taken over
+// whoever wrote it before.  The records are kept by -clean, so the reconcile
+// that follows still removes what the controller rendered before, and they
+// are removed with the keys the reconcile removes.
+//
+// The keys written before the controller recorded its keys are not owned, so
+// they are left alone like the others until they are rendered again.  The
+// same goes for a teardown on shutdown: only the owned keys are deleted.
+
+package l2driver
+
+import (
+	"sort"
+	"strings"
+
+	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/utils"
+)
+
+// ownedKeysLoad reads the records of the owned keys, once
+func (cnpd *sfcCtlrL2CNPDriver) ownedKeysLoad() error {
+
+	if cnpd.ownedKeys != nil {
+		return nil
+	}
+
+	ownedKeys := make(map[string]struct{})
+	kvi, err := cnpd.db.ListValues(l2driver.OwnedKeysKeyPrefix())
+	if err != nil {
+		log.Errorf("ownedKeysLoad: error listing the owned keys: %s", err)
+		return err
+	}
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			break
+		}
+		entry := &l2driver.OwnedKey{}
+		if err := kv.GetValue(entry); err != nil {
+			log.Errorf("ownedKeysLoad: error decoding: '%s': %s", kv.GetKey(), err)
+			return err
+		}
+		ownedKeys[entry.Key] = struct{}{}
+	}
+	cnpd.ownedKeys = ownedKeys
+
+	return nil
+}
+
+// agentKeyOwned is whether the driver wrote the agent key
+func (cnpd *sfcCtlrL2CNPDriver) agentKeyOwned(key string) bool {
+
+	cnpd.ownedKeysMutex.Lock()
+	defer cnpd.ownedKeysMutex.Unlock()
+
+	if err := cnpd.ownedKeysLoad(); err != nil {
+		return false
+	}
+	_, owned := cnpd.ownedKeys[key]
+	return owned
+}
+
+// agentKeyOwn records the agent key as written by the driver, the controller's own keys are not recorded
+func (cnpd *sfcCtlrL2CNPDriver) agentKeyOwn(key string) error {
+
+	if !strings.HasPrefix(key, utils.GetVppAgentPrefix()) {
+		return nil
+	}
+
+	cnpd.ownedKeysMutex.Lock()
+	defer cnpd.ownedKeysMutex.Unlock()
+
+	if err := cnpd.ownedKeysLoad(); err != nil {
+		return err
+	}
+	if _, owned := cnpd.ownedKeys[key]; owned {
+		return nil
+	}
+	if err := cnpd.db.Put(l2driver.OwnedKeyKey(key), &l2driver.OwnedKey{Key: key}); err != nil {
+		log.Errorf("agentKeyOwn: error recording key: '%s': %s", key, err)
+		return err
+	}
+	cnpd.ownedKeys[key] = struct{}{}
+
+	return nil
+}
+
+// agentKeyDisown removes the record of an agent key the driver deleted
+func (cnpd *sfcCtlrL2CNPDriver) agentKeyDisown(key string) {
+
+	cnpd.ownedKeysMutex.Lock()
+	defer cnpd.ownedKeysMutex.Unlock()
+
+	if cnpd.ownedKeys != nil {
+		delete(cnpd.ownedKeys, key)
+	}
+	if _, err := cnpd.db.Delete(l2driver.OwnedKeyKey(key)); err != nil {
+		log.Errorf("agentKeyDisown: error removing the record of key: '%s': %s", key, err)
+	}
+}
+
+// OwnsAgentKey is whether the agent key was written by the driver, the others' keys are left alone
+func (cnpd *sfcCtlrL2CNPDriver) OwnsAgentKey(key string) bool {
+	return cnpd.agentKeyOwned(key)
+}
+
+// DeleteOwnedAgentKeys deletes the agent keys the driver wrote that are selected, in key order, and returns how
+// many were deleted
+func (cnpd *sfcCtlrL2CNPDriver) DeleteOwnedAgentKeys(selected func(key string) bool) (int, error) {
+
+	cnpd.ownedKeysMutex.Lock()
+	if err := cnpd.ownedKeysLoad(); err != nil {
+		cnpd.ownedKeysMutex.Unlock()
+		return 0, err
+	}
+	keys := make([]string, 0)
+	for key := range cnpd.ownedKeys {
+		if selected(key) {
+			keys = append(keys, key)
+		}
+	}
+	cnpd.ownedKeysMutex.Unlock()
+	sort.Strings(keys)
+
+	for i, key := range keys {
+		if err := cnpd.agentDeleteKey(key); err != nil {
+			return i, err
+		}
+	}
+	return len(keys), nil
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package l2driver
+
+import (
+	"testing"
+
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+func TestAgentKeyOwnership(t *testing.T) {
+
+	broker := membroker.New()
+	cnpd := NewSfcCtlrL2CNPDriver("test", broker.NewBroker)
+
+	owned := "/vnf-agent/vswitch/vpp/config/v1/interface/IF1"
+	other := "/vnf-agent/vswitch/vpp/config/v1/interface/IF2"
+	controllerKey := "/sfc-controller/v1/status/SFC/chain1"
+	for _, key := range []string{owned, controllerKey} {
+		if err := cnpd.agentKeyOwn(key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !cnpd.agentKeyOwned(owned) || cnpd.agentKeyOwned(other) {
+		t.Errorf("owned: %t, %t, expected: true, false", cnpd.agentKeyOwned(owned), cnpd.agentKeyOwned(other))
+	}
+
+	// a restarted driver reads the records of the keys it wrote
+	restarted := NewSfcCtlrL2CNPDriver("test", broker.NewBroker)
+	if !restarted.agentKeyOwned(owned) {
+		t.Errorf("the restarted driver does not own key: '%s'", owned)
+	}
+	if restarted.agentKeyOwned(controllerKey) {
+		t.Errorf("the controller's own key: '%s' is recorded", controllerKey)
+	}
+
+	restarted.agentKeyDisown(owned)
+	if NewSfcCtlrL2CNPDriver("test", broker.NewBroker).agentKeyOwned(owned) {
+		t.Errorf("the record of the disowned key: '%s' is kept", owned)
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The per port toggles of the elements' bridge domain ports are implemented
+// in this file.  The agent's bridge domain model only carries the bridge wide
+// flood/learn/forward flags, which are the BDParms, so the port_parms of an
+// element are rendered with the agent objects that do apply to a single i/f.
+// A port that must not forward ipv4 or ipv6 gets an ingress acl on its
+// vswitch i/f that denies the family and permits the other one, and a port
+// with static_learning_only is reached through the l2fib entry of its
+// element's mac, see createElementL2FibEntries.  A port that forwards both
+// families has no acl, the acl of a port whose toggles were dropped is
+// removed when the element is wired again.
+
+package l2driver
+
+import (
+	"strconv"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
+)
+
+// createElementPortACL writes the ingress acl of the element's vswitch i/f in the bridge, or removes the one
+// written before when the port forwards both ip families again
+func (cnpd *sfcCtlrL2CNPDriver) createElementPortACL(vnfChainElement *controller.SfcEntity_SfcElement,
+	ifName string) error {
+
+	parms := vnfChainElement.GetPortParms()
+	portACL := &acl.AccessLists_Acl{
+		AclName: "PORT_" + ifName,
+		Interfaces: &acl.AccessLists_Acl_Interfaces{
+			Ingress: []string{ifName},
+		},
+	}
+
+	if parms == nil || (!parms.NoIp4Forward && !parms.NoIp6Forward) {
+		key := cnpd.agentKey(vnfChainElement.EtcdVppSwitchKey, portACL)
+		if key == "" || !cnpd.agentKeyOwned(key) {
+			return nil
+		}
+		log.Infof("createElementPortACL: port: '%s' forwards ipv4 and ipv6 again", ifName)
+		return cnpd.agentDeleteKey(key)
+	}
+
+	// the acl ends with an implicit deny, so the family that is forwarded is permitted explicitly
+	for _, family := range []struct {
+		network string
+		deny    bool
+	}{{"0.0.0.0/0", parms.NoIp4Forward}, {"::/0", parms.NoIp6Forward}} {
+		action := acl.AclAction_PERMIT
+		if family.deny {
+			action = acl.AclAction_DENY
+		}
+		portACL.Rules = append(portACL.Rules, &acl.AccessLists_Acl_Rule{
+			RuleName: "PORT_RULE_" + strconv.Itoa(len(portACL.Rules)),
+			Actions: &acl.AccessLists_Acl_Rule_Actions{
+				AclAction: action,
+			},
+			Matches: &acl.AccessLists_Acl_Rule_Matches{
+				IpRule: &acl.AccessLists_Acl_Rule_Matches_IpRule{
+					Ip: &acl.AccessLists_Acl_Rule_Matches_IpRule_Ip{
+						SourceNetwork:      family.network,
+						DestinationNetwork: family.network,
+					},
+				},
+			},
+		})
+	}
+
+	log.Info("createElementPortACL: acl: ", portACL)
+
+	if err := cnpd.agentPut(vnfChainElement.EtcdVppSwitchKey, portACL); err != nil {
+		log.Error("createElementPortACL: databroker.Store: ", err)
+		return err
+	}
+
+	return nil
+}
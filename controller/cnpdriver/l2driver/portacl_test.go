@@ -0,0 +1,54 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package l2driver
+
+import (
+	"testing"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
+)
+
+func TestElementPortACL(t *testing.T) {
+
+	broker := membroker.New()
+	cnpd := NewSfcCtlrL2CNPDriver("test", broker.NewBroker)
+
+	element := &controller.SfcEntity_SfcElement{Container: "vnf1", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+		PortParms: &controller.PortParms{NoIp6Forward: true}}
+	key := cnpd.agentKey("vswitch", &acl.AccessLists_Acl{AclName: "PORT_IF1"})
+
+	if err := cnpd.createElementPortACL(element, "IF1"); err != nil {
+		t.Fatal(err)
+	}
+	portACL := &acl.AccessLists_Acl{}
+	found, _, err := cnpd.db.GetValue(key, portACL)
+	if err != nil || !found {
+		t.Fatalf("the acl: '%s' is not written: %v", key, err)
+	}
+	if len(portACL.Rules) != 2 || portACL.Rules[0].Actions.AclAction != acl.AclAction_PERMIT ||
+		portACL.Rules[1].Actions.AclAction != acl.AclAction_DENY {
+		t.Errorf("the acl does not permit ipv4 and deny ipv6: %v", portACL)
+	}
+
+	element.PortParms.NoIp6Forward = false
+	if err := cnpd.createElementPortACL(element, "IF1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := broker.Dump("/vnf-agent/")[key]; exists {
+		t.Errorf("the acl of the port that forwards both families is not removed")
+	}
+}
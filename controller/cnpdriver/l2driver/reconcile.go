@@ -18,28 +18,37 @@ package l2driver
 
 import (
 	"fmt"
+	"strings"
 
-	"github.com/ligato/cn-infra/utils/addrs"
+	"github.com/ligato/cn-infra/db/keyval"
 	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
 	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
 	linuxIntf "github.com/ligato/vpp-agent/plugins/linuxplugin/ifplugin/model/interfaces"
 )
 
+var reconcileLog = logs.Logger(logs.Reconcile)
+
 type reconcileCacheType struct {
 	// maps of ETCD entries indexed by ETCD vpp label
 	ifs      map[string]interfaces.Interfaces_Interface
 	lifs     map[string]linuxIntf.LinuxInterfaces_Interface
 	bds      map[string]l2.BridgeDomains_BridgeDomain
 	l3Routes map[string]l3.StaticRoutes_Route
+	xconns   map[string]l2.XConnectPairs_XConnectPair
+	l2fibs   map[string]l2.FibTableEntries_FibTableEntry
+	arps     map[string]l3.ArpTable_ArpTableEntry // the static arp and ipv6 neighbor entries
 
 	// maps of ETCD entries indexed by ETCD key
 	heIDs    map[string]l2driver.HEIDs
 	he2eeIDs map[string]l2driver.HE2EEIDs
 	he2heIDs map[string]l2driver.HE2HEIDs
 	sfcIDs   map[string]l2driver.SFCIDs
+
+	hostIfNames map[string]l2driver.HostIfName
 }
 
 func (cnpd *sfcCtlrL2CNPDriver) initReconcileCache() error {
@@ -48,19 +57,31 @@ func (cnpd *sfcCtlrL2CNPDriver) initReconcileCache() error {
 	cnpd.reconcileBefore.lifs = make(map[string]linuxIntf.LinuxInterfaces_Interface)
 	cnpd.reconcileBefore.bds = make(map[string]l2.BridgeDomains_BridgeDomain)
 	cnpd.reconcileBefore.l3Routes = make(map[string]l3.StaticRoutes_Route)
+	cnpd.reconcileBefore.xconns = make(map[string]l2.XConnectPairs_XConnectPair)
+	cnpd.reconcileBefore.l2fibs = make(map[string]l2.FibTableEntries_FibTableEntry)
+	cnpd.reconcileBefore.arps = make(map[string]l3.ArpTable_ArpTableEntry)
 	cnpd.reconcileBefore.heIDs = make(map[string]l2driver.HEIDs)
 	cnpd.reconcileBefore.he2eeIDs = make(map[string]l2driver.HE2EEIDs)
 	cnpd.reconcileBefore.he2heIDs = make(map[string]l2driver.HE2HEIDs)
 	cnpd.reconcileBefore.sfcIDs = make(map[string]l2driver.SFCIDs)
+	cnpd.reconcileBefore.hostIfNames = make(map[string]l2driver.HostIfName)
 
 	cnpd.reconcileAfter.ifs = make(map[string]interfaces.Interfaces_Interface)
 	cnpd.reconcileAfter.lifs = make(map[string]linuxIntf.LinuxInterfaces_Interface)
 	cnpd.reconcileAfter.bds = make(map[string]l2.BridgeDomains_BridgeDomain)
 	cnpd.reconcileAfter.l3Routes = make(map[string]l3.StaticRoutes_Route)
+	cnpd.reconcileAfter.xconns = make(map[string]l2.XConnectPairs_XConnectPair)
+	cnpd.reconcileAfter.l2fibs = make(map[string]l2.FibTableEntries_FibTableEntry)
+	cnpd.reconcileAfter.arps = make(map[string]l3.ArpTable_ArpTableEntry)
 	cnpd.reconcileAfter.heIDs = make(map[string]l2driver.HEIDs)
 	cnpd.reconcileAfter.he2eeIDs = make(map[string]l2driver.HE2EEIDs)
 	cnpd.reconcileAfter.he2heIDs = make(map[string]l2driver.HE2HEIDs)
 	cnpd.reconcileAfter.sfcIDs = make(map[string]l2driver.SFCIDs)
+	cnpd.reconcileAfter.hostIfNames = make(map[string]l2driver.HostIfName)
+
+	cnpd.streamedLabels = make(map[string]struct{})
+	cnpd.reconcileLabels = make(map[string]struct{})
+	cnpd.auditEntities = make(map[string]string)
 
 	return nil
 }
@@ -92,28 +113,98 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileStart(vppEtcdLabels map[string]struct{}
 	// reconcile resync is to ONLY make changes if there are new and/or obselete configs.  Existing configs should
 	// reamin un-affected by the resync process.
 
-	log.Info("ReconcileStart: begin ...")
-	defer log.Info("ReconcileStart: exit ...")
+	reconcileLog.Info("ReconcileStart: begin ...")
+	defer reconcileLog.Info("ReconcileStart: exit ...")
+
+	// reconcile is also run at runtime, ie config rollback, so the config is rendered from scratch
+	cnpd.initL2CNPCache()
+
+	cnpd.reconcileMutex.Lock()
+	defer cnpd.reconcileMutex.Unlock()
+	cnpd.initReconcileCache()
 
 	cnpd.reconcileStateSet(true)
 
+	// the records of the owned keys are re-read, another controller may have taken keys over
+	cnpd.ownedKeysMutex.Lock()
+	cnpd.ownedKeys = nil
+	cnpd.ownedKeysMutex.Unlock()
+
 	for vppEtdLabel := range vppEtcdLabels {
+		cnpd.reconcileLabels[vppEtdLabel] = struct{}{}
+		// the agent trees of very large hosts are compared with the after cache as they are read at ReconcileEnd
+		if cnpd.reconcileStreamed(vppEtdLabel) {
+			reconcileLog.Infof("ReconcileStart: vpp label: '%s': agent tree is streamed at ReconcileEnd", vppEtdLabel)
+			cnpd.streamedLabels[vppEtdLabel] = struct{}{}
+			continue
+		}
 		cnpd.reconcileLoadInterfacesIntoCache(vppEtdLabel)
 		cnpd.reconcileLoadLinuxInterfacesIntoCache(vppEtdLabel)
 		cnpd.reconcileLoadBridgeDomainsIntoCache(vppEtdLabel)
 		cnpd.reconcileLoadStaticRoutesIntoCache(vppEtdLabel)
+		cnpd.reconcileLoadXConnectsIntoCache(vppEtdLabel)
+		cnpd.reconcileLoadL2FibsIntoCache(vppEtdLabel)
+		cnpd.reconcileLoadStaticArpsIntoCache(vppEtdLabel)
 	}
 
 	cnpd.reconcileLoadHEIDsIntoCache()
 	cnpd.reconcileLoadHE2EEIDsIntoCache()
 	cnpd.reconcileLoadHE2HEIDsIntoCache()
 	cnpd.reconcileLoadSFCIDsIntoCache()
+	cnpd.reconcileLoadHostIfNamesIntoCache()
 
 	cnpd.sequencerInitFromReconcileCache()
 
 	return nil
 }
 
+// ReconcileEndForLabel is ReconcileEnd for a reconcile started with the single vpp label, the entries rendered
+// for the other labels are dropped from the after cache so only the label's agent tree is touched
+func (cnpd *sfcCtlrL2CNPDriver) ReconcileEndForLabel(vppLabel string) error {
+
+	prefix := utils.GetVppAgentPrefix() + vppLabel + "/"
+
+	cnpd.reconcileMutex.Lock()
+	for key := range cnpd.reconcileAfter.ifs {
+		if !strings.HasPrefix(key, prefix) {
+			delete(cnpd.reconcileAfter.ifs, key)
+		}
+	}
+	for key := range cnpd.reconcileAfter.lifs {
+		if !strings.HasPrefix(key, prefix) {
+			delete(cnpd.reconcileAfter.lifs, key)
+		}
+	}
+	for key := range cnpd.reconcileAfter.bds {
+		if !strings.HasPrefix(key, prefix) {
+			delete(cnpd.reconcileAfter.bds, key)
+		}
+	}
+	for key := range cnpd.reconcileAfter.l3Routes {
+		if !strings.HasPrefix(key, prefix) {
+			delete(cnpd.reconcileAfter.l3Routes, key)
+		}
+	}
+	for key := range cnpd.reconcileAfter.xconns {
+		if !strings.HasPrefix(key, prefix) {
+			delete(cnpd.reconcileAfter.xconns, key)
+		}
+	}
+	for key := range cnpd.reconcileAfter.l2fibs {
+		if !strings.HasPrefix(key, prefix) {
+			delete(cnpd.reconcileAfter.l2fibs, key)
+		}
+	}
+	for key := range cnpd.reconcileAfter.arps {
+		if !strings.HasPrefix(key, prefix) {
+			delete(cnpd.reconcileAfter.arps, key)
+		}
+	}
+	cnpd.reconcileMutex.Unlock()
+
+	return cnpd.ReconcileEnd()
+}
+
 func (cnpd *sfcCtlrL2CNPDriver) reconcileStateSet(state bool) {
 	cnpd.reconcileInProgress = state
 }
@@ -121,11 +212,30 @@ func (cnpd *sfcCtlrL2CNPDriver) reconcileStateSet(state bool) {
 // Perform end processing for the reconcile of the CNP datastore
 func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 
-	log.Info("ReconcileEnd: begin ...")
-	log.Infof("ReconcileEnd: reconcileBefore", cnpd.reconcileBefore)
-	log.Infof("ReconcileEnd: reconcileAfter", cnpd.reconcileAfter)
+	reconcileLog.Info("ReconcileEnd: begin ...")
 	defer cnpd.reconcileStateSet(false)
-	defer log.Info("ReconcileEnd: exit ...")
+	defer reconcileLog.Info("ReconcileEnd: exit ...")
+
+	return cnpd.reconcileWriteBack()
+}
+
+// reconcileWriteBack writes the differences of the before and after caches holding the locks of the agents
+// they were loaded from, and rendered for
+func (cnpd *sfcCtlrL2CNPDriver) reconcileWriteBack() error {
+
+	cnpd.reconcileMutex.Lock()
+	defer cnpd.reconcileMutex.Unlock()
+	defer cnpd.lockAgents(cnpd.reconcileAgentKeys())()
+
+	reconcileLog.Infof("ReconcileEnd: reconcileBefore: %v", cnpd.reconcileBefore)
+	reconcileLog.Infof("ReconcileEnd: reconcileAfter: %v", cnpd.reconcileAfter)
+
+	if err := cnpd.reconcileAudit(); err != nil {
+		return err
+	}
+	if err := cnpd.reconcileStreamWriteBack(); err != nil {
+		return err
+	}
 
 	// 1) For each entry in the before cache, look it up in the after cache
 	//    if it is not in the after cache, delete it from ETCD, and from the after cache
@@ -140,7 +250,8 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 		afterIF, existsInAfterCache := cnpd.reconcileAfter.ifs[key]
 		if !existsInAfterCache {
 			exists, err := cnpd.db.Delete(key)
-			log.Info("ReconcileEnd: remove i/f key from etcd and reconcile cache: ", key, exists, err)
+			cnpd.agentKeyDisown(key)
+			reconcileLog.Info("ReconcileEnd: remove i/f key from etcd and reconcile cache: ", key, exists, err)
 			delete(cnpd.reconcileAfter.ifs, key)
 		} else {
 			if beforeIF.String() == afterIF.String() {
@@ -151,10 +262,10 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 	// Interfaces: now post process the after cache
 	for key := range cnpd.reconcileAfter.ifs {
 		afterIF := cnpd.reconcileAfter.ifs[key]
-		log.Info("ReconcileEnd: add i/f key to etcd: ", key, afterIF)
-		err := cnpd.db.Put(key, &afterIF)
+		reconcileLog.Info("ReconcileEnd: add i/f key to etcd: ", key, afterIF)
+		err := cnpd.agentPutKey(key, &afterIF)
 		if err != nil {
-			log.Error("ReconcileEnd: error storing i/f: '%s'", key, err)
+			reconcileLog.Errorf("ReconcileEnd: error storing i/f: '%s': %s", key, err)
 			return err
 		}
 	}
@@ -165,7 +276,8 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 		afterIF, existsInAfterCache := cnpd.reconcileAfter.lifs[key]
 		if !existsInAfterCache {
 			exists, err := cnpd.db.Delete(key)
-			log.Info("ReconcileEnd: remove linux i/f key from etcd and reconcile cache: ", key, exists, err)
+			cnpd.agentKeyDisown(key)
+			reconcileLog.Info("ReconcileEnd: remove linux i/f key from etcd and reconcile cache: ", key, exists, err)
 			delete(cnpd.reconcileAfter.ifs, key)
 		} else {
 			if beforeIF.String() == afterIF.String() {
@@ -176,10 +288,10 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 	// Linux Interfaces: now post process the after cache
 	for key := range cnpd.reconcileAfter.lifs {
 		afterIF := cnpd.reconcileAfter.lifs[key]
-		log.Info("ReconcileEnd: add linux i/f key to etcd: ", key, afterIF)
-		err := cnpd.db.Put(key, &afterIF)
+		reconcileLog.Info("ReconcileEnd: add linux i/f key to etcd: ", key, afterIF)
+		err := cnpd.agentPutKey(key, &afterIF)
 		if err != nil {
-			log.Error("ReconcileEnd: error storing i/f: '%s'", key, err)
+			reconcileLog.Errorf("ReconcileEnd: error storing i/f: '%s': %s", key, err)
 			return err
 		}
 	}
@@ -190,7 +302,8 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 		afterBD, existsInAfterCache := cnpd.reconcileAfter.bds[key]
 		if !existsInAfterCache {
 			exists, err := cnpd.db.Delete(key)
-			log.Info("ReconcileEnd: remove BD key from etcd and reconcile cache: ", key, exists, err)
+			cnpd.agentKeyDisown(key)
+			reconcileLog.Info("ReconcileEnd: remove BD key from etcd and reconcile cache: ", key, exists, err)
 			delete(cnpd.reconcileAfter.bds, key)
 		} else {
 			cnpd.sortBridgedInterfaces(beforeBD.Interfaces)
@@ -203,10 +316,10 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 	// Bridge Domains: now post process the after cache
 	for key := range cnpd.reconcileAfter.bds {
 		afterBD := cnpd.reconcileAfter.bds[key]
-		log.Info("ReconcileEnd: add BD key to etcd: ", key, afterBD)
-		err := cnpd.db.Put(key, &afterBD)
+		reconcileLog.Info("ReconcileEnd: add BD key to etcd: ", key, afterBD)
+		err := cnpd.agentPutKey(key, &afterBD)
 		if err != nil {
-			log.Error("ReconcileEnd: error storing BD: '%s'", key, err)
+			reconcileLog.Errorf("ReconcileEnd: error storing BD: '%s': %s", key, err)
 			return err
 		}
 	}
@@ -217,33 +330,104 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 		afterSR, existsInAfterCache := cnpd.reconcileAfter.l3Routes[key]
 		if !existsInAfterCache {
 			exists, err := cnpd.db.Delete(key)
-			log.Info("ReconcileEnd: remove static route key from etcd and reconcile cache: ", key, exists, err)
-			log.Info("ReconcileEnd: remove static route before entry: ", beforeSR)
+			cnpd.agentKeyDisown(key)
+			reconcileLog.Info("ReconcileEnd: remove static route key from etcd and reconcile cache: ", key, exists, err)
+			reconcileLog.Info("ReconcileEnd: remove static route before entry: ", beforeSR)
 			delete(cnpd.reconcileAfter.l3Routes, key)
 		} else {
-			// turns out it is possible and OK to have duplicate static routes so recheck the route
-			// body and weed out the fields we care about to discern these routes are the same
-			// the description is filled in which can be different for the various duplicates
-			if beforeSR.DstIpAddr == afterSR.DstIpAddr &&
-				beforeSR.NextHopAddr == afterSR.NextHopAddr &&
-				beforeSR.OutgoingInterface == afterSR.OutgoingInterface &&
-				beforeSR.Preference == afterSR.Preference &&
-				beforeSR.VrfId == afterSR.VrfId &&
-				beforeSR.Weight == afterSR.Weight {
+			if reconcileStaticRouteString(&beforeSR) == reconcileStaticRouteString(&afterSR) {
 				delete(cnpd.reconcileAfter.l3Routes, key)
 			} else {
-				log.Info("ReconcileEnd: before != after ... beforeSR: ", beforeSR)
-				log.Info("ReconcileEnd: before != after ... afterSR: ", afterSR)
+				reconcileLog.Info("ReconcileEnd: before != after ... beforeSR: ", beforeSR)
+				reconcileLog.Info("ReconcileEnd: before != after ... afterSR: ", afterSR)
 			}
 		}
 	}
 	// Static Routes: now post process the after cache
 	for key := range cnpd.reconcileAfter.l3Routes {
 		afterSR := cnpd.reconcileAfter.l3Routes[key]
-		log.Info("ReconcileEnd: add static route key to etcd: ", key, afterSR)
-		err := cnpd.db.Put(key, &afterSR)
+		reconcileLog.Info("ReconcileEnd: add static route key to etcd: ", key, afterSR)
+		err := cnpd.agentPutKey(key, &afterSR)
 		if err != nil {
-			log.Error("ReconcileEnd: error storing static route: '%s'", key, err)
+			reconcileLog.Errorf("ReconcileEnd: error storing static route: '%s': %s", key, err)
+			return err
+		}
+	}
+
+	// XConnects: traverse the before cache
+	for key := range cnpd.reconcileBefore.xconns {
+		beforeXC := cnpd.reconcileBefore.xconns[key]
+		afterXC, existsInAfterCache := cnpd.reconcileAfter.xconns[key]
+		if !existsInAfterCache {
+			exists, err := cnpd.db.Delete(key)
+			cnpd.agentKeyDisown(key)
+			reconcileLog.Info("ReconcileEnd: remove xconnect key from etcd and reconcile cache: ", key, exists, err)
+			delete(cnpd.reconcileAfter.xconns, key)
+		} else {
+			if beforeXC.String() == afterXC.String() {
+				delete(cnpd.reconcileAfter.xconns, key)
+			}
+		}
+	}
+	// XConnects: now post process the after cache
+	for key := range cnpd.reconcileAfter.xconns {
+		afterXC := cnpd.reconcileAfter.xconns[key]
+		reconcileLog.Info("ReconcileEnd: add xconnect key to etcd: ", key, afterXC)
+		err := cnpd.agentPutKey(key, &afterXC)
+		if err != nil {
+			reconcileLog.Errorf("ReconcileEnd: error storing xconnect: '%s': %s", key, err)
+			return err
+		}
+	}
+
+	// L2 FIB entries: traverse the before cache
+	for key := range cnpd.reconcileBefore.l2fibs {
+		beforeFib := cnpd.reconcileBefore.l2fibs[key]
+		afterFib, existsInAfterCache := cnpd.reconcileAfter.l2fibs[key]
+		if !existsInAfterCache {
+			exists, err := cnpd.db.Delete(key)
+			cnpd.agentKeyDisown(key)
+			reconcileLog.Info("ReconcileEnd: remove l2fib key from etcd and reconcile cache: ", key, exists, err)
+			delete(cnpd.reconcileAfter.l2fibs, key)
+		} else {
+			if beforeFib.String() == afterFib.String() {
+				delete(cnpd.reconcileAfter.l2fibs, key)
+			}
+		}
+	}
+	// L2 FIB entries: now post process the after cache
+	for key := range cnpd.reconcileAfter.l2fibs {
+		afterFib := cnpd.reconcileAfter.l2fibs[key]
+		reconcileLog.Info("ReconcileEnd: add l2fib key to etcd: ", key, afterFib)
+		err := cnpd.agentPutKey(key, &afterFib)
+		if err != nil {
+			reconcileLog.Errorf("ReconcileEnd: error storing l2fib: '%s': %s", key, err)
+			return err
+		}
+	}
+
+	// Static ARP entries: traverse the before cache
+	for key := range cnpd.reconcileBefore.arps {
+		beforeArp := cnpd.reconcileBefore.arps[key]
+		afterArp, existsInAfterCache := cnpd.reconcileAfter.arps[key]
+		if !existsInAfterCache {
+			exists, err := cnpd.db.Delete(key)
+			cnpd.agentKeyDisown(key)
+			reconcileLog.Info("ReconcileEnd: remove static arp key from etcd and reconcile cache: ", key, exists, err)
+			delete(cnpd.reconcileAfter.arps, key)
+		} else {
+			if beforeArp.String() == afterArp.String() {
+				delete(cnpd.reconcileAfter.arps, key)
+			}
+		}
+	}
+	// Static ARP entries: now post process the after cache
+	for key := range cnpd.reconcileAfter.arps {
+		afterArp := cnpd.reconcileAfter.arps[key]
+		reconcileLog.Info("ReconcileEnd: add static arp key to etcd: ", key, afterArp)
+		err := cnpd.agentPutKey(key, &afterArp)
+		if err != nil {
+			reconcileLog.Errorf("ReconcileEnd: error storing static arp: '%s': %s", key, err)
 			return err
 		}
 	}
@@ -254,7 +438,7 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 		afterHEID, existsInAfterCache := cnpd.reconcileAfter.heIDs[key]
 		if !existsInAfterCache {
 			exists, err := cnpd.db.Delete(key)
-			log.Info("ReconcileEnd: remove HE ID key from etcd and reconcile cache: ", key, exists, err)
+			reconcileLog.Info("ReconcileEnd: remove HE ID key from etcd and reconcile cache: ", key, exists, err)
 			delete(cnpd.reconcileAfter.heIDs, key)
 		} else {
 			if beforeHEID.String() == afterHEID.String() {
@@ -265,10 +449,10 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 	// HE IDs: now post process the after cache
 	for key := range cnpd.reconcileAfter.heIDs {
 		afterHEID := cnpd.reconcileAfter.heIDs[key]
-		log.Info("ReconcileEnd: add HE ID key to etcd: ", key, afterHEID)
+		reconcileLog.Info("ReconcileEnd: add HE ID key to etcd: ", key, afterHEID)
 		err := cnpd.db.Put(key, &afterHEID)
 		if err != nil {
-			log.Error("ReconcileEnd: error storing HE ID: '%s'", key, err)
+			reconcileLog.Errorf("ReconcileEnd: error storing HE ID: '%s': %s", key, err)
 			return err
 		}
 	}
@@ -279,7 +463,7 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 		afterHE2EEID, existsInAfterCache := cnpd.reconcileAfter.he2eeIDs[key]
 		if !existsInAfterCache {
 			exists, err := cnpd.db.Delete(key)
-			log.Info("ReconcileEnd: remove HE2EE ID key from etcd and reconcile cache: ", key, exists, err)
+			reconcileLog.Info("ReconcileEnd: remove HE2EE ID key from etcd and reconcile cache: ", key, exists, err)
 			delete(cnpd.reconcileAfter.he2eeIDs, key)
 		} else {
 			if beforeHE2EEID.String() == afterHE2EEID.String() {
@@ -290,10 +474,10 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 	// HE to EE IDs: now post process the after cache
 	for key := range cnpd.reconcileAfter.he2eeIDs {
 		afterHE2EEID := cnpd.reconcileAfter.he2eeIDs[key]
-		log.Info("ReconcileEnd: add HE2EE ID key to etcd: ", key, afterHE2EEID)
+		reconcileLog.Info("ReconcileEnd: add HE2EE ID key to etcd: ", key, afterHE2EEID)
 		err := cnpd.db.Put(key, &afterHE2EEID)
 		if err != nil {
-			log.Error("ReconcileEnd: error storing HE2EE ID: '%s'", key, err)
+			reconcileLog.Errorf("ReconcileEnd: error storing HE2EE ID: '%s': %s", key, err)
 			return err
 		}
 	}
@@ -304,7 +488,7 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 		afterHE2HEID, existsInAfterCache := cnpd.reconcileAfter.he2heIDs[key]
 		if !existsInAfterCache {
 			exists, err := cnpd.db.Delete(key)
-			log.Info("ReconcileEnd: remove HE2HE ID key from etcd and reconcile cache: ", key, exists, err)
+			reconcileLog.Info("ReconcileEnd: remove HE2HE ID key from etcd and reconcile cache: ", key, exists, err)
 			delete(cnpd.reconcileAfter.he2heIDs, key)
 		} else {
 			if beforeHE2HEID.String() == afterHE2HEID.String() {
@@ -315,10 +499,10 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 	// HE to HE IDs: now post process the after cache
 	for key := range cnpd.reconcileAfter.he2heIDs {
 		afterHE2HEID := cnpd.reconcileAfter.he2heIDs[key]
-		log.Info("ReconcileEnd: add HE2HE ID key to etcd: ", key, afterHE2HEID)
+		reconcileLog.Info("ReconcileEnd: add HE2HE ID key to etcd: ", key, afterHE2HEID)
 		err := cnpd.db.Put(key, &afterHE2HEID)
 		if err != nil {
-			log.Error("ReconcileEnd: error storing HE2HE ID: '%s'", key, err)
+			reconcileLog.Errorf("ReconcileEnd: error storing HE2HE ID: '%s': %s", key, err)
 			return err
 		}
 	}
@@ -329,7 +513,7 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 		afterSFCID, existsInAfterCache := cnpd.reconcileAfter.sfcIDs[key]
 		if !existsInAfterCache {
 			exists, err := cnpd.db.Delete(key)
-			log.Info("ReconcileEnd: remove SFC ID key from etcd and reconcile cache: ", key, exists, err)
+			reconcileLog.Info("ReconcileEnd: remove SFC ID key from etcd and reconcile cache: ", key, exists, err)
 			delete(cnpd.reconcileAfter.sfcIDs, key)
 		} else {
 			if beforeSFCID.String() == afterSFCID.String() {
@@ -340,146 +524,228 @@ func (cnpd *sfcCtlrL2CNPDriver) ReconcileEnd() error {
 	// SFC IDs: now post process the after cache
 	for key := range cnpd.reconcileAfter.sfcIDs {
 		afterSFCID := cnpd.reconcileAfter.sfcIDs[key]
-		log.Info("ReconcileEnd: add SFC ID key to etcd: ", key, afterSFCID)
+		reconcileLog.Info("ReconcileEnd: add SFC ID key to etcd: ", key, afterSFCID)
 		err := cnpd.db.Put(key, &afterSFCID)
 		if err != nil {
-			log.Error("ReconcileEnd: error storing SFC ID: '%s'", key, err)
+			reconcileLog.Errorf("ReconcileEnd: error storing SFC ID: '%s': %s", key, err)
 			return err
 		}
 	}
 
+	// host i/f names: traverse the before cache, the names rendered again were already written
+	for key := range cnpd.reconcileBefore.hostIfNames {
+		if _, existsInAfterCache := cnpd.reconcileAfter.hostIfNames[key]; !existsInAfterCache {
+			exists, err := cnpd.db.Delete(key)
+			reconcileLog.Info("ReconcileEnd: remove host i/f name key from etcd: ", key, exists, err)
+		}
+	}
+	// the registry is reloaded without the removed names
+	cnpd.hostIfNames = nil
+
 	return nil
 }
 
 func (cnpd *sfcCtlrL2CNPDriver) reconcileBridgeDomain(etcdVppSwitchKey string, bd *l2.BridgeDomains_BridgeDomain) {
-	bdKey := utils.L2BridgeDomainKey(etcdVppSwitchKey, bd.Name)
+	cnpd.reconcileMutex.Lock()
+	defer cnpd.reconcileMutex.Unlock()
+
+	bdKey := cnpd.agentKey(etcdVppSwitchKey, bd)
 	cnpd.reconcileAfter.bds[bdKey] = *bd
+	cnpd.renderedKeys = append(cnpd.renderedKeys, bdKey)
 }
 
 func (cnpd *sfcCtlrL2CNPDriver) reconcileInterface(etcdVppSwitchKey string, currIf *interfaces.Interfaces_Interface) {
-	ifKey := utils.InterfaceKey(etcdVppSwitchKey, currIf.Name)
+	cnpd.reconcileMutex.Lock()
+	defer cnpd.reconcileMutex.Unlock()
+
+	ifKey := cnpd.agentKey(etcdVppSwitchKey, currIf)
 	cnpd.reconcileAfter.ifs[ifKey] = *currIf
+	cnpd.auditRecord(ifKey)
+	cnpd.renderedKeys = append(cnpd.renderedKeys, ifKey)
 }
 
 func (cnpd *sfcCtlrL2CNPDriver) reconcileLinuxInterface(etcdPrefix string, ifname string,
 	currIf *linuxIntf.LinuxInterfaces_Interface) {
 
-	ifKey := utils.LinuxInterfaceKey(etcdPrefix, ifname)
+	cnpd.reconcileMutex.Lock()
+	defer cnpd.reconcileMutex.Unlock()
+
+	ifKey := cnpd.agentKey(etcdPrefix, currIf)
 	cnpd.reconcileAfter.lifs[ifKey] = *currIf
+	cnpd.auditRecord(ifKey)
+	cnpd.renderedKeys = append(cnpd.renderedKeys, ifKey)
 }
 
 func (cnpd *sfcCtlrL2CNPDriver) reconcileStaticRoute(etcdPrefix string, sr *l3.StaticRoutes_Route) {
+	cnpd.reconcileMutex.Lock()
+	defer cnpd.reconcileMutex.Unlock()
 
-	destIPAddr, _, _ := addrs.ParseIPWithPrefix(sr.DstIpAddr)
-	key := utils.L3RouteKey(etcdPrefix, sr.VrfId, destIPAddr, sr.NextHopAddr)
+	key := cnpd.agentKey(etcdPrefix, sr)
 	cnpd.reconcileAfter.l3Routes[key] = *sr
+	cnpd.auditRecord(key)
+	cnpd.renderedKeys = append(cnpd.renderedKeys, key)
 }
 
-func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadInterfacesIntoCache(etcdVppLabel string) error {
+// turns out it is possible and OK to have duplicate static routes so only the fields we care about
+// are used to discern these routes are the same, the description is filled in which can be different
+// for the various duplicates
+func reconcileStaticRouteString(sr *l3.StaticRoutes_Route) string {
+	return fmt.Sprintf("%s/%s/%s/%d/%d/%d", sr.DstIpAddr, sr.NextHopAddr, sr.OutgoingInterface,
+		sr.Preference, sr.VrfId, sr.Weight)
+}
 
-	kvi, err := cnpd.db.ListValues(utils.InterfacePrefixKey(etcdVppLabel))
-	if err != nil {
-		log.Fatal(err)
-		return nil
-	}
+func (cnpd *sfcCtlrL2CNPDriver) reconcileXConnect(etcdPrefix string, xconn *l2.XConnectPairs_XConnectPair) {
+	cnpd.reconcileMutex.Lock()
+	defer cnpd.reconcileMutex.Unlock()
 
-	for {
-		kv, allReceived := kvi.GetNext()
-		if allReceived {
-			return nil
-		}
-		entry := &interfaces.Interfaces_Interface{}
-		err := kv.GetValue(entry)
-		if err != nil {
-			log.Fatal(err)
-			return nil
-		}
+	key := cnpd.agentKey(etcdPrefix, xconn)
+	cnpd.reconcileAfter.xconns[key] = *xconn
+	cnpd.renderedKeys = append(cnpd.renderedKeys, key)
+}
 
-		fmt.Println("reconcileLoadInterfacesIntoCache: adding Interface: ", etcdVppLabel, kv.GetKey(), entry)
-		cnpd.reconcileBefore.ifs[kv.GetKey()] = *entry
-	}
+func (cnpd *sfcCtlrL2CNPDriver) reconcileL2FibEntry(etcdPrefix string, l2fib *l2.FibTableEntries_FibTableEntry) {
+	cnpd.reconcileMutex.Lock()
+	defer cnpd.reconcileMutex.Unlock()
+
+	key := cnpd.agentKey(etcdPrefix, l2fib)
+	cnpd.reconcileAfter.l2fibs[key] = *l2fib
+	cnpd.renderedKeys = append(cnpd.renderedKeys, key)
 }
 
-func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadLinuxInterfacesIntoCache(etcdVppLabel string) error {
+func (cnpd *sfcCtlrL2CNPDriver) reconcileStaticArpEntry(etcdPrefix string, ae *l3.ArpTable_ArpTableEntry) {
+	cnpd.reconcileMutex.Lock()
+	defer cnpd.reconcileMutex.Unlock()
 
-	kvi, err := cnpd.db.ListValues(utils.LinuxInterfacePrefixKey(etcdVppLabel))
-	if err != nil {
-		log.Fatal(err)
-		return nil
-	}
+	key := cnpd.agentKey(etcdPrefix, ae)
+	cnpd.reconcileAfter.arps[key] = *ae
+	cnpd.renderedKeys = append(cnpd.renderedKeys, key)
+}
 
-	for {
-		kv, allReceived := kvi.GetNext()
-		if allReceived {
-			return nil
-		}
-		entry := &linuxIntf.LinuxInterfaces_Interface{}
-		err := kv.GetValue(entry)
-		if err != nil {
-			log.Fatal(err)
-			return nil
-		}
-		fmt.Println("reconcileLoadLinuxInterfacesIntoCache: adding linux nterface: ",
-			etcdVppLabel, kv.GetKey(), entry)
-		cnpd.reconcileBefore.lifs[kv.GetKey()] = *entry
+// reconcileIsL2FibKey is whether the key under the label's bridge domains is an l2fib entry, the entries of a
+// bridge domain are kept under its key
+func reconcileIsL2FibKey(etcdVppLabel string, key string) bool {
+	isFibKey, _, _ := l2.ParseFibKey(strings.TrimPrefix(key, utils.GetVppAgentPrefix()+etcdVppLabel+"/"))
+	return isFibKey
+}
 
-	}
+func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadInterfacesIntoCache(etcdVppLabel string) error {
+
+	return cnpd.agentLoad(etcdVppLabel, &interfaces.Interfaces_Interface{},
+		func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter) {
+			entry := &interfaces.Interfaces_Interface{}
+			if err := adapter.Decode(kv, entry); err != nil {
+				reconcileLog.Fatal(err)
+				return
+			}
+			reconcileLog.Debugf("reconcileLoadInterfacesIntoCache: adding interface: '%s', key: '%s', %v",
+				etcdVppLabel, key, entry)
+			auditStrip(entry)
+			cnpd.reconcileBefore.ifs[key] = *entry
+		})
 }
 
-func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadBridgeDomainsIntoCache(etcdVppLabel string) error {
+func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadLinuxInterfacesIntoCache(etcdVppLabel string) error {
 
-	kvi, err := cnpd.db.ListValues(utils.L2BridgeDomainKeyPrefix(etcdVppLabel))
-	if err != nil {
-		log.Fatal(err)
-		return nil
-	}
+	return cnpd.agentLoad(etcdVppLabel, &linuxIntf.LinuxInterfaces_Interface{},
+		func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter) {
+			entry := &linuxIntf.LinuxInterfaces_Interface{}
+			if err := adapter.Decode(kv, entry); err != nil {
+				reconcileLog.Fatal(err)
+				return
+			}
+			reconcileLog.Debugf("reconcileLoadLinuxInterfacesIntoCache: adding linux interface: '%s', key: '%s', %v",
+				etcdVppLabel, key, entry)
+			auditStrip(entry)
+			cnpd.reconcileBefore.lifs[key] = *entry
+		})
+}
 
-	for {
-		kv, allReceived := kvi.GetNext()
-		if allReceived {
-			return nil
-		}
-		entry := &l2.BridgeDomains_BridgeDomain{}
-		err := kv.GetValue(entry)
-		if err != nil {
-			log.Fatal(err)
-			return nil
-		}
-		fmt.Println("reconcileLoadBridgeDomainsIntoCache: adding bridge doamin: ",
-			etcdVppLabel, kv.GetKey(), entry)
-		cnpd.reconcileBefore.bds[kv.GetKey()] = *entry
-	}
+func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadBridgeDomainsIntoCache(etcdVppLabel string) error {
+
+	return cnpd.agentLoad(etcdVppLabel, &l2.BridgeDomains_BridgeDomain{},
+		func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter) {
+			if reconcileIsL2FibKey(etcdVppLabel, key) {
+				return
+			}
+			entry := &l2.BridgeDomains_BridgeDomain{}
+			if err := adapter.Decode(kv, entry); err != nil {
+				reconcileLog.Fatal(err)
+				return
+			}
+			reconcileLog.Debugf("reconcileLoadBridgeDomainsIntoCache: adding bridge domain: '%s', key: '%s', %v",
+				etcdVppLabel, key, entry)
+			cnpd.reconcileBefore.bds[key] = *entry
+		})
 }
 
 func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadStaticRoutesIntoCache(etcdVppLabel string) error {
 
-	kvi, err := cnpd.db.ListValues(utils.L3RouteKeyPrefix(etcdVppLabel))
-	if err != nil {
-		log.Fatal(err)
-		return nil
-	}
+	return cnpd.agentLoad(etcdVppLabel, &l3.StaticRoutes_Route{},
+		func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter) {
+			entry := &l3.StaticRoutes_Route{}
+			if err := adapter.Decode(kv, entry); err != nil {
+				reconcileLog.Fatal(err)
+				return
+			}
+			reconcileLog.Debugf("reconcileLoadStaticRoutesIntoCache: adding static route: '%s', key: '%s', %v",
+				etcdVppLabel, key, entry)
+			auditStrip(entry)
+			cnpd.reconcileBefore.l3Routes[key] = *entry
+		})
+}
 
-	for {
-		kv, allReceived := kvi.GetNext()
-		if allReceived {
-			return nil
-		}
-		entry := &l3.StaticRoutes_Route{}
-		err := kv.GetValue(entry)
-		if err != nil {
-			log.Fatal(err)
-			return nil
-		}
-		fmt.Println("reconcileLoadStaticRoutesIntoCache: adding static route: ", etcdVppLabel, kv.GetKey(), entry)
-		cnpd.reconcileBefore.l3Routes[kv.GetKey()] = *entry
-	}
+func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadXConnectsIntoCache(etcdVppLabel string) error {
+
+	return cnpd.agentLoad(etcdVppLabel, &l2.XConnectPairs_XConnectPair{},
+		func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter) {
+			entry := &l2.XConnectPairs_XConnectPair{}
+			if err := adapter.Decode(kv, entry); err != nil {
+				reconcileLog.Fatal(err)
+				return
+			}
+			reconcileLog.Debugf("reconcileLoadXConnectsIntoCache: adding xconnect: '%s', key: '%s', %v",
+				etcdVppLabel, key, entry)
+			cnpd.reconcileBefore.xconns[key] = *entry
+		})
+}
+
+func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadL2FibsIntoCache(etcdVppLabel string) error {
+
+	return cnpd.agentLoad(etcdVppLabel, &l2.FibTableEntries_FibTableEntry{},
+		func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter) {
+			if !reconcileIsL2FibKey(etcdVppLabel, key) {
+				return
+			}
+			entry := &l2.FibTableEntries_FibTableEntry{}
+			if err := adapter.Decode(kv, entry); err != nil {
+				reconcileLog.Fatal(err)
+				return
+			}
+			reconcileLog.Debugf("reconcileLoadL2FibsIntoCache: adding l2fib: '%s', key: '%s', %v", etcdVppLabel, key, entry)
+			cnpd.reconcileBefore.l2fibs[key] = *entry
+		})
+}
+
+func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadStaticArpsIntoCache(etcdVppLabel string) error {
+
+	return cnpd.agentLoad(etcdVppLabel, &l3.ArpTable_ArpTableEntry{},
+		func(key string, kv keyval.ProtoKeyVal, adapter AgentAdapter) {
+			entry := &l3.ArpTable_ArpTableEntry{}
+			if err := adapter.Decode(kv, entry); err != nil {
+				reconcileLog.Fatal(err)
+				return
+			}
+			reconcileLog.Debugf("reconcileLoadStaticArpsIntoCache: adding static arp: '%s', key: '%s', %v",
+				etcdVppLabel, key, entry)
+			cnpd.reconcileBefore.arps[key] = *entry
+		})
 }
 
 func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadHEIDsIntoCache() error {
 
 	kvi, err := cnpd.db.ListValues(l2driver.HEIDsKeyPrefix())
 	if err != nil {
-		log.Fatal(err)
+		reconcileLog.Fatal(err)
 		return nil
 	}
 
@@ -491,10 +757,10 @@ func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadHEIDsIntoCache() error {
 		entry := &l2driver.HEIDs{}
 		err := kv.GetValue(entry)
 		if err != nil {
-			log.Fatal(err)
+			reconcileLog.Fatal(err)
 			return nil
 		}
-		fmt.Println("reconcileLoadHEIDsIntoCache: adding HE IDs: ", kv.GetKey(), entry)
+		reconcileLog.Debugf("reconcileLoadHEIDsIntoCache: adding HE IDs: '%s', %v", kv.GetKey(), entry)
 		cnpd.reconcileBefore.heIDs[kv.GetKey()] = *entry
 	}
 }
@@ -503,7 +769,7 @@ func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadHE2EEIDsIntoCache() error {
 
 	kvi, err := cnpd.db.ListValues(l2driver.HE2EEIDsKeyPrefix())
 	if err != nil {
-		log.Fatal(err)
+		reconcileLog.Fatal(err)
 		return nil
 	}
 
@@ -515,10 +781,10 @@ func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadHE2EEIDsIntoCache() error {
 		entry := &l2driver.HE2EEIDs{}
 		err := kv.GetValue(entry)
 		if err != nil {
-			log.Fatal(err)
+			reconcileLog.Fatal(err)
 			return nil
 		}
-		fmt.Println("reconcileLoadHE2EEIDsIntoCache: adding HE to EE IDs: ", kv.GetKey(), entry)
+		reconcileLog.Debugf("reconcileLoadHE2EEIDsIntoCache: adding HE to EE IDs: '%s', %v", kv.GetKey(), entry)
 		cnpd.reconcileBefore.he2eeIDs[kv.GetKey()] = *entry
 	}
 }
@@ -527,7 +793,7 @@ func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadHE2HEIDsIntoCache() error {
 
 	kvi, err := cnpd.db.ListValues(l2driver.HE2HEIDsKeyPrefix())
 	if err != nil {
-		log.Fatal(err)
+		reconcileLog.Fatal(err)
 		return nil
 	}
 
@@ -539,10 +805,10 @@ func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadHE2HEIDsIntoCache() error {
 		entry := &l2driver.HE2HEIDs{}
 		err := kv.GetValue(entry)
 		if err != nil {
-			log.Fatal(err)
+			reconcileLog.Fatal(err)
 			return nil
 		}
-		fmt.Println("reconcileLoadHE2HEIDsIntoCache: adding HE to HE IDs: ", kv.GetKey(), entry)
+		reconcileLog.Debugf("reconcileLoadHE2HEIDsIntoCache: adding HE to HE IDs: '%s', %v", kv.GetKey(), entry)
 		cnpd.reconcileBefore.he2heIDs[kv.GetKey()] = *entry
 	}
 }
@@ -551,7 +817,7 @@ func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadSFCIDsIntoCache() error {
 
 	kvi, err := cnpd.db.ListValues(l2driver.SFCIDsKeyPrefix())
 	if err != nil {
-		log.Fatal(err)
+		reconcileLog.Fatal(err)
 		return nil
 	}
 
@@ -563,14 +829,25 @@ func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadSFCIDsIntoCache() error {
 		entry := &l2driver.SFCIDs{}
 		err := kv.GetValue(entry)
 		if err != nil {
-			log.Fatal(err)
+			reconcileLog.Fatal(err)
 			return nil
 		}
-		fmt.Println("reconcileLoadSFCIDsIntoCache: adding SFC IDs: ", kv.GetKey(), entry)
+		reconcileLog.Debugf("reconcileLoadSFCIDsIntoCache: adding SFC IDs: '%s', %v", kv.GetKey(), entry)
 		cnpd.reconcileBefore.sfcIDs[kv.GetKey()] = *entry
 	}
 }
 
+func (cnpd *sfcCtlrL2CNPDriver) reconcileLoadHostIfNamesIntoCache() error {
+
+	// the registry is reloaded from the db as the names not rendered again are removed at the end
+	cnpd.hostIfNames = nil
+
+	return cnpd.DatastoreHostIfNamesIterate(l2driver.HostIfNamesKeyPrefix(),
+		func(key string, hifName *l2driver.HostIfName) {
+			cnpd.reconcileBefore.hostIfNames[key] = *hifName
+		})
+}
+
 func (cnpd *sfcCtlrL2CNPDriver) sequencerInitFromReconcileCache() {
 
 	// the sequencer is responsible fore choosing unique id's ... after pulling in all the data from
@@ -586,6 +863,11 @@ func (cnpd *sfcCtlrL2CNPDriver) sequencerInitFromReconcileCache() {
 		if heId.LoopbackMacAddrId > maxMacAddrID {
 			maxMacAddrID = heId.LoopbackMacAddrId
 		}
+		for _, macAddrID := range heId.LoopbackMacAddrIds {
+			if macAddrID > maxMacAddrID {
+				maxMacAddrID = macAddrID
+			}
+		}
 	}
 	for _, he2ee := range cnpd.reconcileBefore.he2eeIDs {
 		if he2ee.VlanId > maxVlanID {
@@ -601,6 +883,9 @@ func (cnpd *sfcCtlrL2CNPDriver) sequencerInitFromReconcileCache() {
 		if sfc.MacAddrId > maxMacAddrID {
 			maxMacAddrID = sfc.MacAddrId
 		}
+		if sfc.XconnectVni > maxVlanID {
+			maxVlanID = sfc.XconnectVni
+		}
 		if sfc.MemifId > maxMemifID {
 			maxMemifID = sfc.MemifId
 		}
@@ -612,5 +897,26 @@ func (cnpd *sfcCtlrL2CNPDriver) sequencerInitFromReconcileCache() {
 	cnpd.seq.MemIfID = maxMemifID
 	cnpd.seq.MacInstanceID = maxMacAddrID
 
-	fmt.Println("sequencerInitFromReconcileCache: sequence IDs after loading id's: ", cnpd.seq)
+	// in deterministic mode the loaded id's keep their owners, hashed id's are allocated around them
+	for _, heId := range cnpd.reconcileBefore.heIDs {
+		cnpd.reserveID(idSpaceMacInstance, l2driver.HEIDsNameKey(heId.Name), heId.LoopbackMacAddrId)
+		for loopbackName, macAddrID := range heId.LoopbackMacAddrIds {
+			cnpd.reserveID(idSpaceMacInstance, l2driver.HEIDsNameKey(heId.Name)+"/"+loopbackName, macAddrID)
+		}
+	}
+	for _, he2ee := range cnpd.reconcileBefore.he2eeIDs {
+		cnpd.reserveID(idSpaceVLan, l2driver.HE2EEIDsNameKey(he2ee.HeName, he2ee.EeName), he2ee.VlanId)
+	}
+	for _, he2he := range cnpd.reconcileBefore.he2heIDs {
+		cnpd.reserveID(idSpaceVLan, l2driver.HE2HEIDsNameKey(he2he.ShName, he2he.DhName), he2he.VlanId)
+	}
+	for _, sfc := range cnpd.reconcileBefore.sfcIDs {
+		owner := l2driver.SFCContainerPortIDsNameKey(sfc.SfcName, sfc.Container, sfc.Port)
+		cnpd.reserveID(idSpaceMacInstance, owner, sfc.MacAddrId)
+		cnpd.reserveID(idSpaceMemIf, owner, sfc.MemifId)
+		cnpd.reserveID(idSpaceVeth, owner, sfc.VethId)
+		cnpd.reserveID(idSpaceVLan, owner, sfc.XconnectVni)
+	}
+
+	reconcileLog.Debugf("sequencerInitFromReconcileCache: sequence IDs after loading id's: %+v", cnpd.seq)
 }
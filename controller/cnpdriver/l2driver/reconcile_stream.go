@@ -0,0 +1,311 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The streaming reconcile of very large agent trees is implemented in this
+// file.  With the system reconcile_page_size set, a vpp label whose agent
+// tree holds more keys than the page size is not loaded into the before
+// cache at ReconcileStart.  Instead, at ReconcileEnd, its tree is read back
+// one object type at a time and each entry is compared with the after cache
+// as it is read: an entry rendered the same is dropped from the after cache,
+// and one that is no longer rendered is deleted, a page of keys per ETCD
+// transaction.  The entries left in the after cache for the label are then
+// written, a page at a time.  So only the rendered config of such a host is
+// held in memory, not a second copy of what it is running.  The blue/green
+// and canary cutovers need the running config to fall back to, they load
+// the streamed labels whole.
+
+package l2driver
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
+	linuxIntf "github.com/ligato/vpp-agent/plugins/linuxplugin/ifplugin/model/interfaces"
+)
+
+// reconcileStreamType is how the entries of an agent object type are read, compared and dropped from the after
+// cache
+type reconcileStreamType struct {
+	name  string
+	entry func() proto.Message                   // a new entry to decode into
+	after func(key string) (proto.Message, bool) // the entry rendered under the key
+	drop  func(key string)                       // removes the key from the after cache
+	str   func(entry proto.Message) string       // the comparable form of an entry
+	owns  func(key string) bool                  // nil, or whether a key listed for the type is of the type
+}
+
+func (cnpd *sfcCtlrL2CNPDriver) reconcileStreamTypes(vppLabel string) []reconcileStreamType {
+
+	msgString := func(entry proto.Message) string { return entry.String() }
+	isL2FibKey := func(key string) bool { return reconcileIsL2FibKey(vppLabel, key) }
+
+	return []reconcileStreamType{
+		{
+			name:  "i/f",
+			entry: func() proto.Message { return &interfaces.Interfaces_Interface{} },
+			after: func(key string) (proto.Message, bool) {
+				entry, exists := cnpd.reconcileAfter.ifs[key]
+				return &entry, exists
+			},
+			drop: func(key string) { delete(cnpd.reconcileAfter.ifs, key) },
+			str:  msgString,
+		},
+		{
+			name:  "linux i/f",
+			entry: func() proto.Message { return &linuxIntf.LinuxInterfaces_Interface{} },
+			after: func(key string) (proto.Message, bool) {
+				entry, exists := cnpd.reconcileAfter.lifs[key]
+				return &entry, exists
+			},
+			drop: func(key string) { delete(cnpd.reconcileAfter.lifs, key) },
+			str:  msgString,
+		},
+		{
+			name:  "BD",
+			entry: func() proto.Message { return &l2.BridgeDomains_BridgeDomain{} },
+			after: func(key string) (proto.Message, bool) {
+				entry, exists := cnpd.reconcileAfter.bds[key]
+				return &entry, exists
+			},
+			drop: func(key string) { delete(cnpd.reconcileAfter.bds, key) },
+			str: func(entry proto.Message) string {
+				bd := entry.(*l2.BridgeDomains_BridgeDomain)
+				cnpd.sortBridgedInterfaces(bd.Interfaces)
+				return bd.String()
+			},
+			owns: func(key string) bool { return !isL2FibKey(key) },
+		},
+		{
+			name:  "static route",
+			entry: func() proto.Message { return &l3.StaticRoutes_Route{} },
+			after: func(key string) (proto.Message, bool) {
+				entry, exists := cnpd.reconcileAfter.l3Routes[key]
+				return &entry, exists
+			},
+			drop: func(key string) { delete(cnpd.reconcileAfter.l3Routes, key) },
+			str: func(entry proto.Message) string {
+				return reconcileStaticRouteString(entry.(*l3.StaticRoutes_Route))
+			},
+		},
+		{
+			name:  "xconnect",
+			entry: func() proto.Message { return &l2.XConnectPairs_XConnectPair{} },
+			after: func(key string) (proto.Message, bool) {
+				entry, exists := cnpd.reconcileAfter.xconns[key]
+				return &entry, exists
+			},
+			drop: func(key string) { delete(cnpd.reconcileAfter.xconns, key) },
+			str:  msgString,
+		},
+		{
+			name:  "l2fib",
+			entry: func() proto.Message { return &l2.FibTableEntries_FibTableEntry{} },
+			after: func(key string) (proto.Message, bool) {
+				entry, exists := cnpd.reconcileAfter.l2fibs[key]
+				return &entry, exists
+			},
+			drop: func(key string) { delete(cnpd.reconcileAfter.l2fibs, key) },
+			str:  msgString,
+			owns: isL2FibKey,
+		},
+		{
+			name:  "static arp",
+			entry: func() proto.Message { return &l3.ArpTable_ArpTableEntry{} },
+			after: func(key string) (proto.Message, bool) {
+				entry, exists := cnpd.reconcileAfter.arps[key]
+				return &entry, exists
+			},
+			drop: func(key string) { delete(cnpd.reconcileAfter.arps, key) },
+			str:  msgString,
+		},
+	}
+}
+
+// reconcileStreamed is whether the label's agent tree holds more keys than the reconcile_page_size, it is only
+// counted up to the first key past the page size
+func (cnpd *sfcCtlrL2CNPDriver) reconcileStreamed(vppLabel string) bool {
+
+	pageSize := int(cnpd.l2CNPEntityCache.SysParms.ReconcilePageSize)
+	if pageSize == 0 {
+		return false
+	}
+
+	ki, err := cnpd.db.ListKeys(utils.GetVppAgentPrefix() + vppLabel + "/")
+	if err != nil {
+		reconcileLog.Errorf("reconcileStreamed: vpp label: '%s': %s, it is loaded whole", vppLabel, err)
+		return false
+	}
+	count := 0
+	for {
+		_, _, allReceived := ki.GetNext()
+		if allReceived {
+			return false
+		}
+		if count++; count > pageSize {
+			return true
+		}
+	}
+}
+
+// reconcileStreamLoad loads the streamed labels into the before cache, for the cutovers that need it whole
+func (cnpd *sfcCtlrL2CNPDriver) reconcileStreamLoad() {
+
+	for vppLabel := range cnpd.streamedLabels {
+		cnpd.reconcileLoadInterfacesIntoCache(vppLabel)
+		cnpd.reconcileLoadLinuxInterfacesIntoCache(vppLabel)
+		cnpd.reconcileLoadBridgeDomainsIntoCache(vppLabel)
+		cnpd.reconcileLoadStaticRoutesIntoCache(vppLabel)
+		cnpd.reconcileLoadXConnectsIntoCache(vppLabel)
+		cnpd.reconcileLoadL2FibsIntoCache(vppLabel)
+		cnpd.reconcileLoadStaticArpsIntoCache(vppLabel)
+	}
+	cnpd.streamedLabels = make(map[string]struct{})
+}
+
+// reconcileStreamWriteBack writes the differences of the streamed labels' agent trees and the after cache, the
+// caller holds the reconcile mutex and the locks of the labels' agents
+func (cnpd *sfcCtlrL2CNPDriver) reconcileStreamWriteBack() error {
+
+	var vppLabels []string
+	for vppLabel := range cnpd.streamedLabels {
+		vppLabels = append(vppLabels, vppLabel)
+	}
+	sort.Strings(vppLabels)
+
+	for _, vppLabel := range vppLabels {
+		if err := cnpd.reconcileStreamLabel(vppLabel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reconcileStreamLabel deletes the label's stale entries as its agent tree is read, then writes the entries that
+// are new or changed
+func (cnpd *sfcCtlrL2CNPDriver) reconcileStreamLabel(vppLabel string) error {
+
+	pageSize := int(cnpd.l2CNPEntityCache.SysParms.ReconcilePageSize)
+	prefix := utils.GetVppAgentPrefix() + vppLabel + "/"
+
+	var deletes []string
+	var err error
+	flush := func() {
+		if len(deletes) == 0 || err != nil {
+			return
+		}
+		reconcileLog.Infof("ReconcileEnd: vpp label: '%s': removing a page of %d keys", vppLabel, len(deletes))
+		err = cnpd.agentCommit(nil, nil, deletes)
+		deletes = deletes[:0]
+	}
+
+	types := cnpd.reconcileStreamTypes(vppLabel)
+	for _, st := range types {
+		st := st
+		loadErr := cnpd.agentLoad(vppLabel, st.entry(), func(key string, kv keyval.ProtoKeyVal,
+			adapter AgentAdapter) {
+
+			if st.owns != nil && !st.owns(key) {
+				return
+			}
+			before := st.entry()
+			if decodeErr := adapter.Decode(kv, before); decodeErr != nil {
+				reconcileLog.Errorf("ReconcileEnd: error decoding %s key: '%s': %s", st.name, key, decodeErr)
+				return
+			}
+			auditStrip(before)
+			after, existsInAfterCache := st.after(key)
+			if !existsInAfterCache {
+				reconcileLog.Info("ReconcileEnd: remove "+st.name+" key from etcd: ", key)
+				if deletes = append(deletes, key); len(deletes) >= pageSize {
+					flush()
+				}
+			} else if st.str(before) == st.str(after) {
+				st.drop(key)
+			}
+		})
+		if loadErr != nil {
+			return loadErr
+		}
+		if flush(); err != nil {
+			return err
+		}
+	}
+
+	// the entries left in the after cache are new or changed
+	msgs := make(map[string]proto.Message)
+	puts := cnpd.reconcileAfterKeys(prefix)
+	sort.Strings(puts)
+	for _, key := range puts {
+		for _, st := range types {
+			if after, exists := st.after(key); exists {
+				msgs[key] = after
+				st.drop(key)
+			}
+		}
+	}
+	for len(puts) > 0 {
+		page := puts
+		if len(page) > pageSize {
+			page = puts[:pageSize]
+		}
+		reconcileLog.Infof("ReconcileEnd: vpp label: '%s': writing a page of %d keys", vppLabel, len(page))
+		if err := cnpd.agentCommit(page, msgs, nil); err != nil {
+			return err
+		}
+		puts = puts[len(page):]
+	}
+
+	return nil
+}
+
+// reconcileAfterKeys returns the keys of the agent objects in the after cache under the prefix
+func (cnpd *sfcCtlrL2CNPDriver) reconcileAfterKeys(prefix string) []string {
+
+	var keys []string
+	for key := range cnpd.reconcileAfter.ifs {
+		keys = append(keys, key)
+	}
+	for key := range cnpd.reconcileAfter.lifs {
+		keys = append(keys, key)
+	}
+	for key := range cnpd.reconcileAfter.bds {
+		keys = append(keys, key)
+	}
+	for key := range cnpd.reconcileAfter.l3Routes {
+		keys = append(keys, key)
+	}
+	for key := range cnpd.reconcileAfter.xconns {
+		keys = append(keys, key)
+	}
+	for key := range cnpd.reconcileAfter.l2fibs {
+		keys = append(keys, key)
+	}
+	for key := range cnpd.reconcileAfter.arps {
+		keys = append(keys, key)
+	}
+
+	labelKeys := keys[:0]
+	for _, key := range keys {
+		if strings.HasPrefix(key, prefix) {
+			labelKeys = append(labelKeys, key)
+		}
+	}
+	return labelKeys
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The optimistic concurrency of the read-modify-writes is implemented in
+// this file.  The controller is not the only writer of the keys it updates
+// in place: operators edit the bridge domains of the agents, and the
+// controllers of a sharded topology share the id records.  A read-modify-write
+// loads the value with its mod revision, applies its change, and writes the
+// result only if the key is still at that revision, else it loads the key
+// again and re-applies the change.  The write compares the revision in an
+// etcd txn, see the revisions package, so no concurrent update is lost.
+
+package l2driver
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/utils/revisions"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
+)
+
+// revisionConflictRetries is how many times a read-modify-write is re-applied before it gives up
+const revisionConflictRetries = 5
+
+// decodeFunc loads the value of the kv into obj, ie kv.GetValue or the agent adapter's Decode
+type decodeFunc func(kv keyval.ProtoKeyVal, obj proto.Message) error
+
+func decodeValue(kv keyval.ProtoKeyVal, obj proto.Message) error {
+	return kv.GetValue(obj)
+}
+
+// revisionGet loads the value of the key into obj, the revision is 0 when the key does not exist
+func (cnpd *sfcCtlrL2CNPDriver) revisionGet(key string, obj proto.Message, decode decodeFunc) (bool, int64, error) {
+
+	kvi, err := cnpd.db.ListValues(key)
+	if err != nil {
+		return false, 0, err
+	}
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return false, 0, nil
+		}
+		if kv.GetKey() == key {
+			return true, kv.GetRevision(), decode(kv, obj)
+		}
+	}
+}
+
+// readModifyWrite loads the key into obj, lets modify compute the value to write from it, and writes the
+// value if the key was not updated in the meantime, else it starts over from the updated value
+func (cnpd *sfcCtlrL2CNPDriver) readModifyWrite(key string, obj proto.Message, decode decodeFunc,
+	modify func(found bool) (proto.Message, error)) error {
+
+	for attempt := 1; ; attempt++ {
+
+		obj.Reset()
+		found, rev, err := cnpd.revisionGet(key, obj, decode)
+		if err != nil {
+			log.Errorf("readModifyWrite: error loading key: '%s': %s", key, err)
+			return err
+		}
+		value, err := modify(found)
+		if err != nil {
+			return err
+		}
+
+		if err := cnpd.agentKeyOwn(key); err != nil {
+			return err
+		}
+		written, err := revisions.PutIfRevision(cnpd.db, key, value, rev)
+		if err != nil {
+			log.Errorf("readModifyWrite: error writing key: '%s': %s", key, err)
+			return err
+		}
+		if written {
+			return nil
+		}
+
+		log.Warnf("readModifyWrite: key: '%s' was updated after revision %d, retrying", key, rev)
+		if attempt == revisionConflictRetries {
+			err := fmt.Errorf("readModifyWrite: key: '%s' was updated by another writer at each of %d attempts",
+				key, attempt)
+			log.Error(err)
+			return err
+		}
+	}
+}
+
+// agentBridgeDomainPut writes the bd to the vpp label's agent, the interfaces another writer added to the
+// bd stored in the agent's tree are kept, and added to the bd
+func (cnpd *sfcCtlrL2CNPDriver) agentBridgeDomainPut(vppLabel string, bd *l2.BridgeDomains_BridgeDomain) error {
+
+	adapter := cnpd.agentAdapterFor(vppLabel)
+
+	key, err := adapter.Key(vppLabel, bd)
+	if err != nil {
+		log.Error("agentBridgeDomainPut: ", err)
+		return err
+	}
+
+	unlock := cnpd.agentLocks.Lock(vppLabel)
+	stored := &l2.BridgeDomains_BridgeDomain{}
+	err = cnpd.readModifyWrite(key, stored, adapter.Decode, func(found bool) (proto.Message, error) {
+		for _, storedIf := range stored.Interfaces {
+			if !bridgeDomainHasIf(bd, storedIf.Name) {
+				log.Infof("agentBridgeDomainPut: bd: '%s' keeping i/f: '%s' added to: '%s'", bd.Name,
+					storedIf.Name, key)
+				bd.Interfaces = append(bd.Interfaces, storedIf)
+			}
+		}
+		return adapter.Encode(bd)
+	})
+	unlock()
+	if err != nil {
+		return err
+	}
+
+	log.Info("agentBridgeDomainPut: ", key, bd)
+
+	cnpd.renderedKeys = append(cnpd.renderedKeys, key)
+	cnpd.agentConfirmTrack(vppLabel, bd)
+
+	return nil
+}
+
+func bridgeDomainHasIf(bd *l2.BridgeDomains_BridgeDomain, ifName string) bool {
+	for _, bi := range bd.Interfaces {
+		if bi.Name == ifName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,62 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package l2driver
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
+)
+
+// an update landing between the read and the write of a read-modify-write is not lost, the change is
+// re-applied to it
+func TestReadModifyWriteRetries(t *testing.T) {
+
+	broker := membroker.New()
+	cnpd := NewSfcCtlrL2CNPDriver("test", broker.NewBroker)
+	key := "/vnf-agent/vswitch/vpp/config/v1/bd/BD1"
+
+	attempts := 0
+	stored := &l2.BridgeDomains_BridgeDomain{}
+	err := cnpd.readModifyWrite(key, stored, decodeValue, func(found bool) (proto.Message, error) {
+		attempts++
+		if attempts == 1 {
+			concurrent := &l2.BridgeDomains_BridgeDomain{Name: "BD1",
+				Interfaces: []*l2.BridgeDomains_BridgeDomain_Interfaces{{Name: "IF1"}}}
+			if err := broker.Put(key, concurrent); err != nil {
+				t.Fatal(err)
+			}
+		}
+		bd := &l2.BridgeDomains_BridgeDomain{Name: "BD1", Interfaces: stored.Interfaces}
+		bd.Interfaces = append(bd.Interfaces, &l2.BridgeDomains_BridgeDomain_Interfaces{Name: "IF2"})
+		return bd, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 2 {
+		t.Errorf("the change was applied %d times, expected: 2", attempts)
+	}
+
+	written := &l2.BridgeDomains_BridgeDomain{}
+	if _, _, err := broker.GetValue(key, written); err != nil {
+		t.Fatal(err)
+	}
+	if !bridgeDomainHasIf(written, "IF1") || !bridgeDomainHasIf(written, "IF2") {
+		t.Errorf("the bd holds: %v, expected the interfaces of both writers", written.Interfaces)
+	}
+}
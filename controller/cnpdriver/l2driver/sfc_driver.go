@@ -0,0 +1,69 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The driver selection of the chains is implemented in this file.  A chain
+// can name the driver that renders it in its cnp_driver, else it is rendered
+// by the driver registered with -cnp-driver.  The bridged, xconnect and acl
+// steering drivers are modes of this driver, so whichever one is registered
+// renders the chains of the other two next to its own, with the same hosts
+// and id spaces.  The state of a chain stays with its mode: the bridged
+// chains in the host and bridge state, the xconnect and steered ones in
+// SFCXConns.  When a chain moves from one mode to another, the xconnects,
+// tunnels and acls it no longer has are removed.
+//
+// The hosts of the xconnect and steering drivers get no bridges, so their
+// chains cannot move to the bridged mode.  Which driver can render the
+// chains of which is declared in the cnp driver registry, the controller
+// validates the chains against it.
+
+package l2driver
+
+import (
+	"fmt"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// L2DriverName is the name of the cnp driver in bridged mode
+const L2DriverName = "sfcctlrl2"
+
+// sfcDriverOf returns the driver that renders the chain
+func (cnpd *sfcCtlrL2CNPDriver) sfcDriverOf(sfc *controller.SfcEntity) (string, error) {
+
+	switch {
+	case sfc.CnpDriver == "" || sfc.CnpDriver == cnpd.sfcDriver:
+		return cnpd.sfcDriver, nil
+	case sfc.CnpDriver == XConnectDriverName || sfc.CnpDriver == SteeringDriverName:
+		return sfc.CnpDriver, nil
+	}
+	err := fmt.Errorf("sfcDriverOf: the %s driver cannot render the chains of cnp_driver: '%s', sfc: '%s'",
+		cnpd.sfcDriver, sfc.CnpDriver, sfc.Name)
+	log.Error(err.Error())
+	return "", err
+}
+
+// sfcDriverLeave removes the xconnects, tunnels and acls of a chain that moved to the bridged mode
+func (cnpd *sfcCtlrL2CNPDriver) sfcDriverLeave(sfcName string) error {
+
+	prev, exists := cnpd.l2CNPStateCache.SFCXConns[sfcName]
+	if !exists {
+		return nil
+	}
+	if err := cnpd.xconnEnd(sfcName, prev, make(xconnStateType)); err != nil {
+		return err
+	}
+	delete(cnpd.l2CNPStateCache.SFCXConns, sfcName)
+
+	return nil
+}
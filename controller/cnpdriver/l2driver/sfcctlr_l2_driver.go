@@ -34,25 +34,33 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/ligato/cn-infra/db/keyval"
-	"github.com/ligato/cn-infra/logging/logrus"
 	"github.com/ligato/cn-infra/servicelabel"
 	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
 	"github.com/ligato/sfc-controller/controller/extentitydriver"
 	"github.com/ligato/sfc-controller/controller/model/controller"
-	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/controller/utils/features"
+	"github.com/ligato/sfc-controller/controller/utils/hostlocks"
 	"github.com/ligato/sfc-controller/controller/utils/ipam"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
 	"github.com/ligato/vpp-agent/clientv1/linux"
 	"github.com/ligato/vpp-agent/clientv1/linux/remoteclient"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
 	linuxIntf "github.com/ligato/vpp-agent/plugins/linuxplugin/ifplugin/model/interfaces"
+	linuxL3 "github.com/ligato/vpp-agent/plugins/linuxplugin/l3plugin/model/l3"
 )
 
 var (
-	log = logrus.DefaultLogger()
+	log = logs.Logger(logs.Driver)
+
+	featureGatewayOverlay = features.Register("gateway-overlay",
+		"spokes reach the ee's through a vxlan to their gateway host instead of tunneling to the ee's", true)
+	featureAutoL2Fib = features.Register("auto-l2fib",
+		"the l2fib of a bridge that does not learn is populated from the macs of its elements", true)
 )
 
 type sfcCtlrL2CNPDriver struct {
@@ -63,8 +71,23 @@ type sfcCtlrL2CNPDriver struct {
 	l2CNPStateCache     l2CNPStateCacheType
 	reconcileBefore     reconcileCacheType
 	reconcileAfter      reconcileCacheType
+	reconcileLabels     map[string]struct{} // vpp labels of the reconcile in progress
+	streamedLabels      map[string]struct{} // labels whose agent trees are not in reconcileBefore, see reconcile_stream.go
+	auditEntity         string              // kind/name of the entity being wired, see audit.go
+	auditEntities       map[string]string   // agent key -> kind/name of the entity it was first rendered for
+	auditVersion        uint32              // config version the controller is at, see SetConfigVersion
+	auditFindings       []Inconsistency     // the foreign and stale objects found by the last reconcile
 	reconcileInProgress bool
+	reconcileMutex      sync.Mutex          // guards the reconcile caches
+	agentLocks          *hostlocks.Manager // serializes the writes to each agent
 	seq                 sequencer
+	renderedKeys        []string          // vpp-agent keys rendered since ResetRenderedKeys
+	unconfirmedIfs      map[string]string // i/f state key -> error key, see confirm.go
+	unconfirmedBDs      map[string]string // BD state key -> error key, see confirm.go
+	hostIfNames         map[string]*hostIfNamesType // registered host i/f names by host, see hostifnames.go
+	sfcDriver           string            // the driver of the chains that do not name one, see sfc_driver.go
+	ownedKeys           map[string]struct{} // agent keys the driver wrote, nil until loaded, see ownership.go
+	ownedKeysMutex      sync.Mutex          // guards ownedKeys
 }
 
 // sequencer groups all sequences used by L2 driver.
@@ -75,17 +98,95 @@ type sequencer struct {
 	MemIfID       uint32
 	MacInstanceID uint32
 	VethID        uint32
+	hashed        map[idSpace]*hashedIDs // id's allocated in deterministic mode, see ids.go
+	exhausted     map[idSpace]struct{}   // spaces that ran out since GetExhaustedIDSpaces, see ids.go
 }
 
 type sfcInterfaceAddressStateType struct {
-	ipAddress  string
-	macAddress string
+	ipAddress   string
+	ipv6Address string
+	macAddress  string
+}
+
+// hostUplinkType is the nic, its addresses, and the vxlan tunnel sources a host uses toward a given peer
+type hostUplinkType struct {
+	ifName           string
+	ipv4             string
+	ipv6             string
+	tunnelSrc        string
+	tunnelSrcIpv6    string
+	loopbackEndpoint bool // the tunnel is sourced from the host's loopback, the peers always route to it
 }
 
+// hostUplinkForPeer picks the uplink named in the host's peer_uplinks for this peer he/ee, if there is none
+// the primary eth_if_name, eth_ipv4/eth_ipv6, and vxlan_tunnel_ipv4/vxlan_tunnel_ipv6 are used, a host with
+// loopback_tunnel_endpoints sources its tunnels from its loopback whichever uplink carries them
+func hostUplinkForPeer(he *controller.HostEntity, peerName string) hostUplinkType {
+
+	uplink := hostUplinkType{
+		ifName:        he.EthIfName,
+		ipv4:          he.EthIpv4,
+		ipv6:          he.EthIpv6,
+		tunnelSrc:     he.VxlanTunnelIpv4,
+		tunnelSrcIpv6: he.VxlanTunnelIpv6,
+	}
+
+	for _, peerUplink := range he.GetPeerUplinks() {
+		if peerUplink.Peer != peerName {
+			continue
+		}
+		for _, ul := range he.GetUplinks() {
+			if ul.EthIfName == peerUplink.EthIfName {
+				uplink.ifName = ul.EthIfName
+				uplink.ipv4 = ul.EthIpv4
+				uplink.ipv6 = ul.EthIpv6
+				if ul.VxlanTunnelIpv4 != "" {
+					uplink.tunnelSrc = ul.VxlanTunnelIpv4
+				}
+				if ul.VxlanTunnelIpv6 != "" {
+					uplink.tunnelSrcIpv6 = ul.VxlanTunnelIpv6
+				}
+			}
+		}
+	}
+
+	if he.LoopbackTunnelEndpoints {
+		if he.LoopbackIpv4 != "" {
+			uplink.tunnelSrc = hostPrefix(he.LoopbackIpv4, "/32")
+		}
+		if he.LoopbackIpv6 != "" {
+			uplink.tunnelSrcIpv6 = hostPrefix(he.LoopbackIpv6, "/128")
+		}
+		uplink.loopbackEndpoint = true
+	}
+
+	return uplink
+}
+
+// tunnelIpv6 is whether the vxlan between the two uplinks runs over ipv6, ie both have an ipv6 tunnel source
+// and one of them has no ipv4 one
+func tunnelIpv6(uplink hostUplinkType, peerUplink hostUplinkType) bool {
+	return (uplink.tunnelSrc == "" || peerUplink.tunnelSrc == "") &&
+		uplink.tunnelSrcIpv6 != "" && peerUplink.tunnelSrcIpv6 != ""
+}
+
+// tunnelAddrs returns the uplink's tunnel source, and the address of its nic the tunnel is routed to, in
+// ipv6 or ipv4
+func (uplink hostUplinkType) tunnelAddrs(ipv6 bool) (string, string) {
+	if ipv6 {
+		return uplink.tunnelSrcIpv6, uplink.ipv6
+	}
+	return uplink.tunnelSrc, uplink.ipv4
+}
+
+// IANA assigned vxlan udp port, used when the system/ee parms do not override it
+const vxlanDefaultDstPort = 4789
+
 type heToEEStateType struct {
-	vlanIf  *interfaces.Interfaces_Interface
-	bd      *l2.BridgeDomains_BridgeDomain
-	l3Route *l3.StaticRoutes_Route
+	vlanIf       *interfaces.Interfaces_Interface
+	bd           *l2.BridgeDomains_BridgeDomain
+	l3Route      *l3.StaticRoutes_Route
+	prefixRoutes []*l3.StaticRoutes_Route
 }
 
 type heToHEStateType struct {
@@ -105,6 +206,7 @@ type l2CNPStateCacheType struct {
 	SFCToHEs  map[string]map[string]*heStateType
 	HE        map[string]*heStateType
 	SFCIFAddr map[string]sfcInterfaceAddressStateType
+	SFCXConns map[string]xconnStateType // the agent objects of each sfc in xconnect and steering mode
 }
 
 type l2CNPEntityCacheType struct {
@@ -130,12 +232,17 @@ func NewSfcCtlrL2CNPDriver(name string, dbFactory func(string) keyval.ProtoBroke
 
 	cnpd := &sfcCtlrL2CNPDriver{}
 	cnpd.name = "Sfc Controller L2 Plugin: " + name
+	cnpd.sfcDriver = L2DriverName
 	cnpd.dbFactory = dbFactory
 	cnpd.db = dbFactory(keyval.Root)
+	cnpd.agentLocks = hostlocks.New()
 
 	cnpd.initL2CNPCache()
 	cnpd.initReconcileCache()
 
+	cnpd.unconfirmedIfs = make(map[string]string)
+	cnpd.unconfirmedBDs = make(map[string]string)
+
 	return cnpd
 }
 
@@ -145,6 +252,7 @@ func (cnpd *sfcCtlrL2CNPDriver) initL2CNPCache() {
 	cnpd.l2CNPStateCache.SFCToHEs = make(map[string]map[string]*heStateType)
 	cnpd.l2CNPStateCache.HE = make(map[string]*heStateType)
 	cnpd.l2CNPStateCache.SFCIFAddr = make(map[string]sfcInterfaceAddressStateType)
+	cnpd.l2CNPStateCache.SFCXConns = make(map[string]xconnStateType)
 
 	cnpd.l2CNPEntityCache.EEs = make(map[string]controller.ExternalEntity)
 	cnpd.l2CNPEntityCache.HEs = make(map[string]controller.HostEntity)
@@ -166,6 +274,26 @@ func (cnpd *sfcCtlrL2CNPDriver) GetName() string {
 	return cnpd.name
 }
 
+// GetRenderedKeyCount returns the number of vpp-agent keys rendered so far, a caller takes the count before
+// wiring an entity then asks for the keys rendered from there
+func (cnpd *sfcCtlrL2CNPDriver) GetRenderedKeyCount() uint32 {
+	return uint32(len(cnpd.renderedKeys))
+}
+
+// GetRenderedKeys returns the vpp-agent keys rendered after the first count keys, a key is listed each
+// time it is rendered
+func (cnpd *sfcCtlrL2CNPDriver) GetRenderedKeys(count uint32) []string {
+	if int(count) >= len(cnpd.renderedKeys) {
+		return nil
+	}
+	return cnpd.renderedKeys[count:]
+}
+
+// ResetRenderedKeys forgets the rendered keys once the caller has attributed them to their entities
+func (cnpd *sfcCtlrL2CNPDriver) ResetRenderedKeys() {
+	cnpd.renderedKeys = nil
+}
+
 // SetSystemParameters caches the current settings for the system
 func (cnpd *sfcCtlrL2CNPDriver) SetSystemParameters(sp *controller.SystemParameters) error {
 	cnpd.l2CNPEntityCache.SysParms = *sp
@@ -181,6 +309,7 @@ func (cnpd *sfcCtlrL2CNPDriver) SetSystemParameters(sp *controller.SystemParamet
 func (cnpd *sfcCtlrL2CNPDriver) WireHostEntityToDestinationHostEntity(sh *controller.HostEntity,
 	dh *controller.HostEntity) error {
 
+	cnpd.auditEntity = controller.HostEntityKind + "/" + sh.Name
 	cnpd.l2CNPEntityCache.HEs[sh.Name] = *sh
 	cnpd.l2CNPEntityCache.HEs[dh.Name] = *dh
 
@@ -224,6 +353,11 @@ func (cnpd *sfcCtlrL2CNPDriver) wireExternalEntityToHostEntity(ee *controller.Ex
 	log.Infof("wireExternalEntityToHostEntity: he", he)
 	log.Infof("wireExternalEntityToHostEntity: ee", ee)
 
+	// the controller of the peer site wires its end of the tunnel
+	if isFederationPeer(ee) {
+		return nil
+	}
+
 	// this holds the relationship from the HE to the map of EEs to which this HE is wired
 	heToEEMap, exists := cnpd.l2CNPStateCache.HEToEEs[he.Name]
 	if !exists {
@@ -262,7 +396,8 @@ func (cnpd *sfcCtlrL2CNPDriver) wireExternalEntityToHostEntity(ee *controller.Ex
 
 	// configure static route from this external router to the host
 	description := "IF_STATIC_ROUTE_E2H_" + he.Name
-	sr, err := cnpd.createStaticRoute(0, ee.Name, description, he.VxlanTunnelIpv4, he.EthIpv4, ee.HostInterface.IfName,
+	uplink := hostUplinkForPeer(he, ee.Name)
+	sr, err := cnpd.createStaticRoute(0, ee.Name, description, uplink.tunnelSrc, uplink.ipv4, ee.HostInterface.IfName,
 		cnpd.l2CNPEntityCache.SysParms.DefaultStaticRouteWeight, cnpd.l2CNPEntityCache.SysParms.DefaultStaticRoutePreference)
 	if err != nil {
 		log.Errorf("wireExternalEntityToHostEntity: error creating static route i/f: '%s'", description)
@@ -274,21 +409,27 @@ func (cnpd *sfcCtlrL2CNPDriver) wireExternalEntityToHostEntity(ee *controller.Ex
 
 	// call the external entity api to queue a msg so that the external router config will be sent to the router
 	// this will be replace perhaps by a watcher in the ext-ent driver
-	extentitydriver.SfcCtlrL2WireExternalEntityToHostEntity(*ee, *he, tmpVlanid, sr)
-	return nil
+	return extentitydriver.SfcCtlrL2WireExternalEntityToHostEntity(*ee, *he, tmpVlanid, sr)
 }
 
 // Perform CNP specific wiring for "connecting" a host server to an external router
 func (cnpd *sfcCtlrL2CNPDriver) WireHostEntityToExternalEntity(he *controller.HostEntity,
 	ee *controller.ExternalEntity) error {
 
+	cnpd.auditEntity = controller.ExternalEntityKind + "/" + ee.Name
 	cnpd.l2CNPEntityCache.HEs[he.Name] = *he
 	cnpd.l2CNPEntityCache.EEs[ee.Name] = *ee
 
 	log.Infof("WireHostEntityToExternalEntity: he", he)
 	log.Infof("WireHostEntityToExternalEntity: ee", ee)
 
-	if ee.HostInterface == nil || ee.HostVxlan == nil {
+	// the ee's are reached from the vpp hosts only
+	if he.HostType == controller.HostType_HOST_KERNEL {
+		return nil
+	}
+
+	// a federation peer is reached over the underlay, its host_vxlan is learned from the peer
+	if !isFederationPeer(ee) && (ee.HostInterface == nil || ee.HostVxlan == nil) {
 		log.Error("WireHostEntityToExternalEntity: invalid external entity config")
 		return errors.New("invalid external entity config")
 	}
@@ -303,8 +444,8 @@ func (cnpd *sfcCtlrL2CNPDriver) WireHostEntityToExternalEntity(he *controller.Ho
 	// now ensure this HE has not yet been associated to the EE
 	heToEEState, exists := heToEEMap[ee.Name]
 	if exists {
-		// maybe look at contents to see if they are programmed properly but for now just return
-		return nil
+		// maybe look at contents to see if they are programmed properly but for now just update the prefixes
+		return cnpd.updatePrefixRoutesToExtEntity(he, ee, heToEEState)
 	}
 
 	// delay adding of vlan tunnel, [static_route] and bridge till an sfc entity specifies one
@@ -319,12 +460,73 @@ func (cnpd *sfcCtlrL2CNPDriver) WireHostEntityToExternalEntity(he *controller.Ho
 
 	log.Infof("WireHostEntityToExternalEntity: he: %s, ee: %s", he.Name, ee.Name)
 
+	return cnpd.createPrefixRoutesToExtEntity(he, ee, heToEEState)
+}
+
+// createPrefixRoutesToExtEntity routes the networks behind the ee from the host, via the ee's host_interface
+func (cnpd *sfcCtlrL2CNPDriver) createPrefixRoutesToExtEntity(he *controller.HostEntity,
+	ee *controller.ExternalEntity, heToEEState *heToEEStateType) error {
+
+	ifName := hostUplinkForPeer(he, ee.Name).ifName
+	description := "IF_STATIC_ROUTE_H2E_PREFIX_" + ee.Name
+
+	for _, prefix := range ee.GetPrefixes() {
+		weight := prefix.Weight
+		if weight == 0 {
+			weight = cnpd.l2CNPEntityCache.SysParms.DefaultStaticRouteWeight
+		}
+		pref := prefix.Preference
+		if pref == 0 {
+			pref = cnpd.l2CNPEntityCache.SysParms.DefaultStaticRoutePreference
+		}
+		sr, err := cnpd.createStaticRoute(prefix.VrfId, he.Name, description, prefix.DstIpAddr,
+			ee.HostInterface.Ipv4Addr, ifName, weight, pref)
+		if err != nil {
+			log.Errorf("createPrefixRoutesToExtEntity: error creating static route: '%s' to: '%s'", description,
+				prefix.DstIpAddr)
+			return err
+		}
+		heToEEState.prefixRoutes = append(heToEEState.prefixRoutes, sr)
+	}
+
+	return nil
+}
+
+// updatePrefixRoutesToExtEntity re-routes the prefixes of an ee that was re-posted, and removes the routes of
+// the prefixes it no longer has
+func (cnpd *sfcCtlrL2CNPDriver) updatePrefixRoutesToExtEntity(he *controller.HostEntity,
+	ee *controller.ExternalEntity, heToEEState *heToEEStateType) error {
+
+	prevRoutes := heToEEState.prefixRoutes
+	heToEEState.prefixRoutes = nil
+	if err := cnpd.createPrefixRoutesToExtEntity(he, ee, heToEEState); err != nil {
+		return err
+	}
+	if cnpd.reconcileInProgress {
+		return nil // the routes that are not rendered again are removed at the end of the reconcile
+	}
+
+	rendered := make(map[string]struct{})
+	for _, sr := range heToEEState.prefixRoutes {
+		rendered[cnpd.agentKey(he.Name, sr)] = struct{}{}
+	}
+	for _, sr := range prevRoutes {
+		key := cnpd.agentKey(he.Name, sr)
+		if _, exists := rendered[key]; exists {
+			continue
+		}
+		if err := cnpd.agentDeleteKey(key); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // Perform CNP specific wiring for "preparing" a host server example: create an east-west bridge
 func (cnpd *sfcCtlrL2CNPDriver) WireInternalsForHostEntity(he *controller.HostEntity) error {
 
+	cnpd.auditEntity = controller.HostEntityKind + "/" + he.Name
 	cnpd.l2CNPEntityCache.HEs[he.Name] = *he
 
 	log.Infof("WireInternalsForHostEntity: caching host: ", he)
@@ -337,15 +539,27 @@ func (cnpd *sfcCtlrL2CNPDriver) WireInternalsForHostEntity(he *controller.HostEn
 	heState = &heStateType{}
 	cnpd.l2CNPStateCache.HE[he.Name] = heState
 
+	// a kernel host has no vswitch to wire, its chains' elements are terminated into veths, see kernel_host.go
+	if he.HostType == controller.HostType_HOST_KERNEL {
+		return nil
+	}
+
 	mtu := cnpd.getMtu(he.Mtu)
 
 	// configure the nic/ethernet
 	if he.EthIfName != "" {
-		if err := cnpd.createEthernet(he.Name, he.EthIfName, he.EthIpv4, "", he.EthIpv6, mtu, he.RxMode); err != nil {
+		if err := cnpd.createEthernet(he.Name, he.EthIfName, he.EthIpv4, "", he.EthIpv6, mtu, he.RxMode, ""); err != nil {
 			log.Errorf("WireInternalsForHostEntity: error creating ethernet i/f: '%s'", he.EthIfName)
 			return err
 		}
 	}
+	for _, uplink := range he.GetUplinks() {
+		if err := cnpd.createEthernet(he.Name, uplink.EthIfName, uplink.EthIpv4, "", uplink.EthIpv6, mtu,
+			he.RxMode, ""); err != nil {
+			log.Errorf("WireInternalsForHostEntity: error creating uplink ethernet i/f: '%s'", uplink.EthIfName)
+			return err
+		}
+	}
 
 	var heID *l2driver.HEIDs
 	var loopbackMacAddrID uint32
@@ -357,9 +571,8 @@ func (cnpd *sfcCtlrL2CNPDriver) WireInternalsForHostEntity(he *controller.HostEn
 		if he.LoopbackMacAddr == "" { // if not supplied, generate one
 			heID, _ = cnpd.DatastoreHEIDsRetrieve(he.Name)
 			if heID == nil || heID.LoopbackMacAddrId == 0 {
-				cnpd.seq.MacInstanceID++
-				loopbackMacAddress = formatMacAddress(cnpd.seq.MacInstanceID)
-				loopbackMacAddrID = cnpd.seq.MacInstanceID
+				loopbackMacAddrID = cnpd.nextID(idSpaceMacInstance, l2driver.HEIDsNameKey(he.Name))
+				loopbackMacAddress = formatMacAddress(loopbackMacAddrID)
 			} else {
 				loopbackMacAddress = formatMacAddress(heID.LoopbackMacAddrId)
 				loopbackMacAddrID = heID.LoopbackMacAddrId
@@ -379,27 +592,57 @@ func (cnpd *sfcCtlrL2CNPDriver) WireInternalsForHostEntity(he *controller.HostEn
 		}
 	}
 
-	// create a default flooding/learning/dynamic east-west bd, see controller/validate.go for defaults
-	bdName := "BD_INTERNAL_EW_" + he.Name
-	bd, err := cnpd.bridgedDomainCreateWithIfs(he.Name, bdName, nil, cnpd.l2CNPEntityCache.SysParms.DynamicBridgeParms)
-	if err != nil {
-		log.Errorf("WireInternalsForHostEntity: error creating BD: '%s'", bd.Name)
-		return err
+	// the additional loopbacks, each generated mac is tracked under the loopback's name, an anycast vip
+	// is rendered the same way as the other addresses, it just also exists on the other hosts
+	loopbackMacAddrIDs := make(map[string]uint32)
+	if len(he.GetLoopbacks()) != 0 && heID == nil {
+		heID, _ = cnpd.DatastoreHEIDsRetrieve(he.Name)
 	}
+	for _, loopback := range he.GetLoopbacks() {
 
-	heState.ewBD = bd
+		loopbackMacAddress := loopback.MacAddr
 
-	// create a default static east-west bd, see controller/validate.go for defaults
-	bdName = "BD_INTERNAL_EW_L2FIB_" + he.Name
-	bd, err = cnpd.bridgedDomainCreateWithIfs(he.Name, bdName, nil, cnpd.l2CNPEntityCache.SysParms.StaticBridgeParms)
-	if err != nil {
-		log.Errorf("WireInternalsForHostEntity: error creating BD: '%s'", bd.Name)
-		return err
+		if loopbackMacAddress == "" { // if not supplied, generate one
+			macAddrID := heID.GetLoopbackMacAddrIds()[loopback.Name]
+			if macAddrID == 0 {
+				macAddrID = cnpd.nextID(idSpaceMacInstance, l2driver.HEIDsNameKey(he.Name)+"/"+loopback.Name)
+			}
+			loopbackMacAddress = formatMacAddress(macAddrID)
+			loopbackMacAddrIDs[loopback.Name] = macAddrID
+		}
+
+		loopIfName := "IF_LOOPBACK_H_" + he.Name + "_" + loopback.Name
+		if err := cnpd.createLoopback(he.Name, loopIfName, loopbackMacAddress, loopback.Ipv4, loopback.Ipv6,
+			cnpd.getMtu(he.Mtu), he.RxMode); err != nil {
+			log.Errorf("WireInternalsForHostEntity: error creating loopback i/f: '%s'", loopIfName)
+			return err
+		}
 	}
 
-	heState.ewBDL2Fib = bd
+	// the east-west bridges, there are none in xconnect and steering mode
+	if cnpd.sfcDriver == L2DriverName {
+		// create a default flooding/learning/dynamic east-west bd, see controller/validate.go for defaults
+		bdName := "BD_INTERNAL_EW_" + he.Name
+		bd, err := cnpd.bridgedDomainCreateWithIfs(he.Name, bdName, nil, cnpd.l2CNPEntityCache.SysParms.DynamicBridgeParms)
+		if err != nil {
+			log.Errorf("WireInternalsForHostEntity: error creating BD: '%s'", bdName)
+			return err
+		}
+
+		heState.ewBD = bd
+
+		// create a default static east-west bd, see controller/validate.go for defaults
+		bdName = "BD_INTERNAL_EW_L2FIB_" + he.Name
+		bd, err = cnpd.bridgedDomainCreateWithIfs(he.Name, bdName, nil, cnpd.l2CNPEntityCache.SysParms.StaticBridgeParms)
+		if err != nil {
+			log.Errorf("WireInternalsForHostEntity: error creating BD: '%s'", bdName)
+			return err
+		}
+
+		heState.ewBDL2Fib = bd
+	}
 
-	key, heID, err := cnpd.DatastoreHEIDsCreate(he.Name, loopbackMacAddrID)
+	key, heID, err := cnpd.DatastoreHEIDsCreate(he.Name, loopbackMacAddrID, loopbackMacAddrIDs)
 	if err == nil && cnpd.reconcileInProgress {
 		cnpd.reconcileAfter.heIDs[key] = *heID
 	}
@@ -410,15 +653,42 @@ func (cnpd *sfcCtlrL2CNPDriver) WireInternalsForHostEntity(he *controller.HostEn
 // Perform CNP specific wiring for "preparing" an external entity
 func (cnpd *sfcCtlrL2CNPDriver) WireInternalsForExternalEntity(ee *controller.ExternalEntity) error {
 
-	extentitydriver.SfcCtlrL2WireExternalEntityInternals(*ee)
-
-	return nil
+	return extentitydriver.SfcCtlrL2WireExternalEntityInternals(*ee)
 }
 
 // Perform CNP specific wiring for inter-container wiring, and container to external router wiring
 func (cnpd *sfcCtlrL2CNPDriver) WireSfcEntity(sfc *controller.SfcEntity) error {
 
-	var err error
+	cnpd.auditEntity = controller.SfcEntityKind + "/" + sfc.Name
+	sfcDriver, err := cnpd.sfcDriverOf(sfc)
+	if err != nil {
+		return err
+	}
+	// the elements on kernel hosts are terminated there, the rest of the chain is wired on the vpp hosts
+	vppSfc, err := cnpd.wireSfcKernelHostElements(sfc)
+	if err != nil {
+		return err
+	}
+	if vppSfc != sfc {
+		// the chain is cached whole, the evictions and the lookups are of all its elements
+		defer func(kernelSfc controller.SfcEntity) {
+			cnpd.l2CNPEntityCache.SFCs[kernelSfc.Name] = kernelSfc
+		}(*sfc)
+		if !sfcHasVppElements(vppSfc) {
+			return nil
+		}
+		sfc = vppSfc
+	}
+	switch sfcDriver {
+	case XConnectDriverName:
+		return cnpd.wireSfcXConnElements(sfc)
+	case SteeringDriverName:
+		return cnpd.wireSfcSteeringElements(sfc)
+	}
+	if err = cnpd.sfcDriverLeave(sfc.Name); err != nil {
+		return err
+	}
+
 	// the semantic difference between a north_south vs an east-west sfc entity, it what is the bridge that
 	// the memIf/afPkt if's will be associated.
 	switch sfc.Type {
@@ -456,6 +726,38 @@ func (cnpd *sfcCtlrL2CNPDriver) WireSfcEntity(sfc *controller.SfcEntity) error {
 	return err
 }
 
+// sfcFeatureCheck resolves a feature flag for the sfc: its own flags win over the flags of the ee's and
+// hosts in the chain, which win over the system flags
+func (cnpd *sfcCtlrL2CNPDriver) sfcFeatureCheck(feature string, sfc *controller.SfcEntity) error {
+
+	if !cnpd.sfcFeatureEnabled(feature, sfc) {
+		err := fmt.Errorf("sfcFeatureCheck: feature '%s' is disabled for sfc: '%s'", feature, sfc.Name)
+		log.Error(err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// sfcFeatureEnabled resolves a feature flag for the sfc like sfcFeatureCheck, for the features that change
+// how the sfc is wired rather than whether it can be
+func (cnpd *sfcCtlrL2CNPDriver) sfcFeatureEnabled(feature string, sfc *controller.SfcEntity) bool {
+
+	flagSets := []map[string]bool{sfc.GetFeatureFlags()}
+	for _, sfcEntityElement := range sfc.GetElements() {
+		if sfcEntityElement.Type == controller.SfcElementType_EXTERNAL_ENTITY {
+			if ee, exists := cnpd.l2CNPEntityCache.EEs[sfcEntityElement.Container]; exists {
+				flagSets = append(flagSets, ee.GetFeatureFlags())
+			}
+		} else if he, exists := cnpd.l2CNPEntityCache.HEs[sfcEntityElement.EtcdVppSwitchKey]; exists {
+			flagSets = append(flagSets, he.GetFeatureFlags())
+		}
+	}
+	flagSets = append(flagSets, cnpd.l2CNPEntityCache.SysParms.GetFeatureFlags())
+
+	return features.Enabled(feature, flagSets...)
+}
+
 // for now, ensure there is only one ee ... as each container will be wirred to it
 func (cnpd *sfcCtlrL2CNPDriver) wireSfcNorthSouthVXLANElements(sfc *controller.SfcEntity) error {
 
@@ -593,6 +895,14 @@ func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToExtEntity(sfc *controller.
 		return nil, err
 	}
 
+	// spokes never tunnel to the ee, their gateway does it for them
+	if gwName := cnpd.gatewayForHost(hostName); gwName != "" {
+		if err := cnpd.sfcFeatureCheck(featureGatewayOverlay, sfc); err != nil {
+			return nil, err
+		}
+		return cnpd.createVxLANAndBridgeToExtEntityViaGateway(sfc, hostName, gwName, eeName, heToEEState, vlanID)
+	}
+
 	if heToEEState.vlanIf == nil {
 
 		// first time sfc is wired from this host to this external ee so create a vxlan tunnel
@@ -603,16 +913,27 @@ func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToExtEntity(sfc *controller.
 		// create the vxlan i'f before the BD
 		ifName := "IF_VXLAN_H2E_" + he.Name + "_" + ee.Name
 
+		// the tunnel to a federation peer is rendered once the peer told its endpoint
+		if isFederationPeer(&ee) && (ee.HostVxlan == nil || ee.HostVxlan.SourceIpv4 == "") {
+			err := fmt.Errorf("createVxLANAndBridgeToExtEntity: federation peer: '%s' has not told its tunnel "+
+				"endpoint yet for this sfc: '%s'", ee.Name, sfc.Name)
+			return nil, err
+		}
+
+		if vlanID == 0 && ee.HostVxlan != nil {
+			vlanID = ee.HostVxlan.Vni
+		}
 		if vlanID == 0 {
 			he2eeID, _ := cnpd.DatastoreHE2EEIDsRetrieve(he.Name, ee.Name)
 			if he2eeID == nil || he2eeID.VlanId == 0 {
-				cnpd.seq.VLanID++
-				vlanID = cnpd.seq.VLanID
+				vlanID = cnpd.nextID(idSpaceVLan, l2driver.HE2EEIDsNameKey(he.Name, ee.Name))
 			} else {
 				vlanID = he2eeID.VlanId
 			}
 		}
-		vlanIf, err := cnpd.vxLanCreate(he.Name, ifName, vlanID, he.VxlanTunnelIpv4, ee.HostVxlan.SourceIpv4)
+		uplink := hostUplinkForPeer(&he, ee.Name)
+		vlanIf, err := cnpd.vxLanCreate(he.Name, ifName, vlanID, uplink.tunnelSrc, ee.HostVxlan.SourceIpv4,
+			cnpd.vxlanParmsForEE(&ee))
 		if err != nil {
 			log.Errorf("createVxLANAndBridgeToExtEntity: error creating vxlan: '%s'", ifName)
 			return nil, err
@@ -635,7 +956,7 @@ func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToExtEntity(sfc *controller.
 		if he.CreateVxlanStaticRoute {
 			description := "IF_STATIC_ROUTE_H2E_" + ee.Name
 			sr, err := cnpd.createStaticRoute(0, he.Name, description, ee.HostVxlan.SourceIpv4, ee.HostInterface.Ipv4Addr,
-				he.EthIfName,
+				hostUplinkForPeer(&he, ee.Name).ifName,
 				cnpd.l2CNPEntityCache.SysParms.DefaultStaticRouteWeight,
 				cnpd.l2CNPEntityCache.SysParms.DefaultStaticRoutePreference)
 			if err != nil {
@@ -665,7 +986,7 @@ func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToExtEntity(sfc *controller.
 		// now create the bridge
 		bd, err := cnpd.bridgedDomainCreateWithIfs(he.Name, bdName, ifs, cnpd.l2CNPEntityCache.SysParms.DynamicBridgeParms)
 		if err != nil {
-			log.Errorf("createVxLANAndBridgeToExtEntity: error creating BD: '%s'", bd.Name)
+			log.Errorf("createVxLANAndBridgeToExtEntity: error creating BD: '%s'", bdName)
 			return nil, err
 		}
 
@@ -678,6 +999,168 @@ func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToExtEntity(sfc *controller.
 	return heToEEState.bd, nil
 }
 
+// isFederationPeer returns whether the ee is the sfc-controller of another site
+func isFederationPeer(ee *controller.ExternalEntity) bool {
+	return ee.EeDriverType == controller.ExtEntDriverType_EE_DRIVER_TYPE_SFC_CONTROLLER
+}
+
+// gatewayForHost returns the gateway host a spoke uses to reach the ee's, an empty name means the host
+// tunnels to the ee's directly: either the topology is full mesh, the host is a gateway, or there are no gateways
+func (cnpd *sfcCtlrL2CNPDriver) gatewayForHost(heName string) string {
+
+	if cnpd.l2CNPEntityCache.SysParms.OverlayTopology != controller.OverlayTopologyType_OVERLAY_GATEWAY {
+		return ""
+	}
+
+	he := cnpd.l2CNPEntityCache.HEs[heName]
+	if he.Gateway {
+		return ""
+	}
+	if he.GatewayHost != "" {
+		if gw, exists := cnpd.l2CNPEntityCache.HEs[he.GatewayHost]; exists && gw.Gateway {
+			return gw.Name
+		}
+		log.Warnf("gatewayForHost: he: '%s' gateway_host: '%s' is not a gateway, using default", he.Name,
+			he.GatewayHost)
+	}
+
+	gwNames := make([]string, 0)
+	for _, gw := range cnpd.l2CNPEntityCache.HEs {
+		if gw.Gateway {
+			gwNames = append(gwNames, gw.Name)
+		}
+	}
+	if len(gwNames) == 0 {
+		return ""
+	}
+	sort.Strings(gwNames)
+
+	return gwNames[0]
+}
+
+// createVxLANAndBridgeToExtEntityViaGateway wires a spoke's ee bridge to its gateway instead of the ee, the
+// gateway end of the tunnel is added to the gateway's own bridge to the ee so only the gateway tunnels to the ee
+func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToExtEntityViaGateway(sfc *controller.SfcEntity,
+	shName string, gwName string, eeName string, heToEEState *heToEEStateType,
+	vlanID uint32) (*l2.BridgeDomains_BridgeDomain, error) {
+
+	gwBD, err := cnpd.createVxLANAndBridgeToExtEntity(sfc, gwName, eeName, 0)
+	if err != nil {
+		log.Errorf("createVxLANAndBridgeToExtEntityViaGateway: error wiring gateway: '%s' to ee: '%s'",
+			gwName, eeName)
+		return nil, err
+	}
+
+	sh := cnpd.l2CNPEntityCache.HEs[shName]
+	gw := cnpd.l2CNPEntityCache.HEs[gwName]
+	ee := cnpd.l2CNPEntityCache.EEs[eeName]
+
+	shUplink := hostUplinkForPeer(&sh, gw.Name)
+	gwUplink := hostUplinkForPeer(&gw, sh.Name)
+	ipv6 := tunnelIpv6(shUplink, gwUplink)
+	shTunnelSrc, shAddr := shUplink.tunnelAddrs(ipv6)
+	gwTunnelSrc, gwAddr := gwUplink.tunnelAddrs(ipv6)
+
+	if heToEEState.vlanIf == nil {
+
+		// first time sfc is wired from this spoke to this external ee so create the tunnel to the gateway
+
+		if vlanID == 0 {
+			he2eeID, _ := cnpd.DatastoreHE2EEIDsRetrieve(sh.Name, ee.Name)
+			if he2eeID == nil || he2eeID.VlanId == 0 {
+				vlanID = cnpd.nextID(idSpaceVLan, l2driver.HE2EEIDsNameKey(sh.Name, ee.Name))
+			} else {
+				vlanID = he2eeID.VlanId
+			}
+		}
+
+		ifName := "IF_VXLAN_H2G_" + sh.Name + "_" + ee.Name
+		vlanIf, err := cnpd.vxLanCreate(sh.Name, ifName, vlanID, shTunnelSrc, gwTunnelSrc,
+			cnpd.vxlanParmsForEE(&ee))
+		if err != nil {
+			log.Errorf("createVxLANAndBridgeToExtEntityViaGateway: error creating vxlan: '%s'", ifName)
+			return nil, err
+		}
+
+		heToEEState.vlanIf = vlanIf
+
+		// the gateway end uses the same vni and is bridged toward the ee
+		gwIfName := "IF_VXLAN_G2H_" + gw.Name + "_" + sh.Name + "_" + ee.Name
+		gwIf, err := cnpd.vxLanCreate(gw.Name, gwIfName, vlanID, gwTunnelSrc, shTunnelSrc,
+			cnpd.vxlanParmsForEE(&ee))
+		if err != nil {
+			log.Errorf("createVxLANAndBridgeToExtEntityViaGateway: error creating vxlan: '%s'", gwIfName)
+			return nil, err
+		}
+
+		ifs := make([]*l2.BridgeDomains_BridgeDomain_Interfaces, 1)
+		ifs[0] = &l2.BridgeDomains_BridgeDomain_Interfaces{
+			Name: gwIf.Name,
+		}
+		if err := cnpd.bridgedDomainAssociateWithIfs(gw.Name, gwBD, ifs); err != nil {
+			log.Errorf("createVxLANAndBridgeToExtEntityViaGateway: error adding '%s' to BD: '%s'",
+				gwIfName, gwBD.Name)
+			return nil, err
+		}
+
+		key, he2eeID, err := cnpd.DatastoreHE2EEIDsCreate(sh.Name, ee.Name, vlanID)
+		if err == nil && cnpd.reconcileInProgress {
+			cnpd.reconcileAfter.he2eeIDs[key] = *he2eeID
+		}
+	}
+
+	if heToEEState.l3Route == nil {
+
+		// configure static routes between the spoke and the gateway tunnel endpoints
+		if sh.CreateVxlanStaticRoute || gwUplink.loopbackEndpoint {
+			description := "IF_STATIC_ROUTE_H2G_" + gw.Name
+			sr, err := cnpd.createStaticRoute(0, sh.Name, description, gwTunnelSrc, gwAddr,
+				shUplink.ifName,
+				cnpd.l2CNPEntityCache.SysParms.DefaultStaticRouteWeight,
+				cnpd.l2CNPEntityCache.SysParms.DefaultStaticRoutePreference)
+			if err != nil {
+				log.Errorf("createVxLANAndBridgeToExtEntityViaGateway: error creating static route: '%s'",
+					description)
+				return nil, err
+			}
+
+			heToEEState.l3Route = sr
+		}
+		if gw.CreateVxlanStaticRoute || shUplink.loopbackEndpoint {
+			description := "IF_STATIC_ROUTE_G2H_" + sh.Name
+			if _, err := cnpd.createStaticRoute(0, gw.Name, description, shTunnelSrc, shAddr,
+				gwUplink.ifName,
+				cnpd.l2CNPEntityCache.SysParms.DefaultStaticRouteWeight,
+				cnpd.l2CNPEntityCache.SysParms.DefaultStaticRoutePreference); err != nil {
+				log.Errorf("createVxLANAndBridgeToExtEntityViaGateway: error creating static route: '%s'",
+					description)
+				return nil, err
+			}
+		}
+	}
+
+	if heToEEState.bd == nil {
+
+		bdName := "BD_H2E_" + sh.Name + "_" + ee.Name
+
+		ifs := make([]*l2.BridgeDomains_BridgeDomain_Interfaces, 1)
+		ifs[0] = &l2.BridgeDomains_BridgeDomain_Interfaces{
+			Name: heToEEState.vlanIf.Name,
+		}
+
+		// the ee is not wired back to a spoke, it only knows about the gateway
+		bd, err := cnpd.bridgedDomainCreateWithIfs(sh.Name, bdName, ifs, cnpd.l2CNPEntityCache.SysParms.DynamicBridgeParms)
+		if err != nil {
+			log.Errorf("createVxLANAndBridgeToExtEntityViaGateway: error creating BD: '%s'", bdName)
+			return nil, err
+		}
+
+		heToEEState.bd = bd
+	}
+
+	return heToEEState.bd, nil
+}
+
 // createVxLANAndBridgeToDestHost and ensure vxlan and bridge are created if not already done yet
 func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToDestHost(sfc *controller.SfcEntity,
 	shName string, dhName string, vlanID uint32) (*l2.BridgeDomains_BridgeDomain, error) {
@@ -706,16 +1189,24 @@ func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToDestHost(sfc *controller.S
 		// create the vxlan i'f before the BD
 		ifName := "IF_VXLAN_H2H_" + sh.Name + "_" + dh.Name
 
+		if vlanID == 0 && cnpd.l2CNPEntityCache.SysParms.ShardCount > 1 {
+			vlanID = cnpd.shardedHE2HEVlanID(sh.Name, dh.Name)
+		}
 		if vlanID == 0 {
 			he2eeID, _ := cnpd.DatastoreHE2EEIDsRetrieve(sh.Name, dh.Name)
 			if he2eeID == nil || he2eeID.VlanId == 0 {
-				cnpd.seq.VLanID++
-				vlanID = cnpd.seq.VLanID
+				vlanID = cnpd.nextID(idSpaceVLan, l2driver.HE2HEIDsNameKey(sh.Name, dh.Name))
 			} else {
 				vlanID = he2eeID.VlanId
 			}
 		}
-		vlanIf, err := cnpd.vxLanCreate(sh.Name, ifName, vlanID, sh.VxlanTunnelIpv4, dh.VxlanTunnelIpv4)
+		shUplink := hostUplinkForPeer(&sh, dh.Name)
+		dhUplink := hostUplinkForPeer(&dh, sh.Name)
+		ipv6 := tunnelIpv6(shUplink, dhUplink)
+		shTunnelSrc, _ := shUplink.tunnelAddrs(ipv6)
+		dhTunnelSrc, _ := dhUplink.tunnelAddrs(ipv6)
+		vlanIf, err := cnpd.vxLanCreate(sh.Name, ifName, vlanID, shTunnelSrc, dhTunnelSrc,
+			cnpd.l2CNPEntityCache.SysParms.GetVxlanParms())
 		if err != nil {
 			log.Errorf("createVxLANAndBridgeToDestHost: error creating vxlan: '%s'", ifName)
 			return nil, err
@@ -734,11 +1225,13 @@ func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToDestHost(sfc *controller.S
 		sh := cnpd.l2CNPEntityCache.HEs[shName]
 		dh := cnpd.l2CNPEntityCache.HEs[dhName]
 
-		// configure static route from this host to the dest host
-		if sh.CreateVxlanStaticRoute {
+		// configure static route from this host to the dest host, a loopback endpoint is always routed to
+		if sh.CreateVxlanStaticRoute || hostUplinkForPeer(&dh, sh.Name).loopbackEndpoint {
 			description := "IF_STATIC_ROUTE_H2H_" + dh.Name
-			sr, err := cnpd.createStaticRoute(0, sh.Name, description, dh.VxlanTunnelIpv4, dh.EthIpv4,
-				sh.EthIfName,
+			shUplink := hostUplinkForPeer(&sh, dh.Name)
+			dhUplink := hostUplinkForPeer(&dh, sh.Name)
+			dhTunnelSrc, dhAddr := dhUplink.tunnelAddrs(tunnelIpv6(shUplink, dhUplink))
+			sr, err := cnpd.createStaticRoute(0, sh.Name, description, dhTunnelSrc, dhAddr, shUplink.ifName,
 				cnpd.l2CNPEntityCache.SysParms.DefaultStaticRouteWeight,
 				cnpd.l2CNPEntityCache.SysParms.DefaultStaticRoutePreference)
 			if err != nil {
@@ -768,7 +1261,7 @@ func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToDestHost(sfc *controller.S
 		// now create the bridge
 		bd, err := cnpd.bridgedDomainCreateWithIfs(sh.Name, bdName, ifs, cnpd.l2CNPEntityCache.SysParms.DynamicBridgeParms)
 		if err != nil {
-			log.Errorf("createVxLANAndBridgeToDestHost: error creating BD: '%s'", bd.Name)
+			log.Errorf("createVxLANAndBridgeToDestHost: error creating BD: '%s'", bdName)
 			return nil, err
 		}
 
@@ -781,41 +1274,51 @@ func (cnpd *sfcCtlrL2CNPDriver) createVxLANAndBridgeToDestHost(sfc *controller.S
 // north/south NIC type, memIfs/cntrs connect to physical NIC
 func (cnpd *sfcCtlrL2CNPDriver) wireSfcNorthSouthNICElements(sfc *controller.SfcEntity) error {
 
-	heCount := 0
 	var ifName string
-	var he *controller.SfcEntity_SfcElement
+	var hes []*controller.SfcEntity_SfcElement
 	var bd *l2.BridgeDomains_BridgeDomain
 	var err error
 
-	// find the host entity and ensure there is only one allowed
+	// find the host entities, ie the nics, they are all on the same host
 	for i, sfcEntityElement := range sfc.GetElements() {
 
 		log.Infof("wireSfcNorthSouthNICElements: sfc entity element[%d]: ", i, sfcEntityElement)
 
 		switch sfcEntityElement.Type {
 		case controller.SfcElementType_HOST_ENTITY:
-			heCount++
-			if heCount > 1 {
-				err := fmt.Errorf("wireSfcNorthSouthNICElements: only one he allowed for n/s sfc: '%s'", sfc.Name)
+			if len(hes) != 0 && sfcEntityElement.Container != hes[0].Container {
+				err := fmt.Errorf("wireSfcNorthSouthNICElements: the nics of n/s sfc: '%s' are on different hosts: '%s', '%s'",
+					sfc.Name, hes[0].Container, sfcEntityElement.Container)
 				log.Error(err.Error())
 				return err
 			}
-			he = sfcEntityElement
+			hes = append(hes, sfcEntityElement)
 		}
 	}
 
-	if heCount == 0 {
+	if len(hes) == 0 {
 		err := fmt.Errorf("wireSfcNorthSouthNICElements: NO he specified for n/s sfc: '%s'", sfc.Name)
 		log.Error(err.Error())
 		return err
 	}
 
-	// using the parameters for the host interface, create the eth i/f and a bridge for it if NIC_BD l2fib
+	// using the parameters for the host interfaces, create the eth i/f's and a bridge for them if NIC_BD l2fib
 
-	mtu := cnpd.getMtu(he.Mtu)
-	// physical NIC
-	if err := cnpd.createEthernet(he.Container, he.PortLabel, "", he.MacAddr, he.Ipv6Addr, mtu, he.RxMode); err != nil {
-		log.Errorf("wireSfcNorthSouthNICElements: error creating ethernet i/f: '%s'", he.PortLabel)
+	for _, he := range hes {
+		mtu := cnpd.getMtu(he.Mtu)
+		// physical NIC
+		if err := cnpd.createEthernet(he.Container, he.PortLabel, "", he.MacAddr, he.Ipv6Addr, mtu, he.RxMode,
+			sfcElementDescription(sfc.Name, he)); err != nil {
+			log.Errorf("wireSfcNorthSouthNICElements: error creating ethernet i/f: '%s'", he.PortLabel)
+			return err
+		}
+	}
+
+	// the chain is attached to the nic, only a vrf chain has several nics, it routes over each of them
+	he := hes[0]
+	if len(hes) > 1 && sfc.Type != controller.SfcType_SFC_NS_NIC_VRF {
+		err := fmt.Errorf("wireSfcNorthSouthNICElements: only a vrf n/s sfc has several nics, sfc: '%s'", sfc.Name)
+		log.Error(err.Error())
 		return err
 	}
 
@@ -835,7 +1338,7 @@ func (cnpd *sfcCtlrL2CNPDriver) wireSfcNorthSouthNICElements(sfc *controller.Sfc
 		bd, err = cnpd.bridgedDomainCreateWithIfs(he.Container, bdName,
 			[]*l2.BridgeDomains_BridgeDomain_Interfaces{ifEntry}, bdParms)
 		if err != nil {
-			log.Errorf("wireSfcNorthSouthNICElements: error creating BD: '%s'", bd.Name)
+			log.Errorf("wireSfcNorthSouthNICElements: error creating BD: '%s'", bdName)
 			return err
 		}
 
@@ -853,10 +1356,15 @@ func (cnpd *sfcCtlrL2CNPDriver) wireSfcNorthSouthNICElements(sfc *controller.Sfc
 
 	if sfc.Type == controller.SfcType_SFC_NS_NIC_VRF {
 
-		err := cnpd.createVRFEntries(he.Container, he, he.PortLabel, "VRF_"+sfc.Name+"_"+he.Container+"_"+he.PortLabel)
-		if err != nil {
-			log.Errorf("wireSfcNorthSouthNICElements: error creating processing vrf entries i/f: %s/'%s'", he.PortLabel, he)
-			return err
+		// each nic gets its own routes, the routes of several nics to a prefix are ecmp
+		for _, nicEntry := range hes {
+			err := cnpd.createVRFEntries(nicEntry.Container, nicEntry, nicEntry.PortLabel,
+				"VRF_"+sfc.Name+"_"+nicEntry.Container+"_"+nicEntry.PortLabel)
+			if err != nil {
+				log.Errorf("wireSfcNorthSouthNICElements: error creating processing vrf entries i/f: %s/'%s'",
+					nicEntry.PortLabel, nicEntry)
+				return err
+			}
 		}
 	}
 
@@ -880,15 +1388,9 @@ func (cnpd *sfcCtlrL2CNPDriver) wireSfcNorthSouthNICElements(sfc *controller.Sfc
 				}
 
 				// now create the l2fib entries
-				if sfcEntityElement.L2FibMacs != nil {
-					for _, macAddr := range sfcEntityElement.L2FibMacs {
-						if _, err := cnpd.createL2FibEntry(sfcEntityElement.EtcdVppSwitchKey, bd.Name, macAddr,
-							ifName); err != nil {
-							log.Errorf("wireSfcNorthSouthNICElements: error creating l2fib: ewBD: '%s', mac: '%s', i/f: '%s'",
-								bd.Name, macAddr, ifName)
-							return err
-						}
-					}
+				if err := cnpd.createElementL2FibEntries(sfc, bd, sfcEntityElement, ifName); err != nil {
+					log.Errorf("wireSfcNorthSouthNICElements: error creating l2fib: ewBD: '%s', i/f: '%s'", bd.Name, ifName)
+					return err
 				}
 
 			} else if sfc.Type == controller.SfcType_SFC_NS_NIC_VRF {
@@ -935,15 +1437,9 @@ func (cnpd *sfcCtlrL2CNPDriver) wireSfcNorthSouthNICElements(sfc *controller.Sfc
 				}
 
 				// now create the l2fib entries
-				if sfcEntityElement.L2FibMacs != nil {
-					for _, macAddr := range sfcEntityElement.L2FibMacs {
-						if _, err := cnpd.createL2FibEntry(sfcEntityElement.EtcdVppSwitchKey, bd.Name, macAddr,
-							ifName); err != nil {
-							log.Errorf("wireSfcNorthSouthNICElements: error creating l2fib: ewBD: '%s', mac: '%s', i/f: '%s'",
-								bd.Name, macAddr, ifName)
-							return err
-						}
-					}
+				if err := cnpd.createElementL2FibEntries(sfc, bd, sfcEntityElement, ifName); err != nil {
+					log.Errorf("wireSfcNorthSouthNICElements: error creating l2fib: ewBD: '%s', i/f: '%s'", bd.Name, ifName)
+					return err
 				}
 
 			} else if sfc.Type == controller.SfcType_SFC_NS_NIC_VRF {
@@ -985,7 +1481,26 @@ func (cnpd *sfcCtlrL2CNPDriver) wireSfcNorthSouthNICElements(sfc *controller.Sfc
 func (cnpd *sfcCtlrL2CNPDriver) createVRFEntries(etcdVppSwitchKey string, sfcEntityElement *controller.SfcEntity_SfcElement,
 	ifaceName string, defaultDescription string) error {
 
-	for i, l3VRFRoute := range sfcEntityElement.GetL3VrfRoutes() {
+	if err := cnpd.createVRFRoutes(etcdVppSwitchKey, sfcEntityElement.GetL3VrfRoutes(), ifaceName,
+		defaultDescription); err != nil {
+		return err
+	}
+	if err := cnpd.createVRFRoutes(etcdVppSwitchKey, sfcEntityElement.GetL3VrfIpv6Routes(), ifaceName,
+		defaultDescription); err != nil {
+		return err
+	}
+
+	// the agent's arp table takes ipv6 neighbors too, the ip address selects the family
+	if err := cnpd.createVRFArpEntries(etcdVppSwitchKey, sfcEntityElement.GetL3ArpEntries(), ifaceName); err != nil {
+		return err
+	}
+	return cnpd.createVRFArpEntries(etcdVppSwitchKey, sfcEntityElement.GetL3Ipv6Neighbors(), ifaceName)
+}
+
+func (cnpd *sfcCtlrL2CNPDriver) createVRFRoutes(etcdVppSwitchKey string, l3VRFRoutes []*controller.L3VRFRoute,
+	ifaceName string, defaultDescription string) error {
+
+	for i, l3VRFRoute := range l3VRFRoutes {
 
 		weight := l3VRFRoute.Weight
 		if weight == 0 {
@@ -1011,7 +1526,13 @@ func (cnpd *sfcCtlrL2CNPDriver) createVRFEntries(etcdVppSwitchKey string, sfcEnt
 		log.Info("createVRFEntries: creating vrf route: '%s'", sr)
 	}
 
-	for i, l3VRFArpEntry := range sfcEntityElement.GetL3ArpEntries() {
+	return nil
+}
+
+func (cnpd *sfcCtlrL2CNPDriver) createVRFArpEntries(etcdVppSwitchKey string, l3VRFArpEntries []*controller.L3ArpEntry,
+	ifaceName string) error {
+
+	for i, l3VRFArpEntry := range l3VRFArpEntries {
 
 		ae, err := cnpd.createStaticArpEntry(etcdVppSwitchKey, l3VRFArpEntry.IpAddress, l3VRFArpEntry.PhysAddress,
 			ifaceName)
@@ -1083,7 +1604,7 @@ func (cnpd *sfcCtlrL2CNPDriver) wireSfcEastWestElements(sfc *controller.SfcEntit
 						bdName := "BD_INTERNAL_EW_" + sfc.Name + "_" + sfcEntityElement.EtcdVppSwitchKey
 						bd, err = cnpd.bridgedDomainCreateWithIfs(sfcEntityElement.EtcdVppSwitchKey, bdName, nil, sfc.BdParms)
 						if err != nil {
-							log.Errorf("WireInternalsForHostEntity: error creating BD: '%s'", bd.Name)
+							log.Errorf("WireInternalsForHostEntity: error creating BD: '%s'", bdName)
 							return err
 						}
 						heState = &heStateType{
@@ -1102,15 +1623,9 @@ func (cnpd *sfcCtlrL2CNPDriver) wireSfcEastWestElements(sfc *controller.SfcEntit
 				}
 
 				// now create the l2fib entries
-				if sfcEntityElement.L2FibMacs != nil {
-					for _, macAddr := range sfcEntityElement.L2FibMacs {
-						if _, err := cnpd.createL2FibEntry(sfcEntityElement.EtcdVppSwitchKey, bd.Name, macAddr,
-							ifName); err != nil {
-							log.Errorf("wireSfcNorthSouthNICElements: error creating l2fib: ewBD: '%s', mac: '%s', i/f: '%s'",
-								bd.Name, macAddr, ifName)
-							return err
-						}
-					}
+				if err := cnpd.createElementL2FibEntries(sfc, bd, sfcEntityElement, ifName); err != nil {
+					log.Errorf("wireSfcNorthSouthNICElements: error creating l2fib: ewBD: '%s', i/f: '%s'", bd.Name, ifName)
+					return err
 				}
 
 			} else {
@@ -1171,7 +1686,7 @@ func (cnpd *sfcCtlrL2CNPDriver) wireSfcEastWestElements(sfc *controller.SfcEntit
 						bdName := "BD_INTERNAL_EW_" + sfc.Name + "_" + sfcEntityElement.EtcdVppSwitchKey
 						bd, err = cnpd.bridgedDomainCreateWithIfs(sfcEntityElement.EtcdVppSwitchKey, bdName, nil, sfc.BdParms)
 						if err != nil {
-							log.Errorf("WireInternalsForHostEntity: error creating BD: '%s'", bd.Name)
+							log.Errorf("WireInternalsForHostEntity: error creating BD: '%s'", bdName)
 							return err
 						}
 						heState = &heStateType{
@@ -1191,15 +1706,9 @@ func (cnpd *sfcCtlrL2CNPDriver) wireSfcEastWestElements(sfc *controller.SfcEntit
 				}
 
 				// now create the l2fib entries
-				if sfcEntityElement.L2FibMacs != nil {
-					for _, macAddr := range sfcEntityElement.L2FibMacs {
-						if _, err := cnpd.createL2FibEntry(sfcEntityElement.EtcdVppSwitchKey, bd.Name, macAddr,
-							ifName); err != nil {
-							log.Errorf("wireSfcNorthSouthNICElements: error creating l2fib: ewBD: '%s', mac: '%s', i/f: '%s'",
-								bd.Name, macAddr, ifName)
-							return err
-						}
-					}
+				if err := cnpd.createElementL2FibEntries(sfc, bd, sfcEntityElement, ifName); err != nil {
+					log.Errorf("wireSfcNorthSouthNICElements: error creating l2fib: ewBD: '%s', i/f: '%s'", bd.Name, ifName)
+					return err
 				}
 
 			} else {
@@ -1261,12 +1770,23 @@ func (cnpd *sfcCtlrL2CNPDriver) createOneOrMoreInterContainerMemIfPairs(
 			vnf2Port = vnfElement2.PortLabel
 		}
 
+		// the vnfx containers inserted for repeat counts are not elements of the chain so are not tagged
+		var vnf1Description, vnf2Description string
+		vnf1Enabled, vnf2Enabled := true, true
+		if repeatCount == 0 {
+			vnf1Description = sfcElementDescription(sfcName, vnfElement1)
+			vnf1Enabled = !vnfElement1.AdminDown
+		}
+		if repeatCount == vnfRepeatCount {
+			vnf2Description = sfcElementDescription(sfcName, vnfElement2)
+			vnf2Enabled = !vnfElement2.AdminDown
+		}
+
 		var memifID uint32
 
 		sfcID, _ := cnpd.DatastoreSFCIDsRetrieve(sfcName, container1Name, vnf1Port)
 		if sfcID == nil || sfcID.MemifId == 0 {
-			cnpd.seq.MemIfID++
-			memifID = cnpd.seq.MemIfID
+			memifID = cnpd.nextID(idSpaceMemIf, l2driver.SFCContainerPortIDsNameKey(sfcName, container1Name, vnf1Port))
 		} else {
 			memifID = sfcID.MemifId
 		}
@@ -1278,12 +1798,14 @@ func (cnpd *sfcCtlrL2CNPDriver) createOneOrMoreInterContainerMemIfPairs(
 			container2Name, vnf2Port,
 			mtu,
 			rxMode,
-			memifID); err != nil {
+			memifID,
+			vnf1Description, vnf2Description,
+			vnf1Enabled, vnf2Enabled); err != nil {
 			return err
 		}
 
 		key, sfcID, err := cnpd.DatastoreSFCIDsCreate(sfcName, container1Name, vnf1Port,
-			0, 0, memifID, 0)
+			0, 0, 0, memifID, 0)
 		if err == nil && cnpd.reconcileInProgress {
 			cnpd.reconcileAfter.sfcIDs[key] = *sfcID
 		}
@@ -1299,14 +1821,16 @@ func (cnpd *sfcCtlrL2CNPDriver) createInterContainerMemIfPair(
 	vnf2Container string, vnf2Port string,
 	mtu uint32,
 	rxMode controller.RxModeType,
-	memIFID uint32) error {
+	memIFID uint32,
+	vnf1Description string, vnf2Description string,
+	vnf1Enabled bool, vnf2Enabled bool) error {
 
 	log.Infof("createInterContainerMemIfPair: vnf1: '%s'/'%s', vnf2: '%s'/'%s', memIfID: '%d'",
 		vnf1Container, vnf1Port, vnf2Container, vnf2Port, memIFID)
 
 	// create a memif in the vnf container 1
 	if _, err := cnpd.memIfCreate(vnf1Container, vnf1Port, memIFID, true, vnf1Container,
-		"", "", "", mtu, rxMode); err != nil {
+		"", "", "", mtu, rxMode, vnf1Description, vnf1Enabled); err != nil {
 		log.Errorf("createInterContainerMemIfPair: error creating memIf for container: '%s'/'%s', memIF: '%d'",
 			vnf1Container, vnf1Port, memIFID)
 		return err
@@ -1314,7 +1838,7 @@ func (cnpd *sfcCtlrL2CNPDriver) createInterContainerMemIfPair(
 
 	// create a memif in the vnf container 2
 	if _, err := cnpd.memIfCreate(vnf2Container, vnf2Port, memIFID, false, vnf1Container,
-		"", "", "", mtu, rxMode); err != nil {
+		"", "", "", mtu, rxMode, vnf2Description, vnf2Enabled); err != nil {
 
 		log.Errorf("createInterContainerMemIfPair: error creating memIf for container: '%s'/'%s', memIF: '%d'",
 			vnf1Container, vnf1Port, memIFID)
@@ -1324,6 +1848,51 @@ func (cnpd *sfcCtlrL2CNPDriver) createInterContainerMemIfPair(
 	return nil
 }
 
+// sfcElementAddress returns the element's address in the family of the prefix: the configured one, with the
+// default prefix length if it has none, else the one allocated from the chain's prefix, or the one of the stored
+// id, when addresses are generated
+func (cnpd *sfcCtlrL2CNPDriver) sfcElementAddress(sfc *controller.SfcEntity,
+	vnfChainElement *controller.SfcEntity_SfcElement, prefix string, addr string, defaultPrefixLen string,
+	storedID uint32, generateAddresses bool) (string, uint32, error) {
+
+	var ipAddress string
+	var ipID uint32
+	var err error
+
+	if addr == "" {
+		if generateAddresses && prefix != "" {
+			if storedID == 0 {
+				ipAddress, ipID, err = cnpd.allocateIPAddress(prefix, sfc.Name, vnfChainElement.Container,
+					vnfChainElement.PortLabel)
+				if err != nil {
+					return "", 0, err
+				}
+			} else {
+				ipAddress, err = ipam.SetIpIDInSubnet(prefix, storedID)
+				if err != nil {
+					return "", 0, err
+				}
+				ipID = storedID
+			}
+		}
+	} else {
+		strs := strings.Split(addr, "/")
+		if len(strs) == 2 {
+			ipAddress = addr
+		} else {
+			ipAddress = addr + defaultPrefixLen
+		}
+		if prefix != "" {
+			ipam.SetIpAddrIfInsideSubnet(prefix, strs[0])
+		}
+	}
+	if prefix != "" {
+		log.Info("sfcElementAddress: ", ipam.DumpSubnet(prefix), ipAddress)
+	}
+
+	return ipAddress, ipID, nil
+}
+
 // createMemIfPair creates memif pair and returns vswitch-end memif interface name
 func (cnpd *sfcCtlrL2CNPDriver) createMemIfPair(sfc *controller.SfcEntity, hostName string,
 	vnfChainElement *controller.SfcEntity_SfcElement, generateAddresses bool) (string, error) {
@@ -1332,58 +1901,40 @@ func (cnpd *sfcCtlrL2CNPDriver) createMemIfPair(sfc *controller.SfcEntity, hostN
 
 	var memifID uint32
 	var macAddrID uint32
-	var ipID uint32
 
 	sfcID, err := cnpd.DatastoreSFCIDsRetrieve(sfc.Name, vnfChainElement.Container, vnfChainElement.PortLabel)
 	if sfcID == nil || sfcID.MemifId == 0 {
-		cnpd.seq.MemIfID++
-		memifID = cnpd.seq.MemIfID
+		memifID = cnpd.nextID(idSpaceMemIf, l2driver.SFCContainerPortIDsNameKey(sfc.Name, vnfChainElement.Container,
+			vnfChainElement.PortLabel))
 	} else {
 		memifID = sfcID.MemifId
 	}
 
 	var macAddress string
-	var ipv4Address string
+
+	var storedIPID, storedIpv6ID uint32
+	if sfcID != nil {
+		storedIPID, storedIpv6ID = sfcID.IpId, sfcID.Ipv6Id
+	}
 
 	// the sfc controller can generate addresses if not provided
-	if vnfChainElement.Ipv4Addr == "" {
-		if generateAddresses {
-			if sfc.SfcIpv4Prefix != "" {
-				if sfcID == nil || sfcID.IpId == 0 {
-					ipv4Address, ipID, err = ipam.AllocateFromSubnet(sfc.SfcIpv4Prefix)
-					if err != nil {
-						return "", err
-					}
-				} else {
-					ipv4Address, err = ipam.SetIpIDInSubnet(sfc.SfcIpv4Prefix, sfcID.IpId)
-					if err != nil {
-						return "", err
-					}
-					ipID = sfcID.IpId
-				}
-			}
-		}
-	} else {
-		strs := strings.Split(vnfChainElement.Ipv4Addr, "/")
-		if len(strs) == 2 {
-			ipv4Address = vnfChainElement.Ipv4Addr
-		} else {
-			ipv4Address = vnfChainElement.Ipv4Addr + "/24"
-		}
-		if sfc.SfcIpv4Prefix != "" {
-			ipam.SetIpAddrIfInsideSubnet(sfc.SfcIpv4Prefix, strs[0])
-		}
+	ipv4Address, ipID, err := cnpd.sfcElementAddress(sfc, vnfChainElement, sfc.SfcIpv4Prefix,
+		vnfChainElement.Ipv4Addr, "/24", storedIPID, generateAddresses)
+	if err != nil {
+		return "", err
 	}
-	if sfc.SfcIpv4Prefix != "" {
-		log.Info("createMemIfPair: ", ipam.DumpSubnet(sfc.SfcIpv4Prefix), ipv4Address)
+	ipv6Address, ipv6ID, err := cnpd.sfcElementAddress(sfc, vnfChainElement, sfc.SfcIpv6Prefix,
+		vnfChainElement.Ipv6Addr, "", storedIpv6ID, generateAddresses)
+	if err != nil {
+		return "", err
 	}
 
 	if vnfChainElement.MacAddr == "" {
 		if generateAddresses {
 			if sfcID == nil || sfcID.MacAddrId == 0 {
-				cnpd.seq.MacInstanceID++
-				macAddress = formatMacAddress(cnpd.seq.MacInstanceID)
-				macAddrID = cnpd.seq.MacInstanceID
+				macAddrID = cnpd.nextID(idSpaceMacInstance, l2driver.SFCContainerPortIDsNameKey(sfc.Name,
+					vnfChainElement.Container, vnfChainElement.PortLabel))
+				macAddress = formatMacAddress(macAddrID)
 			} else {
 				macAddress = formatMacAddress(sfcID.MacAddrId)
 				macAddrID = sfcID.MacAddrId
@@ -1395,31 +1946,35 @@ func (cnpd *sfcCtlrL2CNPDriver) createMemIfPair(sfc *controller.SfcEntity, hostN
 
 	mtu := cnpd.getMtu(vnfChainElement.Mtu)
 	rxMode := vnfChainElement.RxMode
+	description := sfcElementDescription(sfc.Name, vnfChainElement)
+	// a quarantined element keeps its wiring with its i/f's disabled, so it is isolated until it is released
+	enabled := !vnfChainElement.AdminDown
 
 	// create a memif in the vnf container
 	memIfName := vnfChainElement.PortLabel
 	if _, err := cnpd.memIfCreate(vnfChainElement.Container, memIfName, memifID, false, vnfChainElement.EtcdVppSwitchKey,
-		ipv4Address, macAddress, vnfChainElement.Ipv6Addr, mtu, rxMode); err != nil {
+		ipv4Address, macAddress, ipv6Address, mtu, rxMode, description, enabled); err != nil {
 		log.Errorf("createMemIfPair: error creating memIf for container: '%s'", memIfName)
 		return "", err
 	}
 
 	// now create a memif for the vpp switch
 	memIfName = "IF_MEMIF_VSWITCH_" + vnfChainElement.Container + "_" + vnfChainElement.PortLabel
-	memIf, err := cnpd.memIfCreate(vnfChainElement.EtcdVppSwitchKey, memIfName, memifID,
-		true, vnfChainElement.EtcdVppSwitchKey, "", "", "", mtu, rxMode)
+	_, err = cnpd.memIfCreate(vnfChainElement.EtcdVppSwitchKey, memIfName, memifID,
+		true, vnfChainElement.EtcdVppSwitchKey, "", "", "", mtu, rxMode, description, enabled)
 	if err != nil {
-		log.Errorf("createMemIfPair: error creating memIf for vpp switch: '%s'", memIf.Name)
+		log.Errorf("createMemIfPair: error creating memIf for vpp switch: '%s'", memIfName)
 		return "", err
 	}
 
 	key, sfcID, err := cnpd.DatastoreSFCIDsCreate(sfc.Name, vnfChainElement.Container, vnfChainElement.PortLabel,
-		ipID, macAddrID, memifID, 0)
+		ipID, ipv6ID, macAddrID, memifID, 0)
 	if err == nil && cnpd.reconcileInProgress {
 		cnpd.reconcileAfter.sfcIDs[key] = *sfcID
 	}
 
-	cnpd.setSfcInterfaceIPAndMac(vnfChainElement.Container, vnfChainElement.PortLabel, ipv4Address, macAddress)
+	cnpd.setSfcInterfaceIPAndMac(vnfChainElement.Container, vnfChainElement.PortLabel, ipv4Address, ipv6Address,
+		macAddress)
 
 	return memIfName, err
 }
@@ -1444,6 +1999,9 @@ func (cnpd *sfcCtlrL2CNPDriver) createMemIfPairAndAddToBridge(sfc *controller.Sf
 		log.Errorf("createMemIfPairAndAddToBridge: error creating BD: '%s'", bd.Name)
 		return "", err
 	}
+	if err := cnpd.createElementPortACL(vnfChainElement, memIfName); err != nil {
+		return "", err
+	}
 
 	return memIfName, nil
 }
@@ -1456,56 +2014,38 @@ func (cnpd *sfcCtlrL2CNPDriver) createAFPacketVEthPair(sfc *controller.SfcEntity
 
 	var macAddrID uint32
 	var vethID uint32
-	var ipID uint32
 	var macAddress string
-	var ipv4Address string
-
-	ipv6Address := vnfChainElement.Ipv6Addr
 
 	sfcID, err := cnpd.DatastoreSFCIDsRetrieve(sfc.Name, vnfChainElement.Container, vnfChainElement.PortLabel)
 
 	if sfcID == nil || sfcID.VethId == 0 {
-		cnpd.seq.VethID++
-		vethID = cnpd.seq.VethID
+		vethID = cnpd.nextID(idSpaceVeth, l2driver.SFCContainerPortIDsNameKey(sfc.Name, vnfChainElement.Container,
+			vnfChainElement.PortLabel))
 	} else {
 		vethID = sfcID.VethId
 	}
 
-	if vnfChainElement.Ipv4Addr == "" {
-		if sfc.SfcIpv4Prefix != "" {
-			if sfcID == nil || sfcID.IpId == 0 {
-				ipv4Address, ipID, err = ipam.AllocateFromSubnet(sfc.SfcIpv4Prefix)
-				if err != nil {
-					return "", err
-				}
-			} else {
-				ipv4Address, err = ipam.SetIpIDInSubnet(sfc.SfcIpv4Prefix, sfcID.IpId)
-				if err != nil {
-					return "", err
-				}
-				ipID = sfcID.IpId
-			}
-		}
-	} else {
-		strs := strings.Split(vnfChainElement.Ipv4Addr, "/")
-		if len(strs) == 2 {
-			ipv4Address = vnfChainElement.Ipv4Addr
-		} else {
-			ipv4Address = vnfChainElement.Ipv4Addr + "/24"
-		}
-		if sfc.SfcIpv4Prefix != "" {
-			ipam.SetIpAddrIfInsideSubnet(sfc.SfcIpv4Prefix, strs[0])
-		}
+	var storedIPID, storedIpv6ID uint32
+	if sfcID != nil {
+		storedIPID, storedIpv6ID = sfcID.IpId, sfcID.Ipv6Id
 	}
-	if sfc.SfcIpv4Prefix != "" {
-		log.Info("createAFPacketVEthPair: ", ipam.DumpSubnet(sfc.SfcIpv4Prefix), ipv4Address)
+
+	ipv4Address, ipID, err := cnpd.sfcElementAddress(sfc, vnfChainElement, sfc.SfcIpv4Prefix,
+		vnfChainElement.Ipv4Addr, "/24", storedIPID, true)
+	if err != nil {
+		return "", err
+	}
+	ipv6Address, ipv6ID, err := cnpd.sfcElementAddress(sfc, vnfChainElement, sfc.SfcIpv6Prefix,
+		vnfChainElement.Ipv6Addr, "", storedIpv6ID, true)
+	if err != nil {
+		return "", err
 	}
 
 	if vnfChainElement.MacAddr == "" {
 		if sfcID == nil || sfcID.MacAddrId == 0 {
-			cnpd.seq.MacInstanceID++
-			macAddress = formatMacAddress(cnpd.seq.MacInstanceID)
-			macAddrID = cnpd.seq.MacInstanceID
+			macAddrID = cnpd.nextID(idSpaceMacInstance, l2driver.SFCContainerPortIDsNameKey(sfc.Name,
+				vnfChainElement.Container, vnfChainElement.PortLabel))
+			macAddress = formatMacAddress(macAddrID)
 		} else {
 			macAddress = formatMacAddress(sfcID.MacAddrId)
 			macAddrID = sfcID.MacAddrId
@@ -1516,21 +2056,26 @@ func (cnpd *sfcCtlrL2CNPDriver) createAFPacketVEthPair(sfc *controller.SfcEntity
 
 	mtu := cnpd.getMtu(vnfChainElement.Mtu)
 	rxMode := vnfChainElement.RxMode
+	description := sfcElementDescription(sfc.Name, vnfChainElement)
+	enabled := !vnfChainElement.AdminDown
 
 	// Create a VETH if for the vnf container. VETH will get created by the agent from a more privileged vswitch.
 	// Note: In Linux kernel the length of an interface name is limited by the constant IFNAMSIZ.
 	//       In most distributions this is 16 characters including the terminating NULL character.
 	//		 The hostname uses chars from the container, and port name plus a unique id base 36
-	//       for a total of at most 15 chars. 3 chars for base36 given 36x36x36 = lots of interfaces
+	//       for a total of at most 15 chars, it is registered per host so it is never given out twice.
 
 	veth1Name := "IF_VETH_VNF_" + vnfChainElement.Container + "_" + vnfChainElement.PortLabel
 	veth2Name := "IF_VETH_VSWITCH_" + vnfChainElement.Container + "_" + vnfChainElement.PortLabel
 
 	host1Name := vnfChainElement.PortLabel
 
-	vethIDStr := strconv.FormatUint(uint64(vethID), 36)
-	baseHostName := constructBaseHostName(vnfChainElement.Container, vnfChainElement.PortLabel, vethIDStr)
-	host2Name := baseHostName + "_" + vethIDStr
+	host2Name, err := cnpd.allocateHostIfName(vnfChainElement.EtcdVppSwitchKey,
+		l2driver.SFCContainerPortIDsNameKey(sfc.Name, vnfChainElement.Container, vnfChainElement.PortLabel),
+		vnfChainElement.Container, vnfChainElement.PortLabel, vethID)
+	if err != nil {
+		return "", err
+	}
 
 	ipv4AddrForVEth := ipv4Address
 	ipv4AddrForAFP := ipv4Address
@@ -1542,43 +2087,84 @@ func (cnpd *sfcCtlrL2CNPDriver) createAFPacketVEthPair(sfc *controller.SfcEntity
 	}
 	// Configure the VETH interface for the VNF end
 	if err := cnpd.vEthIfCreate(vnfChainElement.EtcdVppSwitchKey, veth1Name, host1Name, veth2Name,
-		vnfChainElement.Container, macAddress, ipv4AddrForVEth, ipv6AddrForVEth, mtu); err != nil {
+		vnfChainElement.Container, macAddress, ipv4AddrForVEth, ipv6AddrForVEth, mtu, description,
+		enabled); err != nil {
 		log.Errorf("createAFPacketVEthPair: error creating veth if '%s' for container: '%s'", veth1Name,
 			vnfChainElement.Container)
 		return "", err
 	}
+	// the routes and arp entries of a non vpp container go into its namespace with the veth
+	if vnfChainElement.Type == controller.SfcElementType_NON_VPP_CONTAINER_AFP {
+		if err := cnpd.createLinuxL3Entries(vnfChainElement.EtcdVppSwitchKey, veth1Name, vnfChainElement,
+			description); err != nil {
+			log.Errorf("createAFPacketVEthPair: error creating linux routes for container: '%s'",
+				vnfChainElement.Container)
+			return "", err
+		}
+	}
 	// Configure the VETH interface for the VSWITCH end
 	if err := cnpd.vEthIfCreate(vnfChainElement.EtcdVppSwitchKey, veth2Name, host2Name, veth1Name,
-		vnfChainElement.EtcdVppSwitchKey, "", "", "", mtu); err != nil {
+		vnfChainElement.EtcdVppSwitchKey, "", "", "", mtu, description, enabled); err != nil {
 		log.Errorf("createAFPacketVEthPair: error creating veth if '%s' for container: '%s'", veth2Name,
 			vnfChainElement.EtcdVppSwitchKey)
 		return "", err
 	}
+	// a kernel host has no vpp to plug the host end into, its kernel routes the element's addresses to it
+	if cnpd.kernelHost(vnfChainElement.EtcdVppSwitchKey) {
+		if err := cnpd.createKernelHostRoutes(vnfChainElement, veth2Name, ipv4Address, ipv6Address,
+			description); err != nil {
+			log.Errorf("createAFPacketVEthPair: error creating kernel routes for container: '%s'",
+				vnfChainElement.Container)
+			return "", err
+		}
+		key, sfcID, err := cnpd.DatastoreSFCIDsCreate(sfc.Name, vnfChainElement.Container,
+			vnfChainElement.PortLabel, ipID, ipv6ID, macAddrID, 0, vethID)
+		if err == nil && cnpd.reconcileInProgress {
+			cnpd.reconcileAfter.sfcIDs[key] = *sfcID
+		}
+		cnpd.setSfcInterfaceIPAndMac(vnfChainElement.Container, vnfChainElement.PortLabel, ipv4Address,
+			ipv6Address, macAddress)
+		return veth2Name, nil
+	}
 	// create af_packet for the vnf -end of the veth
 	if vnfChainElement.Type == controller.SfcElementType_VPP_CONTAINER_AFP {
-		afPktIf1, err := cnpd.afPacketCreate(vnfChainElement.Container, vnfChainElement.PortLabel,
-			host1Name, ipv4AddrForAFP, macAddress, ipv6AddrForAFP, mtu, rxMode)
+		_, err := cnpd.afPacketCreate(vnfChainElement.Container, vnfChainElement.PortLabel,
+			host1Name, ipv4AddrForAFP, macAddress, ipv6AddrForAFP, mtu, rxMode, description, enabled)
 		if err != nil {
-			log.Errorf("createAFPacketVEthPair: error creating afpacket for vpp switch: '%s'", afPktIf1.Name)
+			log.Errorf("createAFPacketVEthPair: error creating afpacket for container: '%s'",
+				vnfChainElement.Container)
 			return "", err
 		}
 	}
 	// create af_packet for the vswitch -end of the veth
 	afPktName := "IF_AFPIF_VSWITCH_" + vnfChainElement.Container + "_" + vnfChainElement.PortLabel
 	afPktIf2, err := cnpd.afPacketCreate(vnfChainElement.EtcdVppSwitchKey, afPktName, host2Name,
-		"", "", "", mtu, rxMode)
+		"", "", "", mtu, rxMode, description, enabled)
 	if err != nil {
-		log.Errorf("createAFPacketVEthPair: error creating afpacket for vpp switch: '%s'", afPktIf2.Name)
+		log.Errorf("createAFPacketVEthPair: error creating afpacket for vpp switch: '%s'", afPktName)
 		return "", err
 	}
+	// a routed vswitch end borrows the address of a host loopback so the link takes no subnet space
+	if vnfChainElement.Unnumbered {
+		loopIfName := "IF_LOOPBACK_H_" + vnfChainElement.EtcdVppSwitchKey
+		if vnfChainElement.UnnumberedLoopback != "" {
+			loopIfName += "_" + vnfChainElement.UnnumberedLoopback
+		}
+		if err := cnpd.interfaceSetUnnumbered(vnfChainElement.EtcdVppSwitchKey, afPktIf2, loopIfName); err != nil {
+			log.Errorf("createAFPacketVEthPair: error setting afpacket: '%s' unnumbered to: '%s'", afPktIf2.Name,
+				loopIfName)
+			return "", err
+		}
+	}
 
 	key, sfcID, err := cnpd.DatastoreSFCIDsCreate(sfc.Name, vnfChainElement.Container, vnfChainElement.PortLabel,
-		ipID, macAddrID, 0, vethID)
+		ipID, ipv6ID, macAddrID, 0, vethID)
 	if err == nil && cnpd.reconcileInProgress {
 		cnpd.reconcileAfter.sfcIDs[key] = *sfcID
 	}
 
-	cnpd.setSfcInterfaceIPAndMac(vnfChainElement.Container, vnfChainElement.PortLabel, ipv4Address, macAddress)
+	cnpd.setSfcInterfaceIPAndMac(vnfChainElement.Container, vnfChainElement.PortLabel, ipv4Address, ipv6Address,
+		macAddress)
 
 	return afPktIf2.Name, nil
 }
@@ -1604,6 +2190,9 @@ func (cnpd *sfcCtlrL2CNPDriver) createAFPacketVEthPairAndAddToBridge(sfc *contro
 		log.Errorf("createAFPacketVEthPairAndAddToBridge: error creating BD: '%s'", bd.Name)
 		return "", err
 	}
+	if err := cnpd.createElementPortACL(vnfChainElement, afPktIfName); err != nil {
+		return "", err
+	}
 
 	return afPktIfName, nil
 }
@@ -1628,8 +2217,7 @@ func (cnpd *sfcCtlrL2CNPDriver) bridgedDomainCreateWithIfs(etcdVppSwitchKey stri
 
 		log.Println(bd)
 
-		rc := NewRemoteClientTxn(etcdVppSwitchKey, cnpd.dbFactory)
-		err := rc.Put().BD(bd).Send().ReceiveReply()
+		err := cnpd.agentPut(etcdVppSwitchKey, bd)
 
 		if err != nil {
 			log.Error("vxLanCreate: databroker.Store: ", err)
@@ -1648,14 +2236,7 @@ func (cnpd *sfcCtlrL2CNPDriver) bridgedDomainAssociateWithIfs(etcdVppSwitchKey s
 
 	// only add the interface to ewBD array if it is not already in the bridge's interface array
 	for _, iface := range ifs {
-		found := false
-		for _, bi := range bd.Interfaces {
-			if bi.Name == iface.Name {
-				found = true
-				break
-			}
-		}
-		if !found {
+		if !bridgeDomainHasIf(bd, iface.Name) {
 			bd.Interfaces = append(bd.Interfaces, iface)
 		}
 	}
@@ -1666,8 +2247,8 @@ func (cnpd *sfcCtlrL2CNPDriver) bridgedDomainAssociateWithIfs(etcdVppSwitchKey s
 
 		log.Println(bd)
 
-		rc := NewRemoteClientTxn(etcdVppSwitchKey, cnpd.dbFactory)
-		err := rc.Put().BD(bd).Send().ReceiveReply()
+		// the append is written against the stored bd so the i/f's others added to it are not lost
+		err := cnpd.agentBridgeDomainPut(etcdVppSwitchKey, bd)
 
 		if err != nil {
 			log.Error("vxLanCreate: databroker.Store: ", err)
@@ -1679,11 +2260,45 @@ func (cnpd *sfcCtlrL2CNPDriver) bridgedDomainAssociateWithIfs(etcdVppSwitchKey s
 	return nil
 }
 
+// vxlanParmsForEE merges the ee's encap overrides on top of the system wide vxlan parms, field by field
+func (cnpd *sfcCtlrL2CNPDriver) vxlanParmsForEE(ee *controller.ExternalEntity) *controller.VxlanParms {
+
+	sysParms := cnpd.l2CNPEntityCache.SysParms.GetVxlanParms()
+	eeParms := ee.GetHostVxlan().GetVxlanParms()
+	if eeParms == nil {
+		return sysParms
+	}
+
+	parms := &controller.VxlanParms{}
+	if sysParms != nil {
+		*parms = *sysParms
+	}
+	if eeParms.DstPort != 0 {
+		parms.DstPort = eeParms.DstPort
+	}
+	if eeParms.Tos != 0 {
+		parms.Tos = eeParms.Tos
+	}
+	if eeParms.Ttl != 0 {
+		parms.Ttl = eeParms.Ttl
+	}
+
+	return parms
+}
+
 func (cnpd *sfcCtlrL2CNPDriver) vxLanCreate(etcdVppSwitchKey string, ifname string, vni uint32,
-	srcStr string, dstStr string) (*interfaces.Interfaces_Interface, error) {
+	srcStr string, dstStr string, parms *controller.VxlanParms) (*interfaces.Interfaces_Interface, error) {
 
-	src := stripSlashAndSubnetIpv4Address(srcStr)
-	dst := stripSlashAndSubnetIpv4Address(dstStr)
+	src := stripPrefixLen(srcStr)
+	dst := stripPrefixLen(dstStr)
+
+	// the agent's vxlan model only carries src/dst/vni so non default encap parms cannot be pushed down yet,
+	// make it visible that the tunnel will come up with the agent's port/tos/ttl instead
+	if parms != nil && ((parms.DstPort != 0 && parms.DstPort != vxlanDefaultDstPort) ||
+		parms.Tos != 0 || parms.Ttl != 0) {
+		log.Warnf("vxLanCreate: i/f: '%s' encap parms not supported by agent vxlan model, ignoring: %v",
+			ifname, parms)
+	}
 
 	iface := &interfaces.Interfaces_Interface{
 		Name:    ifname,
@@ -1702,8 +2317,7 @@ func (cnpd *sfcCtlrL2CNPDriver) vxLanCreate(etcdVppSwitchKey string, ifname stri
 
 		log.Println(*iface)
 
-		rc := NewRemoteClientTxn(etcdVppSwitchKey, cnpd.dbFactory)
-		err := rc.Put().VppInterface(iface).Send().ReceiveReply()
+		err := cnpd.agentPut(etcdVppSwitchKey, iface)
 
 		if err != nil {
 			log.Error("vxLanCreate: databroker.Store: ", err)
@@ -1738,12 +2352,13 @@ func constructIpv4AndV6AddressArray(ipv4 string, ipv6 string) []string {
 
 func (cnpd *sfcCtlrL2CNPDriver) memIfCreate(etcdPrefix string, memIfName string, memifID uint32, isMaster bool,
 	masterContainer string, ipv4 string, macAddress string, ipv6 string, mtu uint32,
-	rxMode controller.RxModeType) (*interfaces.Interfaces_Interface, error) {
+	rxMode controller.RxModeType, description string, enabled bool) (*interfaces.Interfaces_Interface, error) {
 
 	memIf := &interfaces.Interfaces_Interface{
 		Name:        memIfName,
+		Description: description,
 		Type:        interfaces.InterfaceType_MEMORY_INTERFACE,
-		Enabled:     true,
+		Enabled:     enabled,
 		PhysAddress: macAddress,
 		Mtu:         mtu,
 		IpAddresses: constructIpv4AndV6AddressArray(ipv4, ipv6),
@@ -1762,8 +2377,7 @@ func (cnpd *sfcCtlrL2CNPDriver) memIfCreate(etcdPrefix string, memIfName string,
 
 		log.Println(*memIf)
 
-		rc := NewRemoteClientTxn(etcdPrefix, cnpd.dbFactory)
-		err := rc.Put().VppInterface(memIf).Send().ReceiveReply()
+		err := cnpd.agentPut(etcdPrefix, memIf)
 
 		if err != nil {
 			log.Error("memIfCreate: databroker.Store: ", err)
@@ -1790,10 +2404,11 @@ func rxModeControllerToInterface(contrtollerRxMode controller.RxModeType) *inter
 }
 
 func (cnpd *sfcCtlrL2CNPDriver) createEthernet(etcdPrefix string, ifname string, ipv4 string, macAddr string,
-	ipv6 string, mtu uint32, rxMode controller.RxModeType) error {
+	ipv6 string, mtu uint32, rxMode controller.RxModeType, description string) error {
 
 	iface := &interfaces.Interfaces_Interface{
 		Name:        ifname,
+		Description: description,
 		Type:        interfaces.InterfaceType_ETHERNET_CSMACD,
 		Enabled:     true,
 		PhysAddress: macAddr,
@@ -1809,8 +2424,7 @@ func (cnpd *sfcCtlrL2CNPDriver) createEthernet(etcdPrefix string, ifname string,
 
 		log.Println(*iface)
 
-		rc := NewRemoteClientTxn(etcdPrefix, cnpd.dbFactory)
-		err := rc.Put().VppInterface(iface).Send().ReceiveReply()
+		err := cnpd.agentPut(etcdPrefix, iface)
 
 		if err != nil {
 			log.Error("createEthernet: databroker.Store: ", err)
@@ -1823,12 +2437,14 @@ func (cnpd *sfcCtlrL2CNPDriver) createEthernet(etcdPrefix string, ifname string,
 }
 
 func (cnpd *sfcCtlrL2CNPDriver) afPacketCreate(etcdPrefix string, ifName string, hostIfName string, ipv4 string,
-	macAddress string, ipv6 string, mtu uint32, rxMode controller.RxModeType) (*interfaces.Interfaces_Interface, error) {
+	macAddress string, ipv6 string, mtu uint32, rxMode controller.RxModeType,
+	description string, enabled bool) (*interfaces.Interfaces_Interface, error) {
 
 	afPacketIf := &interfaces.Interfaces_Interface{
 		Name:        ifName,
+		Description: description,
 		Type:        interfaces.InterfaceType_AF_PACKET_INTERFACE,
-		Enabled:     true,
+		Enabled:     enabled,
 		PhysAddress: macAddress,
 		IpAddresses: constructIpv4AndV6AddressArray(ipv4, ipv6),
 		Mtu:         mtu,
@@ -1845,8 +2461,7 @@ func (cnpd *sfcCtlrL2CNPDriver) afPacketCreate(etcdPrefix string, ifName string,
 
 		log.Println(*afPacketIf)
 
-		rc := NewRemoteClientTxn(etcdPrefix, cnpd.dbFactory)
-		err := rc.Put().VppInterface(afPacketIf).Send().ReceiveReply()
+		err := cnpd.agentPut(etcdPrefix, afPacketIf)
 
 		if err != nil {
 			log.Error("afPacketCreate: databroker.Store: ", err)
@@ -1858,6 +2473,28 @@ func (cnpd *sfcCtlrL2CNPDriver) afPacketCreate(etcdPrefix string, ifName string,
 	return afPacketIf, nil
 }
 
+// interfaceSetUnnumbered re-renders the i/f without addresses of its own, borrowing the loopback's instead
+func (cnpd *sfcCtlrL2CNPDriver) interfaceSetUnnumbered(etcdPrefix string, iface *interfaces.Interfaces_Interface,
+	loopIfName string) error {
+
+	iface.IpAddresses = nil
+	iface.Unnumbered = &interfaces.Interfaces_Interface_Unnumbered{
+		IsUnnumbered:    true,
+		InterfaceWithIP: loopIfName,
+	}
+
+	if cnpd.reconcileInProgress {
+		cnpd.reconcileInterface(etcdPrefix, iface)
+	} else {
+		if err := cnpd.agentPut(etcdPrefix, iface); err != nil {
+			log.Error("interfaceSetUnnumbered: databroker.Store: ", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (cnpd *sfcCtlrL2CNPDriver) createLoopback(etcdPrefix string, ifname string, physAddr string, ipv4 string,
 	ipv6 string, mtu uint32, rxMode controller.RxModeType) error {
 
@@ -1878,8 +2515,7 @@ func (cnpd *sfcCtlrL2CNPDriver) createLoopback(etcdPrefix string, ifname string,
 
 		log.Println(*iface)
 
-		rc := NewRemoteClientTxn(etcdPrefix, cnpd.dbFactory)
-		err := rc.Put().VppInterface(iface).Send().ReceiveReply()
+		err := cnpd.agentPut(etcdPrefix, iface)
 
 		if err != nil {
 			log.Error("createLoopback: databroker.Store: ", err)
@@ -1892,12 +2528,13 @@ func (cnpd *sfcCtlrL2CNPDriver) createLoopback(etcdPrefix string, ifname string,
 }
 
 func (cnpd *sfcCtlrL2CNPDriver) vEthIfCreate(etcdPrefix string, ifname string, hostIfName, peerIfName string, container string,
-	physAddr string, ipv4 string, ipv6 string, mtu uint32) error {
+	physAddr string, ipv4 string, ipv6 string, mtu uint32, description string, enabled bool) error {
 
 	linuxif := &linuxIntf.LinuxInterfaces_Interface{
 		Name:        ifname,
+		Description: description,
 		Type:        linuxIntf.LinuxInterfaces_VETH,
-		Enabled:     true,
+		Enabled:     enabled,
 		PhysAddress: physAddr,
 		HostIfName:  hostIfName,
 		IpAddresses: constructIpv4AndV6AddressArray(ipv4, ipv6),
@@ -1917,8 +2554,7 @@ func (cnpd *sfcCtlrL2CNPDriver) vEthIfCreate(etcdPrefix string, ifname string, h
 
 		log.Println(linuxif)
 
-		rc := NewRemoteClientTxn(etcdPrefix, cnpd.dbFactory)
-		err := rc.Put().LinuxInterface(linuxif).Send().ReceiveReply()
+		err := cnpd.agentPut(etcdPrefix, linuxif)
 
 		if err != nil {
 			log.Error("createLoopback: databroker.Store: ", err)
@@ -1929,6 +2565,75 @@ func (cnpd *sfcCtlrL2CNPDriver) vEthIfCreate(etcdPrefix string, ifname string, h
 	return nil
 }
 
+// createLinuxL3Entries renders the linux routes, default gateway and static arp entries of a non vpp
+// container's element in the container's namespace, on the container end of its veth
+func (cnpd *sfcCtlrL2CNPDriver) createLinuxL3Entries(etcdPrefix string, vethName string,
+	vnfChainElement *controller.SfcEntity_SfcElement, description string) error {
+
+	namePrefix := vnfChainElement.Container + "_" + vnfChainElement.PortLabel + "_"
+	namespace := &linuxL3.LinuxStaticRoutes_Route_Namespace{
+		Type:         linuxL3.LinuxStaticRoutes_Route_Namespace_MICROSERVICE_REF_NS,
+		Microservice: vnfChainElement.Container,
+	}
+
+	routes := make([]*linuxL3.LinuxStaticRoutes_Route, 0, len(vnfChainElement.LinuxRoutes)+1)
+	if vnfChainElement.LinuxDefaultGw != "" {
+		routes = append(routes, &linuxL3.LinuxStaticRoutes_Route{
+			Name:        "ROUTE_" + namePrefix + "DEFAULT",
+			Default:     true,
+			Namespace:   namespace,
+			Interface:   vethName,
+			Description: description,
+			GwAddr:      stripSlashAndSubnetIpv4Address(vnfChainElement.LinuxDefaultGw),
+		})
+	}
+	for _, linuxRoute := range vnfChainElement.GetLinuxRoutes() {
+		routeDescription := description
+		if linuxRoute.Description != "" {
+			routeDescription = linuxRoute.Description
+		}
+		routes = append(routes, &linuxL3.LinuxStaticRoutes_Route{
+			Name:        "ROUTE_" + namePrefix + replaceSlashesWithUScores(linuxRoute.DstIpAddr),
+			Namespace:   namespace,
+			Interface:   vethName,
+			Description: routeDescription,
+			DstIpAddr:   linuxRoute.DstIpAddr,
+			GwAddr:      stripSlashAndSubnetIpv4Address(linuxRoute.GwAddr),
+			Metric:      linuxRoute.Metric,
+		})
+	}
+	for _, route := range routes {
+		log.Info("createLinuxL3Entries: route: ", route)
+		if err := cnpd.agentPut(etcdPrefix, route); err != nil {
+			log.Error("createLinuxL3Entries: databroker.Store: ", err)
+			return err
+		}
+	}
+
+	for _, arpEntry := range vnfChainElement.GetLinuxArpEntries() {
+		ae := &linuxL3.LinuxStaticArpEntries_ArpEntry{
+			Name: "ARP_" + namePrefix + arpEntry.IpAddress,
+			Namespace: &linuxL3.LinuxStaticArpEntries_ArpEntry_Namespace{
+				Type:         linuxL3.LinuxStaticArpEntries_ArpEntry_Namespace_MICROSERVICE_REF_NS,
+				Microservice: vnfChainElement.Container,
+			},
+			Interface: vethName,
+			State: &linuxL3.LinuxStaticArpEntries_ArpEntry_NudState{
+				Type: linuxL3.LinuxStaticArpEntries_ArpEntry_NudState_PERMANENT,
+			},
+			IpAddr:    arpEntry.IpAddress,
+			HwAddress: arpEntry.PhysAddress,
+		}
+		log.Info("createLinuxL3Entries: arp entry: ", ae)
+		if err := cnpd.agentPut(etcdPrefix, ae); err != nil {
+			log.Error("createLinuxL3Entries: databroker.Store: ", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (cnpd *sfcCtlrL2CNPDriver) createStaticRoute(vrfID uint32, etcdPrefix string, description string, destIpv4AddrStr string,
 	netHopIpv4Addr string, outGoingIf string, weight uint32, pref uint32) (*l3.StaticRoutes_Route, error) {
 
@@ -1948,8 +2653,7 @@ func (cnpd *sfcCtlrL2CNPDriver) createStaticRoute(vrfID uint32, etcdPrefix strin
 
 		log.Println(sr)
 
-		rc := NewRemoteClientTxn(etcdPrefix, cnpd.dbFactory)
-		err := rc.Put().StaticRoute(sr).Send().ReceiveReply()
+		err := cnpd.agentPut(etcdPrefix, sr)
 
 		if err != nil {
 			log.Error("createStaticRoute: databroker.Store: ", err)
@@ -1971,27 +2675,20 @@ func (cnpd *sfcCtlrL2CNPDriver) createStaticArpEntry(etcdPrefix string, destIPAd
 		PhysAddress: physAddress,
 	}
 
-	//if cnpd.reconcileInProgress {
-	//cnpd.reconcileStaticArpEntry(etcdPrefix, ae)
-	//} else {
-
-	key := utils.ArpEntryKey(etcdPrefix, outGoingIf, destIPAddress)
-
-	log.Println(key)
-	log.Println(ae)
+	if cnpd.reconcileInProgress {
+		cnpd.reconcileStaticArpEntry(etcdPrefix, ae)
+	} else {
 
-	log.Info("createStaticArpEntry: arp entry: : ", key, ae)
+		log.Info("createStaticArpEntry: arp entry: : ", ae)
 
-	//rc := NewRemoteClientTxn(etcdPrefix, cnpd.dbFactory)
-	err := cnpd.db.Put(key, ae)
-	//err := rc.Put(key, ae)
+		err := cnpd.agentPut(etcdPrefix, ae)
 
-	if err != nil {
-		log.Error("createStaticArpEntry: databroker.Store: ", err)
-		return nil, err
+		if err != nil {
+			log.Error("createStaticArpEntry: databroker.Store: ", err)
+			return nil, err
 
+		}
 	}
-	//}
 
 	return ae, nil
 }
@@ -2018,13 +2715,17 @@ func (cnpd *sfcCtlrL2CNPDriver) createXConnect(etcdPrefix, rxIf, txIf string) er
 		TransmitInterface: txIf,
 	}
 
-	log.Debugf("Storing l2xconnect config: %s", xconn)
+	if cnpd.reconcileInProgress {
+		cnpd.reconcileXConnect(etcdPrefix, xconn)
+	} else {
 
-	rc := NewRemoteClientTxn(etcdPrefix, cnpd.dbFactory)
-	err := rc.Put().XConnect(xconn).Send().ReceiveReply()
-	if err != nil {
-		log.Errorf("Error by storing l2xconnect: %s", err)
-		return err
+		log.Debugf("Storing l2xconnect config: %s", xconn)
+
+		err := cnpd.agentPut(etcdPrefix, xconn)
+		if err != nil {
+			log.Errorf("Error by storing l2xconnect: %s", err)
+			return err
+		}
 	}
 
 	return nil
@@ -2041,25 +2742,51 @@ func (cnpd *sfcCtlrL2CNPDriver) createL2FibEntry(etcdPrefix string, bdName strin
 		StaticConfig:      true,
 	}
 
-	//if cnpd.reconcileInProgress {
-	//	cnpd.reconcileL2FibEntry(etcdPrefix, l2fib)
-	//} else {
+	if cnpd.reconcileInProgress {
+		cnpd.reconcileL2FibEntry(etcdPrefix, l2fib)
+	} else {
 
-	log.Println(l2fib)
+		log.Println(l2fib)
 
-	rc := NewRemoteClientTxn(etcdPrefix, cnpd.dbFactory)
-	err := rc.Put().BDFIB(l2fib).Send().ReceiveReply()
+		err := cnpd.agentPut(etcdPrefix, l2fib)
 
-	if err != nil {
-		log.Error("createL2Fib: databroker.Store: ", err)
-		return nil, err
+		if err != nil {
+			log.Error("createL2Fib: databroker.Store: ", err)
+			return nil, err
 
+		}
 	}
-	//}
 
 	return l2fib, nil
 }
 
+// createElementL2FibEntries creates the element's l2fib entries toward the vswitch i/f, the macs listed for the
+// element, or, on a bridge or port that does not learn, the mac the element was given, so the static bridges of
+// the chains need no lists, the entries of removed elements go with the next reconcile
+func (cnpd *sfcCtlrL2CNPDriver) createElementL2FibEntries(sfc *controller.SfcEntity, bd *l2.BridgeDomains_BridgeDomain,
+	sfcEntityElement *controller.SfcEntity_SfcElement, ifName string) error {
+
+	macAddrs := sfcEntityElement.L2FibMacs
+	learn := bd.Learn
+	if portParms := sfcEntityElement.GetPortParms(); portParms != nil && portParms.StaticLearningOnly {
+		learn = false
+	}
+	if len(macAddrs) == 0 && !learn && cnpd.sfcFeatureEnabled(featureAutoL2Fib, sfc) {
+		sfcIFAddr, exists := cnpd.l2CNPStateCache.SFCIFAddr[sfcEntityElement.Container+"/"+sfcEntityElement.PortLabel]
+		if exists && sfcIFAddr.macAddress != "" {
+			macAddrs = []string{sfcIFAddr.macAddress}
+		}
+	}
+
+	for _, macAddr := range macAddrs {
+		if _, err := cnpd.createL2FibEntry(sfcEntityElement.EtcdVppSwitchKey, bd.Name, macAddr, ifName); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Debug dump routine
 func (cnpd *sfcCtlrL2CNPDriver) Dump() {
 	log.Println(cnpd.seq)
@@ -2124,6 +2851,14 @@ func stripSlashAndSubnetIpv4Address(ipAndSubnetStr string) string {
 	return strs[0]
 }
 
+// stripPrefixLen returns the address of an ipv4 or ipv6 address with or without a /xx prefix length
+func stripPrefixLen(addrAndPrefixLen string) string {
+	if i := strings.IndexByte(addrAndPrefixLen, '/'); i >= 0 {
+		return addrAndPrefixLen[:i]
+	}
+	return addrAndPrefixLen
+}
+
 // ByIfName is used to sort i/f by name
 type ByIfName []*l2.BridgeDomains_BridgeDomain_Interfaces
 
@@ -2135,23 +2870,54 @@ func (cnpd *sfcCtlrL2CNPDriver) sortBridgedInterfaces(ifs []*l2.BridgeDomains_Br
 	sort.Sort(ByIfName(ifs))
 }
 
-func (cnpd *sfcCtlrL2CNPDriver) GetSfcInterfaceIPAndMac(container string, port string) (string, string, error) {
+// GetSfcInterfaceIPAndMac returns the ipv4 and ipv6 addresses, either may be empty, and the mac of the element
+func (cnpd *sfcCtlrL2CNPDriver) GetSfcInterfaceIPAndMac(container string, port string) (string, string, string,
+	error) {
 	if sfcIFAddr, exists := cnpd.l2CNPStateCache.SFCIFAddr[container+"/"+port]; exists {
-		return stripSlashAndSubnetIpv4Address(sfcIFAddr.ipAddress), sfcIFAddr.macAddress, nil
+		return stripSlashAndSubnetIpv4Address(sfcIFAddr.ipAddress),
+			stripSlashAndSubnetIpv4Address(sfcIFAddr.ipv6Address), sfcIFAddr.macAddress, nil
 	}
-	return "", "", fmt.Errorf("GetSfcInterfaceAddresses: container/port addresses not found: '%s/%s'",
+	return "", "", "", fmt.Errorf("GetSfcInterfaceAddresses: container/port addresses not found: '%s/%s'",
 		container, port)
 }
 
-func (cnpd *sfcCtlrL2CNPDriver) setSfcInterfaceIPAndMac(container string, port string, ip string, mac string) {
+func (cnpd *sfcCtlrL2CNPDriver) setSfcInterfaceIPAndMac(container string, port string, ip string, ipv6 string,
+	mac string) {
 
 	sfcIFAddr := sfcInterfaceAddressStateType{
-		ipAddress:  ip,
-		macAddress: mac,
+		ipAddress:   ip,
+		ipv6Address: ipv6,
+		macAddress:  mac,
 	}
 	cnpd.l2CNPStateCache.SFCIFAddr[container+"/"+port] = sfcIFAddr
 }
 
+// sfcElementDescription encodes the element's metadata as the description of the i/f's rendered for it, along
+// with the sfc, container and port, so telemetry can attribute the i/f's back to their chain and tenant, the
+// i/f's of elements without metadata are left without a description
+func sfcElementDescription(sfcName string, sfcElement *controller.SfcEntity_SfcElement) string {
+
+	if len(sfcElement.GetMetadata()) == 0 {
+		return ""
+	}
+
+	tags := []string{
+		"sfc=" + sfcName,
+		"container=" + sfcElement.Container,
+		"port=" + sfcElement.PortLabel,
+	}
+	keys := make([]string, 0, len(sfcElement.Metadata))
+	for key := range sfcElement.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		tags = append(tags, key+"="+sfcElement.Metadata[key])
+	}
+
+	return strings.Join(tags, ",")
+}
+
 func stringFirstNLastM(n int, m int, str string) string {
 	if len(str) <= n+m {
 		return str
@@ -0,0 +1,185 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The state reporting of the driver is implemented in this file.  What the
+// state cache holds for an entity, ie the vxlan tunnels, bridges and static
+// routes of a host to its peers, the bridges a chain has on each host, the
+// objects of a chain in xconnect or steering mode, and the addresses given
+// to the i/f's of a chain's elements, is returned with the agent key each
+// object is rendered under, in a form the status commands can serialize.
+
+package l2driver
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
+)
+
+// the types of the state objects
+const (
+	StateObjectVxlan        = "vxlan"
+	StateObjectMemif        = "memif"
+	StateObjectInterface    = "interface"
+	StateObjectBridgeDomain = "bridge_domain"
+	StateObjectStaticRoute  = "static_route"
+	StateObjectXConnect     = "xconnect"
+)
+
+// EntityState is what the driver rendered, and holds in its state cache, for the entities of a name, an ee,
+// a host and a chain may share one
+type EntityState struct {
+	Name       string                  `json:"name"`
+	Objects    []StateObject           `json:"objects"`
+	Interfaces []StateInterfaceAddress `json:"interfaces,omitempty"`
+}
+
+// StateObject is an agent object of the entity, on the host it is rendered on, with the peer it wires to if any
+type StateObject struct {
+	Kind   string        `json:"kind"`
+	Host   string        `json:"host"`
+	Peer   string        `json:"peer,omitempty"`
+	Type   string        `json:"type"`
+	Key    string        `json:"key"`
+	Object proto.Message `json:"object"`
+}
+
+// StateInterfaceAddress is the addressing the driver gave the i/f of a chain's element
+type StateInterfaceAddress struct {
+	Container   string `json:"container"`
+	PortLabel   string `json:"port_label"`
+	Ipv4Address string `json:"ipv4_address,omitempty"`
+	Ipv6Address string `json:"ipv6_address,omitempty"`
+	MacAddress  string `json:"mac_address,omitempty"`
+}
+
+// GetState returns the cached state of the entities of the name, an error if the driver has none of that name
+func (cnpd *sfcCtlrL2CNPDriver) GetState(entityName string) (*EntityState, error) {
+
+	_, isEE := cnpd.l2CNPEntityCache.EEs[entityName]
+	_, isHE := cnpd.l2CNPEntityCache.HEs[entityName]
+	sfc, isSFC := cnpd.l2CNPEntityCache.SFCs[entityName]
+	if !isEE && !isHE && !isSFC {
+		return nil, fmt.Errorf("GetState: entity: '%s' not found", entityName)
+	}
+
+	state := &EntityState{Name: entityName, Objects: make([]StateObject, 0)}
+
+	if isEE {
+		for _, heName := range sortedStateKeys(cnpd.l2CNPStateCache.HEToEEs) {
+			if heToEEState := cnpd.l2CNPStateCache.HEToEEs[heName][entityName]; heToEEState != nil {
+				cnpd.addStateObjects(state, controller.ExternalEntityKind, heName, entityName, heToEEState.vlanIf,
+					heToEEState.bd, heToEEState.l3Route)
+			}
+		}
+	}
+
+	if isHE {
+		if heState := cnpd.l2CNPStateCache.HE[entityName]; heState != nil {
+			cnpd.addStateObjects(state, controller.HostEntityKind, entityName, "", heState.ewBD, heState.ewBDL2Fib)
+		}
+		for _, eeName := range sortedStateKeys(cnpd.l2CNPStateCache.HEToEEs[entityName]) {
+			heToEEState := cnpd.l2CNPStateCache.HEToEEs[entityName][eeName]
+			cnpd.addStateObjects(state, controller.HostEntityKind, entityName, eeName, heToEEState.vlanIf,
+				heToEEState.bd, heToEEState.l3Route)
+			for _, sr := range heToEEState.prefixRoutes {
+				cnpd.addStateObjects(state, controller.HostEntityKind, entityName, eeName, sr)
+			}
+		}
+		for _, dhName := range sortedStateKeys(cnpd.l2CNPStateCache.HEToHEs[entityName]) {
+			heToHEState := cnpd.l2CNPStateCache.HEToHEs[entityName][dhName]
+			cnpd.addStateObjects(state, controller.HostEntityKind, entityName, dhName, heToHEState.vlanIf,
+				heToHEState.bd, heToHEState.l3Route)
+		}
+	}
+
+	if isSFC {
+		for _, heName := range sortedStateKeys(cnpd.l2CNPStateCache.SFCToHEs[entityName]) {
+			heState := cnpd.l2CNPStateCache.SFCToHEs[entityName][heName]
+			cnpd.addStateObjects(state, controller.SfcEntityKind, heName, "", heState.ewBD, heState.ewBDL2Fib)
+		}
+		xconns := cnpd.l2CNPStateCache.SFCXConns[entityName]
+		for _, key := range sortedStateKeys(xconns) {
+			state.Objects = append(state.Objects, StateObject{
+				Kind:   controller.SfcEntityKind,
+				Host:   utils.GetVppEtcdlabel(key),
+				Type:   stateObjectType(xconns[key]),
+				Key:    key,
+				Object: xconns[key],
+			})
+		}
+		for _, sfcElement := range sfc.GetElements() {
+			sfcIFAddr, exists := cnpd.l2CNPStateCache.SFCIFAddr[sfcElement.Container+"/"+sfcElement.PortLabel]
+			if !exists {
+				continue
+			}
+			state.Interfaces = append(state.Interfaces, StateInterfaceAddress{
+				Container:   sfcElement.Container,
+				PortLabel:   sfcElement.PortLabel,
+				Ipv4Address: sfcIFAddr.ipAddress,
+				Ipv6Address: sfcIFAddr.ipv6Address,
+				MacAddress:  sfcIFAddr.macAddress,
+			})
+		}
+	}
+
+	return state, nil
+}
+
+// addStateObjects adds the objects, of the host, that are set
+func (cnpd *sfcCtlrL2CNPDriver) addStateObjects(state *EntityState, kind string, host string, peer string,
+	objs ...proto.Message) {
+
+	for _, obj := range objs {
+		if reflect.ValueOf(obj).IsNil() { // a state cache entry that was not set
+			continue
+		}
+		state.Objects = append(state.Objects, StateObject{
+			Kind:   kind,
+			Host:   host,
+			Peer:   peer,
+			Type:   stateObjectType(obj),
+			Key:    cnpd.agentKey(host, obj),
+			Object: obj,
+		})
+	}
+}
+
+// stateObjectType returns the type of the object, the i/f's are told apart by their kind
+func stateObjectType(obj proto.Message) string {
+
+	switch o := obj.(type) {
+	case *interfaces.Interfaces_Interface:
+		switch {
+		case o.Vxlan != nil:
+			return StateObjectVxlan
+		case o.Memif != nil:
+			return StateObjectMemif
+		}
+		return StateObjectInterface
+	case *l2.BridgeDomains_BridgeDomain:
+		return StateObjectBridgeDomain
+	case *l3.StaticRoutes_Route:
+		return StateObjectStaticRoute
+	case *l2.XConnectPairs_XConnectPair:
+		return StateObjectXConnect
+	}
+	return proto.MessageName(obj)
+}
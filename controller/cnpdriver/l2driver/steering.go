@@ -0,0 +1,206 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The acl steering mode of the l2 driver is implemented in this file.  The
+// sfcctlracl driver wires the chains like the xconnect mode, each pair of
+// elements is a hop cross connected on its host, or through a vxlan tunnel
+// of the hop between the two hosts, and the hops are classified: the acl of
+// the chain's steering rules is applied on ingress of the vswitch i/f of the
+// first element of each hop, so only the traffic it permits is steered to
+// the second element, the rest is dropped.  The vpp-agent has no redirect,
+// so a hop cannot leave the forwarding of an i/f the vswitch already has as
+// it is, the elements are containers only.
+//
+// The tunnels are of the address family of the hosts' uplinks, and the
+// steering rules classify ipv4 and ipv6 traffic, a rule matches the family
+// of its networks, or both families if it has none.
+
+package l2driver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
+)
+
+// SteeringDriverName is the name of the cnp driver in acl steering mode
+const SteeringDriverName = "sfcctlracl"
+
+// NewSfcCtlrSteeringCNPDriver creates the l2 driver in acl steering mode, the hosts get no bridges and the
+// chains are wired with classified xconnects only
+func NewSfcCtlrSteeringCNPDriver(name string, dbFactory func(string) keyval.ProtoBroker) *sfcCtlrL2CNPDriver {
+
+	cnpd := NewSfcCtlrL2CNPDriver(name, dbFactory)
+	cnpd.name = "Sfc Controller L2 ACL Steering Plugin: " + name
+	cnpd.sfcDriver = SteeringDriverName
+
+	return cnpd
+}
+
+// wireSfcSteeringElements wires the classified hops of the chain, memif chains need no steering, the chain
+// types that need a bridge are refused
+func (cnpd *sfcCtlrL2CNPDriver) wireSfcSteeringElements(sfc *controller.SfcEntity) error {
+
+	switch sfc.Type {
+	case controller.SfcType_SFC_EW_MEMIF:
+		// the memifs connect the containers directly, there is nothing on the vswitch
+		cnpd.l2CNPEntityCache.SFCs[sfc.Name] = *sfc
+		return cnpd.wireSfcEastWestElements(sfc)
+	case controller.SfcType_SFC_EW_L2XCONN:
+	default:
+		err := fmt.Errorf("wireSfcSteeringElements: sfc: '%s' of type '%s' cannot be steered, the %s driver only "+
+			"wires SFC_EW_L2XCONN and SFC_EW_MEMIF sfc's", sfc.Name, sfc.Type, SteeringDriverName)
+		log.Error(err.Error())
+		return err
+	}
+
+	if len(sfc.GetElements())%2 != 0 {
+		err := fmt.Errorf("wireSfcSteeringElements: steered sfc should have pairs of elements: '%s'", sfc.Name)
+		log.Error(err.Error())
+		return err
+	}
+	rules := steeringACLRules(sfc)
+	cnpd.l2CNPEntityCache.SFCs[sfc.Name] = *sfc
+
+	prev := cnpd.l2CNPStateCache.SFCXConns[sfc.Name]
+	rendered := make(xconnStateType)
+
+	elements := sfc.GetElements()
+	for i := 0; i < len(elements); i += 2 {
+		if err := cnpd.wireSteeringHop(sfc, elements[i], elements[i+1], rules, prev, rendered); err != nil {
+			return err
+		}
+	}
+
+	return cnpd.xconnEnd(sfc.Name, prev, rendered)
+}
+
+// wireSteeringHop applies the classifier acl of the hop on its first element's i/f, then cross connects the
+// hop like xconnect mode, so the hop never carries unclassified traffic
+func (cnpd *sfcCtlrL2CNPDriver) wireSteeringHop(sfc *controller.SfcEntity, from *controller.SfcEntity_SfcElement,
+	to *controller.SfcEntity_SfcElement, rules []*acl.AccessLists_Acl_Rule, prev xconnStateType,
+	rendered xconnStateType) error {
+
+	vni := cnpd.xconnHopVni(sfc, from)
+
+	fromIfName, err := cnpd.createXConnElementIf(sfc, from)
+	if err != nil {
+		return err
+	}
+	toIfName, err := cnpd.createXConnElementIf(sfc, to)
+	if err != nil {
+		return err
+	}
+
+	// the acl is named after the hop's first element
+	name := "STEER_" + sfc.Name + "_" + from.Container + "_" + replaceSlashesWithUScores(from.PortLabel)
+	classifier := &acl.AccessLists_Acl{
+		AclName: name,
+		Rules:   rules,
+		Interfaces: &acl.AccessLists_Acl_Interfaces{
+			Ingress: []string{fromIfName},
+		},
+	}
+	if err := cnpd.xconnPut(from.EtcdVppSwitchKey, classifier, prev, rendered); err != nil {
+		log.Errorf("wireSteeringHop: error creating acl: '%s'", name)
+		return err
+	}
+
+	return cnpd.xconnHopPaths(sfc, from, to, fromIfName, toIfName, vni, prev, rendered)
+}
+
+// steeringACLRules returns the permit rules of the chain's steering rules, ones matching all ipv4 and ipv6
+// traffic if the chain has none, the rules were validated by the controller
+func steeringACLRules(sfc *controller.SfcEntity) []*acl.AccessLists_Acl_Rule {
+
+	steeringRules := sfc.GetSteeringRules()
+	if len(steeringRules) == 0 {
+		steeringRules = []*controller.SteeringRule{{}}
+	}
+
+	var rules []*acl.AccessLists_Acl_Rule
+	for _, steeringRule := range steeringRules {
+		for _, networks := range steeringNetworks(steeringRule) {
+			ipRule := &acl.AccessLists_Acl_Rule_Matches_IpRule{
+				Ip: &acl.AccessLists_Acl_Rule_Matches_IpRule_Ip{
+					SourceNetwork:      networks[0],
+					DestinationNetwork: networks[1],
+				},
+			}
+
+			upper := steeringRule.DstPortUpper
+			if upper == 0 {
+				upper = steeringRule.DstPortLower
+			}
+			switch {
+			case steeringRule.Protocol == 6 && steeringRule.DstPortLower != 0:
+				ipRule.Tcp = &acl.AccessLists_Acl_Rule_Matches_IpRule_Tcp{
+					DestinationPortRange: &acl.AccessLists_Acl_Rule_Matches_IpRule_Tcp_DestinationPortRange{
+						LowerPort: steeringRule.DstPortLower,
+						UpperPort: upper,
+					},
+				}
+			case steeringRule.Protocol == 17 && steeringRule.DstPortLower != 0:
+				ipRule.Udp = &acl.AccessLists_Acl_Rule_Matches_IpRule_Udp{
+					DestinationPortRange: &acl.AccessLists_Acl_Rule_Matches_IpRule_Udp_DestinationPortRange{
+						LowerPort: steeringRule.DstPortLower,
+						UpperPort: upper,
+					},
+				}
+			case steeringRule.Protocol != 0:
+				ipRule.Other = &acl.AccessLists_Acl_Rule_Matches_IpRule_Other{
+					Protocol: steeringRule.Protocol,
+				}
+			}
+
+			rules = append(rules, &acl.AccessLists_Acl_Rule{
+				RuleName: "STEER_RULE_" + strconv.Itoa(len(rules)),
+				Actions: &acl.AccessLists_Acl_Rule_Actions{
+					AclAction: acl.AclAction_PERMIT,
+				},
+				Matches: &acl.AccessLists_Acl_Rule_Matches{
+					IpRule: ipRule,
+				},
+			})
+		}
+	}
+
+	return rules
+}
+
+// steeringNetworks returns the source and destination networks of the acl rules of a steering rule, an unset
+// network is any address of the family of the other one, a rule without networks is one rule per family
+func steeringNetworks(steeringRule *controller.SteeringRule) [][2]string {
+
+	src, dst := steeringRule.SrcNetwork, steeringRule.DstNetwork
+	if src == "" && dst == "" {
+		return [][2]string{{"0.0.0.0/0", "0.0.0.0/0"}, {"::/0", "::/0"}}
+	}
+
+	anyNetwork := "0.0.0.0/0"
+	if strings.Contains(src+dst, ":") {
+		anyNetwork = "::/0"
+	}
+	if src == "" {
+		src = anyNetwork
+	}
+	if dst == "" {
+		dst = anyNetwork
+	}
+	return [][2]string{{src, dst}}
+}
@@ -0,0 +1,243 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The consistency check of the driver is implemented in this file.  The
+// wiring the driver cached for the hosts and ee's is compared with the
+// entries in the agents' trees and with the id records of the datastore:
+// a cached object must be in its agent's tree as it was cached, a cached
+// tunnel must have its id record with the tunnel's vni, and an id record
+// must belong to wiring that is cached.  Crashes between writes, or manual
+// edits of ETCD, show up as the inconsistencies reported.
+
+package l2driver
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/gogo/protobuf/proto"
+	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+)
+
+// the kinds of inconsistencies
+const (
+	InconsistencyMissingKey    = "missing_key"    // cached, or recorded as rendered, but not in the agent's tree
+	InconsistencyValueMismatch = "value_mismatch" // the agent's tree has another value than the cached one
+	InconsistencyUnownedKey    = "unowned_key"    // in the agent's tree, but rendered for no entity
+	InconsistencyMissingIDs    = "missing_ids"    // wired, but it has no id record
+	InconsistencyIDMismatch    = "id_mismatch"    // the id record and the wiring disagree
+	InconsistencyOrphanIDs     = "orphan_ids"     // an id record of wiring that is not cached
+	InconsistencyForeignKey    = "foreign_key"    // in a reconciled agent's tree, not written by the controller
+	InconsistencyStaleKey      = "stale_key"      // stamped by the controller, but neither owned nor rendered
+)
+
+// Inconsistency is a disagreement between the caches, the id records and the keys of the agents
+type Inconsistency struct {
+	Kind   string `json:"kind"`
+	Key    string `json:"key"`
+	Detail string `json:"detail"`
+}
+
+type consistencyCheck struct {
+	cnpd            *sfcCtlrL2CNPDriver
+	checkLabel      func(vppLabel string) bool
+	inconsistencies []Inconsistency
+}
+
+func (cc *consistencyCheck) report(kind string, key string, format string, args ...interface{}) {
+	cc.inconsistencies = append(cc.inconsistencies, Inconsistency{
+		Kind:   kind,
+		Key:    key,
+		Detail: fmt.Sprintf(format, args...),
+	})
+}
+
+// VerifyConsistency cross checks the state cache, the id records and the agents' trees, only the trees of
+// the vpp labels checkLabel accepts are read, ie those the controller writes
+func (cnpd *sfcCtlrL2CNPDriver) VerifyConsistency(checkLabel func(vppLabel string) bool) ([]Inconsistency, error) {
+
+	cc := &consistencyCheck{cnpd: cnpd, checkLabel: checkLabel}
+
+	for _, heName := range sortedStateKeys(cnpd.l2CNPStateCache.HE) {
+		heState := cnpd.l2CNPStateCache.HE[heName]
+		if err := cc.checkAgentObjects(heName, heState.ewBD, heState.ewBDL2Fib); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, heName := range sortedStateKeys(cnpd.l2CNPStateCache.HEToEEs) {
+		for _, eeName := range sortedStateKeys(cnpd.l2CNPStateCache.HEToEEs[heName]) {
+			heToEEState := cnpd.l2CNPStateCache.HEToEEs[heName][eeName]
+			if err := cc.checkAgentObjects(heName, heToEEState.vlanIf, heToEEState.bd,
+				heToEEState.l3Route); err != nil {
+				return nil, err
+			}
+			for _, sr := range heToEEState.prefixRoutes {
+				if err := cc.checkAgentObjects(heName, sr); err != nil {
+					return nil, err
+				}
+			}
+			cc.checkHE2EEIDs(heName, eeName, heToEEState)
+		}
+	}
+
+	for _, shName := range sortedStateKeys(cnpd.l2CNPStateCache.HEToHEs) {
+		for _, dhName := range sortedStateKeys(cnpd.l2CNPStateCache.HEToHEs[shName]) {
+			heToHEState := cnpd.l2CNPStateCache.HEToHEs[shName][dhName]
+			if err := cc.checkAgentObjects(shName, heToHEState.vlanIf, heToHEState.bd,
+				heToHEState.l3Route); err != nil {
+				return nil, err
+			}
+			cc.checkHE2HEIDs(shName, dhName, heToHEState)
+		}
+	}
+
+	if err := cc.checkOrphanIDs(); err != nil {
+		return nil, err
+	}
+
+	// the objects the last reconcile's audit found, see audit.go
+	cnpd.reconcileMutex.Lock()
+	for _, finding := range cnpd.auditFindings {
+		if checkLabel(utils.GetVppEtcdlabel(finding.Key)) {
+			cc.inconsistencies = append(cc.inconsistencies, finding)
+		}
+	}
+	cnpd.reconcileMutex.Unlock()
+
+	return cc.inconsistencies, nil
+}
+
+// checkAgentObjects compares the cached objects with the values in the host's agent tree
+func (cc *consistencyCheck) checkAgentObjects(vppLabel string, objs ...proto.Message) error {
+
+	if !cc.checkLabel(vppLabel) {
+		return nil
+	}
+	adapter := cc.cnpd.agentAdapterFor(vppLabel)
+
+	for _, obj := range objs {
+		if reflect.ValueOf(obj).IsNil() { // a state cache entry that was not set
+			continue
+		}
+		key, err := adapter.Key(vppLabel, obj)
+		if err != nil {
+			return err
+		}
+		stored := proto.Clone(obj)
+		stored.Reset()
+		found, _, err := cc.cnpd.revisionGet(key, stored, adapter.Decode)
+		if err != nil {
+			return err
+		}
+		if !found {
+			cc.report(InconsistencyMissingKey, key, "cached for: '%s' but not in its agent's tree", vppLabel)
+			continue
+		}
+		auditStrip(stored)
+		if !proto.Equal(stored, obj) {
+			cc.report(InconsistencyValueMismatch, key, "cached: %v, stored: %v", obj, stored)
+		}
+	}
+
+	return nil
+}
+
+func (cc *consistencyCheck) checkHE2EEIDs(heName string, eeName string, heToEEState *heToEEStateType) {
+
+	key := l2driver.HE2EEIDsNameKey(heName, eeName)
+	he2eeID, _ := cc.cnpd.DatastoreHE2EEIDsRetrieve(heName, eeName)
+	if he2eeID == nil {
+		if heToEEState.vlanIf != nil {
+			cc.report(InconsistencyMissingIDs, key, "he: '%s' is wired to ee: '%s'", heName, eeName)
+		}
+		return
+	}
+	if vni := vxlanVni(heToEEState.vlanIf); vni != 0 && he2eeID.VlanId != vni {
+		cc.report(InconsistencyIDMismatch, key, "records vni %d, the tunnel has vni %d", he2eeID.VlanId, vni)
+	}
+}
+
+func (cc *consistencyCheck) checkHE2HEIDs(shName string, dhName string, heToHEState *heToHEStateType) {
+
+	key := l2driver.HE2HEIDsNameKey(shName, dhName)
+	vni := vxlanVni(heToHEState.vlanIf)
+	if vni == 0 {
+		return
+	}
+	sh2dhID, _ := cc.cnpd.DatastoreHE2HEIDsRetrieve(shName, dhName)
+	if sh2dhID == nil {
+		cc.report(InconsistencyMissingIDs, key, "he: '%s' has a tunnel to he: '%s'", shName, dhName)
+		return
+	}
+	if sh2dhID.VlanId != vni {
+		cc.report(InconsistencyIDMismatch, key, "records vni %d, the tunnel has vni %d", sh2dhID.VlanId, vni)
+	}
+}
+
+// checkOrphanIDs reports the id records of entities, or wiring, the driver has not cached
+func (cc *consistencyCheck) checkOrphanIDs() error {
+
+	cache := &cc.cnpd.l2CNPStateCache
+	entities := &cc.cnpd.l2CNPEntityCache
+
+	if err := cc.cnpd.DatastoreHEIDsIterate(func(key string, heID *l2driver.HEIDs) {
+		if _, exists := entities.HEs[heID.Name]; !exists {
+			cc.report(InconsistencyOrphanIDs, key, "he: '%s' is not cached", heID.Name)
+		}
+	}); err != nil {
+		return err
+	}
+	if err := cc.cnpd.DatastoreHE2EEIDsIterate(func(key string, he2eeID *l2driver.HE2EEIDs) {
+		if _, exists := cache.HEToEEs[he2eeID.HeName][he2eeID.EeName]; !exists {
+			cc.report(InconsistencyOrphanIDs, key, "he: '%s' is not wired to ee: '%s'", he2eeID.HeName,
+				he2eeID.EeName)
+		}
+	}); err != nil {
+		return err
+	}
+	if err := cc.cnpd.DatastoreHE2HEIDsIterate(func(key string, sh2dhID *l2driver.HE2HEIDs) {
+		if _, exists := cache.HEToHEs[sh2dhID.ShName][sh2dhID.DhName]; !exists {
+			cc.report(InconsistencyOrphanIDs, key, "he: '%s' has no tunnel to he: '%s'", sh2dhID.ShName,
+				sh2dhID.DhName)
+		}
+	}); err != nil {
+		return err
+	}
+	return cc.cnpd.DatastoreSFCIDsIterate(func(key string, sfcID *l2driver.SFCIDs) {
+		if _, exists := entities.SFCs[sfcID.SfcName]; !exists {
+			cc.report(InconsistencyOrphanIDs, key, "sfc: '%s' is not cached", sfcID.SfcName)
+		}
+	})
+}
+
+func vxlanVni(vlanIf *interfaces.Interfaces_Interface) uint32 {
+	if vlanIf.GetVxlan() == nil {
+		return 0
+	}
+	return vlanIf.GetVxlan().Vni
+}
+
+// sortedStateKeys returns the keys of a state cache map in order
+func sortedStateKeys(m interface{}) []string {
+	var keys []string
+	for _, k := range reflect.ValueOf(m).MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,326 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The xconnect mode of the l2 driver is implemented in this file.  The
+// sfcctlrxconn driver renders the chains with l2 xconnect pairs only: no
+// bridge domain is created on the hosts, so nothing is flooded or learned,
+// and each hop of a chain is a fixed point to point path.  The elements of a
+// chain are taken in pairs, each pair is a hop: the vswitch i/f's of its two
+// elements are cross connected when they are on the same host, else each is
+// cross connected to a vxlan tunnel of its own between the two hosts.  The
+// vni of a hop is recorded with the ids of its first element.
+//
+// The xconnects and tunnels of each chain are remembered, so when an element
+// is attached to, or detached from, a chain only the xconnects of the hops
+// next to it are rewritten, or removed, the others are left as they are.
+
+package l2driver
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/cn-infra/db/keyval"
+	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
+)
+
+// XConnectDriverName is the name of the cnp driver in xconnect mode
+const XConnectDriverName = "sfcctlrxconn"
+
+// xconnStateType is the agent objects rendered for a chain in xconnect mode, by key
+type xconnStateType map[string]proto.Message
+
+// NewSfcCtlrXConnCNPDriver creates the l2 driver in xconnect mode, the hosts get no bridges and the chains
+// are wired with xconnects only
+func NewSfcCtlrXConnCNPDriver(name string, dbFactory func(string) keyval.ProtoBroker) *sfcCtlrL2CNPDriver {
+
+	cnpd := NewSfcCtlrL2CNPDriver(name, dbFactory)
+	cnpd.name = "Sfc Controller L2 XConnect Plugin: " + name
+	cnpd.sfcDriver = XConnectDriverName
+
+	return cnpd
+}
+
+// wireSfcXConnElements wires the chain in xconnect mode, the chain types that need a bridge are refused
+func (cnpd *sfcCtlrL2CNPDriver) wireSfcXConnElements(sfc *controller.SfcEntity) error {
+
+	switch sfc.Type {
+	case controller.SfcType_SFC_EW_MEMIF:
+		// the memifs connect the containers directly, there is nothing on the vswitch
+		cnpd.l2CNPEntityCache.SFCs[sfc.Name] = *sfc
+		return cnpd.wireSfcEastWestElements(sfc)
+	case controller.SfcType_SFC_NS_NIC_L2XCONN:
+		cnpd.l2CNPEntityCache.SFCs[sfc.Name] = *sfc
+		return cnpd.wireSfcNorthSouthNICElements(sfc)
+	case controller.SfcType_SFC_EW_L2XCONN:
+	default:
+		err := fmt.Errorf("wireSfcXConnElements: sfc: '%s' of type '%s' needs a bridge, the %s driver only wires "+
+			"SFC_EW_L2XCONN, SFC_EW_MEMIF and SFC_NS_NIC_L2XCONN sfc's", sfc.Name, sfc.Type, XConnectDriverName)
+		log.Error(err.Error())
+		return err
+	}
+
+	if len(sfc.GetElements())%2 != 0 {
+		err := fmt.Errorf("wireSfcXConnElements: xconnect sfc should have pairs of elements: '%s'", sfc.Name)
+		log.Error(err.Error())
+		return err
+	}
+	cnpd.l2CNPEntityCache.SFCs[sfc.Name] = *sfc
+
+	prev := cnpd.l2CNPStateCache.SFCXConns[sfc.Name]
+	rendered := make(xconnStateType)
+
+	elements := sfc.GetElements()
+	for i := 0; i < len(elements); i += 2 {
+		if err := cnpd.wireXConnHop(sfc, elements[i], elements[i+1], prev, rendered); err != nil {
+			return err
+		}
+	}
+
+	return cnpd.xconnEnd(sfc.Name, prev, rendered)
+}
+
+// xconnEnd removes the objects of the hops that are gone, ie an element was detached, and remembers the ones
+// rendered, reconcile removes the hops that are gone by itself
+func (cnpd *sfcCtlrL2CNPDriver) xconnEnd(sfcName string, prev xconnStateType, rendered xconnStateType) error {
+
+	if !cnpd.reconcileInProgress {
+		for key := range prev {
+			if _, exists := rendered[key]; !exists {
+				if err := cnpd.xconnDelete(key); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cnpd.l2CNPStateCache.SFCXConns[sfcName] = rendered
+
+	return nil
+}
+
+// wireXConnHop cross connects the vswitch i/f's of the hop's elements, through a tunnel if they are on
+// different hosts
+func (cnpd *sfcCtlrL2CNPDriver) wireXConnHop(sfc *controller.SfcEntity, from *controller.SfcEntity_SfcElement,
+	to *controller.SfcEntity_SfcElement, prev xconnStateType, rendered xconnStateType) error {
+
+	vni := cnpd.xconnHopVni(sfc, from)
+
+	fromIfName, err := cnpd.createXConnElementIf(sfc, from)
+	if err != nil {
+		return err
+	}
+	toIfName, err := cnpd.createXConnElementIf(sfc, to)
+	if err != nil {
+		return err
+	}
+
+	return cnpd.xconnHopPaths(sfc, from, to, fromIfName, toIfName, vni, prev, rendered)
+}
+
+// xconnHopVni returns the vni of the hop, 0 if it has none yet, the vni survives restarts in the from port's
+// ids so it is read before the port's i/f pair rewrites them
+func (cnpd *sfcCtlrL2CNPDriver) xconnHopVni(sfc *controller.SfcEntity, from *controller.SfcEntity_SfcElement) uint32 {
+
+	if sfcID, _ := cnpd.DatastoreSFCIDsRetrieve(sfc.Name, from.Container, from.PortLabel); sfcID != nil {
+		return sfcID.XconnectVni
+	}
+	return 0
+}
+
+// xconnHopPaths cross connects the created vswitch i/f's of the hop's elements, through a tunnel if they are
+// on different hosts
+func (cnpd *sfcCtlrL2CNPDriver) xconnHopPaths(sfc *controller.SfcEntity, from *controller.SfcEntity_SfcElement,
+	to *controller.SfcEntity_SfcElement, fromIfName string, toIfName string, vni uint32, prev xconnStateType,
+	rendered xconnStateType) error {
+
+	if from.EtcdVppSwitchKey == to.EtcdVppSwitchKey {
+		return cnpd.xconnPutPair(from.EtcdVppSwitchKey, fromIfName, toIfName, prev, rendered)
+	}
+
+	for _, heName := range []string{from.EtcdVppSwitchKey, to.EtcdVppSwitchKey} {
+		if _, exists := cnpd.l2CNPEntityCache.HEs[heName]; !exists {
+			err := fmt.Errorf("xconnHopPaths: host not found: '%s' for this sfc: '%s'", heName, sfc.Name)
+			log.Error(err.Error())
+			return err
+		}
+	}
+	if vni == 0 {
+		vni = cnpd.nextID(idSpaceVLan, l2driver.SFCContainerPortIDsNameKey(sfc.Name, from.Container,
+			from.PortLabel))
+	}
+
+	// the tunnel is named after the hop's first element on both hosts
+	tunnelIfName := "IF_VXLAN_XC_" + sfc.Name + "_" + from.Container + "_" + from.PortLabel
+	for _, end := range []struct {
+		heName, peerName, ifName string
+	}{
+		{from.EtcdVppSwitchKey, to.EtcdVppSwitchKey, fromIfName},
+		{to.EtcdVppSwitchKey, from.EtcdVppSwitchKey, toIfName},
+	} {
+		he := cnpd.l2CNPEntityCache.HEs[end.heName]
+		peer := cnpd.l2CNPEntityCache.HEs[end.peerName]
+		heUplink := hostUplinkForPeer(&he, peer.Name)
+		peerUplink := hostUplinkForPeer(&peer, he.Name)
+		ipv6 := tunnelIpv6(heUplink, peerUplink)
+		src, _ := heUplink.tunnelAddrs(ipv6)
+		dst, _ := peerUplink.tunnelAddrs(ipv6)
+		tunnel := &interfaces.Interfaces_Interface{
+			Name:    tunnelIfName,
+			Type:    interfaces.InterfaceType_VXLAN_TUNNEL,
+			Enabled: true,
+			Vxlan: &interfaces.Interfaces_Interface_Vxlan{
+				SrcAddress: stripPrefixLen(src),
+				DstAddress: stripPrefixLen(dst),
+				Vni:        vni,
+			},
+		}
+		if err := cnpd.xconnPut(end.heName, tunnel, prev, rendered); err != nil {
+			log.Errorf("xconnHopPaths: error creating vxlan: '%s'", tunnelIfName)
+			return err
+		}
+		if err := cnpd.xconnHostRoute(he.Name, peer.Name); err != nil {
+			return err
+		}
+		if err := cnpd.xconnPutPair(end.heName, end.ifName, tunnelIfName, prev, rendered); err != nil {
+			return err
+		}
+	}
+
+	key, sfcID, err := cnpd.DatastoreSFCIDsSetXConnectVni(sfc.Name, from.Container, from.PortLabel, vni)
+	if err == nil && cnpd.reconcileInProgress {
+		cnpd.reconcileAfter.sfcIDs[key] = *sfcID
+	}
+
+	return err
+}
+
+// createXConnElementIf creates the i/f pair of the element and returns the name of its vswitch end
+func (cnpd *sfcCtlrL2CNPDriver) createXConnElementIf(sfc *controller.SfcEntity,
+	sfcEntityElement *controller.SfcEntity_SfcElement) (string, error) {
+
+	var ifName string
+	var err error
+
+	switch sfcEntityElement.Type {
+	case controller.SfcElementType_VPP_CONTAINER_AFP:
+		fallthrough
+	case controller.SfcElementType_NON_VPP_CONTAINER_AFP:
+		ifName, err = cnpd.createAFPacketVEthPair(sfc, sfcEntityElement)
+	case controller.SfcElementType_VPP_CONTAINER_MEMIF:
+		fallthrough
+	case controller.SfcElementType_NON_VPP_CONTAINER_MEMIF:
+		ifName, err = cnpd.createMemIfPair(sfc, sfcEntityElement.EtcdVppSwitchKey, sfcEntityElement, false)
+	default:
+		err = fmt.Errorf("createXConnElementIf: element type '%s' not allowed in xconnect sfc: '%s'",
+			sfcEntityElement.Type, sfc.Name)
+		log.Error(err.Error())
+		return "", err
+	}
+	if err != nil {
+		log.Errorf("createXConnElementIf: error creating i/f pair: sfc: '%s', Container: '%s'",
+			sfc.Name, sfcEntityElement.Container)
+	}
+
+	return ifName, err
+}
+
+// xconnHostRoute creates the static route toward the peer host if the host asks for one, once per host pair
+// like the tunnels of the bridged chains
+func (cnpd *sfcCtlrL2CNPDriver) xconnHostRoute(heName string, peerName string) error {
+
+	he := cnpd.l2CNPEntityCache.HEs[heName]
+	if !he.CreateVxlanStaticRoute {
+		return nil
+	}
+	heToHEState, exists := cnpd.l2CNPStateCache.HEToHEs[heName][peerName]
+	if !exists {
+		err := fmt.Errorf("xconnHostRoute: host '%s' not wired to host: '%s'", heName, peerName)
+		log.Error(err.Error())
+		return err
+	}
+	if heToHEState.l3Route != nil {
+		return nil
+	}
+
+	peer := cnpd.l2CNPEntityCache.HEs[peerName]
+	description := "IF_STATIC_ROUTE_H2H_" + peer.Name
+	heUplink := hostUplinkForPeer(&he, peer.Name)
+	peerUplink := hostUplinkForPeer(&peer, he.Name)
+	peerTunnelSrc, peerAddr := peerUplink.tunnelAddrs(tunnelIpv6(heUplink, peerUplink))
+	sr, err := cnpd.createStaticRoute(0, he.Name, description, peerTunnelSrc, peerAddr, heUplink.ifName,
+		cnpd.l2CNPEntityCache.SysParms.DefaultStaticRouteWeight,
+		cnpd.l2CNPEntityCache.SysParms.DefaultStaticRoutePreference)
+	if err != nil {
+		log.Errorf("xconnHostRoute: error creating static route: '%s'", description)
+		return err
+	}
+	heToHEState.l3Route = sr
+
+	return nil
+}
+
+// xconnPutPair cross connects the i/f's both ways
+func (cnpd *sfcCtlrL2CNPDriver) xconnPutPair(vppLabel string, if1 string, if2 string, prev xconnStateType,
+	rendered xconnStateType) error {
+
+	for _, ifs := range [][2]string{{if1, if2}, {if2, if1}} {
+		xconn := &l2.XConnectPairs_XConnectPair{
+			ReceiveInterface:  ifs[0],
+			TransmitInterface: ifs[1],
+		}
+		if err := cnpd.xconnPut(vppLabel, xconn, prev, rendered); err != nil {
+			log.Errorf("xconnPutPair: error creating xconnect: '%s'/'%s'", ifs[0], ifs[1])
+			return err
+		}
+	}
+	return nil
+}
+
+// xconnPut writes the xconnect, tunnel or acl to the agent unless the chain already wrote the same one
+func (cnpd *sfcCtlrL2CNPDriver) xconnPut(vppLabel string, obj proto.Message, prev xconnStateType,
+	rendered xconnStateType) error {
+
+	key := cnpd.agentKey(vppLabel, obj)
+	rendered[key] = obj
+
+	if cnpd.reconcileInProgress {
+		switch o := obj.(type) {
+		case *interfaces.Interfaces_Interface:
+			cnpd.reconcileInterface(vppLabel, o)
+			return nil
+		case *l2.XConnectPairs_XConnectPair:
+			cnpd.reconcileXConnect(vppLabel, o)
+			return nil
+		}
+		// reconcile does not cache the acls, they are written as rendered
+		return cnpd.agentPut(vppLabel, obj)
+	}
+
+	if written, exists := prev[key]; exists && proto.Equal(written, obj) {
+		cnpd.renderedKeys = append(cnpd.renderedKeys, key)
+		return nil
+	}
+	return cnpd.agentPut(vppLabel, obj)
+}
+
+// xconnDelete removes an object the chain no longer has
+func (cnpd *sfcCtlrL2CNPDriver) xconnDelete(key string) error {
+
+	log.Infof("xconnDelete: removing key: '%s'", key)
+
+	return cnpd.agentDeleteKey(key)
+}
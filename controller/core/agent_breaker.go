@@ -0,0 +1,127 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The circuit breaker of the unreachable vpp-agents is implemented in this
+// file.  An agent refreshes its status periodically, when a host's agent has
+// not done so for agent_down_timeout its breaker opens: the keys of the host
+// and of its containers are not written anymore, and the entities rendered
+// toward them are AGENT_PENDING instead of waiting for an agent that will not
+// answer.  Once the agent refreshes its status again the breaker closes and
+// the host's agents are reconciled, which writes what was held back.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ligato/cn-infra/health/statuscheck/model/status"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils"
+)
+
+// agentWritable is whether the keys of the agent are written, its host is in this controller's shard and
+// its breaker is closed
+func (sfcCtrlPlugin *SfcControllerPluginHandler) agentWritable(vppLabel string) bool {
+	return sfcCtrlPlugin.ownsVppLabel(vppLabel) && sfcCtrlPlugin.agentDownHost(vppLabel) == ""
+}
+
+// agentDownHost returns the host of the agent if its breaker is open, "" otherwise
+func (sfcCtrlPlugin *SfcControllerPluginHandler) agentDownHost(vppLabel string) string {
+
+	if len(sfcCtrlPlugin.agentBreakers) == 0 {
+		return ""
+	}
+	host := sfcCtrlPlugin.vppLabelHost(vppLabel)
+	if _, open := sfcCtrlPlugin.agentBreakers[host]; !open {
+		return ""
+	}
+	return host
+}
+
+// agentDownHostOfKeys returns the first host with an open breaker the keys are written to, "" if there is none
+func (sfcCtrlPlugin *SfcControllerPluginHandler) agentDownHostOfKeys(keys map[string]struct{}) string {
+
+	if len(sfcCtrlPlugin.agentBreakers) == 0 {
+		return ""
+	}
+	for key := range keys {
+		if host := sfcCtrlPlugin.agentDownHost(utils.GetVppEtcdlabel(key)); host != "" {
+			return host
+		}
+	}
+	return ""
+}
+
+// agentDownMessage is the status message of an entity rendered toward the host whose agent is down
+func agentDownMessage(host string) string {
+	return fmt.Sprintf("agent of host: '%s' is down", host)
+}
+
+// agentBreakerCheck opens the host's breaker when its agent has not refreshed its status within the timeout,
+// or closes it and reconciles the host once it has, it returns true if the host was reconciled
+func (sfcCtrlPlugin *SfcControllerPluginHandler) agentBreakerCheck(heName string, agentStatus *status.AgentStatus,
+	now int64) bool {
+
+	timeout := int64(sfcCtrlPlugin.ramConfigCache.SysParms.AgentDownTimeout)
+	if timeout == 0 || !sfcCtrlPlugin.ownsVppLabel(heName) {
+		return false
+	}
+	if sfcCtrlPlugin.agentBreakers == nil {
+		sfcCtrlPlugin.agentBreakers = make(map[string]int64)
+	}
+
+	down := agentStatus == nil || now-agentStatus.LastUpdate > timeout
+	_, open := sfcCtrlPlugin.agentBreakers[heName]
+
+	switch {
+	case down && !open:
+		log.Warnf("agentBreakerCheck: agent '%s' is down, suspending its rendering", heName)
+		sfcCtrlPlugin.agentBreakers[heName] = now
+		sfcCtrlPlugin.alarmAgent(heName, true, agentDownMessage(heName))
+	case !down && open:
+		log.Infof("agentBreakerCheck: agent '%s' is back after %ds, reconciling its config", heName,
+			now-sfcCtrlPlugin.agentBreakers[heName])
+		delete(sfcCtrlPlugin.agentBreakers, heName)
+		sfcCtrlPlugin.alarmAgent(heName, false, "")
+		for _, vppLabel := range sfcCtrlPlugin.hostVppLabels(heName) {
+			if err := sfcCtrlPlugin.ReconcileVppLabel(vppLabel); err != nil {
+				log.Errorf("agentBreakerCheck: error reconciling agent '%s': %s", vppLabel, err)
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// hostVppLabels returns the labels of the host's agent and of the agents of the containers on the host
+func (sfcCtrlPlugin *SfcControllerPluginHandler) hostVppLabels(heName string) []string {
+
+	vppLabels := []string{heName}
+	seen := map[string]bool{heName: true}
+	for _, sfcName := range sortedKeysSFC(sfcCtrlPlugin.ramConfigCache.SFCs) {
+		sfc := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+		for _, sfcElement := range sfc.GetElements() {
+			switch sfcElement.Type {
+			case controller.SfcElementType_EXTERNAL_ENTITY, controller.SfcElementType_HOST_ENTITY:
+				continue
+			}
+			if sfcElement.EtcdVppSwitchKey == heName && !seen[sfcElement.Container] {
+				seen[sfcElement.Container] = true
+				vppLabels = append(vppLabels, sfcElement.Container)
+			}
+		}
+	}
+	return vppLabels
+}
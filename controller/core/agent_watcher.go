@@ -0,0 +1,84 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The vpp-agent liveness watcher is implemented in this file.  Every agent
+// publishes its start time in its status tree, when the start time of a
+// host's agent changes the agent has restarted, and the host's config is
+// reconciled on its own so it is restored without touching the other hosts.
+// The breakers of the agents that stopped refreshing their status are checked
+// at the same time, see agent_breaker.go.
+
+package core
+
+import (
+	"time"
+
+	"github.com/ligato/cn-infra/health/statuscheck/model/status"
+	"github.com/ligato/sfc-controller/controller/utils"
+)
+
+// how often the watcher checks whether it has been enabled in the system parameters
+const agentWatchIdleInterval = 5 * time.Second
+
+// agentLivenessWatch runs until the plugin is closed, checking the hosts' agents every agent_watch_interval
+func (sfcCtrlPlugin *SfcControllerPluginHandler) agentLivenessWatch() {
+
+	startTimes := make(map[string]int64) // last start time seen per host
+
+	for {
+		interval := agentWatchIdleInterval
+
+		sfcCtrlPlugin.HttpMutex.Lock()
+		if secs := sfcCtrlPlugin.ramConfigCache.SysParms.AgentWatchInterval; secs != 0 {
+			interval = time.Duration(secs) * time.Second
+			sfcCtrlPlugin.agentLivenessCheck(startTimes)
+		}
+		sfcCtrlPlugin.HttpMutex.Unlock()
+
+		select {
+		case <-sfcCtrlPlugin.agentWatchDone:
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// agentLivenessCheck reconciles the hosts whose agent start time changed since the previous check, the first
+// time an agent is seen its config was just rendered so it is only recorded
+func (sfcCtrlPlugin *SfcControllerPluginHandler) agentLivenessCheck(startTimes map[string]int64) {
+
+	now := time.Now().Unix()
+	for _, heName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		agentStatus := &status.AgentStatus{}
+		found, _, err := sfcCtrlPlugin.db.GetValue(utils.AgentStatusKey(heName), agentStatus)
+		if err != nil || !found {
+			sfcCtrlPlugin.agentBreakerCheck(heName, nil, now)
+			continue
+		}
+
+		lastStartTime, seen := startTimes[heName]
+		startTimes[heName] = agentStatus.StartTime
+		if sfcCtrlPlugin.agentBreakerCheck(heName, agentStatus, now) {
+			continue // reconciled as it came back
+		}
+		if !seen || lastStartTime == agentStatus.StartTime {
+			continue
+		}
+
+		log.Infof("agentLivenessCheck: agent '%s' restarted, reconciling its config", heName)
+		if err := sfcCtrlPlugin.ReconcileVppLabel(heName); err != nil {
+			log.Errorf("agentLivenessCheck: error reconciling agent '%s': %s", heName, err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The critical conditions raised to the NOC are detected in this file.  With
+// -alarm-targets set to a comma separated list of syslog and snmp urls, see
+// the alarms package, an alarm is raised when:
+//
+//	an entity fails to render, it is cleared once the entity renders
+//	an id space of the driver, ie the vlan/vni's, runs out
+//	the agent of a host stops reporting, it is cleared once the agent is back
+//	a consistency check finds the agents diverged from the datastore, it is
+//	cleared by the next check that finds them consistent
+
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/alarms"
+)
+
+// alarmTag names the controller in the syslog messages
+const alarmTag = "sfc-controller"
+
+// initAlarms starts raising the alarms to the targets
+func (sfcCtrlPlugin *SfcControllerPluginHandler) initAlarms(targets string) error {
+
+	var sinks []alarms.Sink
+	for _, target := range strings.Split(targets, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		sink, err := alarms.NewSink(target, alarmTag)
+		if err != nil {
+			return err
+		}
+		sinks = append(sinks, sink)
+	}
+	if len(sinks) == 0 {
+		return fmt.Errorf("Invalid alarm targets: '%s'", targets)
+	}
+	sfcCtrlPlugin.alarmNotifier = alarms.NewNotifier(sinks...)
+
+	log.Infof("initAlarms: raising alarms to: '%s'", targets)
+	return nil
+}
+
+// alarmEntityStatus raises the render failure of an entity, or clears it once the entity rendered
+func (sfcCtrlPlugin *SfcControllerPluginHandler) alarmEntityStatus(kind string, status *controller.EntityStatus) {
+
+	if sfcCtrlPlugin.alarmNotifier == nil {
+		return
+	}
+	name := kind + "/" + status.Name
+	switch status.State {
+	case controller.RenderStateType_RENDER_ERROR, controller.RenderStateType_PARTIALLY_RENDERED:
+		sfcCtrlPlugin.alarmNotifier.Raise(alarms.ConditionRenderFailure, name, status.Message)
+	case controller.RenderStateType_RENDERED:
+		sfcCtrlPlugin.alarmNotifier.Clear(alarms.ConditionRenderFailure, name)
+	}
+}
+
+// alarmExhaustedIDSpaces raises the id spaces the driver ran out of in the last render
+func (sfcCtrlPlugin *SfcControllerPluginHandler) alarmExhaustedIDSpaces() {
+
+	if sfcCtrlPlugin.alarmNotifier == nil {
+		return
+	}
+	for _, space := range sfcCtrlPlugin.cnpDriverPlugin.GetExhaustedIDSpaces() {
+		sfcCtrlPlugin.alarmNotifier.Raise(alarms.ConditionIDExhausted, space,
+			fmt.Sprintf("the %s id space is full, id's are allocated out of its range", space))
+	}
+}
+
+// alarmAgent raises the agent of the host as unreachable, or clears it once it is back
+func (sfcCtrlPlugin *SfcControllerPluginHandler) alarmAgent(heName string, down bool, message string) {
+
+	if sfcCtrlPlugin.alarmNotifier == nil {
+		return
+	}
+	if down {
+		sfcCtrlPlugin.alarmNotifier.Raise(alarms.ConditionAgentUnreachable, heName, message)
+	} else {
+		sfcCtrlPlugin.alarmNotifier.Clear(alarms.ConditionAgentUnreachable, heName)
+	}
+}
+
+// alarmConsistency raises the divergence a consistency check found, or clears it when there was none
+func (sfcCtrlPlugin *SfcControllerPluginHandler) alarmConsistency(report *ConsistencyReport) {
+
+	if sfcCtrlPlugin.alarmNotifier == nil {
+		return
+	}
+	if report.Consistent {
+		sfcCtrlPlugin.alarmNotifier.Clear(alarms.ConditionReconcileDivergence, "")
+		return
+	}
+	sfcCtrlPlugin.alarmNotifier.Raise(alarms.ConditionReconcileDivergence, "",
+		fmt.Sprintf("%d inconsistencies between the agents and the datastore, the first: %s: '%s': %s",
+			len(report.Inconsistencies), report.Inconsistencies[0].Kind, report.Inconsistencies[0].Key,
+			report.Inconsistencies[0].Detail))
+}
@@ -17,8 +17,28 @@
 package core
 
 import (
+	"github.com/ligato/sfc-controller/controller/cnpdriver/l2driver"
 )
 
-func (sfcCtrlPlugin *SfcControllerPluginHandler) GetSfcInterfaceIPAndMac(container string, port string) (string, string, error) {
+// GetSfcInterfaceIPAndMac returns the ipv4 and ipv6 addresses, and the mac, the driver gave the element
+func (sfcCtrlPlugin *SfcControllerPluginHandler) GetSfcInterfaceIPAndMac(container string, port string) (string, string,
+	string, error) {
 	return sfcCtrlPlugin.cnpDriverPlugin.GetSfcInterfaceIPAndMac(container, port)
-}
\ No newline at end of file
+}
+
+// GetEntityState returns what the driver rendered, and holds in its state cache, for the entities of the name
+func (sfcCtrlPlugin *SfcControllerPluginHandler) GetEntityState(entityName string) (*l2driver.EntityState, error) {
+	return sfcCtrlPlugin.cnpDriverPlugin.GetState(entityName)
+}
+
+// GetAllocatedIDs returns the ids the driver allocated, optionally only the ones of the host and of the chain
+func (sfcCtrlPlugin *SfcControllerPluginHandler) GetAllocatedIDs(heName string, sfcName string) (
+	*l2driver.AllocatedIDs, error) {
+	return sfcCtrlPlugin.cnpDriverPlugin.GetAllocatedIDs(heName, sfcName)
+}
+
+// ForensicLookup returns the chains, elements, containers and hosts an observed vni, mac or ip belongs to
+func (sfcCtrlPlugin *SfcControllerPluginHandler) ForensicLookup(query *l2driver.ForensicQuery) (
+	[]*l2driver.ForensicMatch, error) {
+	return sfcCtrlPlugin.cnpDriverPlugin.ForensicLookup(query)
+}
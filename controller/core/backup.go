@@ -0,0 +1,244 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Backup and restore of the controller owned tree in etcd.  This is the config
+// (system parameters, ee's, he's, sfc's), the config versions, and the id's the
+// cnp driver has allocated.  The archive is a stream of json lines, one per
+// etcd key: {"key": "/sfc-controller/v1/...", "value": {...}}.  The vpp agent
+// trees are not archived, they are rendered from the restored config, and
+// neither are the driver's records of the agent keys it owns: they track what
+// is written to the agents, which the restore itself does not change, and the
+// render of the restored config updates them like any other render.
+
+package core
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+type backupEntry struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// backupValue carries an etcd value through the proto broker without knowing its type, the broker
+// serializes with encoding/json so the stored json is handed over as is
+type backupValue struct {
+	data json.RawMessage
+}
+
+func (m *backupValue) Reset()         { m.data = nil }
+func (m *backupValue) String() string { return string(m.data) }
+func (*backupValue) ProtoMessage()    {}
+
+func (m *backupValue) MarshalJSON() ([]byte, error) {
+	return m.data, nil
+}
+
+func (m *backupValue) UnmarshalJSON(data []byte) error {
+	m.data = append(m.data[0:0], data...)
+	return nil
+}
+
+// backedUp is whether the key of the sfc tree is archived and restored, see the top of the file
+func backedUp(key string) bool {
+	return !strings.HasPrefix(key, l2driver.OwnedKeysKeyPrefix())
+}
+
+// DatastoreBackup streams every key in the sfc tree in etcd to the archive, it returns the number of keys
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreBackup(w io.Writer) (int, error) {
+
+	entries, err := sfcCtrlPlugin.datastoreEntries()
+	if err != nil {
+		log.Error("DatastoreBackup: ", err)
+		return 0, err
+	}
+
+	enc := json.NewEncoder(w)
+	for i := range entries {
+		if err := enc.Encode(&entries[i]); err != nil {
+			log.Error("DatastoreBackup: error writing archive: ", err)
+			return i, err
+		}
+	}
+
+	log.Infof("DatastoreBackup: %d keys archived", len(entries))
+
+	return len(entries), nil
+}
+
+// DatastoreRestore replaces the sfc tree in etcd with the contents of the archive, the whole archive
+// is read and checked before the tree is touched, it returns the number of keys restored.  The tree is
+// too large for an etcd txn, so if a write fails, the tree as it was before the restore is written back.
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreRestore(r io.Reader) (int, error) {
+
+	var entries []backupEntry
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry backupEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return 0, fmt.Errorf("DatastoreRestore: archive line %d: %s", line, err)
+		}
+		if !strings.HasPrefix(entry.Key, controller.SfcControllerPrefix()) {
+			return 0, fmt.Errorf("DatastoreRestore: archive line %d: key '%s' is not in the sfc tree",
+				line, entry.Key)
+		}
+		if !backedUp(entry.Key) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Error("DatastoreRestore: error reading archive: ", err)
+		return 0, err
+	}
+
+	snapshot, err := sfcCtrlPlugin.datastoreEntries()
+	if err != nil {
+		log.Error("DatastoreRestore: ", err)
+		return 0, err
+	}
+
+	log.Infof("DatastoreRestore: replacing the sfc tree, restoring %d keys", len(entries))
+
+	if err := sfcCtrlPlugin.datastoreReplace(snapshot, entries); err != nil {
+		log.Error("DatastoreRestore: error replacing the sfc tree, rolling back: ", err)
+		if err := sfcCtrlPlugin.datastoreReplace(entries, snapshot); err != nil {
+			log.Error("DatastoreRestore: error rolling back the sfc tree: ", err)
+		}
+		return 0, err
+	}
+
+	return len(entries), nil
+}
+
+// datastoreEntries reads the keys of the sfc tree that are archived
+func (sfcCtrlPlugin *SfcControllerPluginHandler) datastoreEntries() ([]backupEntry, error) {
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(controller.SfcControllerPrefix())
+	if err != nil {
+		return nil, fmt.Errorf("databroker list: %s", err)
+	}
+
+	var entries []backupEntry
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			break
+		}
+		if !backedUp(kv.GetKey()) {
+			continue
+		}
+		value := &backupValue{}
+		if err := kv.GetValue(value); err != nil {
+			return nil, fmt.Errorf("bad value: '%s': %s", kv.GetKey(), err)
+		}
+		entries = append(entries, backupEntry{Key: kv.GetKey(), Value: value.data})
+	}
+
+	return entries, nil
+}
+
+// datastoreReplace writes the entries over the current ones, the current keys the entries do not have
+// are deleted
+func (sfcCtrlPlugin *SfcControllerPluginHandler) datastoreReplace(current []backupEntry,
+	entries []backupEntry) error {
+
+	keep := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		keep[entry.Key] = struct{}{}
+	}
+	for _, entry := range current {
+		if _, exists := keep[entry.Key]; exists {
+			continue
+		}
+		if _, err := sfcCtrlPlugin.db.Delete(entry.Key); err != nil {
+			return fmt.Errorf("databroker delete: '%s': %s", entry.Key, err)
+		}
+	}
+	for _, entry := range entries {
+		if err := sfcCtrlPlugin.db.Put(entry.Key, &backupValue{data: entry.Value}); err != nil {
+			return fmt.Errorf("databroker put: '%s': %s", entry.Key, err)
+		}
+	}
+
+	return nil
+}
+
+func (sfcCtrlPlugin *SfcControllerPluginHandler) restoreFromFile(fpath string) error {
+
+	f, err := os.Open(fpath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = sfcCtrlPlugin.DatastoreRestore(f)
+
+	return err
+}
+
+// restoreAndRebuild restores the archive, then re-reads the ram cache, the cnp driver's caches and
+// sequencers from etcd, and renders the restored config inside a reconcile like Init does
+func (sfcCtrlPlugin *SfcControllerPluginHandler) restoreAndRebuild(r io.Reader) (int, error) {
+
+	before := sfcCtrlPlugin.ramCacheToConfigVersion()
+
+	count, err := sfcCtrlPlugin.DatastoreRestore(r)
+	if err != nil {
+		return count, err
+	}
+
+	sfcCtrlPlugin.InitRAMCache()
+	if err := sfcCtrlPlugin.ReadEtcdDatastoreIntoRAMCache(); err != nil {
+		return count, err
+	}
+	if err := sfcCtrlPlugin.validateRAMCache(); err != nil {
+		return count, err
+	}
+	sfcCtrlPlugin.evictRemovedEntities(before, sfcCtrlPlugin.ramCacheToConfigVersion())
+
+	// the driver re-reads its id's and primes its sequencers when the reconcile starts
+	sfcCtrlPlugin.ReconcileStart()
+	if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+		sfcCtrlPlugin.ReconcileEnd()
+		return count, err
+	}
+	// if a canary host fails verification, the restored config stays in etcd but the agents keep running
+	// the previous config until it is re-applied, ie by a restart
+	if err := sfcCtrlPlugin.ReconcileEndCanary(); err != nil {
+		return count, err
+	}
+
+	if err := sfcCtrlPlugin.configVersionInitFromDatastore(); err != nil {
+		return count, err
+	}
+
+	sfcCtrlPlugin.recordConfigVersionChanges(before, sfcCtrlPlugin.ramCacheToConfigVersion(), "restore")
+
+	return count, nil
+}
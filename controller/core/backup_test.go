@@ -0,0 +1,107 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// failingBroker fails the puts of one key
+type failingBroker struct {
+	keyval.ProtoBroker
+	failKey string
+}
+
+func (b *failingBroker) Put(key string, data proto.Message, opts ...datasync.PutOption) error {
+	if key == b.failKey {
+		return errors.New("put failed")
+	}
+	return b.ProtoBroker.Put(key, data, opts...)
+}
+
+// the records of the agent keys the driver owns are neither archived nor restored, they track the agents
+func TestBackupRestoreKeepsOwnedKeys(t *testing.T) {
+
+	sfcCtrlPlugin, broker := newTestPlugin(t)
+	owned := broker.Dump(l2driver.OwnedKeysKeyPrefix())
+	if len(owned) == 0 {
+		t.Fatal("the render recorded no owned agent keys")
+	}
+
+	var archive bytes.Buffer
+	if _, err := sfcCtrlPlugin.DatastoreBackup(&archive); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(archive.String(), l2driver.OwnedKeysKeyPrefix()) {
+		t.Errorf("the archive holds owned agent key records")
+	}
+
+	// an archive may still hold the records, ie one taken by an earlier controller
+	stale := "/vnf-agent/vswitch/vpp/config/v1/interface/stale"
+	archive.WriteString(`{"key": "` + l2driver.OwnedKeyKey(stale) + `", "value": {"key": "` + stale + `"}}` + "\n")
+	if _, err := sfcCtrlPlugin.DatastoreRestore(&archive); err != nil {
+		t.Fatal(err)
+	}
+	if restored := broker.Dump(l2driver.OwnedKeysKeyPrefix()); !reflect.DeepEqual(restored, owned) {
+		t.Errorf("the restore changed the owned agent key records: %d, expected: %d", len(restored), len(owned))
+	}
+	if !sfcStored(t, sfcCtrlPlugin, "vnf1-vnf2") {
+		t.Errorf("the sfc is not restored")
+	}
+}
+
+// a restore that fails part way leaves the sfc tree as it was before the restore
+func TestRestoreRollsBack(t *testing.T) {
+
+	sfcCtrlPlugin, broker := newTestPlugin(t, testChain("chain1"))
+	before := broker.Dump(controller.SfcControllerPrefix())
+
+	var archive bytes.Buffer
+	if _, err := sfcCtrlPlugin.DatastoreBackup(&archive); err != nil {
+		t.Fatal(err)
+	}
+
+	// the archive drops chain1, and adds chain2 before the key that fails
+	var restore bytes.Buffer
+	for _, line := range strings.SplitAfter(archive.String(), "\n") {
+		if !strings.Contains(line, controller.SfcEntityNameKey("chain1")+`"`) {
+			restore.WriteString(line)
+		}
+	}
+	failKey := controller.SfcEntityNameKey("fails")
+	restore.WriteString(`{"key": "` + controller.SfcEntityNameKey("chain2") + `", "value": {"name": "chain2"}}` + "\n")
+	restore.WriteString(`{"key": "` + failKey + `", "value": {"name": "fails"}}` + "\n")
+
+	sfcCtrlPlugin.db = &failingBroker{ProtoBroker: sfcCtrlPlugin.db, failKey: failKey}
+	if _, err := sfcCtrlPlugin.DatastoreRestore(&restore); err == nil {
+		t.Fatal("the restore did not fail")
+	}
+	if after := broker.Dump(controller.SfcControllerPrefix()); !reflect.DeepEqual(after, before) {
+		t.Errorf("the failed restore changed the sfc tree: %d keys, expected: %d", len(after), len(before))
+	}
+	if !sfcStored(t, sfcCtrlPlugin, "chain1") || sfcStored(t, sfcCtrlPlugin, "chain2") {
+		t.Errorf("the sfcs are not rolled back")
+	}
+}
@@ -0,0 +1,190 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The bandwidth admission of the chains is implemented in this file.  A
+// chain that declares bandwidth_mbps commits it on every host uplink its
+// traffic crosses: the nic of a host element, and on the hosts of its
+// containers, the uplink toward each external entity and each other host of
+// the chain, ie the peer uplink or eth_if_name.  A chain is validated
+// against the capacity the hosts declare for their nics, and one that would
+// commit more than an uplink has is rejected, or admitted with a warning if
+// the system parameters allow bandwidth_overcommit.  A nic without a
+// capacity is not limited, its commitments are only reported.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// UplinkBandwidth is the bandwidth the chains commit on a host uplink, its capacity is 0 if it is not limited
+type UplinkBandwidth struct {
+	Host          string   `json:"host"`
+	Interface     string   `json:"interface"`
+	CapacityMbps  uint32   `json:"capacity_mbps"`
+	CommittedMbps uint32   `json:"committed_mbps"`
+	RemainingMbps int64    `json:"remaining_mbps"`
+	Sfcs          []string `json:"sfcs"`
+}
+
+type hostUplink struct {
+	host   string
+	ifName string
+}
+
+// hostPeerIfName returns the uplink the host reaches the peer host or external entity by
+func hostPeerIfName(he *controller.HostEntity, peer string) string {
+
+	for _, peerUplink := range he.GetPeerUplinks() {
+		if peerUplink.Peer == peer && peerUplink.EthIfName != "" {
+			return peerUplink.EthIfName
+		}
+	}
+	return he.EthIfName
+}
+
+// sfcHostUplinks returns the host uplinks the chain's traffic crosses
+func (sfcCtrlPlugin *SfcControllerPluginHandler) sfcHostUplinks(sfc *controller.SfcEntity) []hostUplink {
+
+	uplinks := make(map[hostUplink]struct{})
+	var hosts, ees []string
+	for _, sfcElement := range sfc.GetElements() {
+		switch sfcElement.Type {
+		case controller.SfcElementType_HOST_ENTITY:
+			uplinks[hostUplink{host: sfcElement.Container, ifName: sfcElement.PortLabel}] = struct{}{}
+		case controller.SfcElementType_EXTERNAL_ENTITY:
+			ees = append(ees, sfcElement.Container)
+		default:
+			hosts = append(hosts, sfcElement.EtcdVppSwitchKey)
+		}
+	}
+
+	peers := append(ees, hosts...)
+	for _, heName := range hosts {
+		he, exists := sfcCtrlPlugin.ramConfigCache.HEs[heName]
+		if !exists {
+			continue
+		}
+		for _, peer := range peers {
+			if peer != heName {
+				uplinks[hostUplink{host: heName, ifName: hostPeerIfName(&he, peer)}] = struct{}{}
+			}
+		}
+	}
+
+	sorted := make([]hostUplink, 0, len(uplinks))
+	for uplink := range uplinks {
+		sorted = append(sorted, uplink)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].host != sorted[j].host {
+			return sorted[i].host < sorted[j].host
+		}
+		return sorted[i].ifName < sorted[j].ifName
+	})
+	return sorted
+}
+
+// uplinkBandwidth returns the bandwidth the chains commit on the uplinks of the hosts, and on the nics of the
+// host elements
+func (sfcCtrlPlugin *SfcControllerPluginHandler) uplinkBandwidth(
+	sfcs map[string]controller.SfcEntity) map[hostUplink]*UplinkBandwidth {
+
+	bandwidth := make(map[hostUplink]*UplinkBandwidth)
+	addUplink := func(host string, ifName string, capacity uint32) *UplinkBandwidth {
+		uplink := hostUplink{host: host, ifName: ifName}
+		if _, exists := bandwidth[uplink]; !exists {
+			bandwidth[uplink] = &UplinkBandwidth{Host: host, Interface: ifName, CapacityMbps: capacity,
+				RemainingMbps: int64(capacity), Sfcs: make([]string, 0)}
+		}
+		return bandwidth[uplink]
+	}
+
+	for heName, he := range sfcCtrlPlugin.ramConfigCache.HEs {
+		if he.EthIfName != "" {
+			addUplink(heName, he.EthIfName, he.EthBandwidthMbps)
+		}
+		for _, uplink := range he.GetUplinks() {
+			addUplink(heName, uplink.EthIfName, uplink.BandwidthMbps)
+		}
+	}
+
+	for _, sfcName := range sortedKeysSFC(sfcs) {
+		sfc := sfcs[sfcName]
+		if sfc.BandwidthMbps == 0 {
+			continue
+		}
+		for _, uplink := range sfcCtrlPlugin.sfcHostUplinks(&sfc) {
+			ub := addUplink(uplink.host, uplink.ifName, 0)
+			ub.CommittedMbps += sfc.BandwidthMbps
+			ub.RemainingMbps -= int64(sfc.BandwidthMbps)
+			ub.Sfcs = append(ub.Sfcs, sfcName)
+		}
+	}
+
+	return bandwidth
+}
+
+// bandwidthReport returns the bandwidth committed on the uplinks of the host, of all hosts if it is empty
+func (sfcCtrlPlugin *SfcControllerPluginHandler) bandwidthReport(heName string) []UplinkBandwidth {
+
+	report := make([]UplinkBandwidth, 0)
+	for _, ub := range sfcCtrlPlugin.uplinkBandwidth(sfcCtrlPlugin.ramConfigCache.SFCs) {
+		if heName == "" || ub.Host == heName {
+			report = append(report, *ub)
+		}
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if report[i].Host != report[j].Host {
+			return report[i].Host < report[j].Host
+		}
+		return report[i].Interface < report[j].Interface
+	})
+
+	return report
+}
+
+// validateSfcBandwidth admits the chain's bandwidth_mbps on the uplinks it crosses, next to the other chains
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSfcBandwidth(sfc *controller.SfcEntity) error {
+
+	if sfc.BandwidthMbps == 0 {
+		return nil
+	}
+
+	sfcs := make(map[string]controller.SfcEntity, len(sfcCtrlPlugin.ramConfigCache.SFCs)+1)
+	for sfcName, other := range sfcCtrlPlugin.ramConfigCache.SFCs {
+		sfcs[sfcName] = other
+	}
+	sfcs[sfc.Name] = *sfc
+
+	bandwidth := sfcCtrlPlugin.uplinkBandwidth(sfcs)
+	for _, uplink := range sfcCtrlPlugin.sfcHostUplinks(sfc) {
+		ub := bandwidth[uplink]
+		if ub.CapacityMbps == 0 || ub.RemainingMbps >= 0 {
+			continue
+		}
+		err := fmt.Errorf("Invalid bandwidth_mbps: %d for sfc: '%s', i/f: '%s' of he: '%s' is oversubscribed, "+
+			"%d of its %d mbps are committed", sfc.BandwidthMbps, sfc.Name, ub.Interface, ub.Host,
+			ub.CommittedMbps, ub.CapacityMbps)
+		if !sfcCtrlPlugin.ramConfigCache.SysParms.BandwidthOvercommit {
+			return err
+		}
+		log.Warnf("validateSfcBandwidth: admitted by bandwidth_overcommit: %s", err)
+	}
+
+	return nil
+}
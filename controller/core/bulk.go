@@ -0,0 +1,311 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The bulk apply of a batch of entities is implemented in this file.  The
+// ee's, he's and sfc's of the batch are validated together, each against the
+// config with the whole batch applied, so a chain may reference a host or an
+// ee posted in the same batch.  A valid batch is rendered like a rollback:
+// the whole config is re-rendered in a reconcile, so the interfaces, bridge
+// domains, l2fib's, xconnects, static routes and arps are only compared and
+// written when the reconcile ends, after every entity rendered.  The objects
+// the driver writes while it renders, ie. acls, are already in the agents'
+// trees by then.  If an entity fails to render, or the canary rejects the
+// changes, the config that was running is rendered again and the entities
+// the batch created are evicted, so the batch is not kept.
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// the results of the entities of a batch
+const (
+	BulkResultCreated     = "created"
+	BulkResultUpdated     = "updated"
+	BulkResultUnchanged   = "unchanged"
+	BulkResultInvalid     = "invalid"      // the entity failed validation
+	BulkResultRenderError = "render_error" // the entity failed to render
+	BulkResultNotApplied  = "not_applied"  // the entity is fine, another one failed
+)
+
+// BulkApply is a batch of entities applied all or nothing
+type BulkApply struct {
+	ExternalEntities []*controller.ExternalEntity `json:"external_entities,omitempty"`
+	HostEntities     []*controller.HostEntity     `json:"host_entities,omitempty"`
+	SfcEntities      []*controller.SfcEntity      `json:"sfc_entities,omitempty"`
+}
+
+// BulkEntityResult is the result of an entity of a batch
+type BulkEntityResult struct {
+	Kind   string `json:"kind"`
+	Name   string `json:"name"`
+	Result string `json:"result"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BulkApplyResult is the result of a batch, with the result of each of its entities in the batch's order
+type BulkApplyResult struct {
+	Applied bool                `json:"applied"`
+	Version uint32              `json:"version,omitempty"` // the config version recording the batch
+	Results []*BulkEntityResult `json:"results"`
+}
+
+// bulkApply validates and wires the entities of the batch, either all of them or none
+func (sfcCtrlPlugin *SfcControllerPluginHandler) bulkApply(batch *BulkApply, source string) (*BulkApplyResult,
+	error) {
+
+	current := sfcCtrlPlugin.ramCacheToConfigVersion()
+	current.Version = sfcCtrlPlugin.configVersion
+
+	result := &BulkApplyResult{}
+	changed, err := sfcCtrlPlugin.bulkStage(batch, result)
+	if err != nil {
+		sfcCtrlPlugin.configVersionToRAMCache(current)
+		bulkResultsNotApplied(result)
+		return result, err
+	}
+	if changed == 0 {
+		result.Applied = true
+		return result, nil
+	}
+	target := sfcCtrlPlugin.ramCacheToConfigVersion()
+
+	log.Infof("bulkApply: rendering %d changed entities of %d", changed, len(result.Results))
+
+	sfcCtrlPlugin.ReconcileStart()
+	err = sfcCtrlPlugin.renderConfigFromRAMCache()
+	if renderErr := sfcCtrlPlugin.bulkRenderErrors(result); err == nil {
+		err = renderErr
+	}
+	if err == nil {
+		err = sfcCtrlPlugin.ReconcileEndCanary()
+	}
+	if err != nil {
+		log.Errorf("bulkApply: batch not applied, rendering the running config: %s", err)
+		sfcCtrlPlugin.bulkRestore(current, target, result)
+		bulkResultsNotApplied(result)
+		return result, err
+	}
+	result.Applied = true // the agents are wired, errors from here on are the datastore's
+
+	for _, ee := range batch.ExternalEntities {
+		if err := sfcCtrlPlugin.DatastoreExternalEntityCreate(ee); err != nil {
+			return result, err
+		}
+	}
+	for _, he := range batch.HostEntities {
+		if err := sfcCtrlPlugin.DatastoreHostEntityCreate(he); err != nil {
+			return result, err
+		}
+	}
+	for _, sfc := range batch.SfcEntities {
+		if err := sfcCtrlPlugin.DatastoreSfcEntityCreate(sfc); err != nil {
+			return result, err
+		}
+	}
+	sfcCtrlPlugin.recordConfigVersionChanges(current, target, source)
+
+	if err := sfcCtrlPlugin.snapshotConfigVersion(fmt.Sprintf("POST bulk %d entities", changed)); err != nil {
+		return result, err
+	}
+	result.Version = sfcCtrlPlugin.configVersion
+
+	return result, nil
+}
+
+// bulkStage applies the batch to the ram cache, and validates each of its entities against the staged config,
+// the number of entities the batch creates or changes is returned
+func (sfcCtrlPlugin *SfcControllerPluginHandler) bulkStage(batch *BulkApply, result *BulkApplyResult) (int, error) {
+
+	cache := &sfcCtrlPlugin.ramConfigCache
+	seen := make(map[string]bool)
+	entityResult := func(kind string, name string, exists bool, same bool) *BulkEntityResult {
+		r := &BulkEntityResult{Kind: kind, Name: name, Result: BulkResultCreated}
+		switch {
+		case seen[kind+"/"+name]:
+			r.Result, r.Error = BulkResultInvalid, "Invalid batch: the entity is in it more than once"
+		case same:
+			r.Result = BulkResultUnchanged
+		case exists:
+			r.Result = BulkResultUpdated
+		}
+		seen[kind+"/"+name] = true
+		result.Results = append(result.Results, r)
+		return r
+	}
+
+	// everything is staged before it is validated so the entities may reference each other
+	for _, ee := range batch.ExternalEntities {
+		existing, exists := cache.EEs[ee.Name]
+		entityResult(controller.ExternalEntityKind, ee.Name, exists, exists && existing.String() == ee.String())
+		cache.EEs[ee.Name] = *ee
+	}
+	for _, he := range batch.HostEntities {
+		existing, exists := cache.HEs[he.Name]
+		entityResult(controller.HostEntityKind, he.Name, exists, exists && existing.String() == he.String())
+		cache.HEs[he.Name] = *he
+	}
+	previousSFCs := make(map[string]controller.SfcEntity)
+	for _, sfc := range batch.SfcEntities {
+		existing, exists := cache.SFCs[sfc.Name]
+		previousSFCs[sfc.Name] = existing
+		staged := *sfc
+		stampSfcExpiry(&staged, &existing, time.Now().Unix())
+		stampSfcDeleting(&staged, &existing)
+		entityResult(controller.SfcEntityKind, sfc.Name, exists, exists && existing.String() == staged.String())
+		cache.SFCs[sfc.Name] = staged
+	}
+
+	i := 0
+	validated := func(err error) {
+		if err != nil && result.Results[i].Result != BulkResultInvalid {
+			result.Results[i].Result, result.Results[i].Error = BulkResultInvalid, err.Error()
+		}
+		i++
+	}
+	for _, ee := range batch.ExternalEntities {
+		validated(sfcCtrlPlugin.validateEE(ee))
+	}
+	for _, he := range batch.HostEntities {
+		validated(sfcCtrlPlugin.validateHE(he))
+	}
+	for _, sfc := range batch.SfcEntities {
+		err := sfcCtrlPlugin.validateSFC(sfc)
+		if err == nil {
+			err = sfcCtrlPlugin.validateSfcReferences(sfc)
+		}
+		if err == nil {
+			previous := previousSFCs[sfc.Name]
+			err = validateSfcDeletingChange(sfc, &previous)
+		}
+		validated(err)
+	}
+
+	changed, invalid := 0, 0
+	for _, r := range result.Results {
+		switch r.Result {
+		case BulkResultInvalid:
+			invalid++
+		case BulkResultCreated, BulkResultUpdated:
+			changed++
+		}
+	}
+	if invalid != 0 {
+		return 0, fmt.Errorf("Invalid batch: %d of its %d entities are invalid", invalid, len(result.Results))
+	}
+
+	// the validation may have filled in defaults
+	for _, ee := range batch.ExternalEntities {
+		cache.EEs[ee.Name] = *ee
+	}
+	for _, he := range batch.HostEntities {
+		cache.HEs[he.Name] = *he
+	}
+	for _, sfc := range batch.SfcEntities {
+		staged, previous := *sfc, previousSFCs[sfc.Name]
+		stampSfcExpiry(&staged, &previous, time.Now().Unix())
+		stampSfcDeleting(&staged, &previous)
+		cache.SFCs[sfc.Name] = staged
+	}
+
+	return changed, nil
+}
+
+// validateSfcReferences checks the hosts and the ee's the chain's elements reference are configured
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSfcReferences(sfc *controller.SfcEntity) error {
+
+	for _, sfcElement := range sfc.GetElements() {
+		if sfcElement.Type == controller.SfcElementType_EXTERNAL_ENTITY {
+			if _, exists := sfcCtrlPlugin.ramConfigCache.EEs[sfcElement.Container]; !exists {
+				return fmt.Errorf("Invalid element: '%s', sfc: '%s', the external entity is not configured",
+					sfcElement.Container, sfc.Name)
+			}
+			continue
+		}
+		if sfcElement.EtcdVppSwitchKey != "" {
+			if _, exists := sfcCtrlPlugin.ramConfigCache.HEs[sfcElement.EtcdVppSwitchKey]; !exists {
+				return fmt.Errorf("Invalid element: '%s/%s', sfc: '%s', host: '%s' is not configured",
+					sfcElement.Container, sfcElement.PortLabel, sfc.Name, sfcElement.EtcdVppSwitchKey)
+			}
+		}
+	}
+	return nil
+}
+
+// bulkRenderErrors marks the entities the batch created or updated whose render status is an error, and
+// returns an error if there are any
+func (sfcCtrlPlugin *SfcControllerPluginHandler) bulkRenderErrors(result *BulkApplyResult) error {
+
+	failed := 0
+	for _, r := range result.Results {
+		if r.Result != BulkResultCreated && r.Result != BulkResultUpdated {
+			continue // the status of an unchanged entity is of an earlier render
+		}
+		status := &controller.EntityStatus{}
+		found, _, err := sfcCtrlPlugin.db.GetValue(controller.EntityStatusKey(r.Kind, r.Name), status)
+		if err != nil {
+			return err
+		}
+		if found && (status.State == controller.RenderStateType_RENDER_ERROR ||
+			status.State == controller.RenderStateType_PARTIALLY_RENDERED) {
+			r.Result, r.Error = BulkResultRenderError, status.Message
+			failed++
+		}
+	}
+	if failed != 0 {
+		return fmt.Errorf("bulkApply: %d of the batch's %d entities failed to render", failed, len(result.Results))
+	}
+	return nil
+}
+
+// bulkRestore renders the config that was running before the batch, and evicts what the batch created
+func (sfcCtrlPlugin *SfcControllerPluginHandler) bulkRestore(current *controller.ConfigVersion,
+	target *controller.ConfigVersion, result *BulkApplyResult) {
+
+	sfcCtrlPlugin.configVersionToRAMCache(current)
+	sfcCtrlPlugin.evictRemovedEntities(target, current)
+
+	sfcCtrlPlugin.ReconcileStart()
+	if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+		log.Errorf("bulkRestore: error re-rendering version %d: %s", current.Version, err)
+	}
+	sfcCtrlPlugin.ReconcileEnd()
+
+	// the status and the keys of an entity the batch created are of a render that was discarded
+	running := configVersionEntityStrings(current)
+	for _, r := range result.Results {
+		if _, exists := running[r.Kind+"/"+r.Name]; exists {
+			continue
+		}
+		for _, key := range []string{controller.EntityStatusKey(r.Kind, r.Name),
+			controller.EntityKeysKey(r.Kind, r.Name)} {
+			if _, err := sfcCtrlPlugin.db.Delete(key); err != nil {
+				log.Errorf("bulkRestore: error deleting key: '%s': %s", key, err)
+			}
+		}
+	}
+}
+
+// bulkResultsNotApplied marks the entities that did not fail themselves as not applied
+func bulkResultsNotApplied(result *BulkApplyResult) {
+	for _, r := range result.Results {
+		if r.Result != BulkResultInvalid && r.Result != BulkResultRenderError {
+			r.Result = BulkResultNotApplied
+		}
+	}
+}
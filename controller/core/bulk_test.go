@@ -0,0 +1,124 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+func TestBulkStageInvalidBatches(t *testing.T) {
+
+	unknownHost := testChain("chain2")
+	unknownHost.Elements[1].EtcdVppSwitchKey = "vswitch2"
+	unknownEE := testChain("chain3")
+	unknownEE.Elements[1] = &controller.SfcEntity_SfcElement{Container: "ee1",
+		Type: controller.SfcElementType_EXTERNAL_ENTITY}
+
+	tests := []struct {
+		name    string
+		batch   *BulkApply
+		invalid []string
+	}{
+		{"entity twice", &BulkApply{SfcEntities: []*controller.SfcEntity{chainPtr(testChain("chain1")),
+			chainPtr(testChain("chain1"))}}, []string{"chain1"}},
+		{"unknown host", &BulkApply{SfcEntities: []*controller.SfcEntity{chainPtr(testChain("chain1")),
+			&unknownHost}}, []string{"chain2"}},
+		{"unknown ee", &BulkApply{SfcEntities: []*controller.SfcEntity{&unknownEE}}, []string{"chain3"}},
+	}
+	for _, test := range tests {
+		sfcCtrlPlugin, _ := newTestPlugin(t)
+
+		result := &BulkApplyResult{}
+		changed, err := sfcCtrlPlugin.bulkStage(test.batch, result)
+		if err == nil {
+			t.Errorf("%s: the batch is not refused, %d changed", test.name, changed)
+			continue
+		}
+		var invalid []string
+		for _, r := range result.Results {
+			if r.Result == BulkResultInvalid {
+				invalid = append(invalid, r.Name)
+			}
+		}
+		if strings.Join(invalid, ",") != strings.Join(test.invalid, ",") {
+			t.Errorf("%s: invalid entities: %v, expected: %v", test.name, invalid, test.invalid)
+		}
+	}
+}
+
+func TestBulkStageResults(t *testing.T) {
+
+	sfcCtrlPlugin, _ := newTestPlugin(t)
+
+	existing := sfcCtrlPlugin.ramConfigCache.SFCs["vnf1-vnf2"]
+	updated := sfcCtrlPlugin.ramConfigCache.SFCs["vnf1-vnf2"]
+	updated.Description = "updated"
+	batch := &BulkApply{
+		HostEntities: []*controller.HostEntity{{Name: "vswitch"}},
+		SfcEntities:  []*controller.SfcEntity{&updated, chainPtr(testChain("chain1"))},
+	}
+	result := &BulkApplyResult{}
+	changed, err := sfcCtrlPlugin.bulkStage(batch, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed != 2 {
+		t.Errorf("%d entities changed, expected: 2", changed)
+	}
+	expected := []string{BulkResultUnchanged, BulkResultUpdated, BulkResultCreated}
+	for i, r := range result.Results {
+		if r.Result != expected[i] {
+			t.Errorf("%s: '%s' is %s, expected: %s", r.Kind, r.Name, r.Result, expected[i])
+		}
+	}
+	if existing.Description == sfcCtrlPlugin.ramConfigCache.SFCs["vnf1-vnf2"].Description {
+		t.Errorf("the updated sfc is not staged in the ram cache")
+	}
+}
+
+// the status of an entity the batch does not change is of an earlier render, it does not fail the batch
+func TestBulkRenderErrorsSkipUnchanged(t *testing.T) {
+
+	sfcCtrlPlugin, _ := newTestPlugin(t)
+
+	for _, name := range []string{"unchanged", "updated"} {
+		status := &controller.EntityStatus{State: controller.RenderStateType_RENDER_ERROR, Message: "failed"}
+		if err := sfcCtrlPlugin.db.Put(controller.EntityStatusKey(controller.SfcEntityKind, name), status); err != nil {
+			t.Fatal(err)
+		}
+	}
+	result := &BulkApplyResult{Results: []*BulkEntityResult{
+		{Kind: controller.SfcEntityKind, Name: "unchanged", Result: BulkResultUnchanged},
+	}}
+	if err := sfcCtrlPlugin.bulkRenderErrors(result); err != nil {
+		t.Errorf("the unchanged entity failed the batch: %s", err)
+	}
+	result.Results = append(result.Results,
+		&BulkEntityResult{Kind: controller.SfcEntityKind, Name: "updated", Result: BulkResultUpdated})
+	if err := sfcCtrlPlugin.bulkRenderErrors(result); err == nil {
+		t.Errorf("the updated entity that failed to render did not fail the batch")
+	}
+	if result.Results[0].Result != BulkResultUnchanged || result.Results[1].Result != BulkResultRenderError {
+		t.Errorf("results: %s, %s, expected: %s, %s", result.Results[0].Result, result.Results[1].Result,
+			BulkResultUnchanged, BulkResultRenderError)
+	}
+}
+
+func chainPtr(sfc controller.SfcEntity) *controller.SfcEntity {
+	return &sfc
+}
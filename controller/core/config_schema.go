@@ -0,0 +1,532 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The strict schema validation of the config files is implemented in this
+// file.  A yaml, or json, config file is checked against the schema of the
+// proto entities before it is decoded: a field the schema does not have,
+// ie a typo that would otherwise be ignored, a value of the wrong type, and
+// an enum value the proto does not define are errors.  Each error names the
+// path of the field, ie sfc_entities[2].elements[0].port_label, and its line
+// in the file, the line is found for block style yaml and indented json.
+
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/gogo/protobuf/proto"
+	yamlv2 "gopkg.in/yaml.v2"
+)
+
+// SchemaError is a field of a config file that does not match the schema, line is 0 if it was not found
+type SchemaError struct {
+	Line    int
+	Field   string
+	Message string
+}
+
+func (e *SchemaError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Field, e.Message)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Field, e.Message)
+}
+
+// SchemaErrors are all the fields of a config file that do not match the schema, in line order
+type SchemaErrors []*SchemaError
+
+func (errs SchemaErrors) Error() string {
+	msgs := make([]string, 0, len(errs))
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("Invalid config, %d field(s) do not match the schema:\n%s", len(errs),
+		strings.Join(msgs, "\n"))
+}
+
+// ParseYamlConfig checks the config file against the schema and decodes it, the error is a SchemaErrors if
+// fields do not match the schema
+func ParseYamlConfig(data []byte) (*YamlConfig, error) {
+
+	var doc interface{}
+	if err := yamlv2.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	checker := &schemaChecker{lines: strings.Split(string(data), "\n")}
+	checker.check(reflect.TypeOf(YamlConfig{}), doc, nil)
+	if len(checker.errs) != 0 {
+		sort.SliceStable(checker.errs, func(i, j int) bool { return checker.errs[i].Line < checker.errs[j].Line })
+		return nil, checker.errs
+	}
+
+	cfg := &YamlConfig{}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// schemaChecker collects the errors of the fields that do not match the schema
+type schemaChecker struct {
+	lines []string
+	errs  SchemaErrors
+}
+
+// a path element is a field name or a list index
+type schemaPath []interface{}
+
+func (path schemaPath) String() string {
+	var s string
+	for _, elem := range path {
+		switch e := elem.(type) {
+		case int:
+			s += fmt.Sprintf("[%d]", e)
+		default:
+			if s != "" {
+				s += "."
+			}
+			s += fmt.Sprint(e)
+		}
+	}
+	return s
+}
+
+// extend returns a copy of the path with the element added, the path's array is shared otherwise
+func (path schemaPath) extend(elem interface{}) schemaPath {
+	return append(append(schemaPath{}, path...), elem)
+}
+
+func (c *schemaChecker) fail(path schemaPath, format string, args ...interface{}) {
+	c.errs = append(c.errs, &SchemaError{
+		Line:    yamlLine(c.lines, path),
+		Field:   path.String(),
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// check checks the value decoded from the file against the type it is decoded into
+func (c *schemaChecker) check(t reflect.Type, value interface{}, path schemaPath) {
+
+	if value == nil {
+		return // null leaves the field unset
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[interface{}]interface{})
+		if !ok {
+			c.fail(path, "expected a map of fields, got: %s", yamlKind(value))
+			return
+		}
+		fields := jsonFields(t)
+		keys, values := yamlMap(m)
+		for _, key := range keys {
+			field, exists := lookupJSONField(fields, key)
+			if !exists {
+				c.fail(path.extend(key), "unknown field%s", didYouMean(key, fields))
+				continue
+			}
+			c.checkField(field, values[key], path.extend(key))
+		}
+
+	case reflect.Map:
+		m, ok := value.(map[interface{}]interface{})
+		if !ok {
+			c.fail(path, "expected a map, got: %s", yamlKind(value))
+			return
+		}
+		keys, values := yamlMap(m)
+		for _, key := range keys {
+			c.check(t.Elem(), values[key], path.extend(key))
+		}
+
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			c.checkScalar(t, value, path) // bytes are a base64 string
+			return
+		}
+		list, ok := value.([]interface{})
+		if !ok {
+			c.fail(path, "expected a list, got: %s", yamlKind(value))
+			return
+		}
+		for i, elem := range list {
+			c.check(t.Elem(), elem, path.extend(i))
+		}
+
+	default:
+		c.checkScalar(t, value, path)
+	}
+}
+
+// checkField checks the value of a struct field, the values of a proto enum field must be defined by the enum
+func (c *schemaChecker) checkField(field reflect.StructField, value interface{}, path schemaPath) {
+
+	c.check(field.Type, value, path)
+
+	enumName := protoEnumName(field)
+	if enumName == "" {
+		return
+	}
+	values := proto.EnumValueMap(enumName)
+	if values == nil {
+		return
+	}
+	check := func(v interface{}, path schemaPath) {
+		n, ok := yamlInt(v)
+		if !ok {
+			return // the type error is reported already
+		}
+		for _, defined := range values {
+			if int64(defined) == n {
+				return
+			}
+		}
+		c.fail(path, "invalid value: %d for %s, expected one of: %s", n, enumName, enumValues(values))
+	}
+	if list, ok := value.([]interface{}); ok {
+		for i, v := range list {
+			check(v, path.extend(i))
+		}
+		return
+	}
+	check(value, path)
+}
+
+// checkScalar checks a value decoded into a string, bool or number, a string takes any scalar, as the yaml
+// decoder converts it
+func (c *schemaChecker) checkScalar(t reflect.Type, value interface{}, path schemaPath) {
+
+	switch t.Kind() {
+	case reflect.String, reflect.Slice:
+		switch value.(type) {
+		case string, int, int64, uint64, float64, bool:
+			return
+		}
+		c.fail(path, "expected a string, got: %s", yamlKind(value))
+
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			c.fail(path, "expected true or false, got: %s", yamlKind(value))
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := yamlInt(value)
+		if !ok {
+			c.fail(path, "expected an integer, got: %s", yamlKind(value))
+		} else if reflect.New(t).Elem().OverflowInt(n) {
+			c.fail(path, "value: %d is out of range for %s", n, t.Kind())
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u, ok := value.(uint64); ok {
+			if reflect.New(t).Elem().OverflowUint(u) {
+				c.fail(path, "value: %d is out of range for %s", u, t.Kind())
+			}
+			return
+		}
+		n, ok := yamlInt(value)
+		if !ok {
+			c.fail(path, "expected an unsigned integer, got: %s", yamlKind(value))
+		} else if n < 0 {
+			c.fail(path, "value: %d is negative, expected an unsigned integer", n)
+		} else if reflect.New(t).Elem().OverflowUint(uint64(n)) {
+			c.fail(path, "value: %d is out of range for %s", n, t.Kind())
+		}
+
+	case reflect.Float32, reflect.Float64:
+		if _, ok := yamlInt(value); ok {
+			return
+		}
+		if _, ok := value.(float64); !ok {
+			c.fail(path, "expected a number, got: %s", yamlKind(value))
+		}
+	}
+}
+
+// jsonFields returns the struct fields by their json name, the fields the json decoder skips are left out
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || strings.HasPrefix(field.Name, "XXX_") {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			if field.Anonymous && field.Type.Kind() == reflect.Struct {
+				for embeddedName, embedded := range jsonFields(field.Type) {
+					fields[embeddedName] = embedded
+				}
+				continue
+			}
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// lookupJSONField finds the field of the key like the json decoder does, the exact name first, then ignoring
+// the case
+func lookupJSONField(fields map[string]reflect.StructField, key string) (reflect.StructField, bool) {
+
+	if field, exists := fields[key]; exists {
+		return field, true
+	}
+	for name, field := range fields {
+		if strings.EqualFold(name, key) {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// protoEnumName returns the name of the proto enum of the field, ie controller.SfcType, "" if it is not one
+func protoEnumName(field reflect.StructField) string {
+	for _, part := range strings.Split(field.Tag.Get("protobuf"), ",") {
+		if strings.HasPrefix(part, "enum=") {
+			return strings.TrimPrefix(part, "enum=")
+		}
+	}
+	return ""
+}
+
+// enumValues returns the values of the enum with their names, in value order
+func enumValues(values map[string]int32) string {
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return values[names[i]] < values[names[j]] })
+
+	parts := make([]string, 0, len(names))
+	for _, name := range names {
+		parts = append(parts, fmt.Sprintf("%d (%s)", values[name], name))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// didYouMean suggests the field whose name is closest to the unknown key, if one is close enough to be a typo
+func didYouMean(key string, fields map[string]reflect.StructField) string {
+
+	best, bestDistance := "", len(key)/3+1
+	for name := range fields {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(name)); d < bestDistance ||
+			(d == bestDistance && best != "" && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(", did you mean: '%s'?", best)
+}
+
+// editDistance returns the levenshtein distance of the strings
+func editDistance(a string, b string) int {
+
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// yamlInt returns the integer the yaml decoder decoded, false if the value is not one
+func yamlInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint64:
+		return int64(v), v <= 1<<63-1
+	}
+	return 0, false
+}
+
+// yamlKind names the kind of the decoded value for the errors
+func yamlKind(value interface{}) string {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		return "a map"
+	case []interface{}:
+		return "a list"
+	case string:
+		return fmt.Sprintf("the string: '%s'", v)
+	case bool:
+		return fmt.Sprintf("the bool: %t", v)
+	}
+	return fmt.Sprintf("the number: %v", value)
+}
+
+// yamlMap returns the sorted keys of the decoded map as strings, and its values by them
+func yamlMap(m map[interface{}]interface{}) ([]string, map[string]interface{}) {
+
+	values := make(map[string]interface{}, len(m))
+	keys := make([]string, 0, len(m))
+	for key, value := range m {
+		keyString := fmt.Sprint(key)
+		values[keyString] = value
+		keys = append(keys, keyString)
+	}
+	sort.Strings(keys)
+	return keys, values
+}
+
+// yamlLine returns the line of the field at the path, or of the deepest part of the path that was found, in
+// block style yaml or indented json, 0 if none was found
+func yamlLine(lines []string, path schemaPath) int {
+
+	found, from, parentIndent := 0, 0, -1
+	for _, elem := range path {
+		var line int
+		if index, isIndex := elem.(int); isIndex {
+			line, parentIndent = yamlListItemLine(lines, from, parentIndent, index)
+		} else {
+			line, parentIndent = yamlKeyLine(lines, from, parentIndent, fmt.Sprint(elem))
+		}
+		if line < 0 {
+			break
+		}
+		found, from = line+1, line
+	}
+	return found
+}
+
+// yamlKeyLine returns the line of the key in the block of the parent that starts at from, with the indent of
+// the key, -1 if the block has no such key.  A list item's first key is on the line of its dash, so the
+// parent's own line is searched too
+func yamlKeyLine(lines []string, from int, parentIndent int, key string) (int, int) {
+
+	childIndent := -1
+	for i := from; i < len(lines); i++ {
+		indent, lineKey, dashIndent := yamlLineKey(lines[i])
+		if indent < 0 {
+			continue
+		}
+		if i > from && (indent <= parentIndent || (dashIndent >= 0 && dashIndent <= parentIndent)) {
+			return -1, parentIndent // the parent's block ended
+		}
+		if indent <= parentIndent || lineKey == "" {
+			continue
+		}
+		if childIndent < 0 {
+			childIndent = indent
+		}
+		if indent == childIndent && strings.EqualFold(lineKey, key) {
+			return i, indent
+		}
+	}
+	return -1, parentIndent
+}
+
+// yamlListItemLine returns the line of the dash of the list's n'th item, the list is the value of the key on
+// the line from, with the indent of the dash, -1 if the list has no such item
+func yamlListItemLine(lines []string, from int, parentIndent int, n int) (int, int) {
+
+	listIndent, count := -1, 0
+	for i := from + 1; i < len(lines); i++ {
+		indent, _, dashIndent := yamlLineKey(lines[i])
+		if indent < 0 {
+			continue
+		}
+		if dashIndent < 0 {
+			if indent <= parentIndent {
+				return -1, parentIndent // the list ended
+			}
+			continue
+		}
+		if listIndent < 0 {
+			if dashIndent < parentIndent {
+				return -1, parentIndent
+			}
+			listIndent = dashIndent
+		}
+		if dashIndent < listIndent {
+			return -1, parentIndent
+		}
+		if dashIndent == listIndent {
+			if count == n {
+				return i, dashIndent
+			}
+			count++
+		}
+	}
+	return -1, parentIndent
+}
+
+// yamlLineKey returns the indent of the line's content past any list dash, the key the line starts with, ""
+// if none, and the indent of the dash, -1 if none.  The indent is -1 for blank and comment lines
+func yamlLineKey(line string) (indent int, key string, dashIndent int) {
+
+	dashIndent = -1
+	rest := strings.TrimRight(line, " \t\r")
+	content := strings.TrimLeft(rest, " ")
+	if content == "" || strings.HasPrefix(content, "#") || content == "---" {
+		return -1, "", -1
+	}
+	indent = len(rest) - len(content)
+	if content == "-" || strings.HasPrefix(content, "- ") {
+		dashIndent = indent
+		trimmed := strings.TrimLeft(content[1:], " ")
+		indent += len(content) - len(trimmed)
+		content = trimmed
+	}
+
+	if strings.HasPrefix(content, "\"") {
+		if end := strings.Index(content[1:], "\""); end >= 0 && strings.HasPrefix(content[end+2:], ":") {
+			return indent, content[1 : end+1], dashIndent
+		}
+		return indent, "", dashIndent
+	}
+	if colon := strings.Index(content, ":"); colon > 0 &&
+		(colon == len(content)-1 || content[colon+1] == ' ') {
+		return indent, strings.TrimSpace(content[:colon]), dashIndent
+	}
+	return indent, "", dashIndent
+}
@@ -0,0 +1,409 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Every time config is successfully applied, a snapshot of the whole ram
+// cache is stored as a numbered version in etcd.  A rollback loads an older
+// snapshot into the ram cache and re-renders it inside a reconcile so only
+// the vpp-agent entries that differ from the running config are touched.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/cnpdriver"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+const defaultConfigVersionsRetained = 20
+
+// ConfigVersionDiff lists the entities, as <type>/<name>, that differ between two versions
+type ConfigVersionDiff struct {
+	FromVersion uint32   `json:"from_version"`
+	ToVersion   uint32   `json:"to_version"`
+	Added       []string `json:"added,omitempty"`
+	Removed     []string `json:"removed,omitempty"`
+	Changed     []string `json:"changed,omitempty"`
+}
+
+// configVersionInitFromDatastore remembers the latest stored version so new snapshots continue from it
+func (sfcCtrlPlugin *SfcControllerPluginHandler) configVersionInitFromDatastore() error {
+
+	sfcCtrlPlugin.configVersion = 0
+
+	err := sfcCtrlPlugin.DatastoreConfigVersionIterate(func(cv *controller.ConfigVersion) {
+		if cv.Version > sfcCtrlPlugin.configVersion {
+			sfcCtrlPlugin.configVersion = cv.Version
+		}
+	})
+	sfcCtrlPlugin.cnpDriverPlugin.SetConfigVersion(sfcCtrlPlugin.configVersion)
+	return err
+}
+
+// snapshotConfigVersion stores the ram cache as the next config version, then prunes the oldest versions
+func (sfcCtrlPlugin *SfcControllerPluginHandler) snapshotConfigVersion(description string) error {
+
+	cv := sfcCtrlPlugin.ramCacheToConfigVersion()
+	cv.Version = sfcCtrlPlugin.configVersion + 1
+	cv.Timestamp = time.Now().Unix()
+	cv.Description = description
+
+	if err := sfcCtrlPlugin.DatastoreConfigVersionCreate(cv); err != nil {
+		return err
+	}
+	sfcCtrlPlugin.configVersion = cv.Version
+	sfcCtrlPlugin.cnpDriverPlugin.SetConfigVersion(cv.Version)
+
+	retained := sfcCtrlPlugin.ramConfigCache.SysParms.ConfigVersionsRetained
+	if retained == 0 {
+		retained = defaultConfigVersionsRetained
+	}
+	if cv.Version > retained {
+		oldest := cv.Version - retained
+		sfcCtrlPlugin.DatastoreConfigVersionIterate(func(old *controller.ConfigVersion) {
+			if old.Version <= oldest {
+				sfcCtrlPlugin.DatastoreConfigVersionDelete(old.Version)
+			}
+		})
+	}
+
+	return nil
+}
+
+// ramCacheToConfigVersion copies the ram cache into a version, entities are sorted by name so versions
+// of the same config are identical
+func (sfcCtrlPlugin *SfcControllerPluginHandler) ramCacheToConfigVersion() *controller.ConfigVersion {
+
+	sp := sfcCtrlPlugin.ramConfigCache.SysParms
+	cv := &controller.ConfigVersion{
+		SystemParameters: &sp,
+	}
+
+	for _, name := range sortedKeysEE(sfcCtrlPlugin.ramConfigCache.EEs) {
+		ee := sfcCtrlPlugin.ramConfigCache.EEs[name]
+		cv.ExternalEntities = append(cv.ExternalEntities, &ee)
+	}
+	for _, name := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		he := sfcCtrlPlugin.ramConfigCache.HEs[name]
+		cv.HostEntities = append(cv.HostEntities, &he)
+	}
+	for _, name := range sortedKeysSFC(sfcCtrlPlugin.ramConfigCache.SFCs) {
+		sfc := sfcCtrlPlugin.ramConfigCache.SFCs[name]
+		cv.SfcEntities = append(cv.SfcEntities, &sfc)
+	}
+	for _, name := range sortedKeysNS(sfcCtrlPlugin.ramConfigCache.NSs) {
+		ns := sfcCtrlPlugin.ramConfigCache.NSs[name]
+		cv.NetworkServices = append(cv.NetworkServices, &ns)
+	}
+	for _, name := range sortedKeysTPL(sfcCtrlPlugin.ramConfigCache.TPLs) {
+		tpl := sfcCtrlPlugin.ramConfigCache.TPLs[name]
+		cv.SfcTemplates = append(cv.SfcTemplates, &tpl)
+	}
+	for _, name := range sortedKeysWP(sfcCtrlPlugin.ramConfigCache.WPs) {
+		wp := sfcCtrlPlugin.ramConfigCache.WPs[name]
+		cv.WiringPolicies = append(cv.WiringPolicies, &wp)
+	}
+
+	return cv
+}
+
+// configVersionToRAMCache replaces the contents of the ram cache with the version
+func (sfcCtrlPlugin *SfcControllerPluginHandler) configVersionToRAMCache(cv *controller.ConfigVersion) {
+
+	sfcCtrlPlugin.InitRAMCache()
+
+	if cv.SystemParameters != nil {
+		sfcCtrlPlugin.ramConfigCache.SysParms = *cv.SystemParameters
+	} else {
+		sfcCtrlPlugin.ramConfigCache.SysParms = controller.SystemParameters{}
+	}
+	for _, ee := range cv.GetExternalEntities() {
+		sfcCtrlPlugin.ramConfigCache.EEs[ee.Name] = *ee
+	}
+	for _, he := range cv.GetHostEntities() {
+		sfcCtrlPlugin.ramConfigCache.HEs[he.Name] = *he
+	}
+	for _, sfc := range cv.GetSfcEntities() {
+		sfcCtrlPlugin.ramConfigCache.SFCs[sfc.Name] = *sfc
+	}
+	for _, ns := range cv.GetNetworkServices() {
+		sfcCtrlPlugin.ramConfigCache.NSs[ns.Name] = *ns
+	}
+	for _, tpl := range cv.GetSfcTemplates() {
+		sfcCtrlPlugin.ramConfigCache.TPLs[tpl.Name] = *tpl
+	}
+	for _, wp := range cv.GetWiringPolicies() {
+		sfcCtrlPlugin.ramConfigCache.WPs[wp.Name] = *wp
+	}
+}
+
+// diffConfigVersions compares two versions entity by entity
+func diffConfigVersions(from *controller.ConfigVersion, to *controller.ConfigVersion) *ConfigVersionDiff {
+
+	diff := &ConfigVersionDiff{
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+	}
+
+	fromEntities := configVersionEntityStrings(from)
+	toEntities := configVersionEntityStrings(to)
+
+	for key, toStr := range toEntities {
+		if fromStr, exists := fromEntities[key]; !exists {
+			diff.Added = append(diff.Added, key)
+		} else if fromStr != toStr {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for key := range fromEntities {
+		if _, exists := toEntities[key]; !exists {
+			diff.Removed = append(diff.Removed, key)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+
+	return diff
+}
+
+func configVersionEntityStrings(cv *controller.ConfigVersion) map[string]string {
+
+	entities := make(map[string]string)
+
+	entities["SP"] = cv.GetSystemParameters().String()
+	for _, ee := range cv.GetExternalEntities() {
+		entities["EE/"+ee.Name] = ee.String()
+	}
+	for _, he := range cv.GetHostEntities() {
+		entities["HE/"+he.Name] = he.String()
+	}
+	for _, sfc := range cv.GetSfcEntities() {
+		entities["SFC/"+sfc.Name] = sfc.String()
+	}
+	for _, wp := range cv.GetWiringPolicies() {
+		entities["WP/"+wp.Name] = wp.String()
+	}
+
+	return entities
+}
+
+// rollbackToConfigVersion re-renders a stored version inside a reconcile, the reconcile removes the
+// agent config that the version does not produce and leaves the rest untouched, the rollback itself
+// is recorded as a new version.  If a canary host fails verification, the running version is kept.
+func (sfcCtrlPlugin *SfcControllerPluginHandler) rollbackToConfigVersion(version uint32) (*ConfigVersionDiff, error) {
+
+	target, err := sfcCtrlPlugin.DatastoreConfigVersionRetrieve(version)
+	if err != nil {
+		return nil, err
+	}
+
+	current := sfcCtrlPlugin.ramCacheToConfigVersion()
+	current.Version = sfcCtrlPlugin.configVersion
+	diff := diffConfigVersions(current, target)
+
+	log.Infof("rollbackToConfigVersion: from version: %d to version: %d, diff: %v",
+		current.Version, version, diff)
+
+	if err := sfcCtrlPlugin.validateConfigVersionDeletes(diff); err != nil {
+		return nil, fmt.Errorf("rollbackToConfigVersion: version %d is not valid: %s", version, err)
+	}
+
+	sfcCtrlPlugin.configVersionToRAMCache(target)
+	if err := sfcCtrlPlugin.validateRAMCache(); err != nil {
+		// put back what was running, nothing has been rendered yet
+		sfcCtrlPlugin.configVersionToRAMCache(current)
+		return nil, fmt.Errorf("rollbackToConfigVersion: version %d is not valid: %s", version, err)
+	}
+
+	sfcCtrlPlugin.evictRemovedEntities(current, target)
+
+	sfcCtrlPlugin.ReconcileStart()
+
+	sfcCtrlPlugin.DatastoreReInitialize()
+	if err := sfcCtrlPlugin.WriteRAMCacheToEtcd(); err != nil {
+		sfcCtrlPlugin.ReconcileEnd()
+		return nil, err
+	}
+
+	if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+		sfcCtrlPlugin.ReconcileEnd()
+		return nil, err
+	}
+
+	if err := sfcCtrlPlugin.ReconcileEndCanary(); err != nil {
+		// the canary has been put back and no other host was touched, put back what was running
+		sfcCtrlPlugin.configVersionToRAMCache(current)
+		sfcCtrlPlugin.evictRemovedEntities(target, current)
+		sfcCtrlPlugin.ReconcileStart()
+		sfcCtrlPlugin.DatastoreReInitialize()
+		if err := sfcCtrlPlugin.WriteRAMCacheToEtcd(); err != nil {
+			log.Errorf("rollbackToConfigVersion: error restoring version %d: %s", current.Version, err)
+		}
+		if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+			log.Errorf("rollbackToConfigVersion: error re-rendering version %d: %s", current.Version, err)
+		}
+		sfcCtrlPlugin.ReconcileEnd()
+		return diff, err
+	}
+
+	sfcCtrlPlugin.recordConfigVersionChanges(current, target, fmt.Sprintf("rollback to version %d", version))
+
+	if err := sfcCtrlPlugin.snapshotConfigVersion(fmt.Sprintf("rollback to version %d", version)); err != nil {
+		return diff, err
+	}
+
+	return diff, nil
+}
+
+// validateConfigVersionDeletes checks the drivers of the chains the diff removes can remove their wiring
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateConfigVersionDeletes(diff *ConfigVersionDiff) error {
+
+	for _, entity := range diff.Removed {
+		if !strings.HasPrefix(entity, controller.SfcEntityKind+"/") {
+			continue
+		}
+		sfc, exists := sfcCtrlPlugin.ramConfigCache.SFCs[strings.TrimPrefix(entity, controller.SfcEntityKind+"/")]
+		if !exists {
+			continue
+		}
+		if err := cnpdriver.ValidateSfcEntityDelete(cnpDriverName, &sfc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DatastoreConfigVersionCreate creates the specified version in the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreConfigVersionCreate(cv *controller.ConfigVersion) error {
+
+	key := controller.ConfigVersionKey(cv.Version)
+
+	log.Infof("DatastoreConfigVersionCreate: setting key: '%s'", key)
+
+	err := sfcCtrlPlugin.db.Put(key, cv)
+	if err != nil {
+		log.Error("DatastoreConfigVersionCreate: databroker put: ", err)
+		return err
+	}
+	return nil
+}
+
+// DatastoreConfigVersionRetrieve gets the specified version from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreConfigVersionRetrieve(version uint32) (*controller.ConfigVersion, error) {
+
+	key := controller.ConfigVersionKey(version)
+	cv := &controller.ConfigVersion{}
+	found, _, err := sfcCtrlPlugin.db.GetValue(key, cv)
+	if err != nil {
+		log.Error("DatastoreConfigVersionRetrieve: databroker get: ", err)
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("config version not found: %d", version)
+	}
+	return cv, nil
+}
+
+// DatastoreConfigVersionDelete removes the specified version from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreConfigVersionDelete(version uint32) error {
+
+	key := controller.ConfigVersionKey(version)
+	log.Infof("DatastoreConfigVersionDelete: deleting version: '%s'", key)
+	_, err := sfcCtrlPlugin.db.Delete(key)
+
+	return err
+}
+
+// DatastoreConfigVersionIterate iterates over the stored versions in the sfc tree in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreConfigVersionIterate(
+	actionFunc func(cv *controller.ConfigVersion)) error {
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(controller.ConfigVersionKeyPrefix())
+	if err != nil {
+		log.Error("DatastoreConfigVersionIterate: databroker list: ", err)
+		return err
+	}
+
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return nil
+		}
+		cv := &controller.ConfigVersion{}
+		if err := kv.GetValue(cv); err != nil {
+			log.Error("DatastoreConfigVersionIterate: bad version: ", kv.GetKey(), err)
+			continue
+		}
+		actionFunc(cv)
+	}
+}
+
+func sortedKeysEE(m map[string]controller.ExternalEntity) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeysHE(m map[string]controller.HostEntity) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeysNS(m map[string]controller.NetworkService) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeysTPL(m map[string]controller.SfcTemplate) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeysWP(m map[string]controller.WiringPolicy) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedKeysSFC(m map[string]controller.SfcEntity) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -19,21 +19,34 @@
 package core
 
 import (
+	"encoding/json"
+	"io/ioutil"
 	"os"
 	"sync"
+	"time"
 
+	"github.com/coreos/etcd/clientv3"
 	"github.com/ligato/cn-infra/core"
 	"github.com/ligato/cn-infra/db/keyval"
 	"github.com/ligato/cn-infra/db/keyval/etcdv3"
 	"github.com/ligato/cn-infra/flavors/local"
 	"github.com/ligato/cn-infra/health/statuscheck"
 	"github.com/ligato/cn-infra/logging"
-	"github.com/ligato/cn-infra/logging/logrus"
 	"github.com/ligato/cn-infra/rpc/rest"
 	"github.com/ligato/cn-infra/utils/safeclose"
 	"github.com/ligato/sfc-controller/controller/cnpdriver"
 	"github.com/ligato/sfc-controller/controller/extentitydriver"
+	"github.com/ligato/sfc-controller/controller/gnmi"
 	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/alarms"
+	"github.com/ligato/sfc-controller/controller/utils/eventbus"
+	"github.com/ligato/sfc-controller/controller/utils/eventlog"
+	"github.com/ligato/sfc-controller/controller/utils/faults"
+	"github.com/ligato/sfc-controller/controller/utils/hooks"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
+	"github.com/ligato/sfc-controller/controller/utils/mirror"
+	"github.com/ligato/sfc-controller/controller/utils/shadow"
+	"github.com/ligato/sfc-controller/controller/utils/shards"
 	"github.com/namsral/flag"
 )
 
@@ -41,20 +54,68 @@ import (
 const PluginID core.PluginName = "SfcController"
 
 var (
-	cnpDriverName     string // cli flag - see RegisterFlags
-	sfcConfigFile     string // cli flag - see RegisterFlags
-	cleanSfcDatastore bool   // cli flag - see RegisterFlags
-	log               = logrus.DefaultLogger()
+	cnpDriverName     string        // cli flag - see RegisterFlags
+	sfcConfigFile     string        // cli flag - see RegisterFlags
+	cleanSfcDatastore bool          // cli flag - see RegisterFlags
+	restoreFile       string        // cli flag - see RegisterFlags
+	logFormat         string        // cli flag - see RegisterFlags
+	environment       string        // cli flag - see RegisterFlags
+	faultsFile        string        // cli flag - see RegisterFlags
+	shardIndex        uint          // cli flag - see RegisterFlags
+	eventBusURL       string        // cli flag - see RegisterFlags
+	eventTopicPrefix  string        // cli flag - see RegisterFlags
+	eventLogSize      int           // cli flag - see RegisterFlags
+	eventLogRetention time.Duration // cli flag - see RegisterFlags
+	gnmiAddress       string        // cli flag - see RegisterFlags
+	alarmTargets      string        // cli flag - see RegisterFlags
+	shadowMode        bool          // cli flag - see RegisterFlags
+	shutdownPolicy    string        // cli flag - see RegisterFlags
+	shutdownTenants   string        // cli flag - see RegisterFlags
+	mirrorEtcdConfig  string        // cli flag - see RegisterFlags
+	renderHooks       string        // cli flag - see RegisterFlags
+	log               = logs.Logger(logs.Core)
 )
 
 // RegisterFlags add command line flags.
 func RegisterFlags() {
 	flag.StringVar(&cnpDriverName, "cnp-driver", "sfcctlrl2",
-		"Container Networking Policy driver: sfcctlrl2, sfcctlrl3")
+		"Container Networking Policy driver: sfcctlrl2, sfcctlrl3, sfcctlrxconn, sfcctlracl")
 	flag.StringVar(&sfcConfigFile, "sfc-config", "",
 		"Name of a sfc config (yaml) file to load at startup")
 	flag.BoolVar(&cleanSfcDatastore, "clean", false,
 		"Clean the SFC datastore entries")
+	flag.StringVar(&restoreFile, "restore", "",
+		"Name of a backup archive to restore the SFC datastore from at startup")
+	flag.StringVar(&logFormat, "log-format", logs.TextFormat,
+		"Format of the SFC controller logs: text, json")
+	flag.StringVar(&environment, "environment", "",
+		"Name of the environment, ie lab or prod, whose sfc overrides are applied at render time")
+	flag.StringVar(&faultsFile, "faults", "",
+		"Name of a fault injection (json) file, for resilience testing only")
+	flag.UintVar(&shardIndex, "shard", 0,
+		"Shard of hosts this controller renders, when the system parameters set a shard_count")
+	flag.StringVar(&eventBusURL, "event-bus", "",
+		"Message bus to stream the controller's events to: nats://host:port, kafka+http://rest-proxy:port")
+	flag.StringVar(&eventTopicPrefix, "event-topic-prefix", "sfc-controller",
+		"Prefix of the topics the events are published to, ie sfc-controller.wiring")
+	flag.IntVar(&eventLogSize, "event-log-size", eventlog.DefaultMaxEvents,
+		"Number of events kept in the event log at /sfc-controller/v1/status/events")
+	flag.DurationVar(&eventLogRetention, "event-log-retention", eventlog.DefaultRetention,
+		"How long the events are kept in the event log, ie 24h")
+	flag.StringVar(&gnmiAddress, "gnmi-address", "",
+		"Address to serve the controller state on over gNMI Subscribe, ie :9339")
+	flag.StringVar(&alarmTargets, "alarm-targets", "",
+		"Comma separated syslog and SNMP trap targets of the critical alarms: syslog://host:514, snmp://community@host:162")
+	flag.BoolVar(&shadowMode, "shadow", false,
+		"Render without writing to the agents, the withheld writes are published at /sfc-controller/v1/shadow")
+	flag.StringVar(&shutdownPolicy, "shutdown-policy", shutdownPolicyRetain,
+		"What is done with the rendered config on shutdown: retain, teardown, teardown-tenants")
+	flag.StringVar(&shutdownTenants, "shutdown-tenants", "",
+		"Comma separated tenants whose chains are torn down on shutdown, with -shutdown-policy teardown-tenants")
+	flag.StringVar(&mirrorEtcdConfig, "mirror-etcd-config", "",
+		"Name of the etcd config (yaml) file of a standby site's cluster the datastore is mirrored to")
+	flag.StringVar(&renderHooks, "render-hooks", "",
+		"Comma separated hooks the writes to the agents go through: Go plugin paths, http(s) webhook urls")
 }
 
 // LogFlags dumps the command line flags
@@ -62,6 +123,22 @@ func LogFlags() {
 	log.Debugf("LogFlags:")
 	log.Debugf("\tcnpDriver:'%s'", cnpDriverName)
 	log.Debugf("\tsfcConfigFile:'%s'", sfcConfigFile)
+	log.Debugf("\trestoreFile:'%s'", restoreFile)
+	log.Debugf("\tlogFormat:'%s'", logFormat)
+	log.Debugf("\tenvironment:'%s'", environment)
+	log.Debugf("\tfaultsFile:'%s'", faultsFile)
+	log.Debugf("\tshard:'%d'", shardIndex)
+	log.Debugf("\teventBus:'%s'", eventBusURL)
+	log.Debugf("\teventTopicPrefix:'%s'", eventTopicPrefix)
+	log.Debugf("\teventLogSize:'%d'", eventLogSize)
+	log.Debugf("\teventLogRetention:'%s'", eventLogRetention)
+	log.Debugf("\tgnmiAddress:'%s'", gnmiAddress)
+	log.Debugf("\talarmTargets:'%s'", alarmTargets)
+	log.Debugf("\tshadow:'%t'", shadowMode)
+	log.Debugf("\tshutdownPolicy:'%s'", shutdownPolicy)
+	log.Debugf("\tshutdownTenants:'%s'", shutdownTenants)
+	log.Debugf("\tmirrorEtcdConfig:'%s'", mirrorEtcdConfig)
+	log.Debugf("\trenderHooks:'%s'", renderHooks)
 }
 
 // Init is the Go init() function for the sfcCtrlPlugin. It should
@@ -81,6 +158,9 @@ type SfcControllerCacheType struct {
 	EEs      map[string]controller.ExternalEntity
 	HEs      map[string]controller.HostEntity
 	SFCs     map[string]controller.SfcEntity
+	NSs      map[string]controller.NetworkService // the services the SFCs expanded from are kept for GET
+	TPLs     map[string]controller.SfcTemplate
+	WPs      map[string]controller.WiringPolicy
 	SysParms controller.SystemParameters
 }
 
@@ -89,13 +169,34 @@ type SfcControllerPluginHandler struct {
 	Etcd    *etcdv3.Plugin
 	HTTPmux *rest.Plugin
 	*local.FlavorLocal
-	HttpMutex             sync.Mutex
+	HttpMutex             sync.Mutex // held by each REST request and background loop while it uses the caches
 	cnpDriverPlugin       cnpdriver.SfcControllerCNPDriverAPI
 	yamlConfig            *YamlConfig
 	ramConfigCache        SfcControllerCacheType
 	controllerReady       bool
 	db                    keyval.ProtoBroker
 	ReconcileVppLabelsMap ReconcileVppLabelsMapType
+	configVersion         uint32                                 // latest config version stored in etcd
+	entityRenders         map[string]*entityRenderType           // render statuses not yet written, see status.go
+	sfcRenderStates       map[string]controller.RenderStateType  // how each sfc rendered, see sfc_order.go
+	agentWatchDone        chan struct{}                          // closed to stop the agent liveness watcher
+	renderRetries         map[string]*controller.RenderRetry     // failed renders by retry key, see retry.go
+	renderRetryKey        string                                 // the retry being rendered
+	renderRetryDone       chan struct{}                          // closed to stop the render retry loop
+	agentBreakers         map[string]int64                       // host -> unix time its agent went down
+	scheduledChanges      map[string]*controller.ScheduledChange // changes by name, see scheduled.go
+	scheduledChangeDone   chan struct{}                          // closed to stop the scheduled change loop
+	federationSyncDone    chan struct{}                          // closed to stop the federation sync loop
+	backgroundLoops       sync.WaitGroup                         // the loops above, Close waits for them to return
+	eventBus              *eventbus.Bus                          // nil unless -event-bus is set, see events.go
+	eventLog              *eventlog.Log                          // the recent events, see events.go
+	gnmiServer            *gnmi.Server                           // nil unless -gnmi-address is set, see gnmi.go
+	alarmNotifier         *alarms.Notifier                       // nil unless -alarm-targets is set, see alarms.go
+	shadowJournal         *shadow.Journal                        // nil unless -shadow is set
+	mirror                *mirror.Mirror                         // nil unless -mirror-etcd-config is set, see mirror.go
+	etcdTxnClient         *clientv3.Client                       // the etcd client of the txns, see etcd_txn.go
+	dbFactory             func(string) keyval.ProtoBroker        // the brokers of the cnp driver, see driver_reload.go
+	convergence           convergenceTracker                     // submission to render times, see slo.go
 }
 
 // Init the controller, read the db, reconcile/resync, render config to etcd
@@ -105,24 +206,87 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) Init() error {
 	sfcCtrlPlugin.StatusCheck.Register(PluginID, nil)
 	sfcCtrlPlugin.StatusCheck.ReportStateChange(PluginID, statuscheck.Init, nil)
 
-	sfcCtrlPlugin.db = sfcCtrlPlugin.Etcd.NewBroker(keyval.Root)
+	etcdFactory, err := sfcCtrlPlugin.initEtcdTxns(func(prefix string) keyval.ProtoBroker {
+		return sfcCtrlPlugin.Etcd.NewBroker(prefix)
+	})
+	if err != nil {
+		log.Error("error connecting the etcd client of the txns: ", err)
+		os.Exit(1)
+	}
+	if mirrorEtcdConfig != "" {
+		if err := sfcCtrlPlugin.initMirror(mirrorEtcdConfig, etcdFactory); err != nil {
+			log.Error("error initializing the datastore mirror: ", err)
+			os.Exit(1)
+		}
+		etcdFactory = sfcCtrlPlugin.mirror.BrokerFactory()
+	}
+
+	// the datastore faults are only injected once a fault config is set, see -faults
+	dbFactory := faults.WrapBrokerFactory(etcdFactory)
+	dbFactory = shards.WrapBrokerFactory(dbFactory, sfcCtrlPlugin.agentWritable)
+	if renderHooks != "" {
+		chain, err := hooks.ChainFromTargets(renderHooks)
+		if err != nil {
+			log.Error("error loading the render hooks: ", err)
+			os.Exit(1)
+		}
+		log.Infof("render hooks: %v", chain.Names())
+		dbFactory = chain.WrapBrokerFactory(dbFactory)
+	}
+	if shadowMode {
+		log.Info("shadow mode: the writes to the agents are withheld")
+		sfcCtrlPlugin.shadowJournal = shadow.NewJournal()
+		dbFactory = sfcCtrlPlugin.shadowJournal.WrapBrokerFactory(dbFactory)
+	}
+	sfcCtrlPlugin.db = dbFactory(keyval.Root)
+	sfcCtrlPlugin.dbFactory = dbFactory
 
 	sfcCtrlPlugin.InitRAMCache()
 
 	extentitydriver.SfcExternalEntityDriverInit()
 
-	var err error
-
 	log.Infof("Initializing sfcCtrlPlugin '%s'", PluginID)
 
 	// Flag variables registered in init() are ready to use in InitPlugin()
 	LogFlags()
 
+	if err := logs.SetFormat(logFormat); err != nil {
+		log.Error("error setting log format: ", err)
+		os.Exit(1)
+	}
+
+	sfcCtrlPlugin.initEventLog(eventLogSize, eventLogRetention)
+
 	// register northbound controller API's
 	sfcCtrlPlugin.InitHTTPHandlers()
 
-	sfcCtrlPlugin.cnpDriverPlugin, err = cnpdriver.RegisterCNPDriverPlugin(cnpDriverName,
-		func(prefix string) keyval.ProtoBroker { return sfcCtrlPlugin.Etcd.NewBroker(prefix) })
+	if eventBusURL != "" {
+		if err := sfcCtrlPlugin.initEventBus(eventBusURL, eventTopicPrefix); err != nil {
+			log.Error("error initializing the event bus: ", err)
+			os.Exit(1)
+		}
+	}
+
+	if alarmTargets != "" {
+		if err := sfcCtrlPlugin.initAlarms(alarmTargets); err != nil {
+			log.Error("error initializing the alarm targets: ", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := validateShutdownPolicy(shutdownPolicy, shutdownTenants); err != nil {
+		log.Error("error validating the shutdown policy: ", err)
+		os.Exit(1)
+	}
+
+	if faultsFile != "" {
+		if err := loadFaultsFromFile(faultsFile); err != nil {
+			log.Error("error loading fault injection config: ", err)
+			os.Exit(1)
+		}
+	}
+
+	sfcCtrlPlugin.cnpDriverPlugin, err = cnpdriver.RegisterCNPDriverPlugin(cnpDriverName, dbFactory)
 	if err != nil {
 		log.Error("error loading cnp driver sfcCtrlPlugin", err)
 		os.Exit(1)
@@ -140,6 +304,27 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) Init() error {
 		}
 	}
 
+	// if -restore then replace the sfc datastore with the archive, it is read below like any other datastore
+	if restoreFile != "" {
+		if err := sfcCtrlPlugin.restoreFromFile(restoreFile); err != nil {
+			log.Error("error restoring sfc datastore: ", err)
+			os.Exit(1)
+		}
+	}
+
+	if err := sfcCtrlPlugin.DatastoreRenderRetryRetrieveAll(); err != nil {
+		log.Error("error reading render retries: ", err)
+	}
+	if err := sfcCtrlPlugin.DatastoreScheduledChangeRetrieveAll(); err != nil {
+		log.Error("error reading scheduled changes: ", err)
+	}
+
+	// the ownership index as the last run left it, the entities it shows were partially wired are recovered below
+	entityKeysBefore, err := sfcCtrlPlugin.entityKeysSnapshot()
+	if err != nil {
+		log.Error("error reading the ownership index: ", err)
+	}
+
 	sfcCtrlPlugin.ReconcileInit()
 
 	sfcCtrlPlugin.ReconcileStart()
@@ -181,6 +366,10 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) Init() error {
 		}
 	}
 
+	// the chains whose ttl expired, or whose grace period ended, while the controller was down are not wired again
+	sfcCtrlPlugin.dropExpiredSfcs(time.Now().Unix())
+	sfcCtrlPlugin.dropGraceEndedSfcs(time.Now().Unix())
+
 	if err = sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
 		log.Error("error copying config to ram cache: ", err)
 		os.Exit(1)
@@ -188,8 +377,33 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) Init() error {
 
 	sfcCtrlPlugin.ReconcileEnd()
 
+	sfcCtrlPlugin.recoverPartialRenders(entityKeysBefore)
+
+	if err := sfcCtrlPlugin.configVersionInitFromDatastore(); err != nil {
+		log.Error("error reading config versions: ", err)
+	}
+	if err := sfcCtrlPlugin.snapshotConfigVersion("startup"); err != nil {
+		log.Error("error storing startup config version: ", err)
+	}
+
 	sfcCtrlPlugin.controllerReady = true
 
+	sfcCtrlPlugin.agentWatchDone = make(chan struct{})
+	sfcCtrlPlugin.startBackgroundLoop(sfcCtrlPlugin.agentLivenessWatch)
+	sfcCtrlPlugin.renderRetryDone = make(chan struct{})
+	sfcCtrlPlugin.startBackgroundLoop(sfcCtrlPlugin.renderRetryLoop)
+	sfcCtrlPlugin.scheduledChangeDone = make(chan struct{})
+	sfcCtrlPlugin.startBackgroundLoop(sfcCtrlPlugin.scheduledChangeLoop)
+	sfcCtrlPlugin.federationSyncDone = make(chan struct{})
+	sfcCtrlPlugin.startBackgroundLoop(sfcCtrlPlugin.federationSyncLoop)
+
+	if gnmiAddress != "" {
+		if err := sfcCtrlPlugin.startGnmiServer(gnmiAddress); err != nil {
+			log.Error("error starting the gNMI server: ", err)
+			os.Exit(1)
+		}
+	}
+
 	sfcCtrlPlugin.StatusCheck.ReportStateChange(PluginID, statuscheck.OK, nil)
 
 	return nil
@@ -200,9 +414,73 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) InitRAMCache() {
 	sfcCtrlPlugin.ramConfigCache.EEs = make(map[string]controller.ExternalEntity)
 	sfcCtrlPlugin.ramConfigCache.HEs = make(map[string]controller.HostEntity)
 	sfcCtrlPlugin.ramConfigCache.SFCs = make(map[string]controller.SfcEntity)
+	sfcCtrlPlugin.ramConfigCache.NSs = make(map[string]controller.NetworkService)
+	sfcCtrlPlugin.ramConfigCache.TPLs = make(map[string]controller.SfcTemplate)
+	sfcCtrlPlugin.ramConfigCache.WPs = make(map[string]controller.WiringPolicy)
+}
+
+// loadFaultsFromFile turns on fault injection with the settings in the json file
+func loadFaultsFromFile(fpath string) error {
+
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return err
+	}
+	fc := &faults.Config{}
+	if err := json.Unmarshal(b, fc); err != nil {
+		return err
+	}
+
+	log.Warnf("loadFaultsFromFile: fault injection is on: %+v", *fc)
+
+	return faults.SetConfig(fc)
+}
+
+// startBackgroundLoop runs the loop in a goroutine Close waits for
+func (sfcCtrlPlugin *SfcControllerPluginHandler) startBackgroundLoop(loop func()) {
+	sfcCtrlPlugin.backgroundLoops.Add(1)
+	go func() {
+		defer sfcCtrlPlugin.backgroundLoops.Done()
+		loop()
+	}()
 }
 
 // Close performs close down procedures
 func (sfcCtrlPlugin *SfcControllerPluginHandler) Close() error {
+	// the loops are stopped first, so none of them renders or retries while the config is torn down
+	if sfcCtrlPlugin.agentWatchDone != nil {
+		close(sfcCtrlPlugin.agentWatchDone)
+	}
+	if sfcCtrlPlugin.renderRetryDone != nil {
+		close(sfcCtrlPlugin.renderRetryDone)
+	}
+	if sfcCtrlPlugin.scheduledChangeDone != nil {
+		close(sfcCtrlPlugin.scheduledChangeDone)
+	}
+	if sfcCtrlPlugin.federationSyncDone != nil {
+		close(sfcCtrlPlugin.federationSyncDone)
+	}
+	sfcCtrlPlugin.backgroundLoops.Wait()
+
+	if sfcCtrlPlugin.controllerReady {
+		if err := sfcCtrlPlugin.shutdownTeardown(shutdownPolicy, shutdownTenantList(shutdownTenants)); err != nil {
+			log.Error("error tearing down the rendered config: ", err)
+		}
+	}
+	if sfcCtrlPlugin.gnmiServer != nil {
+		sfcCtrlPlugin.gnmiServer.Stop()
+	}
+	if sfcCtrlPlugin.eventBus != nil {
+		sfcCtrlPlugin.eventBus.Close()
+	}
+	if sfcCtrlPlugin.alarmNotifier != nil {
+		sfcCtrlPlugin.alarmNotifier.Close()
+	}
+	if sfcCtrlPlugin.mirror != nil {
+		sfcCtrlPlugin.mirror.Close()
+	}
+	if sfcCtrlPlugin.etcdTxnClient != nil {
+		sfcCtrlPlugin.etcdTxnClient.Close()
+	}
 	return safeclose.Close(extentitydriver.EEOperationChannel)
 }
@@ -50,6 +50,21 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) WriteRAMCacheToEtcd() error {
 			return err
 		}
 	}
+	for _, ns := range sfcCtrlPlugin.ramConfigCache.NSs {
+		if err := sfcCtrlPlugin.DatastoreNetworkServiceCreate(&ns); err != nil {
+			return err
+		}
+	}
+	for _, tpl := range sfcCtrlPlugin.ramConfigCache.TPLs {
+		if err := sfcCtrlPlugin.DatastoreSfcTemplateCreate(&tpl); err != nil {
+			return err
+		}
+	}
+	for _, wp := range sfcCtrlPlugin.ramConfigCache.WPs {
+		if err := sfcCtrlPlugin.DatastoreWiringPolicyCreate(&wp); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -71,6 +86,15 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) ReadEtcdDatastoreIntoRAMCache()
 	if err := sfcCtrlPlugin.DatastoreSfcEntityRetrieveAllIntoRAMCache(); err != nil {
 		return err
 	}
+	if err := sfcCtrlPlugin.DatastoreNetworkServiceRetrieveAllIntoRAMCache(); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.DatastoreSfcTemplateRetrieveAllIntoRAMCache(); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.DatastoreWiringPolicyRetrieveAllIntoRAMCache(); err != nil {
+		return err
+	}
 
 	log.Infof("ReadEtcdDatastoreIntoRAMCache: end ...")
 
@@ -94,6 +118,18 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreReInitialize() error {
 	if err := sfcCtrlPlugin.DatastoreSfcEntityDeleteAll(); err != nil {
 		log.Error("DatastoreReInitialize: DatastoreSfcEntityDeleteAll: ", err)
 	}
+	if err := sfcCtrlPlugin.DatastoreNetworkServiceDeleteAll(); err != nil {
+		log.Error("DatastoreReInitialize: DatastoreNetworkServiceDeleteAll: ", err)
+	}
+	if err := sfcCtrlPlugin.DatastoreSfcTemplateDeleteAll(); err != nil {
+		log.Error("DatastoreReInitialize: DatastoreSfcTemplateDeleteAll: ", err)
+	}
+	if err := sfcCtrlPlugin.DatastoreWiringPolicyDeleteAll(); err != nil {
+		log.Error("DatastoreReInitialize: DatastoreWiringPolicyDeleteAll: ", err)
+	}
+	if err := sfcCtrlPlugin.DatastoreRenderRetryDeleteAll(); err != nil {
+		log.Error("DatastoreReInitialize: DatastoreRenderRetryDeleteAll: ", err)
+	}
 
 	return nil
 }
@@ -332,6 +368,126 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreSfcEntityDelete(ee *co
 	return nil
 }
 
+// DatastoreNetworkServiceCreate creates the specified entity in the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreNetworkServiceCreate(ns *controller.NetworkService) error {
+
+	name := controller.NetworkServiceNameKey(ns.Name)
+
+	log.Infof("DatastoreNetworkServiceCreate: setting key: '%s'", name)
+
+	err := sfcCtrlPlugin.db.Put(name, ns)
+	if err != nil {
+		log.Errorf("DatastoreNetworkServiceCreate: error storing key: '%s': %s", name, err)
+		return err
+	}
+
+	return nil
+}
+
+// DatastoreNetworkServiceRetrieveAllIntoRAMCache pulls the specified entities from the sfc db in etcd into the
+// sfc ram cache, the sfc's they expand into are stored, and retrieved, as sfc entities
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreNetworkServiceRetrieveAllIntoRAMCache() error {
+
+	return sfcCtrlPlugin.DatastoreNetworkServiceIterate(func(key string, ns *controller.NetworkService) {
+		sfcCtrlPlugin.ramConfigCache.NSs[key] = *ns
+		log.Infof("DatastoreNetworkServiceRetrieveAllIntoRAMCache: adding ns: '%s'", key)
+	})
+}
+
+// DatastoreNetworkServiceDeleteAll removes the specified entities from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreNetworkServiceDeleteAll() error {
+
+	return sfcCtrlPlugin.DatastoreNetworkServiceIterate(func(name string, ns *controller.NetworkService) {
+		key := controller.NetworkServiceNameKey(name)
+		log.Infof("DatastoreNetworkServiceDeleteAll: deleting ns: '%s'", key)
+		sfcCtrlPlugin.db.Delete(key)
+	})
+}
+
+// DatastoreNetworkServiceIterate iterates over the set of specified entities in the sfc tree in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreNetworkServiceIterate(actionFunc func(key string,
+	ns *controller.NetworkService)) error {
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(controller.NetworkServiceKeyPrefix())
+	if err != nil {
+		log.Errorf("DatastoreNetworkServiceIterate: error listing: %s", err)
+		return err
+	}
+
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return nil
+		}
+		ns := &controller.NetworkService{}
+		if err := kv.GetValue(ns); err != nil {
+			log.Errorf("DatastoreNetworkServiceIterate: error decoding '%s': %s", kv.GetKey(), err)
+			return err
+		}
+		actionFunc(ns.Name, ns)
+	}
+}
+
+// DatastoreSfcTemplateCreate creates the specified entity in the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreSfcTemplateCreate(tpl *controller.SfcTemplate) error {
+
+	name := controller.SfcTemplateNameKey(tpl.Name)
+
+	log.Infof("DatastoreSfcTemplateCreate: setting key: '%s'", name)
+
+	err := sfcCtrlPlugin.db.Put(name, tpl)
+	if err != nil {
+		log.Errorf("DatastoreSfcTemplateCreate: error storing key: '%s': %s", name, err)
+		return err
+	}
+
+	return nil
+}
+
+// DatastoreSfcTemplateRetrieveAllIntoRAMCache pulls the specified entities from the sfc db in etcd into the
+// sfc ram cache, the instances are stored, and retrieved, as sfc entities
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreSfcTemplateRetrieveAllIntoRAMCache() error {
+
+	return sfcCtrlPlugin.DatastoreSfcTemplateIterate(func(key string, tpl *controller.SfcTemplate) {
+		sfcCtrlPlugin.ramConfigCache.TPLs[key] = *tpl
+		log.Infof("DatastoreSfcTemplateRetrieveAllIntoRAMCache: adding sfc template: '%s'", key)
+	})
+}
+
+// DatastoreSfcTemplateDeleteAll removes the specified entities from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreSfcTemplateDeleteAll() error {
+
+	return sfcCtrlPlugin.DatastoreSfcTemplateIterate(func(name string, tpl *controller.SfcTemplate) {
+		key := controller.SfcTemplateNameKey(name)
+		log.Infof("DatastoreSfcTemplateDeleteAll: deleting sfc template: '%s'", key)
+		sfcCtrlPlugin.db.Delete(key)
+	})
+}
+
+// DatastoreSfcTemplateIterate iterates over the set of specified entities in the sfc tree in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreSfcTemplateIterate(actionFunc func(key string,
+	tpl *controller.SfcTemplate)) error {
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(controller.SfcTemplateKeyPrefix())
+	if err != nil {
+		log.Errorf("DatastoreSfcTemplateIterate: error listing: %s", err)
+		return err
+	}
+
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return nil
+		}
+		tpl := &controller.SfcTemplate{}
+		if err := kv.GetValue(tpl); err != nil {
+			log.Errorf("DatastoreSfcTemplateIterate: error decoding '%s': %s", kv.GetKey(), err)
+			return err
+		}
+		actionFunc(tpl.Name, tpl)
+	}
+}
+
 // DatastoreSystemParametersCreate creates the specified entity in the sfc db in etcd
 func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreSystemParametersCreate(sp *controller.SystemParameters) error {
 
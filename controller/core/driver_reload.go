@@ -0,0 +1,159 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The hot reload of the cnp driver is implemented in this file.  The driver
+// reads the system parameters it allocates from, ie the starting vlan id,
+// when it is created, so a change of the ones that shape the wiring, or a
+// switch to another driver, creates a new driver instance in place of the
+// running one.  The config is then rendered with it inside a
+// reconcile: the new driver re-reads the id's, so what was allocated stays
+// as it is, and only the keys whose wiring changed are written to the
+// agents, the others are left untouched.  The config is validated against
+// the capabilities of the new driver first, and if its canary fails, the
+// previous driver is put back and re-renders what was running.
+//
+// The driver named here is not persisted, a restart starts the -cnp-driver.
+
+package core
+
+import (
+	"reflect"
+
+	"github.com/ligato/sfc-controller/controller/cnpdriver"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// DriverReloadResult is the outcome of a driver reload
+type DriverReloadResult struct {
+	FromDriver string `json:"from_driver"`
+	ToDriver   string `json:"to_driver"`
+	Reason     string `json:"reason"`
+}
+
+// DriverInfo is the running cnp driver and its capabilities
+type DriverInfo struct {
+	Name         string                 `json:"name"`
+	Capabilities cnpdriver.Capabilities `json:"capabilities"`
+	Drivers      []string               `json:"drivers"`
+}
+
+// driverInfo returns the running driver, and the drivers it can be switched to
+func (sfcCtrlPlugin *SfcControllerPluginHandler) driverInfo() (*DriverInfo, error) {
+
+	capabilities, err := cnpdriver.LookupCNPDriverCapabilities(cnpDriverName)
+	if err != nil {
+		return nil, err
+	}
+	return &DriverInfo{
+		Name:         cnpDriverName,
+		Capabilities: capabilities,
+		Drivers:      cnpdriver.CNPDriverNames(),
+	}, nil
+}
+
+// reloadCNPDriver replaces the running driver with a new instance of the named one, the running one if the
+// name is empty, and renders the config with it
+func (sfcCtrlPlugin *SfcControllerPluginHandler) reloadCNPDriver(name string, reason string) (*DriverReloadResult,
+	error) {
+
+	if name == "" {
+		name = cnpDriverName
+	}
+	if err := sfcCtrlPlugin.validateCNPDriver(name); err != nil {
+		return nil, err
+	}
+
+	result := &DriverReloadResult{FromDriver: cnpDriverName, ToDriver: name, Reason: reason}
+	log.Infof("reloadCNPDriver: from driver: '%s' to driver: '%s', %s", result.FromDriver, name, reason)
+
+	driver, err := cnpdriver.RegisterCNPDriverPlugin(name, sfcCtrlPlugin.dbFactory)
+	if err != nil {
+		return nil, err
+	}
+
+	prevDriver, prevName := sfcCtrlPlugin.cnpDriverPlugin, cnpDriverName
+	sfcCtrlPlugin.cnpDriverPlugin, cnpDriverName = driver, name
+
+	sfcCtrlPlugin.ReconcileStart()
+	if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+		sfcCtrlPlugin.ReconcileEnd()
+		return result, err
+	}
+	if err := sfcCtrlPlugin.ReconcileEndCanary(); err != nil {
+		// the canary has been put back and no other host was touched, the previous driver renders again
+		log.Errorf("reloadCNPDriver: driver: '%s' failed its canary, putting back driver: '%s': %s", name,
+			prevName, err)
+		sfcCtrlPlugin.cnpDriverPlugin, cnpDriverName = prevDriver, prevName
+		sfcCtrlPlugin.ReconcileStart()
+		if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+			log.Errorf("reloadCNPDriver: error re-rendering with driver: '%s': %s", prevName, err)
+		}
+		sfcCtrlPlugin.ReconcileEnd()
+		return result, err
+	}
+
+	if err := prevDriver.DeinitPlugin(); err != nil {
+		log.Warnf("reloadCNPDriver: error de-initializing driver: '%s': %s", prevName, err)
+	}
+
+	return result, nil
+}
+
+// validateCNPDriver checks the named driver can render the config that is running
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateCNPDriver(name string) error {
+
+	if _, err := cnpdriver.LookupCNPDriverCapabilities(name); err != nil {
+		return err
+	}
+	for _, heName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		he := sfcCtrlPlugin.ramConfigCache.HEs[heName]
+		if err := cnpdriver.ValidateHostEntity(name, &he); err != nil {
+			return err
+		}
+	}
+	for _, sfc := range sfcCtrlPlugin.ramConfigCache.SFCs {
+		if err := cnpdriver.ValidateSfcEntity(name, &sfc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// systemParametersRewire is whether the system parameters changed the wiring the driver renders, the others,
+// ie the timeouts and retention, are read as they are used
+func systemParametersRewire(prev *controller.SystemParameters, sp *controller.SystemParameters) bool {
+
+	return prev.Mtu != sp.Mtu ||
+		prev.StartingVlanId != sp.StartingVlanId ||
+		prev.DefaultStaticRouteWeight != sp.DefaultStaticRouteWeight ||
+		prev.DefaultStaticRoutePreference != sp.DefaultStaticRoutePreference ||
+		prev.GetDynamicBridgeParms().String() != sp.GetDynamicBridgeParms().String() ||
+		prev.GetStaticBridgeParms().String() != sp.GetStaticBridgeParms().String() ||
+		prev.GetVxlanParms().String() != sp.GetVxlanParms().String() ||
+		prev.OverlayTopology != sp.OverlayTopology ||
+		prev.AgentApi != sp.AgentApi ||
+		prev.DeterministicIds != sp.DeterministicIds ||
+		!reflect.DeepEqual(prev.FeatureFlags, sp.FeatureFlags)
+}
+
+// reloadCNPDriverForSystemParameters reloads the driver if the system parameters change the wiring
+func (sfcCtrlPlugin *SfcControllerPluginHandler) reloadCNPDriverForSystemParameters(
+	prev *controller.SystemParameters, sp *controller.SystemParameters) error {
+
+	if !systemParametersRewire(prev, sp) {
+		return nil
+	}
+	_, err := sfcCtrlPlugin.reloadCNPDriver("", "system parameters changed")
+	return err
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The per environment overrides of an sfc are implemented in this file.  The
+// same sfc can be posted to a lab and a prod controller, each controller is
+// started with its -environment and renders the chain with the matching
+// override applied.  The ram cache, and what is stored in etcd, keeps the sfc
+// as it was posted.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// sfcForEnvironment returns the sfc with the override for the controller's environment applied, the sfc
+// itself is returned when it has none
+func sfcForEnvironment(sfc *controller.SfcEntity) *controller.SfcEntity {
+
+	override, exists := sfc.GetEnvironments()[environment]
+	if environment == "" || !exists || override == nil {
+		return sfc
+	}
+
+	log.Infof("sfcForEnvironment: sfc: '%s', applying environment: '%s'", sfc.Name, environment)
+
+	envSfc := *sfc
+	if override.SfcIpv4Prefix != "" {
+		envSfc.SfcIpv4Prefix = override.SfcIpv4Prefix
+	}
+	envSfc.Elements = make([]*controller.SfcEntity_SfcElement, 0, len(sfc.GetElements()))
+	for _, sfcElement := range sfc.GetElements() {
+		envElement := *sfcElement
+		if override.Mtu != 0 {
+			envElement.Mtu = override.Mtu
+		}
+		if override.RxMode != controller.RxModeType_RX_MODE_UNKNOWN {
+			envElement.RxMode = override.RxMode
+		}
+		for _, elementOverride := range override.GetElements() {
+			if elementOverride.Container != envElement.Container ||
+				elementOverride.PortLabel != envElement.PortLabel {
+				continue
+			}
+			if elementOverride.Mtu != 0 {
+				envElement.Mtu = elementOverride.Mtu
+			}
+			if elementOverride.RxMode != controller.RxModeType_RX_MODE_UNKNOWN {
+				envElement.RxMode = elementOverride.RxMode
+			}
+			if elementOverride.Ipv4Addr != "" {
+				envElement.Ipv4Addr = elementOverride.Ipv4Addr
+			}
+			if elementOverride.Ipv6Addr != "" {
+				envElement.Ipv6Addr = elementOverride.Ipv6Addr
+			}
+		}
+		envSfc.Elements = append(envSfc.Elements, &envElement)
+	}
+
+	return &envSfc
+}
+
+// validateSfcEnvironments ensures every element override names an element of the chain
+func validateSfcEnvironments(sfc *controller.SfcEntity) error {
+
+	elements := make(map[string]bool)
+	for _, sfcElement := range sfc.GetElements() {
+		elements[sfcElement.Container+"/"+sfcElement.PortLabel] = true
+	}
+
+	for envName, override := range sfc.GetEnvironments() {
+		if envName == "" {
+			return fmt.Errorf("Missing environment name for sfc: '%s'", sfc.Name)
+		}
+		for _, elementOverride := range override.GetElements() {
+			if !elements[elementOverride.Container+"/"+elementOverride.PortLabel] {
+				return fmt.Errorf("Environment: '%s' of sfc: '%s' overrides unknown element: '%s/%s'",
+					envName, sfc.Name, elementOverride.Container, elementOverride.PortLabel)
+			}
+		}
+	}
+
+	return nil
+}
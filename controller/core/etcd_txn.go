@@ -0,0 +1,49 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"github.com/coreos/etcd/clientv3"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/db/keyval/etcdv3"
+	"github.com/ligato/sfc-controller/controller/utils/revisions"
+)
+
+// initEtcdTxns returns the etcd factory with brokers that put on a revision in etcd txns, the etcd plugin
+// does not expose its client, so a client of the controller's own is connected with the plugin's config
+func (sfcCtrlPlugin *SfcControllerPluginHandler) initEtcdTxns(
+	etcdFactory func(string) keyval.ProtoBroker) (func(string) keyval.ProtoBroker, error) {
+
+	cfg := &etcdv3.Config{}
+	found, err := sfcCtrlPlugin.Etcd.PluginConfig.GetValue(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		log.Warn("initEtcdTxns: etcd config not found, the read-modify-writes will fail")
+		return etcdFactory, nil
+	}
+	clientCfg, err := etcdv3.ConfigToClientv3(cfg)
+	if err != nil {
+		return nil, err
+	}
+	client, err := clientv3.New(*clientCfg.Config)
+	if err != nil {
+		return nil, err
+	}
+	sfcCtrlPlugin.etcdTxnClient = client
+
+	return revisions.WrapEtcdBrokerFactory(etcdFactory, client, clientCfg.OpTimeout), nil
+}
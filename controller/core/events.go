@@ -0,0 +1,142 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The streaming of the controller's events to a message bus is implemented
+// in this file.  With -event-bus set, the changes of the entities, their
+// render statuses, the reconciles and the drift found by a consistency
+// check are published to Kafka or NATS, see the eventbus package.  Nothing
+// is published, and nothing is queued, without it.
+//
+// The same events are kept in the event log, whatever -event-bus is, for
+// /sfc-controller/v1/status/events to query: the repeats of an event are
+// counted on the one recorded, the bursts are rate limited, and the log is
+// bounded by -event-log-size and -event-log-retention, see the eventlog
+// package.
+
+package core
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/cnpdriver/l2driver"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/eventbus"
+	"github.com/ligato/sfc-controller/controller/utils/eventlog"
+)
+
+// the actions of the entity events
+const (
+	eventActionCreated = "created"
+	eventActionUpdated = "updated"
+	eventActionDeleted = "deleted"
+)
+
+// the actions of the reconcile events
+const (
+	reconcileEventStart = "start"
+	reconcileEventEnd   = "end"
+)
+
+// initEventBus starts publishing to the bus at the url
+func (sfcCtrlPlugin *SfcControllerPluginHandler) initEventBus(busURL string, topicPrefix string) error {
+
+	pub, err := eventbus.NewPublisher(busURL)
+	if err != nil {
+		return err
+	}
+	sfcCtrlPlugin.eventBus = eventbus.New(pub, topicPrefix, eventbus.DefaultQueueLength)
+
+	log.Infof("initEventBus: publishing events to: '%s', topics: '%s'", busURL,
+		sfcCtrlPlugin.eventBus.Topic("<type>"))
+	return nil
+}
+
+// initEventLog starts keeping the events, at most size of them for the retention
+func (sfcCtrlPlugin *SfcControllerPluginHandler) initEventLog(size int, retention time.Duration) {
+
+	policy := eventlog.DefaultPolicy()
+	policy.MaxEvents = size
+	policy.Retention = retention
+	sfcCtrlPlugin.eventLog = eventlog.New(policy)
+}
+
+// emitEvent stamps the event, records it in the event log and queues it on the bus, if there is one
+func (sfcCtrlPlugin *SfcControllerPluginHandler) emitEvent(ev eventbus.Event) {
+	if sfcCtrlPlugin.eventLog != nil {
+		sfcCtrlPlugin.eventLog.Record(ev)
+	}
+	if sfcCtrlPlugin.eventBus == nil {
+		return
+	}
+	ev.Time = time.Now().Unix()
+	sfcCtrlPlugin.eventBus.Emit(ev)
+}
+
+// emitEntityChangeEvent publishes the change recorded in an entity's history, the spec itself is not sent
+func (sfcCtrlPlugin *SfcControllerPluginHandler) emitEntityChangeEvent(change *controller.EntityChange) {
+
+	action := eventActionUpdated
+	switch {
+	case change.OldSpec == "":
+		action = eventActionCreated
+	case change.NewSpec == "":
+		action = eventActionDeleted
+	}
+	sfcCtrlPlugin.emitEvent(eventbus.Event{
+		Type:   eventbus.EventEntity,
+		Action: action,
+		Kind:   change.Kind,
+		Name:   change.Name,
+		Source: change.Source,
+	})
+}
+
+// emitWiringEvent publishes the render status of an entity as it is flushed
+func (sfcCtrlPlugin *SfcControllerPluginHandler) emitWiringEvent(kind string, status *controller.EntityStatus) {
+	sfcCtrlPlugin.emitEvent(eventbus.Event{
+		Type:    eventbus.EventWiring,
+		Action:  strings.ToLower(status.State.String()),
+		Kind:    kind,
+		Name:    status.Name,
+		Message: status.Message,
+	})
+}
+
+// emitReconcileEvent publishes the start or the end of a reconcile, the name is the agent of a single
+// agent reconcile
+func (sfcCtrlPlugin *SfcControllerPluginHandler) emitReconcileEvent(action string, vppLabel string, err error) {
+
+	ev := eventbus.Event{
+		Type:   eventbus.EventReconcile,
+		Action: action,
+		Name:   vppLabel,
+	}
+	if err != nil {
+		ev.Message = err.Error()
+	}
+	sfcCtrlPlugin.emitEvent(ev)
+}
+
+// emitDriftEvents publishes each inconsistency a consistency check found
+func (sfcCtrlPlugin *SfcControllerPluginHandler) emitDriftEvents(inconsistencies []l2driver.Inconsistency) {
+	for _, inconsistency := range inconsistencies {
+		sfcCtrlPlugin.emitEvent(eventbus.Event{
+			Type:    eventbus.EventDrift,
+			Action:  inconsistency.Kind,
+			Name:    inconsistency.Key,
+			Message: inconsistency.Detail,
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The eviction of removed entities from the caches is implemented in this
+// file.  The entities a rollback or a restore drops from the config are
+// evicted from the controller's per entity caches and from the driver's, so
+// a long running controller only caches what is configured.  The sizes of
+// the caches are reported on the caches url.
+
+package core
+
+import (
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// CacheSizes are the number of entries of the controller's and the driver's caches
+type CacheSizes struct {
+	Config     map[string]int `json:"config"`
+	Controller map[string]int `json:"controller"`
+	Driver     map[string]int `json:"driver"`
+}
+
+// evictRemovedEntities evicts the entities that are in the from version but not in the to version
+func (sfcCtrlPlugin *SfcControllerPluginHandler) evictRemovedEntities(from *controller.ConfigVersion,
+	to *controller.ConfigVersion) {
+
+	for _, entity := range diffConfigVersions(from, to).Removed {
+		if i := strings.Index(entity, "/"); i >= 0 {
+			sfcCtrlPlugin.evictEntity(entity[:i], entity[i+1:])
+		}
+	}
+}
+
+// evictEntity removes the entity from the controller's per entity caches and from the driver's caches
+func (sfcCtrlPlugin *SfcControllerPluginHandler) evictEntity(kind string, name string) {
+
+	log.Infof("evictEntity: evicting %s: '%s'", kind, name)
+
+	switch kind {
+	case controller.HostEntityKind:
+		delete(sfcCtrlPlugin.agentBreakers, name)
+	case controller.SfcEntityKind:
+		delete(sfcCtrlPlugin.sfcRenderStates, name)
+	}
+
+	key := controller.RenderRetryKey(kind, name)
+	if _, exists := sfcCtrlPlugin.renderRetries[key]; exists {
+		delete(sfcCtrlPlugin.renderRetries, key)
+		if _, err := sfcCtrlPlugin.db.Delete(key); err != nil {
+			log.Errorf("evictEntity: error deleting key: '%s': %s", key, err)
+		}
+	}
+
+	sfcCtrlPlugin.cnpDriverPlugin.EvictEntity(kind, name)
+}
+
+// cacheSizes returns the number of entries of the caches
+func (sfcCtrlPlugin *SfcControllerPluginHandler) cacheSizes() *CacheSizes {
+
+	return &CacheSizes{
+		Config: map[string]int{
+			"ees":  len(sfcCtrlPlugin.ramConfigCache.EEs),
+			"hes":  len(sfcCtrlPlugin.ramConfigCache.HEs),
+			"sfcs": len(sfcCtrlPlugin.ramConfigCache.SFCs),
+			"nss":  len(sfcCtrlPlugin.ramConfigCache.NSs),
+			"tpls": len(sfcCtrlPlugin.ramConfigCache.TPLs),
+		},
+		Controller: map[string]int{
+			"entity_renders":    len(sfcCtrlPlugin.entityRenders),
+			"sfc_render_states": len(sfcCtrlPlugin.sfcRenderStates),
+			"render_retries":    len(sfcCtrlPlugin.renderRetries),
+			"agent_breakers":    len(sfcCtrlPlugin.agentBreakers),
+		},
+		Driver: sfcCtrlPlugin.cnpDriverPlugin.GetCacheSizes(),
+	}
+}
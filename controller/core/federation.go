@@ -0,0 +1,336 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The federation of controllers managing different sites is implemented in
+// this file.  A chain spanning two sites is a n/s vxlan chain at each site,
+// its ee is the other site, an ee of driver type EE_DRIVER_TYPE_SFC_CONTROLLER
+// named after the site, whose mgmnt address is the peer controller's REST
+// api.  The controllers exchange a record per peer: the host of their site
+// the inter-site segment ends on, its tunnel endpoint and its vni.  Each one
+// stores the peer's endpoint and the agreed vni in the peer's ee, so both
+// render the segment alike.  The vni of the site with the lower name is
+// used by both, the other site does not render the segment before it knows
+// it.  All the chains to a peer must tunnel from one host, or one gateway.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/eventbus"
+)
+
+// how often the records are exchanged with the peers
+const federationSyncInterval = 30 * time.Second
+
+// how long a peer has to answer an exchange
+const federationSyncTimeout = 10 * time.Second
+
+// the port of the peer controller's REST api if its ee has no mgmnt_port
+const federationDefaultPort = 9191
+
+// FederationRecord is what a site tells a federation peer about the inter-site segment of their chains
+type FederationRecord struct {
+	Site           string   `json:"site"`                      // the site sending the record
+	Peer           string   `json:"peer"`                      // the site it is sent to
+	Host           string   `json:"host,omitempty"`            // the host the segment ends on, none without chains
+	TunnelEndpoint string   `json:"tunnel_endpoint,omitempty"` // the vxlan tunnel source of the host
+	Vni            uint32   `json:"vni,omitempty"`             // the vni of the segment, 0 until it is rendered
+	Sfcs           []string `json:"sfcs,omitempty"`            // the chains to the peer
+	Error          string   `json:"error,omitempty"`           // why the record could not be built, never sent
+}
+
+// isFederationPeer returns whether the ee is the controller of another site
+func isFederationPeer(ee *controller.ExternalEntity) bool {
+	return ee.EeDriverType == controller.ExtEntDriverType_EE_DRIVER_TYPE_SFC_CONTROLLER
+}
+
+// federationPeers returns the names of the federation peers, none if the site has no federation_site
+func (sfcCtrlPlugin *SfcControllerPluginHandler) federationPeers() []string {
+
+	if sfcCtrlPlugin.ramConfigCache.SysParms.FederationSite == "" {
+		return nil
+	}
+	var peers []string
+	for _, eeName := range sortedKeysEE(sfcCtrlPlugin.ramConfigCache.EEs) {
+		ee := sfcCtrlPlugin.ramConfigCache.EEs[eeName]
+		if isFederationPeer(&ee) {
+			peers = append(peers, eeName)
+		}
+	}
+	return peers
+}
+
+// federationRecord builds the record of this site for the peer
+func (sfcCtrlPlugin *SfcControllerPluginHandler) federationRecord(peerName string) (*FederationRecord, error) {
+
+	site := sfcCtrlPlugin.ramConfigCache.SysParms.FederationSite
+	if site == "" {
+		return nil, fmt.Errorf("Missing federation_site in the system parameters")
+	}
+	ee, exists := sfcCtrlPlugin.ramConfigCache.EEs[peerName]
+	if !exists || !isFederationPeer(&ee) {
+		return nil, fmt.Errorf("Invalid federation peer: '%s', it is not an ee of ee_driver_type: %s", peerName,
+			controller.ExtEntDriverType_EE_DRIVER_TYPE_SFC_CONTROLLER)
+	}
+
+	record := &FederationRecord{Site: site, Peer: peerName}
+	hosts := make(map[string]bool)
+	for _, sfcName := range sortedKeysSFC(sfcCtrlPlugin.ramConfigCache.SFCs) {
+		sfc := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+		if sfc.Type != controller.SfcType_SFC_NS_VXLAN || !sfcHasExtEntity(&sfc, peerName) {
+			continue
+		}
+		record.Sfcs = append(record.Sfcs, sfcName)
+		for _, sfcElement := range sfc.GetElements() {
+			if sfcElementIsContainer(sfcElement) && sfcElement.EtcdVppSwitchKey != "" {
+				hosts[sfcCtrlPlugin.eeTunnelHost(sfcElement.EtcdVppSwitchKey)] = true
+			}
+		}
+	}
+	if len(hosts) == 0 {
+		return record, nil
+	}
+	if len(hosts) > 1 {
+		hostNames := make([]string, 0, len(hosts))
+		for heName := range hosts {
+			hostNames = append(hostNames, heName)
+		}
+		sort.Strings(hostNames)
+		return nil, fmt.Errorf("Invalid federation with: '%s', its chains tunnel from hosts: %s, they must "+
+			"share one host or gateway", peerName, strings.Join(hostNames, ","))
+	}
+
+	for heName := range hosts {
+		he := sfcCtrlPlugin.ramConfigCache.HEs[heName]
+		_, _, tunnelIpv4 := hostPeerUplinkAddrs(&he, peerName)
+		record.Host, record.TunnelEndpoint = heName, tunnelIpv4
+	}
+	if record.TunnelEndpoint == "" {
+		return nil, fmt.Errorf("Missing vxlan_tunnel_ipv4 of he: '%s' for the federation with: '%s'",
+			record.Host, peerName)
+	}
+
+	// the agreed vni once it is stored in the ee, until then the one the driver rendered, if any
+	if ee.HostVxlan != nil && ee.HostVxlan.Vni != 0 {
+		record.Vni = ee.HostVxlan.Vni
+		return record, nil
+	}
+	ids, err := sfcCtrlPlugin.GetAllocatedIDs(record.Host, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, he2ee := range ids.HostToEEs {
+		if he2ee.EeName == peerName {
+			record.Vni = he2ee.VlanId
+		}
+	}
+	return record, nil
+}
+
+// sfcHasExtEntity returns whether an element of the chain is the ee
+func sfcHasExtEntity(sfc *controller.SfcEntity, eeName string) bool {
+	for _, sfcElement := range sfc.GetElements() {
+		if sfcElement.Type == controller.SfcElementType_EXTERNAL_ENTITY && sfcElement.Container == eeName {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFederationRecord stores the endpoint and the agreed vni of the peer's record in the peer's ee and
+// re-renders the config if they changed, false is returned if they did not
+func (sfcCtrlPlugin *SfcControllerPluginHandler) applyFederationRecord(record *FederationRecord,
+	source string) (bool, error) {
+
+	local, err := sfcCtrlPlugin.federationRecord(record.Site)
+	if err != nil {
+		return false, err
+	}
+	if record.Peer != local.Site {
+		return false, fmt.Errorf("Invalid federation record from: '%s', it is for site: '%s', this site is: '%s'",
+			record.Site, record.Peer, local.Site)
+	}
+	if record.Host == "" {
+		return false, nil // the peer has no chains to this site yet
+	}
+
+	endpoint, vni := record.TunnelEndpoint, local.Vni
+	if record.Site < local.Site {
+		// this site uses the peer's vni, the segment is not rendered with another one meanwhile
+		vni = record.Vni
+		if vni == 0 {
+			endpoint = ""
+		}
+	}
+
+	ee := sfcCtrlPlugin.ramConfigCache.EEs[record.Site]
+	updated := proto.Clone(&ee).(*controller.ExternalEntity)
+	if updated.HostVxlan == nil {
+		updated.HostVxlan = &controller.ExternalEntity_HostVxlan{}
+	}
+	updated.HostVxlan.SourceIpv4, updated.HostVxlan.Vni = endpoint, vni
+	if updated.String() == ee.String() {
+		return false, nil
+	}
+	if err := sfcCtrlPlugin.validateEE(updated); err != nil {
+		return false, fmt.Errorf("Invalid federation record from: '%s': %s", record.Site, err)
+	}
+
+	message := fmt.Sprintf("host: '%s' tunnel endpoint: '%s' vni: %d", record.Host, endpoint, vni)
+	log.Infof("applyFederationRecord: peer: '%s', %s", record.Site, message)
+
+	change := sfcCtrlPlugin.newEntityChange(controller.ExternalEntityKind, updated.Name, updated, source)
+	sfcCtrlPlugin.ramConfigCache.EEs[updated.Name] = *updated
+	if err := sfcCtrlPlugin.DatastoreExternalEntityCreate(updated); err != nil {
+		return false, err
+	}
+	sfcCtrlPlugin.recordEntityChange(change)
+
+	// the tunnels to the peer were rendered with the former endpoint or vni, if at all
+	sfcCtrlPlugin.ReconcileStart()
+	if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+		log.Errorf("applyFederationRecord: error re-rendering the config: %s", err)
+	}
+	sfcCtrlPlugin.ReconcileEnd()
+
+	sfcCtrlPlugin.emitEvent(eventbus.Event{
+		Type:    eventbus.EventFederation,
+		Action:  "synced",
+		Kind:    controller.ExternalEntityKind,
+		Name:    record.Site,
+		Message: message,
+	})
+	if err := sfcCtrlPlugin.snapshotConfigVersion("POST federation/" + record.Site); err != nil {
+		log.Errorf("applyFederationRecord: error storing config version: %s", err)
+	}
+	return true, nil
+}
+
+// syncFederationPeer exchanges the records with the peer, the peer answers its record once it applied ours
+func (sfcCtrlPlugin *SfcControllerPluginHandler) syncFederationPeer(peerName string) error {
+
+	// the mutex is not held while the peer is called, it may be calling us meanwhile, and its handler needs it
+	sfcCtrlPlugin.HttpMutex.Lock()
+	local, err := sfcCtrlPlugin.federationRecord(peerName)
+	ee := sfcCtrlPlugin.ramConfigCache.EEs[peerName]
+	sfcCtrlPlugin.HttpMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	remote, err := postFederationRecord(&ee, local)
+	if err != nil {
+		return fmt.Errorf("federation exchange with: '%s' failed: %s", peerName, err)
+	}
+	if remote.Site != peerName {
+		return fmt.Errorf("Invalid federation record from: '%s', the peer: '%s' answered as site: '%s'",
+			ee.MgmntIpAddress, peerName, remote.Site)
+	}
+
+	sfcCtrlPlugin.HttpMutex.Lock()
+	defer sfcCtrlPlugin.HttpMutex.Unlock()
+
+	_, err = sfcCtrlPlugin.applyFederationRecord(remote, controller.FederationHTTPPrefix()+peerName)
+	return err
+}
+
+// postFederationRecord sends the record to the peer's controller and returns the one it answers
+func postFederationRecord(ee *controller.ExternalEntity, record *FederationRecord) (*FederationRecord, error) {
+
+	body, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	port := ee.MgmntPort
+	if port == 0 {
+		port = federationDefaultPort
+	}
+	url := fmt.Sprintf("http://%s:%d%s", ee.MgmntIpAddress, port, controller.FederationHTTPPrefix())
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Changed-By", "federation/"+record.Site)
+	if ee.BasicAuthUser != "" {
+		req.SetBasicAuth(ee.BasicAuthUser, ee.BasicAuthPasswd)
+	}
+
+	client := &http.Client{Timeout: federationSyncTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+	}
+	remote := &FederationRecord{}
+	if err := json.Unmarshal(data, remote); err != nil {
+		return nil, err
+	}
+	return remote, nil
+}
+
+// federationSyncLoop exchanges the records with the peers until the plugin is closed, the cache is only used under
+// the http mutex, like in the REST requests, but the mutex is released while a peer is called, see syncFederationPeer
+func (sfcCtrlPlugin *SfcControllerPluginHandler) federationSyncLoop() {
+	for {
+		select {
+		case <-sfcCtrlPlugin.federationSyncDone:
+			return
+		case <-time.After(federationSyncInterval):
+		}
+
+		sfcCtrlPlugin.HttpMutex.Lock()
+		peers := sfcCtrlPlugin.federationPeers()
+		sfcCtrlPlugin.HttpMutex.Unlock()
+
+		for _, peerName := range peers {
+			if err := sfcCtrlPlugin.syncFederationPeer(peerName); err != nil {
+				log.Warnf("federationSyncLoop: %s", err)
+			}
+		}
+	}
+}
+
+// federationRecords returns the records of this site for each of its peers, with the error of those that
+// could not be built
+func (sfcCtrlPlugin *SfcControllerPluginHandler) federationRecords() []*FederationRecord {
+
+	records := make([]*FederationRecord, 0)
+	for _, peerName := range sfcCtrlPlugin.federationPeers() {
+		record, err := sfcCtrlPlugin.federationRecord(peerName)
+		if err != nil {
+			record = &FederationRecord{Site: sfcCtrlPlugin.ramConfigCache.SysParms.FederationSite, Peer: peerName,
+				Error: err.Error()}
+		}
+		records = append(records, record)
+	}
+	return records
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The state tree the controller serves over gNMI is built in this file.  With
+// -gnmi-address set, a gNMI server is started, see the gnmi package, and its
+// subscribers read the tree below; each value is the json the REST API
+// returns for it:
+//
+//	/system-parameters
+//	/external-entities/external-entity[name=]
+//	/host-entities/host-entity[name=]
+//	/sfc-entities/sfc-entity[name=]
+//	/network-services/network-service[name=]
+//	/render-statuses/render-status[kind=][name=]
+//	/allocations/host[name=]
+//	/allocations/host-to-external[host=][external=]
+//	/allocations/host-to-host[source=][destination=]
+//	/allocations/sfc-port[sfc=][container=][port=]
+
+package core
+
+import (
+	"encoding/json"
+	"net"
+
+	"github.com/gogo/protobuf/proto"
+	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/gnmi"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+)
+
+// startGnmiServer serves the state tree over gNMI on the address
+func (sfcCtrlPlugin *SfcControllerPluginHandler) startGnmiServer(address string) error {
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	sfcCtrlPlugin.gnmiServer = gnmi.NewServer(sfcCtrlPlugin.gnmiState, gnmi.DefaultSampleInterval)
+	go func() {
+		if err := sfcCtrlPlugin.gnmiServer.Serve(l); err != nil {
+			log.Errorf("startGnmiServer: serving: '%s': %s", address, err)
+		}
+	}()
+
+	log.Infof("startGnmiServer: serving gNMI on: '%s'", address)
+	return nil
+}
+
+// gnmiTree accumulates the values of the state tree
+type gnmiTree struct {
+	updates []*gnmipb.Update
+	err     error
+}
+
+// add adds the json of the value at the path, the elements are name, key, value, ..., name
+func (tree *gnmiTree) add(value interface{}, elems ...*gnmipb.PathElem) {
+	if tree.err != nil {
+		return
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		tree.err = err
+		return
+	}
+	tree.updates = append(tree.updates, &gnmipb.Update{
+		Path: &gnmipb.Path{Elem: elems},
+		Val:  &gnmipb.TypedValue{Value: &gnmipb.TypedValue_JsonIetfVal{JsonIetfVal: b}},
+	})
+}
+
+func gnmiElem(name string, keyValues ...string) *gnmipb.PathElem {
+	elem := &gnmipb.PathElem{Name: name}
+	if len(keyValues) != 0 {
+		elem.Key = make(map[string]string)
+		for i := 0; i+1 < len(keyValues); i += 2 {
+			elem.Key[keyValues[i]] = keyValues[i+1]
+		}
+	}
+	return elem
+}
+
+// gnmiState returns the state tree, it is read under the http mutex so it is consistent with the REST API
+func (sfcCtrlPlugin *SfcControllerPluginHandler) gnmiState() ([]*gnmipb.Update, error) {
+
+	sfcCtrlPlugin.HttpMutex.Lock()
+	defer sfcCtrlPlugin.HttpMutex.Unlock()
+
+	tree := &gnmiTree{}
+	cache := &sfcCtrlPlugin.ramConfigCache
+
+	tree.add(&cache.SysParms, gnmiElem("system-parameters"))
+	for name, ee := range cache.EEs {
+		ee := ee
+		tree.add(&ee, gnmiElem("external-entities"), gnmiElem("external-entity", "name", name))
+	}
+	for name, he := range cache.HEs {
+		he := he
+		tree.add(&he, gnmiElem("host-entities"), gnmiElem("host-entity", "name", name))
+	}
+	for name, sfc := range cache.SFCs {
+		sfc := sfc
+		tree.add(&sfc, gnmiElem("sfc-entities"), gnmiElem("sfc-entity", "name", name))
+	}
+	for name, ns := range cache.NSs {
+		ns := ns
+		tree.add(&ns, gnmiElem("network-services"), gnmiElem("network-service", "name", name))
+	}
+
+	if err := sfcCtrlPlugin.DatastoreEntityStatusIterate("", func(kind string, status *controller.EntityStatus) {
+		tree.add(status, gnmiElem("render-statuses"),
+			gnmiElem("render-status", "kind", kind, "name", status.Name))
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := sfcCtrlPlugin.gnmiAllocations(tree); err != nil {
+		return nil, err
+	}
+
+	return tree.updates, tree.err
+}
+
+// gnmiAllocations adds the id's allocated to the hosts, their connections and the ports of the chains, as
+// recorded by the driver in the datastore
+func (sfcCtrlPlugin *SfcControllerPluginHandler) gnmiAllocations(tree *gnmiTree) error {
+
+	allocations := gnmiElem("allocations")
+
+	if err := sfcCtrlPlugin.gnmiIterate(l2driver.HEIDsKeyPrefix(), func() proto.Message {
+		return &l2driver.HEIDs{}
+	}, func(msg proto.Message) {
+		ids := msg.(*l2driver.HEIDs)
+		tree.add(ids, allocations, gnmiElem("host", "name", ids.Name))
+	}); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.gnmiIterate(l2driver.HE2EEIDsKeyPrefix(), func() proto.Message {
+		return &l2driver.HE2EEIDs{}
+	}, func(msg proto.Message) {
+		ids := msg.(*l2driver.HE2EEIDs)
+		tree.add(ids, allocations, gnmiElem("host-to-external", "host", ids.HeName, "external", ids.EeName))
+	}); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.gnmiIterate(l2driver.HE2HEIDsKeyPrefix(), func() proto.Message {
+		return &l2driver.HE2HEIDs{}
+	}, func(msg proto.Message) {
+		ids := msg.(*l2driver.HE2HEIDs)
+		tree.add(ids, allocations, gnmiElem("host-to-host", "source", ids.ShName, "destination", ids.DhName))
+	}); err != nil {
+		return err
+	}
+	return sfcCtrlPlugin.gnmiIterate(l2driver.SFCIDsKeyPrefix(), func() proto.Message {
+		return &l2driver.SFCIDs{}
+	}, func(msg proto.Message) {
+		ids := msg.(*l2driver.SFCIDs)
+		tree.add(ids, allocations, gnmiElem("sfc-port", "sfc", ids.SfcName, "container", ids.Container,
+			"port", ids.Port))
+	})
+}
+
+// gnmiIterate decodes each value under the prefix into a new message and calls actionFunc with it
+func (sfcCtrlPlugin *SfcControllerPluginHandler) gnmiIterate(prefix string, newMsg func() proto.Message,
+	actionFunc func(msg proto.Message)) error {
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(prefix)
+	if err != nil {
+		log.Errorf("gnmiIterate: error listing key: '%s': %s", prefix, err)
+		return err
+	}
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return nil
+		}
+		msg := newMsg()
+		if err := kv.GetValue(msg); err != nil {
+			log.Errorf("gnmiIterate: error decoding key: '%s': %s", kv.GetKey(), err)
+			return err
+		}
+		actionFunc(msg)
+	}
+}
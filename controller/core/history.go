@@ -0,0 +1,230 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The entity change history is implemented in this file.  Every change of an
+// ee, he, sfc or the system parameters, whether by a REST POST, a rollback or
+// a restore, is stored under the history tree in etcd with the spec before
+// and after the change, who made it, and when.  Operators use it to line up
+// network incidents with topology changes.
+
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+const defaultChangeHistoryRetained = 50
+
+// the system parameters are a single entity, their changes are stored under this name
+const systemParametersHistoryName = "system"
+
+// changeSource identifies who made a REST change: the X-Changed-By header, if any, and the client address
+func changeSource(req *http.Request) string {
+	if changedBy := req.Header.Get("X-Changed-By"); changedBy != "" {
+		return changedBy + "@" + req.RemoteAddr
+	}
+	return req.RemoteAddr
+}
+
+// entitySpecJSON returns the json of the spec as it is posted via REST
+func entitySpecJSON(spec proto.Message) string {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		log.Error("entitySpecJSON: ", err)
+		return ""
+	}
+	return string(data)
+}
+
+// newEntityChange builds the change of an entity to the new spec, it must be called before the ram cache is
+// updated as the old spec is taken from the ram cache
+func (sfcCtrlPlugin *SfcControllerPluginHandler) newEntityChange(kind string, name string,
+	newSpec proto.Message, source string) *controller.EntityChange {
+
+	change := &controller.EntityChange{
+		Name:    name,
+		Kind:    kind,
+		Source:  source,
+		NewSpec: entitySpecJSON(newSpec),
+	}
+
+	switch kind {
+	case controller.ExternalEntityKind:
+		if ee, exists := sfcCtrlPlugin.ramConfigCache.EEs[name]; exists {
+			change.OldSpec = entitySpecJSON(&ee)
+		}
+	case controller.HostEntityKind:
+		if he, exists := sfcCtrlPlugin.ramConfigCache.HEs[name]; exists {
+			change.OldSpec = entitySpecJSON(&he)
+		}
+	case controller.SfcEntityKind:
+		if sfc, exists := sfcCtrlPlugin.ramConfigCache.SFCs[name]; exists {
+			change.OldSpec = entitySpecJSON(&sfc)
+		}
+	case controller.SystemParametersKind:
+		change.Name = systemParametersHistoryName
+		if sfcCtrlPlugin.ramConfigCache.SysParms.String() != "" {
+			change.OldSpec = entitySpecJSON(&sfcCtrlPlugin.ramConfigCache.SysParms)
+		}
+	}
+	if kind != controller.SystemParametersKind {
+		sfcCtrlPlugin.convergence.entitySubmitted(kind, name)
+	}
+
+	return change
+}
+
+// recordEntityChange stores the change in the entity's history, then prunes the entity's oldest changes
+func (sfcCtrlPlugin *SfcControllerPluginHandler) recordEntityChange(change *controller.EntityChange) error {
+
+	now := time.Now()
+	change.Timestamp = now.Unix()
+
+	key := controller.EntityChangeKey(change.Kind, change.Name, now.UnixNano())
+	log.Infof("recordEntityChange: setting key: '%s', source: '%s'", key, change.Source)
+	if err := sfcCtrlPlugin.db.Put(key, change); err != nil {
+		log.Errorf("recordEntityChange: error storing key: '%s': %s", key, err)
+		return err
+	}
+	sfcCtrlPlugin.emitEntityChangeEvent(change)
+
+	retained := sfcCtrlPlugin.ramConfigCache.SysParms.ChangeHistoryRetained
+	if retained == 0 {
+		retained = defaultChangeHistoryRetained
+	}
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(controller.EntityHistoryKeyPrefix(change.Kind, change.Name))
+	if err != nil {
+		log.Error("recordEntityChange: databroker list: ", err)
+		return err
+	}
+	var keys []string
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			break
+		}
+		keys = append(keys, kv.GetKey())
+	}
+	if len(keys) > int(retained) {
+		sort.Strings(keys)
+		for _, oldKey := range keys[:len(keys)-int(retained)] {
+			if _, err := sfcCtrlPlugin.db.Delete(oldKey); err != nil {
+				log.Errorf("recordEntityChange: error deleting key: '%s': %s", oldKey, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// recordConfigVersionChanges stores a change for every entity that differs between two versions, this is
+// how a rollback or restore, which replace the whole config, show up in the history of each entity
+func (sfcCtrlPlugin *SfcControllerPluginHandler) recordConfigVersionChanges(from *controller.ConfigVersion,
+	to *controller.ConfigVersion, source string) {
+
+	fromSpecs := configVersionEntitySpecs(from)
+	toSpecs := configVersionEntitySpecs(to)
+
+	diff := diffConfigVersions(from, to)
+	entities := append(append(diff.Added, diff.Removed...), diff.Changed...)
+	for _, entity := range entities {
+		kind, name := entity, systemParametersHistoryName
+		if i := strings.Index(entity, "/"); i >= 0 {
+			kind, name = entity[:i], entity[i+1:]
+		}
+		change := &controller.EntityChange{
+			Name:   name,
+			Kind:   kind,
+			Source: source,
+		}
+		if spec, exists := fromSpecs[entity]; exists {
+			change.OldSpec = entitySpecJSON(spec)
+		}
+		if spec, exists := toSpecs[entity]; exists {
+			change.NewSpec = entitySpecJSON(spec)
+		}
+		sfcCtrlPlugin.recordEntityChange(change)
+	}
+}
+
+// configVersionEntitySpecs indexes the entities of a version like configVersionEntityStrings does
+func configVersionEntitySpecs(cv *controller.ConfigVersion) map[string]proto.Message {
+
+	specs := make(map[string]proto.Message)
+
+	if cv.GetSystemParameters() != nil {
+		specs[controller.SystemParametersKind] = cv.GetSystemParameters()
+	}
+	for _, ee := range cv.GetExternalEntities() {
+		specs[controller.ExternalEntityKind+"/"+ee.Name] = ee
+	}
+	for _, he := range cv.GetHostEntities() {
+		specs[controller.HostEntityKind+"/"+he.Name] = he
+	}
+	for _, sfc := range cv.GetSfcEntities() {
+		specs[controller.SfcEntityKind+"/"+sfc.Name] = sfc
+	}
+
+	return specs
+}
+
+// DatastoreEntityHistoryIterate iterates over the changes in the history tree in etcd in time order, an
+// empty kind iterates over the changes of every entity
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreEntityHistoryIterate(kind string, name string,
+	actionFunc func(change *controller.EntityChange)) error {
+
+	prefix := controller.HistoryKeyPrefix()
+	if kind != "" {
+		prefix = controller.EntityHistoryKeyPrefix(kind, name)
+	}
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(prefix)
+	if err != nil {
+		log.Error("DatastoreEntityHistoryIterate: databroker list: ", err)
+		return err
+	}
+
+	var changes []*controller.EntityChange
+	var unixNanos = make(map[*controller.EntityChange]string)
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			break
+		}
+		change := &controller.EntityChange{}
+		if err := kv.GetValue(change); err != nil {
+			log.Error("DatastoreEntityHistoryIterate: bad change: ", kv.GetKey(), err)
+			continue
+		}
+		changes = append(changes, change)
+		unixNanos[change] = kv.GetKey()[strings.LastIndex(kv.GetKey(), "/")+1:]
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return unixNanos[changes[i]] < unixNanos[changes[j]]
+	})
+	for _, change := range changes {
+		actionFunc(change)
+	}
+
+	return nil
+}
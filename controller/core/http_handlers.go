@@ -23,14 +23,26 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
+	"github.com/ligato/sfc-controller/controller/cnpdriver/l2driver"
 	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/eventlog"
+	"github.com/ligato/sfc-controller/controller/utils/faults"
+	"github.com/ligato/sfc-controller/controller/utils/features"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
 	"github.com/unrolled/render"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 const (
-	entityName = "entityName"
+	entityName       = "entityName"
+	entityKind       = "entityKind"
+	elementContainer = "elementContainer"
+	elementPortLabel = "elementPortLabel"
+	interfaceName    = "interfaceName"
 )
 
 var ()
@@ -48,35 +60,124 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) InitHTTPHandlers() {
 		systemParametersHandler, "GET", "POST")
 
 	url := fmt.Sprintf(controller.ExternalEntityKeyPrefix()+"{%s}", entityName)
-	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, externalEntityHandler, "GET", "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, externalEntityHandler, "GET", "POST", "PATCH")
 	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.ExternalEntitiesHTTPPrefix(),
 		externalEntitiesHandler, "GET")
 
 	url = fmt.Sprintf(controller.HostEntityKeyPrefix()+"{%s}", entityName)
-	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, hostEntityHandler, "GET", "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, hostEntityHandler, "GET", "POST", "PATCH")
 	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.HostEntitiesHTTPPrefix(), hostEntitiesHandler, "GET")
 
 	url = fmt.Sprintf(controller.SfcEntityKeyPrefix()+"{%s}", entityName)
-	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, sfcChainHandler, "GET", "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, sfcChainHandler, "GET", "POST", "PATCH", "DELETE")
 	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.SfcEntityHTTPPrefix(), sfcChainsHandler, "GET")
+
+	url = fmt.Sprintf(controller.NetworkServiceKeyPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, networkServiceHandler, "GET", "POST", "PATCH")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.NetworkServicesHTTPPrefix(), networkServicesHandler, "GET")
+
+	url = fmt.Sprintf(controller.SfcTemplateKeyPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, sfcTemplateHandler, "GET", "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.SfcTemplatesHTTPPrefix(), sfcTemplatesHandler, "GET")
+	url = fmt.Sprintf(controller.SfcTemplateInstantiateHTTPPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, sfcTemplateInstantiateHandler, "POST")
+	url = fmt.Sprintf(controller.SfcMigrateHTTPPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, sfcMigrateHandler, "POST")
+	url = fmt.Sprintf(controller.SfcUndeleteHTTPPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, sfcUndeleteHandler, "POST")
+	url = fmt.Sprintf(controller.SfcQuarantineHTTPPrefix()+"{%s}/{%s}/{%s}", entityName, elementContainer,
+		elementPortLabel)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, sfcQuarantineHandler, "POST", "DELETE")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.SfcQuarantineHTTPPrefix(), sfcQuarantinesHandler, "GET")
+
+	url = fmt.Sprintf(controller.ConfigVersionKeyPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, configVersionHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.ConfigVersionsHTTPPrefix(), configVersionsHandler, "GET")
+	url = fmt.Sprintf(controller.ConfigRollbackHTTPPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, configRollbackHandler, "POST")
+
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.BackupHTTPPrefix(), backupHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.RestoreHTTPPrefix(), restoreHandler, "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.BulkHTTPPrefix(), bulkHandler, "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.ReconcileHTTPPrefix(), reconcileHandler, "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.DrainHTTPPrefix(), drainHandler, "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.DriverHTTPPrefix(), driverHandler, "GET", "POST")
+
+	url = fmt.Sprintf(controller.ScheduledChangeKeyPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, scheduledChangeHandler, "GET", "POST", "DELETE")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.ScheduledChangeKeyPrefix(), scheduledChangesHandler, "GET")
+
+	url = fmt.Sprintf(controller.WiringPolicyKeyPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, wiringPolicyHandler, "GET", "POST", "DELETE")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.WiringPoliciesHTTPPrefix(), wiringPoliciesHandler, "GET")
+
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.LogHTTPPrefix(), logConfigHandler, "GET", "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.FeaturesHTTPPrefix(), featuresHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.FaultsHTTPPrefix(), faultsHandler, "GET", "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.CachesHTTPPrefix(), cachesHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.ShadowHTTPPrefix(), shadowHandler, "GET", "DELETE")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.MirrorHTTPPrefix(), mirrorHandler, "GET", "POST")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.VerifyHTTPPrefix(), verifyHandler, "GET")
+	url = fmt.Sprintf(controller.BandwidthHTTPPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, bandwidthHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.BandwidthHTTPPrefix(), bandwidthHandler, "GET")
+	url = fmt.Sprintf(controller.ResourcesHTTPPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, resourcesHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.ResourcesHTTPPrefix(), resourcesHandler, "GET")
+	url = fmt.Sprintf(controller.InterfaceOwnerHTTPPrefix()+"{%s}/{%s}", entityName, interfaceName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, interfaceOwnerHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.ConvergenceHTTPPrefix(), convergenceHandler, "GET")
+	url = fmt.Sprintf(controller.DriverStateHTTPPrefix()+"{%s}", entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, driverStateHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.AllocatedIDsHTTPPrefix(), allocatedIDsHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.ForensicLookupHTTPPrefix(), forensicLookupHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.FederationHTTPPrefix(), federationHandler, "GET", "POST")
+
+	url = fmt.Sprintf(controller.EntityKeysKeyPrefix()+"{%s}/{%s}", entityKind, entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, entityKeysHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.EntityKeysKeyPrefix(), keyOwnersHandler, "GET")
+
+	url = fmt.Sprintf(controller.StatusKeyPrefix()+"{%s}/{%s}", entityKind, entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, entityStatusHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.StatusKeyPrefix(), entityStatusesHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.EventLogHTTPPrefix(), eventLogHandler, "GET")
+
+	url = fmt.Sprintf(controller.HistoryKeyPrefix()+"{%s}/{%s}", entityKind, entityName)
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(url, entityHistoryHandler, "GET")
+	sfcCtrlPlugin.HTTPmux.RegisterHTTPHandler(controller.HistoryKeyPrefix(), historyHandler, "GET")
 }
 
-// Example curl invocations: for obtaining ALL external_entities
+// Example curl invocations: for obtaining ALL external_entities, optionally filtered and paged, see list.go
 //   - GET:  curl -v http://localhost:9191/sfc_controller/api/v1/config/EEs
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/EEs?status=RENDER_ERROR&offset=0&limit=50
 func externalEntitiesHandler(formatter *render.Render) http.HandlerFunc {
 
-	sfcplg.HttpMutex.Lock()
-	defer sfcplg.HttpMutex.Unlock()
-
 	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
 		log.Debugf("External Entities HTTP handler: Method %s, URL: %s, sfcPlugin", req.Method, req.URL, sfcplg)
 
-		var eeArray = make([]controller.ExternalEntity, 0)
-		for _, ee := range sfcplg.ramConfigCache.EEs {
-			eeArray = append(eeArray, ee)
-		}
 		switch req.Method {
 		case "GET":
+			q, err := parseListQuery(req, listFilterSelector, listFilterStatus)
+			if err == nil {
+				err = q.loadStates(controller.ExternalEntityKind)
+			}
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var names []string
+			for name, ee := range sfcplg.ramConfigCache.EEs {
+				if q.matchEntity(name, ee.Labels) {
+					names = append(names, name)
+				}
+			}
+			var eeArray = make([]controller.ExternalEntity, 0)
+			for _, name := range q.page(w, names) {
+				eeArray = append(eeArray, sfcplg.ramConfigCache.EEs[name])
+			}
 			formatter.JSON(w, http.StatusOK, eeArray)
 			return
 		}
@@ -86,12 +187,13 @@ func externalEntitiesHandler(formatter *render.Render) http.HandlerFunc {
 // Example curl invocations: for obtaining a provided external entity
 //   - GET:  curl -X GET http://localhost:9191/sfc_controller/api/v1/EE/<entityName>
 //   - POST: curl -v -X POST -d '{"counter":30}' http://localhost:9191/example/test
+//   - PATCH: curl -v -X PATCH -d '{"mgmnt_ip_address":"10.0.0.2"}' http://localhost:9191/sfc-controller/v1/EE/<entityName>
 func externalEntityHandler(formatter *render.Render) http.HandlerFunc {
 
-	sfcplg.HttpMutex.Lock()
-	defer sfcplg.HttpMutex.Unlock()
-
 	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
 		log.Debugf("External Entity HTTP handler: Method %s, URL: %s", req.Method, req.URL)
 		switch req.Method {
 		case "GET":
@@ -105,6 +207,18 @@ func externalEntityHandler(formatter *render.Render) http.HandlerFunc {
 			return
 		case "POST":
 			processExternalEntityPost(formatter, w, req)
+		case "PATCH":
+			vars := mux.Vars(req)
+			ee, exists := sfcplg.ramConfigCache.EEs[vars[entityName]]
+			if !exists {
+				formatter.JSON(w, http.StatusNotFound, "external entity does not found:"+vars[entityName])
+				return
+			}
+			if err := patchRequestBody(req, &ee); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			processExternalEntityPost(formatter, w, req)
 		}
 	}
 }
@@ -143,37 +257,60 @@ func processExternalEntityPost(formatter *render.Render, w http.ResponseWriter,
 		return
 	}
 
+	change := sfcplg.newEntityChange(controller.ExternalEntityKind, ee.Name, &ee, changeSource(req))
+
 	sfcplg.ramConfigCache.EEs[vars[entityName]] = ee
 
 	if err := sfcplg.DatastoreExternalEntityCreate(&ee); err != nil {
 		formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
 		return
 	}
+	sfcplg.recordEntityChange(change)
 
-	if err := sfcplg.renderExternalEntity(&ee, true, true); err != nil {
+	err = sfcplg.renderExternalEntity(&ee, true, true)
+	sfcplg.entityStatusFlush()
+	if err != nil {
 		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
 		return
 	}
 
+	sfcplg.snapshotConfigVersion("POST EE/" + ee.Name)
+
 	formatter.JSON(w, http.StatusOK, "OK")
 }
 
-// Example curl invocations: for obtaining ALL host_entities
+// Example curl invocations: for obtaining ALL host_entities, optionally filtered and paged, see list.go
 //   - GET:  curl -v http://localhost:9191/sfc_controller/api/v1/config/HEs
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/HEs?status=AGENT_PENDING&limit=50
+//   - GET:  curl -v 'http://localhost:9191/sfc-controller/v1/HEs?selector=rack=A3,!maintenance'
 func hostEntitiesHandler(formatter *render.Render) http.HandlerFunc {
 
-	sfcplg.HttpMutex.Lock()
-	defer sfcplg.HttpMutex.Unlock()
-
 	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
 		log.Debugf("Host Entities HTTP handler: Method %s, URL: %s", req.Method, req.URL)
 
-		var heArray = make([]controller.HostEntity, 0)
-		for _, he := range sfcplg.ramConfigCache.HEs {
-			heArray = append(heArray, he)
-		}
 		switch req.Method {
 		case "GET":
+			q, err := parseListQuery(req, listFilterHost, listFilterSelector, listFilterStatus)
+			if err == nil {
+				err = q.loadStates(controller.HostEntityKind)
+			}
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var names []string
+			for name, he := range sfcplg.ramConfigCache.HEs {
+				if (q.host == "" || name == q.host) && q.matchEntity(name, he.Labels) {
+					names = append(names, name)
+				}
+			}
+			var heArray = make([]controller.HostEntity, 0)
+			for _, name := range q.page(w, names) {
+				heArray = append(heArray, sfcplg.ramConfigCache.HEs[name])
+			}
 			formatter.JSON(w, http.StatusOK, heArray)
 			return
 		}
@@ -183,12 +320,13 @@ func hostEntitiesHandler(formatter *render.Render) http.HandlerFunc {
 // Example curl invocations: for obtaining a provided host_entity
 //   - GET:  curl -v http://localhost:9191/sfc_controller/api/v1/config/HEs/<hostName>
 //   - POST: curl -v -X POST -d '{"counter":30}' http://localhost:9191/example/test
+//   - PATCH: curl -v -X PATCH -d '{"mtu":9000}' http://localhost:9191/sfc-controller/v1/HE/<hostName>
 func hostEntityHandler(formatter *render.Render) http.HandlerFunc {
 
-	sfcplg.HttpMutex.Lock()
-	defer sfcplg.HttpMutex.Unlock()
-
 	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
 		log.Debugf("Host Entity HTTP handler: Method %s, URL: %s", req.Method, req.URL)
 
 		switch req.Method {
@@ -202,6 +340,18 @@ func hostEntityHandler(formatter *render.Render) http.HandlerFunc {
 			return
 		case "POST":
 			processHostEntityPost(formatter, w, req)
+		case "PATCH":
+			vars := mux.Vars(req)
+			he, exists := sfcplg.ramConfigCache.HEs[vars[entityName]]
+			if !exists {
+				formatter.JSON(w, http.StatusNotFound, "host entity does not found:"+vars[entityName])
+				return
+			}
+			if err := patchRequestBody(req, &he); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			processHostEntityPost(formatter, w, req)
 		}
 	}
 }
@@ -240,38 +390,72 @@ func processHostEntityPost(formatter *render.Render, w http.ResponseWriter, req
 		}
 	}
 
+	change := sfcplg.newEntityChange(controller.HostEntityKind, he.Name, &he, changeSource(req))
+
+	// the host's labels may select it into, or out of, the wiring policies
+	wiring := sfcplg.wiringPolicyWiring()
+
 	sfcplg.ramConfigCache.HEs[vars[entityName]] = he
 
 	if err := sfcplg.DatastoreHostEntityCreate(&he); err != nil {
 		formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
 		return
 	}
+	sfcplg.recordEntityChange(change)
 
-	if err := sfcplg.renderHostEntity(&he, true, true); err != nil {
+	err = sfcplg.renderHostEntity(&he, true, true)
+	sfcplg.entityStatusFlush()
+	if err != nil {
 		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
 		return
 	}
+	if _, existed := wiring[he.Name]; existed {
+		if err := sfcplg.wiringPolicyReconcile(wiring); err != nil {
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return
+		}
+	}
+
+	sfcplg.snapshotConfigVersion("POST HE/" + he.Name)
 
 	formatter.JSON(w, http.StatusOK, "OK")
 }
 
-// Example curl invocations: for obtaining ALL host_entities
+// Example curl invocations: for obtaining ALL host_entities, optionally filtered and paged, see list.go
 //   - GET:  curl -v http://localhost:9191/sfc_controller/api/v1/config/SFCs
+//   - GET:  curl -v 'http://localhost:9191/sfc-controller/v1/SFCs?host=<host>&tenant=<tenant>&label=<key>=<value>'
+//   - GET:  curl -v 'http://localhost:9191/sfc-controller/v1/SFCs?status=RENDER_ERROR&offset=100&limit=100'
+//   - GET:  curl -v 'http://localhost:9191/sfc-controller/v1/SFCs?selector=tier=gold'
 //   - POST: not supported
 func sfcChainsHandler(formatter *render.Render) http.HandlerFunc {
 
-	sfcplg.HttpMutex.Lock()
-	defer sfcplg.HttpMutex.Unlock()
-
 	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
 		log.Debugf("SFC Chains HTTP handler: Method %s, URL: %s", req.Method, req.URL)
 
-		var sfcArray = make([]controller.SfcEntity, 0)
-		for _, sfc := range sfcplg.ramConfigCache.SFCs {
-			sfcArray = append(sfcArray, sfc)
-		}
 		switch req.Method {
 		case "GET":
+			q, err := parseListQuery(req, listFilterHost, listFilterTenant, listFilterLabel, listFilterSelector,
+				listFilterStatus)
+			if err == nil {
+				err = q.loadStates(controller.SfcEntityKind)
+			}
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var names []string
+			for name, sfc := range sfcplg.ramConfigCache.SFCs {
+				if q.matchSfc(&sfc) {
+					names = append(names, name)
+				}
+			}
+			var sfcArray = make([]controller.SfcEntity, 0)
+			for _, name := range q.page(w, names) {
+				sfcArray = append(sfcArray, sfcplg.ramConfigCache.SFCs[name])
+			}
 			formatter.JSON(w, http.StatusOK, sfcArray)
 			return
 		}
@@ -281,12 +465,15 @@ func sfcChainsHandler(formatter *render.Render) http.HandlerFunc {
 // Example curl invocations: for obtaining a provided host_entity
 //   - GET:  curl -v http://localhost:9191/sfc_controller/api/v1/config/SFCs/<chainName>
 //   - POST: curl -v -X POST -d '{"counter":30}' http://localhost:9191/example/test
+//   - PATCH: curl -v -X PATCH -d '{"description":"web tier"}' http://localhost:9191/sfc-controller/v1/SFC/<chainName>
+//   - DELETE: curl -v -X DELETE http://localhost:9191/sfc-controller/v1/SFC/<chainName>?grace=600
+//   - DELETE: curl -v -X DELETE http://localhost:9191/sfc-controller/v1/SFC/<chainName>?confirm=true
 func sfcChainHandler(formatter *render.Render) http.HandlerFunc {
 
-	sfcplg.HttpMutex.Lock()
-	defer sfcplg.HttpMutex.Unlock()
-
 	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
 		log.Debugf("SFC Chain HTTP handler: Method %s, URL: %s", req.Method, req.URL)
 
 		switch req.Method {
@@ -300,6 +487,95 @@ func sfcChainHandler(formatter *render.Render) http.HandlerFunc {
 			return
 		case "POST":
 			processSfcChainPost(formatter, w, req)
+		case "PATCH":
+			vars := mux.Vars(req)
+			sfc, exists := sfcplg.ramConfigCache.SFCs[vars[entityName]]
+			if !exists {
+				formatter.JSON(w, http.StatusNotFound, "sfc chain does not fouind:"+vars[entityName])
+				return
+			}
+			if err := patchRequestBody(req, &sfc); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			processSfcChainPost(formatter, w, req)
+		case "DELETE":
+			processSfcChainDelete(formatter, w, req)
+		}
+	}
+}
+
+// mark the chain deleting, or unwire and delete a chain marked deleting if the deletion is confirmed, see
+// sfc_soft_delete.go
+func processSfcChainDelete(formatter *render.Render, w http.ResponseWriter, req *http.Request) {
+
+	sfcName := mux.Vars(req)[entityName]
+	if _, exists := sfcplg.ramConfigCache.SFCs[sfcName]; !exists {
+		formatter.JSON(w, http.StatusNotFound, "sfc chain does not fouind:"+sfcName)
+		return
+	}
+
+	if confirm := req.URL.Query().Get("confirm"); confirm != "" {
+		confirmed, err := strconv.ParseBool(confirm)
+		if err != nil {
+			formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{
+				fmt.Sprintf("Invalid confirm: '%s'", confirm)})
+			return
+		}
+		if confirmed {
+			if err := sfcplg.confirmSfcDelete(sfcName); err != nil {
+				formatter.JSON(w, http.StatusConflict, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, "OK")
+			return
+		}
+	}
+
+	var grace *uint32
+	if graceStr := req.URL.Query().Get("grace"); graceStr != "" {
+		secs, err := strconv.ParseUint(graceStr, 10, 32)
+		if err != nil {
+			formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{
+				fmt.Sprintf("Invalid grace: '%s'", graceStr)})
+			return
+		}
+		graceSeconds := uint32(secs)
+		grace = &graceSeconds
+	}
+	marked, err := sfcplg.markSfcDeleting(sfcName, grace, time.Now().Unix(), changeSource(req))
+	if err != nil {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+	if marked {
+		sfcplg.snapshotConfigVersion("DELETE SFC/" + sfcName + " marked")
+	}
+	formatter.JSON(w, http.StatusAccepted, sfcplg.ramConfigCache.SFCs[sfcName])
+}
+
+// Example curl invocations: for cancelling the deletion of a chain that is still in its grace period
+//   - POST: curl -v -X POST http://localhost:9191/sfc-controller/v1/SFCUndelete/<chainName>
+func sfcUndeleteHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("SFC Undelete HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "POST":
+			sfcName := mux.Vars(req)[entityName]
+			cancelled, err := sfcplg.cancelSfcDelete(sfcName, changeSource(req))
+			if err != nil {
+				formatter.JSON(w, http.StatusNotFound, struct{ Error string }{err.Error()})
+				return
+			}
+			if cancelled {
+				sfcplg.snapshotConfigVersion("POST SFCUndelete/" + sfcName)
+			}
+			formatter.JSON(w, http.StatusOK, "OK")
 		}
 	}
 }
@@ -331,6 +607,20 @@ func processSfcChainPost(formatter *render.Render, w http.ResponseWriter, req *h
 		return
 	}
 
+	if existing, exists := sfcplg.ramConfigCache.SFCs[vars[entityName]]; exists && existing.NetworkService != sfc.NetworkService {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{
+			"sfc belongs to network service: " + existing.NetworkService})
+		return
+	}
+
+	previous := sfcplg.ramConfigCache.SFCs[vars[entityName]]
+	stampSfcExpiry(&sfc, &previous, time.Now().Unix())
+	stampSfcDeleting(&sfc, &previous)
+	if err := validateSfcDeletingChange(&sfc, &previous); err != nil {
+		formatter.JSON(w, http.StatusConflict, struct{ Error string }{err.Error()})
+		return
+	}
+
 	if existing, exists := sfcplg.ramConfigCache.SFCs[vars[entityName]]; exists {
 		// convert to string and compare ...
 		if sfc.String() == existing.String() {
@@ -338,76 +628,1731 @@ func processSfcChainPost(formatter *render.Render, w http.ResponseWriter, req *h
 			return
 		}
 		// re-POSTing, need to handle the changes ...
+		if sfc.BlueGreenCutover {
+			change := sfcplg.newEntityChange(controller.SfcEntityKind, sfc.Name, &sfc, changeSource(req))
+			if err := sfcplg.renderServiceFunctionEntityBlueGreen(&existing, &sfc); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			if err := sfcplg.DatastoreSfcEntityCreate(&sfc); err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			sfcplg.recordEntityChange(change)
+			sfcplg.snapshotConfigVersion("POST SFC/" + sfc.Name + " blue/green")
+			formatter.JSON(w, http.StatusOK, "OK")
+			return
+		}
 	}
 
+	change := sfcplg.newEntityChange(controller.SfcEntityKind, sfc.Name, &sfc, changeSource(req))
+
 	sfcplg.ramConfigCache.SFCs[vars[entityName]] = sfc
 
 	if err := sfcplg.DatastoreSfcEntityCreate(&sfc); err != nil {
 		formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
 		return
 	}
+	sfcplg.recordEntityChange(change)
 
-	if err := sfcplg.renderServiceFunctionEntity(&sfc); err != nil {
+	err = sfcplg.renderServiceFunctionEntity(&sfc)
+	if err == nil {
+		err = sfcplg.renderPendingSFCs()
+	}
+	sfcplg.entityStatusFlush()
+	if err != nil {
 		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
 		return
 	}
 
+	sfcplg.snapshotConfigVersion("POST SFC/" + sfc.Name)
+
 	formatter.JSON(w, http.StatusOK, "OK")
 }
 
-// Example curl invocations: for obtaining the system parameters
-//   - GET:  curl -X GET http://localhost:9191/sfc_controller/api/v1/SP
-//   - POST: curl -v -X POST -d '{"mtu":1500}' http://localhost:9191/sfc_controller/api/v1/SP
-func systemParametersHandler(formatter *render.Render) http.HandlerFunc {
+// Example curl invocations: for moving a chain's elements to another host
+//   - POST: curl -v -X POST -d '{"sfc":"<chainName>","from_host":"<host1>","to_host":"<host2>"}'
+//           http://localhost:9191/sfc_controller/api/v1/config/SFCMigrate/<chainName>
+func sfcMigrateHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("SFC Migrate HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "POST":
+			processSfcMigratePost(formatter, w, req)
+		}
+	}
+}
+
+// re-target the chain's elements and cut the chain over to the new host
+func processSfcMigratePost(formatter *render.Render, w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.Debugf("Can't read body, error '%s'", err)
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+	var m controller.SfcMigration
+	err = json.Unmarshal(body, &m)
+	if err != nil {
+		log.Debugf("Can't parse body, error '%s'", err)
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
 
-	sfcplg.HttpMutex.Lock()
-	defer sfcplg.HttpMutex.Unlock()
+	vars := mux.Vars(req)
+	if vars[entityName] != m.Sfc {
+		formatter.JSON(w, http.StatusBadRequest, "json sfc does not matach url name")
+		return
+	}
+
+	migrated, err := sfcplg.migratedSfc(&m)
+	if err != nil {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+	change := sfcplg.newEntityChange(controller.SfcEntityKind, m.Sfc, migrated, changeSource(req))
+
+	// the chain stays on the old host if the agents do not accept the new host's wiring
+	existing := sfcplg.ramConfigCache.SFCs[m.Sfc]
+	if err := sfcplg.renderServiceFunctionEntityBlueGreen(&existing, migrated); err != nil {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+	if err := sfcplg.DatastoreSfcEntityCreate(migrated); err != nil {
+		formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+		return
+	}
+	sfcplg.recordEntityChange(change)
+	sfcplg.snapshotConfigVersion("POST SFCMigrate/" + m.Sfc + " " + m.FromHost + " to " + m.ToHost)
+
+	formatter.JSON(w, http.StatusOK, "OK")
+}
+
+// Example curl invocations: for disabling a chain element's i/f's, and enabling them again
+//   - POST:   curl -v -X POST http://localhost:9191/sfc-controller/v1/SFCQuarantine/<chainName>/<container>/<portLabel>
+//   - DELETE: curl -v -X DELETE http://localhost:9191/sfc-controller/v1/SFCQuarantine/<chainName>/<container>/<portLabel>
+func sfcQuarantineHandler(formatter *render.Render) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, req *http.Request) {
-		log.Debugf("System Parameters HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("SFC Quarantine HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		vars := mux.Vars(req)
+		adminDown := req.Method == "POST"
+
+		switch req.Method {
+		case "POST", "DELETE":
+			changed, err := sfcplg.quarantineSfcElement(vars[entityName], vars[elementContainer],
+				vars[elementPortLabel], adminDown, changeSource(req))
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			if changed {
+				sfcplg.snapshotConfigVersion(req.Method + " SFCQuarantine/" + vars[entityName] + "/" +
+					vars[elementContainer] + "/" + vars[elementPortLabel])
+			}
+			formatter.JSON(w, http.StatusOK, "OK")
+		}
+	}
+}
+
+// Example curl invocations: for obtaining the quarantined chain elements
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/SFCQuarantine/
+func sfcQuarantinesHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("SFC Quarantines HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
 		switch req.Method {
 		case "GET":
+			formatter.JSON(w, http.StatusOK, sfcplg.quarantinedElements())
+		}
+	}
+}
 
-			formatter.JSON(w, http.StatusOK, sfcplg.ramConfigCache.SysParms)
+// Example curl invocations: for obtaining ALL network services, optionally filtered and paged, see list.go
+//   - GET:  curl -v http://localhost:9191/sfc_controller/api/v1/config/NSs
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/NSs?tenant=<tenant>&limit=20
+//   - POST: not supported
+func networkServicesHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Network Services HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			q, err := parseListQuery(req, listFilterHost, listFilterTenant)
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var names []string
+			for name, ns := range sfcplg.ramConfigCache.NSs {
+				if q.matchNS(&ns) {
+					names = append(names, name)
+				}
+			}
+			var nsArray = make([]controller.NetworkService, 0)
+			for _, name := range q.page(w, names) {
+				nsArray = append(nsArray, sfcplg.ramConfigCache.NSs[name])
+			}
+			formatter.JSON(w, http.StatusOK, nsArray)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for obtaining a provided network service
+//   - GET:  curl -v http://localhost:9191/sfc_controller/api/v1/config/NS/<serviceName>
+//   - POST: curl -v -X POST -d @ns.json http://localhost:9191/sfc_controller/api/v1/config/NS/<serviceName>
+//   - PATCH: curl -v -X PATCH -d '{"tenant":"t2"}' http://localhost:9191/sfc-controller/v1/NS/<serviceName>
+func networkServiceHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Network Service HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			vars := mux.Vars(req)
+			if ns, exists := sfcplg.ramConfigCache.NSs[vars[entityName]]; exists {
+				formatter.JSON(w, http.StatusOK, ns)
+			} else {
+				formatter.JSON(w, http.StatusNotFound, "network service not found: "+vars[entityName])
+			}
 			return
 		case "POST":
-			processSystemParametersPost(formatter, w, req)
+			processNetworkServicePost(formatter, w, req)
+		case "PATCH":
+			vars := mux.Vars(req)
+			ns, exists := sfcplg.ramConfigCache.NSs[vars[entityName]]
+			if !exists {
+				formatter.JSON(w, http.StatusNotFound, "network service not found: "+vars[entityName])
+				return
+			}
+			if err := patchRequestBody(req, &ns); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			processNetworkServicePost(formatter, w, req)
 		}
 	}
 }
 
-// create the system parameters
-func processSystemParametersPost(formatter *render.Render, w http.ResponseWriter, req *http.Request) {
+// expand the service into its sfc's and wire the ones that changed
+func processNetworkServicePost(formatter *render.Render, w http.ResponseWriter, req *http.Request) {
 	body, err := ioutil.ReadAll(req.Body)
 	if err != nil {
 		log.Debugf("Can't read body, error '%s'", err)
 		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
 		return
 	}
-	var sp controller.SystemParameters
-	err = json.Unmarshal(body, &sp)
+	var ns controller.NetworkService
+	err = json.Unmarshal(body, &ns)
 	if err != nil {
 		log.Debugf("Can't parse body, error '%s'", err)
 		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
 		return
 	}
 
-	if err := sfcplg.validateSystemParameters(&sp); err != nil {
+	if err := sfcplg.validateNetworkService(&ns); err != nil {
 		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
 		return
 	}
 
-	sfcplg.ramConfigCache.SysParms = sp
+	vars := mux.Vars(req)
+	if vars[entityName] != ns.Name {
+		formatter.JSON(w, http.StatusBadRequest, "json name does not matach url name")
+		return
+	}
 
-	if err := sfcplg.DatastoreSystemParametersCreate(&sp); err != nil {
-		formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+	if existing, exists := sfcplg.ramConfigCache.NSs[ns.Name]; exists && ns.String() == existing.String() {
+		formatter.JSON(w, http.StatusOK, "OK")
 		return
 	}
 
-	if err := sfcplg.renderSystemParameters(&sp); err != nil {
-		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+	if !processSfcChainsChange(formatter, w, req, expandNetworkService(&ns), func() error {
+		sfcplg.ramConfigCache.NSs[ns.Name] = ns
+		return sfcplg.DatastoreNetworkServiceCreate(&ns)
+	}) {
 		return
 	}
 
+	sfcplg.snapshotConfigVersion("POST NS/" + ns.Name)
+
 	formatter.JSON(w, http.StatusOK, "OK")
-}
\ No newline at end of file
+}
+
+// store and wire the chains of a network service or a template that changed, the entity they are expanded
+// from is stored by storeFunc before them, the response is written if it fails
+func processSfcChainsChange(formatter *render.Render, w http.ResponseWriter, req *http.Request,
+	sfcs []controller.SfcEntity, storeFunc func() error) bool {
+
+	changed := make(map[string]controller.SfcEntity)
+	for _, sfc := range sfcs {
+		existing, exists := sfcplg.ramConfigCache.SFCs[sfc.Name]
+		stampSfcDeleting(&sfc, &existing)
+		if err := validateSfcDeletingChange(&sfc, &existing); err != nil {
+			formatter.JSON(w, http.StatusConflict, struct{ Error string }{err.Error()})
+			return false
+		}
+		if !exists || sfc.String() != existing.String() {
+			changed[sfc.Name] = sfc
+		}
+	}
+	order, err := sfcRenderOrder(changed)
+	if err != nil {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return false
+	}
+
+	changes := make(map[string]*controller.EntityChange)
+	for _, sfcName := range order {
+		sfc := changed[sfcName]
+		changes[sfcName] = sfcplg.newEntityChange(controller.SfcEntityKind, sfc.Name, &sfc, changeSource(req))
+	}
+
+	if err := storeFunc(); err != nil {
+		formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+		return false
+	}
+
+	for _, sfcName := range order {
+		sfc := changed[sfcName]
+		sfcplg.ramConfigCache.SFCs[sfcName] = sfc
+		if err := sfcplg.DatastoreSfcEntityCreate(&sfc); err != nil {
+			formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+			return false
+		}
+		sfcplg.recordEntityChange(changes[sfcName])
+		if err = sfcplg.renderServiceFunctionEntity(&sfc); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = sfcplg.renderPendingSFCs()
+	}
+	sfcplg.entityStatusFlush()
+	if err != nil {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return false
+	}
+
+	return true
+}
+
+// Example curl invocations: for obtaining ALL sfc templates, optionally paged, see list.go
+//   - GET:  curl -v http://localhost:9191/sfc_controller/api/v1/config/SFCTemplates
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/SFCTemplates?offset=20&limit=20
+//   - POST: not supported
+func sfcTemplatesHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("SFC Templates HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			q, err := parseListQuery(req)
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var tplArray = make([]controller.SfcTemplate, 0)
+			for _, name := range q.page(w, sortedKeysTPL(sfcplg.ramConfigCache.TPLs)) {
+				tplArray = append(tplArray, sfcplg.ramConfigCache.TPLs[name])
+			}
+			formatter.JSON(w, http.StatusOK, tplArray)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for obtaining a provided sfc template, a POST of a changed template is
+// propagated to its instances
+//   - GET:  curl -v http://localhost:9191/sfc_controller/api/v1/config/SFCTemplate/<templateName>
+//   - POST: curl -v -X POST -d @tpl.json http://localhost:9191/sfc_controller/api/v1/config/SFCTemplate/<templateName>
+func sfcTemplateHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("SFC Template HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			vars := mux.Vars(req)
+			if tpl, exists := sfcplg.ramConfigCache.TPLs[vars[entityName]]; exists {
+				formatter.JSON(w, http.StatusOK, tpl)
+			} else {
+				formatter.JSON(w, http.StatusNotFound, "sfc template not found: "+vars[entityName])
+			}
+			return
+		case "POST":
+			processSfcTemplatePost(formatter, w, req)
+		}
+	}
+}
+
+// store the template and re-render its instances that changed
+func processSfcTemplatePost(formatter *render.Render, w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.Debugf("Can't read body, error '%s'", err)
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+	var tpl controller.SfcTemplate
+	err = json.Unmarshal(body, &tpl)
+	if err != nil {
+		log.Debugf("Can't parse body, error '%s'", err)
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+
+	if err := validateSfcTemplate(&tpl); err != nil {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+
+	vars := mux.Vars(req)
+	if vars[entityName] != tpl.Name {
+		formatter.JSON(w, http.StatusBadRequest, "json name does not matach url name")
+		return
+	}
+
+	if existing, exists := sfcplg.ramConfigCache.TPLs[tpl.Name]; exists && tpl.String() == existing.String() {
+		formatter.JSON(w, http.StatusOK, "OK")
+		return
+	}
+
+	sfcs, err := sfcplg.reinstantiateSfcTemplate(&tpl)
+	if err != nil {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+
+	if !processSfcChainsChange(formatter, w, req, sfcs, func() error {
+		sfcplg.ramConfigCache.TPLs[tpl.Name] = tpl
+		return sfcplg.DatastoreSfcTemplateCreate(&tpl)
+	}) {
+		return
+	}
+
+	sfcplg.snapshotConfigVersion("POST SFCTemplate/" + tpl.Name)
+
+	formatter.JSON(w, http.StatusOK, "OK")
+}
+
+// Example curl invocations: for instantiating a provided sfc template
+//   - POST: curl -v -X POST -d '{"template":"<templateName>","count":4}'
+//           http://localhost:9191/sfc_controller/api/v1/config/SFCTemplateInstantiate/<templateName>
+func sfcTemplateInstantiateHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("SFC Template Instantiate HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "POST":
+			processSfcTemplateInstantiatePost(formatter, w, req)
+		}
+	}
+}
+
+// expand the template into its new instances and wire them
+func processSfcTemplateInstantiatePost(formatter *render.Render, w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.Debugf("Can't read body, error '%s'", err)
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+	var inst controller.SfcTemplateInstantiation
+	err = json.Unmarshal(body, &inst)
+	if err != nil {
+		log.Debugf("Can't parse body, error '%s'", err)
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+
+	vars := mux.Vars(req)
+	if vars[entityName] != inst.Template {
+		formatter.JSON(w, http.StatusBadRequest, "json template does not matach url name")
+		return
+	}
+
+	sfcs, err := sfcplg.instantiateSfcTemplate(&inst)
+	if err != nil {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+
+	if !processSfcChainsChange(formatter, w, req, sfcs, func() error { return nil }) {
+		return
+	}
+
+	sfcplg.snapshotConfigVersion(fmt.Sprintf("POST SFCTemplateInstantiate/%s count %d", inst.Template,
+		inst.Count))
+
+	formatter.JSON(w, http.StatusOK, "OK")
+}
+
+// Example curl invocations: for obtaining the system parameters
+//   - GET:  curl -X GET http://localhost:9191/sfc_controller/api/v1/SP
+//   - POST: curl -v -X POST -d '{"mtu":1500}' http://localhost:9191/sfc_controller/api/v1/SP
+func systemParametersHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("System Parameters HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+		switch req.Method {
+		case "GET":
+
+			formatter.JSON(w, http.StatusOK, sfcplg.ramConfigCache.SysParms)
+			return
+		case "POST":
+			processSystemParametersPost(formatter, w, req)
+		}
+	}
+}
+
+// create the system parameters
+func processSystemParametersPost(formatter *render.Render, w http.ResponseWriter, req *http.Request) {
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		log.Debugf("Can't read body, error '%s'", err)
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+	var sp controller.SystemParameters
+	err = json.Unmarshal(body, &sp)
+	if err != nil {
+		log.Debugf("Can't parse body, error '%s'", err)
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+
+	if err := sfcplg.validateSystemParameters(&sp); err != nil {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+
+	change := sfcplg.newEntityChange(controller.SystemParametersKind, "", &sp, changeSource(req))
+
+	prev := sfcplg.ramConfigCache.SysParms
+	sfcplg.ramConfigCache.SysParms = sp
+
+	if err := sfcplg.DatastoreSystemParametersCreate(&sp); err != nil {
+		formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+		return
+	}
+	sfcplg.recordEntityChange(change)
+
+	if err := sfcplg.renderSystemParameters(&sp); err != nil {
+		formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+		return
+	}
+	if err := sfcplg.reloadCNPDriverForSystemParameters(&prev, &sp); err != nil {
+		formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+		return
+	}
+
+	sfcplg.snapshotConfigVersion("POST SP")
+
+	formatter.JSON(w, http.StatusOK, "OK")
+}
+// Example curl invocations: for obtaining the list of stored config versions
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/versions
+func configVersionsHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Config Versions HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		// only the version headers are listed, GET a version for its entities
+		var cvArray = make([]controller.ConfigVersion, 0)
+		sfcplg.DatastoreConfigVersionIterate(func(cv *controller.ConfigVersion) {
+			cvArray = append(cvArray, controller.ConfigVersion{
+				Version:     cv.Version,
+				Timestamp:   cv.Timestamp,
+				Description: cv.Description,
+			})
+		})
+		switch req.Method {
+		case "GET":
+			formatter.JSON(w, http.StatusOK, cvArray)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for obtaining a stored config version
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/version/<version>
+func configVersionHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Config Version HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			vars := mux.Vars(req)
+			version, err := strconv.ParseUint(vars[entityName], 10, 32)
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			cv, err := sfcplg.DatastoreConfigVersionRetrieve(uint32(version))
+			if err != nil {
+				formatter.JSON(w, http.StatusNotFound, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, cv)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for rolling the config back to a stored version
+//   - POST: curl -v -X POST http://localhost:9191/sfc-controller/v1/rollback/<version>
+func configRollbackHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Config Rollback HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "POST":
+			vars := mux.Vars(req)
+			version, err := strconv.ParseUint(vars[entityName], 10, 32)
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			diff, err := sfcplg.rollbackToConfigVersion(uint32(version))
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, diff)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for archiving the sfc tree in etcd
+//   - GET:  curl -o sfc-backup.json http://localhost:9191/sfc-controller/v1/backup
+func backupHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Backup HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			// the archive is streamed, so once it has started, errors can only be logged
+			w.Header().Set("Content-Type", "application/json")
+			sfcplg.DatastoreBackup(w)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for restoring the sfc tree in etcd from an archive
+//   - POST: curl -v -X POST --data-binary @sfc-backup.json http://localhost:9191/sfc-controller/v1/restore
+func restoreHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Restore HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "POST":
+			count, err := sfcplg.restoreAndRebuild(req.Body)
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, struct{ Restored int }{count})
+			return
+		}
+	}
+}
+
+// Example curl invocations: for applying a batch of ee's, he's and sfc's, either all of them or none
+//   - POST: curl -v -X POST --data-binary @topology.json http://localhost:9191/sfc-controller/v1/bulk
+func bulkHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Bulk HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "POST":
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				log.Debugf("Can't read body, error '%s'", err)
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var batch BulkApply
+			if err := json.Unmarshal(body, &batch); err != nil {
+				log.Debugf("Can't parse body, error '%s'", err)
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			result, err := sfcplg.bulkApply(&batch, changeSource(req))
+			if err != nil && !result.Applied {
+				// the results tell which entities failed
+				log.Errorf("Bulk HTTP handler: batch not applied: %s", err)
+				formatter.JSON(w, http.StatusBadRequest, result)
+				return
+			}
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, result)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for re-rendering the chains whose labels match a selector, see selector.go
+//   - POST: curl -v -X POST 'http://localhost:9191/sfc-controller/v1/reconcile?selector=tier=gold'
+func reconcileHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Reconcile HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "POST":
+			ls, err := parseLabelSelector(req.URL.Query().Get(listFilterSelector))
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			result, err := sfcplg.reconcileSelectedSfcs(ls)
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, result)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the running cnp driver, and for reloading it, or switching to another one,
+// without a restart, see driver_reload.go
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/driver
+//   - POST: curl -v -X POST 'http://localhost:9191/sfc-controller/v1/driver?name=sfcctlrxconn'
+func driverHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Driver HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			info, err := sfcplg.driverInfo()
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, info)
+			return
+		case "POST":
+			result, err := sfcplg.reloadCNPDriver(req.URL.Query().Get("name"), "POST "+changeSource(req))
+			if err != nil && result == nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, result)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for migrating the containers off the hosts whose labels match a selector
+//   - POST: curl -v -X POST 'http://localhost:9191/sfc-controller/v1/drain?selector=rack=A3&to_host=<hostName>'
+func drainHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Drain HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "POST":
+			ls, err := parseLabelSelector(req.URL.Query().Get(listFilterSelector))
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			result, err := sfcplg.drainSelectedHosts(ls, req.URL.Query().Get("to_host"), changeSource(req))
+			if err != nil && result == nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, result)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the changes scheduled into maintenance windows, paged, see scheduled.go
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/scheduled/
+func scheduledChangesHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Scheduled Changes HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			q, err := parseListQuery(req)
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var names []string
+			for name := range sfcplg.scheduledChanges {
+				names = append(names, name)
+			}
+			var scArray = make([]*controller.ScheduledChange, 0)
+			for _, name := range q.page(w, names) {
+				scArray = append(scArray, sfcplg.scheduledChanges[name])
+			}
+			formatter.JSON(w, http.StatusOK, scArray)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for scheduling a batch of ee's, he's and sfc's into a maintenance window
+//   - GET:    curl -v http://localhost:9191/sfc-controller/v1/scheduled/<changeName>
+//   - POST:   curl -v -X POST -d '{"name":"<changeName>","window_start":1767225600,"window_end":1767229200,
+//                 "sfc_entities":[...]}' http://localhost:9191/sfc-controller/v1/scheduled/<changeName>
+//   - DELETE: curl -v -X DELETE http://localhost:9191/sfc-controller/v1/scheduled/<changeName>
+func scheduledChangeHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Scheduled Change HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		vars := mux.Vars(req)
+		switch req.Method {
+		case "GET":
+			if sc, exists := sfcplg.scheduledChanges[vars[entityName]]; exists {
+				formatter.JSON(w, http.StatusOK, sc)
+			} else {
+				formatter.JSON(w, http.StatusNotFound, "scheduled change not found: "+vars[entityName])
+			}
+			return
+		case "POST":
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				log.Debugf("Can't read body, error '%s'", err)
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var sc controller.ScheduledChange
+			if err := json.Unmarshal(body, &sc); err != nil {
+				log.Debugf("Can't parse body, error '%s'", err)
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			if vars[entityName] != sc.Name {
+				formatter.JSON(w, http.StatusBadRequest, "json name does not matach url name")
+				return
+			}
+			sc.SubmittedBy = changeSource(req)
+			result, err := sfcplg.scheduleChange(&sc, time.Now().Unix())
+			if err != nil && result != nil {
+				// the results tell which entities are invalid
+				formatter.JSON(w, http.StatusBadRequest, result)
+				return
+			}
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, &sc)
+			return
+		case "DELETE":
+			if _, exists := sfcplg.scheduledChanges[vars[entityName]]; !exists {
+				formatter.JSON(w, http.StatusNotFound, "scheduled change not found: "+vars[entityName])
+				return
+			}
+			if err := sfcplg.DatastoreScheduledChangeDelete(vars[entityName]); err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, "OK")
+			return
+		}
+	}
+}
+
+// Example curl invocations: for obtaining ALL wiring policies, paged, see list.go
+//   - GET:  curl -v http://localhost:9191/sfc_controller/api/v1/config/WiringPolicies
+//   - POST: not supported
+func wiringPoliciesHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Wiring Policies HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			q, err := parseListQuery(req)
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var wpArray = make([]controller.WiringPolicy, 0)
+			for _, name := range q.page(w, sortedKeysWP(sfcplg.ramConfigCache.WPs)) {
+				wpArray = append(wpArray, sfcplg.ramConfigCache.WPs[name])
+			}
+			formatter.JSON(w, http.StatusOK, wpArray)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for a wiring policy, a POST or DELETE rewires the hosts whose ee's change,
+// see wiring_policy.go
+//   - GET:    curl -v http://localhost:9191/sfc_controller/api/v1/config/WiringPolicy/<policyName>
+//   - POST:   curl -v -X POST -d '{"name":"<policyName>","host_selector":"edge=true","external_entities":["core-rtr-1"]}'
+//                 http://localhost:9191/sfc_controller/api/v1/config/WiringPolicy/<policyName>
+//   - DELETE: curl -v -X DELETE http://localhost:9191/sfc_controller/api/v1/config/WiringPolicy/<policyName>
+func wiringPolicyHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Wiring Policy HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		vars := mux.Vars(req)
+		switch req.Method {
+		case "GET":
+			if wp, exists := sfcplg.ramConfigCache.WPs[vars[entityName]]; exists {
+				formatter.JSON(w, http.StatusOK, wp)
+			} else {
+				formatter.JSON(w, http.StatusNotFound, "wiring policy not found: "+vars[entityName])
+			}
+			return
+		case "POST":
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				log.Debugf("Can't read body, error '%s'", err)
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var wp controller.WiringPolicy
+			if err := json.Unmarshal(body, &wp); err != nil {
+				log.Debugf("Can't parse body, error '%s'", err)
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			if err := validateWiringPolicy(&wp); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			if vars[entityName] != wp.Name {
+				formatter.JSON(w, http.StatusBadRequest, "json name does not matach url name")
+				return
+			}
+			if existing, exists := sfcplg.ramConfigCache.WPs[wp.Name]; exists && wp.String() == existing.String() {
+				formatter.JSON(w, http.StatusOK, "OK")
+				return
+			}
+			if err := sfcplg.applyWiringPolicy(wp.Name, &wp); err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			sfcplg.snapshotConfigVersion("POST WiringPolicy/" + wp.Name)
+			formatter.JSON(w, http.StatusOK, "OK")
+			return
+		case "DELETE":
+			if _, exists := sfcplg.ramConfigCache.WPs[vars[entityName]]; !exists {
+				formatter.JSON(w, http.StatusNotFound, "wiring policy not found: "+vars[entityName])
+				return
+			}
+			if err := sfcplg.applyWiringPolicy(vars[entityName], nil); err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			sfcplg.snapshotConfigVersion("DELETE WiringPolicy/" + vars[entityName])
+			formatter.JSON(w, http.StatusOK, "OK")
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the log level of each subsystem, and the log format
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/log
+//   - POST: curl -v -X POST -d '{"levels":{"sfc-driver":"info"},"format":"json"}' http://localhost:9191/sfc-controller/v1/log
+func logConfigHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Log config HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			formatter.JSON(w, http.StatusOK, logs.GetLogConfig())
+			return
+		case "POST":
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				log.Debugf("Can't read body, error '%s'", err)
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var lc logs.LogConfig
+			if err := json.Unmarshal(body, &lc); err != nil {
+				log.Debugf("Can't parse body, error '%s'", err)
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			if err := logs.SetLogConfig(&lc); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, logs.GetLogConfig())
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the fault injection settings, used to exercise the controller's resilience in CI,
+// a POST is refused unless the controller was started with -faults
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/faults
+//   - POST: curl -v -X POST -d '{"put_error_rate":0.1,"ee_latency_ms":500}' http://localhost:9191/sfc-controller/v1/faults
+//   - POST: curl -v -X POST -d '{}' http://localhost:9191/sfc-controller/v1/faults
+func faultsHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Faults HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			formatter.JSON(w, http.StatusOK, faults.GetConfig())
+			return
+		case "POST":
+			if faultsFile == "" {
+				formatter.JSON(w, http.StatusForbidden,
+					struct{ Error string }{"fault injection is only settable when the controller runs with -faults"})
+				return
+			}
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				log.Debugf("Can't read body, error '%s'", err)
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var fc faults.Config
+			if err := json.Unmarshal(body, &fc); err != nil {
+				log.Debugf("Can't parse body, error '%s'", err)
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			if err := faults.SetConfig(&fc); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			log.Warnf("Faults HTTP handler: fault injection set to: %+v", fc)
+			formatter.JSON(w, http.StatusOK, faults.GetConfig())
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the registered feature flags, and whether they are enabled for the deployment
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/features
+func featuresHandler(formatter *render.Render) http.HandlerFunc {
+
+	type featureStatus struct {
+		features.Feature
+		Enabled bool `json:"enabled"`
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Features HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			var list []featureStatus
+			for _, feature := range features.List() {
+				list = append(list, featureStatus{
+					Feature: feature,
+					Enabled: features.Enabled(feature.Name, sfcplg.ramConfigCache.SysParms.FeatureFlags),
+				})
+			}
+			formatter.JSON(w, http.StatusOK, list)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the number of entries of the controller's and the driver's caches
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/caches
+func cachesHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Caches HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			formatter.JSON(w, http.StatusOK, sfcplg.cacheSizes())
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the agent writes withheld in shadow mode, the last one of each key, see -shadow
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/shadow
+//   - DELETE: curl -v -X DELETE http://localhost:9191/sfc-controller/v1/shadow
+func shadowHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Shadow HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		if sfcplg.shadowJournal == nil {
+			formatter.JSON(w, http.StatusNotFound, struct{ Error string }{"the controller is not in shadow mode"})
+			return
+		}
+
+		switch req.Method {
+		case "GET":
+			formatter.JSON(w, http.StatusOK, sfcplg.shadowJournal.Writes())
+			return
+		case "DELETE":
+			sfcplg.shadowJournal.Reset()
+			formatter.JSON(w, http.StatusOK, "OK")
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the mirror of the datastore to a standby site, see -mirror-etcd-config
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/mirror
+//   - POST: curl -v -X POST 'http://localhost:9191/sfc-controller/v1/mirror?action=promote'
+//   - POST: curl -v -X POST 'http://localhost:9191/sfc-controller/v1/mirror?action=resync'
+func mirrorHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Mirror HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		if sfcplg.mirror == nil {
+			formatter.JSON(w, http.StatusNotFound, struct{ Error string }{"the datastore is not mirrored"})
+			return
+		}
+
+		switch req.Method {
+		case "GET":
+			formatter.JSON(w, http.StatusOK, sfcplg.mirror.Stats())
+			return
+		case "POST":
+			switch action := req.URL.Query().Get("action"); action {
+			case "promote":
+				stats, err := sfcplg.promoteMirror()
+				if err != nil {
+					formatter.JSON(w, http.StatusServiceUnavailable, struct{ Error string }{err.Error()})
+					return
+				}
+				formatter.JSON(w, http.StatusOK, stats)
+			case "resync":
+				sfcplg.mirror.Resync()
+				formatter.JSON(w, http.StatusOK, sfcplg.mirror.Stats())
+			default:
+				formatter.JSON(w, http.StatusBadRequest,
+					struct{ Error string }{fmt.Sprintf("Invalid action: '%s', expected: promote or resync", action)})
+			}
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the bandwidth the chains commit on the host uplinks, and what is left
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/bandwidth/
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/bandwidth/<host name>
+func bandwidthHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Bandwidth HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			heName := mux.Vars(req)[entityName]
+			if _, exists := sfcplg.ramConfigCache.HEs[heName]; heName != "" && !exists {
+				formatter.JSON(w, http.StatusNotFound, "host entity does not found:"+heName)
+				return
+			}
+			formatter.JSON(w, http.StatusOK, sfcplg.bandwidthReport(heName))
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the memifs, bridge domains and i/f's rendered on the hosts, and their limits
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/resources/
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/resources/<host name>
+func resourcesHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Resources HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			heName := mux.Vars(req)[entityName]
+			if _, exists := sfcplg.ramConfigCache.HEs[heName]; heName != "" && !exists {
+				formatter.JSON(w, http.StatusNotFound, "host entity does not found:"+heName)
+				return
+			}
+			resources, err := sfcplg.hostResources(heName)
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, resources)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the entities, and the chain element, an i/f of a host or container is rendered for
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/interface-owner/<host name>/IF_MEMIF_VSWITCH_vnf1_port1
+func interfaceOwnerHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Interface Owner HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			vars := mux.Vars(req)
+			owner, err := sfcplg.interfaceOwner(vars[entityName], vars[interfaceName])
+			if err != nil {
+				formatter.JSON(w, http.StatusNotFound, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, owner)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the percentiles of the time the entities of each kind take to render, and to
+// be confirmed by the agents, once submitted
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/convergence
+func convergenceHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Convergence HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			formatter.JSON(w, http.StatusOK, sfcplg.convergence.report())
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the vxlan's, bridges, routes and addresses the driver holds for an entity
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/driver-state/<entity name>
+func driverStateHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Driver State HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			state, err := sfcplg.GetEntityState(mux.Vars(req)[entityName])
+			if err != nil {
+				formatter.JSON(w, http.StatusNotFound, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, state)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the ids allocated by the driver, optionally of a host and/or of a chain
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/allocated-ids
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/allocated-ids?host=<host name>&sfc=<sfc name>
+func allocatedIDsHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Allocated IDs HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			heName := req.URL.Query().Get("host")
+			if _, exists := sfcplg.ramConfigCache.HEs[heName]; heName != "" && !exists {
+				formatter.JSON(w, http.StatusNotFound, struct{ Error string }{"host entity not found: " + heName})
+				return
+			}
+			sfcName := req.URL.Query().Get("sfc")
+			if _, exists := sfcplg.ramConfigCache.SFCs[sfcName]; sfcName != "" && !exists {
+				formatter.JSON(w, http.StatusNotFound, struct{ Error string }{"sfc entity not found: " + sfcName})
+				return
+			}
+			ids, err := sfcplg.GetAllocatedIDs(heName, sfcName)
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, ids)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the chains, elements, containers and hosts an observed vni, mac or ip belongs to
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/forensic-lookup?vni=<vni>
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/forensic-lookup?mac=<mac>&ip=<ipv4 or ipv6>
+func forensicLookupHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Forensic lookup HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			query := &l2driver.ForensicQuery{
+				Mac: req.URL.Query().Get("mac"),
+				IP:  req.URL.Query().Get("ip"),
+			}
+			if vni := req.URL.Query().Get("vni"); vni != "" {
+				n, err := strconv.ParseUint(vni, 10, 32)
+				if err != nil || n == 0 {
+					formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{"Invalid vni: " + vni})
+					return
+				}
+				query.Vni = uint32(n)
+			}
+			if query.Mac != "" {
+				if _, err := net.ParseMAC(query.Mac); err != nil {
+					formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{"Invalid mac: " + query.Mac})
+					return
+				}
+			}
+			if query.IP != "" && net.ParseIP(query.IP) == nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{"Invalid ip: " + query.IP})
+				return
+			}
+			if query.Vni == 0 && query.Mac == "" && query.IP == "" {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{"Missing vni, mac or ip"})
+				return
+			}
+			matches, err := sfcplg.ForensicLookup(query)
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, matches)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the records of this site for its federation peers, and for the exchange of a
+// peer's record, the peer is answered this site's record once the peer's is applied, see federation.go
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/federation/
+//   - POST: curl -v -X POST -d '{"site":"site-b","peer":"site-a","host":"gw1","tunnel_endpoint":"10.1.0.1",
+//     "vni":5001}' http://localhost:9191/sfc-controller/v1/federation/
+func federationHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Federation HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			formatter.JSON(w, http.StatusOK, sfcplg.federationRecords())
+		case "POST":
+			body, err := ioutil.ReadAll(req.Body)
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			var record FederationRecord
+			if err := json.Unmarshal(body, &record); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			if _, err := sfcplg.applyFederationRecord(&record, changeSource(req)); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			local, err := sfcplg.federationRecord(record.Site)
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, local)
+		}
+	}
+}
+
+// Example curl invocations: for cross checking the caches, the id records and the keys of the agents
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/verify
+func verifyHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Verify HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			report, err := sfcplg.verifyConsistency()
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, report)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for obtaining the vpp-agent keys rendered for an entity, kind is EE, HE or SFC
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/keys/SFC/<entityName>
+func entityKeysHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Entity Keys HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			vars := mux.Vars(req)
+			entityKeys, err := sfcplg.DatastoreEntityKeysRetrieve(vars[entityKind], vars[entityName])
+			if err != nil {
+				formatter.JSON(w, http.StatusNotFound, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, entityKeys)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for obtaining the keys of every entity, or the entities that rendered a key
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/keys/
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/keys/?key=/vnf-agent/<label>/vpp/config/v1/...
+func keyOwnersHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Key Owners HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			agentKey := req.URL.Query().Get("key")
+			owners := make(map[string][]string) // kind/name -> keys
+			err := sfcplg.DatastoreEntityKeysIterate(func(entity string, entityKeys *controller.EntityKeys) {
+				if agentKey == "" {
+					owners[entity] = entityKeys.Keys
+					return
+				}
+				for _, key := range entityKeys.Keys {
+					if key == agentKey {
+						owners[entity] = []string{key}
+						return
+					}
+				}
+			})
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, owners)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for obtaining the render status of an entity, kind is EE, HE or SFC
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/status/SFC/<entityName>
+func entityStatusHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Entity Status HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			vars := mux.Vars(req)
+			status := &controller.EntityStatus{}
+			found, _, err := sfcplg.db.GetValue(controller.EntityStatusKey(vars[entityKind], vars[entityName]),
+				status)
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			if !found {
+				formatter.JSON(w, http.StatusNotFound, "entity status not found: "+vars[entityKind]+"/"+
+					vars[entityName])
+				return
+			}
+			formatter.JSON(w, http.StatusOK, status)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for obtaining the render statuses, optionally filtered and paged, see list.go
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/status/
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/status/?kind=SFC&status=RENDER_ERROR&limit=100
+func entityStatusesHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Entity Statuses HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			q, err := parseListQuery(req, listFilterKind, listFilterStatus)
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			statuses := make(map[string]*EntityStatusEntry) // kind/name -> status
+			var names []string
+			err = sfcplg.DatastoreEntityStatusIterate(q.kind, func(kind string, status *controller.EntityStatus) {
+				if q.status == "" || status.State.String() == q.status {
+					statuses[kind+"/"+status.Name] = &EntityStatusEntry{Kind: kind, EntityStatus: status}
+					names = append(names, kind+"/"+status.Name)
+				}
+			})
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			var statusArray = make([]*EntityStatusEntry, 0)
+			for _, name := range q.page(w, names) {
+				statusArray = append(statusArray, statuses[name])
+			}
+			formatter.JSON(w, http.StatusOK, statusArray)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for the recent events, the repeats of an event are counted on it, see events.go
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/status/events
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/status/events?type=drift&since=<unix time>&limit=100
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/status/events?kind=SFC&name=<entityName>
+func eventLogHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Event Log HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			if sfcplg.eventLog == nil {
+				formatter.JSON(w, http.StatusServiceUnavailable, struct{ Error string }{"the event log is not started"})
+				return
+			}
+			values := req.URL.Query()
+			filter := eventlog.Filter{
+				Type: values.Get("type"),
+				Kind: values.Get("kind"),
+				Name: values.Get("name"),
+			}
+			since, err := listQueryInt(values.Get("since"))
+			if err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			filter.Since = int64(since)
+			if filter.Limit, err = listQueryInt(values.Get("limit")); err != nil {
+				formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, struct {
+				Stats  eventlog.Stats   `json:"stats"`
+				Events []eventlog.Entry `json:"events"`
+			}{sfcplg.eventLog.Stats(), sfcplg.eventLog.Query(filter)})
+			return
+		}
+	}
+}
+
+// Example curl invocations: for obtaining the changes of an entity, kind is EE, HE, SFC or SP (name system)
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/history/SFC/<entityName>
+func entityHistoryHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("Entity History HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			vars := mux.Vars(req)
+			var changes = make([]*controller.EntityChange, 0)
+			err := sfcplg.DatastoreEntityHistoryIterate(vars[entityKind], vars[entityName],
+				func(change *controller.EntityChange) {
+					changes = append(changes, change)
+				})
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, changes)
+			return
+		}
+	}
+}
+
+// Example curl invocations: for obtaining the changes of every entity in time order, optionally since a unix time
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/history/
+//   - GET:  curl -v http://localhost:9191/sfc-controller/v1/history/?since=1510000000
+func historyHandler(formatter *render.Render) http.HandlerFunc {
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		sfcplg.HttpMutex.Lock()
+		defer sfcplg.HttpMutex.Unlock()
+
+		log.Debugf("History HTTP handler: Method %s, URL: %s", req.Method, req.URL)
+
+		switch req.Method {
+		case "GET":
+			var since int64
+			if sinceStr := req.URL.Query().Get("since"); sinceStr != "" {
+				var err error
+				if since, err = strconv.ParseInt(sinceStr, 10, 64); err != nil {
+					formatter.JSON(w, http.StatusBadRequest, struct{ Error string }{err.Error()})
+					return
+				}
+			}
+			var changes = make([]*controller.EntityChange, 0)
+			err := sfcplg.DatastoreEntityHistoryIterate("", "", func(change *controller.EntityChange) {
+				if change.Timestamp >= since {
+					changes = append(changes, change)
+				}
+			})
+			if err != nil {
+				formatter.JSON(w, http.StatusInternalServerError, struct{ Error string }{err.Error()})
+				return
+			}
+			formatter.JSON(w, http.StatusOK, changes)
+			return
+		}
+	}
+}
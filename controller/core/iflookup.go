@@ -0,0 +1,136 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The reverse lookup of an agent i/f is implemented in this file.  An i/f
+// seen on a vswitch, or in a container, ie IF_MEMIF_VSWITCH_vnf1_port1 on
+// host vswitch1, is mapped back to the entities whose rendered keys hold it,
+// and for a chain to the element it was rendered for, with the element's
+// metadata, ie its tenant.  The i/f's the driver does not own, written by
+// hand or by another controller, are reported as such.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils"
+)
+
+// InterfaceOwner is the agent key of an i/f, the entities that rendered it, and the chain element it is for
+type InterfaceOwner struct {
+	Host      string            `json:"host"`
+	Interface string            `json:"interface"`
+	Key       string            `json:"key"`
+	Owned     bool              `json:"owned"`
+	Entities  []string          `json:"entities"`
+	Sfc       string            `json:"sfc,omitempty"`
+	Container string            `json:"container,omitempty"`
+	PortLabel string            `json:"port_label,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+// interfaceOwner returns who owns the i/f of the host, the vpp i/f's are looked up before the linux ones
+func (sfcCtrlPlugin *SfcControllerPluginHandler) interfaceOwner(host string, ifName string) (*InterfaceOwner,
+	error) {
+
+	keys := []string{utils.InterfaceKey(host, ifName), utils.LinuxInterfaceKey(host, ifName)}
+
+	entities := make(map[string][]string) // key -> kind/name
+	err := sfcCtrlPlugin.DatastoreEntityKeysIterate(func(entity string, entityKeys *controller.EntityKeys) {
+		for _, key := range entityKeys.Keys {
+			if key == keys[0] || key == keys[1] {
+				entities[key] = append(entities[key], entity)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	owner := &InterfaceOwner{Host: host, Interface: ifName, Entities: make([]string, 0)}
+	for _, key := range keys {
+		exists := len(entities[key]) != 0
+		if !exists {
+			if exists, err = sfcCtrlPlugin.agentKeyExists(key); err != nil {
+				return nil, err
+			}
+		}
+		if exists {
+			owner.Key = key
+			owner.Entities = append(owner.Entities, entities[key]...)
+			break
+		}
+	}
+	if owner.Key == "" {
+		return nil, fmt.Errorf("Invalid interface lookup, i/f: '%s' of host: '%s' not found", ifName, host)
+	}
+	owner.Owned = sfcCtrlPlugin.cnpDriverPlugin.OwnsAgentKey(owner.Key)
+	sort.Strings(owner.Entities)
+
+	for _, entity := range owner.Entities {
+		if !strings.HasPrefix(entity, controller.SfcEntityKind+"/") {
+			continue
+		}
+		sfc, exists := sfcCtrlPlugin.ramConfigCache.SFCs[strings.TrimPrefix(entity, controller.SfcEntityKind+"/")]
+		if !exists {
+			continue
+		}
+		if sfcElement := sfcElementOfInterface(&sfc, host, ifName); sfcElement != nil {
+			owner.Sfc = sfc.Name
+			owner.Container = sfcElement.Container
+			owner.PortLabel = sfcElement.PortLabel
+			owner.Metadata = sfcElement.Metadata
+			break
+		}
+	}
+
+	return owner, nil
+}
+
+// sfcElementOfInterface returns the chain's element the i/f was rendered for, the container end is named after
+// the port label and the vswitch end ends with the container and port label, nil if it is none of them
+func sfcElementOfInterface(sfc *controller.SfcEntity, host string, ifName string) *controller.SfcEntity_SfcElement {
+
+	for _, sfcElement := range sfc.GetElements() {
+		if host == sfcElement.Container && ifName == sfcElement.PortLabel {
+			return sfcElement
+		}
+		if host == sfcElement.EtcdVppSwitchKey &&
+			strings.HasSuffix(ifName, "_"+sfcElement.Container+"_"+sfcElement.PortLabel) {
+			return sfcElement
+		}
+	}
+	return nil
+}
+
+// agentKeyExists is whether the key is in an agent's tree
+func (sfcCtrlPlugin *SfcControllerPluginHandler) agentKeyExists(key string) (bool, error) {
+
+	ki, err := sfcCtrlPlugin.db.ListKeys(key)
+	if err != nil {
+		return false, err
+	}
+	for {
+		k, _, allReceived := ki.GetNext()
+		if allReceived {
+			return false, nil
+		}
+		if k == key {
+			return true, nil
+		}
+	}
+}
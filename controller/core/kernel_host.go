@@ -0,0 +1,101 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The validation of the kernel hosts is implemented in this file.  A kernel
+// host is a linux only host, it runs the agent without vpp so none of the
+// vswitch config of a host applies to it.  The chains are free to mix vpp
+// and kernel hosts, but only their non vpp afpacket containers, which are
+// terminated into veths, can be placed on a kernel host, by hand or by the
+// placement scheduler.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// validateKernelHE checks a kernel host has none of the config rendered into the vpp of a host
+func validateKernelHE(he *controller.HostEntity) error {
+
+	if he.HostType != controller.HostType_HOST_KERNEL {
+		return nil
+	}
+	for _, vppConfig := range []struct {
+		name string
+		set  bool
+	}{
+		{"loopback_ipv4", he.LoopbackIpv4 != ""},
+		{"loopback_ipv6", he.LoopbackIpv6 != ""},
+		{"loopback_mac_addr", he.LoopbackMacAddr != ""},
+		{"loopbacks", len(he.GetLoopbacks()) != 0},
+		{"loopback_tunnel_endpoints", he.LoopbackTunnelEndpoints},
+		{"vxlan_tunnel_ipv4", he.VxlanTunnelIpv4 != ""},
+		{"vxlan_tunnel_ipv6", he.VxlanTunnelIpv6 != ""},
+		{"create_vxlan_static_route", he.CreateVxlanStaticRoute},
+		{"uplinks", len(he.GetUplinks()) != 0},
+		{"peer_uplinks", len(he.GetPeerUplinks()) != 0},
+		{"gateway", he.Gateway},
+		{"gateway_host", he.GatewayHost != ""},
+		{"rx_mode", he.RxMode != controller.RxModeType_RX_MODE_UNKNOWN},
+	} {
+		if vppConfig.set {
+			return fmt.Errorf("Invalid %s for he: '%s', a kernel host has no vpp", vppConfig.name, he.Name)
+		}
+	}
+	return nil
+}
+
+// kernelHE is whether the host is configured as a kernel host
+func (sfcCtrlPlugin *SfcControllerPluginHandler) kernelHE(heName string) bool {
+	he, exists := sfcCtrlPlugin.ramConfigCache.HEs[heName]
+	return exists && he.HostType == controller.HostType_HOST_KERNEL
+}
+
+// sfcContainersKernelPlaceable is whether every element of the containers is terminated into a veth, so they
+// can be placed on a kernel host
+func sfcContainersKernelPlaceable(sfc *controller.SfcEntity, containers map[string]bool) bool {
+	for _, sfcElement := range sfc.GetElements() {
+		if containers[sfcElement.Container] && sfcElementIsContainer(sfcElement) &&
+			(sfcElement.Type != controller.SfcElementType_NON_VPP_CONTAINER_AFP || sfcElement.Unnumbered) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateSfcKernelHosts checks the elements the chain has on kernel hosts are non vpp afpacket containers
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSfcKernelHosts(sfc *controller.SfcEntity) error {
+
+	for _, sfcElement := range sfc.GetElements() {
+		heName := sfcElement.EtcdVppSwitchKey
+		if sfcElement.Type == controller.SfcElementType_HOST_ENTITY {
+			heName = sfcElement.Container
+		}
+		if sfcElement.Type == controller.SfcElementType_EXTERNAL_ENTITY || !sfcCtrlPlugin.kernelHE(heName) {
+			continue
+		}
+		if sfcElement.Type != controller.SfcElementType_NON_VPP_CONTAINER_AFP {
+			return fmt.Errorf("Invalid type: '%s' for element: '%s/%s', sfc: '%s', only a non vpp afpacket "+
+				"container is wired on kernel he: '%s'", sfcElement.Type, sfcElement.Container,
+				sfcElement.PortLabel, sfc.Name, heName)
+		}
+		if sfcElement.Unnumbered {
+			return fmt.Errorf("Invalid unnumbered for element: '%s/%s', sfc: '%s', kernel he: '%s' has no "+
+				"loopback to borrow the address of", sfcElement.Container, sfcElement.PortLabel, sfc.Name, heName)
+		}
+	}
+	return nil
+}
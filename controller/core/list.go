@@ -0,0 +1,219 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The filtering and the paging of the list urls are implemented in this
+// file.  A list is filtered with query parameters: host, tenant, label (a
+// key=value matched against the metadata of the chains' elements, repeated
+// for several labels), selector (matched against the labels of the entities,
+// see selector.go) and status (the render state of the entity).  Each list
+// only accepts the filters that apply to its entities.  The entries are
+// listed in name order, offset and limit select a page of them, and the
+// total number of matching entries is answered in the X-Total-Count header,
+// along with X-Next-Offset when there are more.
+
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// the list filters
+const (
+	listFilterHost     = "host"
+	listFilterTenant   = "tenant"
+	listFilterLabel    = "label"
+	listFilterStatus   = "status"
+	listFilterKind     = "kind"
+	listFilterSelector = "selector"
+)
+
+// listQuery is the filters and the page of a list request
+type listQuery struct {
+	host     string
+	tenant   string
+	labels   map[string]string
+	status   string // a render state name, ie RENDERED
+	kind     string
+	selector labelSelector
+	offset   int
+	limit    int // 0 lists every entry from the offset
+
+	states map[string]controller.RenderStateType // the states of the kind's entities, if status is filtered
+}
+
+// EntityStatusEntry is an entry of the status list
+type EntityStatusEntry struct {
+	Kind string `json:"kind"`
+	*controller.EntityStatus
+}
+
+// parseListQuery parses the query parameters of a list request, only the filters given are accepted
+func parseListQuery(req *http.Request, filters ...string) (*listQuery, error) {
+
+	values := req.URL.Query()
+	q := &listQuery{labels: make(map[string]string)}
+
+	accepted := make(map[string]bool)
+	for _, filter := range filters {
+		accepted[filter] = true
+	}
+	for _, filter := range []string{listFilterHost, listFilterTenant, listFilterLabel, listFilterStatus,
+		listFilterKind, listFilterSelector} {
+		if _, exists := values[filter]; exists && !accepted[filter] {
+			return nil, fmt.Errorf("Invalid filter: '%s' is not supported by this list", filter)
+		}
+	}
+
+	q.host = values.Get(listFilterHost)
+	q.tenant = values.Get(listFilterTenant)
+	q.kind = values.Get(listFilterKind)
+	for _, label := range values[listFilterLabel] {
+		kv := strings.SplitN(label, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("Invalid label: '%s', must be key=value", label)
+		}
+		q.labels[kv[0]] = kv[1]
+	}
+	var err error
+	if q.selector, err = parseLabelSelector(values.Get(listFilterSelector)); err != nil {
+		return nil, err
+	}
+	if q.status = values.Get(listFilterStatus); q.status != "" {
+		if _, exists := controller.RenderStateType_value[q.status]; !exists {
+			return nil, fmt.Errorf("Invalid status: '%s'", q.status)
+		}
+	}
+
+	if q.offset, err = listQueryInt(values.Get("offset")); err != nil {
+		return nil, err
+	}
+	if q.limit, err = listQueryInt(values.Get("limit")); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func listQueryInt(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	i, err := strconv.Atoi(value)
+	if err != nil || i < 0 {
+		return 0, fmt.Errorf("Invalid offset or limit: '%s'", value)
+	}
+	return i, nil
+}
+
+// loadStates loads the render states of the entities of the kind when the list is filtered by status
+func (q *listQuery) loadStates(kind string) error {
+
+	if q.status == "" {
+		return nil
+	}
+	q.states = make(map[string]controller.RenderStateType)
+	return sfcplg.DatastoreEntityStatusIterate(kind, func(kind string, status *controller.EntityStatus) {
+		q.states[status.Name] = status.State
+	})
+}
+
+// matchStatus is whether the entity rendered in the state filtered
+func (q *listQuery) matchStatus(name string) bool {
+	if q.status == "" {
+		return true
+	}
+	state, exists := q.states[name]
+	return exists && state.String() == q.status
+}
+
+// matchEntity is whether the entity has the labels the selector requires, and rendered in the state filtered
+func (q *listQuery) matchEntity(name string, labels map[string]string) bool {
+	return q.selector.matches(labels) && q.matchStatus(name)
+}
+
+// matchSfc is whether the chain has an element on the host, of the tenant, and the labels
+func (q *listQuery) matchSfc(sfc *controller.SfcEntity) bool {
+
+	if q.host != "" && !sfcOnHost(sfc, q.host) {
+		return false
+	}
+	if q.tenant != "" && !sfcHasMetadata(sfc, "tenant", q.tenant) {
+		return false
+	}
+	for key, value := range q.labels {
+		if !sfcHasMetadata(sfc, key, value) {
+			return false
+		}
+	}
+	return q.matchEntity(sfc.Name, sfc.Labels)
+}
+
+// matchNS is whether the network service is of the tenant, and has a chain on the host
+func (q *listQuery) matchNS(ns *controller.NetworkService) bool {
+
+	if q.tenant != "" && ns.Tenant != q.tenant {
+		return false
+	}
+	if q.host == "" || ns.EtcdVppSwitchKey == q.host {
+		return true
+	}
+	for i := range ns.SfcEntities {
+		if sfcOnHost(ns.SfcEntities[i], q.host) {
+			return true
+		}
+	}
+	return false
+}
+
+func sfcOnHost(sfc *controller.SfcEntity, host string) bool {
+	for _, sfcElement := range sfc.GetElements() {
+		if sfcElement.EtcdVppSwitchKey == host {
+			return true
+		}
+	}
+	return false
+}
+
+func sfcHasMetadata(sfc *controller.SfcEntity, key string, value string) bool {
+	for _, sfcElement := range sfc.GetElements() {
+		if v, exists := sfcElement.Metadata[key]; exists && v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// page returns the names of the page, the names are sorted, and sets the paging headers
+func (q *listQuery) page(w http.ResponseWriter, names []string) []string {
+
+	sort.Strings(names)
+	total := len(names)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+
+	if q.offset >= total {
+		return nil
+	}
+	end := total
+	if q.limit != 0 && q.offset+q.limit < total {
+		end = q.offset + q.limit
+		w.Header().Set("X-Next-Offset", strconv.Itoa(end))
+	}
+	return names[q.offset:end]
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The mirroring of the datastore to a standby site is implemented in this
+// file.  With -mirror-etcd-config set, every write of the controller and of
+// the driver, ie the rendered agent keys, the config and the id's, is also
+// written, asynchronously, to the etcd cluster of the config, see the mirror
+// package.  The standby is resynced from the primary at startup.  A promote
+// flips the two, the controller then reads from and writes to the standby,
+// and the former primary is mirrored to once it is reachable.
+
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ligato/cn-infra/config"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/db/keyval/etcdv3"
+	"github.com/ligato/cn-infra/db/keyval/kvproto"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/controller/utils/mirror"
+)
+
+// the name of the site the controller is started with as the primary
+const mirrorPrimarySite = "local"
+
+// how long a promote waits for the writes queued for the standby to be applied
+const mirrorPromoteTimeout = 30 * time.Second
+
+// initMirror connects to the etcd of the config and mirrors the writes of the primary to it
+func (sfcCtrlPlugin *SfcControllerPluginHandler) initMirror(configFile string,
+	primary func(string) keyval.ProtoBroker) error {
+
+	cfg := &etcdv3.Config{}
+	if err := config.ParseConfigFromYamlFile(configFile, cfg); err != nil {
+		return err
+	}
+	clientCfg, err := etcdv3.ConfigToClientv3(cfg)
+	if err != nil {
+		return err
+	}
+	conn, err := etcdv3.NewEtcdConnectionWithBytes(*clientCfg, log)
+	if err != nil {
+		return err
+	}
+	secondary := kvproto.NewProtoWrapperWithSerializer(conn, &keyval.SerializerJSON{})
+
+	sfcCtrlPlugin.mirror = mirror.New(
+		mirror.Site{Name: mirrorPrimarySite, DBFactory: primary},
+		mirror.Site{Name: strings.Join(clientCfg.Endpoints, ","), DBFactory: secondary.NewBroker},
+		[]string{controller.SfcControllerPrefix(), utils.GetVppAgentPrefix()},
+		mirror.DefaultQueueLength)
+	sfcCtrlPlugin.mirror.Resync()
+
+	log.Infof("initMirror: mirroring to: '%s'", sfcCtrlPlugin.mirror.Stats().Secondary)
+	return nil
+}
+
+// promoteMirror makes the standby the primary
+func (sfcCtrlPlugin *SfcControllerPluginHandler) promoteMirror() (*mirror.Stats, error) {
+
+	if sfcCtrlPlugin.mirror == nil {
+		return nil, fmt.Errorf("Missing mirror, the controller is not started with -mirror-etcd-config")
+	}
+	if err := sfcCtrlPlugin.mirror.Promote(mirrorPromoteTimeout); err != nil {
+		return nil, err
+	}
+	stats := sfcCtrlPlugin.mirror.Stats()
+	return &stats, nil
+}
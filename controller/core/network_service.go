@@ -0,0 +1,127 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The network services are implemented in this file.  A network service
+// groups related chains with the tenant, subnet, external entity and host
+// they share.  It is not rendered as such: each of its chains is expanded
+// into a regular sfc named <service>-<chain>, with the shared parameters
+// filled in where the chain does not set its own, and the expanded sfc's
+// are stored and rendered like posted ones.  The chains dropped from a
+// re-posted service stay configured, as the controller has no delete yet.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// networkServiceSfcName is the name of the sfc a chain of the service is expanded into
+func networkServiceSfcName(ns *controller.NetworkService, chainName string) string {
+	return ns.Name + "-" + chainName
+}
+
+// expandNetworkService returns the sfc's the service's chains are expanded into
+func expandNetworkService(ns *controller.NetworkService) []controller.SfcEntity {
+
+	sfcs := make([]controller.SfcEntity, 0, len(ns.GetSfcEntities()))
+	for _, chain := range ns.GetSfcEntities() {
+
+		sfc := proto.Clone(chain).(*controller.SfcEntity)
+		sfc.Name = networkServiceSfcName(ns, chain.Name)
+		sfc.NetworkService = ns.Name
+		if sfc.Description == "" {
+			sfc.Description = ns.Description
+		}
+		if sfc.SfcIpv4Prefix == "" {
+			sfc.SfcIpv4Prefix = ns.SfcIpv4Prefix
+		}
+
+		// a dependency on a sibling chain is on the sibling's expanded sfc
+		for i, dep := range sfc.DependsOn {
+			for _, sibling := range ns.GetSfcEntities() {
+				if sibling.Name == dep {
+					sfc.DependsOn[i] = networkServiceSfcName(ns, dep)
+				}
+			}
+		}
+
+		for _, sfcElement := range sfc.GetElements() {
+			if sfcElement.EtcdVppSwitchKey == "" {
+				sfcElement.EtcdVppSwitchKey = ns.EtcdVppSwitchKey
+			}
+			if sfcElement.Type == controller.SfcElementType_EXTERNAL_ENTITY && sfcElement.Container == "" {
+				sfcElement.Container = ns.ExternalEntity
+			}
+			if ns.Tenant != "" {
+				if _, exists := sfcElement.GetMetadata()["tenant"]; !exists {
+					if sfcElement.Metadata == nil {
+						sfcElement.Metadata = make(map[string]string)
+					}
+					sfcElement.Metadata["tenant"] = ns.Tenant
+				}
+			}
+		}
+
+		sfcs = append(sfcs, *sfc)
+	}
+
+	return sfcs
+}
+
+// validateNetworkService validates the service and the sfc's it expands into, an expanded sfc must not
+// replace a chain that was posted on its own or that belongs to another service
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateNetworkService(ns *controller.NetworkService) error {
+
+	if ns.Name == "" {
+		return fmt.Errorf("Missing network service name")
+	}
+	if len(ns.GetSfcEntities()) == 0 {
+		return fmt.Errorf("Invalid network service: '%s', it has no sfc_entities", ns.Name)
+	}
+
+	chainNames := make(map[string]bool)
+	for _, chain := range ns.GetSfcEntities() {
+		if chain.Name == "" {
+			return fmt.Errorf("Missing sfc name in network service: '%s'", ns.Name)
+		}
+		if chainNames[chain.Name] {
+			return fmt.Errorf("Duplicate sfc: '%s' in network service: '%s'", chain.Name, ns.Name)
+		}
+		chainNames[chain.Name] = true
+	}
+
+	for _, sfc := range expandNetworkService(ns) {
+		if existing, exists := sfcCtrlPlugin.ramConfigCache.SFCs[sfc.Name]; exists && existing.NetworkService != ns.Name {
+			return fmt.Errorf("Invalid network service: '%s', its sfc: '%s' is already configured", ns.Name,
+				sfc.Name)
+		}
+		if err := sfcCtrlPlugin.validateSFC(&sfc); err != nil {
+			return fmt.Errorf("Invalid network service: '%s': %s", ns.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// copyNetworkServiceToRAMCache caches the service and the sfc's it expands into
+func (sfcCtrlPlugin *SfcControllerPluginHandler) copyNetworkServiceToRAMCache(ns *controller.NetworkService) {
+
+	sfcCtrlPlugin.ramConfigCache.NSs[ns.Name] = *ns
+	for _, sfc := range expandNetworkService(ns) {
+		sfcCtrlPlugin.ramConfigCache.SFCs[sfc.Name] = sfc
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The merge-patch updates of the entities are implemented in this file.  A
+// PATCH of an ee, he, sfc or ns carries a json merge patch (RFC 7396): the
+// fields of the patch replace those of the entity, a null removes a field,
+// and the fields the patch omits are kept.  The patched entity is then
+// posted like any other update, so it is validated, stored and re-rendered,
+// and the driver only touches the wiring that changed.  As the rfc has it,
+// an array is replaced as a whole, so adding an element to a chain patches
+// the chain's complete list of elements.
+
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// mergePatch applies the json merge patch to the json document
+func mergePatch(doc []byte, patch []byte) ([]byte, error) {
+
+	var docValue, patchValue interface{}
+	if err := json.Unmarshal(doc, &docValue); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, fmt.Errorf("Invalid merge patch: %s", err)
+	}
+	return json.Marshal(mergePatchValue(docValue, patchValue))
+}
+
+func mergePatchValue(doc interface{}, patch interface{}) interface{} {
+
+	patchObject, isObject := patch.(map[string]interface{})
+	if !isObject {
+		return patch
+	}
+	docObject, isObject := doc.(map[string]interface{})
+	if !isObject {
+		docObject = make(map[string]interface{})
+	}
+	for field, value := range patchObject {
+		if value == nil {
+			delete(docObject, field)
+		} else {
+			docObject[field] = mergePatchValue(docObject[field], value)
+		}
+	}
+	return docObject
+}
+
+// patchRequestBody replaces the merge patch in the body of the request with the patched entity, the
+// request can then be processed as a POST of the entity
+func patchRequestBody(req *http.Request, entity interface{}) error {
+
+	patch, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return err
+	}
+	doc, err := json.Marshal(entity)
+	if err != nil {
+		return err
+	}
+	patched, err := mergePatch(doc, patch)
+	if err != nil {
+		return err
+	}
+
+	log.Debugf("patchRequestBody: %s patched to: %s", req.URL, patched)
+
+	req.Body = ioutil.NopCloser(bytes.NewReader(patched))
+	req.ContentLength = int64(len(patched))
+	return nil
+}
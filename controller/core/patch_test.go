@@ -0,0 +1,70 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		doc      string
+		patch    string
+		expected string
+	}{
+		{"null deletes a field", `{"a":"b","c":"d"}`, `{"a":null}`, `{"c":"d"}`},
+		{"null of a missing field", `{"c":"d"}`, `{"a":null}`, `{"c":"d"}`},
+		{"field replaced", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"field added", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"nested object merged", `{"a":{"b":"c","d":"e"}}`, `{"a":{"b":"f","d":null}}`, `{"a":{"b":"f"}}`},
+		{"nested object created", `{"e":null}`, `{"a":{"bb":{"ccc":null}}}`, `{"e":null,"a":{"bb":{}}}`},
+		{"object replaces a scalar", `{"a":"b"}`, `{"a":{"c":"d"}}`, `{"a":{"c":"d"}}`},
+		{"array replaced", `{"a":["b","c"]}`, `{"a":["d"]}`, `{"a":["d"]}`},
+		{"array of objects replaced", `{"a":[{"b":"c"}]}`, `{"a":[{"d":"e"}]}`, `{"a":[{"d":"e"}]}`},
+		{"array replaces an object", `{"a":{"b":"c"}}`, `{"a":[1]}`, `{"a":[1]}`},
+		{"non-object patch replaces the doc", `{"a":"b"}`, `["c"]`, `["c"]`},
+		{"scalar patch replaces the doc", `{"a":"b"}`, `"c"`, `"c"`},
+		{"null patch replaces the doc", `{"a":"b"}`, `null`, `null`},
+		{"empty patch keeps the doc", `{"a":"b"}`, `{}`, `{"a":"b"}`},
+	}
+	for _, test := range tests {
+		patched, err := mergePatch([]byte(test.doc), []byte(test.patch))
+		if err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		var got, expected interface{}
+		if err := json.Unmarshal(patched, &got); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(test.expected), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: patched: %s, expected: %s", test.name, patched, test.expected)
+		}
+	}
+}
+
+func TestMergePatchInvalid(t *testing.T) {
+
+	if _, err := mergePatch([]byte(`{"a":"b"}`), []byte(`{"a":`)); err == nil {
+		t.Errorf("the invalid patch is applied")
+	}
+}
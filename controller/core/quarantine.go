@@ -0,0 +1,138 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The quarantine of a chain element is implemented in this file.  A
+// misbehaving vnf is isolated by setting admin_down on its element: the
+// chain is re-rendered with the element's memif, veth and af_packet i/f's
+// disabled, in the container and on the vswitch, and the rest of the chain,
+// its bridges, tunnels and ids, stays as it is.  Releasing the element
+// clears admin_down and re-renders its i/f's enabled.  The flag is part of
+// the chain's config, so a quarantine is kept across restarts and versioned
+// like any other change of the chain.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// QuarantinedElement is a chain element whose i/f's are disabled
+type QuarantinedElement struct {
+	Sfc       string `json:"sfc"`
+	Container string `json:"container"`
+	PortLabel string `json:"port_label"`
+	Host      string `json:"host"`
+}
+
+// sfcElementIsContainer is whether the element is a container, whose i/f's the driver renders
+func sfcElementIsContainer(sfcElement *controller.SfcEntity_SfcElement) bool {
+
+	switch sfcElement.Type {
+	case controller.SfcElementType_VPP_CONTAINER_MEMIF, controller.SfcElementType_VPP_CONTAINER_AFP,
+		controller.SfcElementType_NON_VPP_CONTAINER_MEMIF, controller.SfcElementType_NON_VPP_CONTAINER_AFP:
+		return true
+	}
+	return false
+}
+
+// quarantinedSfc returns the chain with the element's admin_down set as requested, nil if it already is
+func (sfcCtrlPlugin *SfcControllerPluginHandler) quarantinedSfc(sfcName string, container string,
+	portLabel string, adminDown bool) (*controller.SfcEntity, error) {
+
+	sfc, exists := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+	if !exists {
+		return nil, fmt.Errorf("Invalid sfc quarantine, sfc: '%s' not found", sfcName)
+	}
+	if err := sfcCtrlPlugin.sfcDeleting(sfcName); err != nil {
+		return nil, err
+	}
+
+	quarantined := proto.Clone(&sfc).(*controller.SfcEntity)
+	for _, sfcElement := range quarantined.GetElements() {
+		if sfcElement.Container != container || sfcElement.PortLabel != portLabel {
+			continue
+		}
+		if !sfcElementIsContainer(sfcElement) {
+			return nil, fmt.Errorf("Invalid sfc quarantine of: '%s', element: '%s/%s' is not a container",
+				sfcName, container, portLabel)
+		}
+		if sfcElement.AdminDown == adminDown {
+			return nil, nil
+		}
+		sfcElement.AdminDown = adminDown
+		return quarantined, nil
+	}
+
+	return nil, fmt.Errorf("Invalid sfc quarantine of: '%s', element: '%s/%s' not found", sfcName, container,
+		portLabel)
+}
+
+// quarantineSfcElement sets or clears the element's admin_down, and re-renders the chain, it is not changed if
+// the element is already in the requested state
+func (sfcCtrlPlugin *SfcControllerPluginHandler) quarantineSfcElement(sfcName string, container string,
+	portLabel string, adminDown bool, source string) (bool, error) {
+
+	quarantined, err := sfcCtrlPlugin.quarantinedSfc(sfcName, container, portLabel, adminDown)
+	if err != nil || quarantined == nil {
+		return false, err
+	}
+	log.Infof("quarantineSfcElement: sfc: '%s', element: '%s/%s', admin_down: %t", sfcName, container,
+		portLabel, adminDown)
+
+	change := sfcCtrlPlugin.newEntityChange(controller.SfcEntityKind, sfcName, quarantined, source)
+
+	sfcCtrlPlugin.ramConfigCache.SFCs[sfcName] = *quarantined
+	if err := sfcCtrlPlugin.DatastoreSfcEntityCreate(quarantined); err != nil {
+		return false, err
+	}
+	sfcCtrlPlugin.recordEntityChange(change)
+
+	err = sfcCtrlPlugin.renderServiceFunctionEntity(quarantined)
+	sfcCtrlPlugin.entityStatusFlush()
+
+	return true, err
+}
+
+// quarantinedElements returns the elements whose i/f's are disabled, by chain
+func (sfcCtrlPlugin *SfcControllerPluginHandler) quarantinedElements() []QuarantinedElement {
+
+	elements := make([]QuarantinedElement, 0)
+	for _, sfc := range sfcCtrlPlugin.ramConfigCache.SFCs {
+		for _, sfcElement := range sfc.GetElements() {
+			if sfcElement.AdminDown {
+				elements = append(elements, QuarantinedElement{
+					Sfc:       sfc.Name,
+					Container: sfcElement.Container,
+					PortLabel: sfcElement.PortLabel,
+					Host:      sfcElement.EtcdVppSwitchKey,
+				})
+			}
+		}
+	}
+	sort.Slice(elements, func(i, j int) bool {
+		if elements[i].Sfc != elements[j].Sfc {
+			return elements[i].Sfc < elements[j].Sfc
+		}
+		if elements[i].Container != elements[j].Container {
+			return elements[i].Container < elements[j].Container
+		}
+		return elements[i].PortLabel < elements[j].PortLabel
+	})
+
+	return elements
+}
@@ -24,9 +24,14 @@
 package core
 
 import (
+	"time"
+
 	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
 )
 
+var reconcileLog = logs.Logger(logs.Reconcile)
+
 // ReconcileVppLabelsMapType : track all the vpp agents in etcd
 type ReconcileVppLabelsMapType map[string]struct{}
 
@@ -41,8 +46,8 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) ReconcileInit() error {
 // ReconcileStart : init the reconcile procedure for all plguins
 func (sfcCtrlPlugin *SfcControllerPluginHandler) ReconcileStart() error {
 
-	log.Info("ReconcileStart: enter ...")
-	defer log.Info("ReconcileStart: exit ...")
+	reconcileLog.Info("ReconcileStart: enter ...")
+	defer reconcileLog.Info("ReconcileStart: exit ...")
 
 	for k := range sfcCtrlPlugin.ReconcileVppLabelsMap {
 		delete(sfcCtrlPlugin.ReconcileVppLabelsMap, k)
@@ -50,6 +55,7 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) ReconcileStart() error {
 	sfcCtrlPlugin.ReconcileLoadAllVppLabels()
 
 	sfcCtrlPlugin.cnpDriverPlugin.ReconcileStart(sfcCtrlPlugin.ReconcileVppLabelsMap)
+	sfcCtrlPlugin.emitReconcileEvent(reconcileEventStart, "", nil)
 
 	return nil
 }
@@ -57,19 +63,63 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) ReconcileStart() error {
 // ReconcileEnd : perform post processing of the reconcile procedure
 func (sfcCtrlPlugin *SfcControllerPluginHandler) ReconcileEnd() error {
 
-	log.Info("ReconcileEnd: begin ...")
-	defer log.Info("ReconcileEnd: exit ...")
+	reconcileLog.Info("ReconcileEnd: begin ...")
+	defer reconcileLog.Info("ReconcileEnd: exit ...")
 
 	sfcCtrlPlugin.cnpDriverPlugin.ReconcileEnd()
+	sfcCtrlPlugin.emitReconcileEvent(reconcileEventEnd, "", nil)
 
 	return nil
 }
 
+// ReconcileEndCanary : perform post processing of the reconcile procedure, when the system parameters name
+// a canary host, its changes are applied and verified before the rest of the hosts are touched
+func (sfcCtrlPlugin *SfcControllerPluginHandler) ReconcileEndCanary() error {
+
+	sp := sfcCtrlPlugin.ramConfigCache.SysParms
+	if sp.CanaryHost == "" {
+		return sfcCtrlPlugin.ReconcileEnd()
+	}
+	if _, exists := sfcCtrlPlugin.ramConfigCache.HEs[sp.CanaryHost]; !exists {
+		reconcileLog.Warnf("ReconcileEndCanary: canary host '%s' not found, applying to all hosts", sp.CanaryHost)
+		return sfcCtrlPlugin.ReconcileEnd()
+	}
+
+	reconcileLog.Info("ReconcileEndCanary: begin ...")
+	defer reconcileLog.Info("ReconcileEndCanary: exit ...")
+
+	verifyTimeout := time.Duration(sp.CanaryVerifyTimeout) * time.Second
+
+	err := sfcCtrlPlugin.cnpDriverPlugin.ReconcileEndCanary(sp.CanaryHost, verifyTimeout)
+	sfcCtrlPlugin.emitReconcileEvent(reconcileEventEnd, "", err)
+
+	return err
+}
+
+// ReconcileVppLabel : reconcile the config of a single vpp agent, ie after the agent restarted, the whole
+// config is rendered but only the agent's entries are compared and written
+func (sfcCtrlPlugin *SfcControllerPluginHandler) ReconcileVppLabel(vppLabel string) error {
+
+	reconcileLog.Infof("ReconcileVppLabel: begin '%s' ...", vppLabel)
+	defer reconcileLog.Info("ReconcileVppLabel: exit ...")
+
+	sfcCtrlPlugin.cnpDriverPlugin.ReconcileStart(map[string]struct{}{vppLabel: {}})
+	sfcCtrlPlugin.emitReconcileEvent(reconcileEventStart, vppLabel, nil)
+
+	err := sfcCtrlPlugin.renderConfigFromRAMCache()
+	if err == nil {
+		err = sfcCtrlPlugin.cnpDriverPlugin.ReconcileEndForLabel(vppLabel)
+	}
+	sfcCtrlPlugin.emitReconcileEvent(reconcileEventEnd, vppLabel, err)
+
+	return err
+}
+
 // ReconcileLoadAllVppLabels : retrieve all vpp lavels from the etcd datastore
 func (sfcCtrlPlugin *SfcControllerPluginHandler) ReconcileLoadAllVppLabels() {
 
-	log.Info("ReconcileLoadAllVppLabels: begin ...")
-	defer log.Info("ReconcileLoadAllVppLabels: exit ...")
+	reconcileLog.Info("ReconcileLoadAllVppLabels: begin ...")
+	defer reconcileLog.Info("ReconcileLoadAllVppLabels: exit ...")
 
 	keyIter, err := sfcCtrlPlugin.db.ListKeys(utils.GetVppAgentPrefix())
 	if err == nil {
@@ -79,7 +129,7 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) ReconcileLoadAllVppLabels() {
 				label := utils.GetVppEtcdlabel(key)
 				_, exists := sfcCtrlPlugin.ReconcileVppLabelsMap[label]
 				if !exists {
-					log.Info("ReconcileLoadAllVppLabels: adding label to reconcile label map: ", label)
+					reconcileLog.Info("ReconcileLoadAllVppLabels: adding label to reconcile label map: ", label)
 					sfcCtrlPlugin.ReconcileVppLabelsMap[label] = struct{}{}
 				}
 				continue
@@ -0,0 +1,120 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The recovery of the entities left partially rendered by a crash is
+// implemented in this file.  The keys of an entity are recorded in its
+// ownership index once it is wired, so a controller that went down while
+// wiring an entity leaves the index of the entity's previous render, which
+// lists fewer keys than the entity renders to.  At startup the index is
+// snapshot before the config is rendered, the render records the keys each
+// entity renders to, and the entities whose snapshot lacks some of them are
+// wired again, on their own, once the reconcile has completed.  An event is
+// emitted for each entity wired again, see events.go.  The entities that
+// were never rendered before have no index, they are not recovered.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/eventbus"
+)
+
+// the actions of the recovery events
+const (
+	recoveryEventRewired = "rewired"
+	recoveryEventFailed  = "failed"
+)
+
+// entityKeysSnapshot returns the keys of each entity's ownership index, by kind/name
+func (sfcCtrlPlugin *SfcControllerPluginHandler) entityKeysSnapshot() (map[string]map[string]struct{}, error) {
+
+	snapshot := make(map[string]map[string]struct{})
+	err := sfcCtrlPlugin.DatastoreEntityKeysIterate(func(entity string, entityKeys *controller.EntityKeys) {
+		keys := make(map[string]struct{})
+		for _, key := range entityKeys.Keys {
+			keys[key] = struct{}{}
+		}
+		snapshot[entity] = keys
+	})
+	return snapshot, err
+}
+
+// recoverPartialRenders wires the entities whose ownership index, as it was before the startup render, lacks
+// keys the startup render recorded for them
+func (sfcCtrlPlugin *SfcControllerPluginHandler) recoverPartialRenders(before map[string]map[string]struct{}) {
+
+	after, err := sfcCtrlPlugin.entityKeysSnapshot()
+	if err != nil {
+		log.Errorf("recoverPartialRenders: error reading the ownership index: %s", err)
+		return
+	}
+
+	var entities []string
+	missing := make(map[string]int)
+	for entity, keys := range after {
+		beforeKeys, exists := before[entity]
+		if !exists {
+			continue
+		}
+		for key := range keys {
+			if _, exists := beforeKeys[key]; !exists {
+				missing[entity]++
+			}
+		}
+		if missing[entity] != 0 {
+			entities = append(entities, entity)
+		}
+	}
+	sort.Strings(entities)
+
+	for _, entity := range entities {
+		kindName := strings.SplitN(entity, "/", 2)
+		if len(kindName) != 2 {
+			continue
+		}
+		kind, name := kindName[0], kindName[1]
+		if kind != controller.HostEntityKind && kind != controller.ExternalEntityKind &&
+			kind != controller.SfcEntityKind {
+			continue // the system parameters are rendered with every entity
+		}
+		message := fmt.Sprintf("%d of the %d keys it renders to were missing from its ownership index", missing[entity],
+			len(after[entity]))
+		log.Infof("recoverPartialRenders: wiring '%s' again: %s", entity, message)
+
+		action := recoveryEventRewired
+		if !sfcCtrlPlugin.renderEntity(kind, name) {
+			action = recoveryEventFailed
+			message += ", it is not configured anymore"
+		}
+		sfcCtrlPlugin.entityStatusFlush()
+		status := &controller.EntityStatus{}
+		if found, _, err := sfcCtrlPlugin.db.GetValue(controller.EntityStatusKey(kind, name), status); err == nil &&
+			found && status.State != controller.RenderStateType_RENDERED {
+			action = recoveryEventFailed
+			message += ", it is " + status.State.String() + ": " + status.Message
+		}
+
+		sfcCtrlPlugin.emitEvent(eventbus.Event{
+			Type:    eventbus.EventRecovery,
+			Action:  action,
+			Kind:    kind,
+			Name:    name,
+			Message: message,
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Rendering a config outside of the plugin is implemented in this file.  It
+// lets tools and tests see the keys a topology renders to without etcd, the
+// REST API or running agents, ie the golden file tests in tests/gotests.
+
+package core
+
+import (
+	"time"
+
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/cnpdriver"
+	"github.com/ligato/sfc-controller/controller/utils/faults"
+	"github.com/ligato/sfc-controller/controller/utils/shards"
+)
+
+// RenderConfig validates the config and renders it with a fresh controller and cnp driver, the config and
+// the agents' keys are written through the brokers dbFactory returns.  The agents are not waited for, and
+// external entity devices are not configured.  Faults set with the faults package are injected, and the
+// agents of the hosts sharded to other controllers are left alone, like they are in the plugin.
+func RenderConfig(cfg *YamlConfig, dbFactory func(prefix string) keyval.ProtoBroker) error {
+
+	sfcCtrlPlugin, err := newOfflinePlugin(cfg, dbFactory)
+	if err != nil {
+		return err
+	}
+	return sfcCtrlPlugin.renderConfigFromRAMCache()
+}
+
+// newOfflinePlugin returns a fresh controller and cnp driver with the config validated and written to the sfc
+// db, but not rendered yet
+func newOfflinePlugin(cfg *YamlConfig, dbFactory func(prefix string) keyval.ProtoBroker) (
+	*SfcControllerPluginHandler, error) {
+
+	sfcCtrlPlugin := &SfcControllerPluginHandler{}
+
+	dbFactory = shards.WrapBrokerFactory(faults.WrapBrokerFactory(dbFactory), sfcCtrlPlugin.agentWritable)
+	sfcCtrlPlugin.db = dbFactory(keyval.Root)
+	sfcCtrlPlugin.dbFactory = dbFactory
+	sfcCtrlPlugin.InitRAMCache()
+	sfcCtrlPlugin.ReconcileInit()
+	if err := sfcCtrlPlugin.DatastoreRenderRetryRetrieveAll(); err != nil {
+		return nil, err
+	}
+
+	var err error
+	sfcCtrlPlugin.cnpDriverPlugin, err = cnpdriver.RegisterCNPDriverPlugin(cnpDriverName, dbFactory)
+	if err != nil {
+		return nil, err
+	}
+
+	sfcCtrlPlugin.yamlConfig = cfg
+	if err := sfcCtrlPlugin.copyYamlConfigToRAMCache(); err != nil {
+		return nil, err
+	}
+	sfcCtrlPlugin.ramConfigCache.SysParms.WaitForAgent = false
+
+	if err := sfcCtrlPlugin.validateRAMCache(); err != nil {
+		return nil, err
+	}
+	if err := sfcCtrlPlugin.WriteRAMCacheToEtcd(); err != nil {
+		return nil, err
+	}
+	sfcCtrlPlugin.dropExpiredSfcs(time.Now().Unix())
+	sfcCtrlPlugin.dropGraceEndedSfcs(time.Now().Unix())
+
+	return sfcCtrlPlugin, nil
+}
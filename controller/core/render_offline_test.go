@@ -0,0 +1,85 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+// testTopology is a vswitch with an l2x chain between two vnfs
+const testTopology = `
+host_entities:
+    - name: vswitch
+
+sfc_entities:
+    - name: vnf1-vnf2
+      type: 5
+      elements:
+          - container: vnf1
+            port_label: port1
+            etcd_vpp_switch_key: vswitch
+            type: 2
+          - container: vnf2
+            port_label: port1
+            etcd_vpp_switch_key: vswitch
+            type: 2
+`
+
+// newTestPlugin returns a controller with the test topology and the sfcs rendered into an in-memory broker
+func newTestPlugin(t *testing.T, sfcs ...controller.SfcEntity) (*SfcControllerPluginHandler, *membroker.Broker) {
+
+	cfg, err := ParseYamlConfig([]byte(testTopology))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SFCs = append(cfg.SFCs, sfcs...)
+
+	broker := membroker.New()
+	sfcCtrlPlugin, err := newOfflinePlugin(cfg, broker.NewBroker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+		t.Fatal(err)
+	}
+	return sfcCtrlPlugin, broker
+}
+
+// testChain returns an l2x chain between two new vnfs on the vswitch
+func testChain(name string) controller.SfcEntity {
+	return controller.SfcEntity{
+		Name: name,
+		Type: controller.SfcType_SFC_EW_L2XCONN,
+		Elements: []*controller.SfcEntity_SfcElement{
+			{Container: name + "-a", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+			{Container: name + "-b", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+		},
+	}
+}
+
+// sfcStored returns whether the sfc is in the sfc db
+func sfcStored(t *testing.T, sfcCtrlPlugin *SfcControllerPluginHandler, name string) bool {
+
+	found, _, err := sfcCtrlPlugin.db.GetValue(controller.SfcEntityNameKey(name), &controller.SfcEntity{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
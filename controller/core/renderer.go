@@ -18,6 +18,9 @@
 package core
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/ligato/sfc-controller/controller/model/controller"
 )
 
@@ -27,6 +30,7 @@ import (
 // both ends at that time.  See the http handler for an example of how the renderEE is done.
 func (sfcCtrlPlugin *SfcControllerPluginHandler) renderConfigFromRAMCache() error {
 
+	defer sfcCtrlPlugin.entityStatusFlush()
 
 	log.Infof("render system parameters from ram cache")
 	if err := sfcCtrlPlugin.renderSystemParameters(&sfcCtrlPlugin.ramConfigCache.SysParms); err != nil {
@@ -35,7 +39,8 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) renderConfigFromRAMCache() erro
 	}
 
 	log.Infof("render host entities from ram cache")
-	for _, he := range sfcCtrlPlugin.ramConfigCache.HEs {
+	for _, heName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		he := sfcCtrlPlugin.ramConfigCache.HEs[heName]
 		if err := sfcCtrlPlugin.renderHostEntity(&he, true, false); err != nil {
 			log.Error("Error rendering host entity:", he)
 			return err
@@ -43,14 +48,16 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) renderConfigFromRAMCache() erro
 	}
 
 	log.Infof("render external entities from ram cache")
-	for _, ee := range sfcCtrlPlugin.ramConfigCache.EEs {
+	for _, eeName := range sortedKeysEE(sfcCtrlPlugin.ramConfigCache.EEs) {
+		ee := sfcCtrlPlugin.ramConfigCache.EEs[eeName]
 		if err := sfcCtrlPlugin.renderExternalEntity(&ee, true, false); err != nil {
 			log.Error("Error rendering external entity:", ee)
 			return err
 		}
 	}
 
-	for _, he := range sfcCtrlPlugin.ramConfigCache.HEs {
+	for _, heName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		he := sfcCtrlPlugin.ramConfigCache.HEs[heName]
 		if err := sfcCtrlPlugin.renderHostEntity(&he, false, true); err != nil {
 			log.Error("Error rendering host entity:", he)
 			return err
@@ -58,7 +65,8 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) renderConfigFromRAMCache() erro
 	}
 
 	log.Infof("render external entities from ram cache")
-	for _, ee := range sfcCtrlPlugin.ramConfigCache.EEs {
+	for _, eeName := range sortedKeysEE(sfcCtrlPlugin.ramConfigCache.EEs) {
+		ee := sfcCtrlPlugin.ramConfigCache.EEs[eeName]
 		if err := sfcCtrlPlugin.renderExternalEntity(&ee, false, true); err != nil {
 			log.Error("Error rendering external entity:", ee)
 			return err
@@ -66,7 +74,14 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) renderConfigFromRAMCache() erro
 	}
 
 	log.Infof("render sfc's from ram cache")
-	for _, sfc := range sfcCtrlPlugin.ramConfigCache.SFCs {
+	sfcOrder, err := sfcRenderOrder(sfcCtrlPlugin.ramConfigCache.SFCs)
+	if err != nil {
+		log.Error("Error ordering service function chains:", err)
+		return err
+	}
+	sfcCtrlPlugin.sfcRenderStates = nil
+	for _, sfcName := range sfcOrder {
+		sfc := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
 		if err := sfcCtrlPlugin.renderServiceFunctionEntity(&sfc); err != nil {
 			log.Error("Error rendering service function chain:", sfc)
 			return err
@@ -97,16 +112,26 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) renderExternalEntity(ee *contro
 
 	if configOnlyEE {
 		log.Infof("WireInternalsForExternalEntity: ee:'%s'", ee.Name)
-		sfcCtrlPlugin.cnpDriverPlugin.WireInternalsForExternalEntity(ee)
+		keyCount := sfcCtrlPlugin.cnpDriverPlugin.GetRenderedKeyCount()
+		err := sfcCtrlPlugin.cnpDriverPlugin.WireInternalsForExternalEntity(ee)
+		sfcCtrlPlugin.entityStatusRecord(controller.ExternalEntityKind, ee.Name, keyCount, err)
 	}
 
 	if wireToOtherEntities {
-		for _, he := range sfcCtrlPlugin.ramConfigCache.HEs {
+		for _, heName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+			he := sfcCtrlPlugin.ramConfigCache.HEs[heName]
+			if !sfcCtrlPlugin.wiringPolicyWires(&he, ee.Name) {
+				continue
+			}
 			log.Infof("WireHostEntityToExternalEntity: he:'%s' to ee:'%s'", he.Name, ee.Name)
-			sfcCtrlPlugin.cnpDriverPlugin.WireHostEntityToExternalEntity(&he, ee)
+			keyCount := sfcCtrlPlugin.cnpDriverPlugin.GetRenderedKeyCount()
+			err := sfcCtrlPlugin.cnpDriverPlugin.WireHostEntityToExternalEntity(&he, ee)
+			sfcCtrlPlugin.entityStatusRecord(controller.ExternalEntityKind, ee.Name, keyCount, err)
 		}
 	}
 
+	sfcCtrlPlugin.entityStatusConfirm(controller.ExternalEntityKind, ee.Name)
+
 	return nil
 }
 
@@ -120,28 +145,43 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) renderHostEntity(sh *controller
 
 	if configOnlyHE {
 		log.Infof("WireInternalsForHostEntity: he:'%s'", sh.Name)
-		sfcCtrlPlugin.cnpDriverPlugin.WireInternalsForHostEntity(sh)
+		keyCount := sfcCtrlPlugin.cnpDriverPlugin.GetRenderedKeyCount()
+		err := sfcCtrlPlugin.cnpDriverPlugin.WireInternalsForHostEntity(sh)
+		sfcCtrlPlugin.entityStatusRecord(controller.HostEntityKind, sh.Name, keyCount, err)
 	}
 
 	if wireToOtherEntities {
-		for _, ee := range sfcCtrlPlugin.ramConfigCache.EEs {
+		for _, eeName := range sortedKeysEE(sfcCtrlPlugin.ramConfigCache.EEs) {
+			ee := sfcCtrlPlugin.ramConfigCache.EEs[eeName]
+			if !sfcCtrlPlugin.wiringPolicyWires(sh, ee.Name) {
+				continue
+			}
 			log.Infof("WireHostEntityToExternalEntity: he:'%s' to ee:'%s'/'%s'",
 				sh.Name, ee.Name, ee.MgmntIpAddress)
-			sfcCtrlPlugin.cnpDriverPlugin.WireHostEntityToExternalEntity(sh, &ee)
+			keyCount := sfcCtrlPlugin.cnpDriverPlugin.GetRenderedKeyCount()
+			err := sfcCtrlPlugin.cnpDriverPlugin.WireHostEntityToExternalEntity(sh, &ee)
+			sfcCtrlPlugin.entityStatusRecord(controller.HostEntityKind, sh.Name, keyCount, err)
 		}
 
-		for _, dh := range sfcCtrlPlugin.ramConfigCache.HEs {
-			if *sh != dh {
+		for _, dhName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+			dh := sfcCtrlPlugin.ramConfigCache.HEs[dhName]
+			if sh.Name != dh.Name {
 				log.Infof("WireHostEntityToDestinationHostEntity: sh:'%s' to dh:'%s'",
 					sh.Name, dh.Name)
-				sfcCtrlPlugin.cnpDriverPlugin.WireHostEntityToDestinationHostEntity(sh, &dh)
+				keyCount := sfcCtrlPlugin.cnpDriverPlugin.GetRenderedKeyCount()
+				err := sfcCtrlPlugin.cnpDriverPlugin.WireHostEntityToDestinationHostEntity(sh, &dh)
+				sfcCtrlPlugin.entityStatusRecord(controller.HostEntityKind, sh.Name, keyCount, err)
 				log.Infof("WireHostEntityToDestinationHostEntity: dh:'%s' to sh:'%s'",
 					dh.Name, sh.Name)
-				sfcCtrlPlugin.cnpDriverPlugin.WireHostEntityToDestinationHostEntity(&dh, sh)
+				keyCount = sfcCtrlPlugin.cnpDriverPlugin.GetRenderedKeyCount()
+				err = sfcCtrlPlugin.cnpDriverPlugin.WireHostEntityToDestinationHostEntity(&dh, sh)
+				sfcCtrlPlugin.entityStatusRecord(controller.HostEntityKind, sh.Name, keyCount, err)
 			}
 		}
 	}
 
+	sfcCtrlPlugin.entityStatusConfirm(controller.HostEntityKind, sh.Name)
+
 	return nil
 }
 
@@ -150,17 +190,77 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) renderServiceFunctionEntity(sfc
 
 	log.Infof("renderServiceFunctionEntity: sfc:'%s'", sfc.Name)
 
+	if dep := sfcCtrlPlugin.sfcDependencyPending(sfc); dep != "" {
+		log.Infof("renderServiceFunctionEntity: sfc:'%s' is waiting for sfc:'%s'", sfc.Name, dep)
+		sfcCtrlPlugin.sfcRenderStateSet(sfc.Name, controller.RenderStateType_DEPENDENCY_PENDING)
+		sfcCtrlPlugin.entityStatusPending(controller.SfcEntityKind, sfc.Name,
+			fmt.Sprintf("waiting for sfc: '%s'", dep))
+		return nil
+	}
+
 	numSfcElements := len(sfc.GetElements())
 	if numSfcElements == 0 {
 		log.Warnf("renderServiceFunctionEntity: sfc:'%s' has no elements", sfc.Name)
+		sfcCtrlPlugin.sfcRenderStateSet(sfc.Name, controller.RenderStateType_RENDERED)
 		return nil
 	}
 
 	log.Infof("renderServiceFunctionEntity: WireSfcEntities: for '%s'/'%s'",
 		sfc.Name, sfc.Description)
-	if err := sfcCtrlPlugin.cnpDriverPlugin.WireSfcEntity(sfc); err != nil {
+	keyCount := sfcCtrlPlugin.cnpDriverPlugin.GetRenderedKeyCount()
+	err := sfcCtrlPlugin.cnpDriverPlugin.WireSfcEntity(sfcForEnvironment(sfc))
+	sfcCtrlPlugin.entityStatusRecord(controller.SfcEntityKind, sfc.Name, keyCount, err)
+	if err != nil {
+		sfcCtrlPlugin.sfcRenderStateSet(sfc.Name, controller.RenderStateType_RENDER_ERROR)
+		return err
+	}
+
+	if err := sfcCtrlPlugin.entityStatusConfirm(controller.SfcEntityKind, sfc.Name); err != nil {
+		sfcCtrlPlugin.sfcRenderStateSet(sfc.Name, controller.RenderStateType_RENDER_ERROR)
 		return err
 	}
+	sfcCtrlPlugin.sfcRenderStateSet(sfc.Name, controller.RenderStateType_RENDERED)
 
 	return nil
 }
+
+// renderServiceFunctionEntityBlueGreen replaces a running (blue) sfc with a changed (green) version of it.  The
+// whole ram cache is rendered inside a reconcile so only the entries that differ are touched, and the driver
+// cuts the changed entries over once the agents have accepted the new ones.  If they are not accepted, the blue
+// sfc is put back in the ram cache.
+func (sfcCtrlPlugin *SfcControllerPluginHandler) renderServiceFunctionEntityBlueGreen(blue *controller.SfcEntity,
+	green *controller.SfcEntity) error {
+
+	log.Infof("renderServiceFunctionEntityBlueGreen: sfc:'%s'", green.Name)
+
+	sfcCtrlPlugin.ramConfigCache.SFCs[green.Name] = *green
+
+	err := sfcCtrlPlugin.renderBlueGreen()
+	if err != nil {
+		log.Errorf("renderServiceFunctionEntityBlueGreen: sfc:'%s', restoring blue: %s", green.Name, err)
+		sfcCtrlPlugin.ramConfigCache.SFCs[blue.Name] = *blue
+
+		// the driver has already removed what it added for green, re-sync its caches with blue
+		sfcCtrlPlugin.ReconcileStart()
+		if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+			log.Errorf("renderServiceFunctionEntityBlueGreen: error re-rendering blue: %s", err)
+		}
+		sfcCtrlPlugin.ReconcileEnd()
+	}
+
+	return err
+}
+
+func (sfcCtrlPlugin *SfcControllerPluginHandler) renderBlueGreen() error {
+
+	if err := sfcCtrlPlugin.ReconcileStart(); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+		return err
+	}
+
+	acceptTimeout := time.Duration(sfcCtrlPlugin.ramConfigCache.SysParms.BlueGreenAcceptTimeout) * time.Second
+
+	return sfcCtrlPlugin.cnpDriverPlugin.ReconcileEndBlueGreen(acceptTimeout)
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The resource accounting of the hosts is implemented in this file.  The
+// memifs, bridge domains and i/f's of a host's vswitch are counted from the
+// keys the entities rendered to its agent, and checked against the limits
+// of the host, or of the system.  A chain is placed on its hosts only if
+// they can take what it renders on them: one vswitch i/f per container
+// element, a memif for the memif containers, and a bridge domain per host
+// for an l2fib chain with its own bd_parms.  The chains that have not been
+// rendered yet are counted as they will be, and what a re-posted chain
+// rendered before is not counted twice.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils"
+)
+
+// the driver's name of a container's memif on the vswitch starts with it
+const vswitchMemifIfPrefix = "IF_MEMIF_VSWITCH_"
+
+// HostResources are the vswitch objects rendered on a host, and its limits
+type HostResources struct {
+	Host          string                    `json:"host"`
+	Memifs        uint32                    `json:"memifs"`
+	BridgeDomains uint32                    `json:"bridge_domains"`
+	Interfaces    uint32                    `json:"interfaces"`
+	Limits        controller.ResourceLimits `json:"limits"`
+}
+
+// exceeded returns the first limit the host is over, "" if it is within them
+func (hr *HostResources) exceeded() string {
+
+	switch {
+	case hr.Limits.MaxMemifs != 0 && hr.Memifs > hr.Limits.MaxMemifs:
+		return fmt.Sprintf("%d memifs of max %d", hr.Memifs, hr.Limits.MaxMemifs)
+	case hr.Limits.MaxBridgeDomains != 0 && hr.BridgeDomains > hr.Limits.MaxBridgeDomains:
+		return fmt.Sprintf("%d bridge domains of max %d", hr.BridgeDomains, hr.Limits.MaxBridgeDomains)
+	case hr.Limits.MaxInterfaces != 0 && hr.Interfaces > hr.Limits.MaxInterfaces:
+		return fmt.Sprintf("%d i/f's of max %d", hr.Interfaces, hr.Limits.MaxInterfaces)
+	}
+	return ""
+}
+
+// hostResourceLimits returns the limits of the host, the system ones where the host sets none
+func (sfcCtrlPlugin *SfcControllerPluginHandler) hostResourceLimits(
+	he *controller.HostEntity) controller.ResourceLimits {
+
+	limits := controller.ResourceLimits{}
+	if sysLimits := sfcCtrlPlugin.ramConfigCache.SysParms.GetHostResourceLimits(); sysLimits != nil {
+		limits = *sysLimits
+	}
+	if heLimits := he.GetResourceLimits(); heLimits != nil {
+		if heLimits.MaxMemifs != 0 {
+			limits.MaxMemifs = heLimits.MaxMemifs
+		}
+		if heLimits.MaxBridgeDomains != 0 {
+			limits.MaxBridgeDomains = heLimits.MaxBridgeDomains
+		}
+		if heLimits.MaxInterfaces != 0 {
+			limits.MaxInterfaces = heLimits.MaxInterfaces
+		}
+	}
+	return limits
+}
+
+// renderedHostResources counts the objects the entities, but the skipped one, rendered on the vswitch of each
+// host, it also returns the entities that rendered keys
+func (sfcCtrlPlugin *SfcControllerPluginHandler) renderedHostResources(
+	skipEntity string) (map[string]*HostResources, map[string]bool, error) {
+
+	resources := make(map[string]*HostResources)
+	for heName, he := range sfcCtrlPlugin.ramConfigCache.HEs {
+		resources[heName] = &HostResources{Host: heName, Limits: sfcCtrlPlugin.hostResourceLimits(&he)}
+	}
+
+	// the keys shared by entities, ie a host's bridge, are counted once
+	keys := make(map[string]struct{})
+	rendered := make(map[string]bool)
+	err := sfcCtrlPlugin.DatastoreEntityKeysIterate(func(entity string, entityKeys *controller.EntityKeys) {
+		rendered[entity] = len(entityKeys.Keys) != 0
+		if entity == skipEntity {
+			return
+		}
+		for _, key := range entityKeys.Keys {
+			keys[key] = struct{}{}
+		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for key := range keys {
+		vppLabel := utils.GetVppEtcdlabel(key)
+		hr, exists := resources[vppLabel]
+		if !exists {
+			continue
+		}
+		if ifPrefix := utils.InterfacePrefixKey(vppLabel); strings.HasPrefix(key, ifPrefix) {
+			hr.Interfaces++
+			if strings.HasPrefix(strings.TrimPrefix(key, ifPrefix), vswitchMemifIfPrefix) {
+				hr.Memifs++
+			}
+		} else if strings.HasPrefix(key, utils.L2BridgeDomainKeyPrefix(vppLabel)) {
+			hr.BridgeDomains++
+		}
+	}
+
+	return resources, rendered, nil
+}
+
+// sfcResourceDemand returns the objects the chain renders on the vswitch of each of its hosts
+func sfcResourceDemand(sfc *controller.SfcEntity) map[string]*HostResources {
+
+	demand := make(map[string]*HostResources)
+	for _, sfcElement := range sfc.GetElements() {
+		if !sfcElementIsContainer(sfcElement) || sfc.Type == controller.SfcType_SFC_EW_MEMIF {
+			continue // the memif pairs of an e-w memif chain are wired container to container
+		}
+		hr, exists := demand[sfcElement.EtcdVppSwitchKey]
+		if !exists {
+			hr = &HostResources{Host: sfcElement.EtcdVppSwitchKey}
+			if sfc.Type == controller.SfcType_SFC_EW_BD_L2FIB && sfc.BdParms != nil {
+				hr.BridgeDomains = 1
+			}
+			demand[sfcElement.EtcdVppSwitchKey] = hr
+		}
+		hr.Interfaces++
+		switch sfcElement.Type {
+		case controller.SfcElementType_VPP_CONTAINER_MEMIF, controller.SfcElementType_NON_VPP_CONTAINER_MEMIF:
+			hr.Memifs++
+		}
+	}
+	return demand
+}
+
+// addResources adds the demand to the resources of the hosts
+func addResources(resources map[string]*HostResources, demand map[string]*HostResources) {
+	for heName, d := range demand {
+		if hr, exists := resources[heName]; exists {
+			hr.Memifs += d.Memifs
+			hr.BridgeDomains += d.BridgeDomains
+			hr.Interfaces += d.Interfaces
+		}
+	}
+}
+
+// hostResources returns the resources of the host, of all hosts if it is empty
+func (sfcCtrlPlugin *SfcControllerPluginHandler) hostResources(heName string) ([]HostResources, error) {
+
+	resources, _, err := sfcCtrlPlugin.renderedHostResources("")
+	if err != nil {
+		return nil, err
+	}
+
+	report := make([]HostResources, 0)
+	for _, name := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		if heName == "" || name == heName {
+			report = append(report, *resources[name])
+		}
+	}
+	return report, nil
+}
+
+// committedHostResources returns the resources of the hosts committed to the chains but the named one, the
+// chains that are not rendered yet are counted as they will be
+func (sfcCtrlPlugin *SfcControllerPluginHandler) committedHostResources(
+	sfcName string) (map[string]*HostResources, error) {
+
+	resources, rendered, err := sfcCtrlPlugin.renderedHostResources(controller.SfcEntityKind + "/" + sfcName)
+	if err != nil {
+		return nil, err
+	}
+	for otherName, other := range sfcCtrlPlugin.ramConfigCache.SFCs {
+		if otherName != sfcName && !rendered[controller.SfcEntityKind+"/"+otherName] {
+			addResources(resources, sfcResourceDemand(&other))
+		}
+	}
+	return resources, nil
+}
+
+// validateSfcHostResources checks the hosts of the chain can take what it renders on them
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSfcHostResources(sfc *controller.SfcEntity) error {
+
+	demand := sfcResourceDemand(sfc)
+	limited := false
+	for heName := range demand {
+		if he, exists := sfcCtrlPlugin.ramConfigCache.HEs[heName]; exists {
+			limited = limited || sfcCtrlPlugin.hostResourceLimits(&he) != controller.ResourceLimits{}
+		}
+	}
+	if !limited {
+		return nil
+	}
+
+	resources, err := sfcCtrlPlugin.committedHostResources(sfc.Name)
+	if err != nil {
+		return err
+	}
+	addResources(resources, demand)
+
+	heNames := make([]string, 0, len(demand))
+	for heName := range demand {
+		heNames = append(heNames, heName)
+	}
+	sort.Strings(heNames)
+	for _, heName := range heNames {
+		if hr, exists := resources[heName]; exists {
+			if exceeded := hr.exceeded(); exceeded != "" {
+				return fmt.Errorf("Invalid placement of sfc: '%s', he: '%s' would have %s", sfc.Name, heName,
+					exceeded)
+			}
+		}
+	}
+
+	return nil
+}
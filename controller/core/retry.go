@@ -0,0 +1,228 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The retry queue of the failed renders is implemented in this file.  When
+// an entity's render fails, or only partially renders, its retry is recorded
+// in the datastore with the attempts made so far, and a background loop
+// renders the entity again once its backoff has passed.  The backoff doubles
+// with every failed retry, and after render_retry_max_attempts the entity is
+// left alone, its status says the retries are exhausted.  A successful render
+// removes the retry, a re-post of the entity starts its retries over.
+
+package core
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// how often the loop looks for retries that are due
+const renderRetryInterval = time.Second
+
+// renderRetryUpdate updates the entity's retry with the outcome of its render, and its status with its retry
+func (sfcCtrlPlugin *SfcControllerPluginHandler) renderRetryUpdate(kind string, status *controller.EntityStatus,
+	now int64) {
+
+	if sfcCtrlPlugin.renderRetries == nil {
+		sfcCtrlPlugin.renderRetries = make(map[string]*controller.RenderRetry)
+	}
+	key := controller.RenderRetryKey(kind, status.Name)
+	retry, exists := sfcCtrlPlugin.renderRetries[key]
+
+	switch status.State {
+	case controller.RenderStateType_RENDER_ERROR, controller.RenderStateType_PARTIALLY_RENDERED:
+	case controller.RenderStateType_DEPENDENCY_PENDING, controller.RenderStateType_AGENT_PENDING:
+		return
+	default:
+		if exists {
+			delete(sfcCtrlPlugin.renderRetries, key)
+			if _, err := sfcCtrlPlugin.db.Delete(key); err != nil {
+				log.Errorf("renderRetryUpdate: error deleting key: '%s': %s", key, err)
+			}
+		}
+		return
+	}
+
+	if !exists {
+		retry = &controller.RenderRetry{Kind: kind, Name: status.Name}
+		sfcCtrlPlugin.renderRetries[key] = retry
+	}
+
+	sp := &sfcCtrlPlugin.ramConfigCache.SysParms
+	if key == sfcCtrlPlugin.renderRetryKey {
+		retry.Attempts++
+	} else {
+		// the entity was rendered on its own, ie re-posted, so it gets all of its retries again
+		retry.Attempts = 0
+		retry.Exhausted = false
+	}
+	retry.LastError = status.Message
+	retry.NextAttempt = 0
+	if retry.Attempts >= sp.RenderRetryMaxAttempts {
+		log.Errorf("renderRetryUpdate: '%s' still failing after %d retries, giving up: %s", key, retry.Attempts,
+			retry.LastError)
+		retry.Exhausted = true
+	} else {
+		backoff := uint64(sp.RenderRetryBackoff) << retry.Attempts
+		if backoff > uint64(sp.RenderRetryBackoffMax) {
+			backoff = uint64(sp.RenderRetryBackoffMax)
+		}
+		retry.NextAttempt = now + int64(backoff)
+	}
+
+	log.Infof("renderRetryUpdate: setting key: '%s': %v", key, retry)
+	if err := sfcCtrlPlugin.db.Put(key, retry); err != nil {
+		log.Errorf("renderRetryUpdate: error storing key: '%s': %s", key, err)
+	}
+
+	status.RetryAttempts = retry.Attempts
+	status.NextRetry = retry.NextAttempt
+	status.RetriesExhausted = retry.Exhausted
+}
+
+// renderRetryLoop runs until the plugin is closed, rendering the entities whose retries are due, it holds the
+// http mutex like the REST requests so the retried renders do not interleave with their changes to the cache
+func (sfcCtrlPlugin *SfcControllerPluginHandler) renderRetryLoop() {
+	for {
+		sfcCtrlPlugin.HttpMutex.Lock()
+		sfcCtrlPlugin.renderRetriesDue(time.Now().Unix())
+		sfcCtrlPlugin.HttpMutex.Unlock()
+
+		select {
+		case <-sfcCtrlPlugin.renderRetryDone:
+			return
+		case <-time.After(renderRetryInterval):
+		}
+	}
+}
+
+// renderRetriesDue renders the entities whose backoff has passed, the outcome is recorded when their
+// statuses are flushed
+func (sfcCtrlPlugin *SfcControllerPluginHandler) renderRetriesDue(now int64) {
+
+	var keys []string
+	for key, retry := range sfcCtrlPlugin.renderRetries {
+		if !retry.Exhausted && retry.NextAttempt <= now {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		retry := sfcCtrlPlugin.renderRetries[key]
+		if retry == nil || retry.Exhausted {
+			continue // rendered meanwhile, ie as a chain waiting for an earlier one
+		}
+		log.Infof("renderRetriesDue: retrying '%s', attempt %d", key, retry.Attempts+1)
+
+		sfcCtrlPlugin.renderRetryKey = key
+		configured := sfcCtrlPlugin.renderEntity(retry.Kind, retry.Name)
+		sfcCtrlPlugin.entityStatusFlush()
+		sfcCtrlPlugin.renderRetryKey = ""
+
+		if !configured {
+			log.Infof("renderRetriesDue: '%s' is not configured anymore, dropping its retry", key)
+			delete(sfcCtrlPlugin.renderRetries, key)
+			if _, err := sfcCtrlPlugin.db.Delete(key); err != nil {
+				log.Errorf("renderRetriesDue: error deleting key: '%s': %s", key, err)
+			}
+		}
+	}
+}
+
+// renderEntity renders the entity of the kind again, it returns false if the entity is not configured
+func (sfcCtrlPlugin *SfcControllerPluginHandler) renderEntity(kind string, name string) bool {
+
+	switch kind {
+	case controller.HostEntityKind:
+		he, exists := sfcCtrlPlugin.ramConfigCache.HEs[name]
+		if !exists {
+			return false
+		}
+		sfcCtrlPlugin.renderHostEntity(&he, true, true)
+
+	case controller.ExternalEntityKind:
+		ee, exists := sfcCtrlPlugin.ramConfigCache.EEs[name]
+		if !exists {
+			return false
+		}
+		sfcCtrlPlugin.renderExternalEntity(&ee, true, true)
+
+	case controller.SfcEntityKind:
+		sfc, exists := sfcCtrlPlugin.ramConfigCache.SFCs[name]
+		if !exists {
+			return false
+		}
+		if err := sfcCtrlPlugin.renderServiceFunctionEntity(&sfc); err != nil {
+			log.Errorf("renderEntity: sfc: '%s': %s", sfc.Name, err)
+			break
+		}
+		if err := sfcCtrlPlugin.renderPendingSFCs(); err != nil {
+			log.Errorf("renderEntity: sfc's waiting for: '%s': %s", sfc.Name, err)
+		}
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+// DatastoreRenderRetryRetrieveAll loads the retries of the failed renders from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreRenderRetryRetrieveAll() error {
+
+	sfcCtrlPlugin.renderRetries = make(map[string]*controller.RenderRetry)
+
+	return sfcCtrlPlugin.DatastoreRenderRetryIterate(func(key string, retry *controller.RenderRetry) {
+		sfcCtrlPlugin.renderRetries[key] = retry
+		log.Infof("DatastoreRenderRetryRetrieveAll: adding retry: '%s': %v", key, retry)
+	})
+}
+
+// DatastoreRenderRetryDeleteAll removes the retries of the failed renders from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreRenderRetryDeleteAll() error {
+
+	sfcCtrlPlugin.renderRetries = nil
+
+	return sfcCtrlPlugin.DatastoreRenderRetryIterate(func(key string, retry *controller.RenderRetry) {
+		log.Infof("DatastoreRenderRetryDeleteAll: deleting retry: '%s'", key)
+		sfcCtrlPlugin.db.Delete(key)
+	})
+}
+
+// DatastoreRenderRetryIterate iterates over the retries of the failed renders in the sfc tree in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreRenderRetryIterate(actionFunc func(key string,
+	retry *controller.RenderRetry)) error {
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(controller.RenderRetryKeyPrefix())
+	if err != nil {
+		log.Error("DatastoreRenderRetryIterate: ", err)
+		return err
+	}
+
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return nil
+		}
+		retry := &controller.RenderRetry{}
+		if err := kv.GetValue(retry); err != nil {
+			log.Error("DatastoreRenderRetryIterate: ", kv.GetKey(), err)
+			return err
+		}
+		actionFunc(kv.GetKey(), retry)
+	}
+}
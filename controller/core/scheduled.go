@@ -0,0 +1,234 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The scheduling of changes into maintenance windows is implemented in this
+// file.  A scheduled change is a batch of ee's, he's and sfc's, like a bulk
+// apply, that is rendered at the start of its window.  The batch is validated
+// against the running config when it is submitted, so a change that could
+// never apply is refused right away, and it is validated again when its
+// window starts, as the config may have changed meanwhile.  The changes are
+// stored in the datastore, so they survive a restart of the controller, and
+// a change whose window ended while the controller was down expires instead
+// of being rendered late.  An applied change is kept, with its outcome, until
+// it is deleted.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// the states of a scheduled change
+const (
+	ScheduledChangeScheduled = "scheduled"
+	ScheduledChangeApplied   = "applied"
+	ScheduledChangeFailed    = "failed"
+	ScheduledChangeExpired   = "expired"
+)
+
+// how often the loop looks for changes whose window started
+const scheduledChangeInterval = time.Second
+
+// scheduledChangeBatch returns the entities of the change as a bulk apply batch
+func scheduledChangeBatch(sc *controller.ScheduledChange) *BulkApply {
+	return &BulkApply{
+		ExternalEntities: sc.ExternalEntities,
+		HostEntities:     sc.HostEntities,
+		SfcEntities:      sc.SfcEntities,
+	}
+}
+
+// scheduleChange validates the change against the running config and stores it, the entities that fail
+// validation are in the results
+func (sfcCtrlPlugin *SfcControllerPluginHandler) scheduleChange(sc *controller.ScheduledChange,
+	now int64) (*BulkApplyResult, error) {
+
+	if sc.Name == "" {
+		return nil, fmt.Errorf("Missing scheduled change name")
+	}
+	if existing, exists := sfcCtrlPlugin.scheduledChanges[sc.Name]; exists &&
+		existing.State != ScheduledChangeScheduled {
+		return nil, fmt.Errorf("Invalid scheduled change: '%s', it is %s, delete it first", sc.Name,
+			existing.State)
+	}
+	if sc.WindowStart <= 0 {
+		return nil, fmt.Errorf("Invalid window_start: %d for scheduled change: '%s'", sc.WindowStart, sc.Name)
+	}
+	if sc.WindowEnd != 0 && (sc.WindowEnd <= sc.WindowStart || sc.WindowEnd <= now) {
+		return nil, fmt.Errorf("Invalid window_end: %d for scheduled change: '%s', it must be after the window "+
+			"start and in the future", sc.WindowEnd, sc.Name)
+	}
+	if len(sc.ExternalEntities)+len(sc.HostEntities)+len(sc.SfcEntities) == 0 {
+		return nil, fmt.Errorf("Invalid scheduled change: '%s', it has no entities", sc.Name)
+	}
+
+	// the batch is staged to validate it, then the running config is put back
+	current := sfcCtrlPlugin.ramCacheToConfigVersion()
+	result := &BulkApplyResult{}
+	_, err := sfcCtrlPlugin.bulkStage(scheduledChangeBatch(sc), result)
+	sfcCtrlPlugin.configVersionToRAMCache(current)
+	if err != nil {
+		return result, err
+	}
+
+	sc.State = ScheduledChangeScheduled
+	sc.Message, sc.AppliedAt, sc.ConfigVersion = "", 0, 0
+
+	log.Infof("scheduleChange: '%s' scheduled at: %s", sc.Name, time.Unix(sc.WindowStart, 0))
+
+	return result, sfcCtrlPlugin.DatastoreScheduledChangeCreate(sc)
+}
+
+// scheduledChangeLoop runs until the plugin is closed, applying the changes whose window started, expiring
+// the chains whose ttl expired, see sfc_expiry.go, and deleting the chains whose grace period ended, see
+// sfc_soft_delete.go.  It holds the http mutex like the REST requests, which read and write the scheduled
+// changes and the cache alike
+func (sfcCtrlPlugin *SfcControllerPluginHandler) scheduledChangeLoop() {
+	for {
+		sfcCtrlPlugin.HttpMutex.Lock()
+		now := time.Now().Unix()
+		sfcCtrlPlugin.scheduledChangesDue(now)
+		sfcCtrlPlugin.expireSfcs(now)
+		sfcCtrlPlugin.deleteGraceEndedSfcs(now)
+		sfcCtrlPlugin.HttpMutex.Unlock()
+
+		select {
+		case <-sfcCtrlPlugin.scheduledChangeDone:
+			return
+		case <-time.After(scheduledChangeInterval):
+		}
+	}
+}
+
+// scheduledChangesDue applies the changes whose window started, in window start order, and expires those whose
+// window ended before they could be applied
+func (sfcCtrlPlugin *SfcControllerPluginHandler) scheduledChangesDue(now int64) {
+
+	var due []*controller.ScheduledChange
+	for _, sc := range sfcCtrlPlugin.scheduledChanges {
+		if sc.State == ScheduledChangeScheduled && sc.WindowStart <= now {
+			due = append(due, sc)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].WindowStart != due[j].WindowStart {
+			return due[i].WindowStart < due[j].WindowStart
+		}
+		return due[i].Name < due[j].Name
+	})
+
+	for _, sc := range due {
+		if sc.WindowEnd != 0 && sc.WindowEnd < now {
+			log.Warnf("scheduledChangesDue: '%s' expired, its window ended at: %s", sc.Name,
+				time.Unix(sc.WindowEnd, 0))
+			sc.State = ScheduledChangeExpired
+			sc.Message = "the window ended before the change was applied"
+		} else {
+			sfcCtrlPlugin.applyScheduledChange(sc, now)
+		}
+		if err := sfcCtrlPlugin.DatastoreScheduledChangeCreate(sc); err != nil {
+			log.Errorf("scheduledChangesDue: error storing: '%s': %s", sc.Name, err)
+		}
+	}
+}
+
+// applyScheduledChange renders the change's batch, all of it or none, and records the outcome in the change
+func (sfcCtrlPlugin *SfcControllerPluginHandler) applyScheduledChange(sc *controller.ScheduledChange, now int64) {
+
+	log.Infof("applyScheduledChange: applying '%s'", sc.Name)
+
+	result, err := sfcCtrlPlugin.bulkApply(scheduledChangeBatch(sc),
+		fmt.Sprintf("scheduled change %s by %s", sc.Name, sc.SubmittedBy))
+	sc.AppliedAt = now
+	if !result.Applied {
+		sc.State = ScheduledChangeFailed
+		sc.Message = err.Error()
+		for _, r := range result.Results {
+			if r.Error != "" {
+				sc.Message += fmt.Sprintf("; %s: '%s': %s", r.Kind, r.Name, r.Error)
+			}
+		}
+		log.Errorf("applyScheduledChange: '%s' failed: %s", sc.Name, sc.Message)
+		return
+	}
+
+	sc.State = ScheduledChangeApplied
+	sc.ConfigVersion = result.Version
+	if err != nil {
+		sc.Message = err.Error()
+	}
+}
+
+// DatastoreScheduledChangeCreate stores the scheduled change in the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreScheduledChangeCreate(sc *controller.ScheduledChange) error {
+
+	key := controller.ScheduledChangeKey(sc.Name)
+
+	log.Infof("DatastoreScheduledChangeCreate: setting key: '%s'", key)
+
+	if err := sfcCtrlPlugin.db.Put(key, sc); err != nil {
+		log.Errorf("DatastoreScheduledChangeCreate: error storing key: '%s': %s", key, err)
+		return err
+	}
+	if sfcCtrlPlugin.scheduledChanges == nil {
+		sfcCtrlPlugin.scheduledChanges = make(map[string]*controller.ScheduledChange)
+	}
+	sfcCtrlPlugin.scheduledChanges[sc.Name] = sc
+	return nil
+}
+
+// DatastoreScheduledChangeDelete removes the scheduled change from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreScheduledChangeDelete(name string) error {
+
+	key := controller.ScheduledChangeKey(name)
+
+	log.Infof("DatastoreScheduledChangeDelete: deleting key: '%s'", key)
+
+	if _, err := sfcCtrlPlugin.db.Delete(key); err != nil {
+		log.Errorf("DatastoreScheduledChangeDelete: error deleting key: '%s': %s", key, err)
+		return err
+	}
+	delete(sfcCtrlPlugin.scheduledChanges, name)
+	return nil
+}
+
+// DatastoreScheduledChangeRetrieveAll loads the scheduled changes from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreScheduledChangeRetrieveAll() error {
+
+	sfcCtrlPlugin.scheduledChanges = make(map[string]*controller.ScheduledChange)
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(controller.ScheduledChangeKeyPrefix())
+	if err != nil {
+		log.Error("DatastoreScheduledChangeRetrieveAll: ", err)
+		return err
+	}
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return nil
+		}
+		sc := &controller.ScheduledChange{}
+		if err := kv.GetValue(sc); err != nil {
+			log.Error("DatastoreScheduledChangeRetrieveAll: ", kv.GetKey(), err)
+			return err
+		}
+		log.Infof("DatastoreScheduledChangeRetrieveAll: adding scheduled change: '%s', state: %s", sc.Name,
+			sc.State)
+		sfcCtrlPlugin.scheduledChanges[sc.Name] = sc
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+func TestScheduleChangeWindows(t *testing.T) {
+
+	const now = 1000
+	chains := func() []*controller.SfcEntity {
+		return []*controller.SfcEntity{chainPtr(testChain("chain1"))}
+	}
+	tests := []struct {
+		name  string
+		sc    *controller.ScheduledChange
+		valid bool
+	}{
+		{"no name", &controller.ScheduledChange{WindowStart: now + 60, SfcEntities: chains()}, false},
+		{"no window start", &controller.ScheduledChange{Name: "c", SfcEntities: chains()}, false},
+		{"end before start", &controller.ScheduledChange{Name: "c", WindowStart: now + 60, WindowEnd: now + 30,
+			SfcEntities: chains()}, false},
+		{"end in the past", &controller.ScheduledChange{Name: "c", WindowStart: now - 60, WindowEnd: now - 30,
+			SfcEntities: chains()}, false},
+		{"no entities", &controller.ScheduledChange{Name: "c", WindowStart: now + 60}, false},
+		{"open ended", &controller.ScheduledChange{Name: "c", WindowStart: now + 60, SfcEntities: chains()}, true},
+		{"window", &controller.ScheduledChange{Name: "c", WindowStart: now - 60, WindowEnd: now + 60,
+			SfcEntities: chains()}, true},
+	}
+	for _, test := range tests {
+		sfcCtrlPlugin, _ := newTestPlugin(t)
+
+		_, err := sfcCtrlPlugin.scheduleChange(test.sc, now)
+		if test.valid && err != nil {
+			t.Errorf("%s: the change is refused: %s", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: the change is not refused", test.name)
+		}
+		if _, scheduled := sfcCtrlPlugin.scheduledChanges[test.sc.Name]; scheduled != test.valid {
+			t.Errorf("%s: scheduled: %t, expected: %t", test.name, scheduled, test.valid)
+		}
+		if _, staged := sfcCtrlPlugin.ramConfigCache.SFCs["chain1"]; staged {
+			t.Errorf("%s: the change is left staged in the ram cache", test.name)
+		}
+	}
+}
+
+// a change whose entities fail validation is refused when it is submitted, not when its window starts
+func TestScheduleChangeInvalidBatch(t *testing.T) {
+
+	sfcCtrlPlugin, _ := newTestPlugin(t)
+
+	unknownHost := testChain("chain1")
+	unknownHost.Elements[0].EtcdVppSwitchKey = "vswitch2"
+	sc := &controller.ScheduledChange{Name: "c", WindowStart: 2000, SfcEntities: []*controller.SfcEntity{&unknownHost}}
+	result, err := sfcCtrlPlugin.scheduleChange(sc, 1000)
+	if err == nil {
+		t.Fatalf("the change is not refused")
+	}
+	if len(result.Results) != 1 || result.Results[0].Result != BulkResultInvalid {
+		t.Errorf("the invalid sfc is not in the results: %+v", result.Results)
+	}
+}
+
+func TestScheduledChangesDue(t *testing.T) {
+
+	sfcCtrlPlugin, _ := newTestPlugin(t)
+
+	const now = 1000
+	for _, sc := range []*controller.ScheduledChange{
+		{Name: "due", WindowStart: now - 10, WindowEnd: now + 60,
+			SfcEntities: []*controller.SfcEntity{chainPtr(testChain("chain1"))}},
+		{Name: "later", WindowStart: now + 60, SfcEntities: []*controller.SfcEntity{chainPtr(testChain("chain2"))}},
+	} {
+		if _, err := sfcCtrlPlugin.scheduleChange(sc, now-60); err != nil {
+			t.Fatal(err)
+		}
+	}
+	missed := &controller.ScheduledChange{Name: "missed", WindowStart: now - 60, WindowEnd: now - 30,
+		State: ScheduledChangeScheduled, SfcEntities: []*controller.SfcEntity{chainPtr(testChain("chain3"))}}
+	if err := sfcCtrlPlugin.DatastoreScheduledChangeCreate(missed); err != nil {
+		t.Fatal(err)
+	}
+
+	sfcCtrlPlugin.scheduledChangesDue(now)
+
+	for name, state := range map[string]string{"due": ScheduledChangeApplied, "later": ScheduledChangeScheduled,
+		"missed": ScheduledChangeExpired} {
+		if sc := sfcCtrlPlugin.scheduledChanges[name]; sc.State != state {
+			t.Errorf("change: '%s' is %s, expected: %s: %s", name, sc.State, state, sc.Message)
+		}
+	}
+	for name, wired := range map[string]bool{"chain1": true, "chain2": false, "chain3": false} {
+		if _, exists := sfcCtrlPlugin.ramConfigCache.SFCs[name]; exists != wired {
+			t.Errorf("sfc: '%s' in the ram cache: %t, expected: %t", name, exists, wired)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The placement scheduler is implemented in this file.  With the
+// placement-scheduler feature on, the container elements of a chain that
+// name no etcd_vpp_switch_key are given a host before the chain is
+// validated and rendered.  A container that another chain already placed
+// stays on its host, as does one whose affinity containers are placed, and
+// the others are placed one by one, in the order of the elements, on the
+// least loaded host, ie the one with the fewest vswitch i/f's once the
+// chain's are added, that matches the chain's placement_selector, whose
+// agent is up, that can take what the chain renders on it within its
+// resource limits, and that holds none of the anti_affinity containers of
+// the container or of its affinity containers.  The hosts the elements are
+// given are stored with the chain, so the placement stays as it is across
+// restarts and re-renders.
+//
+// The affinity and anti-affinity of the containers, in every chain, are
+// also validated against where the containers are, placed by the scheduler
+// or not.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/features"
+)
+
+var featurePlacementScheduler = features.Register("placement-scheduler",
+	"the container elements without an etcd_vpp_switch_key are placed on a host by the controller", false)
+
+// containerPlacement is where the containers are, and which containers each one must, or must not, share a
+// host with
+type containerPlacement struct {
+	hosts        map[string]string          // container -> host
+	affinity     map[string]map[string]bool // container -> containers
+	antiAffinity map[string]map[string]bool // container -> containers
+}
+
+// newContainerPlacement returns where the containers of the chains, this one included, are placed, and
+// their affinity and anti-affinity
+func (sfcCtrlPlugin *SfcControllerPluginHandler) newContainerPlacement(
+	sfc *controller.SfcEntity) *containerPlacement {
+
+	cp := &containerPlacement{
+		hosts:        make(map[string]string),
+		affinity:     make(map[string]map[string]bool),
+		antiAffinity: make(map[string]map[string]bool),
+	}
+	addElements := func(sfcElements []*controller.SfcEntity_SfcElement) {
+		for _, sfcElement := range sfcElements {
+			if !sfcElementIsContainer(sfcElement) {
+				continue
+			}
+			if sfcElement.EtcdVppSwitchKey != "" {
+				cp.hosts[sfcElement.Container] = sfcElement.EtcdVppSwitchKey
+			}
+			for _, other := range sfcElement.Affinity {
+				addContainerRelation(cp.affinity, sfcElement.Container, other)
+			}
+			for _, other := range sfcElement.AntiAffinity {
+				addContainerRelation(cp.antiAffinity, sfcElement.Container, other)
+			}
+		}
+	}
+	for _, sfcName := range sortedKeysSFC(sfcCtrlPlugin.ramConfigCache.SFCs) {
+		if other := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]; sfcName != sfc.Name {
+			addElements(other.GetElements())
+		}
+	}
+	addElements(sfc.GetElements())
+
+	return cp
+}
+
+// addContainerRelation relates the containers both ways
+func addContainerRelation(relation map[string]map[string]bool, container string, other string) {
+	for _, pair := range [][2]string{{container, other}, {other, container}} {
+		if relation[pair[0]] == nil {
+			relation[pair[0]] = make(map[string]bool)
+		}
+		relation[pair[0]][pair[1]] = true
+	}
+}
+
+func sortedContainers(containers map[string]bool) []string {
+	sorted := make([]string, 0, len(containers))
+	for c := range containers {
+		sorted = append(sorted, c)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// affinityGroup returns the container and the containers that must share its host, directly or through
+// one another
+func (cp *containerPlacement) affinityGroup(container string) map[string]bool {
+
+	group := map[string]bool{container: true}
+	pending := []string{container}
+	for len(pending) != 0 {
+		c := pending[0]
+		pending = pending[1:]
+		for other := range cp.affinity[c] {
+			if !group[other] {
+				group[other] = true
+				pending = append(pending, other)
+			}
+		}
+	}
+	return group
+}
+
+// affinityHost returns the host of the first placed container of the container's affinity group, "" if
+// none is placed
+func (cp *containerPlacement) affinityHost(container string) string {
+	for _, c := range sortedContainers(cp.affinityGroup(container)) {
+		if host := cp.hosts[c]; host != "" {
+			return host
+		}
+	}
+	return ""
+}
+
+// conflicts returns a container on the host the container must not share it with, "" if there is none
+func (cp *containerPlacement) conflicts(container string, host string) string {
+	for _, other := range sortedContainers(cp.antiAffinity[container]) {
+		if cp.hosts[other] == host {
+			return other
+		}
+	}
+	return ""
+}
+
+// placeSfcElements gives a host to the chain's container elements without one
+func (sfcCtrlPlugin *SfcControllerPluginHandler) placeSfcElements(sfc *controller.SfcEntity) error {
+
+	ls, err := parseLabelSelector(sfc.PlacementSelector)
+	if err != nil {
+		return fmt.Errorf("Invalid placement_selector for sfc: '%s': %s", sfc.Name, err)
+	}
+
+	var unplaced []string
+	seen := make(map[string]bool)
+	for _, sfcElement := range sfc.GetElements() {
+		if sfcElementIsContainer(sfcElement) && sfcElement.EtcdVppSwitchKey == "" && !seen[sfcElement.Container] {
+			seen[sfcElement.Container] = true
+			unplaced = append(unplaced, sfcElement.Container)
+		}
+	}
+	if len(unplaced) == 0 || !features.Enabled(featurePlacementScheduler, sfc.GetFeatureFlags(),
+		sfcCtrlPlugin.ramConfigCache.SysParms.GetFeatureFlags()) {
+		return nil
+	}
+
+	resources, err := sfcCtrlPlugin.committedHostResources(sfc.Name)
+	if err != nil {
+		return err
+	}
+
+	cp := sfcCtrlPlugin.newContainerPlacement(sfc)
+	for _, container := range unplaced {
+		host := cp.hosts[container]
+		if host == "" {
+			host = cp.affinityHost(container)
+		}
+		if host == "" {
+			if host = sfcCtrlPlugin.leastLoadedHost(sfc, container, ls, cp, resources); host == "" {
+				return fmt.Errorf("Invalid placement of sfc: '%s', no host can take container: '%s'", sfc.Name,
+					container)
+			}
+		}
+		cp.hosts[container] = host
+		for _, sfcElement := range sfc.GetElements() {
+			if sfcElementIsContainer(sfcElement) && sfcElement.Container == container {
+				sfcElement.EtcdVppSwitchKey = host
+			}
+		}
+		log.Infof("placeSfcElements: sfc: '%s', container: '%s' placed on he: '%s'", sfc.Name, container, host)
+	}
+
+	return nil
+}
+
+// leastLoadedHost returns the host the container, and its affinity group, is placed on, "" if no host can
+// take them
+func (sfcCtrlPlugin *SfcControllerPluginHandler) leastLoadedHost(sfc *controller.SfcEntity, container string,
+	ls labelSelector, cp *containerPlacement, resources map[string]*HostResources) string {
+
+	group := cp.affinityGroup(container)
+
+	best := ""
+	var bestLoad uint32
+nextHost:
+	for _, heName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		if !ls.matches(sfcCtrlPlugin.ramConfigCache.HEs[heName].Labels) {
+			continue
+		}
+		if _, down := sfcCtrlPlugin.agentBreakers[heName]; down {
+			continue
+		}
+		if sfcCtrlPlugin.kernelHE(heName) && !sfcContainersKernelPlaceable(sfc, group) {
+			continue
+		}
+		for _, c := range sortedContainers(group) {
+			if other := cp.conflicts(c, heName); other != "" {
+				log.Debugf("leastLoadedHost: container: '%s' not placed with: '%s' on he: '%s'", c, other,
+					heName)
+				continue nextHost
+			}
+		}
+
+		// what the chain renders on the host with the group on it too
+		onHost := &controller.SfcEntity{Type: sfc.Type, BdParms: sfc.BdParms}
+		for _, sfcElement := range sfc.GetElements() {
+			if sfcElementIsContainer(sfcElement) &&
+				(group[sfcElement.Container] || cp.hosts[sfcElement.Container] == heName) {
+				onHost.Elements = append(onHost.Elements, &controller.SfcEntity_SfcElement{
+					Container: sfcElement.Container, EtcdVppSwitchKey: heName, Type: sfcElement.Type})
+			}
+		}
+		hr := *resources[heName]
+		addResources(map[string]*HostResources{heName: &hr}, sfcResourceDemand(onHost))
+		if exceeded := hr.exceeded(); exceeded != "" {
+			log.Debugf("leastLoadedHost: container: '%s' not placed on he: '%s', it would have %s", container,
+				heName, exceeded)
+			continue
+		}
+
+		if best == "" || hr.Interfaces < bestLoad {
+			best, bestLoad = heName, hr.Interfaces
+		}
+	}
+	return best
+}
+
+// validateSfcAffinity checks the chain's containers share a host with their affinity containers, and not
+// with their anti-affinity ones, of any chain
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSfcAffinity(sfc *controller.SfcEntity) error {
+
+	cp := sfcCtrlPlugin.newContainerPlacement(sfc)
+	for _, sfcElement := range sfc.GetElements() {
+		if !sfcElementIsContainer(sfcElement) {
+			continue
+		}
+		element := sfcElement.Container + "/" + sfcElement.PortLabel
+		group := cp.affinityGroup(sfcElement.Container)
+
+		for _, other := range sortedContainers(cp.antiAffinity[sfcElement.Container]) {
+			if group[other] {
+				return fmt.Errorf("Invalid anti_affinity for element: '%s', sfc: '%s', container: '%s' must "+
+					"share its host by affinity", element, sfc.Name, other)
+			}
+		}
+
+		host := sfcElement.EtcdVppSwitchKey
+		if host == "" {
+			continue
+		}
+		for _, other := range sortedContainers(group) {
+			if otherHost := cp.hosts[other]; otherHost != "" && otherHost != host {
+				return fmt.Errorf("Invalid affinity for element: '%s', sfc: '%s', container: '%s' is on he: "+
+					"'%s', not on he: '%s'", element, sfc.Name, other, otherHost, host)
+			}
+		}
+		if other := cp.conflicts(sfcElement.Container, host); other != "" {
+			return fmt.Errorf("Invalid anti_affinity for element: '%s', sfc: '%s', container: '%s' is on the "+
+				"same he: '%s'", element, sfc.Name, other, host)
+		}
+	}
+
+	return nil
+}
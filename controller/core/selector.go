@@ -0,0 +1,265 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The labels of the entities and the selectors matching them are implemented
+// in this file.  An ee, he or sfc may carry labels, ie tier: gold or
+// rack: A3, and a selector is a comma separated list of requirements the
+// labels must all meet: key=value, key!=value, key (the label is set) and
+// !key (it is not).  The ee, he and sfc lists are filtered by a selector, and
+// the operations on a set of entities name them by one: the chains matching
+// a selector are reconciled, and the hosts matching one are drained, ie their
+// chains' containers are migrated to another host.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// the results of the entities of a selector operation
+const (
+	SelectorResultReconciled = "reconciled"
+	SelectorResultMigrated   = "migrated"
+	SelectorResultFailed     = "failed"
+)
+
+// labelRequirement is a requirement of a selector on a label
+type labelRequirement struct {
+	key    string
+	value  string
+	equals bool // key=value, else key!=value
+	exists bool // key or !key, the value is not compared
+	not    bool // !key
+}
+
+// labelSelector is the requirements the labels of an entity must all meet, the empty selector matches
+// every entity
+type labelSelector []labelRequirement
+
+// SelectorOperationResult is the result of an operation on the entities matching a selector
+type SelectorOperationResult struct {
+	Selector string              `json:"selector"`
+	Results  []*BulkEntityResult `json:"results"`
+}
+
+// parseLabelSelector parses a selector, ie tier=gold,rack!=A3,gpu,!legacy
+func parseLabelSelector(selector string) (labelSelector, error) {
+
+	var ls labelSelector
+	for _, requirement := range strings.Split(selector, ",") {
+		requirement = strings.TrimSpace(requirement)
+		if requirement == "" {
+			continue
+		}
+		var r labelRequirement
+		switch {
+		case strings.Contains(requirement, "!="):
+			kv := strings.SplitN(requirement, "!=", 2)
+			r = labelRequirement{key: strings.TrimSpace(kv[0]), value: strings.TrimSpace(kv[1])}
+		case strings.Contains(requirement, "="):
+			kv := strings.SplitN(requirement, "=", 2)
+			r = labelRequirement{key: strings.TrimSpace(kv[0]), value: strings.TrimSpace(kv[1]), equals: true}
+		case strings.HasPrefix(requirement, "!"):
+			r = labelRequirement{key: strings.TrimSpace(requirement[1:]), exists: true, not: true}
+		default:
+			r = labelRequirement{key: requirement, exists: true}
+		}
+		if err := validateLabelKey(r.key); err != nil {
+			return nil, fmt.Errorf("Invalid selector: '%s': %s", selector, err)
+		}
+		ls = append(ls, r)
+	}
+	return ls, nil
+}
+
+// matches is whether the labels meet every requirement of the selector
+func (ls labelSelector) matches(labels map[string]string) bool {
+
+	for _, r := range ls {
+		value, exists := labels[r.key]
+		switch {
+		case r.exists && exists == r.not:
+			return false
+		case r.exists:
+		case r.equals && (!exists || value != r.value):
+			return false
+		case !r.equals && exists && value == r.value:
+			return false
+		}
+	}
+	return true
+}
+
+func (ls labelSelector) String() string {
+
+	var requirements []string
+	for _, r := range ls {
+		switch {
+		case r.exists && r.not:
+			requirements = append(requirements, "!"+r.key)
+		case r.exists:
+			requirements = append(requirements, r.key)
+		case r.equals:
+			requirements = append(requirements, r.key+"="+r.value)
+		default:
+			requirements = append(requirements, r.key+"!="+r.value)
+		}
+	}
+	return strings.Join(requirements, ",")
+}
+
+// validateLabelKey checks the key can be named by a selector
+func validateLabelKey(key string) error {
+	if key == "" || strings.ContainsAny(key, ",=! ") {
+		return fmt.Errorf("label key: '%s' must be non empty, without any of: ',=! '", key)
+	}
+	return nil
+}
+
+// validateLabels checks the labels of an entity
+func validateLabels(labels map[string]string) error {
+	for key, value := range labels {
+		if err := validateLabelKey(key); err != nil {
+			return err
+		}
+		if strings.Contains(value, ",") {
+			return fmt.Errorf("label: '%s' value: '%s' must not contain a ','", key, value)
+		}
+	}
+	return nil
+}
+
+// reconcileSelectedSfcs renders the chains matching the selector again, in render order, their agent config
+// is re-written and their render statuses updated
+func (sfcCtrlPlugin *SfcControllerPluginHandler) reconcileSelectedSfcs(ls labelSelector) (*SelectorOperationResult,
+	error) {
+
+	result := &SelectorOperationResult{Selector: ls.String(), Results: make([]*BulkEntityResult, 0)}
+
+	sfcOrder, err := sfcRenderOrder(sfcCtrlPlugin.ramConfigCache.SFCs)
+	if err != nil {
+		return nil, err
+	}
+	defer sfcCtrlPlugin.entityStatusFlush()
+
+	for _, sfcName := range sfcOrder {
+		sfc := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+		if !ls.matches(sfc.Labels) {
+			continue
+		}
+		r := &BulkEntityResult{Kind: controller.SfcEntityKind, Name: sfc.Name, Result: SelectorResultReconciled}
+		if err := sfcCtrlPlugin.renderServiceFunctionEntity(&sfc); err != nil {
+			r.Result, r.Error = SelectorResultFailed, err.Error()
+		}
+		result.Results = append(result.Results, r)
+	}
+
+	log.Infof("reconcileSelectedSfcs: selector: '%s', %d chains", ls, len(result.Results))
+
+	return result, nil
+}
+
+// drainSelectedHosts migrates the containers of the chains on the hosts matching the selector to the
+// destination host, each chain is moved blue/green so it stays where it is if its move fails
+func (sfcCtrlPlugin *SfcControllerPluginHandler) drainSelectedHosts(ls labelSelector, toHost string,
+	source string) (*SelectorOperationResult, error) {
+
+	to, exists := sfcCtrlPlugin.ramConfigCache.HEs[toHost]
+	if !exists {
+		return nil, fmt.Errorf("Invalid drain, to_host: '%s' not found", toHost)
+	}
+	if ls.matches(to.Labels) {
+		return nil, fmt.Errorf("Invalid drain, to_host: '%s' matches the selector: '%s'", toHost, ls)
+	}
+
+	result := &SelectorOperationResult{Selector: ls.String(), Results: make([]*BulkEntityResult, 0)}
+
+	for _, heName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		if !ls.matches(sfcCtrlPlugin.ramConfigCache.HEs[heName].Labels) {
+			continue
+		}
+		var sfcNames []string
+		for name, sfc := range sfcCtrlPlugin.ramConfigCache.SFCs {
+			if sfcHasContainerOnHost(&sfc, heName) {
+				sfcNames = append(sfcNames, name)
+			}
+		}
+		sort.Strings(sfcNames)
+
+		for _, sfcName := range sfcNames {
+			r := &BulkEntityResult{Kind: controller.SfcEntityKind, Name: sfcName, Result: SelectorResultMigrated}
+			result.Results = append(result.Results, r)
+			if err := sfcCtrlPlugin.drainSfc(sfcName, heName, toHost, source); err != nil {
+				r.Result, r.Error = SelectorResultFailed, err.Error()
+			}
+		}
+	}
+
+	log.Infof("drainSelectedHosts: selector: '%s', to_host: '%s', %d chains", ls, toHost, len(result.Results))
+
+	// the whole drain is one version
+	migrated := 0
+	for _, r := range result.Results {
+		if r.Result == SelectorResultMigrated {
+			migrated++
+		}
+	}
+	if migrated != 0 {
+		if err := sfcCtrlPlugin.snapshotConfigVersion("POST drain " + ls.String() + " to " + toHost); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// drainSfc migrates the chain's containers on the host to the destination host
+func (sfcCtrlPlugin *SfcControllerPluginHandler) drainSfc(sfcName string, fromHost string, toHost string,
+	source string) error {
+
+	migrated, err := sfcCtrlPlugin.migratedSfc(&controller.SfcMigration{Sfc: sfcName, FromHost: fromHost,
+		ToHost: toHost})
+	if err != nil {
+		return err
+	}
+	change := sfcCtrlPlugin.newEntityChange(controller.SfcEntityKind, sfcName, migrated, source)
+
+	existing := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+	if err := sfcCtrlPlugin.renderServiceFunctionEntityBlueGreen(&existing, migrated); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.DatastoreSfcEntityCreate(migrated); err != nil {
+		return err
+	}
+	sfcCtrlPlugin.recordEntityChange(change)
+
+	return nil
+}
+
+func sfcHasContainerOnHost(sfc *controller.SfcEntity, host string) bool {
+	for _, sfcElement := range sfc.GetElements() {
+		switch sfcElement.Type {
+		case controller.SfcElementType_EXTERNAL_ENTITY, controller.SfcElementType_HOST_ENTITY:
+			continue
+		}
+		if sfcElement.EtcdVppSwitchKey == host {
+			return true
+		}
+	}
+	return false
+}
@@ -18,19 +18,24 @@
 package core
 
 import (
-	"github.com/ghodss/yaml"
+	"fmt"
 	"github.com/ligato/sfc-controller/controller/model/controller"
 	"io/ioutil"
+	"time"
 )
 
 // YamlConfig is container struct for yaml config file
 type YamlConfig struct {
-	Version     int                           `json:"sfc_controller_config_version"`
-	Description string                        `json:"description"`
-	EEs         []controller.ExternalEntity   `json:"external_entities"`
-	HEs         []controller.HostEntity       `json:"host_entities"`
-	SFCs        []controller.SfcEntity        `json:"sfc_entities"`
-	SysParms    controller.SystemParameters   `json:"system_parameters"`
+	Version     int                                   `json:"sfc_controller_config_version"`
+	Description string                                `json:"description"`
+	EEs         []controller.ExternalEntity           `json:"external_entities"`
+	HEs         []controller.HostEntity               `json:"host_entities"`
+	SFCs        []controller.SfcEntity                `json:"sfc_entities"`
+	NSs         []controller.NetworkService           `json:"network_services,omitempty"`
+	TPLs        []controller.SfcTemplate              `json:"sfc_templates,omitempty"`
+	TPLInsts    []controller.SfcTemplateInstantiation `json:"sfc_template_instantiations,omitempty"`
+	WPs         []controller.WiringPolicy             `json:"wiring_policies,omitempty"`
+	SysParms    controller.SystemParameters           `json:"system_parameters"`
 }
 
 // open the file and parse the yaml into the json datastructure
@@ -43,10 +48,10 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) readConfigFromFile(fpath string
 		return err
 	}
 
-	sfcCtrlPlugin.yamlConfig = &YamlConfig{}
-
-	if err := yaml.Unmarshal(b, sfcCtrlPlugin.yamlConfig); err != nil {
-		return err
+	// a field that is not in the schema is refused rather than ignored, see config_schema.go
+	sfcCtrlPlugin.yamlConfig, err = ParseYamlConfig(b)
+	if err != nil {
+		return fmt.Errorf("%s: %s", fpath, err)
 	}
 
 	log.Debugf("sfc-config: '%s'", sfcCtrlPlugin.yamlConfig)
@@ -69,6 +74,9 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) copyYamlConfigToRAMCache() erro
 		log.Debugf("copyYamlConfigToRAMCache: he: ", he)
 	}
 	for _, sfc := range sfcCtrlPlugin.yamlConfig.SFCs {
+		previous := sfcCtrlPlugin.ramConfigCache.SFCs[sfc.Name]
+		stampSfcExpiry(&sfc, &previous, time.Now().Unix())
+		stampSfcDeleting(&sfc, &previous)
 		sfcCtrlPlugin.ramConfigCache.SFCs[sfc.Name] = sfc
 		log.Debugf("copyYamlConfigToRAMCache: sfc: ", sfc)
 		log.Debugf("copyYamlConfigToRAMCache: num_chain_elements=%d", len(sfc.GetElements()))
@@ -76,6 +84,37 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) copyYamlConfigToRAMCache() erro
 			log.Debugf("copyYamlConfigToRAMCache: sfc_chain_element[%d]=", i, sfcChainElement)
 		}
 	}
+	for i := range sfcCtrlPlugin.yamlConfig.NSs {
+		ns := &sfcCtrlPlugin.yamlConfig.NSs[i]
+		if err := sfcCtrlPlugin.validateNetworkService(ns); err != nil {
+			return err
+		}
+		sfcCtrlPlugin.copyNetworkServiceToRAMCache(ns)
+		log.Debugf("copyYamlConfigToRAMCache: ns: %v", *ns)
+	}
+	for _, tpl := range sfcCtrlPlugin.yamlConfig.TPLs {
+		if err := validateSfcTemplate(&tpl); err != nil {
+			return err
+		}
+		sfcCtrlPlugin.ramConfigCache.TPLs[tpl.Name] = tpl
+		log.Debugf("copyYamlConfigToRAMCache: sfc template: %v", tpl)
+	}
+	for _, wp := range sfcCtrlPlugin.yamlConfig.WPs {
+		if err := validateWiringPolicy(&wp); err != nil {
+			return err
+		}
+		sfcCtrlPlugin.ramConfigCache.WPs[wp.Name] = wp
+		log.Debugf("copyYamlConfigToRAMCache: wiring policy: %v", wp)
+	}
+	for i := range sfcCtrlPlugin.yamlConfig.TPLInsts {
+		sfcs, err := sfcCtrlPlugin.instantiateSfcTemplate(&sfcCtrlPlugin.yamlConfig.TPLInsts[i])
+		if err != nil {
+			return err
+		}
+		for _, sfc := range sfcs {
+			sfcCtrlPlugin.ramConfigCache.SFCs[sfc.Name] = sfc
+		}
+	}
 
 	return nil
 }
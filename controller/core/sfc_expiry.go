@@ -0,0 +1,183 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The expiry of temporary chains is implemented in this file.  A chain
+// posted with a ttl, ie a tap for troubleshooting or a chain for test
+// traffic, is stamped with the time it expires at, the stamp is stored with
+// the chain so it survives a restart of the controller.  Posting the chain
+// again with the same ttl keeps its expiry, changing the ttl restarts it,
+// and an expires_at set in the chain is used as is.  The scheduled change
+// loop unwires and deletes the chains that expired, and a chain that
+// expired while the controller was down is dropped before the startup
+// render.  An expiry event is emitted for each expired chain, see events.go.
+
+package core
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/cnpdriver"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/eventbus"
+)
+
+// the action of the expiry events
+const expiryEventExpired = "expired"
+
+// stampSfcExpiry sets the time the chain expires at from its ttl, the expiry of the previous config of the
+// chain, empty if there is none, is kept if its ttl did not change
+func stampSfcExpiry(sfc *controller.SfcEntity, previous *controller.SfcEntity, now int64) {
+
+	if sfc.ExpiresAt != 0 || sfc.TtlSeconds == 0 {
+		return
+	}
+	if previous.TtlSeconds == sfc.TtlSeconds && previous.ExpiresAt != 0 {
+		sfc.ExpiresAt = previous.ExpiresAt
+		return
+	}
+	sfc.ExpiresAt = now + int64(sfc.TtlSeconds)
+}
+
+// expiredSfcs returns the names of the chains expired at now, in name order, a chain whose driver cannot
+// remove its wiring is left alone
+func (sfcCtrlPlugin *SfcControllerPluginHandler) expiredSfcs(now int64) []string {
+
+	var expired []string
+	for name, sfc := range sfcCtrlPlugin.ramConfigCache.SFCs {
+		if sfc.ExpiresAt == 0 || sfc.ExpiresAt > now {
+			continue
+		}
+		if err := cnpdriver.ValidateSfcEntityDelete(cnpDriverName, &sfc); err != nil {
+			log.Warnf("expiredSfcs: sfc: '%s' expired at: %s, it is not removed: %s", name,
+				time.Unix(sfc.ExpiresAt, 0), err)
+			continue
+		}
+		expired = append(expired, name)
+	}
+	sort.Strings(expired)
+	return expired
+}
+
+// removeExpiredSfcs deletes the chains expired at now from the ram cache and the sfc db, the versions of the
+// config before and after are returned, nil if no chain expired
+func (sfcCtrlPlugin *SfcControllerPluginHandler) removeExpiredSfcs(now int64) (*controller.ConfigVersion,
+	*controller.ConfigVersion) {
+
+	expired := sfcCtrlPlugin.expiredSfcs(now)
+	for _, name := range expired {
+		log.Infof("removeExpiredSfcs: sfc: '%s' expired at: %s", name,
+			time.Unix(sfcCtrlPlugin.ramConfigCache.SFCs[name].ExpiresAt, 0))
+	}
+	return sfcCtrlPlugin.removeSfcs(expired)
+}
+
+// removeSfcs deletes the chains from the ram cache and the sfc db, the versions of the config before and after
+// are returned, nil if there are no chains to delete
+func (sfcCtrlPlugin *SfcControllerPluginHandler) removeSfcs(names []string) (*controller.ConfigVersion,
+	*controller.ConfigVersion) {
+
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	current := sfcCtrlPlugin.ramCacheToConfigVersion()
+	for _, name := range names {
+		delete(sfcCtrlPlugin.ramConfigCache.SFCs, name)
+		for _, key := range []string{controller.SfcEntityNameKey(name),
+			controller.EntityStatusKey(controller.SfcEntityKind, name),
+			controller.EntityKeysKey(controller.SfcEntityKind, name)} {
+			if _, err := sfcCtrlPlugin.db.Delete(key); err != nil {
+				log.Errorf("removeSfcs: error deleting key: '%s': %s", key, err)
+			}
+		}
+	}
+	target := sfcCtrlPlugin.ramCacheToConfigVersion()
+	sfcCtrlPlugin.evictRemovedEntities(current, target)
+
+	return current, target
+}
+
+// unwireRemovedSfcs re-renders the config once chains were removed from it, the reconcile removes what they
+// were wired to
+func (sfcCtrlPlugin *SfcControllerPluginHandler) unwireRemovedSfcs() {
+
+	sfcCtrlPlugin.ReconcileStart()
+	if err := sfcCtrlPlugin.renderConfigFromRAMCache(); err != nil {
+		log.Errorf("unwireRemovedSfcs: error re-rendering the config: %s", err)
+	}
+	sfcCtrlPlugin.ReconcileEnd()
+}
+
+// dropExpiredSfcs removes the chains that expired before the config is rendered, the reconcile of the render
+// removes what they were wired to
+func (sfcCtrlPlugin *SfcControllerPluginHandler) dropExpiredSfcs(now int64) {
+
+	current, target := sfcCtrlPlugin.removeExpiredSfcs(now)
+	if current == nil {
+		return
+	}
+	sfcCtrlPlugin.sfcsExpired(current, target)
+}
+
+// expireSfcs unwires and deletes the chains expired at now
+func (sfcCtrlPlugin *SfcControllerPluginHandler) expireSfcs(now int64) {
+
+	current, target := sfcCtrlPlugin.removeExpiredSfcs(now)
+	if current == nil {
+		return
+	}
+
+	sfcCtrlPlugin.unwireRemovedSfcs()
+
+	names := sfcsRemovedNames(current, target)
+	sfcCtrlPlugin.sfcsExpired(current, target)
+	if err := sfcCtrlPlugin.snapshotConfigVersion("expire SFC/" + strings.Join(names, ",")); err != nil {
+		log.Errorf("expireSfcs: error storing config version: %s", err)
+	}
+}
+
+// sfcsExpired records the expired chains in their history and emits an expiry event for each
+func (sfcCtrlPlugin *SfcControllerPluginHandler) sfcsExpired(current *controller.ConfigVersion,
+	target *controller.ConfigVersion) {
+
+	sfcCtrlPlugin.recordConfigVersionChanges(current, target, "ttl expiry")
+
+	expiresAt := make(map[string]int64)
+	for _, sfc := range current.GetSfcEntities() {
+		expiresAt[sfc.Name] = sfc.ExpiresAt
+	}
+	for _, name := range sfcsRemovedNames(current, target) {
+		sfcCtrlPlugin.emitEvent(eventbus.Event{
+			Type:    eventbus.EventExpiry,
+			Action:  expiryEventExpired,
+			Kind:    controller.SfcEntityKind,
+			Name:    name,
+			Message: "the ttl expired at " + time.Unix(expiresAt[name], 0).UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// sfcsRemovedNames returns the names of the chains in the current version that are not in the target
+func sfcsRemovedNames(current *controller.ConfigVersion, target *controller.ConfigVersion) []string {
+
+	var names []string
+	for _, entity := range diffConfigVersions(current, target).Removed {
+		if strings.HasPrefix(entity, controller.SfcEntityKind+"/") {
+			names = append(names, strings.TrimPrefix(entity, controller.SfcEntityKind+"/"))
+		}
+	}
+	return names
+}
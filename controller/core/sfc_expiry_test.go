@@ -0,0 +1,68 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+func TestStampSfcExpiry(t *testing.T) {
+
+	const now = 1000
+	tests := []struct {
+		name      string
+		sfc       controller.SfcEntity
+		previous  controller.SfcEntity
+		expiresAt int64
+	}{
+		{"no ttl", controller.SfcEntity{}, controller.SfcEntity{}, 0},
+		{"new ttl", controller.SfcEntity{TtlSeconds: 60}, controller.SfcEntity{}, now + 60},
+		{"same ttl", controller.SfcEntity{TtlSeconds: 60}, controller.SfcEntity{TtlSeconds: 60, ExpiresAt: 500}, 500},
+		{"changed ttl", controller.SfcEntity{TtlSeconds: 90}, controller.SfcEntity{TtlSeconds: 60, ExpiresAt: 500},
+			now + 90},
+		{"given expiry", controller.SfcEntity{TtlSeconds: 60, ExpiresAt: 700}, controller.SfcEntity{}, 700},
+	}
+	for _, test := range tests {
+		stampSfcExpiry(&test.sfc, &test.previous, now)
+		if test.sfc.ExpiresAt != test.expiresAt {
+			t.Errorf("%s: expires at: %d, expected: %d", test.name, test.sfc.ExpiresAt, test.expiresAt)
+		}
+	}
+}
+
+func TestExpireSfcs(t *testing.T) {
+
+	now := time.Now().Unix()
+	expired, live := testChain("expired"), testChain("live")
+	expired.TtlSeconds, expired.ExpiresAt = 60, now+30
+	live.TtlSeconds, live.ExpiresAt = 3600, now+3600
+	sfcCtrlPlugin, broker := newTestPlugin(t, expired, live)
+
+	rendered := len(broker.Dump("/vnf-agent/"))
+	sfcCtrlPlugin.expireSfcs(now + 60)
+
+	if _, exists := sfcCtrlPlugin.ramConfigCache.SFCs["expired"]; exists || sfcStored(t, sfcCtrlPlugin, "expired") {
+		t.Errorf("the expired sfc is not deleted")
+	}
+	if _, exists := sfcCtrlPlugin.ramConfigCache.SFCs["live"]; !exists || !sfcStored(t, sfcCtrlPlugin, "live") {
+		t.Errorf("the sfc whose ttl has not expired is deleted")
+	}
+	if unwired := len(broker.Dump("/vnf-agent/")); unwired >= rendered {
+		t.Errorf("the expired sfc is not unwired: %d agent keys, %d before it expired", unwired, rendered)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The migration of a chain between hosts is implemented in this file.  The
+// elements that move get the new host's etcd_vpp_switch_key, and the chain
+// is re-rendered blue/green: the driver allocates and writes the new host's
+// i/f's, tunnels and bridges next to the old ones, waits for the agents to
+// accept them, cuts the chain over, then removes the old host's entries.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// migratedSfc returns the chain with the elements that move re-targeted to the new host
+func (sfcCtrlPlugin *SfcControllerPluginHandler) migratedSfc(m *controller.SfcMigration) (*controller.SfcEntity, error) {
+
+	sfc, exists := sfcCtrlPlugin.ramConfigCache.SFCs[m.Sfc]
+	if !exists {
+		return nil, fmt.Errorf("Invalid sfc migration, sfc: '%s' not found", m.Sfc)
+	}
+	if err := sfcCtrlPlugin.sfcDeleting(m.Sfc); err != nil {
+		return nil, err
+	}
+	if _, exists := sfcCtrlPlugin.ramConfigCache.HEs[m.ToHost]; !exists {
+		return nil, fmt.Errorf("Invalid sfc migration of: '%s', to_host: '%s' not found", m.Sfc, m.ToHost)
+	}
+	if m.FromHost == "" || m.FromHost == m.ToHost {
+		return nil, fmt.Errorf("Invalid sfc migration of: '%s', from_host: '%s' to_host: '%s'", m.Sfc,
+			m.FromHost, m.ToHost)
+	}
+	if (m.Container == "") != (m.PortLabel == "") {
+		return nil, fmt.Errorf("Invalid sfc migration of: '%s', container and port_label go together", m.Sfc)
+	}
+
+	migrated := proto.Clone(&sfc).(*controller.SfcEntity)
+	moved := 0
+	for _, sfcElement := range migrated.GetElements() {
+		if sfcElement.EtcdVppSwitchKey != m.FromHost {
+			continue
+		}
+		if m.Container != "" && (sfcElement.Container != m.Container || sfcElement.PortLabel != m.PortLabel) {
+			continue
+		}
+		switch sfcElement.Type {
+		case controller.SfcElementType_EXTERNAL_ENTITY, controller.SfcElementType_HOST_ENTITY:
+			if m.Container != "" {
+				return nil, fmt.Errorf("Invalid sfc migration of: '%s', element: '%s/%s' is not a container",
+					m.Sfc, m.Container, m.PortLabel)
+			}
+			continue
+		}
+		sfcElement.EtcdVppSwitchKey = m.ToHost
+		moved++
+	}
+	if moved == 0 {
+		return nil, fmt.Errorf("Invalid sfc migration of: '%s', no element on from_host: '%s' to move", m.Sfc,
+			m.FromHost)
+	}
+
+	if err := sfcCtrlPlugin.validateSFC(migrated); err != nil {
+		return nil, fmt.Errorf("Invalid sfc migration of: '%s': %s", m.Sfc, err)
+	}
+
+	return migrated, nil
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The ordering of the sfc's is implemented in this file.  A chain lists the
+// chains it depends_on, ie a shared infrastructure chain, and is rendered
+// after them.  Of the chains whose dependencies are rendered, the ones with
+// a higher priority go first, then they go by name, so the order does not
+// depend on the order the chains were configured in.  A chain is not wired
+// until every chain it depends on has rendered, until then its status is
+// DEPENDENCY_PENDING, and it is wired once the last of them renders.
+
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// sfcRenderOrder returns the names of the chains in the order they are rendered, a dependency on a chain that
+// is not configured does not order anything, the chain waits for it at render time
+func sfcRenderOrder(sfcs map[string]controller.SfcEntity) ([]string, error) {
+
+	waitingFor := make(map[string]int)      // chain -> number of its configured dependencies not ordered yet
+	dependents := make(map[string][]string) // chain -> chains that depend on it
+	for _, name := range sortedKeysSFC(sfcs) {
+		sfc := sfcs[name]
+		seen := make(map[string]bool)
+		for _, dep := range sfc.DependsOn {
+			if _, exists := sfcs[dep]; !exists || seen[dep] {
+				continue
+			}
+			seen[dep] = true
+			waitingFor[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var ready []string
+	for _, name := range sortedKeysSFC(sfcs) {
+		if waitingFor[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+
+	order := make([]string, 0, len(sfcs))
+	for len(ready) != 0 {
+		next := 0
+		for i, name := range ready {
+			if sfcRendersBefore(sfcs[name], sfcs[ready[next]]) {
+				next = i
+			}
+		}
+		name := ready[next]
+		ready = append(ready[:next], ready[next+1:]...)
+		order = append(order, name)
+
+		for _, dependent := range dependents[name] {
+			waitingFor[dependent]--
+			if waitingFor[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+
+	if len(order) != len(sfcs) {
+		var cycle []string
+		for _, name := range sortedKeysSFC(sfcs) {
+			if waitingFor[name] != 0 {
+				cycle = append(cycle, name)
+			}
+		}
+		return nil, fmt.Errorf("depends_on cycle between the sfc's: '%s'", strings.Join(cycle, "', '"))
+	}
+
+	return order, nil
+}
+
+// sfcRendersBefore orders two chains whose dependencies are met
+func sfcRendersBefore(sfc1 controller.SfcEntity, sfc2 controller.SfcEntity) bool {
+	if sfc1.Priority != sfc2.Priority {
+		return sfc1.Priority > sfc2.Priority
+	}
+	return sfc1.Name < sfc2.Name
+}
+
+// sfcDependencyPending returns the first chain the sfc depends on that has not rendered, "" if there is none
+func (sfcCtrlPlugin *SfcControllerPluginHandler) sfcDependencyPending(sfc *controller.SfcEntity) string {
+	for _, dep := range sfc.DependsOn {
+		if sfcCtrlPlugin.sfcRenderStates[dep] != controller.RenderStateType_RENDERED {
+			return dep
+		}
+	}
+	return ""
+}
+
+// sfcRenderStateSet records how the chain rendered, the chains waiting for it are wired by renderPendingSFCs
+func (sfcCtrlPlugin *SfcControllerPluginHandler) sfcRenderStateSet(name string, state controller.RenderStateType) {
+	if sfcCtrlPlugin.sfcRenderStates == nil {
+		sfcCtrlPlugin.sfcRenderStates = make(map[string]controller.RenderStateType)
+	}
+	sfcCtrlPlugin.sfcRenderStates[name] = state
+}
+
+// renderPendingSFCs wires the chains that were waiting for their dependencies and now have them rendered
+func (sfcCtrlPlugin *SfcControllerPluginHandler) renderPendingSFCs() error {
+
+	order, err := sfcRenderOrder(sfcCtrlPlugin.ramConfigCache.SFCs)
+	if err != nil {
+		return err
+	}
+	for _, sfcName := range order {
+		if sfcCtrlPlugin.sfcRenderStates[sfcName] != controller.RenderStateType_DEPENDENCY_PENDING {
+			continue
+		}
+		sfc := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+		if sfcCtrlPlugin.sfcDependencyPending(&sfc) != "" {
+			continue
+		}
+		if err := sfcCtrlPlugin.renderServiceFunctionEntity(&sfc); err != nil {
+			log.Error("Error rendering service function chain:", sfc)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSfcDependsOn checks the chain's dependencies do not loop back to it through the configured chains
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSfcDependsOn(sfc *controller.SfcEntity) error {
+
+	if len(sfc.DependsOn) == 0 {
+		return nil
+	}
+
+	sfcs := make(map[string]controller.SfcEntity, len(sfcCtrlPlugin.ramConfigCache.SFCs)+1)
+	for name, configured := range sfcCtrlPlugin.ramConfigCache.SFCs {
+		sfcs[name] = configured
+	}
+	sfcs[sfc.Name] = *sfc
+
+	for _, dep := range sfc.DependsOn {
+		if dep == "" || dep == sfc.Name {
+			return fmt.Errorf("Invalid depends_on: '%s' for sfc: '%s'", dep, sfc.Name)
+		}
+	}
+	if _, err := sfcRenderOrder(sfcs); err != nil {
+		return fmt.Errorf("Invalid depends_on for sfc: '%s': %s", sfc.Name, err)
+	}
+
+	return nil
+}
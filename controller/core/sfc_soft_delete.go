@@ -0,0 +1,270 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The soft delete of chains is implemented in this file.  Deleting a chain
+// only marks it deleting, the chain stays wired and carries traffic, but the
+// changes that would affect its traffic are refused, only its description,
+// labels and ttl may still change.  The chain is unwired and deleted once
+// the deletion is confirmed, or once its grace period ends, the grace period
+// is the sfc_delete_grace_seconds of the system parameters unless the delete
+// gives one, 0 waits for a confirm.  Until then the deletion may be
+// cancelled.  The mark is stored with the chain so it survives a restart of
+// the controller, a chain whose grace period ended while the controller was
+// down is dropped before the startup render.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/cnpdriver"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/eventbus"
+)
+
+// the actions of the delete events
+const (
+	deleteEventMarked    = "marked"
+	deleteEventCancelled = "cancelled"
+	deleteEventDeleted   = "deleted"
+)
+
+// stampSfcDeleting carries the deletion mark of the previous config of the chain, empty if there is none, over
+// to the chain so posting a chain does not cancel its deletion, a mark set in the chain, ie by a restore of a
+// backup, is used as is
+func stampSfcDeleting(sfc *controller.SfcEntity, previous *controller.SfcEntity) {
+	if sfc.DeletingSince != 0 {
+		return
+	}
+	sfc.DeletingSince = previous.DeletingSince
+	sfc.DeleteAt = previous.DeleteAt
+}
+
+// validateSfcDeletingChange refuses a change to a chain being deleted that would affect its traffic
+func validateSfcDeletingChange(sfc *controller.SfcEntity, previous *controller.SfcEntity) error {
+
+	if previous.DeletingSince == 0 {
+		return nil
+	}
+	if sfcTrafficSpec(sfc).String() != sfcTrafficSpec(previous).String() {
+		return fmt.Errorf("Invalid sfc: '%s', it is being deleted since: %s, cancel the deletion to change it",
+			sfc.Name, time.Unix(previous.DeletingSince, 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// sfcTrafficSpec returns a copy of the chain without the fields that do not affect its traffic
+func sfcTrafficSpec(sfc *controller.SfcEntity) *controller.SfcEntity {
+
+	spec := proto.Clone(sfc).(*controller.SfcEntity)
+	spec.Description = ""
+	spec.Labels = nil
+	spec.TtlSeconds = 0
+	spec.ExpiresAt = 0
+	spec.DeletingSince = 0
+	spec.DeleteAt = 0
+	return spec
+}
+
+// sfcDeleting returns an error if the chain is being deleted, for the operations on a chain that affect its
+// traffic
+func (sfcCtrlPlugin *SfcControllerPluginHandler) sfcDeleting(sfcName string) error {
+
+	if sfc, exists := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]; exists && sfc.DeletingSince != 0 {
+		return fmt.Errorf("Invalid sfc: '%s', it is being deleted since: %s", sfcName,
+			time.Unix(sfc.DeletingSince, 0).UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// markSfcDeleting marks the chain deleting, its grace period is the system one if grace is nil, false is
+// returned if the chain was already marked
+func (sfcCtrlPlugin *SfcControllerPluginHandler) markSfcDeleting(sfcName string, grace *uint32, now int64,
+	source string) (bool, error) {
+
+	sfc, exists := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+	if !exists {
+		return false, fmt.Errorf("Invalid sfc delete, sfc: '%s' not found", sfcName)
+	}
+	if sfc.DeletingSince != 0 {
+		return false, nil
+	}
+	if err := cnpdriver.ValidateSfcEntityDelete(cnpDriverName, &sfc); err != nil {
+		return false, fmt.Errorf("Invalid sfc delete of: '%s': %s", sfcName, err)
+	}
+
+	graceSeconds := sfcCtrlPlugin.ramConfigCache.SysParms.SfcDeleteGraceSeconds
+	if grace != nil {
+		graceSeconds = *grace
+	}
+	sfc.DeletingSince = now
+	sfc.DeleteAt = 0
+	if graceSeconds != 0 {
+		sfc.DeleteAt = now + int64(graceSeconds)
+	}
+
+	message := "the deletion waits to be confirmed"
+	if sfc.DeleteAt != 0 {
+		message = "the chain is deleted at " + time.Unix(sfc.DeleteAt, 0).UTC().Format(time.RFC3339)
+	}
+	log.Infof("markSfcDeleting: sfc: '%s', %s", sfcName, message)
+
+	if err := sfcCtrlPlugin.storeSfcDeleteMark(&sfc, source); err != nil {
+		return false, err
+	}
+	sfcCtrlPlugin.emitEvent(eventbus.Event{
+		Type:    eventbus.EventDelete,
+		Action:  deleteEventMarked,
+		Kind:    controller.SfcEntityKind,
+		Name:    sfcName,
+		Message: message,
+	})
+	return true, nil
+}
+
+// cancelSfcDelete clears the deletion mark of the chain, false is returned if it was not marked
+func (sfcCtrlPlugin *SfcControllerPluginHandler) cancelSfcDelete(sfcName string, source string) (bool, error) {
+
+	sfc, exists := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+	if !exists {
+		return false, fmt.Errorf("Invalid sfc undelete, sfc: '%s' not found", sfcName)
+	}
+	if sfc.DeletingSince == 0 {
+		return false, nil
+	}
+	log.Infof("cancelSfcDelete: sfc: '%s' deleting since: %s", sfcName, time.Unix(sfc.DeletingSince, 0))
+
+	sfc.DeletingSince = 0
+	sfc.DeleteAt = 0
+	if err := sfcCtrlPlugin.storeSfcDeleteMark(&sfc, source); err != nil {
+		return false, err
+	}
+	sfcCtrlPlugin.emitEvent(eventbus.Event{
+		Type:    eventbus.EventDelete,
+		Action:  deleteEventCancelled,
+		Kind:    controller.SfcEntityKind,
+		Name:    sfcName,
+		Message: "the deletion was cancelled",
+	})
+	return true, nil
+}
+
+// storeSfcDeleteMark stores the chain with its deletion mark set or cleared, the wiring is not changed
+func (sfcCtrlPlugin *SfcControllerPluginHandler) storeSfcDeleteMark(sfc *controller.SfcEntity,
+	source string) error {
+
+	change := sfcCtrlPlugin.newEntityChange(controller.SfcEntityKind, sfc.Name, sfc, source)
+	sfcCtrlPlugin.ramConfigCache.SFCs[sfc.Name] = *sfc
+	if err := sfcCtrlPlugin.DatastoreSfcEntityCreate(sfc); err != nil {
+		return err
+	}
+	sfcCtrlPlugin.recordEntityChange(change)
+	return nil
+}
+
+// confirmSfcDelete unwires and deletes a chain marked deleting without waiting for its grace period, a chain
+// that was not marked is refused so a chain is never torn down by a single request
+func (sfcCtrlPlugin *SfcControllerPluginHandler) confirmSfcDelete(sfcName string) error {
+
+	sfc, exists := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+	if !exists {
+		return fmt.Errorf("Invalid sfc delete confirm, sfc: '%s' not found", sfcName)
+	}
+	if sfc.DeletingSince == 0 {
+		return fmt.Errorf("Invalid sfc delete confirm, sfc: '%s' is not being deleted, delete it first",
+			sfcName)
+	}
+	if err := cnpdriver.ValidateSfcEntityDelete(cnpDriverName, &sfc); err != nil {
+		return fmt.Errorf("Invalid sfc delete confirm of: '%s': %s", sfcName, err)
+	}
+
+	log.Infof("confirmSfcDelete: sfc: '%s' deleting since: %s", sfcName, time.Unix(sfc.DeletingSince, 0))
+	sfcCtrlPlugin.deleteSfcs([]string{sfcName}, "the deletion was confirmed")
+	return nil
+}
+
+// graceEndedSfcs returns the names of the chains marked deleting whose grace period ended at now, in name
+// order, a chain whose driver cannot remove its wiring is left alone
+func (sfcCtrlPlugin *SfcControllerPluginHandler) graceEndedSfcs(now int64) []string {
+
+	var ended []string
+	for name, sfc := range sfcCtrlPlugin.ramConfigCache.SFCs {
+		if sfc.DeletingSince == 0 || sfc.DeleteAt == 0 || sfc.DeleteAt > now {
+			continue
+		}
+		if err := cnpdriver.ValidateSfcEntityDelete(cnpDriverName, &sfc); err != nil {
+			log.Warnf("graceEndedSfcs: sfc: '%s' grace period ended at: %s, it is not removed: %s", name,
+				time.Unix(sfc.DeleteAt, 0), err)
+			continue
+		}
+		ended = append(ended, name)
+	}
+	sort.Strings(ended)
+	return ended
+}
+
+// deleteGraceEndedSfcs unwires and deletes the chains whose grace period ended at now
+func (sfcCtrlPlugin *SfcControllerPluginHandler) deleteGraceEndedSfcs(now int64) {
+
+	if ended := sfcCtrlPlugin.graceEndedSfcs(now); len(ended) != 0 {
+		sfcCtrlPlugin.deleteSfcs(ended, "the grace period ended")
+	}
+}
+
+// dropGraceEndedSfcs removes the chains whose grace period ended before the config is rendered, the reconcile
+// of the render removes what they were wired to
+func (sfcCtrlPlugin *SfcControllerPluginHandler) dropGraceEndedSfcs(now int64) {
+
+	current, target := sfcCtrlPlugin.removeSfcs(sfcCtrlPlugin.graceEndedSfcs(now))
+	if current == nil {
+		return
+	}
+	sfcCtrlPlugin.sfcsDeleted(current, target, "the grace period ended")
+}
+
+// deleteSfcs unwires and deletes the chains, the reason is recorded in their history and delete events
+func (sfcCtrlPlugin *SfcControllerPluginHandler) deleteSfcs(names []string, reason string) {
+
+	current, target := sfcCtrlPlugin.removeSfcs(names)
+	if current == nil {
+		return
+	}
+	sfcCtrlPlugin.unwireRemovedSfcs()
+
+	sfcCtrlPlugin.sfcsDeleted(current, target, reason)
+	if err := sfcCtrlPlugin.snapshotConfigVersion("DELETE SFC/" + strings.Join(names, ",")); err != nil {
+		log.Errorf("deleteSfcs: error storing config version: %s", err)
+	}
+}
+
+// sfcsDeleted records the deleted chains in their history and emits a delete event for each
+func (sfcCtrlPlugin *SfcControllerPluginHandler) sfcsDeleted(current *controller.ConfigVersion,
+	target *controller.ConfigVersion, reason string) {
+
+	sfcCtrlPlugin.recordConfigVersionChanges(current, target, "soft delete, "+reason)
+
+	for _, name := range sfcsRemovedNames(current, target) {
+		sfcCtrlPlugin.emitEvent(eventbus.Event{
+			Type:    eventbus.EventDelete,
+			Action:  deleteEventDeleted,
+			Kind:    controller.SfcEntityKind,
+			Name:    name,
+			Message: reason,
+		})
+	}
+}
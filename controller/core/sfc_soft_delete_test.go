@@ -0,0 +1,64 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateSfcDeletingChange(t *testing.T) {
+
+	previous := testChain("chain1")
+	previous.DeletingSince = 1000
+
+	relabeled := testChain("chain1")
+	relabeled.Description, relabeled.Labels, relabeled.TtlSeconds = "relabeled", map[string]string{"a": "b"}, 60
+	if err := validateSfcDeletingChange(&relabeled, &previous); err != nil {
+		t.Errorf("the description, labels and ttl change is refused: %s", err)
+	}
+	rewired := testChain("chain1")
+	rewired.Elements = rewired.Elements[:1]
+	if err := validateSfcDeletingChange(&rewired, &previous); err == nil {
+		t.Errorf("the change of the elements of a chain being deleted is not refused")
+	}
+	previous.DeletingSince = 0
+	if err := validateSfcDeletingChange(&rewired, &previous); err != nil {
+		t.Errorf("the change of a chain not being deleted is refused: %s", err)
+	}
+}
+
+func TestDeleteGraceEndedSfcs(t *testing.T) {
+
+	now := time.Now().Unix()
+	ended, grace, unconfirmed := testChain("ended"), testChain("grace"), testChain("unconfirmed")
+	ended.DeletingSince, ended.DeleteAt = now-60, now+30
+	grace.DeletingSince, grace.DeleteAt = now-60, now+3600
+	unconfirmed.DeletingSince = now - 60
+	sfcCtrlPlugin, broker := newTestPlugin(t, ended, grace, unconfirmed)
+
+	rendered := len(broker.Dump("/vnf-agent/"))
+	sfcCtrlPlugin.deleteGraceEndedSfcs(now + 60)
+
+	for name, kept := range map[string]bool{"ended": false, "grace": true, "unconfirmed": true} {
+		if _, exists := sfcCtrlPlugin.ramConfigCache.SFCs[name]; exists != kept || sfcStored(t, sfcCtrlPlugin,
+			name) != kept {
+			t.Errorf("sfc: '%s' kept: %t, expected: %t", name, exists, kept)
+		}
+	}
+	if unwired := len(broker.Dump("/vnf-agent/")); unwired >= rendered {
+		t.Errorf("the sfc whose grace period ended is not unwired: %d agent keys, %d before", unwired, rendered)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The sfc templates are implemented in this file.  A template is a chain
+// whose strings reference variables, ie the host, the subnet or a vni, as
+// ${name}.  An instantiation expands the template into a number of chains,
+// instance n takes the values it is given, or the n'th value of a variable's
+// pool, or the variable's default.  Every instance records its template and
+// the values it resolved, so a changed template is propagated to its
+// instances by expanding it again with the values they were given.
+
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// sfcTemplateIndexVariable is the variable every instance has, its index
+const sfcTemplateIndexVariable = "index"
+
+var sfcTemplateVariableRef = regexp.MustCompile(`\$\{([^}]*)\}`)
+
+// sfcTemplatePoolValue returns the n'th value of the pool, the first being n = 1
+func sfcTemplatePoolValue(pool string, n uint32) (string, error) {
+
+	if strings.Contains(pool, ",") {
+		values := strings.Split(pool, ",")
+		if n < 1 || int(n) > len(values) {
+			return "", fmt.Errorf("pool: '%s' has no value %d", pool, n)
+		}
+		return strings.TrimSpace(values[n-1]), nil
+	}
+
+	bounds := strings.Split(pool, "-")
+	if len(bounds) != 2 {
+		return "", fmt.Errorf("pool: '%s' is neither first-last nor a list", pool)
+	}
+	first, err := strconv.ParseUint(strings.TrimSpace(bounds[0]), 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("pool: '%s': %s", pool, err)
+	}
+	last, err := strconv.ParseUint(strings.TrimSpace(bounds[1]), 10, 32)
+	if err != nil {
+		return "", fmt.Errorf("pool: '%s': %s", pool, err)
+	}
+	if n < 1 || first+uint64(n)-1 > last {
+		return "", fmt.Errorf("pool: '%s' has no value %d", pool, n)
+	}
+	return strconv.FormatUint(first+uint64(n)-1, 10), nil
+}
+
+// sfcTemplateResolveVariables returns the values of the instance, the values it was given win over the
+// pools and defaults
+func sfcTemplateResolveVariables(tpl *controller.SfcTemplate, index uint32,
+	given map[string]string) (map[string]string, error) {
+
+	values := make(map[string]string)
+	for _, variable := range tpl.GetVariables() {
+		if value, exists := given[variable.Name]; exists {
+			values[variable.Name] = value
+		} else if variable.Pool != "" {
+			value, err := sfcTemplatePoolValue(variable.Pool, index)
+			if err != nil {
+				return nil, fmt.Errorf("variable: '%s': %s", variable.Name, err)
+			}
+			values[variable.Name] = value
+		} else {
+			values[variable.Name] = variable.Default
+		}
+	}
+	values[sfcTemplateIndexVariable] = strconv.FormatUint(uint64(index), 10)
+
+	return values, nil
+}
+
+// sfcTemplateSubstitute replaces the variables in the strings of the decoded json value
+func sfcTemplateSubstitute(v interface{}, values map[string]string, undefined map[string]bool) interface{} {
+
+	switch t := v.(type) {
+	case string:
+		return sfcTemplateVariableRef.ReplaceAllStringFunc(t, func(ref string) string {
+			name := sfcTemplateVariableRef.FindStringSubmatch(ref)[1]
+			if value, exists := values[name]; exists {
+				return value
+			}
+			undefined[name] = true
+			return ref
+		})
+	case []interface{}:
+		for i := range t {
+			t[i] = sfcTemplateSubstitute(t[i], values, undefined)
+		}
+	case map[string]interface{}:
+		for k := range t {
+			t[k] = sfcTemplateSubstitute(t[k], values, undefined)
+		}
+	}
+	return v
+}
+
+// expandSfcTemplate returns the instance of the template with the values
+func expandSfcTemplate(tpl *controller.SfcTemplate, values map[string]string) (*controller.SfcEntity, error) {
+
+	data, err := json.Marshal(tpl.GetSfc())
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	undefined := make(map[string]bool)
+	if data, err = json.Marshal(sfcTemplateSubstitute(decoded, values, undefined)); err != nil {
+		return nil, err
+	}
+	for name := range undefined {
+		return nil, fmt.Errorf("Invalid sfc template: '%s', undefined variable: '%s'", tpl.Name, name)
+	}
+
+	sfc := &controller.SfcEntity{}
+	if err := json.Unmarshal(data, sfc); err != nil {
+		return nil, err
+	}
+	if sfc.Name == "" {
+		sfc.Name = tpl.Name + "-" + values[sfcTemplateIndexVariable]
+	}
+	if sfc.Description == "" {
+		sfc.Description = tpl.Description
+	}
+	sfc.Template = tpl.Name
+	sfc.TemplateVariables = values
+
+	if tpl.VniVariable != "" {
+		vni, err := strconv.ParseUint(values[tpl.VniVariable], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid sfc template: '%s', vni: %s", tpl.Name, err)
+		}
+		for _, sfcElement := range sfc.GetElements() {
+			if sfcElement.VlanId == 0 {
+				sfcElement.VlanId = uint32(vni)
+			}
+		}
+	}
+
+	return sfc, nil
+}
+
+// instantiateSfcTemplate returns the chains the instantiation expands into
+func (sfcCtrlPlugin *SfcControllerPluginHandler) instantiateSfcTemplate(
+	inst *controller.SfcTemplateInstantiation) ([]controller.SfcEntity, error) {
+
+	tpl, exists := sfcCtrlPlugin.ramConfigCache.TPLs[inst.Template]
+	if !exists {
+		return nil, fmt.Errorf("Invalid sfc template instantiation, template: '%s' not found", inst.Template)
+	}
+	if inst.Count == 0 {
+		return nil, fmt.Errorf("Invalid sfc template instantiation of: '%s', count is 0", inst.Template)
+	}
+	for name := range inst.GetVariables() {
+		if sfcTemplateVariable(&tpl, name) == nil {
+			return nil, fmt.Errorf("Invalid sfc template instantiation of: '%s', unknown variable: '%s'",
+				inst.Template, name)
+		}
+	}
+
+	firstIndex := inst.FirstIndex
+	if firstIndex == 0 {
+		firstIndex = 1
+	}
+
+	sfcs := make([]controller.SfcEntity, 0, inst.Count)
+	for index := firstIndex; index < firstIndex+inst.Count; index++ {
+		values, err := sfcTemplateResolveVariables(&tpl, index, inst.GetVariables())
+		if err != nil {
+			return nil, fmt.Errorf("Invalid sfc template instantiation of: '%s': %s", inst.Template, err)
+		}
+		sfc, err := expandSfcTemplate(&tpl, values)
+		if err != nil {
+			return nil, err
+		}
+		sfcs = append(sfcs, *sfc)
+	}
+
+	return sfcs, sfcCtrlPlugin.validateSfcTemplateInstances(&tpl, sfcs)
+}
+
+// reinstantiateSfcTemplate returns the instances of the template expanded again with the values they were
+// instantiated with, the variables the template gained get their pool values or defaults
+func (sfcCtrlPlugin *SfcControllerPluginHandler) reinstantiateSfcTemplate(
+	tpl *controller.SfcTemplate) ([]controller.SfcEntity, error) {
+
+	var sfcs []controller.SfcEntity
+	for _, sfcName := range sortedKeysSFC(sfcCtrlPlugin.ramConfigCache.SFCs) {
+		instance := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+		if instance.Template != tpl.Name {
+			continue
+		}
+		index, err := strconv.ParseUint(instance.TemplateVariables[sfcTemplateIndexVariable], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid sfc template instance: '%s', index: %s", sfcName, err)
+		}
+		given := make(map[string]string)
+		for name, value := range instance.TemplateVariables {
+			if sfcTemplateVariable(tpl, name) != nil {
+				given[name] = value
+			}
+		}
+		values, err := sfcTemplateResolveVariables(tpl, uint32(index), given)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid sfc template: '%s', instance: '%s': %s", tpl.Name, sfcName, err)
+		}
+		sfc, err := expandSfcTemplate(tpl, values)
+		if err != nil {
+			return nil, err
+		}
+		sfcs = append(sfcs, *sfc)
+	}
+
+	return sfcs, sfcCtrlPlugin.validateSfcTemplateInstances(tpl, sfcs)
+}
+
+// validateSfcTemplateInstances validates the instances, an instance must not replace a chain that is not an
+// instance of the template
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSfcTemplateInstances(tpl *controller.SfcTemplate,
+	sfcs []controller.SfcEntity) error {
+
+	names := make(map[string]bool)
+	for _, sfc := range sfcs {
+		if names[sfc.Name] {
+			return fmt.Errorf("Invalid sfc template: '%s', duplicate instance: '%s'", tpl.Name, sfc.Name)
+		}
+		names[sfc.Name] = true
+		if existing, exists := sfcCtrlPlugin.ramConfigCache.SFCs[sfc.Name]; exists && existing.Template != tpl.Name {
+			return fmt.Errorf("Invalid sfc template: '%s', its instance: '%s' is already configured", tpl.Name,
+				sfc.Name)
+		}
+		if err := sfcCtrlPlugin.validateSFC(&sfc); err != nil {
+			return fmt.Errorf("Invalid sfc template: '%s', instance: '%s': %s", tpl.Name, sfc.Name, err)
+		}
+	}
+	return nil
+}
+
+func sfcTemplateVariable(tpl *controller.SfcTemplate, name string) *controller.SfcTemplate_Variable {
+	for _, variable := range tpl.GetVariables() {
+		if variable.Name == name {
+			return variable
+		}
+	}
+	return nil
+}
+
+// validateSfcTemplate validates the template itself, its instances are validated when they are expanded
+func validateSfcTemplate(tpl *controller.SfcTemplate) error {
+
+	if tpl.Name == "" {
+		return fmt.Errorf("Missing sfc template name")
+	}
+	if tpl.GetSfc() == nil {
+		return fmt.Errorf("Invalid sfc template: '%s', it has no sfc", tpl.Name)
+	}
+
+	names := make(map[string]bool)
+	for _, variable := range tpl.GetVariables() {
+		if variable.Name == "" || variable.Name == sfcTemplateIndexVariable || names[variable.Name] {
+			return fmt.Errorf("Invalid sfc template: '%s', variable: '%s'", tpl.Name, variable.Name)
+		}
+		names[variable.Name] = true
+		if variable.Pool != "" {
+			if _, err := sfcTemplatePoolValue(variable.Pool, 1); err != nil {
+				return fmt.Errorf("Invalid sfc template: '%s', variable: '%s': %s", tpl.Name, variable.Name, err)
+			}
+		}
+	}
+	if tpl.VniVariable != "" && !names[tpl.VniVariable] {
+		return fmt.Errorf("Invalid sfc template: '%s', vni_variable: '%s' is not a variable", tpl.Name,
+			tpl.VniVariable)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The sharding of the hosts across controllers is implemented in this file.
+// With a shard_count in the system parameters, each controller is started
+// with the -shard it owns.  A host's shard is its host_shards group, else
+// the hash of its name.  The controllers share the config and the datastore
+// records, and each renders all of it, but only the agents of its own hosts
+// and of their containers are written.  The vni of a tunnel between hosts of
+// different shards is agreed on through the shared he to he id records, see
+// the l2 driver.
+
+package core
+
+import (
+	"fmt"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/shards"
+)
+
+// hostShard returns the shard of the host
+func (sfcCtrlPlugin *SfcControllerPluginHandler) hostShard(heName string) uint32 {
+	sp := &sfcCtrlPlugin.ramConfigCache.SysParms
+	return shards.Assign(heName, sp.ShardCount, sp.GetHostShards())
+}
+
+// ownsVppLabel is whether the agent is written by this controller, a container's agent goes with the host
+// the container is on, the agents of unknown labels are written by every controller
+func (sfcCtrlPlugin *SfcControllerPluginHandler) ownsVppLabel(vppLabel string) bool {
+
+	if sfcCtrlPlugin.ramConfigCache.SysParms.ShardCount <= 1 {
+		return true
+	}
+
+	host := sfcCtrlPlugin.vppLabelHost(vppLabel)
+	if host == "" {
+		return true
+	}
+
+	return sfcCtrlPlugin.hostShard(host) == uint32(shardIndex)
+}
+
+// vppLabelHost returns the host of the agent, the host a container is on for a container's agent, "" if the
+// label is neither a host nor a container in an sfc
+func (sfcCtrlPlugin *SfcControllerPluginHandler) vppLabelHost(vppLabel string) string {
+	if _, exists := sfcCtrlPlugin.ramConfigCache.HEs[vppLabel]; exists {
+		return vppLabel
+	}
+	return sfcCtrlPlugin.containerHost(vppLabel)
+}
+
+// containerHost returns the host the container is on, "" if it is not in any sfc
+func (sfcCtrlPlugin *SfcControllerPluginHandler) containerHost(container string) string {
+	for _, sfc := range sfcCtrlPlugin.ramConfigCache.SFCs {
+		for _, sfcElement := range sfc.GetElements() {
+			switch sfcElement.Type {
+			case controller.SfcElementType_EXTERNAL_ENTITY, controller.SfcElementType_HOST_ENTITY:
+				continue
+			}
+			if sfcElement.Container == container {
+				return sfcElement.EtcdVppSwitchKey
+			}
+		}
+	}
+	return ""
+}
+
+// validateShards validates the sharding of the system parameters against this controller's -shard, the
+// controllers must allocate the same id's for the records they share so the id's have to be deterministic
+func validateShards(sp *controller.SystemParameters) error {
+
+	if sp.ShardCount <= 1 {
+		if len(sp.GetHostShards()) != 0 {
+			return fmt.Errorf("Invalid host_shards, shard_count: %d", sp.ShardCount)
+		}
+		return nil
+	}
+	if uint32(shardIndex) >= sp.ShardCount {
+		return fmt.Errorf("Invalid shard_count: %d for this controller's shard: %d", sp.ShardCount, shardIndex)
+	}
+	if !sp.DeterministicIds {
+		return fmt.Errorf("Invalid shard_count: %d, deterministic_ids must be set", sp.ShardCount)
+	}
+	for host, shard := range sp.GetHostShards() {
+		if shard >= sp.ShardCount {
+			return fmt.Errorf("Invalid host_shards, host: '%s' shard: %d, shard_count: %d", host, shard,
+				sp.ShardCount)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The shutdown policy is implemented in this file.  By default the config
+// rendered to the agents is left in place when the controller stops, so the
+// data plane keeps forwarding and the next start reconciles against it.
+// With -shutdown-policy teardown every agent key the driver owns is deleted,
+// and with teardown-tenants only the keys rendered for the chains of the
+// -shutdown-tenants are, less the ones another entity was rendered with, ie
+// the tunnels and bridges the chains share with the other tenants.  The
+// controller's own tree is kept either way, so a restart renders it all
+// again.
+
+package core
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// the shutdown policies, see -shutdown-policy
+const (
+	shutdownPolicyRetain          = "retain"
+	shutdownPolicyTeardown        = "teardown"
+	shutdownPolicyTeardownTenants = "teardown-tenants"
+)
+
+// validateShutdownPolicy checks the policy, and that the tenants are given with teardown-tenants only
+func validateShutdownPolicy(policy string, tenants string) error {
+
+	switch policy {
+	case shutdownPolicyRetain, shutdownPolicyTeardown:
+		if tenants != "" {
+			return fmt.Errorf("Invalid shutdown-tenants: '%s', only used with shutdown-policy: '%s'", tenants,
+				shutdownPolicyTeardownTenants)
+		}
+	case shutdownPolicyTeardownTenants:
+		if len(shutdownTenantList(tenants)) == 0 {
+			return fmt.Errorf("Missing shutdown-tenants for shutdown-policy: '%s'", policy)
+		}
+	default:
+		return fmt.Errorf("Invalid shutdown-policy: '%s', expected: %s, %s or %s", policy, shutdownPolicyRetain,
+			shutdownPolicyTeardown, shutdownPolicyTeardownTenants)
+	}
+	return nil
+}
+
+// shutdownTenantList returns the tenants of the comma separated list
+func shutdownTenantList(tenants string) []string {
+	list := make([]string, 0)
+	for _, tenant := range strings.Split(tenants, ",") {
+		if tenant = strings.TrimSpace(tenant); tenant != "" {
+			list = append(list, tenant)
+		}
+	}
+	return list
+}
+
+// shutdownTeardown deletes the rendered config the policy tears down, none if it retains it
+func (sfcCtrlPlugin *SfcControllerPluginHandler) shutdownTeardown(policy string, tenants []string) error {
+
+	if policy == shutdownPolicyRetain {
+		log.Info("shutdownTeardown: the rendered config is left in place")
+		return nil
+	}
+
+	sfcCtrlPlugin.HttpMutex.Lock()
+	defer sfcCtrlPlugin.HttpMutex.Unlock()
+
+	selected := func(key string) bool { return true }
+	if policy == shutdownPolicyTeardownTenants {
+		keys, err := sfcCtrlPlugin.tenantAgentKeys(tenants)
+		if err != nil {
+			return err
+		}
+		selected = func(key string) bool {
+			_, exists := keys[key]
+			return exists
+		}
+	}
+
+	deleted, err := sfcCtrlPlugin.cnpDriverPlugin.DeleteOwnedAgentKeys(selected)
+	log.Infof("shutdownTeardown: policy: '%s', tenants: %v, deleted %d agent keys", policy, tenants, deleted)
+	return err
+}
+
+// tenantAgentKeys returns the keys rendered for the chains of the tenants that no other entity was rendered with
+func (sfcCtrlPlugin *SfcControllerPluginHandler) tenantAgentKeys(tenants []string) (map[string]struct{}, error) {
+
+	tornDown := make(map[string]bool) // kind/name
+	for _, sfcName := range sortedKeysSFC(sfcCtrlPlugin.ramConfigCache.SFCs) {
+		sfc := sfcCtrlPlugin.ramConfigCache.SFCs[sfcName]
+		for _, tenant := range tenants {
+			if sfcHasMetadata(&sfc, "tenant", tenant) {
+				tornDown[controller.SfcEntityKind+"/"+sfcName] = true
+			}
+		}
+	}
+
+	keys := make(map[string]struct{})
+	shared := make(map[string]struct{})
+	err := sfcCtrlPlugin.DatastoreEntityKeysIterate(func(entity string, entityKeys *controller.EntityKeys) {
+		for _, key := range entityKeys.Keys {
+			if tornDown[entity] {
+				keys[key] = struct{}{}
+			} else {
+				shared[key] = struct{}{}
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for key := range shared {
+		delete(keys, key)
+	}
+	return keys, nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The convergence reporting is implemented in this file.  The time an
+// entity is submitted, by the rest api or any other change source, is kept
+// until the entity renders, and the time to its full render, and to the
+// agents' confirmation when the system parameters wait for the agents, is
+// sampled per entity kind.  An entity that fails or waits for another one
+// converges only once a later render succeeds, so retries count against it.
+// The entities rendered from the startup config, or by a reconcile, are
+// timed from the start of their render.  The percentiles of the latest
+// samples are published for tracking the convergence slo's.
+
+package core
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// the samples kept per entity kind, the oldest are dropped
+const convergenceMaxSamples = 1000
+
+// ConvergencePercentiles are the percentiles of the sampled convergence times, in milliseconds
+type ConvergencePercentiles struct {
+	Count int     `json:"count"`
+	P50Ms float64 `json:"p50_ms"`
+	P90Ms float64 `json:"p90_ms"`
+	P99Ms float64 `json:"p99_ms"`
+	MaxMs float64 `json:"max_ms"`
+}
+
+// ConvergenceSLO is how fast the entities of a kind render, and are confirmed by the agents, once submitted
+type ConvergenceSLO struct {
+	Kind    string                  `json:"kind"`
+	Render  ConvergencePercentiles  `json:"render"`
+	Confirm *ConvergencePercentiles `json:"confirm,omitempty"`
+}
+
+// convergenceTracker is the pending submissions and the samples of each kind, the renders and the rest api
+// use it concurrently
+type convergenceTracker struct {
+	sync.Mutex
+	submitted map[string]time.Time       // kind/name -> submission
+	render    map[string][]time.Duration // kind -> samples
+	confirm   map[string][]time.Duration // kind -> samples
+}
+
+// entitySubmitted starts timing the convergence of the entity, a re-submission restarts it
+func (ct *convergenceTracker) entitySubmitted(kind string, name string) {
+
+	ct.Lock()
+	defer ct.Unlock()
+
+	if ct.submitted == nil {
+		ct.submitted = make(map[string]time.Time)
+	}
+	ct.submitted[kind+"/"+name] = time.Now()
+}
+
+// entityRenderStarted times the entity from now if it was not submitted
+func (ct *convergenceTracker) entityRenderStarted(kind string, name string) {
+
+	ct.Lock()
+	defer ct.Unlock()
+
+	if ct.submitted == nil {
+		ct.submitted = make(map[string]time.Time)
+	}
+	if _, exists := ct.submitted[kind+"/"+name]; !exists {
+		ct.submitted[kind+"/"+name] = time.Now()
+	}
+}
+
+// entityConverged samples the times the entity took to render and to be confirmed, a zero confirmation time
+// is not sampled
+func (ct *convergenceTracker) entityConverged(kind string, name string, rendered time.Time, confirmed time.Time) {
+
+	ct.Lock()
+	defer ct.Unlock()
+
+	submitted, exists := ct.submitted[kind+"/"+name]
+	if !exists {
+		return
+	}
+	delete(ct.submitted, kind+"/"+name)
+
+	if ct.render == nil {
+		ct.render = make(map[string][]time.Duration)
+		ct.confirm = make(map[string][]time.Duration)
+	}
+	ct.render[kind] = appendConvergenceSample(ct.render[kind], rendered.Sub(submitted))
+	if !confirmed.IsZero() {
+		ct.confirm[kind] = appendConvergenceSample(ct.confirm[kind], confirmed.Sub(submitted))
+	}
+}
+
+func appendConvergenceSample(samples []time.Duration, sample time.Duration) []time.Duration {
+	if len(samples) == convergenceMaxSamples {
+		samples = samples[1:]
+	}
+	return append(samples, sample)
+}
+
+// report returns the percentiles of each kind, by kind
+func (ct *convergenceTracker) report() []ConvergenceSLO {
+
+	ct.Lock()
+	defer ct.Unlock()
+
+	kinds := make([]string, 0, len(ct.render))
+	for kind := range ct.render {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+
+	report := make([]ConvergenceSLO, 0, len(kinds))
+	for _, kind := range kinds {
+		slo := ConvergenceSLO{Kind: kind, Render: convergencePercentiles(ct.render[kind])}
+		if len(ct.confirm[kind]) != 0 {
+			confirm := convergencePercentiles(ct.confirm[kind])
+			slo.Confirm = &confirm
+		}
+		report = append(report, slo)
+	}
+	return report
+}
+
+// convergencePercentiles returns the nearest rank percentiles of the samples
+func convergencePercentiles(samples []time.Duration) ConvergencePercentiles {
+
+	sorted := make([]time.Duration, len(samples))
+	copy(sorted, samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	percentile := func(p int) float64 {
+		rank := (p*len(sorted) + 99) / 100
+		if rank < 1 {
+			rank = 1
+		}
+		return float64(sorted[rank-1]) / float64(time.Millisecond)
+	}
+	return ConvergencePercentiles{
+		Count: len(sorted),
+		P50Ms: percentile(50),
+		P90Ms: percentile(90),
+		P99Ms: percentile(99),
+		MaxMs: percentile(100),
+	}
+}
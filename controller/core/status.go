@@ -0,0 +1,230 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The per entity render status records are implemented in this file.  Each
+// render of an ee, he or sfc is recorded under the status tree in etcd so
+// external systems can watch whether, and how completely, an entity was
+// wired instead of scraping the logs.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// entityRenderType is the pending status and the rendered keys of an entity, kept until the render is flushed
+type entityRenderType struct {
+	kind      string
+	status    controller.EntityStatus
+	keys      map[string]struct{}
+	confirmed time.Time // when the agents last confirmed the render, see slo.go
+}
+
+// entityStatusRecord adds the outcome of one wiring step of an entity to its pending status, an entity is
+// usually wired in several steps, ie its internals then its connections to the other entities
+func (sfcCtrlPlugin *SfcControllerPluginHandler) entityStatusRecord(kind string, name string,
+	keyCountBefore uint32, err error) {
+
+	if sfcCtrlPlugin.entityRenders == nil {
+		sfcCtrlPlugin.entityRenders = make(map[string]*entityRenderType)
+	}
+	entityRender, exists := sfcCtrlPlugin.entityRenders[kind+"/"+name]
+	if !exists {
+		entityRender = &entityRenderType{
+			kind:   kind,
+			status: controller.EntityStatus{Name: name},
+			keys:   make(map[string]struct{}),
+		}
+		sfcCtrlPlugin.entityRenders[kind+"/"+name] = entityRender
+		sfcCtrlPlugin.convergence.entityRenderStarted(kind, name)
+	}
+
+	for _, key := range sfcCtrlPlugin.cnpDriverPlugin.GetRenderedKeys(keyCountBefore) {
+		entityRender.keys[key] = struct{}{}
+	}
+	if err != nil && entityRender.status.State != controller.RenderStateType_RENDER_ERROR {
+		entityRender.status.State = controller.RenderStateType_RENDER_ERROR
+		entityRender.status.Message = err.Error()
+	}
+}
+
+// entityStatusPending records that the entity was not wired as it waits for another entity to render
+func (sfcCtrlPlugin *SfcControllerPluginHandler) entityStatusPending(kind string, name string, message string) {
+
+	sfcCtrlPlugin.entityStatusRecord(kind, name, sfcCtrlPlugin.cnpDriverPlugin.GetRenderedKeyCount(), nil)
+
+	status := &sfcCtrlPlugin.entityRenders[kind+"/"+name].status
+	status.State = controller.RenderStateType_DEPENDENCY_PENDING
+	status.Message = message
+}
+
+// entityStatusConfirm waits for the agents to confirm what was rendered for the entity when the system
+// parameters ask for it, a rejection or timeout is recorded in the entity's status
+func (sfcCtrlPlugin *SfcControllerPluginHandler) entityStatusConfirm(kind string, name string) error {
+
+	sp := &sfcCtrlPlugin.ramConfigCache.SysParms
+	if !sp.WaitForAgent {
+		return nil
+	}
+
+	// the agents that are down would only time out, what was tracked for them is dropped
+	if entityRender := sfcCtrlPlugin.entityRenders[kind+"/"+name]; entityRender != nil {
+		if host := sfcCtrlPlugin.agentDownHostOfKeys(entityRender.keys); host != "" {
+			sfcCtrlPlugin.cnpDriverPlugin.WaitForAgents(0)
+			log.Infof("entityStatusConfirm: '%s' not confirmed: %s", name, agentDownMessage(host))
+			return nil
+		}
+	}
+
+	keyCount := sfcCtrlPlugin.cnpDriverPlugin.GetRenderedKeyCount()
+	err := sfcCtrlPlugin.cnpDriverPlugin.WaitForAgents(time.Duration(sp.AgentConfirmTimeout) * time.Second)
+	if err != nil {
+		log.Errorf("entityStatusConfirm: '%s' not confirmed: %s", name, err)
+	}
+	sfcCtrlPlugin.entityStatusRecord(kind, name, keyCount, err)
+	if err == nil {
+		sfcCtrlPlugin.entityRenders[kind+"/"+name].confirmed = time.Now()
+	}
+
+	return err
+}
+
+// entityStatusFlush writes the pending statuses, and the keys, of the entities rendered since the last flush,
+// an entity that failed after some of its keys were rendered is partially rendered
+func (sfcCtrlPlugin *SfcControllerPluginHandler) entityStatusFlush() {
+
+	now := time.Now().Unix()
+	for _, entityRender := range sfcCtrlPlugin.entityRenders {
+		status := &entityRender.status
+		status.KeyCount = uint32(len(entityRender.keys))
+		switch status.State {
+		case controller.RenderStateType_DEPENDENCY_PENDING:
+		case controller.RenderStateType_RENDER_ERROR:
+			if status.KeyCount != 0 {
+				status.State = controller.RenderStateType_PARTIALLY_RENDERED
+			}
+		default:
+			status.State = controller.RenderStateType_RENDERED
+			if host := sfcCtrlPlugin.agentDownHostOfKeys(entityRender.keys); host != "" {
+				status.State = controller.RenderStateType_AGENT_PENDING
+				status.Message = agentDownMessage(host)
+			}
+		}
+		if status.State == controller.RenderStateType_RENDERED {
+			sfcCtrlPlugin.convergence.entityConverged(entityRender.kind, status.Name, time.Now(),
+				entityRender.confirmed)
+		}
+		status.Timestamp = now
+		sfcCtrlPlugin.renderRetryUpdate(entityRender.kind, status, now)
+		sfcCtrlPlugin.emitWiringEvent(entityRender.kind, status)
+		sfcCtrlPlugin.alarmEntityStatus(entityRender.kind, status)
+
+		key := controller.EntityStatusKey(entityRender.kind, status.Name)
+		log.Infof("entityStatusFlush: setting key: '%s': %v", key, status)
+		if err := sfcCtrlPlugin.db.Put(key, status); err != nil {
+			log.Errorf("entityStatusFlush: error storing key: '%s': %s", key, err)
+		}
+
+		entityKeys := &controller.EntityKeys{Name: status.Name}
+		for renderedKey := range entityRender.keys {
+			entityKeys.Keys = append(entityKeys.Keys, renderedKey)
+		}
+		sort.Strings(entityKeys.Keys)
+
+		key = controller.EntityKeysKey(entityRender.kind, status.Name)
+		if err := sfcCtrlPlugin.db.Put(key, entityKeys); err != nil {
+			log.Errorf("entityStatusFlush: error storing key: '%s': %s", key, err)
+		}
+	}
+	sfcCtrlPlugin.entityRenders = nil
+	sfcCtrlPlugin.cnpDriverPlugin.ResetRenderedKeys()
+	sfcCtrlPlugin.alarmExhaustedIDSpaces()
+}
+
+// DatastoreEntityKeysRetrieve gets the keys rendered for the entity from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreEntityKeysRetrieve(kind string,
+	name string) (*controller.EntityKeys, error) {
+
+	key := controller.EntityKeysKey(kind, name)
+	entityKeys := &controller.EntityKeys{}
+	found, _, err := sfcCtrlPlugin.db.GetValue(key, entityKeys)
+	if err != nil {
+		log.Errorf("DatastoreEntityKeysRetrieve: error reading key: '%s': %s", key, err)
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("DatastoreEntityKeysRetrieve: not found: %s", key)
+	}
+
+	return entityKeys, nil
+}
+
+// DatastoreEntityKeysIterate iterates over the keys rendered for every entity, the action is given the
+// entity's kind/name
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreEntityKeysIterate(actionFunc func(entity string,
+	entityKeys *controller.EntityKeys)) error {
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(controller.EntityKeysKeyPrefix())
+	if err != nil {
+		log.Error("DatastoreEntityKeysIterate: ", err)
+		return err
+	}
+
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return nil
+		}
+		entityKeys := &controller.EntityKeys{}
+		if err := kv.GetValue(entityKeys); err != nil {
+			log.Error("DatastoreEntityKeysIterate: ", kv.GetKey(), err)
+			return err
+		}
+		actionFunc(strings.TrimPrefix(kv.GetKey(), controller.EntityKeysKeyPrefix()), entityKeys)
+	}
+}
+
+// DatastoreEntityStatusIterate calls actionFunc with the render status of each entity of the kind, of every
+// kind if it is empty
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreEntityStatusIterate(kind string,
+	actionFunc func(kind string, status *controller.EntityStatus)) error {
+
+	prefix := controller.StatusKeyPrefix()
+	if kind != "" {
+		prefix += kind + "/"
+	}
+	kvi, err := sfcCtrlPlugin.db.ListValues(prefix)
+	if err != nil {
+		log.Errorf("DatastoreEntityStatusIterate: error listing key: '%s': %s", prefix, err)
+		return err
+	}
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return nil
+		}
+		status := &controller.EntityStatus{}
+		if err := kv.GetValue(status); err != nil {
+			log.Errorf("DatastoreEntityStatusIterate: error decoding key: '%s': %s", kv.GetKey(), err)
+			return err
+		}
+		entityKind := strings.SplitN(strings.TrimPrefix(kv.GetKey(), controller.StatusKeyPrefix()), "/", 2)[0]
+		actionFunc(entityKind, status)
+	}
+}
@@ -19,7 +19,12 @@ package core
 
 import (
 	"fmt"
+	"net"
+	"strings"
+	"github.com/ligato/sfc-controller/controller/cnpdriver"
+	"github.com/ligato/sfc-controller/controller/cnpdriver/l2driver"
 	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/features"
 )
 
 func (sfcCtrlPlugin *SfcControllerPluginHandler) validateRAMCache() error {
@@ -64,6 +69,30 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSystemParameters(sp *co
 		log.Info("validateSystemParameters: sys default static route weight = 0, defaulting to 5")
 		sp.DefaultStaticRouteWeight = 5 // if not provided, default it to 5
 	}
+	if sp.BlueGreenAcceptTimeout == 0 {
+		log.Info("validateSystemParameters: sys blue green accept timeout = 0, defaulting to 10")
+		sp.BlueGreenAcceptTimeout = 10 // if not provided, default it to 10 secs
+	}
+	if sp.CanaryVerifyTimeout == 0 {
+		log.Info("validateSystemParameters: sys canary verify timeout = 0, defaulting to 30")
+		sp.CanaryVerifyTimeout = 30 // if not provided, default it to 30 secs
+	}
+	if sp.AgentConfirmTimeout == 0 {
+		log.Info("validateSystemParameters: sys agent confirm timeout = 0, defaulting to 10")
+		sp.AgentConfirmTimeout = 10 // if not provided, default it to 10 secs
+	}
+	if sp.RenderRetryMaxAttempts == 0 {
+		log.Info("validateSystemParameters: sys render retry max attempts = 0, defaulting to 5")
+		sp.RenderRetryMaxAttempts = 5 // if not provided, default it to 5
+	}
+	if sp.RenderRetryBackoff == 0 {
+		log.Info("validateSystemParameters: sys render retry backoff = 0, defaulting to 2")
+		sp.RenderRetryBackoff = 2 // if not provided, default it to 2 secs
+	}
+	if sp.RenderRetryBackoffMax == 0 {
+		log.Info("validateSystemParameters: sys render retry backoff max = 0, defaulting to 300")
+		sp.RenderRetryBackoffMax = 300 // if not provided, default it to 300 secs
+	}
 	if sp.DynamicBridgeParms == nil {
 		sp.DynamicBridgeParms = &controller.BDParms{
 			Learn: true,
@@ -84,6 +113,21 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSystemParameters(sp *co
 			MacAge: 0,
 		}
 	}
+	if err := validateVxlanParms(sp.VxlanParms); err != nil {
+		return err
+	}
+	if _, err := l2driver.LookupAgentAdapter(sp.AgentApi); err != nil {
+		return err
+	}
+	if err := features.Validate(sp.FeatureFlags); err != nil {
+		return err
+	}
+	if err := validateShards(sp); err != nil {
+		return err
+	}
+	if sp.AgentDownTimeout != 0 && sp.AgentWatchInterval == 0 {
+		return fmt.Errorf("Invalid agent_down_timeout: %d, agent_watch_interval must be set", sp.AgentDownTimeout)
+	}
 	log.Info("validateSystemParameters: final SP's", sp)
 
 	return nil
@@ -99,6 +143,80 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) validateEE(ee *controller.Exter
 		err := fmt.Errorf("Invalid mgmt_ip_address: '%s'", ee.MgmntIpAddress)
 		return err
 	}
+	if err := validateVxlanParms(ee.GetHostVxlan().GetVxlanParms()); err != nil {
+		return err
+	}
+	if ee.HostVxlan != nil {
+		if err := validateHostAddrFamily(ee.HostVxlan.SourceIpv4, false); err != nil {
+			return fmt.Errorf("Invalid host_vxlan source_ipv4 for ee: '%s': %s", ee.Name, err)
+		}
+	}
+	if ee.HostInterface != nil {
+		if err := validateHostAddrFamily(ee.HostInterface.Ipv4Addr, false); err != nil {
+			return fmt.Errorf("Invalid host_interface ipv4_addr for ee: '%s': %s", ee.Name, err)
+		}
+	}
+	for _, heName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		he := sfcCtrlPlugin.ramConfigCache.HEs[heName]
+		if err := validateEEHostLink(ee, &he); err != nil {
+			return err
+		}
+	}
+	if err := features.Validate(ee.FeatureFlags); err != nil {
+		return fmt.Errorf("Invalid feature_flags for ee: '%s': %s", ee.Name, err)
+	}
+	if err := validateLabels(ee.Labels); err != nil {
+		return fmt.Errorf("Invalid labels for ee: '%s': %s", ee.Name, err)
+	}
+	if err := validateEEPrefixes(ee); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validate the prefixes behind the ee, they are routed via its host_interface, once per vrf
+func validateEEPrefixes(ee *controller.ExternalEntity) error {
+
+	if len(ee.GetPrefixes()) == 0 {
+		return nil
+	}
+	if ee.HostInterface == nil || ee.HostInterface.Ipv4Addr == "" {
+		return fmt.Errorf("Missing host_interface ipv4_addr for the prefixes of ee: '%s'", ee.Name)
+	}
+
+	vrfPrefixes := make(map[string]bool)
+	for _, prefix := range ee.GetPrefixes() {
+		dstIP, dstNet, err := net.ParseCIDR(prefix.DstIpAddr)
+		if err != nil || dstIP.To4() == nil {
+			return fmt.Errorf("Invalid prefix dst_ip_addr: '%s' for ee: '%s'", prefix.DstIpAddr, ee.Name)
+		}
+		key := fmt.Sprintf("%d/%s", prefix.VrfId, dstNet.String())
+		if vrfPrefixes[key] {
+			return fmt.Errorf("Invalid prefix dst_ip_addr: '%s' for ee: '%s', it is already in vrf: %d",
+				prefix.DstIpAddr, ee.Name, prefix.VrfId)
+		}
+		vrfPrefixes[key] = true
+	}
+
+	return nil
+}
+
+// validate the vxlan encap parms, 0 for any of them means use the default
+func validateVxlanParms(vp *controller.VxlanParms) error {
+
+	if vp == nil {
+		return nil
+	}
+	if vp.DstPort > 65535 {
+		return fmt.Errorf("Invalid vxlan dst_port: '%d'", vp.DstPort)
+	}
+	if vp.Tos > 255 {
+		return fmt.Errorf("Invalid vxlan tos: '%d'", vp.Tos)
+	}
+	if vp.Ttl > 255 {
+		return fmt.Errorf("Invalid vxlan ttl: '%d'", vp.Ttl)
+	}
 
 	return nil
 }
@@ -110,10 +228,195 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) validateHE(he *controller.HostE
 		err := fmt.Errorf("Missing entity name")
 		return err
 	}
+	if _, err := l2driver.LookupAgentAdapter(he.AgentApi); err != nil {
+		return fmt.Errorf("Invalid agent_api for he: '%s': %s", he.Name, err)
+	}
+	if err := features.Validate(he.FeatureFlags); err != nil {
+		return fmt.Errorf("Invalid feature_flags for he: '%s': %s", he.Name, err)
+	}
+	if err := validateLabels(he.Labels); err != nil {
+		return fmt.Errorf("Invalid labels for he: '%s': %s", he.Name, err)
+	}
+	if err := cnpdriver.ValidateHostEntity(cnpDriverName, he); err != nil {
+		return err
+	}
+	if err := validateKernelHE(he); err != nil {
+		return err
+	}
+
+	uplinks := make(map[string]bool)
+	uplinks[he.EthIfName] = true
+	for _, uplink := range he.GetUplinks() {
+		if uplink.EthIfName == "" {
+			err := fmt.Errorf("Missing uplink eth_if_name for he: '%s'", he.Name)
+			return err
+		}
+		uplinks[uplink.EthIfName] = true
+	}
+	for _, peerUplink := range he.GetPeerUplinks() {
+		if !uplinks[peerUplink.EthIfName] {
+			err := fmt.Errorf("Invalid peer_uplink eth_if_name: '%s' for peer: '%s', he: '%s'",
+				peerUplink.EthIfName, peerUplink.Peer, he.Name)
+			return err
+		}
+	}
+
+	// the tunnel sources are of their family, an ipv6 only host tunnels and routes over its ipv6 ones
+	tunnelSrcs := []struct{ addr, ipv6 string }{{he.VxlanTunnelIpv4, he.VxlanTunnelIpv6}}
+	for _, uplink := range he.GetUplinks() {
+		tunnelSrcs = append(tunnelSrcs, struct{ addr, ipv6 string }{uplink.VxlanTunnelIpv4, uplink.VxlanTunnelIpv6})
+	}
+	for _, tunnelSrc := range tunnelSrcs {
+		if err := validateHostAddrFamily(tunnelSrc.addr, false); err != nil {
+			return fmt.Errorf("Invalid vxlan_tunnel_ipv4 for he: '%s': %s", he.Name, err)
+		}
+		if err := validateHostAddrFamily(tunnelSrc.ipv6, true); err != nil {
+			return fmt.Errorf("Invalid vxlan_tunnel_ipv6 for he: '%s': %s", he.Name, err)
+		}
+	}
+	if he.VxlanTunnelIpv6 != "" && he.VxlanTunnelIpv4 == "" && he.EthIpv6 == "" {
+		return fmt.Errorf("Missing eth_ipv6 for the ipv6 vxlan tunnels of he: '%s'", he.Name)
+	}
+	if he.LoopbackTunnelEndpoints && he.LoopbackIpv4 == "" && he.LoopbackIpv6 == "" {
+		return fmt.Errorf("Missing loopback_ipv4 or loopback_ipv6 for the loopback tunnel endpoints of he: '%s'",
+			he.Name)
+	}
+	for _, eeName := range sortedKeysEE(sfcCtrlPlugin.ramConfigCache.EEs) {
+		ee := sfcCtrlPlugin.ramConfigCache.EEs[eeName]
+		if err := validateEEHostLink(&ee, he); err != nil {
+			return err
+		}
+	}
+
+	loopbacks := make(map[string]bool)
+	for _, loopback := range he.GetLoopbacks() {
+		if loopback.Name == "" {
+			err := fmt.Errorf("Missing loopback name for he: '%s'", he.Name)
+			return err
+		}
+		if loopbacks[loopback.Name] {
+			err := fmt.Errorf("Duplicate loopback name: '%s' for he: '%s'", loopback.Name, he.Name)
+			return err
+		}
+		if loopback.Ipv4 == "" && loopback.Ipv6 == "" {
+			err := fmt.Errorf("Missing ipv4 and ipv6 for loopback: '%s', he: '%s'", loopback.Name, he.Name)
+			return err
+		}
+		loopbacks[loopback.Name] = true
+	}
+
+	// an address may only be on several hosts if every one of them declares it as an anycast vip
+	heAddrs := hostEntityLoopbackAddrs(he)
+	for _, otherHE := range sfcCtrlPlugin.ramConfigCache.HEs {
+		if otherHE.Name == he.Name {
+			continue
+		}
+		for addr, otherAnycast := range hostEntityLoopbackAddrs(&otherHE) {
+			if anycast, exists := heAddrs[addr]; exists && !(anycast && otherAnycast) {
+				err := fmt.Errorf("Loopback address: '%s' of he: '%s' is also on he: '%s' but is not anycast on both",
+					addr, he.Name, otherHE.Name)
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateHostAddrFamily checks the address, if any, is an ipv6 or an ipv4 one, with or without a prefix length
+func validateHostAddrFamily(addr string, ipv6 bool) error {
+
+	if addr == "" {
+		return nil
+	}
+	ip := net.ParseIP(strings.Split(addr, "/")[0])
+	switch {
+	case ip == nil:
+		return fmt.Errorf("'%s' is not an ip address", addr)
+	case ipv6 && ip.To4() != nil:
+		return fmt.Errorf("'%s' is not an ipv6 address", addr)
+	case !ipv6 && ip.To4() == nil:
+		return fmt.Errorf("'%s' is not an ipv4 address", addr)
+	}
+	return nil
+}
+
+// hostPeerUplinkAddrs returns the eth_ipv4 and the vxlan tunnel source of the uplink the host reaches the peer by,
+// as the driver picks them
+func hostPeerUplinkAddrs(he *controller.HostEntity, peer string) (ifName string, ethIpv4 string, tunnelIpv4 string) {
+
+	ifName, ethIpv4, tunnelIpv4 = he.EthIfName, he.EthIpv4, he.VxlanTunnelIpv4
+	peerIfName := hostPeerIfName(he, peer)
+	for _, uplink := range he.GetUplinks() {
+		if uplink.EthIfName == peerIfName {
+			ifName, ethIpv4 = uplink.EthIfName, uplink.EthIpv4
+			if uplink.VxlanTunnelIpv4 != "" {
+				tunnelIpv4 = uplink.VxlanTunnelIpv4
+			}
+		}
+	}
+	if he.LoopbackTunnelEndpoints && he.LoopbackIpv4 != "" {
+		tunnelIpv4 = strings.Split(he.LoopbackIpv4, "/")[0]
+	}
+	return ifName, ethIpv4, tunnelIpv4
+}
+
+// validateEEHostLink checks the ee's host_interface and the host's uplink to it are on one subnet, each routes to
+// the other's vxlan tunnel source via the other's address, so it must be on link
+func validateEEHostLink(ee *controller.ExternalEntity, he *controller.HostEntity) error {
+
+	if ee.HostInterface == nil || ee.HostInterface.Ipv4Addr == "" {
+		return nil
+	}
+	eeAddr := ee.HostInterface.Ipv4Addr
+	ifName, heAddr, _ := hostPeerUplinkAddrs(he, ee.Name)
+	if heAddr == "" {
+		return nil
+	}
+	eeIP := net.ParseIP(strings.Split(eeAddr, "/")[0])
+	heIP := net.ParseIP(strings.Split(heAddr, "/")[0])
+	if eeIP == nil || heIP == nil {
+		return nil
+	}
+
+	if eeIP.Equal(heIP) {
+		return fmt.Errorf("Invalid host_interface ipv4_addr: '%s' for ee: '%s', it is the address of he: '%s' "+
+			"i/f: '%s'", eeAddr, ee.Name, he.Name, ifName)
+	}
+	for _, link := range []struct{ addr, peerAddr string }{{heAddr, eeAddr}, {eeAddr, heAddr}} {
+		_, ipNet, err := net.ParseCIDR(link.addr)
+		if err != nil {
+			continue // no prefix length, the subnet is not known
+		}
+		if !ipNet.Contains(net.ParseIP(strings.Split(link.peerAddr, "/")[0])) {
+			return fmt.Errorf("Invalid host_interface ipv4_addr: '%s' for ee: '%s', it is not on the subnet of "+
+				"he: '%s' i/f: '%s': '%s'", eeAddr, ee.Name, he.Name, ifName, heAddr)
+		}
+	}
 
 	return nil
 }
 
+// hostEntityLoopbackAddrs returns the loopback addresses of the host, and whether each is an anycast vip
+func hostEntityLoopbackAddrs(he *controller.HostEntity) map[string]bool {
+
+	addrs := make(map[string]bool)
+	for _, addr := range []string{he.LoopbackIpv4, he.LoopbackIpv6} {
+		if addr != "" {
+			addrs[strings.Split(addr, "/")[0]] = false
+		}
+	}
+	for _, loopback := range he.GetLoopbacks() {
+		for _, addr := range []string{loopback.Ipv4, loopback.Ipv6} {
+			if addr != "" {
+				addrs[strings.Split(addr, "/")[0]] = loopback.Anycast
+			}
+		}
+	}
+
+	return addrs
+}
+
 // validate the SFC, TODO: perform better/complete validation
 func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSFC(sfc *controller.SfcEntity) error {
 
@@ -121,6 +424,77 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSFC(sfc *controller.Sfc
 		err := fmt.Errorf("Missing entity name")
 		return err
 	}
+	if err := features.Validate(sfc.FeatureFlags); err != nil {
+		return fmt.Errorf("Invalid feature_flags for sfc: '%s': %s", sfc.Name, err)
+	}
+	// the elements are placed first, so the rest is validated where they are
+	if err := sfcCtrlPlugin.placeSfcElements(sfc); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.validateSfcAffinity(sfc); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.validateSfcKernelHosts(sfc); err != nil {
+		return err
+	}
+	if err := validateLabels(sfc.Labels); err != nil {
+		return fmt.Errorf("Invalid labels for sfc: '%s': %s", sfc.Name, err)
+	}
+	if err := validateSfcEnvironments(sfc); err != nil {
+		return err
+	}
+	if sfc.SfcIpv6Prefix != "" {
+		if ip, _, err := net.ParseCIDR(sfc.SfcIpv6Prefix); err != nil || ip.To4() != nil {
+			return fmt.Errorf("Invalid sfc_ipv6_prefix: '%s' for sfc: '%s'", sfc.SfcIpv6Prefix, sfc.Name)
+		}
+	}
+	if err := validateSfcIpv6L3Entries(sfc); err != nil {
+		return err
+	}
+	if err := validateSfcNics(sfc); err != nil {
+		return err
+	}
+	if err := validateSfcSteeringRules(sfc); err != nil {
+		return err
+	}
+	if err := cnpdriver.ValidateSfcEntity(cnpDriverName, sfc); err != nil {
+		return err
+	}
+	if err := validateSfcLinuxL3Entries(sfc); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.validateSfcUnnumbered(sfc); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.validateSfcDependsOn(sfc); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.validateSfcBandwidth(sfc); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.validateSfcHostResources(sfc); err != nil {
+		return err
+	}
+	if err := sfcCtrlPlugin.validateSfcExtEntityTunnels(sfc); err != nil {
+		return err
+	}
+	for _, sfcElement := range sfc.GetElements() {
+		for key, value := range sfcElement.GetMetadata() {
+			// the metadata is rendered as comma separated key=value tags
+			if key == "" || strings.ContainsAny(key, ",=") || strings.Contains(value, ",") {
+				return fmt.Errorf("Invalid metadata: '%s=%s' for element: '%s/%s', sfc: '%s'",
+					key, value, sfcElement.Container, sfcElement.PortLabel, sfc.Name)
+			}
+		}
+		if sfcElement.AdminDown && !sfcElementIsContainer(sfcElement) {
+			return fmt.Errorf("Invalid admin_down for element: '%s/%s', sfc: '%s', only a container's i/f's "+
+				"can be disabled", sfcElement.Container, sfcElement.PortLabel, sfc.Name)
+		}
+		if sfcElement.PortParms != nil && (!sfcElementIsContainer(sfcElement) || !sfcIsBridged(sfc)) {
+			return fmt.Errorf("Invalid port_parms for element: '%s/%s', sfc: '%s', only a container's port in "+
+				"a bridge domain has per port toggles", sfcElement.Container, sfcElement.PortLabel, sfc.Name)
+		}
+	}
 	numSfcElements := len(sfc.GetElements())
 	if numSfcElements <= 0 {
 		return nil
@@ -144,3 +518,321 @@ func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSFC(sfc *controller.Sfc
 
 	return nil
 }
+
+// validate the vxlan tunnels a n/s chain has its hosts, or their gateways, bring up to its ee, both ends need an
+// ipv4 tunnel source of their own, and the routes to them need the ee's and the host's uplink addresses
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSfcExtEntityTunnels(sfc *controller.SfcEntity) error {
+
+	if sfc.Type != controller.SfcType_SFC_NS_VXLAN {
+		return nil
+	}
+	var ee *controller.ExternalEntity
+	for _, sfcElement := range sfc.GetElements() {
+		if sfcElement.Type == controller.SfcElementType_EXTERNAL_ENTITY {
+			if eeEntity, exists := sfcCtrlPlugin.ramConfigCache.EEs[sfcElement.Container]; exists {
+				ee = &eeEntity
+			}
+		}
+	}
+	if ee == nil {
+		return nil
+	}
+	if isFederationPeer(ee) && (ee.HostVxlan == nil || ee.HostVxlan.SourceIpv4 == "") {
+		return nil // the endpoint is learned from the peer, see federation.go
+	}
+	if ee.HostVxlan == nil || ee.HostVxlan.SourceIpv4 == "" {
+		return fmt.Errorf("Missing host_vxlan source_ipv4 of ee: '%s' for the vxlan tunnels of sfc: '%s'",
+			ee.Name, sfc.Name)
+	}
+	eeTunnelIP := net.ParseIP(strings.Split(ee.HostVxlan.SourceIpv4, "/")[0])
+
+	seen := make(map[string]bool)
+	for _, sfcElement := range sfc.GetElements() {
+		if !sfcElementIsContainer(sfcElement) || sfcElement.EtcdVppSwitchKey == "" {
+			continue
+		}
+		he, exists := sfcCtrlPlugin.ramConfigCache.HEs[sfcCtrlPlugin.eeTunnelHost(sfcElement.EtcdVppSwitchKey)]
+		if !exists || seen[he.Name] {
+			continue
+		}
+		seen[he.Name] = true
+
+		_, ethIpv4, tunnelIpv4 := hostPeerUplinkAddrs(&he, ee.Name)
+		if tunnelIpv4 == "" {
+			return fmt.Errorf("Missing vxlan_tunnel_ipv4 of he: '%s' for the vxlan tunnel to ee: '%s', sfc: '%s'",
+				he.Name, ee.Name, sfc.Name)
+		}
+		if net.ParseIP(strings.Split(tunnelIpv4, "/")[0]).Equal(eeTunnelIP) {
+			return fmt.Errorf("Invalid vxlan tunnel of sfc: '%s', he: '%s' and ee: '%s' have the same source: '%s'",
+				sfc.Name, he.Name, ee.Name, ee.HostVxlan.SourceIpv4)
+		}
+		if ethIpv4 == "" {
+			return fmt.Errorf("Missing eth_ipv4 of he: '%s' for the route of ee: '%s' to its vxlan tunnel, sfc: '%s'",
+				he.Name, ee.Name, sfc.Name)
+		}
+		if he.CreateVxlanStaticRoute && (ee.HostInterface == nil || ee.HostInterface.Ipv4Addr == "") {
+			return fmt.Errorf("Missing host_interface ipv4_addr of ee: '%s' for the vxlan static route of he: '%s', "+
+				"sfc: '%s'", ee.Name, he.Name, sfc.Name)
+		}
+	}
+
+	return nil
+}
+
+// eeTunnelHost returns the host that tunnels to the ee's for the host, with the gateway overlay topology a
+// spoke's gateway does, as the driver picks it
+func (sfcCtrlPlugin *SfcControllerPluginHandler) eeTunnelHost(heName string) string {
+
+	if sfcCtrlPlugin.ramConfigCache.SysParms.OverlayTopology != controller.OverlayTopologyType_OVERLAY_GATEWAY {
+		return heName
+	}
+	he := sfcCtrlPlugin.ramConfigCache.HEs[heName]
+	if he.Gateway {
+		return heName
+	}
+	if gw, exists := sfcCtrlPlugin.ramConfigCache.HEs[he.GatewayHost]; exists && gw.Gateway {
+		return gw.Name
+	}
+	for _, gwName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		if sfcCtrlPlugin.ramConfigCache.HEs[gwName].Gateway {
+			return gwName
+		}
+	}
+	return heName
+}
+
+// validate the ipv6 vrf routes and neighbors of the sfc's elements, the routes of a chain are rendered
+// per vrf, so two routes with the same vrf, dst and next hop would overwrite each other in the agent
+func validateSfcIpv6L3Entries(sfc *controller.SfcEntity) error {
+
+	vrfRoutes := make(map[string]string)
+
+	for _, sfcElement := range sfc.GetElements() {
+		element := sfcElement.Container + "/" + sfcElement.PortLabel
+
+		for _, route := range sfcElement.GetL3VrfIpv6Routes() {
+			dstIP, dstNet, err := net.ParseCIDR(route.DstIpAddr)
+			if err != nil || dstIP.To4() != nil {
+				return fmt.Errorf("Invalid ipv6 route dst_ip_addr: '%s' for element: '%s', sfc: '%s'",
+					route.DstIpAddr, element, sfc.Name)
+			}
+			nextHop := strings.Split(route.NextHopAddr, "/")[0]
+			if nextHop != "" {
+				nextHopIP := net.ParseIP(nextHop)
+				if nextHopIP == nil || nextHopIP.To4() != nil || nextHopIP.IsUnspecified() ||
+					nextHopIP.IsMulticast() {
+					return fmt.Errorf("Invalid ipv6 route next_hop_addr: '%s' for element: '%s', sfc: '%s'",
+						route.NextHopAddr, element, sfc.Name)
+				}
+				nextHop = nextHopIP.String()
+			}
+			key := fmt.Sprintf("%d/%s/%s", route.VrfId, dstNet.String(), nextHop)
+			if other, exists := vrfRoutes[key]; exists {
+				return fmt.Errorf("Duplicate ipv6 route: vrf: '%d', dst: '%s', next hop: '%s' for elements: '%s' and '%s', sfc: '%s'",
+					route.VrfId, dstNet.String(), nextHop, other, element, sfc.Name)
+			}
+			vrfRoutes[key] = element
+		}
+
+		for _, neighbor := range sfcElement.GetL3Ipv6Neighbors() {
+			ip := net.ParseIP(neighbor.IpAddress)
+			if ip == nil || ip.To4() != nil {
+				return fmt.Errorf("Invalid ipv6 neighbor ip_address: '%s' for element: '%s', sfc: '%s'",
+					neighbor.IpAddress, element, sfc.Name)
+			}
+			if _, err := net.ParseMAC(neighbor.PhysAddress); err != nil {
+				return fmt.Errorf("Invalid ipv6 neighbor phys_address: '%s' for element: '%s', sfc: '%s'",
+					neighbor.PhysAddress, element, sfc.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validate the nics of a n/s nic chain, several host entity elements are nics of the same host a vrf chain
+// routes over, the vpp-agent has no bond to attach a bd or l2xconn chain to several nics
+func validateSfcNics(sfc *controller.SfcEntity) error {
+
+	switch sfc.Type {
+	case controller.SfcType_SFC_NS_NIC_BD, controller.SfcType_SFC_NS_NIC_L2XCONN, controller.SfcType_SFC_NS_NIC_VRF:
+	default:
+		return nil
+	}
+
+	var nics []*controller.SfcEntity_SfcElement
+	for _, sfcElement := range sfc.GetElements() {
+		if sfcElement.Type != controller.SfcElementType_HOST_ENTITY {
+			continue
+		}
+		for _, nic := range nics {
+			if nic.Container != sfcElement.Container {
+				return fmt.Errorf("Invalid nic: '%s/%s' for sfc: '%s', the nics of a chain are on one host: '%s'",
+					sfcElement.Container, sfcElement.PortLabel, sfc.Name, nic.Container)
+			}
+			if nic.PortLabel == sfcElement.PortLabel {
+				return fmt.Errorf("Invalid nic: '%s/%s' for sfc: '%s', the nic is listed twice",
+					sfcElement.Container, sfcElement.PortLabel, sfc.Name)
+			}
+		}
+		nics = append(nics, sfcElement)
+	}
+
+	if len(nics) > 1 && sfc.Type != controller.SfcType_SFC_NS_NIC_VRF {
+		return fmt.Errorf("Invalid nic: '%s/%s' for sfc: '%s', only a vrf chain has several nics, the vpp-agent "+
+			"has no bond to attach the chain to", nics[1].Container, nics[1].PortLabel, sfc.Name)
+	}
+
+	return nil
+}
+
+// validate the steering rules of the chain, the networks of a rule are of the same family and the ports are
+// of a tcp or udp rule
+func validateSfcSteeringRules(sfc *controller.SfcEntity) error {
+
+	for i, rule := range sfc.GetSteeringRules() {
+		families := make(map[bool]bool)
+		for _, network := range []string{rule.SrcNetwork, rule.DstNetwork} {
+			if network == "" {
+				continue
+			}
+			ip, _, err := net.ParseCIDR(network)
+			if err != nil {
+				return fmt.Errorf("Invalid steering_rules[%d] network: '%s' for sfc: '%s'", i, network, sfc.Name)
+			}
+			families[ip.To4() == nil] = true
+		}
+		if len(families) > 1 {
+			return fmt.Errorf("Mixed ipv4 and ipv6 steering_rules[%d] networks for sfc: '%s'", i, sfc.Name)
+		}
+		if rule.Protocol > 255 {
+			return fmt.Errorf("Invalid steering_rules[%d] protocol: %d for sfc: '%s'", i, rule.Protocol, sfc.Name)
+		}
+		if rule.DstPortLower == 0 && rule.DstPortUpper == 0 {
+			continue
+		}
+		if rule.Protocol != 6 && rule.Protocol != 17 {
+			return fmt.Errorf("Invalid steering_rules[%d] for sfc: '%s', only tcp (6) and udp (17) rules have ports",
+				i, sfc.Name)
+		}
+		if rule.DstPortLower == 0 || rule.DstPortLower > 65535 || rule.DstPortUpper > 65535 ||
+			(rule.DstPortUpper != 0 && rule.DstPortUpper < rule.DstPortLower) {
+			return fmt.Errorf("Invalid steering_rules[%d] port range: %d-%d for sfc: '%s'", i, rule.DstPortLower,
+				rule.DstPortUpper, sfc.Name)
+		}
+	}
+
+	return nil
+}
+
+// validate the linux routes, default gateway and arp entries of the sfc's elements, they are rendered in the
+// namespace of a non vpp container on its veth, so only its af_packet elements can have them
+// an unnumbered element borrows the address of one of its host's loopbacks, the host is checked if it is
+// already known as the chain can be posted before it
+func (sfcCtrlPlugin *SfcControllerPluginHandler) validateSfcUnnumbered(sfc *controller.SfcEntity) error {
+
+	for _, sfcElement := range sfc.GetElements() {
+		element := sfcElement.Container + "/" + sfcElement.PortLabel
+
+		if !sfcElement.Unnumbered {
+			if sfcElement.UnnumberedLoopback != "" {
+				return fmt.Errorf("Invalid unnumbered_loopback: '%s' for element: '%s', sfc: '%s', the element is not unnumbered",
+					sfcElement.UnnumberedLoopback, element, sfc.Name)
+			}
+			continue
+		}
+		if sfc.Type != controller.SfcType_SFC_NS_NIC_VRF {
+			return fmt.Errorf("Invalid unnumbered element: '%s', sfc: '%s', only l3vrf sfc types have unnumbered elements",
+				element, sfc.Name)
+		}
+		if sfcElement.Type == controller.SfcElementType_HOST_ENTITY ||
+			sfcElement.Type == controller.SfcElementType_EXTERNAL_ENTITY {
+			return fmt.Errorf("Invalid unnumbered element: '%s', sfc: '%s', only container elements are unnumbered",
+				element, sfc.Name)
+		}
+
+		he, exists := sfcCtrlPlugin.ramConfigCache.HEs[sfcElement.EtcdVppSwitchKey]
+		if !exists {
+			continue
+		}
+		if sfcElement.UnnumberedLoopback == "" {
+			if he.LoopbackIpv4 == "" && he.LoopbackIpv6 == "" {
+				return fmt.Errorf("Invalid unnumbered element: '%s', sfc: '%s', host: '%s' has no loopback address",
+					element, sfc.Name, he.Name)
+			}
+			continue
+		}
+		found := false
+		for _, loopback := range he.GetLoopbacks() {
+			if loopback.Name == sfcElement.UnnumberedLoopback {
+				found = loopback.Ipv4 != "" || loopback.Ipv6 != ""
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("Invalid unnumbered_loopback: '%s' for element: '%s', sfc: '%s', host: '%s' has no such loopback with an address",
+				sfcElement.UnnumberedLoopback, element, sfc.Name, he.Name)
+		}
+	}
+
+	return nil
+}
+
+func validateSfcLinuxL3Entries(sfc *controller.SfcEntity) error {
+
+	for _, sfcElement := range sfc.GetElements() {
+		element := sfcElement.Container + "/" + sfcElement.PortLabel
+
+		if len(sfcElement.GetLinuxRoutes()) == 0 && sfcElement.LinuxDefaultGw == "" &&
+			len(sfcElement.GetLinuxArpEntries()) == 0 {
+			continue
+		}
+		if sfcElement.Type != controller.SfcElementType_NON_VPP_CONTAINER_AFP {
+			return fmt.Errorf("Invalid linux routes/arp entries for element: '%s', sfc: '%s', only non vpp container af_packet elements have them",
+				element, sfc.Name)
+		}
+
+		if sfcElement.LinuxDefaultGw != "" && net.ParseIP(strings.Split(sfcElement.LinuxDefaultGw, "/")[0]) == nil {
+			return fmt.Errorf("Invalid linux_default_gw: '%s' for element: '%s', sfc: '%s'",
+				sfcElement.LinuxDefaultGw, element, sfc.Name)
+		}
+		for _, route := range sfcElement.GetLinuxRoutes() {
+			dstIP, _, err := net.ParseCIDR(route.DstIpAddr)
+			if err != nil {
+				return fmt.Errorf("Invalid linux route dst_ip_addr: '%s' for element: '%s', sfc: '%s'",
+					route.DstIpAddr, element, sfc.Name)
+			}
+			if route.GwAddr == "" {
+				continue
+			}
+			gwIP := net.ParseIP(strings.Split(route.GwAddr, "/")[0])
+			if gwIP == nil || (gwIP.To4() == nil) != (dstIP.To4() == nil) {
+				return fmt.Errorf("Invalid linux route gw_addr: '%s' for dst: '%s', element: '%s', sfc: '%s'",
+					route.GwAddr, route.DstIpAddr, element, sfc.Name)
+			}
+		}
+		for _, arpEntry := range sfcElement.GetLinuxArpEntries() {
+			if net.ParseIP(arpEntry.IpAddress) == nil {
+				return fmt.Errorf("Invalid linux arp entry ip_address: '%s' for element: '%s', sfc: '%s'",
+					arpEntry.IpAddress, element, sfc.Name)
+			}
+			if _, err := net.ParseMAC(arpEntry.PhysAddress); err != nil {
+				return fmt.Errorf("Invalid linux arp entry phys_address: '%s' for element: '%s', sfc: '%s'",
+					arpEntry.PhysAddress, element, sfc.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// sfcIsBridged is whether the chain's container elements are wired into a bridge domain
+func sfcIsBridged(sfc *controller.SfcEntity) bool {
+
+	switch sfc.Type {
+	case controller.SfcType_SFC_NS_VXLAN, controller.SfcType_SFC_NS_NIC_BD, controller.SfcType_SFC_EW_BD,
+		controller.SfcType_SFC_EW_BD_L2FIB:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The consistency checker is implemented in this file.  The driver compares
+// its state cache with the id records and the agents' trees, and the keys
+// recorded as rendered for each entity are compared with the config keys
+// actually under each agent's prefix: a recorded key must exist, and a key
+// that exists must have been rendered for an entity.  Only the agents the
+// controller writes are checked, the hosts of other shards and the hosts
+// whose agent is down are skipped.
+
+package core
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/cnpdriver/l2driver"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils"
+)
+
+// ConsistencyReport is the outcome of a consistency check
+type ConsistencyReport struct {
+	Consistent      bool                     `json:"consistent"`
+	Inconsistencies []l2driver.Inconsistency `json:"inconsistencies,omitempty"`
+}
+
+// verifyConsistency cross checks the caches, the id records and the keys rendered to the agents
+func (sfcCtrlPlugin *SfcControllerPluginHandler) verifyConsistency() (*ConsistencyReport, error) {
+
+	inconsistencies, err := sfcCtrlPlugin.cnpDriverPlugin.VerifyConsistency(sfcCtrlPlugin.agentWritable)
+	if err != nil {
+		log.Errorf("verifyConsistency: driver: %s", err)
+		return nil, err
+	}
+
+	agentKeys := make(map[string]bool)
+	ki, err := sfcCtrlPlugin.db.ListKeys(utils.GetVppAgentPrefix())
+	if err != nil {
+		log.Errorf("verifyConsistency: error listing the agents' keys: %s", err)
+		return nil, err
+	}
+	for {
+		key, _, allReceived := ki.GetNext()
+		if allReceived {
+			break
+		}
+		// the keys configured by hand, or by other controllers, are not the controller's to check
+		if strings.Contains(key, "/config/") && sfcCtrlPlugin.agentWritable(utils.GetVppEtcdlabel(key)) &&
+			sfcCtrlPlugin.cnpDriverPlugin.OwnsAgentKey(key) {
+			agentKeys[key] = true
+		}
+	}
+
+	owned := make(map[string]bool)
+	err = sfcCtrlPlugin.DatastoreEntityKeysIterate(func(entity string, entityKeys *controller.EntityKeys) {
+		for _, key := range entityKeys.Keys {
+			owned[key] = true
+			if !agentKeys[key] && sfcCtrlPlugin.agentWritable(utils.GetVppEtcdlabel(key)) {
+				inconsistencies = append(inconsistencies, l2driver.Inconsistency{
+					Kind:   l2driver.InconsistencyMissingKey,
+					Key:    key,
+					Detail: "rendered for: '" + entity + "' but not in its agent's tree",
+				})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var unowned []string
+	for key := range agentKeys {
+		if !owned[key] {
+			unowned = append(unowned, key)
+		}
+	}
+	sort.Strings(unowned)
+	for _, key := range unowned {
+		inconsistencies = append(inconsistencies, l2driver.Inconsistency{
+			Kind:   l2driver.InconsistencyUnownedKey,
+			Key:    key,
+			Detail: "not rendered for any entity",
+		})
+	}
+
+	report := &ConsistencyReport{
+		Consistent:      len(inconsistencies) == 0,
+		Inconsistencies: inconsistencies,
+	}
+	if !report.Consistent {
+		log.Warnf("verifyConsistency: %d inconsistencies", len(inconsistencies))
+		sfcCtrlPlugin.emitDriftEvents(inconsistencies)
+	}
+	sfcCtrlPlugin.alarmConsistency(report)
+
+	return report, nil
+}
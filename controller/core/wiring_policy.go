@@ -0,0 +1,216 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// The policies wiring the hosts to the ees are implemented in this file.  A
+// policy names ees and a selector of the hosts wired to them, ie edge=true
+// to core-rtr-1.  An ee that no policy names is wired to every host, like
+// it is without policies.  The policies are evaluated whenever a host or ee
+// is rendered, so a host is wired to an ee once it gets a matching label.
+// When a policy changes, or a host's labels do, the agents of the hosts
+// whose wiring changed are reconciled, which also removes the wiring of the
+// hosts that no longer match.
+
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+)
+
+// validateWiringPolicy checks the policy on its own, its ees may be posted after it
+func validateWiringPolicy(wp *controller.WiringPolicy) error {
+
+	if wp.Name == "" {
+		return fmt.Errorf("Missing entity name")
+	}
+	if _, err := parseLabelSelector(wp.HostSelector); err != nil {
+		return fmt.Errorf("Invalid host_selector: '%s' for wiring policy: '%s': %s", wp.HostSelector, wp.Name, err)
+	}
+	if len(wp.ExternalEntities) == 0 {
+		return fmt.Errorf("Missing external_entities for wiring policy: '%s'", wp.Name)
+	}
+	names := make(map[string]struct{})
+	for _, eeName := range wp.ExternalEntities {
+		if eeName == "" {
+			return fmt.Errorf("Invalid external_entities for wiring policy: '%s', an ee name is empty", wp.Name)
+		}
+		if _, exists := names[eeName]; exists {
+			return fmt.Errorf("Invalid external_entities for wiring policy: '%s', ee: '%s' is listed twice",
+				wp.Name, eeName)
+		}
+		names[eeName] = struct{}{}
+	}
+
+	return nil
+}
+
+// wiringPolicyWires is whether the host is wired to the ee, it is if a policy naming the ee selects it, or
+// if no policy names the ee
+func (sfcCtrlPlugin *SfcControllerPluginHandler) wiringPolicyWires(he *controller.HostEntity, eeName string) bool {
+
+	governed := false
+	for _, wp := range sfcCtrlPlugin.ramConfigCache.WPs {
+		for _, name := range wp.ExternalEntities {
+			if name != eeName {
+				continue
+			}
+			governed = true
+			// the selector was validated when the policy was stored
+			if ls, err := parseLabelSelector(wp.HostSelector); err == nil && ls.matches(he.Labels) {
+				return true
+			}
+		}
+	}
+	return !governed
+}
+
+// wiringPolicyWiring returns the ees each host is wired to, as a comparable string
+func (sfcCtrlPlugin *SfcControllerPluginHandler) wiringPolicyWiring() map[string]string {
+
+	wiring := make(map[string]string)
+	for _, heName := range sortedKeysHE(sfcCtrlPlugin.ramConfigCache.HEs) {
+		he := sfcCtrlPlugin.ramConfigCache.HEs[heName]
+		var eeNames []string
+		for _, eeName := range sortedKeysEE(sfcCtrlPlugin.ramConfigCache.EEs) {
+			if sfcCtrlPlugin.wiringPolicyWires(&he, eeName) {
+				eeNames = append(eeNames, eeName)
+			}
+		}
+		wiring[heName] = strings.Join(eeNames, ",")
+	}
+	return wiring
+}
+
+// wiringPolicyReconcile reconciles the agents of the hosts whose wiring differs from before, ie as returned
+// by wiringPolicyWiring before the change
+func (sfcCtrlPlugin *SfcControllerPluginHandler) wiringPolicyReconcile(before map[string]string) error {
+
+	after := sfcCtrlPlugin.wiringPolicyWiring()
+
+	var changed []string
+	for heName, eeNames := range after {
+		if before[heName] != eeNames && sfcCtrlPlugin.agentWritable(heName) {
+			changed = append(changed, heName)
+		}
+	}
+	sort.Strings(changed)
+
+	for _, heName := range changed {
+		log.Infof("wiringPolicyReconcile: he: '%s' wired to ees: '%s', was: '%s'", heName, after[heName],
+			before[heName])
+		if err := sfcCtrlPlugin.ReconcileVppLabel(heName); err != nil {
+			log.Errorf("wiringPolicyReconcile: he: '%s': %s", heName, err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyWiringPolicy stores the policy, or removes it if wp is nil, and rewires the hosts it changes
+func (sfcCtrlPlugin *SfcControllerPluginHandler) applyWiringPolicy(name string, wp *controller.WiringPolicy) error {
+
+	before := sfcCtrlPlugin.wiringPolicyWiring()
+
+	if wp == nil {
+		if err := sfcCtrlPlugin.DatastoreWiringPolicyDelete(name); err != nil {
+			return err
+		}
+		delete(sfcCtrlPlugin.ramConfigCache.WPs, name)
+	} else {
+		if err := sfcCtrlPlugin.DatastoreWiringPolicyCreate(wp); err != nil {
+			return err
+		}
+		sfcCtrlPlugin.ramConfigCache.WPs[name] = *wp
+	}
+
+	return sfcCtrlPlugin.wiringPolicyReconcile(before)
+}
+
+// DatastoreWiringPolicyCreate creates the specified entity in the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreWiringPolicyCreate(wp *controller.WiringPolicy) error {
+
+	name := controller.WiringPolicyNameKey(wp.Name)
+
+	log.Infof("DatastoreWiringPolicyCreate: setting key: '%s'", name)
+
+	if err := sfcCtrlPlugin.db.Put(name, wp); err != nil {
+		log.Errorf("DatastoreWiringPolicyCreate: error storing key: '%s': %s", name, err)
+		return err
+	}
+
+	return nil
+}
+
+// DatastoreWiringPolicyDelete removes the specified entity from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreWiringPolicyDelete(name string) error {
+
+	key := controller.WiringPolicyNameKey(name)
+
+	log.Infof("DatastoreWiringPolicyDelete: deleting key: '%s'", key)
+
+	if _, err := sfcCtrlPlugin.db.Delete(key); err != nil {
+		log.Errorf("DatastoreWiringPolicyDelete: error deleting key: '%s': %s", key, err)
+		return err
+	}
+
+	return nil
+}
+
+// DatastoreWiringPolicyRetrieveAllIntoRAMCache pulls the specified entities from the sfc db in etcd into the
+// sfc ram cache
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreWiringPolicyRetrieveAllIntoRAMCache() error {
+
+	return sfcCtrlPlugin.DatastoreWiringPolicyIterate(func(key string, wp *controller.WiringPolicy) {
+		sfcCtrlPlugin.ramConfigCache.WPs[key] = *wp
+		log.Infof("DatastoreWiringPolicyRetrieveAllIntoRAMCache: adding wiring policy: '%s'", key)
+	})
+}
+
+// DatastoreWiringPolicyDeleteAll removes the specified entities from the sfc db in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreWiringPolicyDeleteAll() error {
+
+	return sfcCtrlPlugin.DatastoreWiringPolicyIterate(func(name string, wp *controller.WiringPolicy) {
+		key := controller.WiringPolicyNameKey(name)
+		log.Infof("DatastoreWiringPolicyDeleteAll: deleting wiring policy: '%s'", key)
+		sfcCtrlPlugin.db.Delete(key)
+	})
+}
+
+// DatastoreWiringPolicyIterate iterates over the set of specified entities in the sfc tree in etcd
+func (sfcCtrlPlugin *SfcControllerPluginHandler) DatastoreWiringPolicyIterate(actionFunc func(key string,
+	wp *controller.WiringPolicy)) error {
+
+	kvi, err := sfcCtrlPlugin.db.ListValues(controller.WiringPolicyKeyPrefix())
+	if err != nil {
+		log.Errorf("DatastoreWiringPolicyIterate: error listing: %s", err)
+		return err
+	}
+
+	for {
+		kv, allReceived := kvi.GetNext()
+		if allReceived {
+			return nil
+		}
+		wp := &controller.WiringPolicy{}
+		if err := kv.GetValue(wp); err != nil {
+			log.Errorf("DatastoreWiringPolicyIterate: error decoding '%s': %s", kv.GetKey(), err)
+			return err
+		}
+		actionFunc(wp.Name, wp)
+	}
+}
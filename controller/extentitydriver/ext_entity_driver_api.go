@@ -20,9 +20,10 @@ import (
 	"strings"
 	"time"
 
-	"github.com/ligato/cn-infra/logging/logrus"
 	"github.com/ligato/sfc-controller/controller/model/controller"
 	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/controller/utils/faults"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
 
 	"net"
@@ -62,17 +63,24 @@ var eeConfigCache map[string]*eeConfig // map of external entity configurations
 // EEOperationChannel is channel for external entity operations
 var EEOperationChannel = make(chan *EEOperation, 100)
 
-var log = logrus.DefaultLogger()
+var log = logs.Logger(logs.EEDriver)
 
 // SfcExternalEntityDriverInit starts process for EEOperationChannel
 func SfcExternalEntityDriverInit() {
-	go processEEOperationChannel()
+	// the plugin's Close closes the channel, so each init needs a new one for the plugin to be restartable
+	EEOperationChannel = make(chan *EEOperation, 100)
+	go processEEOperationChannel(EEOperationChannel)
 }
 
 // SfcCtlrL2WireExternalEntityToHostEntity (called from the sfcctlr l2 driver) configures the bridge, vxlan tunnel, and static route
 func SfcCtlrL2WireExternalEntityToHostEntity(ee controller.ExternalEntity, he controller.HostEntity,
 	vni uint32, sr *l3.StaticRoutes_Route) error {
 
+	if err := faults.EECall("wire ee " + ee.Name + " to he " + he.Name); err != nil {
+		log.Error("SfcCtlrL2WireExternalEntityToHostEntity: ", err)
+		return err
+	}
+
 	switch ee.EeDriverType {
 	case controller.ExtEntDriverType_EE_DRIVER_TYPE_IOSXE_SSH:
 
@@ -88,6 +96,11 @@ func SfcCtlrL2WireExternalEntityToHostEntity(ee controller.ExternalEntity, he co
 
 		return nil
 
+	case controller.ExtEntDriverType_EE_DRIVER_TYPE_SFC_CONTROLLER:
+		// the peer controller wires its own site's end of the tunnel
+		log.Infof("SfcCtlrL2WireExternalEntityToHostEntity: federation peer: %s wires its end to he: %s, vni: %d",
+			ee.Name, he.Name, vni)
+
 	default:
 		log.Infof("SfcCtlrL2WireExternalEntityToHostEntity: NO Driver configured: ee: %s, he: %s, vni: %d, static route: %s",
 			ee.Name, he.Name, vni, sr.String())
@@ -98,6 +111,11 @@ func SfcCtlrL2WireExternalEntityToHostEntity(ee controller.ExternalEntity, he co
 // SfcCtlrL2WireExternalEntityInternals (called from the sfcctlr l2 driver) configures basic entities in prep for connecting to all hosts
 func SfcCtlrL2WireExternalEntityInternals(ee controller.ExternalEntity) error {
 
+	if err := faults.EECall("wire ee " + ee.Name + " internals"); err != nil {
+		log.Error("SfcCtlrL2WireExternalEntityInternals: ", err)
+		return err
+	}
+
 	switch ee.EeDriverType {
 	case controller.ExtEntDriverType_EE_DRIVER_TYPE_IOSXE_SSH:
 
@@ -110,14 +128,17 @@ func SfcCtlrL2WireExternalEntityInternals(ee controller.ExternalEntity) error {
 
 		return nil
 
+	case controller.ExtEntDriverType_EE_DRIVER_TYPE_SFC_CONTROLLER:
+		log.Infof("SfcCtlrL2WireExternalEntityInternals: federation peer: %s wires its own site", ee.Name)
+
 	default:
 		log.Infof("SfcCtlrL2WireExternalEntityInternals: NO Driver configured: ee: %s", ee.Name)
 	}
 	return nil
 }
 
-func processEEOperationChannel() {
-	for eeOp := range EEOperationChannel {
+func processEEOperationChannel(eeOps chan *EEOperation) {
+	for eeOp := range eeOps {
 
 		if eeOp.ee.MgmntIpAddress == "0.0.0.0" || eeOp.ee.MgmntIpAddress == "" {
 			log.Warn("Skipping EE Operation with null management IP address.")
@@ -0,0 +1,195 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package gnmi
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+// testState is a state tree the test changes between the samples
+type testState struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func (ts *testState) set(path string, value string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if value == "" {
+		delete(ts.values, path)
+	} else {
+		ts.values[path] = value
+	}
+}
+
+func (ts *testState) state() ([]*gnmipb.Update, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	var updates []*gnmipb.Update
+	for path, value := range ts.values {
+		p, err := ParsePath(path)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, &gnmipb.Update{Path: p, Val: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_JsonIetfVal{JsonIetfVal: []byte(value)},
+		}})
+	}
+	return updates, nil
+}
+
+func startServer(t *testing.T, ts *testState) (*Server, gnmipb.GNMI_SubscribeClient, func()) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer(ts.state, 50*time.Millisecond)
+	go s.Serve(l)
+
+	conn, err := grpc.Dial(l.Addr().String(), grpc.WithInsecure(), grpc.WithBlock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := gnmipb.NewGNMIClient(conn).Subscribe(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, stream, func() {
+		cancel()
+		conn.Close()
+		s.Stop()
+	}
+}
+
+func mustParsePath(t *testing.T, s string) *gnmipb.Path {
+	p, err := ParsePath(s)
+	if err != nil {
+		t.Fatalf("ParsePath: '%s': %s", s, err)
+	}
+	return p
+}
+
+func recv(t *testing.T, stream gnmipb.GNMI_SubscribeClient) *gnmipb.SubscribeResponse {
+	resp, err := stream.Recv()
+	if err != nil {
+		t.Fatalf("Recv: %s", err)
+	}
+	return resp
+}
+
+func updatePaths(n *gnmipb.Notification) []string {
+	var paths []string
+	for _, update := range n.Update {
+		paths = append(paths, PathString(update.Path))
+	}
+	return paths
+}
+
+func TestSubscribeOnce(t *testing.T) {
+
+	ts := &testState{values: map[string]string{
+		"/sfc-entities/sfc-entity[name=sfc1]":                   `{"name":"sfc1"}`,
+		"/sfc-entities/sfc-entity[name=sfc2]":                   `{"name":"sfc2"}`,
+		"/render-statuses/render-status[kind=SFC][name=sfc1]":   `{"state":"RENDERED"}`,
+		"/render-statuses/render-status[kind=HE][name=vswitch]": `{"state":"RENDERED"}`,
+	}}
+	_, stream, stop := startServer(t, ts)
+	defer stop()
+
+	err := stream.Send(&gnmipb.SubscribeRequest{Request: &gnmipb.SubscribeRequest_Subscribe{
+		Subscribe: &gnmipb.SubscriptionList{
+			Mode:     gnmipb.SubscriptionList_ONCE,
+			Encoding: gnmipb.Encoding_JSON,
+			Subscription: []*gnmipb.Subscription{
+				{Path: mustParsePath(t, "/render-statuses/render-status[kind=SFC][name=*]")},
+				{Path: mustParsePath(t, "/sfc-entities/sfc-entity[name=sfc2]")},
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Send: %s", err)
+	}
+
+	resp := recv(t, stream)
+	paths := updatePaths(resp.GetUpdate())
+	if len(paths) != 2 || paths[0] != "/render-statuses/render-status[kind=SFC][name=sfc1]" ||
+		paths[1] != "/sfc-entities/sfc-entity[name=sfc2]" {
+		t.Errorf("unexpected paths: %v", paths)
+	}
+	if val := resp.GetUpdate().Update[1].Val; string(val.GetJsonVal()) != `{"name":"sfc2"}` {
+		t.Errorf("the JSON encoding was not used: %+v", val)
+	}
+	if !recv(t, stream).GetSyncResponse() {
+		t.Error("expected the sync response after the initial values")
+	}
+}
+
+func TestSubscribeStreamOnChange(t *testing.T) {
+
+	ts := &testState{values: map[string]string{
+		"/host-entities/host-entity[name=h1]": `{"name":"h1"}`,
+		"/host-entities/host-entity[name=h2]": `{"name":"h2"}`,
+	}}
+	_, stream, stop := startServer(t, ts)
+	defer stop()
+
+	err := stream.Send(&gnmipb.SubscribeRequest{Request: &gnmipb.SubscribeRequest_Subscribe{
+		Subscribe: &gnmipb.SubscriptionList{
+			Mode:     gnmipb.SubscriptionList_STREAM,
+			Encoding: gnmipb.Encoding_JSON_IETF,
+			Prefix:   mustParsePath(t, "/host-entities"),
+			Subscription: []*gnmipb.Subscription{
+				{Path: mustParsePath(t, "/host-entity"), Mode: gnmipb.SubscriptionMode_ON_CHANGE},
+			},
+		},
+	}})
+	if err != nil {
+		t.Fatalf("Send: %s", err)
+	}
+	if paths := updatePaths(recv(t, stream).GetUpdate()); len(paths) != 2 {
+		t.Fatalf("unexpected initial paths: %v", paths)
+	}
+	if !recv(t, stream).GetSyncResponse() {
+		t.Fatal("expected the sync response")
+	}
+
+	ts.set("/host-entities/host-entity[name=h1]", `{"name":"h1","eth_if_name":"G0"}`)
+	ts.set("/host-entities/host-entity[name=h2]", "")
+
+	n := recv(t, stream).GetUpdate()
+	if paths := updatePaths(n); len(paths) != 1 || paths[0] != "/host-entities/host-entity[name=h1]" {
+		t.Errorf("unexpected changed paths: %v", paths)
+	}
+	if len(n.Delete) != 1 || PathString(n.Delete[0]) != "/host-entities/host-entity[name=h2]" {
+		t.Errorf("unexpected deleted paths: %v", n.Delete)
+	}
+}
+
+func TestParsePathInvalid(t *testing.T) {
+	for _, s := range []string{"/a[]", "/a[=v]", "/a//b"} {
+		if _, err := ParsePath(s); err == nil {
+			t.Errorf("path: '%s' should be invalid", s)
+		}
+	}
+}
@@ -0,0 +1,399 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package gnmi serves the state of the controller over the Subscribe rpc
+// of gNMI, for the network management systems that collect their telemetry
+// with gNMI.  The state is a tree of json values, ie the spec of each
+// entity at /sfc-entities/sfc-entity[name=sfc1], which the controller
+// returns on demand; the server reads it for each poll, and every sample
+// interval for the streamed subscriptions.  The ONCE, POLL and STREAM
+// subscription lists are served, a streamed subscription in ON_CHANGE or
+// TARGET_DEFINED mode is sent the values that changed, and the deleted
+// paths, since the previous sample, one in SAMPLE mode all its values.
+// Paths are matched by prefix, an element named '*', or a key valued '*',
+// matches any.  The messages and the service are the code generated from the
+// gnmi.proto of openconfig, vendored with the code.
+package gnmi
+
+import (
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
+	gnmipb "github.com/openconfig/gnmi/proto/gnmi"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultSampleInterval is how often the state is sampled for the streamed subscriptions without an interval
+const DefaultSampleInterval = 10 * time.Second
+
+// minSampleInterval bounds the sample interval a client asks for
+const minSampleInterval = 100 * time.Millisecond
+
+var log = logs.Logger(logs.Core)
+
+// StateFunc returns the values of the controller's state tree, json encoded in JsonIetfVal
+type StateFunc func() ([]*gnmipb.Update, error)
+
+// Server is a gNMI server of the controller's state
+type Server struct {
+	state          StateFunc
+	sampleInterval time.Duration
+	grpcServer     *grpc.Server
+}
+
+// NewServer returns a server of the state, the streamed subscriptions without an interval are sampled every
+// sampleInterval
+func NewServer(state StateFunc, sampleInterval time.Duration) *Server {
+
+	if sampleInterval <= 0 {
+		sampleInterval = DefaultSampleInterval
+	}
+	s := &Server{
+		state:          state,
+		sampleInterval: sampleInterval,
+		grpcServer:     grpc.NewServer(),
+	}
+	gnmipb.RegisterGNMIServer(s.grpcServer, s)
+	return s
+}
+
+// Serve accepts the gNMI connections on the listener until the server is stopped
+func (s *Server) Serve(l net.Listener) error {
+	return s.grpcServer.Serve(l)
+}
+
+// Stop closes the listeners and the connections
+func (s *Server) Stop() {
+	s.grpcServer.Stop()
+}
+
+// Capabilities is not implemented
+func (s *Server) Capabilities(context.Context, *gnmipb.CapabilityRequest) (*gnmipb.CapabilityResponse, error) {
+	return nil, grpc.Errorf(codes.Unimplemented, "only Subscribe is implemented")
+}
+
+// Get is not implemented
+func (s *Server) Get(context.Context, *gnmipb.GetRequest) (*gnmipb.GetResponse, error) {
+	return nil, grpc.Errorf(codes.Unimplemented, "only Subscribe is implemented")
+}
+
+// Set is not implemented, the state is read only
+func (s *Server) Set(context.Context, *gnmipb.SetRequest) (*gnmipb.SetResponse, error) {
+	return nil, grpc.Errorf(codes.Unimplemented, "only Subscribe is implemented")
+}
+
+// subscription is a subscription of a list, with the values it was last sent
+type subscription struct {
+	path   *gnmipb.Path
+	sample bool
+	sent   map[string]*gnmipb.Update
+}
+
+// Subscribe serves a Subscribe rpc, the first request of the client must be its subscription list
+func (s *Server) Subscribe(stream gnmipb.GNMI_SubscribeServer) error {
+
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	sl := req.GetSubscribe()
+	if sl == nil {
+		return grpc.Errorf(codes.InvalidArgument, "the first request must be a subscription list")
+	}
+	if sl.Encoding != gnmipb.Encoding_JSON && sl.Encoding != gnmipb.Encoding_JSON_IETF {
+		return grpc.Errorf(codes.Unimplemented, "encoding %d is not supported, only JSON and JSON_IETF", sl.Encoding)
+	}
+
+	var subs []*subscription
+	interval := time.Duration(0)
+	for _, sub := range sl.Subscription {
+		subs = append(subs, &subscription{
+			path:   joinPaths(sl.Prefix, sub.Path),
+			sample: sub.Mode == gnmipb.SubscriptionMode_SAMPLE,
+		})
+		if sub.Mode == gnmipb.SubscriptionMode_SAMPLE && sub.SampleInterval != 0 {
+			subInterval := time.Duration(sub.SampleInterval)
+			if subInterval < minSampleInterval {
+				subInterval = minSampleInterval
+			}
+			if interval == 0 || subInterval < interval {
+				interval = subInterval
+			}
+		}
+	}
+	if len(subs) == 0 {
+		subs = append(subs, &subscription{path: joinPaths(sl.Prefix, nil)})
+	}
+	if interval == 0 {
+		interval = s.sampleInterval
+	}
+
+	// the initial values, then the end of them
+	notification, err := s.sample(subs, sl.Encoding, true)
+	if err != nil {
+		return err
+	}
+	if !sl.UpdatesOnly && len(notification.Update) != 0 {
+		if err := sendUpdate(stream, notification); err != nil {
+			return err
+		}
+	}
+	if err := sendSync(stream); err != nil {
+		return err
+	}
+
+	switch sl.Mode {
+	case gnmipb.SubscriptionList_ONCE:
+		return nil
+	case gnmipb.SubscriptionList_POLL:
+		return s.poll(stream, subs, sl.Encoding)
+	default:
+		return s.stream(stream, subs, sl.Encoding, interval)
+	}
+}
+
+func sendUpdate(stream gnmipb.GNMI_SubscribeServer, notification *gnmipb.Notification) error {
+	return stream.Send(&gnmipb.SubscribeResponse{
+		Response: &gnmipb.SubscribeResponse_Update{Update: notification},
+	})
+}
+
+func sendSync(stream gnmipb.GNMI_SubscribeServer) error {
+	return stream.Send(&gnmipb.SubscribeResponse{
+		Response: &gnmipb.SubscribeResponse_SyncResponse{SyncResponse: true},
+	})
+}
+
+// poll sends all the values of the subscriptions for each poll of the client
+func (s *Server) poll(stream gnmipb.GNMI_SubscribeServer, subs []*subscription, encoding gnmipb.Encoding) error {
+	for {
+		req, err := stream.Recv()
+		if err != nil {
+			return nil // the client is done
+		}
+		if req.GetPoll() == nil {
+			return grpc.Errorf(codes.InvalidArgument, "a POLL subscription only accepts polls")
+		}
+		notification, err := s.sample(subs, encoding, true)
+		if err != nil {
+			return err
+		}
+		if err := sendUpdate(stream, notification); err != nil {
+			return err
+		}
+		if err := sendSync(stream); err != nil {
+			return err
+		}
+	}
+}
+
+// stream samples the state every interval until the client goes away
+func (s *Server) stream(stream gnmipb.GNMI_SubscribeServer, subs []*subscription, encoding gnmipb.Encoding,
+	interval time.Duration) error {
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+		notification, err := s.sample(subs, encoding, false)
+		if err != nil {
+			return err
+		}
+		if len(notification.Update) == 0 && len(notification.Delete) == 0 {
+			continue
+		}
+		if err := sendUpdate(stream, notification); err != nil {
+			return err
+		}
+	}
+}
+
+// sample reads the state and returns the notification of the subscriptions: all their values when all is
+// set or for the SAMPLE subscriptions, else the values that changed and the paths deleted since the last
+// sample
+func (s *Server) sample(subs []*subscription, encoding gnmipb.Encoding, all bool) (*gnmipb.Notification,
+	error) {
+
+	updates, err := s.state()
+	if err != nil {
+		log.Errorf("gnmi: error reading the state: %s", err)
+		return nil, grpc.Errorf(codes.Internal, "error reading the state: %s", err)
+	}
+
+	notification := &gnmipb.Notification{Timestamp: time.Now().UnixNano()}
+	sent := make(map[string]bool) // the paths of overlapping subscriptions are sent once
+	for _, sub := range subs {
+		current := make(map[string]*gnmipb.Update)
+		for _, update := range updates {
+			if pathMatches(sub.path, update.Path) {
+				current[PathString(update.Path)] = update
+			}
+		}
+
+		for _, p := range sortedPaths(current) {
+			update := current[p]
+			if !all && !sub.sample {
+				if prev, exists := sub.sent[p]; exists && proto.Equal(prev.Val, update.Val) {
+					continue
+				}
+			}
+			if !sent[p] {
+				sent[p] = true
+				notification.Update = append(notification.Update, encode(update, encoding))
+			}
+		}
+		if !all && !sub.sample {
+			for _, p := range sortedPaths(sub.sent) {
+				if _, exists := current[p]; !exists && !sent[p] {
+					sent[p] = true
+					notification.Delete = append(notification.Delete, sub.sent[p].Path)
+				}
+			}
+		}
+		sub.sent = current
+	}
+
+	return notification, nil
+}
+
+// encode returns the update with its json value in the field of the encoding
+func encode(update *gnmipb.Update, encoding gnmipb.Encoding) *gnmipb.Update {
+	if encoding == gnmipb.Encoding_JSON && update.Val.GetJsonIetfVal() != nil {
+		return &gnmipb.Update{Path: update.Path, Val: &gnmipb.TypedValue{
+			Value: &gnmipb.TypedValue_JsonVal{JsonVal: update.Val.GetJsonIetfVal()},
+		}}
+	}
+	return update
+}
+
+func sortedPaths(m map[string]*gnmipb.Update) []string {
+	paths := make([]string, 0, len(m))
+	for p := range m {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// joinPaths returns the path of a subscription under the prefix of its list
+func joinPaths(prefix *gnmipb.Path, path *gnmipb.Path) *gnmipb.Path {
+	joined := &gnmipb.Path{}
+	if prefix != nil {
+		joined.Elem = append(joined.Elem, prefix.Elem...)
+	}
+	if path != nil {
+		joined.Elem = append(joined.Elem, path.Elem...)
+	}
+	return joined
+}
+
+// pathMatches tells whether the path is the subscribed path or under it
+func pathMatches(subscribed *gnmipb.Path, path *gnmipb.Path) bool {
+
+	if len(path.Elem) < len(subscribed.Elem) {
+		return false
+	}
+	for i, subElem := range subscribed.Elem {
+		elem := path.Elem[i]
+		if subElem.Name != "*" && subElem.Name != elem.Name {
+			return false
+		}
+		for k, v := range subElem.Key {
+			if v != "*" && elem.Key[k] != v {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// PathString returns the path in its string form, the keys of each element in order:
+// /sfc-entities/sfc-entity[name=sfc1]
+func PathString(p *gnmipb.Path) string {
+
+	s := ""
+	for _, elem := range p.Elem {
+		s += "/" + elem.Name
+		keys := make([]string, 0, len(elem.Key))
+		for k := range elem.Key {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			s += "[" + k + "=" + elem.Key[k] + "]"
+		}
+	}
+	if s == "" {
+		return "/"
+	}
+	return s
+}
+
+// ParsePath parses the string form of a path, the key values must not contain a ']'
+func ParsePath(s string) (*gnmipb.Path, error) {
+
+	p := &gnmipb.Path{}
+	s = strings.TrimPrefix(s, "/")
+	for s != "" {
+		// the element ends at the first '/' outside of the keys
+		end, depth := len(s), 0
+		for i, c := range s {
+			if c == '[' {
+				depth++
+			} else if c == ']' {
+				depth--
+			} else if c == '/' && depth == 0 {
+				end = i
+				break
+			}
+		}
+		elemStr := s[:end]
+		s = strings.TrimPrefix(s[end:], "/")
+
+		elem := &gnmipb.PathElem{}
+		i := strings.Index(elemStr, "[")
+		if i < 0 {
+			elem.Name = elemStr
+		} else {
+			elem.Name = elemStr[:i]
+			for _, kv := range strings.Split(strings.TrimSuffix(elemStr[i+1:], "]"), "][") {
+				pair := strings.SplitN(kv, "=", 2)
+				if len(pair) != 2 || pair[0] == "" {
+					return nil, grpc.Errorf(codes.InvalidArgument, "invalid key: '%s' of path element: '%s'",
+						kv, elemStr)
+				}
+				if elem.Key == nil {
+					elem.Key = make(map[string]string)
+				}
+				elem.Key[pair[0]] = pair[1]
+			}
+		}
+		if elem.Name == "" {
+			return nil, grpc.Errorf(codes.InvalidArgument, "empty path element in: '%s'", elemStr)
+		}
+		p.Elem = append(p.Elem, elem)
+	}
+	return p, nil
+}
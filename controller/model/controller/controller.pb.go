@@ -10,13 +10,29 @@ It is generated from these files:
 
 It has these top-level messages:
 	BDParms
+	PortParms
+	VxlanParms
 	SystemParameters
 	ExternalEntity
+	ResourceLimits
 	HostEntity
 	CustomInfoType
 	L3VRFRoute
 	L3ArpEntry
+	LinuxRoute
+	SteeringRule
 	SfcEntity
+	NetworkService
+	SfcTemplate
+	SfcTemplateInstantiation
+	SfcMigration
+	WiringPolicy
+	ConfigVersion
+	EntityStatus
+	RenderRetry
+	EntityKeys
+	EntityChange
+	ScheduledChange
 */
 package controller
 
@@ -51,23 +67,66 @@ func (x RxModeType) String() string {
 type ExtEntDriverType int32
 
 const (
-	ExtEntDriverType_EE_DRIVER_TYPE_UNKNOWN   ExtEntDriverType = 0
-	ExtEntDriverType_EE_DRIVER_TYPE_IOSXE_SSH ExtEntDriverType = 1
+	ExtEntDriverType_EE_DRIVER_TYPE_UNKNOWN        ExtEntDriverType = 0
+	ExtEntDriverType_EE_DRIVER_TYPE_IOSXE_SSH      ExtEntDriverType = 1
+	ExtEntDriverType_EE_DRIVER_TYPE_SFC_CONTROLLER ExtEntDriverType = 2
 )
 
 var ExtEntDriverType_name = map[int32]string{
 	0: "EE_DRIVER_TYPE_UNKNOWN",
 	1: "EE_DRIVER_TYPE_IOSXE_SSH",
+	2: "EE_DRIVER_TYPE_SFC_CONTROLLER",
 }
 var ExtEntDriverType_value = map[string]int32{
-	"EE_DRIVER_TYPE_UNKNOWN":   0,
-	"EE_DRIVER_TYPE_IOSXE_SSH": 1,
+	"EE_DRIVER_TYPE_UNKNOWN":        0,
+	"EE_DRIVER_TYPE_IOSXE_SSH":      1,
+	"EE_DRIVER_TYPE_SFC_CONTROLLER": 2,
 }
 
 func (x ExtEntDriverType) String() string {
 	return proto.EnumName(ExtEntDriverType_name, int32(x))
 }
 
+type OverlayTopologyType int32
+
+const (
+	OverlayTopologyType_OVERLAY_FULL_MESH OverlayTopologyType = 0
+	OverlayTopologyType_OVERLAY_GATEWAY   OverlayTopologyType = 1
+)
+
+var OverlayTopologyType_name = map[int32]string{
+	0: "OVERLAY_FULL_MESH",
+	1: "OVERLAY_GATEWAY",
+}
+var OverlayTopologyType_value = map[string]int32{
+	"OVERLAY_FULL_MESH": 0,
+	"OVERLAY_GATEWAY":   1,
+}
+
+func (x OverlayTopologyType) String() string {
+	return proto.EnumName(OverlayTopologyType_name, int32(x))
+}
+
+type HostType int32
+
+const (
+	HostType_HOST_VPP    HostType = 0
+	HostType_HOST_KERNEL HostType = 1
+)
+
+var HostType_name = map[int32]string{
+	0: "HOST_VPP",
+	1: "HOST_KERNEL",
+}
+var HostType_value = map[string]int32{
+	"HOST_VPP":    0,
+	"HOST_KERNEL": 1,
+}
+
+func (x HostType) String() string {
+	return proto.EnumName(HostType_name, int32(x))
+}
+
 type SfcType int32
 
 const (
@@ -150,6 +209,38 @@ func (x SfcElementType) String() string {
 	return proto.EnumName(SfcElementType_name, int32(x))
 }
 
+type RenderStateType int32
+
+const (
+	RenderStateType_RENDER_STATE_UNKNOWN RenderStateType = 0
+	RenderStateType_RENDERED             RenderStateType = 1
+	RenderStateType_PARTIALLY_RENDERED   RenderStateType = 2
+	RenderStateType_RENDER_ERROR         RenderStateType = 3
+	RenderStateType_DEPENDENCY_PENDING   RenderStateType = 4
+	RenderStateType_AGENT_PENDING        RenderStateType = 5
+)
+
+var RenderStateType_name = map[int32]string{
+	0: "RENDER_STATE_UNKNOWN",
+	1: "RENDERED",
+	2: "PARTIALLY_RENDERED",
+	3: "RENDER_ERROR",
+	4: "DEPENDENCY_PENDING",
+	5: "AGENT_PENDING",
+}
+var RenderStateType_value = map[string]int32{
+	"RENDER_STATE_UNKNOWN": 0,
+	"RENDERED":             1,
+	"PARTIALLY_RENDERED":   2,
+	"RENDER_ERROR":         3,
+	"DEPENDENCY_PENDING":   4,
+	"AGENT_PENDING":        5,
+}
+
+func (x RenderStateType) String() string {
+	return proto.EnumName(RenderStateType_name, int32(x))
+}
+
 type BDParms struct {
 	Flood               bool   `protobuf:"varint,1,opt,name=flood,proto3" json:"flood,omitempty"`
 	UnknownUnicastFlood bool   `protobuf:"varint,2,opt,name=unknown_unicast_flood,proto3" json:"unknown_unicast_flood,omitempty"`
@@ -163,13 +254,58 @@ func (m *BDParms) Reset()         { *m = BDParms{} }
 func (m *BDParms) String() string { return proto.CompactTextString(m) }
 func (*BDParms) ProtoMessage()    {}
 
+type PortParms struct {
+	StaticLearningOnly bool `protobuf:"varint,2,opt,name=static_learning_only,proto3" json:"static_learning_only,omitempty"`
+	NoIp4Forward       bool `protobuf:"varint,3,opt,name=no_ip4_forward,proto3" json:"no_ip4_forward,omitempty"`
+	NoIp6Forward       bool `protobuf:"varint,4,opt,name=no_ip6_forward,proto3" json:"no_ip6_forward,omitempty"`
+}
+
+func (m *PortParms) Reset()         { *m = PortParms{} }
+func (m *PortParms) String() string { return proto.CompactTextString(m) }
+func (*PortParms) ProtoMessage()    {}
+
+type VxlanParms struct {
+	DstPort uint32 `protobuf:"varint,1,opt,name=dst_port,proto3" json:"dst_port,omitempty"`
+	Tos     uint32 `protobuf:"varint,2,opt,name=tos,proto3" json:"tos,omitempty"`
+	Ttl     uint32 `protobuf:"varint,3,opt,name=ttl,proto3" json:"ttl,omitempty"`
+}
+
+func (m *VxlanParms) Reset()         { *m = VxlanParms{} }
+func (m *VxlanParms) String() string { return proto.CompactTextString(m) }
+func (*VxlanParms) ProtoMessage()    {}
+
 type SystemParameters struct {
-	Mtu                          uint32   `protobuf:"varint,1,opt,name=mtu,proto3" json:"mtu,omitempty"`
-	StartingVlanId               uint32   `protobuf:"varint,2,opt,name=starting_vlan_id,proto3" json:"starting_vlan_id,omitempty"`
-	DefaultStaticRouteWeight     uint32   `protobuf:"varint,3,opt,name=default_static_route_weight,proto3" json:"default_static_route_weight,omitempty"`
-	DefaultStaticRoutePreference uint32   `protobuf:"varint,4,opt,name=default_static_route_preference,proto3" json:"default_static_route_preference,omitempty"`
-	DynamicBridgeParms           *BDParms `protobuf:"bytes,5,opt,name=dynamic_bridge_parms" json:"dynamic_bridge_parms,omitempty"`
-	StaticBridgeParms            *BDParms `protobuf:"bytes,6,opt,name=static_bridge_parms" json:"static_bridge_parms,omitempty"`
+	Mtu                          uint32              `protobuf:"varint,1,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	StartingVlanId               uint32              `protobuf:"varint,2,opt,name=starting_vlan_id,proto3" json:"starting_vlan_id,omitempty"`
+	DefaultStaticRouteWeight     uint32              `protobuf:"varint,3,opt,name=default_static_route_weight,proto3" json:"default_static_route_weight,omitempty"`
+	DefaultStaticRoutePreference uint32              `protobuf:"varint,4,opt,name=default_static_route_preference,proto3" json:"default_static_route_preference,omitempty"`
+	DynamicBridgeParms           *BDParms            `protobuf:"bytes,5,opt,name=dynamic_bridge_parms" json:"dynamic_bridge_parms,omitempty"`
+	StaticBridgeParms            *BDParms            `protobuf:"bytes,6,opt,name=static_bridge_parms" json:"static_bridge_parms,omitempty"`
+	VxlanParms                   *VxlanParms         `protobuf:"bytes,8,opt,name=vxlan_parms" json:"vxlan_parms,omitempty"`
+	OverlayTopology              OverlayTopologyType `protobuf:"varint,9,opt,name=overlay_topology,proto3,enum=controller.OverlayTopologyType" json:"overlay_topology,omitempty"`
+	ConfigVersionsRetained       uint32              `protobuf:"varint,10,opt,name=config_versions_retained,proto3" json:"config_versions_retained,omitempty"`
+	BlueGreenAcceptTimeout       uint32              `protobuf:"varint,11,opt,name=blue_green_accept_timeout,proto3" json:"blue_green_accept_timeout,omitempty"`
+	AgentApi                     string              `protobuf:"bytes,12,opt,name=agent_api,proto3" json:"agent_api,omitempty"`
+	CanaryHost                   string              `protobuf:"bytes,13,opt,name=canary_host,proto3" json:"canary_host,omitempty"`
+	CanaryVerifyTimeout          uint32              `protobuf:"varint,14,opt,name=canary_verify_timeout,proto3" json:"canary_verify_timeout,omitempty"`
+	FeatureFlags                 map[string]bool     `protobuf:"bytes,15,rep,name=feature_flags" json:"feature_flags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	WaitForAgent                 bool                `protobuf:"varint,16,opt,name=wait_for_agent,proto3" json:"wait_for_agent,omitempty"`
+	AgentConfirmTimeout          uint32              `protobuf:"varint,17,opt,name=agent_confirm_timeout,proto3" json:"agent_confirm_timeout,omitempty"`
+	AgentWatchInterval           uint32              `protobuf:"varint,18,opt,name=agent_watch_interval,proto3" json:"agent_watch_interval,omitempty"`
+	ChangeHistoryRetained        uint32              `protobuf:"varint,19,opt,name=change_history_retained,proto3" json:"change_history_retained,omitempty"`
+	DeterministicIds             bool                `protobuf:"varint,20,opt,name=deterministic_ids,proto3" json:"deterministic_ids,omitempty"`
+	ShardCount                   uint32              `protobuf:"varint,21,opt,name=shard_count,proto3" json:"shard_count,omitempty"`
+	HostShards                   map[string]uint32   `protobuf:"bytes,22,rep,name=host_shards" json:"host_shards,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	RenderRetryMaxAttempts       uint32              `protobuf:"varint,23,opt,name=render_retry_max_attempts,proto3" json:"render_retry_max_attempts,omitempty"`
+	RenderRetryBackoff           uint32              `protobuf:"varint,24,opt,name=render_retry_backoff,proto3" json:"render_retry_backoff,omitempty"`
+	RenderRetryBackoffMax        uint32              `protobuf:"varint,25,opt,name=render_retry_backoff_max,proto3" json:"render_retry_backoff_max,omitempty"`
+	AgentDownTimeout             uint32              `protobuf:"varint,26,opt,name=agent_down_timeout,proto3" json:"agent_down_timeout,omitempty"`
+	BandwidthOvercommit          bool                `protobuf:"varint,27,opt,name=bandwidth_overcommit,proto3" json:"bandwidth_overcommit,omitempty"`
+	HostResourceLimits           *ResourceLimits     `protobuf:"bytes,28,opt,name=host_resource_limits" json:"host_resource_limits,omitempty"`
+	ReconcilePageSize            uint32              `protobuf:"varint,29,opt,name=reconcile_page_size,proto3" json:"reconcile_page_size,omitempty"`
+	AuditDescriptions            bool                `protobuf:"varint,30,opt,name=audit_descriptions,proto3" json:"audit_descriptions,omitempty"`
+	SfcDeleteGraceSeconds        uint32              `protobuf:"varint,31,opt,name=sfc_delete_grace_seconds,proto3" json:"sfc_delete_grace_seconds,omitempty"`
+	FederationSite               string              `protobuf:"bytes,32,opt,name=federation_site,proto3" json:"federation_site,omitempty"`
 }
 
 func (m *SystemParameters) Reset()         { *m = SystemParameters{} }
@@ -190,6 +326,34 @@ func (m *SystemParameters) GetStaticBridgeParms() *BDParms {
 	return nil
 }
 
+func (m *SystemParameters) GetVxlanParms() *VxlanParms {
+	if m != nil {
+		return m.VxlanParms
+	}
+	return nil
+}
+
+func (m *SystemParameters) GetFeatureFlags() map[string]bool {
+	if m != nil {
+		return m.FeatureFlags
+	}
+	return nil
+}
+
+func (m *SystemParameters) GetHostShards() map[string]uint32 {
+	if m != nil {
+		return m.HostShards
+	}
+	return nil
+}
+
+func (m *SystemParameters) GetHostResourceLimits() *ResourceLimits {
+	if m != nil {
+		return m.HostResourceLimits
+	}
+	return nil
+}
+
 type ExternalEntity struct {
 	Name            string                        `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	MgmntIpAddress  string                        `protobuf:"bytes,2,opt,name=mgmnt_ip_address,proto3" json:"mgmnt_ip_address,omitempty"`
@@ -200,6 +364,9 @@ type ExternalEntity struct {
 	HostInterface   *ExternalEntity_HostInterface `protobuf:"bytes,7,opt,name=host_interface" json:"host_interface,omitempty"`
 	HostVxlan       *ExternalEntity_HostVxlan     `protobuf:"bytes,8,opt,name=host_vxlan" json:"host_vxlan,omitempty"`
 	HostBd          *ExternalEntity_HostBD        `protobuf:"bytes,9,opt,name=host_bd" json:"host_bd,omitempty"`
+	FeatureFlags    map[string]bool               `protobuf:"bytes,12,rep,name=feature_flags" json:"feature_flags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Labels          map[string]string             `protobuf:"bytes,13,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Prefixes        []*ExternalEntity_Prefix      `protobuf:"bytes,14,rep,name=prefixes" json:"prefixes,omitempty"`
 }
 
 func (m *ExternalEntity) Reset()         { *m = ExternalEntity{} }
@@ -227,6 +394,27 @@ func (m *ExternalEntity) GetHostBd() *ExternalEntity_HostBD {
 	return nil
 }
 
+func (m *ExternalEntity) GetFeatureFlags() map[string]bool {
+	if m != nil {
+		return m.FeatureFlags
+	}
+	return nil
+}
+
+func (m *ExternalEntity) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *ExternalEntity) GetPrefixes() []*ExternalEntity_Prefix {
+	if m != nil {
+		return m.Prefixes
+	}
+	return nil
+}
+
 type ExternalEntity_HostInterface struct {
 	IfName   string `protobuf:"bytes,1,opt,name=if_name,proto3" json:"if_name,omitempty"`
 	Ipv4Addr string `protobuf:"bytes,2,opt,name=ipv4_addr,proto3" json:"ipv4_addr,omitempty"`
@@ -237,14 +425,23 @@ func (m *ExternalEntity_HostInterface) String() string { return proto.CompactTex
 func (*ExternalEntity_HostInterface) ProtoMessage()    {}
 
 type ExternalEntity_HostVxlan struct {
-	IfName     string `protobuf:"bytes,1,opt,name=if_name,proto3" json:"if_name,omitempty"`
-	SourceIpv4 string `protobuf:"bytes,2,opt,name=source_ipv4,proto3" json:"source_ipv4,omitempty"`
+	IfName     string      `protobuf:"bytes,1,opt,name=if_name,proto3" json:"if_name,omitempty"`
+	SourceIpv4 string      `protobuf:"bytes,2,opt,name=source_ipv4,proto3" json:"source_ipv4,omitempty"`
+	VxlanParms *VxlanParms `protobuf:"bytes,3,opt,name=vxlan_parms" json:"vxlan_parms,omitempty"`
+	Vni        uint32      `protobuf:"varint,4,opt,name=vni,proto3" json:"vni,omitempty"`
 }
 
 func (m *ExternalEntity_HostVxlan) Reset()         { *m = ExternalEntity_HostVxlan{} }
 func (m *ExternalEntity_HostVxlan) String() string { return proto.CompactTextString(m) }
 func (*ExternalEntity_HostVxlan) ProtoMessage()    {}
 
+func (m *ExternalEntity_HostVxlan) GetVxlanParms() *VxlanParms {
+	if m != nil {
+		return m.VxlanParms
+	}
+	return nil
+}
+
 type ExternalEntity_HostBD struct {
 	Id         uint32   `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
 	BdiIpv4    string   `protobuf:"bytes,2,opt,name=bdi_ipv4,proto3" json:"bdi_ipv4,omitempty"`
@@ -255,24 +452,134 @@ func (m *ExternalEntity_HostBD) Reset()         { *m = ExternalEntity_HostBD{} }
 func (m *ExternalEntity_HostBD) String() string { return proto.CompactTextString(m) }
 func (*ExternalEntity_HostBD) ProtoMessage()    {}
 
+type ExternalEntity_Prefix struct {
+	DstIpAddr  string `protobuf:"bytes,1,opt,name=dst_ip_addr,proto3" json:"dst_ip_addr,omitempty"`
+	VrfId      uint32 `protobuf:"varint,2,opt,name=vrf_id,proto3" json:"vrf_id,omitempty"`
+	Weight     uint32 `protobuf:"varint,3,opt,name=weight,proto3" json:"weight,omitempty"`
+	Preference uint32 `protobuf:"varint,4,opt,name=preference,proto3" json:"preference,omitempty"`
+}
+
+func (m *ExternalEntity_Prefix) Reset()         { *m = ExternalEntity_Prefix{} }
+func (m *ExternalEntity_Prefix) String() string { return proto.CompactTextString(m) }
+func (*ExternalEntity_Prefix) ProtoMessage()    {}
+
+type ResourceLimits struct {
+	MaxMemifs        uint32 `protobuf:"varint,1,opt,name=max_memifs,proto3" json:"max_memifs,omitempty"`
+	MaxBridgeDomains uint32 `protobuf:"varint,2,opt,name=max_bridge_domains,proto3" json:"max_bridge_domains,omitempty"`
+	MaxInterfaces    uint32 `protobuf:"varint,3,opt,name=max_interfaces,proto3" json:"max_interfaces,omitempty"`
+}
+
+func (m *ResourceLimits) Reset()         { *m = ResourceLimits{} }
+func (m *ResourceLimits) String() string { return proto.CompactTextString(m) }
+func (*ResourceLimits) ProtoMessage()    {}
+
 type HostEntity struct {
-	Name                   string     `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	EthIfName              string     `protobuf:"bytes,2,opt,name=eth_if_name,proto3" json:"eth_if_name,omitempty"`
-	EthIpv4                string     `protobuf:"bytes,3,opt,name=eth_ipv4,proto3" json:"eth_ipv4,omitempty"`
-	EthIpv6                string     `protobuf:"bytes,4,opt,name=eth_ipv6,proto3" json:"eth_ipv6,omitempty"`
-	LoopbackMacAddr        string     `protobuf:"bytes,5,opt,name=loopback_mac_addr,proto3" json:"loopback_mac_addr,omitempty"`
-	LoopbackIpv4           string     `protobuf:"bytes,6,opt,name=loopback_ipv4,proto3" json:"loopback_ipv4,omitempty"`
-	LoopbackIpv6           string     `protobuf:"bytes,7,opt,name=loopback_ipv6,proto3" json:"loopback_ipv6,omitempty"`
-	VxlanTunnelIpv4        string     `protobuf:"bytes,8,opt,name=vxlan_tunnel_ipv4,proto3" json:"vxlan_tunnel_ipv4,omitempty"`
-	CreateVxlanStaticRoute bool       `protobuf:"varint,9,opt,name=create_vxlan_static_route,proto3" json:"create_vxlan_static_route,omitempty"`
-	Mtu                    uint32     `protobuf:"varint,10,opt,name=mtu,proto3" json:"mtu,omitempty"`
-	RxMode                 RxModeType `protobuf:"varint,11,opt,name=rx_mode,proto3,enum=controller.RxModeType" json:"rx_mode,omitempty"`
+	Name                    string                   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	EthIfName               string                   `protobuf:"bytes,2,opt,name=eth_if_name,proto3" json:"eth_if_name,omitempty"`
+	EthIpv4                 string                   `protobuf:"bytes,3,opt,name=eth_ipv4,proto3" json:"eth_ipv4,omitempty"`
+	EthIpv6                 string                   `protobuf:"bytes,4,opt,name=eth_ipv6,proto3" json:"eth_ipv6,omitempty"`
+	LoopbackMacAddr         string                   `protobuf:"bytes,5,opt,name=loopback_mac_addr,proto3" json:"loopback_mac_addr,omitempty"`
+	LoopbackIpv4            string                   `protobuf:"bytes,6,opt,name=loopback_ipv4,proto3" json:"loopback_ipv4,omitempty"`
+	LoopbackIpv6            string                   `protobuf:"bytes,7,opt,name=loopback_ipv6,proto3" json:"loopback_ipv6,omitempty"`
+	VxlanTunnelIpv4         string                   `protobuf:"bytes,8,opt,name=vxlan_tunnel_ipv4,proto3" json:"vxlan_tunnel_ipv4,omitempty"`
+	CreateVxlanStaticRoute  bool                     `protobuf:"varint,9,opt,name=create_vxlan_static_route,proto3" json:"create_vxlan_static_route,omitempty"`
+	Mtu                     uint32                   `protobuf:"varint,10,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	RxMode                  RxModeType               `protobuf:"varint,11,opt,name=rx_mode,proto3,enum=controller.RxModeType" json:"rx_mode,omitempty"`
+	Uplinks                 []*HostEntity_Uplink     `protobuf:"bytes,12,rep,name=uplinks" json:"uplinks,omitempty"`
+	PeerUplinks             []*HostEntity_PeerUplink `protobuf:"bytes,13,rep,name=peer_uplinks" json:"peer_uplinks,omitempty"`
+	Gateway                 bool                     `protobuf:"varint,14,opt,name=gateway,proto3" json:"gateway,omitempty"`
+	GatewayHost             string                   `protobuf:"bytes,15,opt,name=gateway_host,proto3" json:"gateway_host,omitempty"`
+	AgentApi                string                   `protobuf:"bytes,16,opt,name=agent_api,proto3" json:"agent_api,omitempty"`
+	FeatureFlags            map[string]bool          `protobuf:"bytes,17,rep,name=feature_flags" json:"feature_flags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Loopbacks               []*HostEntity_Loopback   `protobuf:"bytes,19,rep,name=loopbacks" json:"loopbacks,omitempty"`
+	Labels                  map[string]string        `protobuf:"bytes,20,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	EthBandwidthMbps        uint32                   `protobuf:"varint,22,opt,name=eth_bandwidth_mbps,proto3" json:"eth_bandwidth_mbps,omitempty"`
+	ResourceLimits          *ResourceLimits          `protobuf:"bytes,23,opt,name=resource_limits" json:"resource_limits,omitempty"`
+	VxlanTunnelIpv6         string                   `protobuf:"bytes,24,opt,name=vxlan_tunnel_ipv6,proto3" json:"vxlan_tunnel_ipv6,omitempty"`
+	LoopbackTunnelEndpoints bool                     `protobuf:"varint,25,opt,name=loopback_tunnel_endpoints,proto3" json:"loopback_tunnel_endpoints,omitempty"`
+	HostType                HostType                 `protobuf:"varint,26,opt,name=host_type,proto3,enum=controller.HostType" json:"host_type,omitempty"`
 }
 
 func (m *HostEntity) Reset()         { *m = HostEntity{} }
 func (m *HostEntity) String() string { return proto.CompactTextString(m) }
 func (*HostEntity) ProtoMessage()    {}
 
+func (m *HostEntity) GetUplinks() []*HostEntity_Uplink {
+	if m != nil {
+		return m.Uplinks
+	}
+	return nil
+}
+
+func (m *HostEntity) GetPeerUplinks() []*HostEntity_PeerUplink {
+	if m != nil {
+		return m.PeerUplinks
+	}
+	return nil
+}
+
+func (m *HostEntity) GetFeatureFlags() map[string]bool {
+	if m != nil {
+		return m.FeatureFlags
+	}
+	return nil
+}
+
+func (m *HostEntity) GetResourceLimits() *ResourceLimits {
+	if m != nil {
+		return m.ResourceLimits
+	}
+	return nil
+}
+
+func (m *HostEntity) GetLoopbacks() []*HostEntity_Loopback {
+	if m != nil {
+		return m.Loopbacks
+	}
+	return nil
+}
+
+func (m *HostEntity) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+type HostEntity_Uplink struct {
+	EthIfName       string `protobuf:"bytes,1,opt,name=eth_if_name,proto3" json:"eth_if_name,omitempty"`
+	EthIpv4         string `protobuf:"bytes,2,opt,name=eth_ipv4,proto3" json:"eth_ipv4,omitempty"`
+	EthIpv6         string `protobuf:"bytes,3,opt,name=eth_ipv6,proto3" json:"eth_ipv6,omitempty"`
+	VxlanTunnelIpv4 string `protobuf:"bytes,4,opt,name=vxlan_tunnel_ipv4,proto3" json:"vxlan_tunnel_ipv4,omitempty"`
+	BandwidthMbps   uint32 `protobuf:"varint,6,opt,name=bandwidth_mbps,proto3" json:"bandwidth_mbps,omitempty"`
+	VxlanTunnelIpv6 string `protobuf:"bytes,7,opt,name=vxlan_tunnel_ipv6,proto3" json:"vxlan_tunnel_ipv6,omitempty"`
+}
+
+func (m *HostEntity_Uplink) Reset()         { *m = HostEntity_Uplink{} }
+func (m *HostEntity_Uplink) String() string { return proto.CompactTextString(m) }
+func (*HostEntity_Uplink) ProtoMessage()    {}
+
+type HostEntity_Loopback struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Ipv4    string `protobuf:"bytes,2,opt,name=ipv4,proto3" json:"ipv4,omitempty"`
+	Ipv6    string `protobuf:"bytes,3,opt,name=ipv6,proto3" json:"ipv6,omitempty"`
+	MacAddr string `protobuf:"bytes,4,opt,name=mac_addr,proto3" json:"mac_addr,omitempty"`
+	Anycast bool   `protobuf:"varint,5,opt,name=anycast,proto3" json:"anycast,omitempty"`
+}
+
+func (m *HostEntity_Loopback) Reset()         { *m = HostEntity_Loopback{} }
+func (m *HostEntity_Loopback) String() string { return proto.CompactTextString(m) }
+func (*HostEntity_Loopback) ProtoMessage()    {}
+
+type HostEntity_PeerUplink struct {
+	Peer      string `protobuf:"bytes,1,opt,name=peer,proto3" json:"peer,omitempty"`
+	EthIfName string `protobuf:"bytes,2,opt,name=eth_if_name,proto3" json:"eth_if_name,omitempty"`
+}
+
+func (m *HostEntity_PeerUplink) Reset()         { *m = HostEntity_PeerUplink{} }
+func (m *HostEntity_PeerUplink) String() string { return proto.CompactTextString(m) }
+func (*HostEntity_PeerUplink) ProtoMessage()    {}
+
 type CustomInfoType struct {
 	Label string `protobuf:"bytes,1,opt,name=label,proto3" json:"label,omitempty"`
 }
@@ -304,14 +611,55 @@ func (m *L3ArpEntry) Reset()         { *m = L3ArpEntry{} }
 func (m *L3ArpEntry) String() string { return proto.CompactTextString(m) }
 func (*L3ArpEntry) ProtoMessage()    {}
 
+type LinuxRoute struct {
+	DstIpAddr   string `protobuf:"bytes,1,opt,name=dst_ip_addr,proto3" json:"dst_ip_addr,omitempty"`
+	GwAddr      string `protobuf:"bytes,2,opt,name=gw_addr,proto3" json:"gw_addr,omitempty"`
+	Metric      uint32 `protobuf:"varint,3,opt,name=metric,proto3" json:"metric,omitempty"`
+	Description string `protobuf:"bytes,4,opt,name=description,proto3" json:"description,omitempty"`
+}
+
+func (m *LinuxRoute) Reset()         { *m = LinuxRoute{} }
+func (m *LinuxRoute) String() string { return proto.CompactTextString(m) }
+func (*LinuxRoute) ProtoMessage()    {}
+
+type SteeringRule struct {
+	SrcNetwork   string `protobuf:"bytes,1,opt,name=src_network,proto3" json:"src_network,omitempty"`
+	DstNetwork   string `protobuf:"bytes,2,opt,name=dst_network,proto3" json:"dst_network,omitempty"`
+	Protocol     uint32 `protobuf:"varint,3,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	DstPortLower uint32 `protobuf:"varint,4,opt,name=dst_port_lower,proto3" json:"dst_port_lower,omitempty"`
+	DstPortUpper uint32 `protobuf:"varint,5,opt,name=dst_port_upper,proto3" json:"dst_port_upper,omitempty"`
+}
+
+func (m *SteeringRule) Reset()         { *m = SteeringRule{} }
+func (m *SteeringRule) String() string { return proto.CompactTextString(m) }
+func (*SteeringRule) ProtoMessage()    {}
+
 type SfcEntity struct {
-	Name           string                  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Description    string                  `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
-	Type           SfcType                 `protobuf:"varint,3,opt,name=type,proto3,enum=controller.SfcType" json:"type,omitempty"`
-	SfcIpv4Prefix  string                  `protobuf:"bytes,4,opt,name=sfc_ipv4_prefix,proto3" json:"sfc_ipv4_prefix,omitempty"`
-	VnfRepeatCount uint32                  `protobuf:"varint,5,opt,name=vnf_repeat_count,proto3" json:"vnf_repeat_count,omitempty"`
-	BdParms        *BDParms                `protobuf:"bytes,6,opt,name=bd_parms" json:"bd_parms,omitempty"`
-	Elements       []*SfcEntity_SfcElement `protobuf:"bytes,7,rep,name=elements" json:"elements,omitempty"`
+	Name              string                                    `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description       string                                    `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Type              SfcType                                   `protobuf:"varint,3,opt,name=type,proto3,enum=controller.SfcType" json:"type,omitempty"`
+	SfcIpv4Prefix     string                                    `protobuf:"bytes,4,opt,name=sfc_ipv4_prefix,proto3" json:"sfc_ipv4_prefix,omitempty"`
+	VnfRepeatCount    uint32                                    `protobuf:"varint,5,opt,name=vnf_repeat_count,proto3" json:"vnf_repeat_count,omitempty"`
+	BdParms           *BDParms                                  `protobuf:"bytes,6,opt,name=bd_parms" json:"bd_parms,omitempty"`
+	Elements          []*SfcEntity_SfcElement                   `protobuf:"bytes,7,rep,name=elements" json:"elements,omitempty"`
+	BlueGreenCutover  bool                                      `protobuf:"varint,8,opt,name=blue_green_cutover,proto3" json:"blue_green_cutover,omitempty"`
+	FeatureFlags      map[string]bool                           `protobuf:"bytes,9,rep,name=feature_flags" json:"feature_flags,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Environments      map[string]*SfcEntity_EnvironmentOverride `protobuf:"bytes,10,rep,name=environments" json:"environments,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value"`
+	DependsOn         []string                                  `protobuf:"bytes,12,rep,name=depends_on" json:"depends_on,omitempty"`
+	Priority          int32                                     `protobuf:"varint,13,opt,name=priority,proto3" json:"priority,omitempty"`
+	NetworkService    string                                    `protobuf:"bytes,14,opt,name=network_service,proto3" json:"network_service,omitempty"`
+	Template          string                                    `protobuf:"bytes,15,opt,name=template,proto3" json:"template,omitempty"`
+	TemplateVariables map[string]string                         `protobuf:"bytes,16,rep,name=template_variables" json:"template_variables,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Labels            map[string]string                         `protobuf:"bytes,17,rep,name=labels" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	SteeringRules     []*SteeringRule                           `protobuf:"bytes,18,rep,name=steering_rules" json:"steering_rules,omitempty"`
+	CnpDriver         string                                    `protobuf:"bytes,19,opt,name=cnp_driver,proto3" json:"cnp_driver,omitempty"`
+	BandwidthMbps     uint32                                    `protobuf:"varint,20,opt,name=bandwidth_mbps,proto3" json:"bandwidth_mbps,omitempty"`
+	PlacementSelector string                                    `protobuf:"bytes,21,opt,name=placement_selector,proto3" json:"placement_selector,omitempty"`
+	SfcIpv6Prefix     string                                    `protobuf:"bytes,22,opt,name=sfc_ipv6_prefix,proto3" json:"sfc_ipv6_prefix,omitempty"`
+	TtlSeconds        uint32                                    `protobuf:"varint,25,opt,name=ttl_seconds,proto3" json:"ttl_seconds,omitempty"`
+	ExpiresAt         int64                                     `protobuf:"varint,26,opt,name=expires_at,proto3" json:"expires_at,omitempty"`
+	DeletingSince     int64                                     `protobuf:"varint,27,opt,name=deleting_since,proto3" json:"deleting_since,omitempty"`
+	DeleteAt          int64                                     `protobuf:"varint,28,opt,name=delete_at,proto3" json:"delete_at,omitempty"`
 }
 
 func (m *SfcEntity) Reset()         { *m = SfcEntity{} }
@@ -332,20 +680,67 @@ func (m *SfcEntity) GetElements() []*SfcEntity_SfcElement {
 	return nil
 }
 
+func (m *SfcEntity) GetFeatureFlags() map[string]bool {
+	if m != nil {
+		return m.FeatureFlags
+	}
+	return nil
+}
+
+func (m *SfcEntity) GetEnvironments() map[string]*SfcEntity_EnvironmentOverride {
+	if m != nil {
+		return m.Environments
+	}
+	return nil
+}
+
+func (m *SfcEntity) GetTemplateVariables() map[string]string {
+	if m != nil {
+		return m.TemplateVariables
+	}
+	return nil
+}
+
+func (m *SfcEntity) GetLabels() map[string]string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+func (m *SfcEntity) GetSteeringRules() []*SteeringRule {
+	if m != nil {
+		return m.SteeringRules
+	}
+	return nil
+}
+
 type SfcEntity_SfcElement struct {
-	Container        string         `protobuf:"bytes,1,opt,name=container,proto3" json:"container,omitempty"`
-	PortLabel        string         `protobuf:"bytes,2,opt,name=port_label,proto3" json:"port_label,omitempty"`
-	EtcdVppSwitchKey string         `protobuf:"bytes,3,opt,name=etcd_vpp_switch_key,proto3" json:"etcd_vpp_switch_key,omitempty"`
-	Ipv4Addr         string         `protobuf:"bytes,4,opt,name=ipv4_addr,proto3" json:"ipv4_addr,omitempty"`
-	MacAddr          string         `protobuf:"bytes,5,opt,name=mac_addr,proto3" json:"mac_addr,omitempty"`
-	Type             SfcElementType `protobuf:"varint,6,opt,name=type,proto3,enum=controller.SfcElementType" json:"type,omitempty"`
-	VlanId           uint32         `protobuf:"varint,7,opt,name=vlan_id,proto3" json:"vlan_id,omitempty"`
-	Mtu              uint32         `protobuf:"varint,8,opt,name=mtu,proto3" json:"mtu,omitempty"`
-	RxMode           RxModeType     `protobuf:"varint,9,opt,name=rx_mode,proto3,enum=controller.RxModeType" json:"rx_mode,omitempty"`
-	L2FibMacs        []string       `protobuf:"bytes,10,rep,name=l2fib_macs" json:"l2fib_macs,omitempty"`
-	Ipv6Addr         string         `protobuf:"bytes,11,opt,name=ipv6_addr,proto3" json:"ipv6_addr,omitempty"`
-	L3VrfRoutes      []*L3VRFRoute  `protobuf:"bytes,12,rep,name=l3vrf_routes" json:"l3vrf_routes,omitempty"`
-	L3ArpEntries     []*L3ArpEntry  `protobuf:"bytes,13,rep,name=l3arp_entries" json:"l3arp_entries,omitempty"`
+	Container          string            `protobuf:"bytes,1,opt,name=container,proto3" json:"container,omitempty"`
+	PortLabel          string            `protobuf:"bytes,2,opt,name=port_label,proto3" json:"port_label,omitempty"`
+	EtcdVppSwitchKey   string            `protobuf:"bytes,3,opt,name=etcd_vpp_switch_key,proto3" json:"etcd_vpp_switch_key,omitempty"`
+	Ipv4Addr           string            `protobuf:"bytes,4,opt,name=ipv4_addr,proto3" json:"ipv4_addr,omitempty"`
+	MacAddr            string            `protobuf:"bytes,5,opt,name=mac_addr,proto3" json:"mac_addr,omitempty"`
+	Type               SfcElementType    `protobuf:"varint,6,opt,name=type,proto3,enum=controller.SfcElementType" json:"type,omitempty"`
+	VlanId             uint32            `protobuf:"varint,7,opt,name=vlan_id,proto3" json:"vlan_id,omitempty"`
+	Mtu                uint32            `protobuf:"varint,8,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	RxMode             RxModeType        `protobuf:"varint,9,opt,name=rx_mode,proto3,enum=controller.RxModeType" json:"rx_mode,omitempty"`
+	L2FibMacs          []string          `protobuf:"bytes,10,rep,name=l2fib_macs" json:"l2fib_macs,omitempty"`
+	Ipv6Addr           string            `protobuf:"bytes,11,opt,name=ipv6_addr,proto3" json:"ipv6_addr,omitempty"`
+	L3VrfRoutes        []*L3VRFRoute     `protobuf:"bytes,12,rep,name=l3vrf_routes" json:"l3vrf_routes,omitempty"`
+	L3ArpEntries       []*L3ArpEntry     `protobuf:"bytes,13,rep,name=l3arp_entries" json:"l3arp_entries,omitempty"`
+	Metadata           map[string]string `protobuf:"bytes,14,rep,name=metadata" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	L3VrfIpv6Routes    []*L3VRFRoute     `protobuf:"bytes,15,rep,name=l3vrf_ipv6_routes" json:"l3vrf_ipv6_routes,omitempty"`
+	L3Ipv6Neighbors    []*L3ArpEntry     `protobuf:"bytes,16,rep,name=l3ipv6_neighbors" json:"l3ipv6_neighbors,omitempty"`
+	LinuxRoutes        []*LinuxRoute     `protobuf:"bytes,17,rep,name=linux_routes" json:"linux_routes,omitempty"`
+	LinuxDefaultGw     string            `protobuf:"bytes,18,opt,name=linux_default_gw,proto3" json:"linux_default_gw,omitempty"`
+	LinuxArpEntries    []*L3ArpEntry     `protobuf:"bytes,19,rep,name=linux_arp_entries" json:"linux_arp_entries,omitempty"`
+	Unnumbered         bool              `protobuf:"varint,20,opt,name=unnumbered,proto3" json:"unnumbered,omitempty"`
+	UnnumberedLoopback string            `protobuf:"bytes,21,opt,name=unnumbered_loopback,proto3" json:"unnumbered_loopback,omitempty"`
+	AdminDown          bool              `protobuf:"varint,22,opt,name=admin_down,proto3" json:"admin_down,omitempty"`
+	AntiAffinity       []string          `protobuf:"bytes,23,rep,name=anti_affinity" json:"anti_affinity,omitempty"`
+	Affinity           []string          `protobuf:"bytes,24,rep,name=affinity" json:"affinity,omitempty"`
+	PortParms          *PortParms        `protobuf:"bytes,26,opt,name=port_parms" json:"port_parms,omitempty"`
 }
 
 func (m *SfcEntity_SfcElement) Reset()         { *m = SfcEntity_SfcElement{} }
@@ -366,9 +761,353 @@ func (m *SfcEntity_SfcElement) GetL3ArpEntries() []*L3ArpEntry {
 	return nil
 }
 
+func (m *SfcEntity_SfcElement) GetMetadata() map[string]string {
+	if m != nil {
+		return m.Metadata
+	}
+	return nil
+}
+
+func (m *SfcEntity_SfcElement) GetL3VrfIpv6Routes() []*L3VRFRoute {
+	if m != nil {
+		return m.L3VrfIpv6Routes
+	}
+	return nil
+}
+
+func (m *SfcEntity_SfcElement) GetL3Ipv6Neighbors() []*L3ArpEntry {
+	if m != nil {
+		return m.L3Ipv6Neighbors
+	}
+	return nil
+}
+
+func (m *SfcEntity_SfcElement) GetLinuxRoutes() []*LinuxRoute {
+	if m != nil {
+		return m.LinuxRoutes
+	}
+	return nil
+}
+
+func (m *SfcEntity_SfcElement) GetLinuxArpEntries() []*L3ArpEntry {
+	if m != nil {
+		return m.LinuxArpEntries
+	}
+	return nil
+}
+
+func (m *SfcEntity_SfcElement) GetPortParms() *PortParms {
+	if m != nil {
+		return m.PortParms
+	}
+	return nil
+}
+
+type SfcEntity_EnvironmentOverride struct {
+	SfcIpv4Prefix string                                           `protobuf:"bytes,1,opt,name=sfc_ipv4_prefix,proto3" json:"sfc_ipv4_prefix,omitempty"`
+	Mtu           uint32                                           `protobuf:"varint,2,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	RxMode        RxModeType                                       `protobuf:"varint,3,opt,name=rx_mode,proto3,enum=controller.RxModeType" json:"rx_mode,omitempty"`
+	Elements      []*SfcEntity_EnvironmentOverride_ElementOverride `protobuf:"bytes,4,rep,name=elements" json:"elements,omitempty"`
+}
+
+func (m *SfcEntity_EnvironmentOverride) Reset()         { *m = SfcEntity_EnvironmentOverride{} }
+func (m *SfcEntity_EnvironmentOverride) String() string { return proto.CompactTextString(m) }
+func (*SfcEntity_EnvironmentOverride) ProtoMessage()    {}
+
+func (m *SfcEntity_EnvironmentOverride) GetElements() []*SfcEntity_EnvironmentOverride_ElementOverride {
+	if m != nil {
+		return m.Elements
+	}
+	return nil
+}
+
+type SfcEntity_EnvironmentOverride_ElementOverride struct {
+	Container string     `protobuf:"bytes,1,opt,name=container,proto3" json:"container,omitempty"`
+	PortLabel string     `protobuf:"bytes,2,opt,name=port_label,proto3" json:"port_label,omitempty"`
+	Mtu       uint32     `protobuf:"varint,3,opt,name=mtu,proto3" json:"mtu,omitempty"`
+	RxMode    RxModeType `protobuf:"varint,4,opt,name=rx_mode,proto3,enum=controller.RxModeType" json:"rx_mode,omitempty"`
+	Ipv4Addr  string     `protobuf:"bytes,5,opt,name=ipv4_addr,proto3" json:"ipv4_addr,omitempty"`
+	Ipv6Addr  string     `protobuf:"bytes,6,opt,name=ipv6_addr,proto3" json:"ipv6_addr,omitempty"`
+}
+
+func (m *SfcEntity_EnvironmentOverride_ElementOverride) Reset() {
+	*m = SfcEntity_EnvironmentOverride_ElementOverride{}
+}
+func (m *SfcEntity_EnvironmentOverride_ElementOverride) String() string {
+	return proto.CompactTextString(m)
+}
+func (*SfcEntity_EnvironmentOverride_ElementOverride) ProtoMessage() {}
+
+// a network service groups related chains with the parameters they share, each chain is expanded into the
+// sfc <service name>-<chain name> with the shared parameters filled in where the chain does not set its own
+type NetworkService struct {
+	Name             string       `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description      string       `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Tenant           string       `protobuf:"bytes,3,opt,name=tenant,proto3" json:"tenant,omitempty"`
+	SfcIpv4Prefix    string       `protobuf:"bytes,4,opt,name=sfc_ipv4_prefix,proto3" json:"sfc_ipv4_prefix,omitempty"`
+	ExternalEntity   string       `protobuf:"bytes,5,opt,name=external_entity,proto3" json:"external_entity,omitempty"`
+	EtcdVppSwitchKey string       `protobuf:"bytes,6,opt,name=etcd_vpp_switch_key,proto3" json:"etcd_vpp_switch_key,omitempty"`
+	SfcEntities      []*SfcEntity `protobuf:"bytes,7,rep,name=sfc_entities" json:"sfc_entities,omitempty"`
+}
+
+func (m *NetworkService) Reset()         { *m = NetworkService{} }
+func (m *NetworkService) String() string { return proto.CompactTextString(m) }
+func (*NetworkService) ProtoMessage()    {}
+
+func (m *NetworkService) GetSfcEntities() []*SfcEntity {
+	if m != nil {
+		return m.SfcEntities
+	}
+	return nil
+}
+
+// a template chain, its strings may reference the variables as ${name}, and ${index}, the index of the
+// instance, an instantiation expands it into chains named <template>-<index> unless the sfc is named
+type SfcTemplate struct {
+	Name        string                  `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description string                  `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	Sfc         *SfcEntity              `protobuf:"bytes,3,opt,name=sfc" json:"sfc,omitempty"`
+	Variables   []*SfcTemplate_Variable `protobuf:"bytes,4,rep,name=variables" json:"variables,omitempty"`
+	VniVariable string                  `protobuf:"bytes,5,opt,name=vni_variable,proto3" json:"vni_variable,omitempty"`
+}
+
+func (m *SfcTemplate) Reset()         { *m = SfcTemplate{} }
+func (m *SfcTemplate) String() string { return proto.CompactTextString(m) }
+func (*SfcTemplate) ProtoMessage()    {}
+
+func (m *SfcTemplate) GetSfc() *SfcEntity {
+	if m != nil {
+		return m.Sfc
+	}
+	return nil
+}
+
+func (m *SfcTemplate) GetVariables() []*SfcTemplate_Variable {
+	if m != nil {
+		return m.Variables
+	}
+	return nil
+}
+
+type SfcTemplate_Variable struct {
+	Name    string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Default string `protobuf:"bytes,2,opt,name=default,proto3" json:"default,omitempty"`
+	Pool    string `protobuf:"bytes,3,opt,name=pool,proto3" json:"pool,omitempty"`
+}
+
+func (m *SfcTemplate_Variable) Reset()         { *m = SfcTemplate_Variable{} }
+func (m *SfcTemplate_Variable) String() string { return proto.CompactTextString(m) }
+func (*SfcTemplate_Variable) ProtoMessage()    {}
+
+type SfcTemplateInstantiation struct {
+	Template   string            `protobuf:"bytes,1,opt,name=template,proto3" json:"template,omitempty"`
+	Count      uint32            `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	FirstIndex uint32            `protobuf:"varint,3,opt,name=first_index,proto3" json:"first_index,omitempty"`
+	Variables  map[string]string `protobuf:"bytes,4,rep,name=variables" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+}
+
+func (m *SfcTemplateInstantiation) Reset()         { *m = SfcTemplateInstantiation{} }
+func (m *SfcTemplateInstantiation) String() string { return proto.CompactTextString(m) }
+func (*SfcTemplateInstantiation) ProtoMessage()    {}
+
+func (m *SfcTemplateInstantiation) GetVariables() map[string]string {
+	if m != nil {
+		return m.Variables
+	}
+	return nil
+}
+
+// re-targets the elements of a chain on a host to another host, or only one of them if container and
+// port_label are given, the chain is re-rendered blue/green so the old host's wiring is torn down once the
+// new host's is accepted
+type SfcMigration struct {
+	Sfc       string `protobuf:"bytes,1,opt,name=sfc,proto3" json:"sfc,omitempty"`
+	FromHost  string `protobuf:"bytes,2,opt,name=from_host,proto3" json:"from_host,omitempty"`
+	ToHost    string `protobuf:"bytes,3,opt,name=to_host,proto3" json:"to_host,omitempty"`
+	Container string `protobuf:"bytes,4,opt,name=container,proto3" json:"container,omitempty"`
+	PortLabel string `protobuf:"bytes,5,opt,name=port_label,proto3" json:"port_label,omitempty"`
+}
+
+func (m *SfcMigration) Reset()         { *m = SfcMigration{} }
+func (m *SfcMigration) String() string { return proto.CompactTextString(m) }
+func (*SfcMigration) ProtoMessage()    {}
+
+// wires the hosts matching host_selector to the ees, an ee no policy names is wired to every host, the
+// wiring follows the hosts' labels as they change
+type WiringPolicy struct {
+	Name             string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description      string   `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	HostSelector     string   `protobuf:"bytes,3,opt,name=host_selector,proto3" json:"host_selector,omitempty"`
+	ExternalEntities []string `protobuf:"bytes,4,rep,name=external_entities" json:"external_entities,omitempty"`
+}
+
+func (m *WiringPolicy) Reset()         { *m = WiringPolicy{} }
+func (m *WiringPolicy) String() string { return proto.CompactTextString(m) }
+func (*WiringPolicy) ProtoMessage()    {}
+
+type ConfigVersion struct {
+	Version          uint32            `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	Timestamp        int64             `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Description      string            `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	SystemParameters *SystemParameters `protobuf:"bytes,4,opt,name=system_parameters" json:"system_parameters,omitempty"`
+	ExternalEntities []*ExternalEntity `protobuf:"bytes,5,rep,name=external_entities" json:"external_entities,omitempty"`
+	HostEntities     []*HostEntity     `protobuf:"bytes,6,rep,name=host_entities" json:"host_entities,omitempty"`
+	SfcEntities      []*SfcEntity      `protobuf:"bytes,7,rep,name=sfc_entities" json:"sfc_entities,omitempty"`
+	NetworkServices  []*NetworkService `protobuf:"bytes,8,rep,name=network_services" json:"network_services,omitempty"`
+	SfcTemplates     []*SfcTemplate    `protobuf:"bytes,9,rep,name=sfc_templates" json:"sfc_templates,omitempty"`
+	WiringPolicies   []*WiringPolicy   `protobuf:"bytes,10,rep,name=wiring_policies" json:"wiring_policies,omitempty"`
+}
+
+func (m *ConfigVersion) Reset()         { *m = ConfigVersion{} }
+func (m *ConfigVersion) String() string { return proto.CompactTextString(m) }
+func (*ConfigVersion) ProtoMessage()    {}
+
+func (m *ConfigVersion) GetSystemParameters() *SystemParameters {
+	if m != nil {
+		return m.SystemParameters
+	}
+	return nil
+}
+
+func (m *ConfigVersion) GetExternalEntities() []*ExternalEntity {
+	if m != nil {
+		return m.ExternalEntities
+	}
+	return nil
+}
+
+func (m *ConfigVersion) GetHostEntities() []*HostEntity {
+	if m != nil {
+		return m.HostEntities
+	}
+	return nil
+}
+
+func (m *ConfigVersion) GetSfcEntities() []*SfcEntity {
+	if m != nil {
+		return m.SfcEntities
+	}
+	return nil
+}
+
+func (m *ConfigVersion) GetNetworkServices() []*NetworkService {
+	if m != nil {
+		return m.NetworkServices
+	}
+	return nil
+}
+
+func (m *ConfigVersion) GetSfcTemplates() []*SfcTemplate {
+	if m != nil {
+		return m.SfcTemplates
+	}
+	return nil
+}
+
+func (m *ConfigVersion) GetWiringPolicies() []*WiringPolicy {
+	if m != nil {
+		return m.WiringPolicies
+	}
+	return nil
+}
+
+type EntityStatus struct {
+	Name             string          `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	State            RenderStateType `protobuf:"varint,2,opt,name=state,proto3,enum=controller.RenderStateType" json:"state,omitempty"`
+	Message          string          `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp        int64           `protobuf:"varint,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	KeyCount         uint32          `protobuf:"varint,5,opt,name=key_count,proto3" json:"key_count,omitempty"`
+	RetryAttempts    uint32          `protobuf:"varint,6,opt,name=retry_attempts,proto3" json:"retry_attempts,omitempty"`
+	NextRetry        int64           `protobuf:"varint,7,opt,name=next_retry,proto3" json:"next_retry,omitempty"`
+	RetriesExhausted bool            `protobuf:"varint,8,opt,name=retries_exhausted,proto3" json:"retries_exhausted,omitempty"`
+}
+
+func (m *EntityStatus) Reset()         { *m = EntityStatus{} }
+func (m *EntityStatus) String() string { return proto.CompactTextString(m) }
+func (*EntityStatus) ProtoMessage()    {}
+
+type RenderRetry struct {
+	Kind        string `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Name        string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Attempts    uint32 `protobuf:"varint,3,opt,name=attempts,proto3" json:"attempts,omitempty"`
+	NextAttempt int64  `protobuf:"varint,4,opt,name=next_attempt,proto3" json:"next_attempt,omitempty"`
+	LastError   string `protobuf:"bytes,5,opt,name=last_error,proto3" json:"last_error,omitempty"`
+	Exhausted   bool   `protobuf:"varint,6,opt,name=exhausted,proto3" json:"exhausted,omitempty"`
+}
+
+func (m *RenderRetry) Reset()         { *m = RenderRetry{} }
+func (m *RenderRetry) String() string { return proto.CompactTextString(m) }
+func (*RenderRetry) ProtoMessage()    {}
+
+type EntityKeys struct {
+	Name string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Keys []string `protobuf:"bytes,2,rep,name=keys" json:"keys,omitempty"`
+}
+
+func (m *EntityKeys) Reset()         { *m = EntityKeys{} }
+func (m *EntityKeys) String() string { return proto.CompactTextString(m) }
+func (*EntityKeys) ProtoMessage()    {}
+
+type EntityChange struct {
+	Name      string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Kind      string `protobuf:"bytes,2,opt,name=kind,proto3" json:"kind,omitempty"`
+	Timestamp int64  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	Source    string `protobuf:"bytes,4,opt,name=source,proto3" json:"source,omitempty"`
+	OldSpec   string `protobuf:"bytes,5,opt,name=old_spec,proto3" json:"old_spec,omitempty"`
+	NewSpec   string `protobuf:"bytes,6,opt,name=new_spec,proto3" json:"new_spec,omitempty"`
+}
+
+func (m *EntityChange) Reset()         { *m = EntityChange{} }
+func (m *EntityChange) String() string { return proto.CompactTextString(m) }
+func (*EntityChange) ProtoMessage()    {}
+
+// a batch of entity changes rendered at the start of a maintenance window, validated when it is submitted
+type ScheduledChange struct {
+	Name             string            `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Description      string            `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	WindowStart      int64             `protobuf:"varint,3,opt,name=window_start,proto3" json:"window_start,omitempty"`
+	WindowEnd        int64             `protobuf:"varint,4,opt,name=window_end,proto3" json:"window_end,omitempty"`
+	ExternalEntities []*ExternalEntity `protobuf:"bytes,5,rep,name=external_entities" json:"external_entities,omitempty"`
+	HostEntities     []*HostEntity     `protobuf:"bytes,6,rep,name=host_entities" json:"host_entities,omitempty"`
+	SfcEntities      []*SfcEntity      `protobuf:"bytes,7,rep,name=sfc_entities" json:"sfc_entities,omitempty"`
+	State            string            `protobuf:"bytes,8,opt,name=state,proto3" json:"state,omitempty"`
+	Message          string            `protobuf:"bytes,9,opt,name=message,proto3" json:"message,omitempty"`
+	AppliedAt        int64             `protobuf:"varint,10,opt,name=applied_at,proto3" json:"applied_at,omitempty"`
+	ConfigVersion    uint32            `protobuf:"varint,11,opt,name=config_version,proto3" json:"config_version,omitempty"`
+	SubmittedBy      string            `protobuf:"bytes,12,opt,name=submitted_by,proto3" json:"submitted_by,omitempty"`
+}
+
+func (m *ScheduledChange) Reset()         { *m = ScheduledChange{} }
+func (m *ScheduledChange) String() string { return proto.CompactTextString(m) }
+func (*ScheduledChange) ProtoMessage()    {}
+
+func (m *ScheduledChange) GetExternalEntities() []*ExternalEntity {
+	if m != nil {
+		return m.ExternalEntities
+	}
+	return nil
+}
+
+func (m *ScheduledChange) GetHostEntities() []*HostEntity {
+	if m != nil {
+		return m.HostEntities
+	}
+	return nil
+}
+
+func (m *ScheduledChange) GetSfcEntities() []*SfcEntity {
+	if m != nil {
+		return m.SfcEntities
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterEnum("controller.RxModeType", RxModeType_name, RxModeType_value)
 	proto.RegisterEnum("controller.ExtEntDriverType", ExtEntDriverType_name, ExtEntDriverType_value)
+	proto.RegisterEnum("controller.OverlayTopologyType", OverlayTopologyType_name, OverlayTopologyType_value)
+	proto.RegisterEnum("controller.HostType", HostType_name, HostType_value)
 	proto.RegisterEnum("controller.SfcType", SfcType_name, SfcType_value)
 	proto.RegisterEnum("controller.SfcElementType", SfcElementType_name, SfcElementType_value)
+	proto.RegisterEnum("controller.RenderStateType", RenderStateType_name, RenderStateType_value)
 }
@@ -14,6 +14,8 @@
 
 package controller
 
+import "fmt"
+
 // SfcControllerPrefix provides sfc controller prefix
 func SfcControllerPrefix() string {
 	return "/sfc-controller/v1/"
@@ -68,3 +70,261 @@ func SfcEntityHTTPPrefix() string {
 func SfcEntityNameKey(name string) string {
 	return SfcEntityKeyPrefix() + name
 }
+
+// NetworkServiceKeyPrefix provides sfc controller's network service key prefix
+func NetworkServiceKeyPrefix() string {
+	return SfcControllerPrefix() + "NS/"
+}
+
+// NetworkServicesHTTPPrefix provides sfc controller's network services HTTP prefix
+func NetworkServicesHTTPPrefix() string {
+	return SfcControllerPrefix() + "NSs"
+}
+
+// NetworkServiceNameKey provides sfc controller's network service name key
+func NetworkServiceNameKey(name string) string {
+	return NetworkServiceKeyPrefix() + name
+}
+
+// SfcTemplateKeyPrefix provides sfc controller's sfc template key prefix
+func SfcTemplateKeyPrefix() string {
+	return SfcControllerPrefix() + "SFCTemplate/"
+}
+
+// SfcTemplatesHTTPPrefix provides sfc controller's sfc templates HTTP prefix
+func SfcTemplatesHTTPPrefix() string {
+	return SfcControllerPrefix() + "SFCTemplates"
+}
+
+// SfcTemplateNameKey provides sfc controller's sfc template name key
+func SfcTemplateNameKey(name string) string {
+	return SfcTemplateKeyPrefix() + name
+}
+
+// WiringPolicyKeyPrefix provides sfc controller's wiring policy key prefix
+func WiringPolicyKeyPrefix() string {
+	return SfcControllerPrefix() + "WiringPolicy/"
+}
+
+// WiringPoliciesHTTPPrefix provides sfc controller's wiring policies HTTP prefix
+func WiringPoliciesHTTPPrefix() string {
+	return SfcControllerPrefix() + "WiringPolicies"
+}
+
+// WiringPolicyNameKey provides sfc controller's wiring policy name key
+func WiringPolicyNameKey(name string) string {
+	return WiringPolicyKeyPrefix() + name
+}
+
+// SfcTemplateInstantiateHTTPPrefix provides sfc controller's sfc template instantiation HTTP prefix
+func SfcTemplateInstantiateHTTPPrefix() string {
+	return SfcControllerPrefix() + "SFCTemplateInstantiate/"
+}
+
+// SfcMigrateHTTPPrefix provides sfc controller's sfc migration HTTP prefix
+func SfcMigrateHTTPPrefix() string {
+	return SfcControllerPrefix() + "SFCMigrate/"
+}
+
+// SfcUndeleteHTTPPrefix provides sfc controller's cancelling of the deletion of a chain HTTP prefix
+func SfcUndeleteHTTPPrefix() string {
+	return SfcControllerPrefix() + "SFCUndelete/"
+}
+
+// SfcQuarantineHTTPPrefix provides sfc controller's quarantine of a chain element HTTP prefix
+func SfcQuarantineHTTPPrefix() string {
+	return SfcControllerPrefix() + "SFCQuarantine/"
+}
+
+// ConfigVersionKeyPrefix provides sfc controller's config version key prefix
+func ConfigVersionKeyPrefix() string {
+	return SfcControllerPrefix() + "version/"
+}
+
+// ConfigVersionsHTTPPrefix provides sfc controller's config versions HTTP prefix
+func ConfigVersionsHTTPPrefix() string {
+	return SfcControllerPrefix() + "versions"
+}
+
+// ConfigVersionKey provides sfc controller's config version key, zero padded so keys list in version order
+func ConfigVersionKey(version uint32) string {
+	return ConfigVersionKeyPrefix() + fmt.Sprintf("%010d", version)
+}
+
+// ConfigRollbackHTTPPrefix provides sfc controller's config rollback HTTP prefix
+func ConfigRollbackHTTPPrefix() string {
+	return SfcControllerPrefix() + "rollback/"
+}
+
+// BackupHTTPPrefix provides sfc controller's datastore backup HTTP prefix
+func BackupHTTPPrefix() string {
+	return SfcControllerPrefix() + "backup"
+}
+
+// RestoreHTTPPrefix provides sfc controller's datastore restore HTTP prefix
+func RestoreHTTPPrefix() string {
+	return SfcControllerPrefix() + "restore"
+}
+
+// BulkHTTPPrefix provides sfc controller's bulk apply HTTP prefix
+func BulkHTTPPrefix() string {
+	return SfcControllerPrefix() + "bulk"
+}
+
+// ReconcileHTTPPrefix provides sfc controller's reconcile of the chains matching a selector HTTP prefix
+func ReconcileHTTPPrefix() string {
+	return SfcControllerPrefix() + "reconcile"
+}
+
+// DriverHTTPPrefix provides sfc controller's cnp driver and its hot reload HTTP prefix
+func DriverHTTPPrefix() string {
+	return SfcControllerPrefix() + "driver"
+}
+
+// BandwidthHTTPPrefix provides sfc controller's bandwidth committed on the host uplinks HTTP prefix
+func BandwidthHTTPPrefix() string {
+	return SfcControllerPrefix() + "bandwidth/"
+}
+
+// ResourcesHTTPPrefix provides sfc controller's resources rendered on the hosts HTTP prefix
+func ResourcesHTTPPrefix() string {
+	return SfcControllerPrefix() + "resources/"
+}
+
+// InterfaceOwnerHTTPPrefix provides sfc controller's reverse lookup of the agents' i/f's HTTP prefix
+func InterfaceOwnerHTTPPrefix() string {
+	return SfcControllerPrefix() + "interface-owner/"
+}
+
+// DriverStateHTTPPrefix provides sfc controller's cached driver state of an entity HTTP prefix
+func DriverStateHTTPPrefix() string {
+	return SfcControllerPrefix() + "driver-state/"
+}
+
+// AllocatedIDsHTTPPrefix provides sfc controller's vni's, vlans, memif and veth ids allocated by the driver HTTP prefix
+func AllocatedIDsHTTPPrefix() string {
+	return SfcControllerPrefix() + "allocated-ids"
+}
+
+// ForensicLookupHTTPPrefix provides sfc controller's owners of an observed vni, mac or ip HTTP prefix
+func ForensicLookupHTTPPrefix() string {
+	return SfcControllerPrefix() + "forensic-lookup"
+}
+
+// FederationHTTPPrefix provides sfc controller's exchange of the inter-site segments with its federation peers HTTP prefix
+func FederationHTTPPrefix() string {
+	return SfcControllerPrefix() + "federation/"
+}
+
+// ConvergenceHTTPPrefix provides sfc controller's rendering convergence percentiles HTTP prefix
+func ConvergenceHTTPPrefix() string {
+	return SfcControllerPrefix() + "convergence"
+}
+
+// DrainHTTPPrefix provides sfc controller's drain of the hosts matching a selector HTTP prefix
+func DrainHTTPPrefix() string {
+	return SfcControllerPrefix() + "drain"
+}
+
+// FeaturesHTTPPrefix provides sfc controller's feature flags prefix
+func FeaturesHTTPPrefix() string {
+	return SfcControllerPrefix() + "features"
+}
+
+// FaultsHTTPPrefix provides sfc controller's fault injection prefix
+func FaultsHTTPPrefix() string {
+	return SfcControllerPrefix() + "faults"
+}
+
+// ShadowHTTPPrefix provides sfc controller's withheld agent writes prefix
+func ShadowHTTPPrefix() string {
+	return SfcControllerPrefix() + "shadow"
+}
+
+// MirrorHTTPPrefix provides sfc controller's datastore mirror prefix
+func MirrorHTTPPrefix() string {
+	return SfcControllerPrefix() + "mirror"
+}
+
+// CachesHTTPPrefix provides sfc controller's cache sizes prefix
+func CachesHTTPPrefix() string {
+	return SfcControllerPrefix() + "caches"
+}
+
+// VerifyHTTPPrefix provides sfc controller's consistency check prefix
+func VerifyHTTPPrefix() string {
+	return SfcControllerPrefix() + "verify"
+}
+
+// StatusKeyPrefix provides sfc controller's entity render status key prefix
+func StatusKeyPrefix() string {
+	return SfcControllerPrefix() + "status/"
+}
+
+// The kinds of entities in the status, key ownership and change history trees
+const (
+	ExternalEntityKind   = "EE"
+	HostEntityKind       = "HE"
+	SfcEntityKind        = "SFC"
+	SystemParametersKind = "SP"
+)
+
+// EventLogHTTPPrefix provides sfc controller's recent events prefix
+func EventLogHTTPPrefix() string {
+	return StatusKeyPrefix() + "events"
+}
+
+// EntityStatusKey provides sfc controller's entity render status key
+func EntityStatusKey(kind string, name string) string {
+	return StatusKeyPrefix() + kind + "/" + name
+}
+
+// EntityKeysKeyPrefix provides sfc controller's entity key ownership prefix
+func EntityKeysKeyPrefix() string {
+	return SfcControllerPrefix() + "keys/"
+}
+
+// EntityKeysKey provides sfc controller's key of the vpp-agent keys rendered for an entity
+func EntityKeysKey(kind string, name string) string {
+	return EntityKeysKeyPrefix() + kind + "/" + name
+}
+
+// RenderRetryKeyPrefix provides sfc controller's failed render retry queue prefix
+func RenderRetryKeyPrefix() string {
+	return SfcControllerPrefix() + "retry/"
+}
+
+// RenderRetryKey provides sfc controller's key of the retries of an entity's failed render
+func RenderRetryKey(kind string, name string) string {
+	return RenderRetryKeyPrefix() + kind + "/" + name
+}
+
+// ScheduledChangeKeyPrefix provides sfc controller's scheduled change prefix
+func ScheduledChangeKeyPrefix() string {
+	return SfcControllerPrefix() + "scheduled/"
+}
+
+// ScheduledChangeKey provides sfc controller's key of a scheduled change
+func ScheduledChangeKey(name string) string {
+	return ScheduledChangeKeyPrefix() + name
+}
+
+// HistoryKeyPrefix provides sfc controller's entity change history prefix
+func HistoryKeyPrefix() string {
+	return SfcControllerPrefix() + "history/"
+}
+
+// EntityHistoryKeyPrefix provides sfc controller's prefix of the changes of an entity
+func EntityHistoryKeyPrefix(kind string, name string) string {
+	return HistoryKeyPrefix() + kind + "/" + name + "/"
+}
+
+// EntityChangeKey provides sfc controller's key of an entity change, zero padded so keys list in time order
+func EntityChangeKey(kind string, name string, unixNano int64) string {
+	return EntityHistoryKeyPrefix(kind, name) + fmt.Sprintf("%020d", unixNano)
+}
+
+// LogHTTPPrefix provides sfc controller's log levels and format prefix
+func LogHTTPPrefix() string {
+	return SfcControllerPrefix() + "log"
+}
@@ -18,10 +18,13 @@ import (
 	"net"
 	"strings"
 
+	"github.com/ligato/cn-infra/health/statuscheck/model/status"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
 	linuxIntf "github.com/ligato/vpp-agent/plugins/linuxplugin/ifplugin/model/interfaces"
+	linuxL3 "github.com/ligato/vpp-agent/plugins/linuxplugin/l3plugin/model/l3"
 )
 
 // this must match what utils the vpp-agent uses
@@ -51,6 +54,11 @@ func InterfaceStatePrefixKey(vppLabel string) string {
 	return agentPrefix + vppLabel + "/" + interfaces.InterfaceStateKeyPrefix()
 }
 
+// InterfaceErrorKey constructs interface error db key
+func InterfaceErrorKey(vppLabel string, ifaceLabel string) string {
+	return agentPrefix + vppLabel + "/" + interfaces.InterfaceErrorKey(ifaceLabel)
+}
+
 // InterfaceKey constructs interface db key
 func InterfaceKey(vppLabel string, ifaceLabel string) string {
 	return agentPrefix + vppLabel + "/" + interfaces.InterfaceKey(ifaceLabel)
@@ -71,6 +79,16 @@ func LinuxInterfacePrefixKey(vppLabel string) string {
 	return agentPrefix + vppLabel + "/" + linuxIntf.InterfaceKeyPrefix()
 }
 
+// LinuxStaticRouteKey constructs Linux static route db key
+func LinuxStaticRouteKey(vppLabel string, routeLabel string) string {
+	return agentPrefix + vppLabel + "/" + linuxL3.StaticRouteKey(routeLabel)
+}
+
+// LinuxStaticArpKey constructs Linux static arp entry db key
+func LinuxStaticArpKey(vppLabel string, arpLabel string) string {
+	return agentPrefix + vppLabel + "/" + linuxL3.StaticArpKey(arpLabel)
+}
+
 // L2BridgeDomainKey constructs L2 bridge domain db key
 func L2BridgeDomainKey(vppLabel string, bdName string) string {
 	return agentPrefix + vppLabel + "/" + l2.BridgeDomainKey(bdName)
@@ -81,11 +99,36 @@ func L2BridgeDomainKeyPrefix(vppLabel string) string {
 	return agentPrefix + vppLabel + "/" + l2.BridgeDomainKeyPrefix()
 }
 
+// L2BridgeDomainStateKey constructs L2 bridge domain state db key
+func L2BridgeDomainStateKey(vppLabel string, bdName string) string {
+	return agentPrefix + vppLabel + "/" + l2.BridgeDomainStateKey(bdName)
+}
+
+// L2BridgeDomainErrorKey constructs L2 bridge domain error db key
+func L2BridgeDomainErrorKey(vppLabel string, bdName string) string {
+	return agentPrefix + vppLabel + "/" + l2.BridgeDomainErrorKey(bdName)
+}
+
+// AgentStatusKey constructs the agent's operational status db key
+func AgentStatusKey(vppLabel string) string {
+	return agentPrefix + vppLabel + "/" + status.AgentStatusKey()
+}
+
 // L2XConnectKey constructs L2 XConnect db key
 func L2XConnectKey(vppLabel string, rxIf string) string {
 	return agentPrefix + vppLabel + "/" + l2.XConnectKey(rxIf)
 }
 
+// L2FibKey constructs L2 FIB entry db key
+func L2FibKey(vppLabel string, bdName string, mac string) string {
+	return agentPrefix + vppLabel + "/" + l2.FibKey(bdName, mac)
+}
+
+// L2XConnectKeyPrefix constructs L2 XConnect db key prefix
+func L2XConnectKeyPrefix(vppLabel string) string {
+	return agentPrefix + vppLabel + "/" + l2.XConnectKeyPrefix()
+}
+
 // L3RouteKeyPrefix constructs L3 route db key prefix
 func L3RouteKeyPrefix(vppLabel string) string {
 	//return agentPrefix + vppLabel + "/" + l3.RouteKeyPrefix()
@@ -97,7 +140,17 @@ func L3RouteKey(vppLabel string, vrf uint32, destNet *net.IPNet, nextHop string)
 	return agentPrefix + vppLabel + "/" + l3.RouteKey(vrf, destNet.String(), nextHop)
 }
 
+// ArpEntryKeyPrefix constructs l3 arp entry db key prefix
+func ArpEntryKeyPrefix(vppLabel string) string {
+	return agentPrefix + vppLabel + "/" + l3.ArpKeyPrefix()
+}
+
 // ArpEntryKeyl3 arp key
 func ArpEntryKey(vppLabel string, iface string, ipAddress string) string {
 	return agentPrefix + vppLabel + "/" + l3.ArpEntryKey(iface, ipAddress)
 }
+
+// AclKey constructs acl db key
+func AclKey(vppLabel string, aclName string) string {
+	return agentPrefix + vppLabel + "/" + acl.Key(aclName)
+}
@@ -0,0 +1,192 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package alarms raises the controller's critical conditions to the NOC
+// tooling that still lives on syslog and SNMP.  An alarm is raised once per
+// condition and entity: it is sent again only after it was cleared, so an
+// entity failing on every render retry does not flood the NOC.  Alarms are
+// sent from a goroutine, to every target, and dropped when too many are
+// waiting.
+//
+// A target is picked by the scheme of its url:
+//
+//	syslog://host:514                    syslog over udp, at priority daemon.crit
+//	syslog+tcp://host:601
+//	snmp://community@host:162            SNMPv2c traps
+//	snmp://community@host:162?oid=1.3.6.1.4.1.x.y
+package alarms
+
+import (
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/utils/logs"
+)
+
+// the critical conditions
+const (
+	ConditionRenderFailure       = "render-failure"       // an entity failed to render
+	ConditionIDExhausted         = "id-exhausted"         // an id space, ie the vlan/vni's, ran out
+	ConditionAgentUnreachable    = "agent-unreachable"    // the agent of a host stopped reporting
+	ConditionReconcileDivergence = "reconcile-divergence" // the agents' config diverged from the datastore
+)
+
+// conditions is the order of the conditions, the SNMP notification of a condition is numbered from it
+var conditions = []string{
+	ConditionRenderFailure,
+	ConditionIDExhausted,
+	ConditionAgentUnreachable,
+	ConditionReconcileDivergence,
+}
+
+// maxPending is the number of alarms that can wait to be sent
+const maxPending = 256
+
+var log = logs.Logger(logs.Core)
+
+// Alarm is a critical condition of an entity, or of the controller when Name is empty
+type Alarm struct {
+	Time      time.Time
+	Condition string
+	Name      string
+	Message   string
+}
+
+// String is the text of the alarm as it is logged
+func (a *Alarm) String() string {
+	if a.Name == "" {
+		return fmt.Sprintf("%s: %s", a.Condition, a.Message)
+	}
+	return fmt.Sprintf("%s: '%s': %s", a.Condition, a.Name, a.Message)
+}
+
+// Sink sends alarms to a target
+type Sink interface {
+	Send(a *Alarm) error
+	Close() error
+}
+
+// NewSink returns the sink for the scheme of the target url, tag names the sender
+func NewSink(targetURL string, tag string) (Sink, error) {
+
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("Invalid alarm target: '%s', missing host", targetURL)
+	}
+	switch u.Scheme {
+	case "syslog":
+		return newSyslogSink("udp", u.Host, tag), nil
+	case "syslog+tcp":
+		return newSyslogSink("tcp", u.Host, tag), nil
+	case "snmp":
+		community := "public"
+		if u.User != nil {
+			community = u.User.Username()
+		}
+		oid := DefaultEnterpriseOID
+		if q := u.Query().Get("oid"); q != "" {
+			oid = q
+		}
+		return newTrapSink(u.Host, community, oid)
+	default:
+		return nil, fmt.Errorf("Invalid alarm target: '%s', the scheme must be syslog, syslog+tcp or snmp",
+			targetURL)
+	}
+}
+
+// Notifier raises the alarms to its sinks
+type Notifier struct {
+	sinks   []Sink
+	mu      sync.Mutex
+	active  map[string]bool // condition/name of the alarms raised and not cleared
+	pending chan *Alarm
+	done    chan struct{}
+	once    sync.Once
+}
+
+// NewNotifier starts sending the alarms raised to the sinks
+func NewNotifier(sinks ...Sink) *Notifier {
+	n := &Notifier{
+		sinks:   sinks,
+		active:  make(map[string]bool),
+		pending: make(chan *Alarm, maxPending),
+		done:    make(chan struct{}),
+	}
+	go n.run()
+	return n
+}
+
+// Raise sends the alarm unless it is already raised for the condition and the name, it returns whether it was
+// sent
+func (n *Notifier) Raise(condition string, name string, message string) bool {
+
+	key := condition + "/" + name
+	n.mu.Lock()
+	if n.active[key] {
+		n.mu.Unlock()
+		return false
+	}
+	n.active[key] = true
+	n.mu.Unlock()
+
+	a := &Alarm{Time: time.Now(), Condition: condition, Name: name, Message: message}
+	log.Errorf("alarm: %s", a)
+	select {
+	case n.pending <- a:
+	default:
+		log.Warnf("alarm: too many pending, dropping: %s", a)
+	}
+	return true
+}
+
+// Clear ends the condition of the name, the next Raise of it is sent
+func (n *Notifier) Clear(condition string, name string) {
+	n.mu.Lock()
+	delete(n.active, condition+"/"+name)
+	n.mu.Unlock()
+}
+
+// Active returns whether the alarm of the condition and the name is raised
+func (n *Notifier) Active(condition string, name string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.active[condition+"/"+name]
+}
+
+// Close sends the pending alarms and closes the sinks
+func (n *Notifier) Close() {
+	n.once.Do(func() {
+		close(n.pending)
+		<-n.done
+		for _, sink := range n.sinks {
+			sink.Close()
+		}
+	})
+}
+
+func (n *Notifier) run() {
+	defer close(n.done)
+	for a := range n.pending {
+		for _, sink := range n.sinks {
+			if err := sink.Send(a); err != nil {
+				log.Errorf("alarm: error sending: %s", err)
+			}
+		}
+	}
+}
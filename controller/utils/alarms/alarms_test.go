@@ -0,0 +1,180 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alarms
+
+import (
+	"bytes"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingSink struct {
+	mu     sync.Mutex
+	alarms []*Alarm
+}
+
+func (s *recordingSink) Send(a *Alarm) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.alarms = append(s.alarms, a)
+	return nil
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func listenUDP(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+// berContentLen returns the length in the header of the tlv
+func berContentLen(tlv []byte) int {
+	if tlv[1] < 0x80 {
+		return int(tlv[1])
+	}
+	n := 0
+	for _, b := range tlv[2 : 2+int(tlv[1]&0x7f)] {
+		n = n<<8 | int(b)
+	}
+	return n
+}
+
+func TestRaiseOnceUntilCleared(t *testing.T) {
+
+	sink := &recordingSink{}
+	n := NewNotifier(sink)
+
+	if !n.Raise(ConditionRenderFailure, "sfc1", "no host") {
+		t.Error("the first alarm was not sent")
+	}
+	if n.Raise(ConditionRenderFailure, "sfc1", "no host") {
+		t.Error("an active alarm was sent again")
+	}
+	if !n.Raise(ConditionRenderFailure, "sfc2", "no host") {
+		t.Error("the alarm of another entity was not sent")
+	}
+	n.Clear(ConditionRenderFailure, "sfc1")
+	if !n.Raise(ConditionRenderFailure, "sfc1", "no host") {
+		t.Error("a cleared alarm was not sent again")
+	}
+	n.Close()
+
+	if len(sink.alarms) != 3 {
+		t.Errorf("sent %d alarms, expected 3", len(sink.alarms))
+	}
+}
+
+func TestEncodeOID(t *testing.T) {
+
+	b, err := encodeOID(oidSnmpTrapOID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []byte{0x2b, 0x06, 0x01, 0x06, 0x03, 0x01, 0x01, 0x04, 0x01, 0x00}
+	if !bytes.Equal(b, expected) {
+		t.Errorf("encoded % x, expected % x", b, expected)
+	}
+	if b, _ := encodeOID("1.3.6.1.4.1.311"); !bytes.Equal(b, []byte{0x2b, 0x06, 0x01, 0x04, 0x01, 0x82, 0x37}) {
+		t.Errorf("multi-octet arc encoded % x", b)
+	}
+	for _, oid := range []string{"1", "1.x", "3.1", "1.40"} {
+		if _, err := encodeOID(oid); err == nil {
+			t.Errorf("oid '%s' should be invalid", oid)
+		}
+	}
+}
+
+func TestTrap(t *testing.T) {
+
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	sink, err := NewSink("snmp://noc@"+conn.LocalAddr().String()+"?oid=1.3.6.1.4.1.9999", "sfc-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	a := &Alarm{Time: time.Now(), Condition: ConditionAgentUnreachable, Name: "vswitch1", Message: "down"}
+	if err := sink.Send(a); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := buf[:n]
+	content := msg[len(msg)-berContentLen(msg):]
+	if !bytes.Equal(berTLV(berSequence, content), msg) {
+		t.Fatalf("not a sequence of the message's length: % x", msg)
+	}
+	// version 2c then the community then the trap pdu
+	header := []byte{berInteger, 1, snmpVersion2c, berOctetString, 3, 'n', 'o', 'c', berTrapV2PDU}
+	if !bytes.HasPrefix(content, header) {
+		t.Errorf("unexpected header: % x", msg)
+	}
+	notification, _ := encodeOID("1.3.6.1.4.1.9999.0.3")
+	if !bytes.Contains(msg, berTLV(berOID, notification)) {
+		t.Errorf("the trap oid of agent-unreachable is missing: % x", msg)
+	}
+	for _, s := range []string{ConditionAgentUnreachable, "vswitch1", "down"} {
+		if !bytes.Contains(msg, berTLV(berOctetString, []byte(s))) {
+			t.Errorf("the varbind '%s' is missing", s)
+		}
+	}
+}
+
+func TestSyslog(t *testing.T) {
+
+	conn := listenUDP(t)
+	defer conn.Close()
+
+	sink, err := NewSink("syslog://"+conn.LocalAddr().String(), "sfc-controller")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+	if err := sink.Send(&Alarm{Condition: ConditionIDExhausted, Message: "vlan id space is full"}); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1500)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	line := string(buf[:n])
+	// daemon.crit is priority 3*8+2
+	if !strings.HasPrefix(line, "<26>") || !strings.Contains(line, "sfc-controller") ||
+		!strings.Contains(line, "id-exhausted: vlan id space is full") {
+		t.Errorf("unexpected syslog line: '%s'", line)
+	}
+}
+
+func TestNewSinkInvalid(t *testing.T) {
+	for _, target := range []string{"smtp://host", "syslog://", "snmp://host?oid=1.x"} {
+		if _, err := NewSink(target, "sfc-controller"); err == nil {
+			t.Errorf("target '%s' should be invalid", target)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alarms
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultEnterpriseOID is the base of the trap OIDs when the target does not set one.  It is under the
+// experimental arc, a deployment with its own enterprise number should set ?oid= in the target.  Under the
+// base:
+//
+//	base.0.n   the notification of the n'th condition: render-failure, id-exhausted, agent-unreachable,
+//	           reconcile-divergence
+//	base.1.1   the condition, a string
+//	base.1.2   the name of the entity, a string
+//	base.1.3   the message, a string
+const DefaultEnterpriseOID = "1.3.6.1.3.65535"
+
+// the OIDs every SNMPv2 trap starts with
+const (
+	oidSysUpTime   = "1.3.6.1.2.1.1.3.0"
+	oidSnmpTrapOID = "1.3.6.1.6.3.1.1.4.1.0"
+)
+
+// the BER tags of an SNMPv2c trap
+const (
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berOID         = 0x06
+	berSequence    = 0x30
+	berTimeTicks   = 0x43
+	berTrapV2PDU   = 0xa7
+)
+
+const snmpVersion2c = 1
+
+// trapSink sends the alarms as SNMPv2c traps over udp
+type trapSink struct {
+	addr      string
+	community string
+	base      string
+	start     time.Time
+	requestID int32
+	conn      net.Conn
+}
+
+func newTrapSink(addr string, community string, base string) (*trapSink, error) {
+	if _, err := encodeOID(base); err != nil {
+		return nil, fmt.Errorf("Invalid trap oid: '%s': %s", base, err)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "162")
+	}
+	return &trapSink{addr: addr, community: community, base: base, start: time.Now()}, nil
+}
+
+// Send sends the trap of the alarm, udp so it is not known whether the manager received it
+func (s *trapSink) Send(a *Alarm) error {
+	if s.conn == nil {
+		conn, err := net.Dial("udp", s.addr)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	msg, err := s.encodeTrap(a)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn.Write(msg)
+	return err
+}
+
+func (s *trapSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// notificationOID returns the OID of the condition's notification
+func (s *trapSink) notificationOID(condition string) string {
+	for i, c := range conditions {
+		if c == condition {
+			return s.base + ".0." + strconv.Itoa(i+1)
+		}
+	}
+	return s.base + ".0.0"
+}
+
+// encodeTrap returns the SNMPv2c message of the trap of the alarm
+func (s *trapSink) encodeTrap(a *Alarm) ([]byte, error) {
+
+	upTime := uint32(a.Time.Sub(s.start) / (10 * time.Millisecond))
+
+	var varBinds []byte
+	for _, vb := range []struct {
+		oid   string
+		value []byte
+	}{
+		{oidSysUpTime, berUint(berTimeTicks, upTime)},
+		{oidSnmpTrapOID, nil},
+		{s.base + ".1.1", berTLV(berOctetString, []byte(a.Condition))},
+		{s.base + ".1.2", berTLV(berOctetString, []byte(a.Name))},
+		{s.base + ".1.3", berTLV(berOctetString, []byte(a.Message))},
+	} {
+		oid, err := encodeOID(vb.oid)
+		if err != nil {
+			return nil, err
+		}
+		value := vb.value
+		if value == nil {
+			trapOID, err := encodeOID(s.notificationOID(a.Condition))
+			if err != nil {
+				return nil, err
+			}
+			value = berTLV(berOID, trapOID)
+		}
+		varBinds = append(varBinds, berTLV(berSequence, append(berTLV(berOID, oid), value...))...)
+	}
+
+	requestID := atomic.AddInt32(&s.requestID, 1)
+	pdu := berInt(requestID)
+	pdu = append(pdu, berInt(0)...) // error-status
+	pdu = append(pdu, berInt(0)...) // error-index
+	pdu = append(pdu, berTLV(berSequence, varBinds)...)
+
+	msg := berInt(snmpVersion2c)
+	msg = append(msg, berTLV(berOctetString, []byte(s.community))...)
+	msg = append(msg, berTLV(berTrapV2PDU, pdu)...)
+	return berTLV(berSequence, msg), nil
+}
+
+// berTLV returns the tag, the length and the value
+func berTLV(tag byte, value []byte) []byte {
+	b := []byte{tag}
+	n := len(value)
+	switch {
+	case n < 0x80:
+		b = append(b, byte(n))
+	case n <= 0xff:
+		b = append(b, 0x81, byte(n))
+	default:
+		b = append(b, 0x82, byte(n>>8), byte(n))
+	}
+	return append(b, value...)
+}
+
+// berInt returns an INTEGER in the fewest octets of its two's complement
+func berInt(v int32) []byte {
+	b := []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	for len(b) > 1 && ((b[0] == 0 && b[1]&0x80 == 0) || (b[0] == 0xff && b[1]&0x80 != 0)) {
+		b = b[1:]
+	}
+	return berTLV(berInteger, b)
+}
+
+// berUint returns an unsigned application type, ie TimeTicks
+func berUint(tag byte, v uint32) []byte {
+	b := []byte{0, byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+	for len(b) > 1 && b[0] == 0 && b[1]&0x80 == 0 {
+		b = b[1:]
+	}
+	return berTLV(tag, b)
+}
+
+// encodeOID returns the content octets of the dotted OID
+func encodeOID(oid string) ([]byte, error) {
+
+	arcs := strings.Split(oid, ".")
+	if len(arcs) < 2 {
+		return nil, fmt.Errorf("an oid has at least 2 arcs")
+	}
+	values := make([]uint32, len(arcs))
+	for i, arc := range arcs {
+		v, err := strconv.ParseUint(arc, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid arc: '%s'", arc)
+		}
+		values[i] = uint32(v)
+	}
+	if values[0] > 2 || (values[0] < 2 && values[1] >= 40) {
+		return nil, fmt.Errorf("invalid first arcs: %d.%d", values[0], values[1])
+	}
+
+	b := encodeArc(nil, values[0]*40+values[1])
+	for _, v := range values[2:] {
+		b = encodeArc(b, v)
+	}
+	return b, nil
+}
+
+// encodeArc appends the arc in base 128, the high bit set on all but its last octet
+func encodeArc(b []byte, v uint32) []byte {
+	var octets [5]byte
+	i := len(octets) - 1
+	octets[i] = byte(v & 0x7f)
+	for v >>= 7; v != 0; v >>= 7 {
+		i--
+		octets[i] = byte(v&0x7f) | 0x80
+	}
+	return append(b, octets[i:]...)
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package alarms
+
+import (
+	"log/syslog"
+)
+
+// syslogSink forwards the alarms to a remote syslog daemon
+type syslogSink struct {
+	network string
+	addr    string
+	tag     string
+	w       *syslog.Writer
+}
+
+func newSyslogSink(network string, addr string, tag string) *syslogSink {
+	return &syslogSink{network: network, addr: addr, tag: tag}
+}
+
+// Send writes the alarm at priority crit, the connection is made on the first alarm so an unreachable daemon
+// does not hold up the start of the controller; the writer reconnects by itself after that
+func (s *syslogSink) Send(a *Alarm) error {
+	if s.w == nil {
+		w, err := syslog.Dial(s.network, s.addr, syslog.LOG_CRIT|syslog.LOG_DAEMON, s.tag)
+		if err != nil {
+			return err
+		}
+		s.w = w
+	}
+	return s.w.Crit(a.String())
+}
+
+func (s *syslogSink) Close() error {
+	if s.w == nil {
+		return nil
+	}
+	return s.w.Close()
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eventbus streams the controller's events to a message bus, for
+// the OSS/BSS pipelines that follow the topology.  Events are queued and
+// published from a goroutine so a slow or unreachable bus never holds up
+// the wiring: when the queue is full the event is dropped and counted.
+// Each type of event has its own topic, the prefix and the type joined by a
+// '.', ie sfc-controller.wiring, which is both a valid Kafka topic and a
+// NATS subject.
+//
+// The publisher is picked by the scheme of the bus url:
+//
+//	nats://host:4222                   NATS, the client protocol is spoken directly
+//	kafka+http://host:8082             Kafka, through the Confluent REST proxy
+//	kafka+https://host:8082/some/path
+package eventbus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ligato/sfc-controller/controller/utils/logs"
+)
+
+// the types of events, each is published to its own topic
+const (
+	EventEntity     = "entity"     // an entity was created, updated or deleted
+	EventWiring     = "wiring"     // the render status of an entity
+	EventReconcile  = "reconcile"  // a reconcile of the agents started or ended
+	EventDrift      = "drift"      // the agents' config drifted from what was rendered
+	EventRecovery   = "recovery"   // an entity left partially rendered by a crash was wired again at startup
+	EventExpiry     = "expiry"     // a chain's ttl expired, it was unwired and deleted
+	EventDelete     = "delete"     // a chain was marked deleting, its deletion was cancelled, or it was unwired and deleted
+	EventFederation = "federation" // a federation peer told a new tunnel endpoint or vni of the inter-site segment
+)
+
+// DefaultQueueLength is the number of events that can wait to be published
+const DefaultQueueLength = 1024
+
+var log = logs.Logger(logs.Core)
+
+// Event is the message published for each event, as json
+type Event struct {
+	Time    int64  `json:"time"`
+	Type    string `json:"type"`
+	Action  string `json:"action"`
+	Kind    string `json:"kind,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Source  string `json:"source,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// Publisher sends a payload to a topic of a message bus
+type Publisher interface {
+	Publish(topic string, payload []byte) error
+	Close() error
+}
+
+// NewPublisher returns the publisher for the scheme of the bus url
+func NewPublisher(busURL string) (Publisher, error) {
+
+	u, err := url.Parse(busURL)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "nats":
+		if u.Host == "" {
+			return nil, fmt.Errorf("Invalid event bus url: '%s', missing host", busURL)
+		}
+		return newNatsPublisher(u.Host), nil
+	case "kafka+http", "kafka+https":
+		if u.Host == "" {
+			return nil, fmt.Errorf("Invalid event bus url: '%s', missing host", busURL)
+		}
+		u.Scheme = u.Scheme[len("kafka+"):]
+		return newKafkaRESTPublisher(u.String()), nil
+	default:
+		return nil, fmt.Errorf("Invalid event bus url: '%s', the scheme must be nats, kafka+http or kafka+https",
+			busURL)
+	}
+}
+
+// Stats are the counts of the events of a bus
+type Stats struct {
+	Published uint64 `json:"published"`
+	Dropped   uint64 `json:"dropped"` // the queue was full
+	Failed    uint64 `json:"failed"`  // the publisher returned an error
+}
+
+// Bus queues the events and publishes them in order
+type Bus struct {
+	pub         Publisher
+	topicPrefix string
+	queue       chan *Event
+	done        chan struct{}
+	closeOnce   sync.Once
+	published   uint64
+	dropped     uint64
+	failed      uint64
+}
+
+// New starts publishing the events emitted on the bus with the publisher
+func New(pub Publisher, topicPrefix string, queueLength int) *Bus {
+
+	if queueLength <= 0 {
+		queueLength = DefaultQueueLength
+	}
+	b := &Bus{
+		pub:         pub,
+		topicPrefix: topicPrefix,
+		queue:       make(chan *Event, queueLength),
+		done:        make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// Topic returns the topic the events of the type are published to
+func (b *Bus) Topic(eventType string) string {
+	if b.topicPrefix == "" {
+		return eventType
+	}
+	return b.topicPrefix + "." + eventType
+}
+
+// Emit queues the event, it does not block: the event is dropped if the queue is full
+func (b *Bus) Emit(ev Event) {
+	select {
+	case b.queue <- &ev:
+	default:
+		if atomic.AddUint64(&b.dropped, 1) == 1 {
+			log.Warnf("eventbus: queue full, dropping events")
+		}
+	}
+}
+
+// Stats returns the counts of the events published, dropped and failed so far
+func (b *Bus) Stats() Stats {
+	return Stats{
+		Published: atomic.LoadUint64(&b.published),
+		Dropped:   atomic.LoadUint64(&b.dropped),
+		Failed:    atomic.LoadUint64(&b.failed),
+	}
+}
+
+// Close publishes the events still queued, then closes the publisher
+func (b *Bus) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.queue)
+		<-b.done
+	})
+	return b.pub.Close()
+}
+
+func (b *Bus) run() {
+
+	defer close(b.done)
+
+	for ev := range b.queue {
+		payload, err := json.Marshal(ev)
+		if err != nil {
+			atomic.AddUint64(&b.failed, 1)
+			continue
+		}
+		if err := b.pub.Publish(b.Topic(ev.Type), payload); err != nil {
+			if atomic.AddUint64(&b.failed, 1) == 1 {
+				log.Errorf("eventbus: error publishing: %s", err)
+			}
+			continue
+		}
+		atomic.AddUint64(&b.published, 1)
+	}
+}
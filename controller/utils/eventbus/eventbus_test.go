@@ -0,0 +1,158 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blockingPublisher publishes when it is released, so the queue fills up
+type blockingPublisher struct {
+	release chan struct{}
+	topics  []string
+}
+
+func (bp *blockingPublisher) Publish(topic string, payload []byte) error {
+	<-bp.release
+	bp.topics = append(bp.topics, topic)
+	return nil
+}
+
+func (bp *blockingPublisher) Close() error { return nil }
+
+func TestBusDropsWhenFull(t *testing.T) {
+
+	pub := &blockingPublisher{release: make(chan struct{})}
+	b := New(pub, "sfc", 2)
+
+	// the first is taken by the publishing goroutine, two are queued, the rest are dropped
+	for i := 0; i < 10; i++ {
+		b.Emit(Event{Type: EventWiring, Name: "sfc1"})
+		time.Sleep(time.Millisecond)
+	}
+	close(pub.release)
+	b.Close()
+
+	stats := b.Stats()
+	if stats.Published != 3 || stats.Dropped != 7 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+	if pub.topics[0] != "sfc.wiring" {
+		t.Errorf("published to: '%s'", pub.topics[0])
+	}
+}
+
+func TestNewPublisherURLs(t *testing.T) {
+
+	for _, busURL := range []string{"nats://nats1:4222", "kafka+http://proxy:8082", "kafka+https://proxy/kafka"} {
+		if _, err := NewPublisher(busURL); err != nil {
+			t.Errorf("NewPublisher: '%s': %s", busURL, err)
+		}
+	}
+	for _, busURL := range []string{"http://proxy:8082", "nats://", "kafka+http:///x", "amqp://broker"} {
+		if _, err := NewPublisher(busURL); err == nil {
+			t.Errorf("NewPublisher: '%s' should be invalid", busURL)
+		}
+	}
+}
+
+func TestNatsPublish(t *testing.T) {
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+		r := bufio.NewReader(conn)
+		connect, _ := r.ReadString('\n')
+		if !strings.HasPrefix(connect, "CONNECT ") {
+			received <- "bad connect: " + connect
+			return
+		}
+		conn.Write([]byte("PING\r\n"))
+		pub, _ := r.ReadString('\n')
+		payload, _ := r.ReadString('\n')
+		pong, _ := r.ReadString('\n')
+		received <- pub + payload + pong
+	}()
+
+	np := newNatsPublisher(l.Addr().String())
+	defer np.Close()
+	if err := np.Publish("sfc.entity", []byte(`{"name":"sfc1"}`)); err != nil {
+		t.Fatalf("Publish: %s", err)
+	}
+
+	select {
+	case got := <-received:
+		for _, expected := range []string{"PUB sfc.entity 15\r\n", "{\"name\":\"sfc1\"}\r\n", "PONG\r\n"} {
+			if !strings.Contains(got, expected) {
+				t.Errorf("the server received: %q, expected: %q", got, expected)
+			}
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the server received nothing")
+	}
+}
+
+func TestKafkaRESTPublish(t *testing.T) {
+
+	var path, contentType string
+	var records kafkaRecords
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		path = req.URL.Path
+		contentType = req.Header.Get("Content-Type")
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &records)
+		if strings.HasSuffix(path, "/missing") {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	pub, err := NewPublisher("kafka+" + server.URL + "/proxy")
+	if err != nil {
+		t.Fatalf("NewPublisher: %s", err)
+	}
+	if err := pub.Publish("sfc.drift", []byte(`{"action":"missing_key"}`)); err != nil {
+		t.Fatalf("Publish: %s", err)
+	}
+	if path != "/proxy/topics/sfc.drift" || contentType != "application/vnd.kafka.json.v2+json" {
+		t.Errorf("unexpected request: path: '%s', content type: '%s'", path, contentType)
+	}
+	if len(records.Records) != 1 || string(records.Records[0].Value) != `{"action":"missing_key"}` {
+		t.Errorf("unexpected records: %+v", records)
+	}
+
+	if err := pub.Publish("missing", []byte(`{}`)); err == nil {
+		t.Error("expected an error for a 404 of the proxy")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const kafkaRESTTimeout = 10 * time.Second
+
+// kafkaRESTPublisher produces the messages to Kafka through the REST proxy, a POST of the records to
+// /topics/<topic> in the v2 json embedded format
+type kafkaRESTPublisher struct {
+	baseURL string
+	client  *http.Client
+}
+
+type kafkaRecord struct {
+	Value json.RawMessage `json:"value"`
+}
+
+type kafkaRecords struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+func newKafkaRESTPublisher(baseURL string) *kafkaRESTPublisher {
+	return &kafkaRESTPublisher{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		client:  &http.Client{Timeout: kafkaRESTTimeout},
+	}
+}
+
+func (kp *kafkaRESTPublisher) Publish(topic string, payload []byte) error {
+
+	body, err := json.Marshal(&kafkaRecords{Records: []kafkaRecord{{Value: payload}}})
+	if err != nil {
+		return err
+	}
+	u := kp.baseURL + "/topics/" + url.PathEscape(topic)
+	req, err := http.NewRequest("POST", u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	req.Header.Set("Accept", "application/vnd.kafka.v2+json")
+
+	resp, err := kp.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("kafka: POST %s: %s: %s", u, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+func (kp *kafkaRESTPublisher) Close() error {
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const natsDialTimeout = 5 * time.Second
+
+// natsPublisher publishes with the text protocol of NATS: CONNECT once, then a PUB per message, the server's
+// PINGs are answered by a reader goroutine.  The connection is dialed on the first publish and again after
+// an error.
+type natsPublisher struct {
+	addr string
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func newNatsPublisher(addr string) *natsPublisher {
+	return &natsPublisher{addr: addr}
+}
+
+func (np *natsPublisher) Publish(subject string, payload []byte) error {
+
+	np.mu.Lock()
+	defer np.mu.Unlock()
+
+	if np.conn == nil {
+		if err := np.connect(); err != nil {
+			return err
+		}
+	}
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\n", subject, len(payload), payload)
+	if _, err := np.conn.Write([]byte(msg)); err != nil {
+		np.conn.Close()
+		np.conn = nil
+		return err
+	}
+	return nil
+}
+
+// connect dials the server, reads its INFO and sends the CONNECT, np.mu is held
+func (np *natsPublisher) connect() error {
+
+	conn, err := net.DialTimeout("tcp", np.addr, natsDialTimeout)
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(natsDialTimeout))
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return fmt.Errorf("nats: '%s' did not send INFO: '%s'", np.addr, strings.TrimSpace(line))
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	if _, err := conn.Write([]byte("CONNECT {\"verbose\":false,\"pedantic\":false," +
+		"\"name\":\"sfc-controller\"}\r\n")); err != nil {
+		conn.Close()
+		return err
+	}
+
+	np.conn = conn
+	go np.read(conn, r)
+	return nil
+}
+
+// read answers the server's PINGs until the connection is closed
+func (np *natsPublisher) read(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			np.mu.Lock()
+			if np.conn == conn {
+				conn.Write([]byte("PONG\r\n"))
+			}
+			np.mu.Unlock()
+		case strings.HasPrefix(line, "-ERR"):
+			log.Errorf("eventbus: nats: %s", strings.TrimSpace(line))
+		}
+	}
+}
+
+func (np *natsPublisher) Close() error {
+
+	np.mu.Lock()
+	defer np.mu.Unlock()
+
+	if np.conn == nil {
+		return nil
+	}
+	err := np.conn.Close()
+	np.conn = nil
+	return err
+}
@@ -0,0 +1,252 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eventlog keeps the controller's recent events in memory so they
+// can be queried instead of dug out of the logs.  An event identical to one
+// recorded within the dedup window, ie the same drift found by every
+// periodic check, is not recorded again: the count and the last time of the
+// recorded one are bumped.  New events are rate limited by a token bucket,
+// the ones over the rate are dropped and counted.  Events older than the
+// retention are expired, and the oldest are dropped once the log is full.
+package eventlog
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/utils/eventbus"
+)
+
+// the defaults of the policy
+const (
+	DefaultMaxEvents   = 1000
+	DefaultRetention   = 24 * time.Hour
+	DefaultDedupWindow = 5 * time.Minute
+	DefaultRate        = 50 // new events per second
+	DefaultBurst       = 200
+)
+
+// Policy is the retention, the size limit, the dedup window and the rate limit of a log
+type Policy struct {
+	MaxEvents   int
+	Retention   time.Duration
+	DedupWindow time.Duration
+	Rate        float64 // new events per second, 0 is not limited
+	Burst       int
+}
+
+// DefaultPolicy returns the policy used when none is configured
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxEvents:   DefaultMaxEvents,
+		Retention:   DefaultRetention,
+		DedupWindow: DefaultDedupWindow,
+		Rate:        DefaultRate,
+		Burst:       DefaultBurst,
+	}
+}
+
+// Entry is a recorded event, Count is the number of times it was seen between FirstTime and LastTime
+type Entry struct {
+	eventbus.Event
+	Seq       uint64 `json:"seq"`
+	Count     uint32 `json:"count"`
+	FirstTime int64  `json:"first_time"`
+	LastTime  int64  `json:"last_time"`
+}
+
+// Filter selects the entries of a query, the fields that are not set match every entry
+type Filter struct {
+	Type  string
+	Kind  string
+	Name  string
+	Since int64 // unix time, the entries last seen at or after it
+	Limit int   // the most recent ones
+}
+
+// Stats are the counts of the events of a log
+type Stats struct {
+	Recorded     uint64 `json:"recorded"`
+	Deduplicated uint64 `json:"deduplicated"`
+	RateLimited  uint64 `json:"rate_limited"`
+	Expired      uint64 `json:"expired"` // older than the retention, or the oldest of a full log
+	Size         int    `json:"size"`
+}
+
+// Log is the in-memory store of the events
+type Log struct {
+	mu      sync.Mutex
+	policy  Policy
+	entries []*Entry          // in the order they were first recorded
+	latest  map[string]*Entry // the most recent entry of each identical event
+	seq     uint64
+	tokens  float64
+	refill  time.Time
+	stats   Stats
+	now     func() time.Time
+}
+
+// New returns an empty log with the policy, the limits not set in it, but the rate, are the defaults
+func New(policy Policy) *Log {
+
+	if policy.MaxEvents <= 0 {
+		policy.MaxEvents = DefaultMaxEvents
+	}
+	if policy.Retention <= 0 {
+		policy.Retention = DefaultRetention
+	}
+	if policy.DedupWindow <= 0 {
+		policy.DedupWindow = DefaultDedupWindow
+	}
+	if policy.Burst <= 0 {
+		policy.Burst = DefaultBurst
+	}
+	return &Log{
+		policy: policy,
+		latest: make(map[string]*Entry),
+		tokens: float64(policy.Burst),
+		now:    time.Now,
+	}
+}
+
+// eventIdentity is what makes two events identical, the time is not part of it
+func eventIdentity(ev *eventbus.Event) string {
+	return ev.Type + "\x00" + ev.Action + "\x00" + ev.Kind + "\x00" + ev.Name + "\x00" + ev.Source + "\x00" +
+		ev.Message
+}
+
+// Record adds the event, it returns false if the event was rate limited
+func (l *Log) Record(ev eventbus.Event) bool {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.expire(now)
+
+	identity := eventIdentity(&ev)
+	if entry, exists := l.latest[identity]; exists &&
+		now.Sub(time.Unix(entry.LastTime, 0)) <= l.policy.DedupWindow {
+		entry.Count++
+		entry.LastTime = now.Unix()
+		l.stats.Deduplicated++
+		return true
+	}
+
+	if !l.take(now) {
+		l.stats.RateLimited++
+		return false
+	}
+
+	l.seq++
+	ev.Time = now.Unix()
+	entry := &Entry{Event: ev, Seq: l.seq, Count: 1, FirstTime: now.Unix(), LastTime: now.Unix()}
+	l.entries = append(l.entries, entry)
+	l.latest[identity] = entry
+	l.stats.Recorded++
+
+	if len(l.entries) > l.policy.MaxEvents {
+		l.drop(len(l.entries) - l.policy.MaxEvents)
+	}
+	return true
+}
+
+// Query returns copies of the entries the filter selects, in the order they were first recorded
+func (l *Log) Query(filter Filter) []Entry {
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.expire(l.now())
+
+	entries := make([]Entry, 0)
+	for _, entry := range l.entries {
+		if (filter.Type != "" && entry.Type != filter.Type) ||
+			(filter.Kind != "" && entry.Kind != filter.Kind) ||
+			(filter.Name != "" && entry.Name != filter.Name) ||
+			entry.LastTime < filter.Since {
+			continue
+		}
+		entries = append(entries, *entry)
+	}
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries
+}
+
+// Stats returns the counts of the events recorded, deduplicated, rate limited and expired so far
+func (l *Log) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	stats := l.stats
+	stats.Size = len(l.entries)
+	return stats
+}
+
+// take consumes a token of the bucket, refilled at the rate since it was last taken from
+func (l *Log) take(now time.Time) bool {
+
+	if l.policy.Rate <= 0 {
+		return true
+	}
+	if !l.refill.IsZero() {
+		l.tokens += now.Sub(l.refill).Seconds() * l.policy.Rate
+		if l.tokens > float64(l.policy.Burst) {
+			l.tokens = float64(l.policy.Burst)
+		}
+	}
+	l.refill = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// expire removes the entries last seen before the retention
+func (l *Log) expire(now time.Time) {
+
+	cutoff := now.Add(-l.policy.Retention).Unix()
+	kept := l.entries[:0]
+	for _, entry := range l.entries {
+		if entry.LastTime < cutoff {
+			l.forget(entry)
+			l.stats.Expired++
+			continue
+		}
+		kept = append(kept, entry)
+	}
+	for i := len(kept); i < len(l.entries); i++ {
+		l.entries[i] = nil
+	}
+	l.entries = kept
+}
+
+// drop removes the n entries first recorded
+func (l *Log) drop(n int) {
+	for _, entry := range l.entries[:n] {
+		l.forget(entry)
+		l.stats.Expired++
+	}
+	l.entries = append([]*Entry(nil), l.entries[n:]...)
+}
+
+// forget removes the entry from the dedup index, unless a later identical event replaced it there
+func (l *Log) forget(entry *Entry) {
+	identity := eventIdentity(&entry.Event)
+	if l.latest[identity] == entry {
+		delete(l.latest, identity)
+	}
+}
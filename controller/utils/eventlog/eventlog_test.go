@@ -0,0 +1,155 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventlog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ligato/sfc-controller/controller/utils/eventbus"
+)
+
+// newTestLog returns a log whose clock is advanced by the returned func
+func newTestLog(policy Policy) (*Log, func(d time.Duration)) {
+	now := time.Unix(1500000000, 0)
+	l := New(policy)
+	l.now = func() time.Time { return now }
+	return l, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestLogDeduplicates(t *testing.T) {
+
+	l, advance := newTestLog(Policy{DedupWindow: time.Minute})
+	drift := eventbus.Event{Type: eventbus.EventDrift, Action: "missing", Name: "/vnf-agent/h1/k"}
+
+	for i := 0; i < 5; i++ {
+		l.Record(drift)
+		advance(10 * time.Second)
+	}
+	l.Record(eventbus.Event{Type: eventbus.EventDrift, Action: "missing", Name: "/vnf-agent/h2/k"})
+
+	entries := l.Query(Filter{})
+	if len(entries) != 2 || entries[0].Count != 5 || entries[1].Count != 1 {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if entries[0].LastTime-entries[0].FirstTime != 40 {
+		t.Errorf("unexpected first/last time: %+v", entries[0])
+	}
+
+	// once the window since it was last seen has passed, the event is recorded again
+	advance(2 * time.Minute)
+	l.Record(drift)
+	if entries := l.Query(Filter{Name: drift.Name}); len(entries) != 2 || entries[1].Count != 1 {
+		t.Errorf("unexpected entries after the dedup window: %+v", entries)
+	}
+	if stats := l.Stats(); stats.Recorded != 3 || stats.Deduplicated != 4 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestLogRateLimits(t *testing.T) {
+
+	l, advance := newTestLog(Policy{Rate: 2, Burst: 3})
+
+	recorded := 0
+	for i := 0; i < 10; i++ {
+		if l.Record(eventbus.Event{Type: eventbus.EventWiring, Name: fmt.Sprintf("sfc%d", i)}) {
+			recorded++
+		}
+	}
+	if recorded != 3 {
+		t.Errorf("burst: recorded %d, expected 3", recorded)
+	}
+
+	advance(time.Second)
+	if !l.Record(eventbus.Event{Type: eventbus.EventWiring, Name: "a"}) ||
+		!l.Record(eventbus.Event{Type: eventbus.EventWiring, Name: "b"}) ||
+		l.Record(eventbus.Event{Type: eventbus.EventWiring, Name: "c"}) {
+		t.Error("the bucket is not refilled at the rate")
+	}
+	// an event identical to a recorded one is counted even when the bucket is empty
+	if !l.Record(eventbus.Event{Type: eventbus.EventWiring, Name: "a"}) {
+		t.Error("a duplicate is rate limited")
+	}
+	if stats := l.Stats(); stats.RateLimited != 8 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestLogRetention(t *testing.T) {
+
+	l, advance := newTestLog(Policy{MaxEvents: 3, Retention: time.Hour, DedupWindow: time.Hour})
+
+	for i := 0; i < 5; i++ {
+		l.Record(eventbus.Event{Type: eventbus.EventEntity, Kind: "SFC", Name: fmt.Sprintf("sfc%d", i)})
+	}
+	entries := l.Query(Filter{})
+	if len(entries) != 3 || entries[0].Name != "sfc2" {
+		t.Fatalf("the oldest are not dropped: %+v", entries)
+	}
+
+	advance(30 * time.Minute)
+	l.Record(eventbus.Event{Type: eventbus.EventEntity, Kind: "SFC", Name: "sfc4"})
+	advance(45 * time.Minute)
+	entries = l.Query(Filter{})
+	if len(entries) != 1 || entries[0].Name != "sfc4" || entries[0].Count != 2 {
+		t.Errorf("the expired are not removed: %+v", entries)
+	}
+	if stats := l.Stats(); stats.Expired != 4 || stats.Size != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+
+	// the dedup index is forgotten with the entry
+	advance(2 * time.Hour)
+	l.Record(eventbus.Event{Type: eventbus.EventEntity, Kind: "SFC", Name: "sfc4"})
+	if entries := l.Query(Filter{}); len(entries) != 1 || entries[0].Count != 1 {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
+
+func TestLogQuery(t *testing.T) {
+
+	l, advance := newTestLog(Policy{})
+
+	l.Record(eventbus.Event{Type: eventbus.EventEntity, Kind: "HE", Name: "h1"})
+	advance(time.Minute)
+	l.Record(eventbus.Event{Type: eventbus.EventWiring, Kind: "SFC", Name: "s1"})
+	l.Record(eventbus.Event{Type: eventbus.EventWiring, Kind: "SFC", Name: "s2"})
+	l.Record(eventbus.Event{Type: eventbus.EventWiring, Kind: "HE", Name: "h1"})
+
+	for _, test := range []struct {
+		filter Filter
+		names  string
+	}{
+		{Filter{}, "h1,s1,s2,h1"},
+		{Filter{Type: eventbus.EventWiring}, "s1,s2,h1"},
+		{Filter{Kind: "HE"}, "h1,h1"},
+		{Filter{Name: "s2"}, "s2"},
+		{Filter{Since: l.now().Unix()}, "s1,s2,h1"},
+		{Filter{Limit: 2}, "s2,h1"},
+	} {
+		names := ""
+		for i, entry := range l.Query(test.filter) {
+			if i != 0 {
+				names += ","
+			}
+			names += entry.Name
+		}
+		if names != test.names {
+			t.Errorf("filter: %+v: got: %s, expected: %s", test.filter, names, test.names)
+		}
+	}
+}
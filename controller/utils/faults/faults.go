@@ -0,0 +1,166 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package faults is the fault injection layer used to exercise the
+// controller's resilience in CI.  Once configured, writes to the datastore
+// and calls to the external entity drivers are delayed, and fail at the
+// configured rate, as if etcd or the external routers misbehaved.  It is off
+// unless a config is set, and must never be turned on in production.
+package faults
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/utils/revisions"
+)
+
+// Config is the fault injection settings, a rate is the probability, 0 to 1, that a call fails
+type Config struct {
+	PutErrorRate float64 `json:"put_error_rate,omitempty"` // datastore puts, deletes and txn commits
+	PutLatencyMs uint32  `json:"put_latency_ms,omitempty"`
+	EEErrorRate  float64 `json:"ee_error_rate,omitempty"` // external entity driver calls
+	EELatencyMs  uint32  `json:"ee_latency_ms,omitempty"`
+	Seed         int64   `json:"seed,omitempty"` // optional, makes the failures repeatable, default is the time
+}
+
+var (
+	mutex  sync.Mutex
+	config Config
+	random = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// SetConfig checks then applies the settings, an empty config turns fault injection off
+func SetConfig(c *Config) error {
+
+	for _, rate := range []float64{c.PutErrorRate, c.EEErrorRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("fault error rate %v is not between 0 and 1", rate)
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	config = *c
+	if c.Seed != 0 {
+		random = rand.New(rand.NewSource(c.Seed))
+	}
+
+	return nil
+}
+
+// GetConfig returns the current settings
+func GetConfig() *Config {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	c := config
+	return &c
+}
+
+// inject delays the call then decides whether it fails
+func inject(errorRate float64, latencyMs uint32, call string) error {
+
+	mutex.Lock()
+	failed := errorRate > 0 && random.Float64() < errorRate
+	mutex.Unlock()
+
+	if latencyMs != 0 {
+		time.Sleep(time.Duration(latencyMs) * time.Millisecond)
+	}
+	if failed {
+		return fmt.Errorf("injected fault: %s", call)
+	}
+	return nil
+}
+
+func injectPut(call string) error {
+	c := GetConfig()
+	return inject(c.PutErrorRate, c.PutLatencyMs, call)
+}
+
+// EECall is called by the external entity drivers before each operation, it returns the injected error
+func EECall(call string) error {
+	c := GetConfig()
+	return inject(c.EEErrorRate, c.EELatencyMs, call)
+}
+
+// WrapBrokerFactory returns a factory whose brokers have the datastore faults injected
+func WrapBrokerFactory(dbFactory func(string) keyval.ProtoBroker) func(string) keyval.ProtoBroker {
+	return func(prefix string) keyval.ProtoBroker {
+		return WrapBroker(dbFactory(prefix))
+	}
+}
+
+// WrapBroker returns the broker with the datastore faults injected, reads are passed through
+func WrapBroker(broker keyval.ProtoBroker) keyval.ProtoBroker {
+	return &faultyBroker{ProtoBroker: broker}
+}
+
+type faultyBroker struct {
+	keyval.ProtoBroker
+}
+
+func (b *faultyBroker) Put(key string, value proto.Message, opts ...datasync.PutOption) error {
+	if err := injectPut("put " + key); err != nil {
+		return err
+	}
+	return b.ProtoBroker.Put(key, value, opts...)
+}
+
+func (b *faultyBroker) PutIfRevision(key string, value proto.Message, revision int64) (bool, error) {
+	if err := injectPut("put " + key); err != nil {
+		return false, err
+	}
+	return revisions.PutIfRevision(b.ProtoBroker, key, value, revision)
+}
+
+func (b *faultyBroker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	if err := injectPut("delete " + key); err != nil {
+		return false, err
+	}
+	return b.ProtoBroker.Delete(key, opts...)
+}
+
+func (b *faultyBroker) NewTxn() keyval.ProtoTxn {
+	return &faultyTxn{ProtoTxn: b.ProtoBroker.NewTxn()}
+}
+
+type faultyTxn struct {
+	keyval.ProtoTxn
+}
+
+func (t *faultyTxn) Put(key string, value proto.Message) keyval.ProtoTxn {
+	t.ProtoTxn.Put(key, value)
+	return t
+}
+
+func (t *faultyTxn) Delete(key string) keyval.ProtoTxn {
+	t.ProtoTxn.Delete(key)
+	return t
+}
+
+func (t *faultyTxn) Commit() error {
+	if err := injectPut("txn commit"); err != nil {
+		return err
+	}
+	return t.ProtoTxn.Commit()
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package faults
+
+import (
+	"testing"
+
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+func putFailures(t *testing.T, c *Config, puts int) []bool {
+	if err := SetConfig(c); err != nil {
+		t.Fatal(err)
+	}
+	defer SetConfig(&Config{})
+
+	broker := WrapBroker(membroker.New())
+	failures := make([]bool, puts)
+	for i := range failures {
+		failures[i] = broker.Put("/key", &controller.EntityKeys{Name: "key"}) != nil
+	}
+	return failures
+}
+
+func TestPutErrorRate(t *testing.T) {
+	for _, failed := range putFailures(t, &Config{}, 100) {
+		if failed {
+			t.Fatal("put failed with fault injection off")
+		}
+	}
+	for _, failed := range putFailures(t, &Config{PutErrorRate: 1}, 100) {
+		if !failed {
+			t.Fatal("put succeeded with an error rate of 1")
+		}
+	}
+}
+
+func TestSeedIsRepeatable(t *testing.T) {
+	first := putFailures(t, &Config{PutErrorRate: 0.5, Seed: 42}, 100)
+	second := putFailures(t, &Config{PutErrorRate: 0.5, Seed: 42}, 100)
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("put %d: failures differ with the same seed", i)
+		}
+	}
+}
+
+func TestBadErrorRate(t *testing.T) {
+	if err := SetConfig(&Config{EEErrorRate: 1.5}); err == nil {
+		t.Fatal("error rate 1.5 accepted")
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package features is the registry of feature flags.  A wiring behavior that
+// is rolled out incrementally registers a flag with its default, then the
+// flag can be turned on or off for the whole deployment in the system
+// parameters, or for individual entities in their feature_flags.
+package features
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Feature is a registered flag
+type Feature struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Default     bool   `json:"default"`
+}
+
+var (
+	mutex    sync.Mutex
+	registry = make(map[string]Feature)
+)
+
+// Register adds a flag to the registry and returns its name, it is meant to be called when the package
+// implementing the feature is initialized so a name can only be registered once
+func Register(name string, description string, dflt bool) string {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if _, exists := registry[name]; exists {
+		panic(fmt.Errorf("feature flag '%s' is already registered", name))
+	}
+	registry[name] = Feature{Name: name, Description: description, Default: dflt}
+
+	return name
+}
+
+// List returns the registered flags sorted by name
+func List() []Feature {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	list := make([]Feature, 0, len(registry))
+	for _, feature := range registry {
+		list = append(list, feature)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	return list
+}
+
+// Validate ensures every flag in the set is registered
+func Validate(flags map[string]bool) error {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	for name := range flags {
+		if _, exists := registry[name]; !exists {
+			return fmt.Errorf("unknown feature flag: '%s'", name)
+		}
+	}
+
+	return nil
+}
+
+// Enabled resolves a flag from the sets, most specific first, ie entity flags then system flags, the
+// first set that has the flag decides, else the flag's default is used
+func Enabled(name string, flagSets ...map[string]bool) bool {
+
+	for _, flags := range flagSets {
+		if enabled, exists := flags[name]; exists {
+			return enabled
+		}
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	return registry[name].Default
+}
@@ -0,0 +1,223 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hooks runs the controller's writes to the agents through a chain
+// of site specific hooks before they are written.  A hook sees each object
+// the driver wires or unwires, it may change the object, ie to add tags or
+// extra routes, or veto the write, ie for a compliance check.  A vetoed
+// write fails like a datastore error does, so the entity that rendered it
+// is in error.  The hooks are Go plugins loaded at startup, see plugin.go,
+// or webhooks, see webhook.go.  The controller's own records are not hooked.
+package hooks
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
+	"github.com/ligato/sfc-controller/controller/utils/revisions"
+)
+
+const (
+	// OpPut is the operation of an object wired to an agent
+	OpPut = "put"
+	// OpDelete is the operation of an object unwired from an agent, it has no value
+	OpDelete = "delete"
+)
+
+var log = logs.Logger(logs.Core)
+
+// Op is a write to an agent, a hook may replace or change its value
+type Op struct {
+	Op       string        `json:"op"`
+	Key      string        `json:"key"`
+	VppLabel string        `json:"vpp_label"`
+	Value    proto.Message `json:"-"`
+}
+
+// Hook is called with each write to an agent before it is written, an error vetoes the write
+type Hook func(op *Op) error
+
+// VetoError is the error of a write vetoed by a hook
+type VetoError struct {
+	Hook   string
+	Key    string
+	Reason string
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("write of key: '%s' vetoed by hook: '%s': %s", e.Key, e.Hook, e.Reason)
+}
+
+type namedHook struct {
+	name string
+	hook Hook
+}
+
+// Chain is the hooks the writes go through, in the order they were added
+type Chain struct {
+	sync.RWMutex
+	hooks []namedHook
+}
+
+// NewChain returns a chain without hooks, the writes go through it unchanged
+func NewChain() *Chain {
+	return &Chain{}
+}
+
+// Add appends the hook to the chain
+func (c *Chain) Add(name string, hook Hook) {
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.hooks = append(c.hooks, namedHook{name: name, hook: hook})
+}
+
+// Names returns the names of the hooks, in chain order
+func (c *Chain) Names() []string {
+
+	c.RLock()
+	defer c.RUnlock()
+
+	names := make([]string, 0, len(c.hooks))
+	for _, h := range c.hooks {
+		names = append(names, h.name)
+	}
+	return names
+}
+
+// Run calls the hooks in chain order, the first one that vetoes the write stops it
+func (c *Chain) Run(op *Op) error {
+
+	c.RLock()
+	defer c.RUnlock()
+
+	for _, h := range c.hooks {
+		if err := h.hook(op); err != nil {
+			log.Warnf("Run: hook: '%s' vetoed %s of key: '%s': %s", h.name, op.Op, op.Key, err)
+			return &VetoError{Hook: h.name, Key: op.Key, Reason: err.Error()}
+		}
+		if op.Op == OpPut && op.Value == nil {
+			return &VetoError{Hook: h.name, Key: op.Key, Reason: "the value was removed"}
+		}
+	}
+	return nil
+}
+
+// WrapBrokerFactory returns a factory whose brokers run the writes to the agents through the chain
+func (c *Chain) WrapBrokerFactory(dbFactory func(string) keyval.ProtoBroker) func(string) keyval.ProtoBroker {
+
+	return func(prefix string) keyval.ProtoBroker {
+		return &hookedBroker{ProtoBroker: dbFactory(prefix), prefix: prefix, chain: c}
+	}
+}
+
+type hookedBroker struct {
+	keyval.ProtoBroker
+	prefix string
+	chain  *Chain
+}
+
+// run runs a write to an agent through the chain, the value to write is returned, the writes to the
+// controller's own records are returned as they are.  The hooks change a copy of the value, the driver's
+// caches are left as they were rendered
+func (b *hookedBroker) run(opName string, key string, value proto.Message) (proto.Message, error) {
+
+	if !strings.HasPrefix(b.prefix+key, utils.GetVppAgentPrefix()) {
+		return value, nil
+	}
+	if value != nil {
+		value = proto.Clone(value)
+	}
+	op := &Op{
+		Op:       opName,
+		Key:      b.prefix + key,
+		VppLabel: utils.GetVppEtcdlabel(b.prefix + key),
+		Value:    value,
+	}
+	if err := b.chain.Run(op); err != nil {
+		return nil, err
+	}
+	return op.Value, nil
+}
+
+func (b *hookedBroker) Put(key string, value proto.Message, opts ...datasync.PutOption) error {
+	value, err := b.run(OpPut, key, value)
+	if err != nil {
+		return err
+	}
+	return b.ProtoBroker.Put(key, value, opts...)
+}
+
+func (b *hookedBroker) PutIfRevision(key string, value proto.Message, revision int64) (bool, error) {
+	value, err := b.run(OpPut, key, value)
+	if err != nil {
+		return false, err
+	}
+	return revisions.PutIfRevision(b.ProtoBroker, key, value, revision)
+}
+
+func (b *hookedBroker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	if _, err := b.run(OpDelete, key, nil); err != nil {
+		return false, err
+	}
+	return b.ProtoBroker.Delete(key, opts...)
+}
+
+func (b *hookedBroker) NewTxn() keyval.ProtoTxn {
+	return &hookedTxn{ProtoTxn: b.ProtoBroker.NewTxn(), broker: b}
+}
+
+// hookedTxn runs each write through the chain as it is added, the txn is not committed if one is vetoed
+type hookedTxn struct {
+	keyval.ProtoTxn
+	broker *hookedBroker
+	err    error
+}
+
+func (t *hookedTxn) Put(key string, value proto.Message) keyval.ProtoTxn {
+	value, err := t.broker.run(OpPut, key, value)
+	if err != nil {
+		if t.err == nil {
+			t.err = err
+		}
+		return t
+	}
+	t.ProtoTxn.Put(key, value)
+	return t
+}
+
+func (t *hookedTxn) Delete(key string) keyval.ProtoTxn {
+	if _, err := t.broker.run(OpDelete, key, nil); err != nil {
+		if t.err == nil {
+			t.err = err
+		}
+		return t
+	}
+	t.ProtoTxn.Delete(key)
+	return t
+}
+
+func (t *hookedTxn) Commit() error {
+	if t.err != nil {
+		return t.err
+	}
+	return t.ProtoTxn.Commit()
+}
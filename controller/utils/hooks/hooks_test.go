@@ -0,0 +1,145 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+func TestChainMutatesAndVetoes(t *testing.T) {
+
+	chain := NewChain()
+	chain.Add("tag", func(op *Op) error {
+		if op.Op == OpPut {
+			op.Value.(*controller.EntityKeys).Keys = append(op.Value.(*controller.EntityKeys).Keys, "site=a")
+		}
+		return nil
+	})
+	chain.Add("compliance", func(op *Op) error {
+		if strings.HasSuffix(op.Key, "/FORBIDDEN") {
+			return fmt.Errorf("not compliant")
+		}
+		return nil
+	})
+
+	mem := membroker.New()
+	broker := chain.WrapBrokerFactory(mem.NewBroker)(keyval.Root)
+
+	agentKey := "/vnf-agent/HOST1/vpp/config/v1/interface/IF1"
+	rendered := &controller.EntityKeys{Name: "if1"}
+	if err := broker.Put(agentKey, rendered); err != nil {
+		t.Fatal(err)
+	}
+	value := &controller.EntityKeys{}
+	if found, _, _ := mem.GetValue(agentKey, value); !found || len(value.Keys) != 1 || value.Keys[0] != "site=a" {
+		t.Errorf("the hook's change was not written: %v", value)
+	}
+	if len(rendered.Keys) != 0 {
+		t.Errorf("the hook changed the rendered object: %v", rendered)
+	}
+
+	ownKey := "/sfc-controller/v1/id/H2H/HOST1_HOST2"
+	if err := broker.Put(ownKey, &controller.EntityKeys{Name: "id"}); err != nil {
+		t.Fatal(err)
+	}
+	value = &controller.EntityKeys{}
+	if found, _, _ := mem.GetValue(ownKey, value); !found || len(value.Keys) != 0 {
+		t.Errorf("the controller's own record was hooked: %v", value)
+	}
+
+	err := broker.Put("/vnf-agent/HOST1/vpp/config/v1/interface/FORBIDDEN", &controller.EntityKeys{})
+	if veto, ok := err.(*VetoError); !ok || veto.Hook != "compliance" {
+		t.Errorf("the write was not vetoed by the compliance hook: %v", err)
+	}
+	if _, err := broker.Delete("/vnf-agent/HOST1/vpp/config/v1/interface/FORBIDDEN"); err == nil {
+		t.Error("the delete was not vetoed")
+	}
+
+	// a txn with a vetoed write is not committed at all
+	txn := broker.NewTxn()
+	txn.Put("/vnf-agent/HOST1/vpp/config/v1/interface/IF2", &controller.EntityKeys{})
+	txn.Put("/vnf-agent/HOST1/vpp/config/v1/interface/FORBIDDEN", &controller.EntityKeys{})
+	if err := txn.Commit(); err == nil {
+		t.Error("the txn with a vetoed write was committed")
+	}
+	if _, exists := mem.Dump("/")["/vnf-agent/HOST1/vpp/config/v1/interface/IF2"]; exists {
+		t.Error("a write of the vetoed txn was written")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+
+	var request WebhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := ioutil.ReadAll(req.Body)
+		json.Unmarshal(body, &request)
+		switch {
+		case strings.HasSuffix(request.Key, "/DENIED"):
+			w.Write([]byte(`{"allowed":false,"reason":"denied by policy"}`))
+		case strings.HasSuffix(request.Key, "/BROKEN"):
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"allowed":true,"value":{"name":"if1","keys":["tagged"]}}`))
+		}
+	}))
+	defer server.Close()
+
+	hook := NewWebhook(server.URL)
+
+	op := &Op{Op: OpPut, Key: "/vnf-agent/HOST1/vpp/config/v1/interface/IF1", VppLabel: "HOST1",
+		Value: &controller.EntityKeys{Name: "if1"}}
+	if err := hook(op); err != nil {
+		t.Fatal(err)
+	}
+	if request.VppLabel != "HOST1" || string(request.Value) != `{"name":"if1"}` {
+		t.Errorf("unexpected request: %+v", request)
+	}
+	if value := op.Value.(*controller.EntityKeys); len(value.Keys) != 1 || value.Keys[0] != "tagged" {
+		t.Errorf("the webhook's value did not replace the object: %v", op.Value)
+	}
+
+	op = &Op{Op: OpPut, Key: "/vnf-agent/HOST1/vpp/config/v1/interface/DENIED", Value: &controller.EntityKeys{}}
+	if err := hook(op); err == nil || err.Error() != "denied by policy" {
+		t.Errorf("unexpected error for a denied write: %v", err)
+	}
+	op = &Op{Op: OpDelete, Key: "/vnf-agent/HOST1/vpp/config/v1/interface/BROKEN"}
+	if err := hook(op); err == nil {
+		t.Error("a failing webhook did not veto the write")
+	}
+}
+
+func TestChainFromTargets(t *testing.T) {
+
+	chain, err := ChainFromTargets(" http://localhost:8080/hook, https://hooks.example.com/compliance ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := chain.Names(); len(names) != 2 || names[0] != "http://localhost:8080/hook" {
+		t.Errorf("unexpected hooks: %v", names)
+	}
+	if _, err := ChainFromTargets("/no/such/plugin.so"); err == nil {
+		t.Error("expected an error for a missing plugin")
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hooks
+
+import (
+	"fmt"
+	"plugin"
+	"strings"
+)
+
+// PluginSymbol is the name of the func(op *hooks.Op) error a Go plugin exports as its hook, the plugin must be
+// built against the same controller sources, with go build -buildmode=plugin
+const PluginSymbol = "RenderHook"
+
+// LoadPlugin opens the Go plugin and returns its hook
+func LoadPlugin(path string) (Hook, error) {
+
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, err
+	}
+	switch hook := sym.(type) {
+	case func(*Op) error:
+		return hook, nil
+	case *func(*Op) error:
+		return *hook, nil
+	}
+	return nil, fmt.Errorf("Invalid plugin: '%s', %s is a %T, not a func(*hooks.Op) error", path, PluginSymbol,
+		sym)
+}
+
+// ChainFromTargets returns the chain of the comma separated hooks, the http and https urls are webhooks, the
+// others the paths of Go plugins
+func ChainFromTargets(targets string) (*Chain, error) {
+
+	chain := NewChain()
+	for _, target := range strings.Split(targets, ",") {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			continue
+		}
+		if strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://") {
+			chain.Add(target, NewWebhook(target))
+			continue
+		}
+		hook, err := LoadPlugin(target)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid hook: '%s': %s", target, err)
+		}
+		chain.Add(target, hook)
+	}
+	return chain, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/db/keyval"
+)
+
+const webhookTimeout = 5 * time.Second
+
+var serializer = &keyval.SerializerJSON{}
+
+// WebhookRequest is the body of the POST to a webhook, the value is the object as it is written to the agent
+type WebhookRequest struct {
+	Op
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// WebhookResponse is the webhook's answer, a value replaces the object written, a put without one is written
+// as it is
+type WebhookResponse struct {
+	Allowed bool            `json:"allowed"`
+	Reason  string          `json:"reason,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+}
+
+// NewWebhook returns a hook that POSTs each write to the url, a webhook that cannot be reached or answers
+// with an error vetoes the write, so a compliance check is never skipped
+func NewWebhook(url string) Hook {
+
+	client := &http.Client{Timeout: webhookTimeout}
+
+	return func(op *Op) error {
+
+		request := &WebhookRequest{Op: *op}
+		if op.Value != nil {
+			data, err := serializer.Marshal(op.Value)
+			if err != nil {
+				return err
+			}
+			request.Value = data
+		}
+		body, err := json.Marshal(request)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode/100 != 2 {
+			return fmt.Errorf("POST %s: %s: %s", url, resp.Status, strings.TrimSpace(string(data)))
+		}
+		response := &WebhookResponse{}
+		if err := json.Unmarshal(data, response); err != nil {
+			return fmt.Errorf("POST %s: invalid response: %s", url, err)
+		}
+		if !response.Allowed {
+			return fmt.Errorf("%s", response.Reason)
+		}
+		if op.Op == OpPut && len(response.Value) != 0 {
+			value := proto.Clone(op.Value)
+			value.Reset()
+			if err := serializer.Unmarshal(response.Value, value); err != nil {
+				return fmt.Errorf("POST %s: invalid value: %s", url, err)
+			}
+			op.Value = value
+		}
+		return nil
+	}
+}
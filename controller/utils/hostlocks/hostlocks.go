@@ -0,0 +1,106 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package hostlocks serializes the work done on each host.  A lock is kept
+// per etcd vpp switch key, ie per agent, so the writes of a reconcile and of
+// a wiring change to one agent do not interleave, while the work on other
+// hosts proceeds.  The locks of several hosts are always taken in name order
+// so two callers locking overlapping hosts cannot deadlock, and the lock of
+// a host is dropped once nobody holds or waits on it.
+package hostlocks
+
+import (
+	"sort"
+	"sync"
+)
+
+// Manager holds the locks of the hosts
+type Manager struct {
+	mu    sync.Mutex
+	hosts map[string]*hostLock
+}
+
+type hostLock struct {
+	sync.Mutex
+	refs int // holders and waiters
+}
+
+// New returns a manager without any host locked
+func New() *Manager {
+	return &Manager{hosts: make(map[string]*hostLock)}
+}
+
+// Lock locks the hosts, blocking until they are all held, and returns the func unlocking them
+func (m *Manager) Lock(hosts ...string) (unlock func()) {
+
+	names := make([]string, 0, len(hosts))
+	seen := make(map[string]struct{}, len(hosts))
+	for _, host := range hosts {
+		if _, exists := seen[host]; !exists {
+			seen[host] = struct{}{}
+			names = append(names, host)
+		}
+	}
+	sort.Strings(names)
+
+	for _, host := range names {
+		m.acquire(host).Lock()
+	}
+
+	return func() {
+		for i := len(names) - 1; i >= 0; i-- {
+			m.release(names[i])
+		}
+	}
+}
+
+// Locked returns the hosts currently held or waited on
+func (m *Manager) Locked() []string {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var hosts []string
+	for host := range m.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+func (m *Manager) acquire(host string) *hostLock {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hl, exists := m.hosts[host]
+	if !exists {
+		hl = &hostLock{}
+		m.hosts[host] = hl
+	}
+	hl.refs++
+	return hl
+}
+
+func (m *Manager) release(host string) {
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	hl := m.hosts[host]
+	hl.Unlock()
+	if hl.refs--; hl.refs == 0 {
+		delete(m.hosts, host)
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostlocks
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLockSerializesAHost(t *testing.T) {
+
+	m := New()
+	unlock := m.Lock("HOST1")
+
+	locked := make(chan struct{})
+	go func() {
+		defer m.Lock("HOST1")()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("HOST1 locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("HOST1 not locked after it was unlocked")
+	}
+}
+
+func TestLockOtherHostsProceed(t *testing.T) {
+
+	m := New()
+	defer m.Lock("HOST1")()
+
+	locked := make(chan struct{})
+	go func() {
+		defer m.Lock("HOST2", "HOST3")()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("HOST2 and HOST3 blocked by HOST1")
+	}
+}
+
+func TestLockOverlappingHosts(t *testing.T) {
+
+	m := New()
+	var wg sync.WaitGroup
+	counts := make(map[string]int)
+	for i := 0; i < 50; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			defer m.Lock("HOST1", "HOST2")()
+			counts["HOST1"]++
+			counts["HOST2"]++
+		}()
+		go func() {
+			defer wg.Done()
+			defer m.Lock("HOST2", "HOST1", "HOST2")()
+			counts["HOST1"]++
+			counts["HOST2"]++
+		}()
+	}
+	wg.Wait()
+
+	if counts["HOST1"] != 100 || counts["HOST2"] != 100 {
+		t.Fatalf("lost updates: %v", counts)
+	}
+	if locked := m.Locked(); len(locked) != 0 {
+		t.Fatalf("hosts: %v still locked", locked)
+	}
+}
@@ -68,6 +68,29 @@ func (bm *Bitmap) FindFirstClear() uint32 {
 	return 0
 }
 
+// FindClearFrom returns the first clear bit of bits 1 to last, starting the search at start and wrapping
+// around to bit 1, or 0 if all are set
+func (bm *Bitmap) FindClearFrom(start uint32, last uint32) uint32 {
+	if last > bm.numBits {
+		last = bm.numBits
+	}
+	if start < 1 || start > last {
+		start = 1
+	}
+	for n := uint32(0); n < last; n++ {
+		bit := (start-1+n)%last + 1
+		if !bm.IsSet(bit) {
+			return bit
+		}
+	}
+	return 0
+}
+
+// NumBits returns the number of bits in the bitmap
+func (bm *Bitmap) NumBits() uint32 {
+	return bm.numBits
+}
+
 func (bm *Bitmap) String() string {
 	str := fmt.Sprintf("numBits: %d, bits:", bm.numBits)
 
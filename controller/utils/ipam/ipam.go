@@ -21,24 +21,31 @@
 // contained in other pools and the hierarchy can be "walked" to ensure
 // addresses are set and cleared across levels.  Also, might have to have
 // configurable address blocks per subnet so not allocating undesirable
-// addresses.
+// addresses.  The subnets may be ipv4 or ipv6, the id of an address is its
+// host part, which for a large ipv6 subnet is limited to its low order bits.
 package ipam
 
 import (
 	"fmt"
+	"hash/fnv"
 	"net"
 	"github.com/ligato/sfc-controller/controller/utils/ipam/bitmap"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
 )
 
+var log = logs.Logger(logs.IPAM)
+
 type ipamSubnet struct {
-	subnetStr     string // example form 10.5.3.0/24
-	ipNetworku32  uint32
-	ipMasku32     uint32
+	subnetStr     string // example form 10.5.3.0/24 or 2001:db8::/112
+	ipNetwork     *net.IPNet
 	numBitsInMask int
 	bm            *bitmap.Bitmap
-	ipNetwork     *net.IPNet
 }
 
+// the ids of an ipv6 subnet are its low order bits, at most this many, a larger subnet is allocated from its
+// first 2**16 addresses
+const maxIpv6IDBits = 16
+
 var ipamSubnetCache map[string]*ipamSubnet = make(map[string]*ipamSubnet)
 
 func AllocateFromSubnet(ipamSubnetStr string) (string, uint32, error) {
@@ -51,11 +58,59 @@ func AllocateFromSubnet(ipamSubnetStr string) (string, uint32, error) {
 	if !exists {
 		ipamSubnet, err = newIPAMSubnet(ipamSubnetStr)
 		if err != nil {
+			log.Errorf("AllocateFromSubnet: subnet '%s': %s", ipamSubnetStr, err)
 			return "", 0, err
 		}
 		ipamSubnetCache[ipamSubnetStr] = ipamSubnet
 	}
-	return ipamSubnet.allocateFromSubnet()
+	ipAddrStr, ipID, err := ipamSubnet.allocateFromSubnet()
+	if err != nil {
+		log.Error(err)
+		return "", 0, err
+	}
+	log.Debugf("AllocateFromSubnet: subnet '%s': allocated '%s'", ipamSubnetStr, ipAddrStr)
+	return ipAddrStr, ipID, nil
+}
+
+// AllocateHashedFromSubnet allocates the address derived from the hash of name, or the next free one after
+// it, so the address depends on the name and not on the order of the allocations
+func AllocateHashedFromSubnet(ipamSubnetStr string, name string) (string, uint32, error) {
+
+	var ipamSubnet *ipamSubnet
+	var exists bool
+	var err error
+
+	ipamSubnet, exists = ipamSubnetCache[ipamSubnetStr]
+	if !exists {
+		ipamSubnet, err = newIPAMSubnet(ipamSubnetStr)
+		if err != nil {
+			log.Errorf("AllocateHashedFromSubnet: subnet '%s': %s", ipamSubnetStr, err)
+			return "", 0, err
+		}
+		ipamSubnetCache[ipamSubnetStr] = ipamSubnet
+	}
+
+	// the last id is the broadcast address, unless the subnet is a single address
+	last := ipamSubnet.bm.NumBits()
+	if last > 1 {
+		last--
+	}
+	if last == 0 {
+		return "", 0, fmt.Errorf("AllocateHashedFromSubnet: no addresses in '%s", ipamSubnetStr)
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	freeBit := ipamSubnet.bm.FindClearFrom(h.Sum32()%last+1, last)
+	if freeBit == 0 {
+		return "", 0, fmt.Errorf("AllocateHashedFromSubnet: all addresses allocated in '%s", ipamSubnetStr)
+	}
+	ipAddrStr, err := ipamSubnet.setIpIDInSubnet(freeBit)
+	if err != nil {
+		return "", 0, err
+	}
+	log.Debugf("AllocateHashedFromSubnet: subnet '%s': allocated '%s' for '%s'", ipamSubnetStr, ipAddrStr, name)
+	return ipAddrStr, freeBit, nil
 }
 
 func SetIpIDInSubnet(ipamSubnetStr string, ipID uint32) (string, error) {
@@ -85,6 +140,7 @@ func SetIpAddrIfInsideSubnet(ipamSubnetStr string, ipAddress string) {
 	if !exists {
 		ipamSubnet, err = newIPAMSubnet(ipamSubnetStr)
 		if err != nil {
+			log.Errorf("SetIpAddrIfInsideSubnet: subnet '%s': %s", ipamSubnetStr, err)
 			return
 		}
 		ipamSubnetCache[ipamSubnetStr] = ipamSubnet
@@ -121,18 +177,27 @@ func (ipamSubnet *ipamSubnet) setIpIDInSubnet(ipID uint32) (string, error) {
 		return "", fmt.Errorf("setIpIDInSubnet: ipID(%d) not in subnet '%s", ipID, ipamSubnet.subnetStr)
 	}
 
-	ipAddru32 := ipamSubnet.ipNetworku32 | ipID
-	ipAddrStr := fmt.Sprintf("%d.", (ipAddru32>>24) & 0xFF) +
-		fmt.Sprintf("%d.", (ipAddru32>>16) & 0xFF) +
-		fmt.Sprintf("%d.", (ipAddru32>>8) & 0xFF) +
-		fmt.Sprintf("%d", (ipAddru32) & 0xFF) +
-		fmt.Sprintf("/%d", ipamSubnet.numBitsInMask)
+	ipAddrStr := ipamSubnet.ipAddrStr(ipID)
 
 	//fmt.Println("setIpIDInSubnet: ", ipAddrStr)
 
 	return ipAddrStr, nil
 }
 
+// ipAddrStr returns the address of the id in the subnet, with the subnet's prefix length
+func (ipamSubnet *ipamSubnet) ipAddrStr(ipID uint32) string {
+
+	ip := make(net.IP, len(ipamSubnet.ipNetwork.IP))
+	copy(ip, ipamSubnet.ipNetwork.IP)
+	n := len(ip)
+	ip[n-4] |= byte(ipID >> 24)
+	ip[n-3] |= byte(ipID >> 16)
+	ip[n-2] |= byte(ipID >> 8)
+	ip[n-1] |= byte(ipID)
+
+	return fmt.Sprintf("%s/%d", ip, ipamSubnet.numBitsInMask)
+}
+
 func (ipamSubnet *ipamSubnet) setIpAddrIfInsideSubnet(ipAddressStr string) {
 
 	// see if this address falls within the subnet, and if it does, set the addr in the bitmap
@@ -141,8 +206,13 @@ func (ipamSubnet *ipamSubnet) setIpAddrIfInsideSubnet(ipAddressStr string) {
 
 		//fmt.Println("setIpAddrIfInsideSubnet: ", ipAddress)
 		ip := ipAddress.To4()
-		ipAddressu32 := uint32(ip[0])<<24 | uint32(ip[1])<<16 | uint32(ip[2])<<8 | uint32(ip[3])
-		ipID := ipAddressu32 &^ ipamSubnet.ipMasku32
+		if ip == nil {
+			ip = ipAddress.To16()
+		}
+		n := len(ip)
+		mask := ipamSubnet.ipNetwork.Mask
+		ipID := uint32(ip[n-4]&^mask[n-4])<<24 | uint32(ip[n-3]&^mask[n-3])<<16 |
+			uint32(ip[n-2]&^mask[n-2])<<8 | uint32(ip[n-1]&^mask[n-1])
 		//fmt.Println("setIpAddrIfInsideSubnet: ipID", ipID)
 		ipamSubnet.setIpIDInSubnet(ipID)
 	}
@@ -156,13 +226,7 @@ func (ipamSubnet *ipamSubnet) allocateFromSubnet() (string, uint32, error) {
 
 	ipamSubnet.bm.Set(freeBit)
 
-	ipAddru32 := ipamSubnet.ipNetworku32 | freeBit
-
-	ipAddrStr := fmt.Sprintf("%d.", (ipAddru32>>24) & 0xFF) +
-		fmt.Sprintf("%d.", (ipAddru32>>16) & 0xFF) +
-		fmt.Sprintf("%d.", (ipAddru32>>8) & 0xFF) +
-		fmt.Sprintf("%d", (ipAddru32) & 0xFF) +
-		fmt.Sprintf("/%d", ipamSubnet.numBitsInMask)
+	ipAddrStr := ipamSubnet.ipAddrStr(freeBit)
 
 	//fmt.Println("AllocateFromSubnet: ", ipAddrStr, freeBit )
 
@@ -176,18 +240,20 @@ func newIPAMSubnet(ipSubnetStr string) (*ipamSubnet, error) {
 		return nil, err
 	}
 
-	numBits, _ := n.Mask.Size()
+	numBits, addrBits := n.Mask.Size()
 
 	// example: 32 - /24 is 8 bits so need 2**8 for the bitmap
-	bm := bitmap.NewBitmap((1<<uint32(32 - numBits))-1)
+	idBits := addrBits - numBits
+	if addrBits == 8*net.IPv6len && idBits > maxIpv6IDBits {
+		idBits = maxIpv6IDBits
+	}
+	bm := bitmap.NewBitmap((1<<uint32(idBits))-1)
 
 	ipamSubnet := &ipamSubnet{
 		subnetStr:     ipSubnetStr,
-		ipNetworku32:  uint32(n.IP[0])<<24 | uint32(n.IP[1])<<16 | uint32(n.IP[2])<<8 | uint32(n.IP[3]),
-		ipMasku32:     uint32(n.Mask[0])<<24 | uint32(n.Mask[1])<<16 | uint32(n.Mask[2])<<8 | uint32(n.Mask[3]),
+		ipNetwork:     n,
 		numBitsInMask: numBits,
 		bm:            bm,
-		ipNetwork:     n,
 	}
 
 	//fmt.Println("newIPAMSubnet: ", ipamSubnet, bm)
@@ -0,0 +1,52 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ipam
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAllocateFromSubnet(t *testing.T) {
+	for _, tc := range []struct {
+		subnet, first, taken, third string
+		prefixLen                   int
+	}{
+		{"10.5.3.0/24", "10.5.3.1/24", "10.5.3.2", "10.5.3.3/24", 24},
+		{"2001:db8:1::/112", "2001:db8:1::1/112", "2001:db8:1::2", "2001:db8:1::3/112", 112},
+		{"2001:db8:2::/64", "2001:db8:2::1/64", "2001:db8:2::2", "2001:db8:2::3/64", 64},
+	} {
+		for _, expected := range []string{tc.first, tc.third} {
+			addr, _, err := AllocateFromSubnet(tc.subnet)
+			if err != nil {
+				t.Fatalf("subnet: '%s': %s", tc.subnet, err)
+			}
+			if addr != expected {
+				t.Fatalf("subnet: '%s' allocated: '%s', expected: '%s'", tc.subnet, addr, expected)
+			}
+			SetIpAddrIfInsideSubnet(tc.subnet, tc.taken)
+		}
+		taken := fmt.Sprintf("%s/%d", tc.taken, tc.prefixLen)
+		if addr, err := SetIpIDInSubnet(tc.subnet, 2); err != nil || addr != taken {
+			t.Fatalf("subnet: '%s' id 2 is: '%s', %v", tc.subnet, addr, err)
+		}
+	}
+}
+
+func TestLargeIpv6Subnet(t *testing.T) {
+	if _, err := SetIpIDInSubnet("2001:db8:3::/64", 1<<maxIpv6IDBits); err == nil {
+		t.Fatalf("id beyond the first 2**%d addresses allocated", maxIpv6IDBits)
+	}
+}
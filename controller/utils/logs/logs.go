@@ -0,0 +1,172 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package logs provides a named logger per sfc controller subsystem so the
+// level of each subsystem, and the output format of all of them, can be
+// changed at runtime.
+package logs
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ligato/cn-infra/logging"
+	"github.com/ligato/cn-infra/logging/logrus"
+)
+
+// The subsystem logger names
+const (
+	Core      = "sfc-core"
+	Driver    = "sfc-driver"
+	Reconcile = "sfc-reconcile"
+	EEDriver  = "sfc-ee-driver"
+	IPAM      = "sfc-ipam"
+)
+
+// AllLoggers can be used in place of a logger name to change the level of every subsystem
+const AllLoggers = "all"
+
+// The output formats
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// the controller has always logged at debug, so the subsystem loggers start there
+const initialLevel = logging.DebugLevel
+
+const textTimestampFormat = "2006-01-02 15:04:05.00000"
+
+// LogConfig is the level of each subsystem logger, and the output format
+type LogConfig struct {
+	Levels map[string]string `json:"levels,omitempty"`
+	Format string            `json:"format,omitempty"`
+}
+
+var (
+	mutex   sync.Mutex
+	loggers = make(map[string]*logrus.Logger)
+	format  = TextFormat
+)
+
+// Logger returns the named subsystem logger, it is created on first use with the current format
+func Logger(name string) *logrus.Logger {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if logger, exists := loggers[name]; exists {
+		return logger
+	}
+
+	logger := logrus.NewLogger(name)
+	logger.SetLevel(initialLevel)
+	setFormatter(logger, format)
+	loggers[name] = logger
+
+	return logger
+}
+
+// GetLogConfig returns the level of every subsystem logger, and the output format
+func GetLogConfig() *LogConfig {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	lc := &LogConfig{
+		Levels: make(map[string]string),
+		Format: format,
+	}
+	for name, logger := range loggers {
+		lc.Levels[name] = logger.GetLevel().String()
+	}
+
+	return lc
+}
+
+// SetLogConfig checks then applies the levels and format, the ones that are not provided are left as is
+func SetLogConfig(lc *LogConfig) error {
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	levels := make(map[string]logging.LogLevel)
+	for name, levelStr := range lc.Levels {
+		level, err := parseLevel(levelStr)
+		if err != nil {
+			return err
+		}
+		if _, exists := loggers[name]; !exists && name != AllLoggers {
+			return fmt.Errorf("unknown logger: '%s'", name)
+		}
+		levels[name] = level
+	}
+	if lc.Format != "" && lc.Format != TextFormat && lc.Format != JSONFormat {
+		return fmt.Errorf("unknown log format: '%s', expected %s or %s", lc.Format, TextFormat, JSONFormat)
+	}
+
+	// all first so a logger named along with it keeps its own level
+	if level, exists := levels[AllLoggers]; exists {
+		for _, logger := range loggers {
+			logger.SetLevel(level)
+		}
+	}
+	for name, level := range levels {
+		if name != AllLoggers {
+			loggers[name].SetLevel(level)
+		}
+	}
+	if lc.Format != "" {
+		format = lc.Format
+		for _, logger := range loggers {
+			setFormatter(logger, format)
+		}
+	}
+
+	return nil
+}
+
+// SetFormat switches every subsystem logger, and the ones created later, to the format
+func SetFormat(f string) error {
+	return SetLogConfig(&LogConfig{Format: f})
+}
+
+func setFormatter(logger *logrus.Logger, f string) {
+	if f == JSONFormat {
+		logger.SetFormatter(logrus.NewJSONFormatter())
+		return
+	}
+	tf := logrus.NewTextFormatter()
+	tf.TimestampFormat = textTimestampFormat
+	logger.SetFormatter(tf)
+}
+
+func parseLevel(levelStr string) (logging.LogLevel, error) {
+	switch strings.ToLower(levelStr) {
+	case "debug":
+		return logging.DebugLevel, nil
+	case "info":
+		return logging.InfoLevel, nil
+	case "warn", "warning":
+		return logging.WarnLevel, nil
+	case "error":
+		return logging.ErrorLevel, nil
+	case "fatal":
+		return logging.FatalLevel, nil
+	case "panic":
+		return logging.PanicLevel, nil
+	}
+	return logging.DebugLevel, fmt.Errorf("unknown log level: '%s'", levelStr)
+}
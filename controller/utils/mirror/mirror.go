@@ -0,0 +1,445 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package mirror fans the controller's writes out to a second datastore, ie
+// the etcd cluster of a standby site, so the standby takes over with a warm
+// datastore.  The brokers read from and write to the primary, and each write
+// that succeeds there is queued and replayed on the secondary, in order, by
+// a goroutine, so a slow or unreachable standby never holds up the wiring.
+// A write that does not fit in the queue is dropped, and the secondary is
+// resynced, copied from the primary, once the queue drains.  A promote
+// flips the two: the queue is drained first, then the brokers read from and
+// write to the former secondary, and the former primary is resynced from it
+// and mirrored to from then on.
+package mirror
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
+	"github.com/ligato/sfc-controller/controller/utils/revisions"
+)
+
+// DefaultQueueLength is the number of writes that can wait to be mirrored
+const DefaultQueueLength = 4096
+
+var log = logs.Logger(logs.Core)
+
+// Site is a datastore the mirror writes to
+type Site struct {
+	Name      string
+	DBFactory func(string) keyval.ProtoBroker
+}
+
+// Stats are the names of the sites and the counts of the writes mirrored so far
+type Stats struct {
+	Primary   string `json:"primary"`
+	Secondary string `json:"secondary"`
+	Queued    int    `json:"queued"`
+	Mirrored  uint64 `json:"mirrored"`
+	Dropped   uint64 `json:"dropped"` // the queue was full, a resync follows
+	Failed    uint64 `json:"failed"`  // the secondary returned an error
+	Resyncs   uint64 `json:"resyncs"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+// the kinds of the queued operations
+const (
+	opPut = iota
+	opDelete
+	opResync
+	opFlush
+)
+
+type op struct {
+	kind    int
+	key     string
+	value   *rawValue
+	delOpts []datasync.DelOption
+	done    chan struct{} // closed once a flush is reached
+}
+
+// Mirror is the primary and secondary sites and the writes queued for the secondary
+type Mirror struct {
+	sync.RWMutex
+	sites         [2]Site // primary, secondary
+	prefixes      []string
+	queue         chan *op
+	queueMutex    sync.RWMutex // the queue is closed under the write lock, queued to under the read lock
+	closed        bool
+	done          chan struct{}
+	resyncPending int32
+	mirrored      uint64
+	dropped       uint64
+	failed        uint64
+	resyncs       uint64
+	lastError     atomic.Value
+}
+
+// New starts mirroring the writes to the primary onto the secondary, a resync copies the keys under the
+// prefixes
+func New(primary Site, secondary Site, prefixes []string, queueLength int) *Mirror {
+
+	if queueLength <= 0 {
+		queueLength = DefaultQueueLength
+	}
+	m := &Mirror{
+		sites:    [2]Site{primary, secondary},
+		prefixes: prefixes,
+		queue:    make(chan *op, queueLength),
+		done:     make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// BrokerFactory returns a factory whose brokers use the current primary and mirror their writes
+func (m *Mirror) BrokerFactory() func(string) keyval.ProtoBroker {
+	return func(prefix string) keyval.ProtoBroker {
+		return &mirrorBroker{prefix: prefix, mirror: m}
+	}
+}
+
+// Resync queues a copy of the primary onto the secondary, after the writes already queued
+func (m *Mirror) Resync() {
+	m.enqueue(&op{kind: opResync})
+}
+
+// Promote makes the secondary the primary once the writes queued for it are applied, the former primary is
+// resynced from it
+func (m *Mirror) Promote(timeout time.Duration) error {
+
+	expired := time.After(timeout)
+	flushed := make(chan struct{})
+	m.queueMutex.RLock()
+	if m.closed {
+		m.queueMutex.RUnlock()
+		return fmt.Errorf("Promote: the mirror is closed")
+	}
+	select {
+	case m.queue <- &op{kind: opFlush, done: flushed}:
+	case <-expired:
+	}
+	m.queueMutex.RUnlock()
+	select {
+	case <-flushed:
+	case <-expired:
+		return fmt.Errorf("Promote: the writes queued for: '%s' were not applied within %s",
+			m.Stats().Secondary, timeout)
+	}
+
+	m.Lock()
+	m.sites[0], m.sites[1] = m.sites[1], m.sites[0]
+	m.Unlock()
+
+	stats := m.Stats()
+	log.Warnf("mirror: promoted: '%s', mirroring to: '%s'", stats.Primary, stats.Secondary)
+	m.Resync()
+
+	return nil
+}
+
+// Stats returns the sites and the counts of the writes mirrored, dropped and failed so far
+func (m *Mirror) Stats() Stats {
+
+	m.RLock()
+	defer m.RUnlock()
+
+	stats := Stats{
+		Primary:   m.sites[0].Name,
+		Secondary: m.sites[1].Name,
+		Queued:    len(m.queue),
+		Mirrored:  atomic.LoadUint64(&m.mirrored),
+		Dropped:   atomic.LoadUint64(&m.dropped),
+		Failed:    atomic.LoadUint64(&m.failed),
+		Resyncs:   atomic.LoadUint64(&m.resyncs),
+	}
+	if lastError, ok := m.lastError.Load().(string); ok {
+		stats.LastError = lastError
+	}
+	return stats
+}
+
+// Close mirrors the writes still queued, the writes to the brokers from then on are not mirrored
+func (m *Mirror) Close() {
+
+	m.queueMutex.Lock()
+	if m.closed {
+		m.queueMutex.Unlock()
+		return
+	}
+	m.closed = true
+	close(m.queue)
+	m.queueMutex.Unlock()
+
+	<-m.done
+}
+
+// site returns the broker of the primary, or of the secondary, for the prefix
+func (m *Mirror) site(secondary bool, prefix string) keyval.ProtoBroker {
+
+	m.RLock()
+	defer m.RUnlock()
+
+	if secondary {
+		return m.sites[1].DBFactory(prefix)
+	}
+	return m.sites[0].DBFactory(prefix)
+}
+
+// enqueue queues the op, it does not block: a write that does not fit is dropped and a resync is asked for,
+// one made once the mirror is closed is dropped too
+func (m *Mirror) enqueue(o *op) {
+
+	m.queueMutex.RLock()
+	defer m.queueMutex.RUnlock()
+
+	if m.closed {
+		atomic.AddUint64(&m.dropped, 1)
+		return
+	}
+	select {
+	case m.queue <- o:
+	default:
+		m.drop()
+	}
+}
+
+// drop counts a write that is not mirrored, the secondary is resynced once the queue drains
+func (m *Mirror) drop() {
+	atomic.StoreInt32(&m.resyncPending, 1)
+	if atomic.AddUint64(&m.dropped, 1) == 1 {
+		log.Warnf("mirror: queue full, dropping writes, the secondary will be resynced")
+	}
+}
+
+func (m *Mirror) run() {
+
+	defer close(m.done)
+
+	for o := range m.queue {
+		switch o.kind {
+		case opPut:
+			m.apply(m.site(true, keyval.Root).Put(o.key, o.value))
+		case opDelete:
+			_, err := m.site(true, keyval.Root).Delete(o.key, o.delOpts...)
+			m.apply(err)
+		case opResync:
+			m.resync()
+		case opFlush:
+			close(o.done)
+		}
+		if len(m.queue) == 0 && atomic.CompareAndSwapInt32(&m.resyncPending, 1, 0) {
+			m.resync()
+		}
+	}
+}
+
+// apply counts the result of a mirrored write
+func (m *Mirror) apply(err error) {
+	if err != nil {
+		m.failure(err)
+		return
+	}
+	atomic.AddUint64(&m.mirrored, 1)
+}
+
+func (m *Mirror) failure(err error) {
+	m.lastError.Store(err.Error())
+	if atomic.AddUint64(&m.failed, 1) == 1 {
+		log.Errorf("mirror: error writing to the secondary: %s", err)
+	}
+}
+
+// resync copies the keys under the prefixes from the primary onto the secondary, and removes the secondary's
+// keys the primary does not have
+func (m *Mirror) resync() {
+
+	atomic.AddUint64(&m.resyncs, 1)
+
+	primary := m.site(false, keyval.Root)
+	secondary := m.site(true, keyval.Root)
+
+	for _, prefix := range m.prefixes {
+		keys := make(map[string]struct{})
+		kvi, err := primary.ListValues(prefix)
+		if err != nil {
+			m.failure(err)
+			return
+		}
+		for {
+			kv, allReceived := kvi.GetNext()
+			if allReceived {
+				break
+			}
+			value := &rawValue{}
+			if err := kv.GetValue(value); err != nil {
+				m.failure(err)
+				continue
+			}
+			keys[kv.GetKey()] = struct{}{}
+			m.apply(secondary.Put(kv.GetKey(), value))
+		}
+
+		ki, err := secondary.ListKeys(prefix)
+		if err != nil {
+			m.failure(err)
+			return
+		}
+		for {
+			key, _, allReceived := ki.GetNext()
+			if allReceived {
+				break
+			}
+			if _, exists := keys[key]; !exists {
+				_, err := secondary.Delete(key)
+				m.apply(err)
+			}
+		}
+	}
+	log.Infof("mirror: resynced: '%s' from: '%s'", m.Stats().Secondary, m.Stats().Primary)
+}
+
+// rawValue is the json of a value as it was when it was written, the serializer of the brokers is json so it
+// is stored as is
+type rawValue struct {
+	data json.RawMessage
+}
+
+func (v *rawValue) Reset()         { v.data = nil }
+func (v *rawValue) String() string { return string(v.data) }
+func (*rawValue) ProtoMessage()    {}
+
+func (v *rawValue) MarshalJSON() ([]byte, error) {
+	return v.data, nil
+}
+
+func (v *rawValue) UnmarshalJSON(data []byte) error {
+	v.data = append(v.data[:0], data...)
+	return nil
+}
+
+// newRawValue takes the json of the value, it may change once the write returns
+func newRawValue(value proto.Message) (*rawValue, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return &rawValue{data: data}, nil
+}
+
+type mirrorBroker struct {
+	prefix string
+	mirror *Mirror
+}
+
+func (b *mirrorBroker) primary() keyval.ProtoBroker {
+	return b.mirror.site(false, b.prefix)
+}
+
+func (b *mirrorBroker) Put(key string, value proto.Message, opts ...datasync.PutOption) error {
+	if err := b.primary().Put(key, value, opts...); err != nil {
+		return err
+	}
+	raw, err := newRawValue(value)
+	if err != nil {
+		b.mirror.drop()
+		return nil
+	}
+	b.mirror.enqueue(&op{kind: opPut, key: b.prefix + key, value: raw})
+	return nil
+}
+
+func (b *mirrorBroker) PutIfRevision(key string, value proto.Message, revision int64) (bool, error) {
+	written, err := revisions.PutIfRevision(b.primary(), key, value, revision)
+	if err != nil || !written {
+		return written, err
+	}
+	raw, err := newRawValue(value)
+	if err != nil {
+		b.mirror.drop()
+		return true, nil
+	}
+	b.mirror.enqueue(&op{kind: opPut, key: b.prefix + key, value: raw})
+	return true, nil
+}
+
+func (b *mirrorBroker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	if existed, err = b.primary().Delete(key, opts...); err != nil {
+		return existed, err
+	}
+	b.mirror.enqueue(&op{kind: opDelete, key: b.prefix + key, delOpts: opts})
+	return existed, nil
+}
+
+func (b *mirrorBroker) GetValue(key string, reqObj proto.Message) (found bool, revision int64, err error) {
+	return b.primary().GetValue(key, reqObj)
+}
+
+func (b *mirrorBroker) ListValues(key string) (keyval.ProtoKeyValIterator, error) {
+	return b.primary().ListValues(key)
+}
+
+func (b *mirrorBroker) ListKeys(prefix string) (keyval.ProtoKeyIterator, error) {
+	return b.primary().ListKeys(prefix)
+}
+
+func (b *mirrorBroker) NewTxn() keyval.ProtoTxn {
+	return &mirrorTxn{ProtoTxn: b.primary().NewTxn(), broker: b}
+}
+
+// mirrorTxn queues the writes of the txn once it is committed on the primary
+type mirrorTxn struct {
+	keyval.ProtoTxn
+	broker  *mirrorBroker
+	ops     []*op
+	dropped bool
+}
+
+func (t *mirrorTxn) Put(key string, value proto.Message) keyval.ProtoTxn {
+	t.ProtoTxn.Put(key, value)
+	raw, err := newRawValue(value)
+	if err != nil {
+		t.dropped = true
+		return t
+	}
+	t.ops = append(t.ops, &op{kind: opPut, key: t.broker.prefix + key, value: raw})
+	return t
+}
+
+func (t *mirrorTxn) Delete(key string) keyval.ProtoTxn {
+	t.ProtoTxn.Delete(key)
+	t.ops = append(t.ops, &op{kind: opDelete, key: t.broker.prefix + key})
+	return t
+}
+
+func (t *mirrorTxn) Commit() error {
+	if err := t.ProtoTxn.Commit(); err != nil {
+		return err
+	}
+	if t.dropped {
+		t.broker.mirror.drop()
+	}
+	for _, o := range t.ops {
+		t.broker.mirror.enqueue(o)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mirror
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+var prefixes = []string{"/sfc-controller/", "/vnf-agent/"}
+
+func TestWritesMirrored(t *testing.T) {
+	primary, secondary := membroker.New(), membroker.New()
+	m := New(Site{"a", primary.NewBroker}, Site{"b", secondary.NewBroker}, prefixes, 0)
+	broker := m.BrokerFactory()("/sfc-controller/")
+
+	if err := broker.Put("v1/id/H1", &controller.EntityKeys{Name: "h1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Put("v1/id/H2", &controller.EntityKeys{Name: "h2"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.Delete("v1/id/H1"); err != nil {
+		t.Fatal(err)
+	}
+	txn := broker.NewTxn()
+	txn.Put("v1/id/H3", &controller.EntityKeys{Name: "h3"})
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	m.Close()
+
+	if !reflect.DeepEqual(primary.Dump("/"), secondary.Dump("/")) || len(secondary.Dump("/")) != 2 {
+		t.Errorf("expected the secondary to match the primary: %v, got: %v", primary.Dump("/"),
+			secondary.Dump("/"))
+	}
+	if stats := m.Stats(); stats.Mirrored != 4 || stats.Failed != 0 || stats.Dropped != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestResync(t *testing.T) {
+	primary, secondary := membroker.New(), membroker.New()
+	primary.NewBroker(keyval.Root).Put("/vnf-agent/H1/a", &controller.EntityKeys{Name: "a"})
+	primary.NewBroker(keyval.Root).Put("/other/x", &controller.EntityKeys{Name: "x"})
+	secondary.NewBroker(keyval.Root).Put("/vnf-agent/H1/stale", &controller.EntityKeys{})
+	m := New(Site{"a", primary.NewBroker}, Site{"b", secondary.NewBroker}, prefixes, 0)
+
+	m.Resync()
+	m.Close()
+
+	if !reflect.DeepEqual(primary.Dump("/vnf-agent/"), secondary.Dump("/vnf-agent/")) {
+		t.Errorf("expected the resync to match the secondary to the primary: %v, got: %v",
+			primary.Dump("/vnf-agent/"), secondary.Dump("/vnf-agent/"))
+	}
+	if len(secondary.Dump("/other/")) != 0 {
+		t.Errorf("a key outside the prefixes was resynced: %v", secondary.Dump("/other/"))
+	}
+}
+
+func TestPromote(t *testing.T) {
+	primary, secondary := membroker.New(), membroker.New()
+	m := New(Site{"a", primary.NewBroker}, Site{"b", secondary.NewBroker}, prefixes, 0)
+	broker := m.BrokerFactory()(keyval.Root)
+
+	if err := broker.Put("/sfc-controller/v1/id/H1", &controller.EntityKeys{Name: "h1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Promote(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if stats := m.Stats(); stats.Primary != "b" || stats.Secondary != "a" {
+		t.Errorf("expected b to be promoted: %+v", stats)
+	}
+
+	// the brokers already handed out write to the promoted site
+	if err := broker.Put("/sfc-controller/v1/id/H2", &controller.EntityKeys{Name: "h2"}); err != nil {
+		t.Fatal(err)
+	}
+	found, _, err := m.BrokerFactory()(keyval.Root).GetValue("/sfc-controller/v1/id/H1", &controller.EntityKeys{})
+	if err != nil || !found {
+		t.Errorf("the write mirrored before the promote is not on the promoted site: %v", err)
+	}
+	m.Close()
+
+	if len(secondary.Dump("/")) != 2 || !reflect.DeepEqual(primary.Dump("/"), secondary.Dump("/")) {
+		t.Errorf("expected the former primary to be mirrored to: %v, got: %v", secondary.Dump("/"),
+			primary.Dump("/"))
+	}
+}
+
+func TestWritesAfterClose(t *testing.T) {
+	primary, secondary := membroker.New(), membroker.New()
+	m := New(Site{"a", primary.NewBroker}, Site{"b", secondary.NewBroker}, prefixes, 0)
+	broker := m.BrokerFactory()("/sfc-controller/")
+
+	// the writes racing the close, and those after it, are written to the primary only
+	writes := make(chan struct{})
+	go func() {
+		defer close(writes)
+		for i := 0; i < 1000; i++ {
+			broker.Put("v1/id/H1", &controller.EntityKeys{Name: "h1"})
+		}
+	}()
+	m.Close()
+	<-writes
+
+	if err := broker.Put("v1/id/H2", &controller.EntityKeys{Name: "h2"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(primary.Dump("/")) != 2 || len(secondary.Dump("/")) > 1 {
+		t.Errorf("unexpected keys, primary: %v, secondary: %v", primary.Dump("/"), secondary.Dump("/"))
+	}
+	if err := m.Promote(time.Second); err == nil {
+		t.Errorf("the closed mirror is promoted")
+	}
+	m.Close()
+}
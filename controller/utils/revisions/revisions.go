@@ -0,0 +1,89 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package revisions writes a key only if it was not updated since it was
+// read.  The read-modify-writes of the keys the controller shares with other
+// writers load the value with its mod revision, and put the changed value on
+// that revision.  The etcd brokers put it in an etcd txn comparing the mod
+// revision of the key, so no update landing in between is lost.  The broker
+// wrappers of the controller, ie the faults, shards, hooks, shadow and mirror
+// ones, pass the puts on a revision on to the broker they wrap.
+package revisions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/db/keyval"
+)
+
+// Putter is implemented by the brokers that can write a key only if it is at a mod revision
+type Putter interface {
+	// PutIfRevision writes the value if the mod revision of the key is revision, 0 is the revision of
+	// a key that does not exist, it returns whether the value was written
+	PutIfRevision(key string, value proto.Message, revision int64) (bool, error)
+}
+
+// PutIfRevision writes the value with the broker if the key is at the revision, it returns whether the
+// value was written
+func PutIfRevision(broker keyval.ProtoBroker, key string, value proto.Message, revision int64) (bool, error) {
+
+	putter, ok := broker.(Putter)
+	if !ok {
+		return false, fmt.Errorf("PutIfRevision: key: '%s': the broker cannot put on a revision", key)
+	}
+	return putter.PutIfRevision(key, value, revision)
+}
+
+// WrapEtcdBrokerFactory returns a factory whose brokers put on a revision in etcd txns on kv, the factory
+// must return the brokers of the etcd plugin, the values are serialized as those do, ie to json
+func WrapEtcdBrokerFactory(dbFactory func(string) keyval.ProtoBroker, kv clientv3.KV,
+	opTimeout time.Duration) func(string) keyval.ProtoBroker {
+
+	return func(prefix string) keyval.ProtoBroker {
+		return &etcdBroker{ProtoBroker: dbFactory(prefix), prefix: prefix, kv: kv, opTimeout: opTimeout}
+	}
+}
+
+var serializer = &keyval.SerializerJSON{}
+
+type etcdBroker struct {
+	keyval.ProtoBroker
+	prefix    string
+	kv        clientv3.KV
+	opTimeout time.Duration
+}
+
+func (b *etcdBroker) PutIfRevision(key string, value proto.Message, revision int64) (bool, error) {
+
+	data, err := serializer.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), b.opTimeout)
+	defer cancel()
+
+	resp, err := b.kv.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(b.prefix+key), "=", revision)).
+		Then(clientv3.OpPut(b.prefix+key, string(data))).
+		Commit()
+	if err != nil {
+		return false, err
+	}
+	return resp.Succeeded, nil
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package revisions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	pb "github.com/coreos/etcd/etcdserver/etcdserverpb"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+// fakeKV evaluates the txns of the puts on a revision, the mod revision of a key is bumped by each put
+type fakeKV struct {
+	clientv3.KV
+	revision int64
+	kvs      map[string]int64
+	values   map[string]string
+}
+
+func (kv *fakeKV) Txn(ctx context.Context) clientv3.Txn {
+	return &fakeTxn{kv: kv}
+}
+
+type fakeTxn struct {
+	kv   *fakeKV
+	cmps []clientv3.Cmp
+	ops  []clientv3.Op
+}
+
+func (t *fakeTxn) If(cs ...clientv3.Cmp) clientv3.Txn {
+	t.cmps = append(t.cmps, cs...)
+	return t
+}
+
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.ops = append(t.ops, ops...)
+	return t
+}
+
+func (t *fakeTxn) Else(ops ...clientv3.Op) clientv3.Txn {
+	return t
+}
+
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	for i := range t.cmps {
+		cmp := &t.cmps[i]
+		if cmp.Target != pb.Compare_MOD || cmp.Result != pb.Compare_EQUAL {
+			return &clientv3.TxnResponse{}, nil
+		}
+		if t.kv.kvs[string(cmp.KeyBytes())] != cmp.TargetUnion.(*pb.Compare_ModRevision).ModRevision {
+			return &clientv3.TxnResponse{}, nil
+		}
+	}
+	for _, op := range t.ops {
+		t.kv.revision++
+		t.kv.kvs[string(op.KeyBytes())] = t.kv.revision
+		t.kv.values[string(op.KeyBytes())] = string(op.ValueBytes())
+	}
+	return &clientv3.TxnResponse{Succeeded: true}, nil
+}
+
+func TestEtcdPutIfRevision(t *testing.T) {
+
+	kv := &fakeKV{kvs: make(map[string]int64), values: make(map[string]string)}
+	broker := WrapEtcdBrokerFactory(membroker.New().NewBroker, kv, time.Second)("/prefix")
+	key := "/id/H2H/HOST1_HOST2"
+
+	if written, err := PutIfRevision(broker, key, &controller.EntityKeys{Name: "v1"}, 1); err != nil || written {
+		t.Fatalf("a key that does not exist was put on revision 1: %v, %v", written, err)
+	}
+	if written, err := PutIfRevision(broker, key, &controller.EntityKeys{Name: "v1"}, 0); err != nil || !written {
+		t.Fatalf("a new key was not put: %v, %v", written, err)
+	}
+	revision := kv.kvs["/prefix"+key]
+
+	if written, err := PutIfRevision(broker, key, &controller.EntityKeys{Name: "v2"}, revision); err != nil ||
+		!written {
+		t.Fatalf("the key was not put on its revision: %v, %v", written, err)
+	}
+	if written, err := PutIfRevision(broker, key, &controller.EntityKeys{Name: "v3"}, revision); err != nil ||
+		written {
+		t.Fatalf("the key was put on a stale revision: %v, %v", written, err)
+	}
+	if value := kv.values["/prefix"+key]; value != `{"name":"v2"}` {
+		t.Errorf("the key holds: '%s', expected the value put on its revision", value)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shadow withholds the controller's writes to the agents.  It is for
+// bringing a manually configured deployment under the controller: the
+// controller renders, allocates and reconciles as usual, but the puts and
+// deletes of the agents' keys are kept in a journal instead of being written,
+// so what the controller would change on each agent can be reviewed before it
+// is allowed to.  The agents' trees are read as they are, so a reconcile is
+// diffed against the config that is running.  The controller's own records,
+// ie the id's, are written, so the allocations that were reviewed are the
+// ones used once the controller leaves shadow mode.
+package shadow
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/controller/utils/revisions"
+)
+
+const (
+	// OpPut is the operation of a withheld put
+	OpPut = "put"
+	// OpDelete is the operation of a withheld delete
+	OpDelete = "delete"
+)
+
+var serializer = &keyval.SerializerJSON{}
+
+// Write is a withheld write, the last one of its key
+type Write struct {
+	Key   string          `json:"key"`
+	Op    string          `json:"op"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Journal keeps the last withheld write of each agent key
+type Journal struct {
+	sync.Mutex
+	writes map[string]*Write
+}
+
+// NewJournal returns an empty journal
+func NewJournal() *Journal {
+	return &Journal{writes: make(map[string]*Write)}
+}
+
+// WrapBrokerFactory returns a factory whose brokers keep the writes to the agents in the journal instead of
+// writing them
+func (j *Journal) WrapBrokerFactory(dbFactory func(string) keyval.ProtoBroker) func(string) keyval.ProtoBroker {
+
+	return func(prefix string) keyval.ProtoBroker {
+		return &shadowBroker{ProtoBroker: dbFactory(prefix), prefix: prefix, journal: j}
+	}
+}
+
+// Writes returns the withheld writes in key order
+func (j *Journal) Writes() []Write {
+
+	j.Lock()
+	defer j.Unlock()
+
+	writes := make([]Write, 0, len(j.writes))
+	for _, w := range j.writes {
+		writes = append(writes, *w)
+	}
+	sort.Slice(writes, func(i, k int) bool { return writes[i].Key < writes[k].Key })
+
+	return writes
+}
+
+// Reset empties the journal
+func (j *Journal) Reset() {
+
+	j.Lock()
+	defer j.Unlock()
+
+	j.writes = make(map[string]*Write)
+}
+
+func (j *Journal) put(key string, value proto.Message) error {
+
+	data, err := serializer.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	j.Lock()
+	defer j.Unlock()
+	j.writes[key] = &Write{Key: key, Op: OpPut, Value: data}
+
+	return nil
+}
+
+func (j *Journal) delete(key string) {
+
+	j.Lock()
+	defer j.Unlock()
+	j.writes[key] = &Write{Key: key, Op: OpDelete}
+}
+
+type shadowBroker struct {
+	keyval.ProtoBroker
+	prefix  string
+	journal *Journal
+}
+
+// withheld is whether the key is in the tree of an agent
+func (b *shadowBroker) withheld(key string) bool {
+	return strings.HasPrefix(b.prefix+key, utils.GetVppAgentPrefix())
+}
+
+func (b *shadowBroker) Put(key string, value proto.Message, opts ...datasync.PutOption) error {
+	if b.withheld(key) {
+		return b.journal.put(b.prefix+key, value)
+	}
+	return b.ProtoBroker.Put(key, value, opts...)
+}
+
+func (b *shadowBroker) PutIfRevision(key string, value proto.Message, revision int64) (bool, error) {
+	if b.withheld(key) {
+		return true, b.journal.put(b.prefix+key, value)
+	}
+	return revisions.PutIfRevision(b.ProtoBroker, key, value, revision)
+}
+
+func (b *shadowBroker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	if b.withheld(key) {
+		b.journal.delete(b.prefix + key)
+		return false, nil
+	}
+	return b.ProtoBroker.Delete(key, opts...)
+}
+
+func (b *shadowBroker) NewTxn() keyval.ProtoTxn {
+	return &shadowTxn{ProtoTxn: b.ProtoBroker.NewTxn(), broker: b}
+}
+
+// shadowTxn journals the withheld writes of the txn when it is committed
+type shadowTxn struct {
+	keyval.ProtoTxn
+	broker *shadowBroker
+	writes []*Write
+	values []proto.Message
+}
+
+func (t *shadowTxn) Put(key string, value proto.Message) keyval.ProtoTxn {
+	if t.broker.withheld(key) {
+		t.writes = append(t.writes, &Write{Key: key, Op: OpPut})
+		t.values = append(t.values, value)
+	} else {
+		t.ProtoTxn.Put(key, value)
+	}
+	return t
+}
+
+func (t *shadowTxn) Delete(key string) keyval.ProtoTxn {
+	if t.broker.withheld(key) {
+		t.writes = append(t.writes, &Write{Key: key, Op: OpDelete})
+		t.values = append(t.values, nil)
+	} else {
+		t.ProtoTxn.Delete(key)
+	}
+	return t
+}
+
+func (t *shadowTxn) Commit() error {
+	if err := t.ProtoTxn.Commit(); err != nil {
+		return err
+	}
+	for i, w := range t.writes {
+		if w.Op == OpDelete {
+			t.broker.journal.delete(t.broker.prefix + w.Key)
+			continue
+		}
+		if err := t.broker.journal.put(t.broker.prefix+w.Key, t.values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
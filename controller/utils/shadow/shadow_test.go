@@ -0,0 +1,69 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadow
+
+import (
+	"testing"
+
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+func TestAgentWritesWithheld(t *testing.T) {
+	mem := membroker.New()
+	mem.NewBroker(keyval.Root).Put("/vnf-agent/HOST1/vpp/config/v1/interface/IF0", &controller.EntityKeys{})
+
+	journal := NewJournal()
+	broker := journal.WrapBrokerFactory(mem.NewBroker)(keyval.Root)
+
+	value := &controller.EntityKeys{Name: "key"}
+	if err := broker.Put("/vnf-agent/HOST1/vpp/config/v1/interface/IF1", value); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.Put("/sfc-controller/v1/id/H2H/HOST1_HOST2", value); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.Delete("/vnf-agent/HOST1/vpp/config/v1/interface/IF0"); err != nil {
+		t.Fatal(err)
+	}
+	txn := broker.NewTxn()
+	txn.Put("/vnf-agent/HOST2/vpp/config/v1/interface/IF2", value)
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	dump := mem.Dump("/")
+	if len(dump) != 2 {
+		t.Errorf("the agent writes were not withheld: %v", dump)
+	}
+	if _, exists := dump["/sfc-controller/v1/id/H2H/HOST1_HOST2"]; !exists {
+		t.Error("the controller's record was withheld")
+	}
+
+	writes := journal.Writes()
+	if len(writes) != 3 {
+		t.Fatalf("expected 3 withheld writes: %v", writes)
+	}
+	if writes[0].Op != OpDelete || writes[1].Op != OpPut || string(writes[1].Value) != `{"name":"key"}` ||
+		writes[2].Key != "/vnf-agent/HOST2/vpp/config/v1/interface/IF2" {
+		t.Errorf("unexpected withheld writes: %v", writes)
+	}
+
+	journal.Reset()
+	if len(journal.Writes()) != 0 {
+		t.Error("the journal was not reset")
+	}
+}
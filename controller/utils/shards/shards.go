@@ -0,0 +1,117 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shards splits the hosts of a topology across several controllers.
+// A host belongs to the shard it is explicitly grouped in, else to the hash
+// of its name modulo the shard count.  Every controller renders the whole
+// config, but its datastore writes to the agents of the hosts it does not own
+// are dropped, so each agent is only written by one controller.  The
+// controller's own records, ie the id's, are shared by all of them.
+package shards
+
+import (
+	"hash/fnv"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/controller/utils/revisions"
+)
+
+// Assign returns the shard of the host, its group if it has one, else the hash of its name
+func Assign(host string, shardCount uint32, groups map[string]uint32) uint32 {
+
+	if shard, exists := groups[host]; exists {
+		return shard
+	}
+	if shardCount <= 1 {
+		return 0
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(host))
+	return h.Sum32() % shardCount
+}
+
+// WrapBrokerFactory returns a factory whose brokers drop the writes to the agents the controller does not
+// own, owns is called with the vpp label of each agent key written
+func WrapBrokerFactory(dbFactory func(string) keyval.ProtoBroker,
+	owns func(vppLabel string) bool) func(string) keyval.ProtoBroker {
+
+	return func(prefix string) keyval.ProtoBroker {
+		return &shardedBroker{ProtoBroker: dbFactory(prefix), prefix: prefix, owns: owns}
+	}
+}
+
+type shardedBroker struct {
+	keyval.ProtoBroker
+	prefix string
+	owns   func(vppLabel string) bool
+}
+
+// dropped is whether the key is in the tree of an agent owned by another controller
+func (b *shardedBroker) dropped(key string) bool {
+
+	key = b.prefix + key
+	if !strings.HasPrefix(key, utils.GetVppAgentPrefix()) {
+		return false
+	}
+	return !b.owns(utils.GetVppEtcdlabel(key))
+}
+
+func (b *shardedBroker) Put(key string, value proto.Message, opts ...datasync.PutOption) error {
+	if b.dropped(key) {
+		return nil
+	}
+	return b.ProtoBroker.Put(key, value, opts...)
+}
+
+func (b *shardedBroker) PutIfRevision(key string, value proto.Message, revision int64) (bool, error) {
+	if b.dropped(key) {
+		return true, nil
+	}
+	return revisions.PutIfRevision(b.ProtoBroker, key, value, revision)
+}
+
+func (b *shardedBroker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+	if b.dropped(key) {
+		return false, nil
+	}
+	return b.ProtoBroker.Delete(key, opts...)
+}
+
+func (b *shardedBroker) NewTxn() keyval.ProtoTxn {
+	return &shardedTxn{ProtoTxn: b.ProtoBroker.NewTxn(), broker: b}
+}
+
+type shardedTxn struct {
+	keyval.ProtoTxn
+	broker *shardedBroker
+}
+
+func (t *shardedTxn) Put(key string, value proto.Message) keyval.ProtoTxn {
+	if !t.broker.dropped(key) {
+		t.ProtoTxn.Put(key, value)
+	}
+	return t
+}
+
+func (t *shardedTxn) Delete(key string) keyval.ProtoTxn {
+	if !t.broker.dropped(key) {
+		t.ProtoTxn.Delete(key)
+	}
+	return t
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shards
+
+import (
+	"testing"
+
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+func TestAssign(t *testing.T) {
+	for _, host := range []string{"HOST1", "HOST2", "HOST3"} {
+		if shard := Assign(host, 4, nil); shard >= 4 {
+			t.Fatalf("host: '%s' assigned to shard %d of 4", host, shard)
+		}
+		if Assign(host, 4, nil) != Assign(host, 4, nil) {
+			t.Fatalf("host: '%s' assigned to different shards", host)
+		}
+		if shard := Assign(host, 1, nil); shard != 0 {
+			t.Fatalf("host: '%s' assigned to shard %d without sharding", host, shard)
+		}
+	}
+	if shard := Assign("HOST1", 4, map[string]uint32{"HOST1": 3}); shard != 3 {
+		t.Fatalf("grouped host assigned to shard %d instead of 3", shard)
+	}
+}
+
+func TestWritesToOtherShardsDropped(t *testing.T) {
+	mem := membroker.New()
+	dbFactory := WrapBrokerFactory(mem.NewBroker, func(vppLabel string) bool {
+		return vppLabel == "HOST1"
+	})
+	broker := dbFactory(keyval.Root)
+
+	value := &controller.EntityKeys{Name: "key"}
+	keys := []string{
+		"/vnf-agent/HOST1/vpp/config/v1/interface/IF1",
+		"/vnf-agent/HOST2/vpp/config/v1/interface/IF1",
+		"/sfc-controller/v1/id/H2H/HOST1_HOST2",
+	}
+	for _, key := range keys {
+		if err := broker.Put(key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	txn := broker.NewTxn()
+	txn.Put("/vnf-agent/HOST1/vpp/config/v1/interface/IF2", value)
+	txn.Put("/vnf-agent/HOST2/vpp/config/v1/interface/IF2", value)
+	if err := txn.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	dump := mem.Dump("/")
+	for _, key := range []string{keys[0], keys[2], "/vnf-agent/HOST1/vpp/config/v1/interface/IF2"} {
+		if _, exists := dump[key]; !exists {
+			t.Errorf("key: '%s' was not written", key)
+		}
+	}
+	for _, key := range []string{keys[1], "/vnf-agent/HOST2/vpp/config/v1/interface/IF2"} {
+		if _, exists := dump[key]; exists {
+			t.Errorf("key: '%s' of another shard was written", key)
+		}
+	}
+}
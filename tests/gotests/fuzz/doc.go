@@ -0,0 +1,28 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fuzz contains the go-fuzz targets for the controller's entity
+// config.  Each target decodes the fuzzed bytes as the json of an entity,
+// adds it to the topology in testdata/base.yaml, then validates and renders
+// it into the in-memory broker.  Invalid config may be rejected, but it must
+// not panic, hang, or render colliding memif id's.  The seed corpus in
+// testdata/sfc and testdata/host runs with go test, to fuzz run ie. from
+// this directory:
+//
+//	go-fuzz-build github.com/ligato/sfc-controller/tests/gotests/fuzz
+//	go-fuzz -bin fuzz-fuzz.zip -workdir testdata/sfc
+//
+// and build with -func FuzzHostEntity and use -workdir testdata/host to
+// fuzz the host entities.
+package fuzz
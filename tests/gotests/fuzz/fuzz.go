@@ -0,0 +1,104 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ligato/sfc-controller/controller/core"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/controller/utils/logs"
+	"github.com/ligato/sfc-controller/tests/gotests/golden"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+)
+
+func init() {
+	// the controller logs every key it renders at debug, which slows the fuzzing down to a crawl
+	logs.SetLogConfig(&logs.LogConfig{Levels: map[string]string{logs.AllLoggers: "error"}})
+}
+
+// fuzzSfcEntity adds the json sfc in data to the base topology and renders it, the return value follows
+// go-fuzz: 1 if the sfc rendered, 0 if it was rejected, -1 if data is not json of an sfc
+func fuzzSfcEntity(data []byte) int {
+	var sfc controller.SfcEntity
+	if err := json.Unmarshal(data, &sfc); err != nil {
+		return -1
+	}
+	cfg := loadBase()
+	cfg.SFCs = append(cfg.SFCs, sfc)
+	return renderAndCheck(cfg)
+}
+
+// fuzzHostEntity adds the json host in data to the base topology and renders it, the return value is as
+// for fuzzSfcEntity
+func fuzzHostEntity(data []byte) int {
+	var he controller.HostEntity
+	if err := json.Unmarshal(data, &he); err != nil {
+		return -1
+	}
+	cfg := loadBase()
+	cfg.HEs = append(cfg.HEs, he)
+	return renderAndCheck(cfg)
+}
+
+// loadBase reads the base topology for each input, rendering may modify the entities it is given
+func loadBase() *core.YamlConfig {
+	cfg, err := golden.LoadConfig("testdata/base.yaml")
+	if err != nil {
+		panic(fmt.Sprintf("error loading base topology: %s", err))
+	}
+	return cfg
+}
+
+// renderAndCheck renders the config, a config rejected by validation or wiring is fine, a render that
+// allocated the same memif id twice is not
+func renderAndCheck(cfg *core.YamlConfig) int {
+
+	broker, err := golden.Render(cfg)
+	if err != nil {
+		return 0
+	}
+	checkMemifIDs(broker)
+	return 1
+}
+
+// checkMemifIDs panics if two memifs of the same role share a socket and id, each end of a memif
+// pair has the id of the pair so only the master or the slave end may use an id once per socket
+func checkMemifIDs(broker *membroker.Broker) {
+
+	owners := make(map[string]string)
+	for key, data := range broker.Dump(utils.GetVppAgentPrefix()) {
+		if !strings.Contains(key, "/vpp/config/v1/interface/") {
+			continue
+		}
+		iface := &interfaces.Interfaces_Interface{}
+		if err := json.Unmarshal(data, iface); err != nil {
+			panic(fmt.Sprintf("bad interface: '%s': %s", key, err))
+		}
+		memif := iface.GetMemif()
+		if memif == nil {
+			continue
+		}
+		id := fmt.Sprintf("%s/%d/master=%t", memif.SocketFilename, memif.Id, memif.Master)
+		if owner, exists := owners[id]; exists {
+			panic(fmt.Sprintf("memif id allocated twice: %s: '%s' and '%s'", id, owner, key))
+		}
+		owners[id] = key
+	}
+}
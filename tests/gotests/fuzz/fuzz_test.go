@@ -0,0 +1,47 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+// runCorpus feeds each seed of a go-fuzz corpus to the target, a seed that panics fails the test
+func runCorpus(t *testing.T, dir string, target func([]byte) int) {
+
+	seeds, err := filepath.Glob(filepath.Join(dir, "*"))
+	if err != nil || len(seeds) == 0 {
+		t.Fatalf("no seeds in '%s': %v", dir, err)
+	}
+	for _, seed := range seeds {
+		data, err := ioutil.ReadFile(seed)
+		if err != nil {
+			t.Fatalf("error reading seed '%s': %s", seed, err)
+		}
+		if target(data) < 0 {
+			t.Errorf("seed '%s' is not valid json", seed)
+		}
+	}
+}
+
+func TestSfcEntityCorpus(t *testing.T) {
+	runCorpus(t, "testdata/sfc/corpus", fuzzSfcEntity)
+}
+
+func TestHostEntityCorpus(t *testing.T) {
+	runCorpus(t, "testdata/host/corpus", fuzzHostEntity)
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build gofuzz
+// +build gofuzz
+
+package fuzz
+
+// Fuzz is the go-fuzz entry point for the sfc entity config
+func Fuzz(data []byte) int {
+	return fuzzSfcEntity(data)
+}
+
+// FuzzHostEntity is the go-fuzz entry point for the host entity config, build it with -func FuzzHostEntity
+func FuzzHostEntity(data []byte) int {
+	return fuzzHostEntity(data)
+}
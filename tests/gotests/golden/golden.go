@@ -0,0 +1,187 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package golden renders a topology into an in-memory broker and compares
+// the vpp-agent keys it produced with a golden file, so a topology's wiring
+// can be pinned down in a regression test.  The golden file has the layout
+// of a backup archive, one json line per key, sorted by key:
+// {"key": "/vnf-agent/...", "value": {...}}.  Run the tests with -update to
+// rewrite the golden files from the current rendering.
+//
+// A test typically looks like:
+//
+//	func TestMyTopology(t *testing.T) {
+//		golden.CheckConfigFile(t, "testdata/my_topology.yaml", "testdata/my_topology.golden")
+//	}
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/ligato/sfc-controller/controller/core"
+	"github.com/ligato/sfc-controller/controller/utils"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files with the rendered keys")
+
+type goldenEntry struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+// LoadConfig reads a topology in the format of the controller's -sfc-config yaml file
+func LoadConfig(fpath string) (*core.YamlConfig, error) {
+
+	b, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		return nil, err
+	}
+	return core.ParseYamlConfig(b)
+}
+
+// Render renders the topology into a new in-memory broker, and returns the broker
+func Render(cfg *core.YamlConfig) (*membroker.Broker, error) {
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		return nil, err
+	}
+	return broker, nil
+}
+
+// AgentKeys renders the keys the broker holds in the vpp-agent trees in the golden file layout
+func AgentKeys(broker *membroker.Broker) ([]byte, error) {
+
+	dump := broker.Dump(utils.GetVppAgentPrefix())
+	keys := make([]string, 0, len(dump))
+	for key := range dump {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, key := range keys {
+		if err := enc.Encode(&goldenEntry{Key: key, Value: dump[key]}); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Compare returns an error naming the keys that are missing, extra or different in the rendered keys
+// compared to the golden ones, both in the golden file layout
+func Compare(rendered []byte, golden []byte) error {
+
+	renderedKeys, err := parseEntries(rendered)
+	if err != nil {
+		return fmt.Errorf("rendered keys: %s", err)
+	}
+	goldenKeys, err := parseEntries(golden)
+	if err != nil {
+		return fmt.Errorf("golden keys: %s", err)
+	}
+
+	var diffs []string
+	for key, goldenValue := range goldenKeys {
+		renderedValue, exists := renderedKeys[key]
+		if !exists {
+			diffs = append(diffs, fmt.Sprintf("missing: %s", key))
+		} else if renderedValue != goldenValue {
+			diffs = append(diffs, fmt.Sprintf("changed: %s\n\twant: %s\n\tgot:  %s", key, goldenValue,
+				renderedValue))
+		}
+	}
+	for key := range renderedKeys {
+		if _, exists := goldenKeys[key]; !exists {
+			diffs = append(diffs, fmt.Sprintf("extra: %s", key))
+		}
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	sort.Strings(diffs)
+	var buf bytes.Buffer
+	for _, diff := range diffs {
+		fmt.Fprintln(&buf, diff)
+	}
+	return fmt.Errorf("%d keys differ from the golden keys:\n%s", len(diffs), buf.String())
+}
+
+// parseEntries indexes the compacted json of each value by key, so the formatting of a hand edited golden
+// file does not matter
+func parseEntries(data []byte) (map[string]string, error) {
+
+	entries := make(map[string]string)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	for dec.More() {
+		var entry goldenEntry
+		if err := dec.Decode(&entry); err != nil {
+			return nil, err
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, entry.Value); err != nil {
+			return nil, fmt.Errorf("key '%s': %s", entry.Key, err)
+		}
+		entries[entry.Key] = compact.String()
+	}
+	return entries, nil
+}
+
+// CheckConfig renders the topology and fails the test if its vpp-agent keys differ from the golden file,
+// with -update the golden file is rewritten instead
+func CheckConfig(t *testing.T, cfg *core.YamlConfig, goldenFile string) {
+
+	broker, err := Render(cfg)
+	if err != nil {
+		t.Fatalf("error rendering config: %s", err)
+	}
+	rendered, err := AgentKeys(broker)
+	if err != nil {
+		t.Fatalf("error dumping rendered keys: %s", err)
+	}
+
+	if *update {
+		if err := ioutil.WriteFile(goldenFile, rendered, 0644); err != nil {
+			t.Fatalf("error writing golden file: %s", err)
+		}
+		return
+	}
+
+	golden, err := ioutil.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("error reading golden file: %s", err)
+	}
+	if err := Compare(rendered, golden); err != nil {
+		t.Errorf("%s: %s", goldenFile, err)
+	}
+}
+
+// CheckConfigFile is CheckConfig for a topology in a yaml file
+func CheckConfigFile(t *testing.T, configFile string, goldenFile string) {
+
+	cfg, err := LoadConfig(configFile)
+	if err != nil {
+		t.Fatalf("error loading config: %s", err)
+	}
+	CheckConfig(t, cfg, goldenFile)
+}
@@ -0,0 +1,2085 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package golden
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/sfc-controller/controller/cnpdriver"
+	l2cnpdriver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver"
+	l2driver "github.com/ligato/sfc-controller/controller/cnpdriver/l2driver/model"
+	"github.com/ligato/sfc-controller/controller/core"
+	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils/faults"
+	"github.com/ligato/sfc-controller/controller/utils/revisions"
+	"github.com/ligato/sfc-controller/tests/gotests/membroker"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/acl"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l3"
+)
+
+func TestBasicTopology(t *testing.T) {
+	CheckConfigFile(t, "testdata/basic.yaml", "testdata/basic.golden")
+}
+
+func TestVrfTopology(t *testing.T) {
+	CheckConfigFile(t, "testdata/vrf.yaml", "testdata/vrf.golden")
+}
+
+// a render whose datastore writes fail now and then returns an error, it must not panic on the failed writes
+func TestVrfTopologyUnderFaults(t *testing.T) {
+
+	defer faults.SetConfig(&faults.Config{})
+	for seed := int64(1); seed <= 100; seed++ {
+		cfg, err := LoadConfig("testdata/vrf.yaml")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := faults.SetConfig(&faults.Config{PutErrorRate: 0.05, Seed: seed}); err != nil {
+			t.Fatal(err)
+		}
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("seed %d: the render panicked: %v", seed, r)
+				}
+			}()
+			Render(cfg)
+		}()
+	}
+}
+
+func TestRenderIsRepeatable(t *testing.T) {
+	cfg, err := LoadConfig("testdata/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var first []byte
+	for i := 0; i < 3; i++ {
+		broker, err := Render(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rendered, err := AgentKeys(broker)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first == nil {
+			first = rendered
+			continue
+		}
+		if err := Compare(rendered, first); err != nil {
+			t.Errorf("render %d: %s", i, err)
+		}
+	}
+}
+
+// renderBasic renders the basic topology with deterministic ids and the given extra sfcs, and returns the
+// compacted json of the vpp-agent keys by key
+func renderBasic(t *testing.T, sfcs ...controller.SfcEntity) map[string]string {
+
+	cfg, err := LoadConfig("testdata/basic.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.SysParms.DeterministicIds = true
+	cfg.SFCs = append(cfg.SFCs, sfcs...)
+	broker, err := Render(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := AgentKeys(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := parseEntries(rendered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// with deterministic ids, adding a chain that renders before the others must not change their keys
+func TestDeterministicIDsIgnoreOrder(t *testing.T) {
+
+	base := renderBasic(t)
+	extended := renderBasic(t, controller.SfcEntity{
+		Name: "aaa-vnf8-vnf9",
+		Type: controller.SfcType_SFC_EW_L2XCONN,
+		Elements: []*controller.SfcEntity_SfcElement{
+			{Container: "vnf8", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+			{Container: "vnf9", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+				Type: controller.SfcElementType_NON_VPP_CONTAINER_AFP},
+		},
+	})
+	for key, value := range base {
+		if extended[key] != value {
+			t.Errorf("key changed by an unrelated chain: %s\n\twant: %s\n\tgot:  %s", key, value, extended[key])
+		}
+	}
+}
+
+// containers whose names shorten alike must not get the same host i/f name, even when the second one is
+// rendered by a new controller that starts its veth id's over
+func TestHostIfNamesStayUnique(t *testing.T) {
+
+	afpChain := func(name string, container string) controller.SfcEntity {
+		return controller.SfcEntity{
+			Name: name,
+			Type: controller.SfcType_SFC_EW_L2XCONN,
+			Elements: []*controller.SfcEntity_SfcElement{
+				{Container: container, PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+					Type: controller.SfcElementType_NON_VPP_CONTAINER_AFP},
+				{Container: name + "-vnf", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+			},
+		}
+	}
+	cfg := &core.YamlConfig{
+		HEs:  []controller.HostEntity{{Name: "vswitch"}},
+		SFCs: []controller.SfcEntity{afpChain("chain-b", "vnf-b-001")},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+	cfg.SFCs = append([]controller.SfcEntity{afpChain("chain-a", "vnf-a-001")}, cfg.SFCs...)
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	owners := make(map[string]string)
+	for key, value := range broker.Dump("/vnf-agent/vswitch/vpp/config/v1/interface/") {
+		var iface struct {
+			Afpacket struct {
+				HostIfName string `json:"host_if_name"`
+			} `json:"afpacket"`
+		}
+		if err := json.Unmarshal(value, &iface); err != nil {
+			t.Fatal(err)
+		}
+		name := iface.Afpacket.HostIfName
+		if name == "" {
+			continue
+		}
+		if len(name) > 15 {
+			t.Errorf("%s: host i/f name '%s' is longer than 15 chars", key, name)
+		}
+		if owner, taken := owners[name]; taken {
+			t.Errorf("host i/f name '%s' is used by %s and %s", name, owner, key)
+		}
+		owners[name] = key
+	}
+	if len(owners) != 2 {
+		t.Errorf("found %d afpacket host i/f names, expected 2: %v", len(owners), owners)
+	}
+	for name := range owners {
+		if !strings.Contains(name, "001_port1_") {
+			t.Errorf("host i/f name '%s' is not shortened from the container and port", name)
+		}
+	}
+}
+
+// a chain renders after the chains it depends on whatever their names, and is not wired while one of them is
+// missing
+func TestSfcDependsOn(t *testing.T) {
+
+	memifChain := func(name string, container string, dependsOn ...string) controller.SfcEntity {
+		return controller.SfcEntity{
+			Name:      name,
+			Type:      controller.SfcType_SFC_EW_L2XCONN,
+			DependsOn: dependsOn,
+			Elements: []*controller.SfcEntity_SfcElement{
+				{Container: container, PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+				{Container: container, PortLabel: "port2", EtcdVppSwitchKey: "vswitch",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+			},
+		}
+	}
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{{Name: "vswitch"}},
+		SFCs: []controller.SfcEntity{
+			memifChain("aaa-app", "app", "zzz-infra"),
+			memifChain("bbb-app", "orphan", "missing"),
+			memifChain("zzz-infra", "infra"),
+		},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	memifIDs := make(map[string]uint32)
+	for key, value := range broker.Dump("/vnf-agent/vswitch/vpp/config/v1/interface/IF_MEMIF_VSWITCH_") {
+		var iface struct {
+			Memif struct {
+				ID uint32 `json:"id"`
+			} `json:"memif"`
+		}
+		if err := json.Unmarshal(value, &iface); err != nil {
+			t.Fatal(err)
+		}
+		memifIDs[strings.TrimPrefix(key, "/vnf-agent/vswitch/vpp/config/v1/interface/IF_MEMIF_VSWITCH_")] =
+			iface.Memif.ID
+	}
+	if memifIDs["infra_port2"] == 0 || memifIDs["app_port1"] == 0 || memifIDs["infra_port2"] > memifIDs["app_port1"] {
+		t.Errorf("zzz-infra did not render before aaa-app: %v", memifIDs)
+	}
+	if _, exists := memifIDs["orphan_port1"]; exists {
+		t.Errorf("bbb-app was wired without the chain it depends on: %v", memifIDs)
+	}
+
+	status := &controller.EntityStatus{}
+	for key, value := range broker.Dump(controller.EntityStatusKey(controller.SfcEntityKind, "bbb-app")) {
+		if err := json.Unmarshal(value, status); err != nil {
+			t.Fatalf("%s: %s", key, err)
+		}
+	}
+	if status.State != controller.RenderStateType_DEPENDENCY_PENDING {
+		t.Errorf("bbb-app status is %s, expected %s", status.State, controller.RenderStateType_DEPENDENCY_PENDING)
+	}
+}
+
+func TestNetworkService(t *testing.T) {
+
+	memifChain := func(name string, container string, dependsOn ...string) *controller.SfcEntity {
+		return &controller.SfcEntity{
+			Name:      name,
+			Type:      controller.SfcType_SFC_EW_L2XCONN,
+			DependsOn: dependsOn,
+			Elements: []*controller.SfcEntity_SfcElement{
+				{Container: container, PortLabel: "port1", Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+				{Container: container, PortLabel: "port2", Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+			},
+		}
+	}
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{{Name: "vswitch"}},
+		NSs: []controller.NetworkService{{
+			Name:             "tenant1",
+			Tenant:           "t1",
+			EtcdVppSwitchKey: "vswitch",
+			SfcEntities:      []*controller.SfcEntity{memifChain("app", "app", "infra"), memifChain("infra", "infra")},
+		}},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(broker.Dump(controller.NetworkServiceNameKey("tenant1"))) != 1 {
+		t.Errorf("network service tenant1 is not stored")
+	}
+	for _, chain := range []string{"app", "infra"} {
+		sfcs := broker.Dump(controller.SfcEntityNameKey("tenant1-" + chain))
+		if len(sfcs) != 1 {
+			t.Errorf("chain %s of network service tenant1 is not expanded", chain)
+		}
+		for key, value := range sfcs {
+			sfc := &controller.SfcEntity{}
+			if err := json.Unmarshal(value, sfc); err != nil {
+				t.Fatalf("%s: %s", key, err)
+			}
+			if sfc.NetworkService != "tenant1" {
+				t.Errorf("%s: network_service is '%s'", key, sfc.NetworkService)
+			}
+			for _, sfcElement := range sfc.Elements {
+				if sfcElement.EtcdVppSwitchKey != "vswitch" || sfcElement.Metadata["tenant"] != "t1" {
+					t.Errorf("%s: element did not get the service's parameters: %v", key, sfcElement)
+				}
+			}
+		}
+		memifs := broker.Dump("/vnf-agent/vswitch/vpp/config/v1/interface/IF_MEMIF_VSWITCH_" + chain + "_port1")
+		if len(memifs) != 1 {
+			t.Errorf("chain %s of network service tenant1 is not wired", chain)
+		}
+	}
+
+	status := &controller.EntityStatus{}
+	for key, value := range broker.Dump(controller.EntityStatusKey(controller.SfcEntityKind, "tenant1-app")) {
+		if err := json.Unmarshal(value, status); err != nil {
+			t.Fatalf("%s: %s", key, err)
+		}
+	}
+	if status.State != controller.RenderStateType_RENDERED {
+		t.Errorf("tenant1-app status is %s, expected %s", status.State, controller.RenderStateType_RENDERED)
+	}
+}
+
+func TestSfcTemplate(t *testing.T) {
+
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{{Name: "vswitch"}},
+		TPLs: []controller.SfcTemplate{{
+			Name: "web",
+			Sfc: &controller.SfcEntity{
+				Type: controller.SfcType_SFC_EW_L2XCONN,
+				Elements: []*controller.SfcEntity_SfcElement{
+					{Container: "web${index}", PortLabel: "port1", EtcdVppSwitchKey: "${host}",
+						Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+					{Container: "web${index}", PortLabel: "port2", EtcdVppSwitchKey: "${host}",
+						Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+				},
+			},
+			Variables: []*controller.SfcTemplate_Variable{
+				{Name: "host", Default: "vswitch"},
+				{Name: "vni", Pool: "5000-5009"},
+			},
+			VniVariable: "vni",
+		}},
+		TPLInsts: []controller.SfcTemplateInstantiation{{Template: "web", Count: 2, FirstIndex: 3}},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	for index, vni := range map[string]uint32{"3": 5002, "4": 5003} {
+		sfcs := broker.Dump(controller.SfcEntityNameKey("web-" + index))
+		if len(sfcs) != 1 {
+			t.Errorf("instance %s of template web is not expanded", index)
+		}
+		for key, value := range sfcs {
+			sfc := &controller.SfcEntity{}
+			if err := json.Unmarshal(value, sfc); err != nil {
+				t.Fatalf("%s: %s", key, err)
+			}
+			if sfc.Template != "web" || sfc.TemplateVariables["index"] != index {
+				t.Errorf("%s: template linkage is: '%s' %v", key, sfc.Template, sfc.TemplateVariables)
+			}
+			for _, sfcElement := range sfc.Elements {
+				if sfcElement.Container != "web"+index || sfcElement.EtcdVppSwitchKey != "vswitch" ||
+					sfcElement.VlanId != vni {
+					t.Errorf("%s: element is not substituted: %v", key, sfcElement)
+				}
+			}
+		}
+		memifs := broker.Dump("/vnf-agent/vswitch/vpp/config/v1/interface/IF_MEMIF_VSWITCH_web" + index + "_port1")
+		if len(memifs) != 1 {
+			t.Errorf("instance %s of template web is not wired", index)
+		}
+	}
+
+	cfg.TPLInsts[0].Variables = map[string]string{"unknown": "x"}
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Errorf("instantiation with an unknown variable is accepted")
+	}
+}
+
+func TestFailedRenderRetry(t *testing.T) {
+
+	chain := controller.SfcEntity{
+		Name: "chain",
+		Type: controller.SfcType_SFC_NS_VXLAN,
+		Elements: []*controller.SfcEntity_SfcElement{
+			{Container: "vnf", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+		},
+	}
+	cfg := &core.YamlConfig{
+		HEs:  []controller.HostEntity{{Name: "vswitch"}},
+		SFCs: []controller.SfcEntity{chain},
+	}
+	retryKey := controller.RenderRetryKey(controller.SfcEntityKind, "chain")
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err == nil {
+		t.Fatal("n/s chain without an ee rendered")
+	}
+	retry := &controller.RenderRetry{}
+	value, exists := broker.Dump(retryKey)[retryKey]
+	if !exists {
+		t.Fatal("no retry queued for the failed render")
+	}
+	if err := json.Unmarshal(value, retry); err != nil {
+		t.Fatal(err)
+	}
+	if retry.Attempts != 0 || retry.Exhausted || retry.NextAttempt == 0 || retry.LastError == "" {
+		t.Errorf("unexpected retry: %v", retry)
+	}
+
+	status := &controller.EntityStatus{}
+	for key, value := range broker.Dump(controller.EntityStatusKey(controller.SfcEntityKind, "chain")) {
+		if err := json.Unmarshal(value, status); err != nil {
+			t.Fatalf("%s: %s", key, err)
+		}
+	}
+	if status.NextRetry != retry.NextAttempt || status.RetriesExhausted {
+		t.Errorf("retry not in the status: %v", status)
+	}
+
+	chain.Type = controller.SfcType_SFC_EW_L2XCONN
+	chain.Elements = append(chain.Elements, &controller.SfcEntity_SfcElement{Container: "vnf",
+		PortLabel: "port2", EtcdVppSwitchKey: "vswitch", Type: controller.SfcElementType_VPP_CONTAINER_MEMIF})
+	cfg.SFCs = []controller.SfcEntity{chain}
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+	if _, exists := broker.Dump(retryKey)[retryKey]; exists {
+		t.Error("retry kept after the chain rendered")
+	}
+}
+
+// concurrentBroker writes the operator's bd the first time the driver puts the bd's key on a revision, ie
+// between the driver's read of the bd and its write
+type concurrentBroker struct {
+	keyval.ProtoBroker
+	operatorBD *l2.BridgeDomains_BridgeDomain
+	written    bool
+}
+
+func (b *concurrentBroker) PutIfRevision(key string, value proto.Message, revision int64) (bool, error) {
+	if !b.written && strings.HasSuffix(key, "/bd/"+b.operatorBD.Name) {
+		b.written = true
+		if err := b.ProtoBroker.Put(key, b.operatorBD); err != nil {
+			return false, err
+		}
+	}
+	return revisions.PutIfRevision(b.ProtoBroker, key, value, revision)
+}
+
+func TestBridgeDomainAppendKeepsConcurrentUpdate(t *testing.T) {
+
+	chain := controller.SfcEntity{
+		Name: "chain",
+		Type: controller.SfcType_SFC_EW_BD,
+		Elements: []*controller.SfcEntity_SfcElement{
+			{Container: "vnf1", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+			{Container: "vnf2", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+		},
+	}
+	cfg := &core.YamlConfig{
+		HEs:  []controller.HostEntity{{Name: "vswitch"}},
+		SFCs: []controller.SfcEntity{chain},
+	}
+
+	broker := membroker.New()
+	concurrent := &concurrentBroker{operatorBD: &l2.BridgeDomains_BridgeDomain{
+		Name:       "BD_INTERNAL_EW_vswitch",
+		Interfaces: []*l2.BridgeDomains_BridgeDomain_Interfaces{{Name: "IF_OPERATOR"}},
+	}}
+	dbFactory := func(prefix string) keyval.ProtoBroker {
+		concurrent.ProtoBroker = broker.NewBroker(prefix)
+		return concurrent
+	}
+	if err := core.RenderConfig(cfg, dbFactory); err != nil {
+		t.Fatal(err)
+	}
+	if !concurrent.written {
+		t.Fatal("the bd was not appended to")
+	}
+
+	bdKey := "/vnf-agent/vswitch/vpp/config/v1/bd/BD_INTERNAL_EW_vswitch"
+	bd := &l2.BridgeDomains_BridgeDomain{}
+	if err := json.Unmarshal(broker.Dump(bdKey)[bdKey], bd); err != nil {
+		t.Fatal(err)
+	}
+	ifNames := make(map[string]bool)
+	for _, bi := range bd.Interfaces {
+		ifNames[bi.Name] = true
+	}
+	if !ifNames["IF_OPERATOR"] || len(ifNames) != 3 {
+		t.Errorf("concurrent update of the bd lost: %v", bd)
+	}
+}
+
+// chains naming another mode of the l2 driver are rendered by it next to the bridged ones
+func TestSfcCnpDriver(t *testing.T) {
+
+	memif := func(container string, host string) *controller.SfcEntity_SfcElement {
+		return &controller.SfcEntity_SfcElement{Container: container, PortLabel: "port1", EtcdVppSwitchKey: host,
+			Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}
+	}
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{
+			{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24", VxlanTunnelIpv4: "10.0.0.1"},
+			{Name: "h2", EthIfName: "eth0", EthIpv4: "10.0.0.2/24", VxlanTunnelIpv4: "10.0.0.2"},
+		},
+		SFCs: []controller.SfcEntity{
+			{Name: "bridged", Type: controller.SfcType_SFC_EW_BD,
+				Elements: []*controller.SfcEntity_SfcElement{memif("e", "h1"), memif("f", "h1")}},
+			{Name: "xc", Type: controller.SfcType_SFC_EW_L2XCONN, CnpDriver: "sfcctlrxconn",
+				Elements: []*controller.SfcEntity_SfcElement{memif("a", "h1"), memif("b", "h2")}},
+			{Name: "steered", Type: controller.SfcType_SFC_EW_L2XCONN, CnpDriver: "sfcctlracl",
+				Elements:      []*controller.SfcEntity_SfcElement{memif("c", "h1"), memif("d", "h1")},
+				SteeringRules: []*controller.SteeringRule{{DstNetwork: "10.9.0.0/16"}}},
+		},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	xconn := &l2.XConnectPairs_XConnectPair{}
+	xconnKey := "/vnf-agent/h1/vpp/config/v1/xconnect/IF_MEMIF_VSWITCH_a_port1"
+	if err := json.Unmarshal(broker.Dump(xconnKey)[xconnKey], xconn); err != nil {
+		t.Fatalf("%s: %s", xconnKey, err)
+	}
+	if xconn.TransmitInterface != "IF_VXLAN_XC_xc_a_port1" {
+		t.Errorf("xc is not cross connected to its tunnel: %v", xconn)
+	}
+	classifier := &acl.AccessLists_Acl{}
+	aclKey := "/vnf-agent/h1/vpp/config/v1/acl/STEER_steered_c_port1"
+	if err := json.Unmarshal(broker.Dump(aclKey)[aclKey], classifier); err != nil {
+		t.Fatalf("%s: %s", aclKey, err)
+	}
+	if classifier.Interfaces == nil || len(classifier.Interfaces.Ingress) != 1 ||
+		classifier.Interfaces.Ingress[0] != "IF_MEMIF_VSWITCH_c_port1" {
+		t.Errorf("steered is not classified on its first i/f: %v", classifier)
+	}
+	xconnKey = "/vnf-agent/h1/vpp/config/v1/xconnect/IF_MEMIF_VSWITCH_c_port1"
+	if err := json.Unmarshal(broker.Dump(xconnKey)[xconnKey], xconn); err != nil {
+		t.Fatalf("%s: %s", xconnKey, err)
+	}
+	if xconn.TransmitInterface != "IF_MEMIF_VSWITCH_d_port1" {
+		t.Errorf("steered is not cross connected: %v", xconn)
+	}
+	for key, value := range broker.Dump("/vnf-agent/h1/vpp/config/v1/bd/") {
+		if strings.Contains(string(value), "IF_MEMIF_VSWITCH_a_") || strings.Contains(string(value), "IF_MEMIF_VSWITCH_c_") {
+			t.Errorf("%s: bridges an i/f of a chain of another mode: %s", key, value)
+		}
+		if strings.HasSuffix(key, "BD_INTERNAL_EW_h1") && !strings.Contains(string(value), "IF_MEMIF_VSWITCH_e_") {
+			t.Errorf("%s: bridged is not bridged: %s", key, value)
+		}
+	}
+
+	cfg.SFCs[1].CnpDriver = "sfcctlrsrv6"
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("a chain naming an unknown driver is accepted")
+	}
+}
+
+// the chains are validated against the capabilities of the driver that renders them
+func TestCnpDriverCapabilities(t *testing.T) {
+
+	caps, err := cnpdriver.LookupCNPDriverCapabilities("sfcctlracl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !caps.IPv6 || caps.Delete || !caps.MultiHostEastWest {
+		t.Errorf("unexpected capabilities of the acl steering driver: %+v", caps)
+	}
+	if _, err := cnpdriver.LookupCNPDriverCapabilities("sfcctlrsrv6"); err == nil {
+		t.Error("an unknown driver has capabilities")
+	}
+
+	element := &controller.SfcEntity_SfcElement{Container: "a", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+		Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}
+	sfc := &controller.SfcEntity{Name: "c", Type: controller.SfcType_SFC_EW_L2XCONN, CnpDriver: "sfcctlracl",
+		Elements: []*controller.SfcEntity_SfcElement{element}}
+	if err := cnpdriver.ValidateSfcEntity("sfcctlrl2", sfc); err != nil {
+		t.Errorf("a steered chain is refused: %s", err)
+	}
+	sfc.Type = controller.SfcType_SFC_NS_NIC_L2XCONN
+	if err := cnpdriver.ValidateSfcEntity("sfcctlrl2", sfc); err == nil {
+		t.Error("a steered chain of a nic is accepted")
+	}
+	sfc.CnpDriver, sfc.Type = "sfcctlrxconn", controller.SfcType_SFC_EW_BD
+	if err := cnpdriver.ValidateSfcEntity("sfcctlrl2", sfc); err == nil {
+		t.Error("a bridged chain of the xconnect driver is accepted")
+	}
+	sfc.CnpDriver, sfc.Type = "", controller.SfcType_SFC_EW_BD
+	if err := cnpdriver.ValidateSfcEntity("sfcctlrxconn", sfc); err == nil {
+		t.Error("a bridged chain is accepted by the xconnect driver")
+	}
+	if err := cnpdriver.ValidateSfcEntityDelete("sfcctlrl2", &controller.SfcEntity{Name: "c",
+		CnpDriver: "sfcctlracl"}); err == nil {
+		t.Error("a steered chain can be deleted")
+	}
+}
+
+// the steered hops between ipv6 only hosts are tunneled over ipv6, and the rules classify the family of their
+// networks, or both families if they have none
+func TestSteeringAddressFamilies(t *testing.T) {
+
+	memif := func(container string, host string) *controller.SfcEntity_SfcElement {
+		return &controller.SfcEntity_SfcElement{Container: container, PortLabel: "port1", EtcdVppSwitchKey: host,
+			Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}
+	}
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{
+			{Name: "h1", EthIfName: "eth0", EthIpv6: "2001:db8::1/64", VxlanTunnelIpv6: "2001:db8:1::1/128"},
+			{Name: "h2", EthIfName: "eth0", EthIpv6: "2001:db8::2/64", VxlanTunnelIpv6: "2001:db8:1::2/128"},
+		},
+		SFCs: []controller.SfcEntity{
+			{Name: "steered", Type: controller.SfcType_SFC_EW_L2XCONN, CnpDriver: "sfcctlracl",
+				Elements: []*controller.SfcEntity_SfcElement{memif("a", "h1"), memif("b", "h2")},
+				SteeringRules: []*controller.SteeringRule{{}, {DstNetwork: "2001:db8:9::/48", Protocol: 6,
+					DstPortLower: 443}}},
+		},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	tunnel := &interfaces.Interfaces_Interface{}
+	tunnelKey := "/vnf-agent/h1/vpp/config/v1/interface/IF_VXLAN_XC_steered_a_port1"
+	if err := json.Unmarshal(broker.Dump(tunnelKey)[tunnelKey], tunnel); err != nil {
+		t.Fatalf("%s: %s", tunnelKey, err)
+	}
+	if tunnel.Vxlan == nil || tunnel.Vxlan.SrcAddress != "2001:db8:1::1" || tunnel.Vxlan.DstAddress != "2001:db8:1::2" {
+		t.Errorf("unexpected tunnel of the hop: %v", tunnel.Vxlan)
+	}
+
+	classifier := &acl.AccessLists_Acl{}
+	aclKey := "/vnf-agent/h1/vpp/config/v1/acl/STEER_steered_a_port1"
+	if err := json.Unmarshal(broker.Dump(aclKey)[aclKey], classifier); err != nil {
+		t.Fatalf("%s: %s", aclKey, err)
+	}
+	var networks []string
+	for _, rule := range classifier.Rules {
+		ip := rule.Matches.IpRule.Ip
+		networks = append(networks, ip.SourceNetwork+" "+ip.DestinationNetwork)
+	}
+	expected := []string{"0.0.0.0/0 0.0.0.0/0", "::/0 ::/0", "::/0 2001:db8:9::/48"}
+	if strings.Join(networks, ",") != strings.Join(expected, ",") {
+		t.Errorf("unexpected networks of the rules: %v", networks)
+	}
+	if len(broker.Dump("/vnf-agent/h2/vpp/config/v1/acl/")) != 0 {
+		t.Error("the hop is classified again on the peer host")
+	}
+
+	cfg.SFCs[0].SteeringRules = []*controller.SteeringRule{{SrcNetwork: "10.1.0.0/16", DstNetwork: "2001:db8:9::/48"}}
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("a steering rule of mixed families is accepted")
+	}
+}
+
+// a quarantined element keeps its wiring with its i/f's disabled
+func TestSfcElementAdminDown(t *testing.T) {
+
+	memif := func(container string) *controller.SfcEntity_SfcElement {
+		return &controller.SfcEntity_SfcElement{Container: container, PortLabel: "port1", EtcdVppSwitchKey: "h1",
+			Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}
+	}
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"}},
+		SFCs: []controller.SfcEntity{{Name: "c", Type: controller.SfcType_SFC_EW_BD,
+			Elements: []*controller.SfcEntity_SfcElement{memif("a"), memif("b")}}},
+	}
+	cfg.SFCs[0].Elements[1].AdminDown = true
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	enabled := map[string]bool{
+		"/vnf-agent/a/vpp/config/v1/interface/port1":                     true,
+		"/vnf-agent/h1/vpp/config/v1/interface/IF_MEMIF_VSWITCH_a_port1": true,
+		"/vnf-agent/b/vpp/config/v1/interface/port1":                     false,
+		"/vnf-agent/h1/vpp/config/v1/interface/IF_MEMIF_VSWITCH_b_port1": false,
+	}
+	for key, expected := range enabled {
+		var iface struct{ Enabled bool }
+		if err := json.Unmarshal(broker.Dump(key)[key], &iface); err != nil {
+			t.Fatalf("%s: %s", key, err)
+		}
+		if iface.Enabled != expected {
+			t.Errorf("%s: enabled: %t, expected: %t", key, iface.Enabled, expected)
+		}
+	}
+	bdKey := "/vnf-agent/h1/vpp/config/v1/bd/BD_INTERNAL_EW_h1"
+	if value := broker.Dump(bdKey)[bdKey]; !strings.Contains(string(value), "IF_MEMIF_VSWITCH_b_port1") {
+		t.Errorf("the quarantined element is not bridged: %s", value)
+	}
+
+	cfg.SFCs[0].Elements[1].Type = controller.SfcElementType_EXTERNAL_ENTITY
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("an external entity element is accepted admin_down")
+	}
+}
+
+func TestExternalEntityPrefixes(t *testing.T) {
+
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{
+			{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"},
+			{Name: "h2", EthIfName: "eth0", EthIpv4: "10.0.0.2/24"},
+		},
+		EEs: []controller.ExternalEntity{
+			{Name: "r1", MgmntIpAddress: "192.168.0.1",
+				HostInterface: &controller.ExternalEntity_HostInterface{IfName: "ge0", Ipv4Addr: "10.0.0.254/24"},
+				HostVxlan:     &controller.ExternalEntity_HostVxlan{SourceIpv4: "10.0.0.254"},
+				Prefixes: []*controller.ExternalEntity_Prefix{
+					{DstIpAddr: "172.16.0.0/16"},
+					{DstIpAddr: "172.17.0.0/16", VrfId: 2, Weight: 3, Preference: 4},
+				}},
+		},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, host := range []string{"h1", "h2"} {
+		routes := broker.Dump("/vnf-agent/" + host + "/vpp/config/v1/vrf/")
+		if len(routes) != 2 {
+			t.Errorf("%s: expected a route per prefix, got: %v", host, routes)
+		}
+		for key, value := range routes {
+			sr := &l3.StaticRoutes_Route{}
+			if err := json.Unmarshal(value, sr); err != nil {
+				t.Fatalf("%s: %s", key, err)
+			}
+			if sr.NextHopAddr != "10.0.0.254" || sr.OutgoingInterface != "eth0" {
+				t.Errorf("%s: unexpected route: %v", key, sr)
+			}
+			if sr.VrfId == 2 && (sr.Weight != 3 || sr.Preference != 4) {
+				t.Errorf("%s: unexpected weight or preference: %v", key, sr)
+			}
+		}
+	}
+
+	cfg.EEs[0].HostInterface = nil
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("prefixes without a host_interface ipv4_addr are accepted")
+	}
+}
+
+// a chain is admitted only if the uplinks it crosses have the bandwidth it commits
+func TestSfcBandwidthAdmission(t *testing.T) {
+
+	chain := func(name string) controller.SfcEntity {
+		return controller.SfcEntity{Name: name, Type: controller.SfcType_SFC_EW_L2XCONN, BandwidthMbps: 60,
+			Elements: []*controller.SfcEntity_SfcElement{
+				{Container: name + "1", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+				{Container: name + "2", PortLabel: "port1", EtcdVppSwitchKey: "h2",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}}}
+	}
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{
+			{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24", EthBandwidthMbps: 100},
+			{Name: "h2", EthIfName: "eth0", EthIpv4: "10.0.0.2/24"},
+		},
+		SFCs: []controller.SfcEntity{chain("a")},
+	}
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.SFCs = append(cfg.SFCs, chain("b"))
+	err := core.RenderConfig(cfg, membroker.New().NewBroker)
+	if err == nil || !strings.Contains(err.Error(), "oversubscribed") {
+		t.Errorf("a chain that oversubscribes eth0 of h1 is admitted: %v", err)
+	}
+
+	cfg.SysParms.BandwidthOvercommit = true
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err != nil {
+		t.Errorf("bandwidth_overcommit does not admit the chain: %s", err)
+	}
+}
+
+// a chain is placed only on hosts that can take the memifs, bridge domains and i/f's it renders
+func TestHostResourceLimits(t *testing.T) {
+
+	memif := func(container string) *controller.SfcEntity_SfcElement {
+		return &controller.SfcEntity_SfcElement{Container: container, PortLabel: "port1", EtcdVppSwitchKey: "h1",
+			Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}
+	}
+	cfg := &core.YamlConfig{
+		SysParms: controller.SystemParameters{HostResourceLimits: &controller.ResourceLimits{MaxMemifs: 3}},
+		HEs:      []controller.HostEntity{{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"}},
+		SFCs: []controller.SfcEntity{
+			{Name: "c1", Type: controller.SfcType_SFC_EW_BD,
+				Elements: []*controller.SfcEntity_SfcElement{memif("a"), memif("b")}},
+			{Name: "c2", Type: controller.SfcType_SFC_EW_BD,
+				Elements: []*controller.SfcEntity_SfcElement{memif("c"), memif("d")}},
+		},
+	}
+
+	err := core.RenderConfig(cfg, membroker.New().NewBroker)
+	if err == nil || !strings.Contains(err.Error(), "4 memifs of max 3") {
+		t.Errorf("the chains are placed beyond the memifs of h1: %v", err)
+	}
+
+	cfg.HEs[0].ResourceLimits = &controller.ResourceLimits{MaxMemifs: 4, MaxInterfaces: 5}
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err != nil {
+		t.Errorf("the limits of h1 do not override the system ones: %s", err)
+	}
+}
+
+// the scheduler places the containers without a host on the least loaded hosts the chain selects
+func TestPlacementScheduler(t *testing.T) {
+
+	memif := func(container string, antiAffinity ...string) *controller.SfcEntity_SfcElement {
+		return &controller.SfcEntity_SfcElement{Container: container, PortLabel: "port1",
+			Type: controller.SfcElementType_VPP_CONTAINER_MEMIF, AntiAffinity: antiAffinity}
+	}
+	cfg := &core.YamlConfig{
+		SysParms: controller.SystemParameters{FeatureFlags: map[string]bool{"placement-scheduler": true}},
+		HEs: []controller.HostEntity{
+			{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24", Labels: map[string]string{"tier": "gold"}},
+			{Name: "h2", EthIfName: "eth0", EthIpv4: "10.0.0.2/24", Labels: map[string]string{"tier": "gold"}},
+			{Name: "h3", EthIfName: "eth0", EthIpv4: "10.0.0.3/24", Labels: map[string]string{"tier": "silver"}},
+		},
+		SFCs: []controller.SfcEntity{{Name: "c", Type: controller.SfcType_SFC_EW_L2XCONN,
+			PlacementSelector: "tier=gold",
+			Elements:          []*controller.SfcEntity_SfcElement{memif("a"), memif("b", "a"), memif("c")}}},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+	for container, host := range map[string]string{"a": "h1", "b": "h2", "c": "h1"} {
+		key := "/vnf-agent/" + host + "/vpp/config/v1/interface/IF_MEMIF_VSWITCH_" + container + "_port1"
+		if len(broker.Dump(key)) != 1 {
+			t.Errorf("container: '%s' is not placed on: '%s'", container, host)
+		}
+	}
+	if len(broker.Dump("/vnf-agent/h3/vpp/config/v1/interface/IF_MEMIF")) != 0 {
+		t.Error("a container is placed on a host the chain does not select")
+	}
+
+	for _, element := range cfg.SFCs[0].Elements {
+		element.EtcdVppSwitchKey = ""
+	}
+	cfg.SysParms.HostResourceLimits = &controller.ResourceLimits{MaxMemifs: 1}
+	err := core.RenderConfig(cfg, membroker.New().NewBroker)
+	if err == nil || !strings.Contains(err.Error(), "no host can take container: 'c'") {
+		t.Errorf("the containers are placed beyond the limits of the hosts: %v", err)
+	}
+}
+
+// the containers share a host with their affinity containers and not with their anti-affinity ones
+func TestSfcElementAffinity(t *testing.T) {
+
+	memif := func(container string, host string) *controller.SfcEntity_SfcElement {
+		return &controller.SfcEntity_SfcElement{Container: container, PortLabel: "port1", EtcdVppSwitchKey: host,
+			Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}
+	}
+	render := func(elements ...*controller.SfcEntity_SfcElement) (*membroker.Broker, error) {
+		cfg := &core.YamlConfig{
+			SysParms: controller.SystemParameters{FeatureFlags: map[string]bool{"placement-scheduler": true}},
+			HEs: []controller.HostEntity{
+				{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"},
+				{Name: "h2", EthIfName: "eth0", EthIpv4: "10.0.0.2/24"},
+			},
+			SFCs: []controller.SfcEntity{{Name: "c", Type: controller.SfcType_SFC_EW_L2XCONN, Elements: elements}},
+		}
+		broker := membroker.New()
+		return broker, core.RenderConfig(cfg, broker.NewBroker)
+	}
+
+	fw, ids := memif("fw", "h1"), memif("ids", "h1")
+	ids.AntiAffinity = []string{"fw"}
+	if _, err := render(fw, ids); err == nil || !strings.Contains(err.Error(), "is on the same he: 'h1'") {
+		t.Errorf("the anti-affinity containers share a host: %v", err)
+	}
+
+	a, b := memif("a", "h1"), memif("b", "h2")
+	b.Affinity = []string{"a"}
+	if _, err := render(a, b); err == nil || !strings.Contains(err.Error(), "Invalid affinity") {
+		t.Errorf("the affinity containers are on different hosts: %v", err)
+	}
+
+	// a is kept off fw's host, and b follows a
+	fw, a, b = memif("fw", "h1"), memif("a", ""), memif("b", "")
+	a.AntiAffinity, b.Affinity = []string{"fw"}, []string{"a"}
+	broker, err := render(fw, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, container := range []string{"a", "b"} {
+		key := "/vnf-agent/h2/vpp/config/v1/interface/IF_MEMIF_VSWITCH_" + container + "_port1"
+		if len(broker.Dump(key)) != 1 {
+			t.Errorf("container: '%s' is not placed on h2", container)
+		}
+	}
+}
+
+// the hosts without ipv4 tunnel sources are meshed with ipv6 vxlans and routes, the others keep ipv4
+func TestIpv6OnlyHosts(t *testing.T) {
+
+	memif := func(container string, host string) *controller.SfcEntity_SfcElement {
+		return &controller.SfcEntity_SfcElement{Container: container, PortLabel: "port1", EtcdVppSwitchKey: host,
+			Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}
+	}
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{
+			{Name: "h1", EthIfName: "eth0", EthIpv6: "2001:db8::1/64", LoopbackIpv6: "2001:db8:f::1/128",
+				VxlanTunnelIpv6: "2001:db8:1::1/128", CreateVxlanStaticRoute: true},
+			{Name: "h2", EthIfName: "eth0", EthIpv6: "2001:db8::2/64", VxlanTunnelIpv6: "2001:db8:1::2/128",
+				CreateVxlanStaticRoute: true},
+			{Name: "h3", EthIfName: "eth0", EthIpv4: "10.0.0.3/24", VxlanTunnelIpv4: "10.0.1.3/32",
+				EthIpv6: "2001:db8::3/64", VxlanTunnelIpv6: "2001:db8:1::3/128", CreateVxlanStaticRoute: true},
+			{Name: "h4", EthIfName: "eth0", EthIpv4: "10.0.0.4/24", VxlanTunnelIpv4: "10.0.1.4/32",
+				CreateVxlanStaticRoute: true},
+		},
+	}
+	// a container on the first host, tunneled to the second one
+	for _, chain := range [][3]string{{"a", "h1", "h2"}, {"b", "h1", "h3"}, {"c", "h3", "h1"}, {"d", "h3", "h4"}} {
+		cfg.SFCs = append(cfg.SFCs, controller.SfcEntity{Name: chain[0], Type: controller.SfcType_SFC_NS_VXLAN,
+			Elements: []*controller.SfcEntity_SfcElement{
+				{Container: chain[2], PortLabel: "eth0", Type: controller.SfcElementType_HOST_ENTITY},
+				memif(chain[0], chain[1]),
+			}})
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		host, peer, src, dst, nextHop string
+	}{
+		{"h1", "h2", "2001:db8:1::1", "2001:db8:1::2", "2001:db8::2"},
+		{"h1", "h3", "2001:db8:1::1", "2001:db8:1::3", "2001:db8::3"},
+		{"h3", "h1", "2001:db8:1::3", "2001:db8:1::1", "2001:db8::1"},
+		{"h3", "h4", "10.0.1.3", "10.0.1.4", "10.0.0.4"},
+	} {
+		key := "/vnf-agent/" + tc.host + "/vpp/config/v1/interface/IF_VXLAN_H2H_" + tc.host + "_" + tc.peer
+		value, exists := broker.Dump(key)[key]
+		if !exists {
+			t.Errorf("%s: vxlan not rendered", key)
+			continue
+		}
+		iface := &interfaces.Interfaces_Interface{}
+		if err := json.Unmarshal(value, iface); err != nil {
+			t.Fatalf("%s: %s", key, err)
+		}
+		if iface.Vxlan.SrcAddress != tc.src || iface.Vxlan.DstAddress != tc.dst {
+			t.Errorf("%s: unexpected vxlan: %v", key, iface.Vxlan)
+		}
+
+		found := false
+		for routeKey, value := range broker.Dump("/vnf-agent/" + tc.host + "/vpp/config/v1/vrf/") {
+			sr := &l3.StaticRoutes_Route{}
+			if err := json.Unmarshal(value, sr); err != nil {
+				t.Fatalf("%s: %s", routeKey, err)
+			}
+			found = found || (strings.HasPrefix(sr.DstIpAddr, tc.dst+"/") && sr.NextHopAddr == tc.nextHop)
+		}
+		if !found {
+			t.Errorf("%s: no route to tunnel dst: '%s' via: '%s'", tc.host, tc.dst, tc.nextHop)
+		}
+	}
+
+	cfg.HEs[1].VxlanTunnelIpv6 = "10.0.1.2/32"
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("an ipv4 vxlan_tunnel_ipv6 is accepted")
+	}
+}
+
+// with both prefixes the elements get an address of each family, and the ids of both are stored
+func TestDualStackSfcElements(t *testing.T) {
+
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"}},
+		SFCs: []controller.SfcEntity{{Name: "ds", Type: controller.SfcType_SFC_EW_BD,
+			SfcIpv4Prefix: "10.1.2.0/24", SfcIpv6Prefix: "2001:db8:5::/112",
+			Elements: []*controller.SfcEntity_SfcElement{
+				{Container: "a", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+				{Container: "b", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+					Type: controller.SfcElementType_NON_VPP_CONTAINER_AFP},
+			}}},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{
+		"/vnf-agent/a/vpp/config/v1/interface/port1",
+		"/vnf-agent/h1/linux/config/v1/interface/IF_VETH_VNF_b_port1",
+	} {
+		value, exists := broker.Dump(key)[key]
+		if !exists {
+			t.Errorf("%s: not rendered", key)
+			continue
+		}
+		addrs := string(value)
+		if !strings.Contains(addrs, "\"10.1.2.") || !strings.Contains(addrs, "\"2001:db8:5::") {
+			t.Errorf("%s: expected an ipv4 and an ipv6 address: %s", key, addrs)
+		}
+	}
+
+	sfcIDs := broker.Dump(l2driver.SFCIDsKeyPrefix())
+	if len(sfcIDs) != 2 {
+		t.Fatalf("expected the ids of both elements, got: %v", sfcIDs)
+	}
+	for key, value := range sfcIDs {
+		ids := &l2driver.SFCIDs{}
+		if err := json.Unmarshal(value, ids); err != nil {
+			t.Fatalf("%s: %s", key, err)
+		}
+		if ids.IpId == 0 || ids.Ipv6Id == 0 {
+			t.Errorf("%s: expected an ipv4 and an ipv6 id: %v", key, ids)
+		}
+	}
+
+	cfg.SFCs[0].SfcIpv6Prefix = "10.1.3.0/24"
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("an ipv4 sfc_ipv6_prefix is accepted")
+	}
+}
+
+// the tunnels and routes between a host and an ee are validated against both ends' addressing
+func TestExternalEntityHostVxlanAddressing(t *testing.T) {
+
+	newCfg := func() *core.YamlConfig {
+		return &core.YamlConfig{
+			HEs: []controller.HostEntity{
+				{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24", VxlanTunnelIpv4: "10.0.1.1/32",
+					CreateVxlanStaticRoute: true},
+			},
+			EEs: []controller.ExternalEntity{
+				{Name: "r1", MgmntIpAddress: "192.168.0.1",
+					HostInterface: &controller.ExternalEntity_HostInterface{IfName: "ge0", Ipv4Addr: "10.0.0.254/24"},
+					HostVxlan:     &controller.ExternalEntity_HostVxlan{SourceIpv4: "10.0.2.254"}},
+			},
+			SFCs: []controller.SfcEntity{
+				{Name: "ns", Type: controller.SfcType_SFC_NS_VXLAN,
+					Elements: []*controller.SfcEntity_SfcElement{
+						{Container: "r1", PortLabel: "ge0", Type: controller.SfcElementType_EXTERNAL_ENTITY},
+						{Container: "vnf1", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+							Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+					}},
+			},
+		}
+	}
+	if err := core.RenderConfig(newCfg(), membroker.New().NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	for desc, breakCfg := range map[string]func(cfg *core.YamlConfig){
+		"a host_interface off the host's subnet": func(cfg *core.YamlConfig) {
+			cfg.EEs[0].HostInterface.Ipv4Addr = "10.9.0.254/24"
+		},
+		"a host_interface with the host's address": func(cfg *core.YamlConfig) {
+			cfg.EEs[0].HostInterface.Ipv4Addr = "10.0.0.1/24"
+		},
+		"an ipv6 source_ipv4": func(cfg *core.YamlConfig) { cfg.EEs[0].HostVxlan.SourceIpv4 = "2001:db8::1" },
+		"a chain to an ee without host_vxlan": func(cfg *core.YamlConfig) { cfg.EEs[0].HostVxlan = nil },
+		"a chain from a host without vxlan_tunnel_ipv4": func(cfg *core.YamlConfig) {
+			cfg.HEs[0].VxlanTunnelIpv4 = ""
+		},
+		"a host and an ee with the same tunnel source": func(cfg *core.YamlConfig) {
+			cfg.HEs[0].VxlanTunnelIpv4 = "10.0.2.254/32"
+		},
+	} {
+		cfg := newCfg()
+		breakCfg(cfg)
+		if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+			t.Errorf("%s is accepted", desc)
+		}
+	}
+}
+
+// the driver reports what it holds for an entity with the agent keys it is rendered under
+func TestCnpDriverGetState(t *testing.T) {
+
+	broker := membroker.New()
+	cnpd, err := cnpdriver.RegisterCNPDriverPlugin("sfcctlrl2", broker.NewBroker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bdParms := &controller.BDParms{Learn: true, UnknownUnicastFlood: true, Flood: true, Forward: true}
+	if err := cnpd.SetSystemParameters(&controller.SystemParameters{Mtu: 1500, StartingVlanId: 5000,
+		DynamicBridgeParms: bdParms, StaticBridgeParms: bdParms}); err != nil {
+		t.Fatal(err)
+	}
+	hes := []controller.HostEntity{
+		{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24", VxlanTunnelIpv4: "10.0.1.1/32"},
+		{Name: "h2", EthIfName: "eth0", EthIpv4: "10.0.0.2/24", VxlanTunnelIpv4: "10.0.1.2/32"},
+	}
+	for i := range hes {
+		if err := cnpd.WireInternalsForHostEntity(&hes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cnpd.WireHostEntityToDestinationHostEntity(&hes[0], &hes[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := cnpd.WireSfcEntity(&controller.SfcEntity{Name: "c", Type: controller.SfcType_SFC_NS_VXLAN,
+		Elements: []*controller.SfcEntity_SfcElement{
+			{Container: "h2", PortLabel: "eth0", Type: controller.SfcElementType_HOST_ENTITY},
+			{Container: "a", PortLabel: "port1", EtcdVppSwitchKey: "h1", Ipv4Addr: "10.5.0.9/24",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+		}}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := cnpd.GetState("h1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	types := make(map[string]int)
+	for _, obj := range state.Objects {
+		if obj.Host != "h1" || !strings.HasPrefix(obj.Key, "/vnf-agent/h1/") {
+			t.Errorf("h1: object of another host: %+v", obj)
+		}
+		if _, exists := broker.Dump(obj.Key)[obj.Key]; !exists {
+			t.Errorf("h1: %s: not rendered", obj.Key)
+		}
+		types[obj.Type]++
+	}
+	if types["vxlan"] != 1 || types["bridge_domain"] == 0 {
+		t.Errorf("h1: expected its vxlan to h2 and its bridges, got: %+v", state.Objects)
+	}
+
+	state, err = cnpd.GetState("c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Interfaces) != 1 || state.Interfaces[0].Ipv4Address != "10.5.0.9/24" {
+		t.Errorf("c: expected the addresses of its container, got: %+v", state.Interfaces)
+	}
+	if _, err := json.Marshal(state); err != nil {
+		t.Errorf("c: %s", err)
+	}
+
+	if _, err := cnpd.GetState("nope"); err == nil {
+		t.Error("the state of an unknown entity is returned")
+	}
+}
+
+// the hosts source their tunnels from their loopbacks and route to each other's loopbacks
+func TestLoopbackTunnelEndpoints(t *testing.T) {
+
+	broker := membroker.New()
+	cnpd, err := cnpdriver.RegisterCNPDriverPlugin("sfcctlrl2", broker.NewBroker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bdParms := &controller.BDParms{Learn: true, UnknownUnicastFlood: true, Flood: true, Forward: true}
+	if err := cnpd.SetSystemParameters(&controller.SystemParameters{Mtu: 1500, StartingVlanId: 5000,
+		DynamicBridgeParms: bdParms, StaticBridgeParms: bdParms}); err != nil {
+		t.Fatal(err)
+	}
+	hes := []controller.HostEntity{
+		{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24", VxlanTunnelIpv4: "10.0.1.1/32",
+			LoopbackIpv4: "10.9.0.1/32", LoopbackTunnelEndpoints: true},
+		{Name: "h2", EthIfName: "eth0", EthIpv4: "10.0.0.2/24", VxlanTunnelIpv4: "10.0.1.2/32",
+			LoopbackIpv4: "10.9.0.2/32", LoopbackTunnelEndpoints: true},
+	}
+	for i := range hes {
+		if err := cnpd.WireInternalsForHostEntity(&hes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cnpd.WireHostEntityToDestinationHostEntity(&hes[0], &hes[1]); err != nil {
+		t.Fatal(err)
+	}
+	if err := cnpd.WireSfcEntity(&controller.SfcEntity{Name: "c", Type: controller.SfcType_SFC_NS_VXLAN,
+		Elements: []*controller.SfcEntity_SfcElement{
+			{Container: "h2", PortLabel: "eth0", Type: controller.SfcElementType_HOST_ENTITY},
+			{Container: "a", PortLabel: "port1", EtcdVppSwitchKey: "h1", Ipv4Addr: "10.5.0.9/24",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+		}}); err != nil {
+		t.Fatal(err)
+	}
+
+	state, err := cnpd.GetState("h1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vxlans, routes := 0, 0
+	for _, obj := range state.Objects {
+		switch obj.Type {
+		case "vxlan":
+			vxlan := obj.Object.(*interfaces.Interfaces_Interface).Vxlan
+			if vxlan.SrcAddress != "10.9.0.1" || vxlan.DstAddress != "10.9.0.2" {
+				t.Errorf("h1: expected the vxlan between the loopbacks, got: %+v", vxlan)
+			}
+			vxlans++
+		case "static_route":
+			sr := obj.Object.(*l3.StaticRoutes_Route)
+			if sr.DstIpAddr != "10.9.0.2/32" || sr.NextHopAddr != "10.0.0.2" || sr.OutgoingInterface != "eth0" {
+				t.Errorf("h1: expected the route to the loopback of h2 via its eth0, got: %+v", sr)
+			}
+			routes++
+		}
+	}
+	if vxlans != 1 || routes != 1 {
+		t.Errorf("h1: expected its vxlan and route to h2, got: %+v", state.Objects)
+	}
+}
+
+func TestDeleteOwnedAgentKeys(t *testing.T) {
+
+	broker := membroker.New()
+	cnpd, err := cnpdriver.RegisterCNPDriverPlugin("sfcctlrl2", broker.NewBroker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bdParms := &controller.BDParms{Learn: true, UnknownUnicastFlood: true, Flood: true, Forward: true}
+	if err := cnpd.SetSystemParameters(&controller.SystemParameters{Mtu: 1500, DynamicBridgeParms: bdParms,
+		StaticBridgeParms: bdParms}); err != nil {
+		t.Fatal(err)
+	}
+	if err := cnpd.WireInternalsForHostEntity(&controller.HostEntity{Name: "h1", EthIfName: "eth0",
+		EthIpv4: "10.0.0.1/24"}); err != nil {
+		t.Fatal(err)
+	}
+	// written by hand, it is not torn down
+	handKey := "/vnf-agent/h1/vpp/config/v1/interface/by-hand"
+	if err := broker.NewBroker("").Put(handKey, &interfaces.Interfaces_Interface{Name: "by-hand"}); err != nil {
+		t.Fatal(err)
+	}
+
+	rendered := len(broker.Dump("/vnf-agent/"))
+	deleted, err := cnpd.DeleteOwnedAgentKeys(func(key string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := broker.Dump("/vnf-agent/")
+	if deleted != rendered-1 || len(left) != 1 {
+		t.Errorf("expected the %d rendered keys deleted, deleted: %d, left: %d", rendered-1, deleted, len(left))
+	}
+	if _, exists := left[handKey]; !exists {
+		t.Errorf("%s: deleted", handKey)
+	}
+}
+
+// a host reconciled a page of keys at a time ends up with the config of a fresh render
+func TestReconcilePaged(t *testing.T) {
+
+	bdParms := &controller.BDParms{Learn: true, UnknownUnicastFlood: true, Flood: true, Forward: true}
+	sp := &controller.SystemParameters{Mtu: 1500, DynamicBridgeParms: bdParms, StaticBridgeParms: bdParms,
+		ReconcilePageSize: 2}
+	host := func(eth1Ipv4 string, uplinks ...string) *controller.HostEntity {
+		he := &controller.HostEntity{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"}
+		for _, uplink := range uplinks {
+			ul := &controller.HostEntity_Uplink{EthIfName: uplink, EthIpv4: "10.0.1.1/24"}
+			if uplink == "eth1" {
+				ul.EthIpv4 = eth1Ipv4
+			}
+			he.Uplinks = append(he.Uplinks, ul)
+		}
+		return he
+	}
+	render := func(broker *membroker.Broker, he *controller.HostEntity) {
+		cnpd, err := cnpdriver.RegisterCNPDriverPlugin("sfcctlrl2", broker.NewBroker)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.SetSystemParameters(sp); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.ReconcileStart(map[string]struct{}{"h1": {}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.WireInternalsForHostEntity(he); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.ReconcileEnd(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	broker := membroker.New()
+	render(broker, host("10.0.2.1/24", "eth1", "eth2", "eth3", "eth4"))
+	render(broker, host("10.0.3.1/24", "eth1", "eth2"))
+
+	fresh := membroker.New()
+	render(fresh, host("10.0.3.1/24", "eth1", "eth2"))
+
+	ifPrefix := "/vnf-agent/h1/vpp/config/v1/interface/"
+	if reconciled, rendered := broker.Dump(ifPrefix), fresh.Dump(ifPrefix); !reflect.DeepEqual(reconciled, rendered) {
+		t.Errorf("expected the reconciled i/f's: %v, got: %v", rendered, reconciled)
+	}
+}
+
+// the stamps in the descriptions tell the controller's stale objects from the foreign ones
+func TestAuditDescriptions(t *testing.T) {
+
+	broker := membroker.New()
+	cnpd, err := cnpdriver.RegisterCNPDriverPlugin("sfcctlrl2", broker.NewBroker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bdParms := &controller.BDParms{Learn: true, UnknownUnicastFlood: true, Flood: true, Forward: true}
+	if err := cnpd.SetSystemParameters(&controller.SystemParameters{Mtu: 1500, DynamicBridgeParms: bdParms,
+		StaticBridgeParms: bdParms, AuditDescriptions: true}); err != nil {
+		t.Fatal(err)
+	}
+	cnpd.SetConfigVersion(3)
+	reconcile := func() {
+		if err := cnpd.ReconcileStart(map[string]struct{}{"h1": {}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.WireInternalsForHostEntity(&controller.HostEntity{Name: "h1", EthIfName: "eth0",
+			EthIpv4: "10.0.0.1/24"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.ReconcileEnd(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reconcile()
+
+	ifPrefix := "/vnf-agent/h1/vpp/config/v1/interface/"
+	eth0 := &interfaces.Interfaces_Interface{}
+	if found, _, err := broker.NewBroker("").GetValue(ifPrefix+"eth0", eth0); err != nil || !found {
+		t.Fatalf("eth0 not rendered: %v", err)
+	}
+	if !strings.Contains(eth0.Description, "sfc-controller: entity=HE/h1 version=3 rendered=") {
+		t.Errorf("unexpected eth0 description: '%s'", eth0.Description)
+	}
+
+	// the stamped one was left behind by a controller, the other one was configured by hand
+	stale := &interfaces.Interfaces_Interface{Name: "stale", Description: eth0.Description}
+	if err := broker.NewBroker("").Put(ifPrefix+"stale", stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := broker.NewBroker("").Put(ifPrefix+"by-hand", &interfaces.Interfaces_Interface{Name: "by-hand"}); err != nil {
+		t.Fatal(err)
+	}
+	cnpd.ResetRenderedKeys()
+	cnpd.SetConfigVersion(4)
+	reconcile()
+
+	restamped := &interfaces.Interfaces_Interface{}
+	broker.NewBroker("").GetValue(ifPrefix+"eth0", restamped)
+	if restamped.Description != eth0.Description {
+		t.Errorf("eth0 rendered the same was rewritten: '%s'", restamped.Description)
+	}
+
+	inconsistencies, err := cnpd.VerifyConsistency(func(string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]string)
+	for _, inconsistency := range inconsistencies {
+		kinds[inconsistency.Key] = inconsistency.Kind
+	}
+	if kinds[ifPrefix+"stale"] != "stale_key" || kinds[ifPrefix+"by-hand"] != "foreign_key" {
+		t.Errorf("expected the stale and foreign i/f's to be reported: %v", inconsistencies)
+	}
+}
+
+// a bd chain has one nic, the vpp-agent has no bond, and a vrf chain routes over each of its nics
+func TestNorthSouthMultiNic(t *testing.T) {
+
+	nic := func(portLabel string) *controller.SfcEntity_SfcElement {
+		return &controller.SfcEntity_SfcElement{Container: "h1", PortLabel: portLabel,
+			Type: controller.SfcElementType_HOST_ENTITY}
+	}
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"}},
+		SFCs: []controller.SfcEntity{{Name: "s1", Type: controller.SfcType_SFC_NS_NIC_BD,
+			Elements: []*controller.SfcEntity_SfcElement{nic("eth1"), nic("eth2"),
+				{Container: "c1", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}}}},
+	}
+
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("a bd chain with several nics is accepted")
+	}
+
+	route := func(nextHop string) []*controller.L3VRFRoute {
+		return []*controller.L3VRFRoute{{DstIpAddr: "10.9.0.0/16", NextHopAddr: nextHop}}
+	}
+	cfg.SFCs[0].Type = controller.SfcType_SFC_NS_NIC_VRF
+	cfg.SFCs[0].Elements[0].L3VrfRoutes = route("10.2.0.254")
+	cfg.SFCs[0].Elements[1].L3VrfRoutes = route("10.3.0.254")
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+	for _, ifName := range []string{"eth1", "eth2"} {
+		if len(broker.Dump("/vnf-agent/h1/vpp/config/v1/interface/"+ifName)) != 1 {
+			t.Errorf("nic: '%s' is not rendered", ifName)
+		}
+	}
+	for _, ifName := range []string{"eth1", "eth2"} {
+		found := false
+		for key, value := range broker.Dump("/vnf-agent/h1/vpp/config/v1/vrf/") {
+			route := &l3.StaticRoutes_Route{}
+			if err := json.Unmarshal(value, route); err != nil {
+				t.Fatalf("%s: %s", key, err)
+			}
+			found = found || (route.OutgoingInterface == ifName && route.DstIpAddr == "10.9.0.0/16")
+		}
+		if !found {
+			t.Errorf("no route over nic: '%s'", ifName)
+		}
+	}
+
+	cfg.SFCs[0].Elements[1].Container = "h2"
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("the nics of a chain are accepted on different hosts")
+	}
+}
+
+// a bridge that does not learn gets the l2fib entries of its elements, and loses those of the removed ones
+func TestAutoL2Fib(t *testing.T) {
+
+	static := &controller.BDParms{Forward: true}
+	sp := &controller.SystemParameters{Mtu: 1500, StaticBridgeParms: static,
+		DynamicBridgeParms: &controller.BDParms{Learn: true, Flood: true, UnknownUnicastFlood: true, Forward: true}}
+	he := &controller.HostEntity{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"}
+	element := func(container string, macAddr string) *controller.SfcEntity_SfcElement {
+		return &controller.SfcEntity_SfcElement{Container: container, PortLabel: "port1", EtcdVppSwitchKey: "h1",
+			MacAddr: macAddr, Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}
+	}
+	render := func(broker *membroker.Broker, elements ...*controller.SfcEntity_SfcElement) {
+		cnpd, err := cnpdriver.RegisterCNPDriverPlugin("sfcctlrl2", broker.NewBroker)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.SetSystemParameters(sp); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.ReconcileStart(map[string]struct{}{"h1": {}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.WireInternalsForHostEntity(he); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.WireSfcEntity(&controller.SfcEntity{Name: "s1", Type: controller.SfcType_SFC_EW_BD_L2FIB,
+			Elements: elements}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.ReconcileEnd(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fibPrefix := "/vnf-agent/h1/vpp/config/v1/bd/BD_INTERNAL_EW_L2FIB_h1/fib/"
+	broker := membroker.New()
+	render(broker, element("c1", "02:00:00:00:00:01"), element("c2", "02:00:00:00:00:02"))
+
+	fibs := broker.Dump(fibPrefix)
+	fib := &l2.FibTableEntries_FibTableEntry{}
+	if err := json.Unmarshal(fibs[fibPrefix+"02:00:00:00:00:02"], fib); err != nil {
+		t.Fatalf("missing the l2fib of c2: %v", fibs)
+	}
+	if len(fibs) != 2 || fib.OutgoingInterface != "IF_MEMIF_VSWITCH_c2_port1" {
+		t.Errorf("unexpected l2fib: %v", fibs)
+	}
+
+	render(broker, element("c1", "02:00:00:00:00:01"))
+	if fibs := broker.Dump(fibPrefix); len(fibs) != 1 || fibs[fibPrefix+"02:00:00:00:00:01"] == nil {
+		t.Errorf("expected only the l2fib of c1 after c2 is removed: %v", fibs)
+	}
+	bdKey := "/vnf-agent/h1/vpp/config/v1/bd/BD_INTERNAL_EW_L2FIB_h1"
+	if len(broker.Dump(bdKey)[bdKey]) == 0 {
+		t.Error("the bridge is removed with its l2fib entries")
+	}
+}
+
+// the static arp and ipv6 neighbor entries of a vrf chain's element are diffed on resync, the removed ones are deleted
+func TestStaticArpReconcile(t *testing.T) {
+
+	bdParms := &controller.BDParms{Learn: true, Flood: true, UnknownUnicastFlood: true, Forward: true}
+	sp := &controller.SystemParameters{Mtu: 1500, StaticBridgeParms: bdParms, DynamicBridgeParms: bdParms}
+	he := &controller.HostEntity{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"}
+	render := func(broker *membroker.Broker, arps []*controller.L3ArpEntry, neighbors []*controller.L3ArpEntry) {
+		cnpd, err := cnpdriver.RegisterCNPDriverPlugin("sfcctlrl2", broker.NewBroker)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.SetSystemParameters(sp); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.ReconcileStart(map[string]struct{}{"h1": {}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.WireInternalsForHostEntity(he); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.WireSfcEntity(&controller.SfcEntity{Name: "s1", Type: controller.SfcType_SFC_NS_NIC_VRF,
+			Elements: []*controller.SfcEntity_SfcElement{
+				{Container: "h1", PortLabel: "eth1", Type: controller.SfcElementType_HOST_ENTITY,
+					L3ArpEntries: arps, L3Ipv6Neighbors: neighbors},
+				{Container: "c1", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := cnpd.ReconcileEnd(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	arpPrefix := "/vnf-agent/h1/vpp/config/v1/arp/"
+	broker := membroker.New()
+	render(broker, []*controller.L3ArpEntry{
+		{IpAddress: "10.2.0.254", PhysAddress: "02:00:00:00:00:01"},
+		{IpAddress: "10.2.0.253", PhysAddress: "02:00:00:00:00:02"}},
+		[]*controller.L3ArpEntry{{IpAddress: "fd00::1", PhysAddress: "02:00:00:00:00:03"}})
+
+	arps := broker.Dump(arpPrefix)
+	arp := &l3.ArpTable_ArpTableEntry{}
+	if err := json.Unmarshal(arps[arpPrefix+"eth1/fd00::1"], arp); err != nil {
+		t.Fatalf("missing the ipv6 neighbor: %v", arps)
+	}
+	if len(arps) != 3 || !arp.Static || arp.PhysAddress != "02:00:00:00:00:03" {
+		t.Errorf("unexpected arp entries: %v", arps)
+	}
+
+	render(broker, []*controller.L3ArpEntry{{IpAddress: "10.2.0.254", PhysAddress: "02:00:00:00:00:04"}}, nil)
+	arps = broker.Dump(arpPrefix)
+	if err := json.Unmarshal(arps[arpPrefix+"eth1/10.2.0.254"], arp); err != nil || len(arps) != 1 ||
+		arp.PhysAddress != "02:00:00:00:00:04" {
+		t.Errorf("expected only the updated arp entry after the others are removed: %v", arps)
+	}
+
+	render(broker, nil, nil)
+	if arps := broker.Dump(arpPrefix); len(arps) != 0 {
+		t.Errorf("the arp entries of the element are not deleted: %v", arps)
+	}
+}
+
+// an ee named by a wiring policy is wired only to the hosts its selector matches, the others to every host
+func TestWiringPolicies(t *testing.T) {
+
+	ee := func(name string, ipv4 string, prefix string) controller.ExternalEntity {
+		return controller.ExternalEntity{Name: name, MgmntIpAddress: "192.168.0." + name[1:],
+			HostInterface: &controller.ExternalEntity_HostInterface{IfName: "ge0", Ipv4Addr: ipv4 + "/24"},
+			HostVxlan:     &controller.ExternalEntity_HostVxlan{SourceIpv4: ipv4},
+			Prefixes:      []*controller.ExternalEntity_Prefix{{DstIpAddr: prefix}}}
+	}
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{
+			{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24", Labels: map[string]string{"edge": "true"}},
+			{Name: "h2", EthIfName: "eth0", EthIpv4: "10.0.0.2/24"},
+		},
+		EEs: []controller.ExternalEntity{
+			ee("r1", "10.0.0.254", "172.16.0.0/16"),
+			ee("r2", "10.0.0.253", "172.17.0.0/16"),
+		},
+		WPs: []controller.WiringPolicy{
+			{Name: "edge", HostSelector: "edge=true", ExternalEntities: []string{"r1"}},
+		},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string][]string{"h1": {"10.0.0.253", "10.0.0.254"}, "h2": {"10.0.0.253"}}
+	for host, nextHops := range expected {
+		var got []string
+		for key, value := range broker.Dump("/vnf-agent/" + host + "/vpp/config/v1/vrf/") {
+			sr := &l3.StaticRoutes_Route{}
+			if err := json.Unmarshal(value, sr); err != nil {
+				t.Fatalf("%s: %s", key, err)
+			}
+			got = append(got, sr.NextHopAddr)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(nextHops, ",") {
+			t.Errorf("%s: expected routes via: %v, got: %v", host, nextHops, got)
+		}
+	}
+
+	cfg.WPs[0].HostSelector = "=true"
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("a wiring policy with an invalid host_selector is accepted")
+	}
+}
+
+// the ids the driver allocated are reported from its datastore, all of them or those of a host and of a chain
+func TestCnpDriverGetAllocatedIDs(t *testing.T) {
+
+	broker := membroker.New()
+	cnpd, err := cnpdriver.RegisterCNPDriverPlugin("sfcctlrl2", broker.NewBroker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bdParms := &controller.BDParms{Learn: true, UnknownUnicastFlood: true, Flood: true, Forward: true}
+	if err := cnpd.SetSystemParameters(&controller.SystemParameters{Mtu: 1500, StartingVlanId: 5000,
+		DynamicBridgeParms: bdParms, StaticBridgeParms: bdParms}); err != nil {
+		t.Fatal(err)
+	}
+	hes := []controller.HostEntity{
+		{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24", VxlanTunnelIpv4: "10.0.1.1/32"},
+		{Name: "h2", EthIfName: "eth0", EthIpv4: "10.0.0.2/24", VxlanTunnelIpv4: "10.0.1.2/32"},
+	}
+	for i := range hes {
+		if err := cnpd.WireInternalsForHostEntity(&hes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cnpd.WireHostEntityToDestinationHostEntity(&hes[0], &hes[1]); err != nil {
+		t.Fatal(err)
+	}
+	for _, sfc := range []*controller.SfcEntity{
+		{Name: "c1", Type: controller.SfcType_SFC_EW_BD, Elements: []*controller.SfcEntity_SfcElement{
+			{Container: "a", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+			{Container: "b", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+		}},
+		{Name: "c2", Type: controller.SfcType_SFC_NS_VXLAN, Elements: []*controller.SfcEntity_SfcElement{
+			{Container: "h2", PortLabel: "eth0", Type: controller.SfcElementType_HOST_ENTITY},
+			{Container: "c", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+		}},
+	} {
+		if err := cnpd.WireSfcEntity(sfc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids, err := cnpd.GetAllocatedIDs("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids.HostToHosts) != 1 || ids.HostToHosts[0].VlanId == 0 || len(ids.SfcElements) != 3 {
+		t.Errorf("unexpected ids: %+v", ids)
+	}
+	memifIDs := make(map[uint32]bool)
+	for _, element := range ids.SfcElements {
+		if element.MemifId == 0 || memifIDs[element.MemifId] {
+			t.Errorf("element: %s/%s: memif id: %d not unique", element.Container, element.Port, element.MemifId)
+		}
+		memifIDs[element.MemifId] = true
+	}
+
+	ids, err = cnpd.GetAllocatedIDs("h2", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids.Hosts) != 1 || ids.Hosts[0].Name != "h2" || len(ids.HostToHosts) != 1 || len(ids.SfcElements) != 0 {
+		t.Errorf("h2: unexpected ids: %+v", ids)
+	}
+	ids, err = cnpd.GetAllocatedIDs("h1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, element := range ids.SfcElements {
+		if element.Host != "h1" {
+			t.Errorf("h1: element of another host: %+v", element)
+		}
+	}
+
+	ids, err = cnpd.GetAllocatedIDs("", "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids.HostToHosts) != 0 || len(ids.SfcElements) != 2 {
+		t.Errorf("c1: unexpected ids: %+v", ids)
+	}
+	ids, err = cnpd.GetAllocatedIDs("h2", "c2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids.Hosts) != 0 || len(ids.SfcElements) != 0 {
+		t.Errorf("c2 on h2: unexpected ids: %+v", ids)
+	}
+}
+
+// the elements of a chain on a kernel host are terminated into veths, rendered with the linux models only
+func TestKernelHost(t *testing.T) {
+
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"},
+			{Name: "h2", EthIpv4: "10.0.0.2/24", HostType: controller.HostType_HOST_KERNEL}},
+		SFCs: []controller.SfcEntity{{Name: "s1", Type: controller.SfcType_SFC_EW_BD, SfcIpv4Prefix: "10.1.0.0/24",
+			Elements: []*controller.SfcEntity_SfcElement{
+				{Container: "c1", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+				{Container: "c2", PortLabel: "port1", EtcdVppSwitchKey: "h2",
+					Type: controller.SfcElementType_NON_VPP_CONTAINER_AFP}}}},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	if vpp := broker.Dump("/vnf-agent/h2/vpp/"); len(vpp) != 0 {
+		t.Errorf("vpp config is rendered for the kernel host: %v", vpp)
+	}
+	if vxlans := broker.Dump("/vnf-agent/h1/vpp/config/v1/interface/IF_VXLAN"); len(vxlans) != 0 {
+		t.Errorf("the vpp host tunnels to the kernel host: %v", vxlans)
+	}
+	for _, ifName := range []string{"IF_VETH_VNF_c2_port1", "IF_VETH_VSWITCH_c2_port1"} {
+		if len(broker.Dump("/vnf-agent/h2/linux/config/v1/interface/"+ifName)) != 1 {
+			t.Errorf("veth: '%s' is not rendered on the kernel host", ifName)
+		}
+	}
+	routes := broker.Dump("/vnf-agent/h2/linux/config/v1/route/")
+	if len(routes) != 1 {
+		t.Fatalf("expected the host route to c2: %v", routes)
+	}
+	for key, value := range routes {
+		if !strings.Contains(string(value), "IF_VETH_VSWITCH_c2_port1") || !strings.Contains(string(value), "/32") {
+			t.Errorf("%s: unexpected route: %s", key, value)
+		}
+	}
+	bdKey := "/vnf-agent/h1/vpp/config/v1/bd/BD_INTERNAL_EW_h1"
+	if value := string(broker.Dump(bdKey)[bdKey]); !strings.Contains(value, "IF_MEMIF_VSWITCH_c1_port1") {
+		t.Errorf("the vpp host's element is not bridged: %s", value)
+	}
+
+	cfg.SFCs[0].Elements[1].Type = controller.SfcElementType_VPP_CONTAINER_MEMIF
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("a vpp container is accepted on the kernel host")
+	}
+	cfg.SFCs[0].Elements[1].Type = controller.SfcElementType_NON_VPP_CONTAINER_AFP
+	cfg.HEs[1].LoopbackIpv4 = "10.9.0.2/32"
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("a loopback is accepted on the kernel host")
+	}
+}
+
+// a chain whose ttl expired is not wired, one whose ttl has not expired yet is
+func TestSfcExpiry(t *testing.T) {
+
+	tap := func(name string, ttl uint32, expiresAt int64) controller.SfcEntity {
+		return controller.SfcEntity{
+			Name:       name,
+			Type:       controller.SfcType_SFC_EW_L2XCONN,
+			TtlSeconds: ttl,
+			ExpiresAt:  expiresAt,
+			Elements: []*controller.SfcEntity_SfcElement{
+				{Container: name + "-a", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+				{Container: name + "-b", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+					Type: controller.SfcElementType_NON_VPP_CONTAINER_AFP},
+			},
+		}
+	}
+
+	base := renderBasic(t)
+	expired := renderBasic(t, tap("tap1", 60, time.Now().Add(-time.Minute).Unix()))
+	if !reflect.DeepEqual(expired, base) {
+		t.Errorf("the expired chain is wired: %d keys, expected: %d", len(expired), len(base))
+	}
+	live := renderBasic(t, tap("tap1", 3600, 0))
+	if len(live) <= len(base) {
+		t.Errorf("the chain whose ttl has not expired is not wired: %d keys, expected more than: %d", len(live),
+			len(base))
+	}
+}
+
+// a chain marked deleting stays wired until its grace period ends, one waiting for a confirm stays wired
+func TestSfcSoftDelete(t *testing.T) {
+
+	deleting := func(name string, deleteAt int64) controller.SfcEntity {
+		return controller.SfcEntity{
+			Name:          name,
+			Type:          controller.SfcType_SFC_EW_L2XCONN,
+			DeletingSince: time.Now().Add(-time.Hour).Unix(),
+			DeleteAt:      deleteAt,
+			Elements: []*controller.SfcEntity_SfcElement{
+				{Container: name + "-a", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+					Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+				{Container: name + "-b", PortLabel: "port1", EtcdVppSwitchKey: "vswitch",
+					Type: controller.SfcElementType_NON_VPP_CONTAINER_AFP},
+			},
+		}
+	}
+
+	base := renderBasic(t)
+	ended := renderBasic(t, deleting("old", time.Now().Add(-time.Minute).Unix()))
+	if !reflect.DeepEqual(ended, base) {
+		t.Errorf("the chain whose grace period ended is wired: %d keys, expected: %d", len(ended), len(base))
+	}
+	if grace := renderBasic(t, deleting("old", time.Now().Add(time.Hour).Unix())); len(grace) <= len(base) {
+		t.Errorf("the chain in its grace period is not wired: %d keys, expected more than: %d", len(grace),
+			len(base))
+	}
+	if unconfirmed := renderBasic(t, deleting("old", 0)); len(unconfirmed) <= len(base) {
+		t.Errorf("the chain waiting for a confirm is not wired: %d keys, expected more than: %d", len(unconfirmed),
+			len(base))
+	}
+}
+
+// a vni, mac or ip seen in a capture is traced back to the chain, element and host it was rendered for
+func TestCnpDriverForensicLookup(t *testing.T) {
+
+	broker := membroker.New()
+	cnpd, err := cnpdriver.RegisterCNPDriverPlugin("sfcctlrl2", broker.NewBroker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bdParms := &controller.BDParms{Learn: true, UnknownUnicastFlood: true, Flood: true, Forward: true}
+	if err := cnpd.SetSystemParameters(&controller.SystemParameters{Mtu: 1500, StartingVlanId: 5000,
+		DynamicBridgeParms: bdParms, StaticBridgeParms: bdParms}); err != nil {
+		t.Fatal(err)
+	}
+	hes := []controller.HostEntity{
+		{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24", VxlanTunnelIpv4: "10.0.1.1/32"},
+		{Name: "h2", EthIfName: "eth0", EthIpv4: "10.0.0.2/24", VxlanTunnelIpv4: "10.0.1.2/32"},
+	}
+	for i := range hes {
+		if err := cnpd.WireInternalsForHostEntity(&hes[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cnpd.WireHostEntityToDestinationHostEntity(&hes[0], &hes[1]); err != nil {
+		t.Fatal(err)
+	}
+	for _, sfc := range []*controller.SfcEntity{
+		{Name: "c1", Type: controller.SfcType_SFC_EW_BD, SfcIpv4Prefix: "10.1.0.0/24", Elements: []*controller.SfcEntity_SfcElement{
+			{Container: "a", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+			{Container: "b", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+		}},
+		{Name: "c2", Type: controller.SfcType_SFC_NS_VXLAN, Elements: []*controller.SfcEntity_SfcElement{
+			{Container: "h2", PortLabel: "eth0", Type: controller.SfcElementType_HOST_ENTITY},
+			{Container: "c", PortLabel: "port1", EtcdVppSwitchKey: "h1",
+				Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+		}},
+	} {
+		if err := cnpd.WireSfcEntity(sfc); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ids, err := cnpd.GetAllocatedIDs("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids.HostToHosts) != 1 {
+		t.Fatalf("unexpected ids: %+v", ids)
+	}
+	matches, err := cnpd.ForensicLookup(&l2cnpdriver.ForensicQuery{Vni: ids.HostToHosts[0].VlanId})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Owner != "host_to_host_vxlan" || matches[0].Host != "h1" ||
+		matches[0].PeerHost != "h2" || !reflect.DeepEqual(matches[0].Sfcs, []string{"c2"}) {
+		t.Errorf("vni: unexpected matches: %+v", matches)
+	}
+
+	ipv4, _, mac, err := cnpd.GetSfcInterfaceIPAndMac("b", "port1")
+	if err != nil || ipv4 == "" || mac == "" {
+		t.Fatalf("no address or mac for b/port1: %v", err)
+	}
+	matches, err = cnpd.ForensicLookup(&l2cnpdriver.ForensicQuery{Mac: strings.ToLower(mac)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Owner != "sfc_element" || matches[0].Sfc != "c1" ||
+		matches[0].Container != "b" || matches[0].Port != "port1" || matches[0].Host != "h1" {
+		t.Errorf("mac: %s: unexpected matches: %+v", mac, matches)
+	}
+	matches, err = cnpd.ForensicLookup(&l2cnpdriver.ForensicQuery{IP: ipv4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Container != "b" {
+		t.Errorf("ip: %s: unexpected matches: %+v", ipv4, matches)
+	}
+
+	matches, err = cnpd.ForensicLookup(&l2cnpdriver.ForensicQuery{IP: "10.0.0.2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Owner != "host" || matches[0].Host != "h2" || matches[0].Interface != "eth0" {
+		t.Errorf("host ip: unexpected matches: %+v", matches)
+	}
+	if matches, err = cnpd.ForensicLookup(&l2cnpdriver.ForensicQuery{IP: "192.0.2.1"}); err != nil || len(matches) != 0 {
+		t.Errorf("unknown ip: unexpected matches: %+v, %v", matches, err)
+	}
+}
+
+// two federated sites render the inter-site segment of a chain alike, once each learned the other's endpoint
+func TestFederationSegment(t *testing.T) {
+
+	site := func(name string, host string, tunnel string, peer string, peerTunnel string,
+		vni uint32) *core.YamlConfig {
+		return &core.YamlConfig{
+			SysParms: controller.SystemParameters{FederationSite: name},
+			HEs: []controller.HostEntity{
+				{Name: host, EthIfName: "eth0", EthIpv4: tunnel + "/24", VxlanTunnelIpv4: tunnel},
+			},
+			EEs: []controller.ExternalEntity{
+				{Name: peer, MgmntIpAddress: "192.168.0.1",
+					EeDriverType: controller.ExtEntDriverType_EE_DRIVER_TYPE_SFC_CONTROLLER,
+					HostVxlan:    &controller.ExternalEntity_HostVxlan{SourceIpv4: peerTunnel, Vni: vni}},
+			},
+			SFCs: []controller.SfcEntity{
+				{Name: "span", Type: controller.SfcType_SFC_NS_VXLAN,
+					Elements: []*controller.SfcEntity_SfcElement{
+						{Container: peer, PortLabel: "vxlan", Type: controller.SfcElementType_EXTERNAL_ENTITY},
+						{Container: name + "-vnf", PortLabel: "port1", EtcdVppSwitchKey: host,
+							Type: controller.SfcElementType_VPP_CONTAINER_MEMIF},
+					}},
+			},
+		}
+	}
+	segment := func(cfg *core.YamlConfig) *interfaces.Interfaces_Interface {
+		broker := membroker.New()
+		core.RenderConfig(cfg, broker.NewBroker) // a chain to a peer without an endpoint fails to render
+		host, peer := cfg.HEs[0].Name, cfg.EEs[0].Name
+		vxlan := &interfaces.Interfaces_Interface{}
+		key := "/vnf-agent/" + host + "/vpp/config/v1/interface/IF_VXLAN_H2E_" + host + "_" + peer
+		if found, _, err := broker.NewBroker("").GetValue(key, vxlan); err != nil || !found {
+			return nil
+		}
+		return vxlan
+	}
+
+	a := segment(site("site-a", "gw-a", "10.1.0.1", "site-b", "10.2.0.1", 5123))
+	b := segment(site("site-b", "gw-b", "10.2.0.1", "site-a", "10.1.0.1", 5123))
+	if a == nil || b == nil {
+		t.Fatalf("the inter-site segment is not rendered: site-a: %v, site-b: %v", a, b)
+	}
+	if a.Vxlan.Vni != 5123 || b.Vxlan.Vni != 5123 {
+		t.Errorf("the sites do not use the agreed vni: site-a: %d, site-b: %d", a.Vxlan.Vni, b.Vxlan.Vni)
+	}
+	if a.Vxlan.SrcAddress != b.Vxlan.DstAddress || a.Vxlan.DstAddress != b.Vxlan.SrcAddress {
+		t.Errorf("the tunnel ends do not match: site-a: %v, site-b: %v", a.Vxlan, b.Vxlan)
+	}
+
+	if unsynced := segment(site("site-b", "gw-b", "10.2.0.1", "site-a", "", 0)); unsynced != nil {
+		t.Errorf("the segment is rendered before the peer told its endpoint: %v", unsynced)
+	}
+}
+
+// a config file with fields that are not in the schema, values of the wrong type or undefined enum values is
+// refused with the line and path of each
+func TestConfigSchema(t *testing.T) {
+
+	cfg := `sfc_controller_config_version: 1
+host_entities:
+    - name: vswitch
+      create_vxlan_static_route: yes
+      mtu: -1
+sfc_entities:
+    - name: vnf1-vnf2
+      type: 42
+      elements:
+          - container: vnf1
+            port_lable: port1
+            etcd_vpp_switch_key: vswitch
+            type: 2
+          - container: vnf2
+            port_label: [port1]
+            type: 2
+system_parameters:
+    mtu: 1500
+    starting_vlan_id: "5000"
+`
+	_, err := core.ParseYamlConfig([]byte(cfg))
+	schemaErrs, ok := err.(core.SchemaErrors)
+	if !ok {
+		t.Fatalf("expected schema errors, got: %v", err)
+	}
+	expected := []struct {
+		line  int
+		field string
+	}{
+		{5, "host_entities[0].mtu"},
+		{8, "sfc_entities[0].type"},
+		{11, "sfc_entities[0].elements[0].port_lable"},
+		{15, "sfc_entities[0].elements[1].port_label"},
+		{19, "system_parameters.starting_vlan_id"},
+	}
+	if len(schemaErrs) != len(expected) {
+		t.Fatalf("expected %d errors, got: %v", len(expected), err)
+	}
+	for i, e := range expected {
+		if schemaErrs[i].Line != e.line || schemaErrs[i].Field != e.field {
+			t.Errorf("expected line %d: %s, got: %s", e.line, e.field, schemaErrs[i])
+		}
+	}
+	if !strings.Contains(schemaErrs[2].Message, "did you mean: 'port_label'") {
+		t.Errorf("the typo is not pointed at the field: %s", schemaErrs[2])
+	}
+
+	// json is checked alike
+	_, err = core.ParseYamlConfig([]byte("{\n  \"host_entities\": [],\n  \"sfc_entitys\": []\n}\n"))
+	if schemaErrs, ok := err.(core.SchemaErrors); !ok || len(schemaErrs) != 1 || schemaErrs[0].Line != 3 {
+		t.Errorf("expected the unknown json field on line 3, got: %v", err)
+	}
+
+	if _, err := LoadConfig("testdata/basic.yaml"); err != nil {
+		t.Errorf("a valid config is refused: %s", err)
+	}
+}
+
+// the port_parms of an element are rendered as the ingress acl of its vswitch i/f and, for a port that does not
+// learn, as the l2fib entry of its mac, and the acl of a port that forwards both families again is removed
+func TestBridgePortParms(t *testing.T) {
+
+	element := func(container string, macAddr string) *controller.SfcEntity_SfcElement {
+		return &controller.SfcEntity_SfcElement{Container: container, PortLabel: "port1", EtcdVppSwitchKey: "h1",
+			MacAddr: macAddr, Type: controller.SfcElementType_VPP_CONTAINER_MEMIF}
+	}
+	c1 := element("c1", "02:00:00:00:00:01")
+	c1.PortParms = &controller.PortParms{StaticLearningOnly: true, NoIp6Forward: true}
+	cfg := &core.YamlConfig{
+		HEs: []controller.HostEntity{{Name: "h1", EthIfName: "eth0", EthIpv4: "10.0.0.1/24"}},
+		SFCs: []controller.SfcEntity{{Name: "s1", Type: controller.SfcType_SFC_EW_BD_L2FIB,
+			BdParms:  &controller.BDParms{Learn: true, Flood: true, UnknownUnicastFlood: true, Forward: true},
+			Elements: []*controller.SfcEntity_SfcElement{c1, element("c2", "02:00:00:00:00:02")}}},
+	}
+
+	broker := membroker.New()
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+
+	aclKey := "/vnf-agent/h1/" + acl.Key("PORT_IF_MEMIF_VSWITCH_c1_port1")
+	portACL := &acl.AccessLists_Acl{}
+	if err := json.Unmarshal(broker.Dump(aclKey)[aclKey], portACL); err != nil {
+		t.Fatalf("%s: %s", aclKey, err)
+	}
+	if portACL.Interfaces == nil ||
+		!reflect.DeepEqual(portACL.Interfaces.Ingress, []string{"IF_MEMIF_VSWITCH_c1_port1"}) ||
+		len(portACL.Rules) != 2 || portACL.Rules[0].Actions.AclAction != acl.AclAction_PERMIT ||
+		portACL.Rules[0].Matches.IpRule.Ip.SourceNetwork != "0.0.0.0/0" ||
+		portACL.Rules[1].Actions.AclAction != acl.AclAction_DENY ||
+		portACL.Rules[1].Matches.IpRule.Ip.SourceNetwork != "::/0" {
+		t.Errorf("unexpected acl of c1: %v", portACL)
+	}
+	aclPrefix := "/vnf-agent/h1/" + acl.KeyPrefix()
+	if acls := broker.Dump(aclPrefix); len(acls) != 1 {
+		t.Errorf("expected only the acl of c1: %v", acls)
+	}
+	fibPrefix := "/vnf-agent/h1/vpp/config/v1/bd/BD_INTERNAL_EW_s1_h1/fib/"
+	if fibs := broker.Dump(fibPrefix); len(fibs) != 1 || fibs[fibPrefix+"02:00:00:00:00:01"] == nil {
+		t.Errorf("expected only the l2fib of the port that does not learn: %v", fibs)
+	}
+
+	c1.PortParms = &controller.PortParms{StaticLearningOnly: true}
+	if err := core.RenderConfig(cfg, broker.NewBroker); err != nil {
+		t.Fatal(err)
+	}
+	if acls := broker.Dump(aclPrefix); len(acls) != 0 {
+		t.Errorf("the acl of a port that forwards both families is left: %v", acls)
+	}
+
+	cfg.SFCs[0].Type = controller.SfcType_SFC_EW_L2XCONN
+	if err := core.RenderConfig(cfg, membroker.New().NewBroker); err == nil {
+		t.Error("the port_parms of an element that is not in a bridge domain are accepted")
+	}
+}
@@ -1,7 +1,9 @@
 package itest
 
 import (
+	"context"
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/ligato/cn-infra/core"
@@ -14,6 +16,8 @@ import (
 	"io/ioutil"
 	"time"
 
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/etcdserver/api/v3client"
 	"github.com/golang/protobuf/proto"
 	agent_api "github.com/ligato/cn-infra/core"
 	"github.com/ligato/cn-infra/datasync"
@@ -26,6 +30,7 @@ import (
 	"github.com/ligato/cn-infra/servicelabel"
 	sfccore "github.com/ligato/sfc-controller/controller/core"
 	"github.com/ligato/sfc-controller/controller/model/controller"
+	"github.com/ligato/sfc-controller/controller/utils"
 	"github.com/ligato/sfc-controller/plugins/vnfdriver"
 	vppiface "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
 	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
@@ -46,6 +51,9 @@ type AgentTestHelper struct {
 
 	golangT *testing.T
 	stopDB  func()
+
+	embedETCD  *etcdmock.Embedded
+	fakeAgents map[string]*FakeAgent
 }
 
 // Given is composition of multiple test step methods (see BDD Given keyword)
@@ -55,6 +63,7 @@ type Given struct {
 
 // When is composition of multiple test step methods (see BDD When keyword)
 type When struct {
+	agentT *AgentTestHelper
 }
 
 // Then is composition of multiple test step methods (see BDD Then keyword)
@@ -74,20 +83,29 @@ func (t *AgentTestHelper) DefaultSetup(golangT *testing.T) {
 		ETCD:        *etcdPlug,
 	}
 	t.stopDB = embedETCD.Stop
+	t.embedETCD = embedETCD
+	t.fakeAgents = make(map[string]*FakeAgent)
 
 	t.httpMock = MockHTTP()
 
-	t.sfcFalvor = &Flavor{
-		FlavorLocal: &local.FlavorLocal{ServiceLabel: servicelabel.Plugin{MicroserviceLabel: "sfc-controller"}},
-		HTTP:        *rest.FromExistingServer(t.httpMock.SetHandler),
-		ETCD:        *etcdPlug}
-
 	t.tAgent = core.NewAgent(tFlavorLocal.LoggerFor("tAgent"), 1*time.Second, t.tFlavor.Plugins()...)
 	err := t.tAgent.Start()
 	if err != nil {
 		panic(err)
 	}
 
+	t.newSfcAgent()
+}
+
+// newSfcAgent creates the sfcAgent with its own connection to the embedded ETCD, stopping the sfcAgent
+// closes the connection so the testing connectivity survives a restart of the SFC Controller
+func (t *AgentTestHelper) newSfcAgent() {
+	flavorLocal := &local.FlavorLocal{ServiceLabel: servicelabel.Plugin{MicroserviceLabel: "sfc-controller"}}
+	t.sfcFalvor = &Flavor{
+		FlavorLocal: flavorLocal,
+		HTTP:        *rest.FromExistingServer(t.httpMock.SetHandler),
+		ETCD:        *ConnectEmbeddedETCD(t.embedETCD, flavorLocal)}
+
 	t.sfcAgent = core.NewAgent(logrus.DefaultLogger(), 2000*time.Second, t.sfcFalvor.Plugins()...)
 }
 
@@ -99,6 +117,19 @@ func (t *Given) StartAgent() {
 	}
 }
 
+// FakeAgents starts a fake vpp-agent acknowledging the config for each of the microservice labels
+func (t *Given) FakeAgents(microserviceLabels ...string) {
+	for _, label := range microserviceLabels {
+		t.agentT.fakeAgents[label] = StartFakeAgent(t.agentT.tFlavor.ETCD.NewBroker(""), label)
+	}
+}
+
+// SystemParametersViaETCD puts the SFC Controller system parameters to keyvalue store (e.g. ETCD)
+func (t *Given) SystemParametersViaETCD(sp *controller.SystemParameters) {
+	db := t.agentT.tFlavor.ETCD.NewBroker("" /*TODO use Root Const*/)
+	db.Put(controller.SystemParametersKey(), sp)
+}
+
 // EmptyETCD deletes all keys in ETCD
 func (t *Given) EmptyETCD() {
 	db := t.agentT.tFlavor.ETCD.NewBroker("" /*TODO use Root Const*/)
@@ -152,6 +183,103 @@ func (t *Given) ConfigSFCviaREST(cfg *sfccore.YamlConfig) {
 	}
 }
 
+// RestartController stops the SFC Controller and starts a new instance of it on the same ETCD,
+// the new instance reconciles the vpp-agent configuration with the config in ETCD
+func (t *When) RestartController() {
+	if err := t.agentT.sfcAgent.Stop(); err != nil {
+		t.agentT.golangT.Fatal("error stopping sfcAgent ", err)
+	}
+	t.agentT.newSfcAgent()
+	if err := t.agentT.sfcAgent.Start(); err != nil {
+		t.agentT.golangT.Fatal("error restarting sfcAgent ", err)
+	}
+}
+
+// RestartFakeAgent simulates a restart of the vpp-agent, if clearConfig is set it lost its config
+func (t *When) RestartFakeAgent(microserviceLabel string, clearConfig bool) {
+	agent, exists := t.agentT.fakeAgents[microserviceLabel]
+	if !exists {
+		t.agentT.golangT.Fatal("no fake agent ", microserviceLabel)
+	}
+	agent.Restart(clearConfig)
+}
+
+// VppAgentCfg returns the vpp-agent configuration of the agent by key, ie to compare it after a restart
+func (t *Then) VppAgentCfg(microserviceLabel string) map[string]string {
+	prefix := servicelabel.GetDifferentAgentPrefix(microserviceLabel) + "vpp/config/"
+	resp, err := t.agentT.embedETCD.Client().Get(context.Background(), prefix, clientv3.WithPrefix())
+	gomega.Expect(err).Should(gomega.BeNil(), "error reading "+prefix)
+
+	cfg := make(map[string]string)
+	for _, kv := range resp.Kvs {
+		cfg[string(kv.Key)] = string(kv.Value)
+	}
+	return cfg
+}
+
+// VppAgentCfgEquals checks that the agent config is (eventually) exactly the expected config returned
+// by VppAgentCfg
+func (t *Then) VppAgentCfgEquals(microserviceLabel string, expected map[string]string) {
+	gomega.Expect(expected).ShouldNot(gomega.BeEmpty(), "no agent config to compare to")
+	gomega.Eventually(func() map[string]string {
+		return t.VppAgentCfg(microserviceLabel)
+	}, 5*time.Second, fakeAgentPollInterval).Should(gomega.Equal(expected), "agent config changed")
+}
+
+// AgentAcked checks that the fake agent acknowledged all the interfaces and bridge domains of its config
+func (t *Then) AgentAcked(microserviceLabel string) {
+	gomega.Eventually(func() []string {
+		return t.unackedAgentCfg(microserviceLabel)
+	}, 5*time.Second, fakeAgentPollInterval).Should(gomega.BeEmpty(), "config not acknowledged by "+microserviceLabel)
+}
+
+// unackedAgentCfg returns the names of the interfaces and bridge domains of the agent without a state key
+func (t *Then) unackedAgentCfg(microserviceLabel string) []string {
+	db := t.agentT.tFlavor.ETCD.NewBroker("")
+	var unacked []string
+
+	ifs, err := db.ListValues(utils.InterfacePrefixKey(microserviceLabel))
+	gomega.Expect(err).Should(gomega.BeNil(), "error listing interfaces")
+	for {
+		kv, stop := ifs.GetNext()
+		if stop {
+			break
+		}
+		iface := &vppiface.Interfaces_Interface{}
+		if err := kv.GetValue(iface); err == nil &&
+			!t.keyExists(utils.InterfaceStateKey(microserviceLabel, iface.Name)) {
+			unacked = append(unacked, iface.Name)
+		}
+	}
+	ifs.Close()
+
+	bdPrefix := utils.L2BridgeDomainKeyPrefix(microserviceLabel)
+	bds, err := db.ListValues(bdPrefix)
+	gomega.Expect(err).Should(gomega.BeNil(), "error listing bridge domains")
+	for {
+		kv, stop := bds.GetNext()
+		if stop {
+			break
+		}
+		if strings.Contains(strings.TrimPrefix(kv.GetKey(), bdPrefix), "/") {
+			continue // l2 fib entry
+		}
+		bd := &l2.BridgeDomains_BridgeDomain{}
+		if err := kv.GetValue(bd); err == nil &&
+			!t.keyExists(utils.L2BridgeDomainStateKey(microserviceLabel, bd.Name)) {
+			unacked = append(unacked, bd.Name)
+		}
+	}
+	bds.Close()
+
+	return unacked
+}
+
+func (t *Then) keyExists(key string) bool {
+	resp, err := t.agentT.embedETCD.Client().Get(context.Background(), key, clientv3.WithCountOnly())
+	return err == nil && resp.Count > 0
+}
+
 // VppAgentCfgContains checks whether agent config contains given interfaces and/or bridge domains
 func (t *Then) VppAgentCfgContains(micorserviceLabel string, interfaceBDEtc ...proto.Message) {
 	db := t.agentT.tFlavor.ETCD.NewBroker(servicelabel.GetDifferentAgentPrefix(micorserviceLabel))
@@ -241,6 +369,9 @@ func (t *Then) HTTPGetEntities(sfcCfg *sfccore.YamlConfig) {
 
 // Teardown stops the sfcAgent
 func (t *AgentTestHelper) Teardown() {
+	for _, agent := range t.fakeAgents {
+		agent.Stop()
+	}
 	if t.sfcAgent != nil {
 		err := t.sfcAgent.Stop()
 		if err != nil {
@@ -278,6 +409,18 @@ func StartEmbeddedETCD(t *testing.T, flavorLocal *local.FlavorLocal) (*etcdv3.Pl
 	return etcdv3.FromExistingConnection(etcdBytesCon, &flavorLocal.ServiceLabel), &embeddedETCD
 }
 
+// ConnectEmbeddedETCD returns plugin instance with a new connection to the running embedded ETCD
+func ConnectEmbeddedETCD(embeddedETCD *etcdmock.Embedded, flavorLocal *local.FlavorLocal) *etcdv3.Plugin {
+	etcdClientLogger := flavorLocal.LoggerFor("embedEtcdClient")
+	etcdBytesCon, err := etcdv3.NewEtcdConnectionUsingClient(v3client.New(embeddedETCD.ETCD.Server),
+		etcdClientLogger)
+	if err != nil {
+		panic(err)
+	}
+
+	return etcdv3.FromExistingConnection(etcdBytesCon, &flavorLocal.ServiceLabel)
+}
+
 // Flavor is set of common used generic plugins. This flavour can be used as a base
 // for different flavours. The plugins are initialized in the same order as they appear
 // in the structure.
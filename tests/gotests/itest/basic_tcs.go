@@ -3,6 +3,7 @@ package itest
 import (
 	"testing"
 	sfccore "github.com/ligato/sfc-controller/controller/core"
+	"github.com/ligato/sfc-controller/controller/model/controller"
 	"github.com/golang/protobuf/proto"
 )
 
@@ -18,6 +19,7 @@ type basicTCSuite struct {
 func (t *basicTCSuite) DefaultSetup() {
 	t.AgentTestHelper.DefaultSetup(t.T)
 	t.Given.agentT = &t.AgentTestHelper
+	t.When.agentT = &t.AgentTestHelper
 	t.Then.agentT = &t.AgentTestHelper
 }
 
@@ -48,3 +50,56 @@ func (t *basicTCSuite) TC02HTTPPost(sfcCfg *sfccore.YamlConfig, vppAgentCfg ...
 	t.Then.VppAgentCfgContains("HOST-1", vppAgentCfg...)
 	t.Then.HTTPGetEntities(sfcCfg)
 }
+
+// TC03WaitForAgentAcks asserts the end to end wiring with vpp-agents acknowledging the configuration.
+// The SFC Controller waits for the (fake) agents to confirm every interface and bridge domain it writes.
+func (t *basicTCSuite) TC03WaitForAgentAcks(sfcCfg *sfccore.YamlConfig, vppAgentCfg ... proto.Message) {
+	t.DefaultSetup()
+	defer t.Teardown()
+
+	t.Given.EmptyETCD()
+	t.Given.FakeAgents("HOST-1")
+	t.Given.SystemParametersViaETCD(&controller.SystemParameters{WaitForAgent: true})
+	t.Given.StartAgent()
+	t.Given.ConfigSFCviaREST(sfcCfg)
+	t.Then.VppAgentCfgContains("HOST-1", vppAgentCfg...)
+	t.Then.AgentAcked("HOST-1")
+}
+
+// TC04ReconcileAfterRestart asserts that a restarted SFC Controller reconciles to the same vpp-agent
+// configuration it rendered before the restart.
+func (t *basicTCSuite) TC04ReconcileAfterRestart(sfcCfg *sfccore.YamlConfig, vppAgentCfg ... proto.Message) {
+	t.DefaultSetup()
+	defer t.Teardown()
+
+	t.Given.EmptyETCD()
+	t.Given.FakeAgents("HOST-1")
+	t.Given.ConfigSFCviaETCD(sfcCfg)
+	t.Given.StartAgent()
+	t.Then.AgentAcked("HOST-1")
+	before := t.Then.VppAgentCfg("HOST-1")
+
+	t.When.RestartController()
+	t.Then.VppAgentCfgEquals("HOST-1", before)
+	t.Then.VppAgentCfgContains("HOST-1", vppAgentCfg...)
+	t.Then.HTTPGetEntities(sfcCfg)
+}
+
+// TC05AgentRestartRerender asserts that the SFC Controller renders the configuration again for a vpp-agent
+// that restarted without its configuration.
+func (t *basicTCSuite) TC05AgentRestartRerender(sfcCfg *sfccore.YamlConfig, vppAgentCfg ... proto.Message) {
+	t.DefaultSetup()
+	defer t.Teardown()
+
+	t.Given.EmptyETCD()
+	t.Given.FakeAgents("HOST-1")
+	t.Given.SystemParametersViaETCD(&controller.SystemParameters{AgentWatchInterval: 1})
+	t.Given.ConfigSFCviaETCD(sfcCfg)
+	t.Given.StartAgent()
+	t.Then.AgentAcked("HOST-1")
+	before := t.Then.VppAgentCfg("HOST-1")
+
+	t.When.RestartFakeAgent("HOST-1", true)
+	t.Then.VppAgentCfgEquals("HOST-1", before)
+	t.Then.AgentAcked("HOST-1")
+}
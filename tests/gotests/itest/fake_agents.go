@@ -0,0 +1,189 @@
+package itest
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+	"github.com/ligato/cn-infra/health/statuscheck/model/status"
+	"github.com/ligato/sfc-controller/controller/utils"
+	vppiface "github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/interfaces"
+	"github.com/ligato/vpp-agent/plugins/defaultplugins/common/model/l2"
+)
+
+// fakeAgentPollInterval is how often a fake agent looks for new config in ETCD
+const fakeAgentPollInterval = 50 * time.Millisecond
+
+// FakeAgent simulates a vpp-agent: it publishes its status and acknowledges the interfaces and bridge
+// domains the SFC Controller writes under its microservice label by writing their state keys, or their
+// error keys for the ones it was told to reject
+type FakeAgent struct {
+	Label string
+
+	db        keyval.ProtoBroker
+	startTime int64
+	rejected  map[string]string
+	mutex     sync.Mutex
+	stopChan  chan struct{}
+	wg        sync.WaitGroup
+}
+
+// StartFakeAgent starts acknowledging the config written for the agent with the label
+func StartFakeAgent(db keyval.ProtoBroker, label string) *FakeAgent {
+	agent := &FakeAgent{
+		Label:    label,
+		db:       db,
+		rejected: make(map[string]string),
+		stopChan: make(chan struct{}),
+	}
+	agent.publishStatus()
+
+	agent.wg.Add(1)
+	go agent.run()
+
+	return agent
+}
+
+// Reject makes the agent write an error key instead of the state key for the interface or bridge domain
+func (agent *FakeAgent) Reject(name string, errorMessage string) {
+	agent.mutex.Lock()
+	defer agent.mutex.Unlock()
+	agent.rejected[name] = errorMessage
+}
+
+// Restart simulates a restarted agent: the state it reported is lost and it publishes a new start time,
+// if clearConfig is set it also lost the config it was given
+func (agent *FakeAgent) Restart(clearConfig bool) {
+	agent.mutex.Lock()
+	defer agent.mutex.Unlock()
+
+	agent.db.Delete(utils.InterfaceStatePrefixKey(agent.Label), datasync.WithPrefix())
+	agent.db.Delete(utils.GetVppAgentPrefix()+agent.Label+"/"+l2.BridgeDomainStateKeyPrefix(), datasync.WithPrefix())
+	if clearConfig {
+		agent.db.Delete(utils.InterfacePrefixKey(agent.Label), datasync.WithPrefix())
+		agent.db.Delete(utils.L2BridgeDomainKeyPrefix(agent.Label), datasync.WithPrefix())
+	}
+	agent.publishStatus()
+}
+
+// Stop stops acknowledging the config
+func (agent *FakeAgent) Stop() {
+	close(agent.stopChan)
+	agent.wg.Wait()
+}
+
+func (agent *FakeAgent) run() {
+	defer agent.wg.Done()
+
+	ticker := time.NewTicker(fakeAgentPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-agent.stopChan:
+			return
+		case <-ticker.C:
+			agent.mutex.Lock()
+			agent.acknowledgeInterfaces()
+			agent.acknowledgeBridgeDomains()
+			agent.mutex.Unlock()
+		}
+	}
+}
+
+func (agent *FakeAgent) publishStatus() {
+	// the start time must change on every restart, even within the same second
+	startTime := time.Now().Unix()
+	if startTime <= agent.startTime {
+		startTime = agent.startTime + 1
+	}
+	agent.startTime = startTime
+
+	agent.db.Put(utils.AgentStatusKey(agent.Label), &status.AgentStatus{
+		State:      status.OperationalState_OK,
+		StartTime:  agent.startTime,
+		LastChange: agent.startTime,
+		LastUpdate: agent.startTime,
+	})
+}
+
+func (agent *FakeAgent) acknowledgeInterfaces() {
+	it, err := agent.db.ListValues(utils.InterfacePrefixKey(agent.Label))
+	if err != nil {
+		return
+	}
+	defer it.Close()
+
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			return
+		}
+		iface := &vppiface.Interfaces_Interface{}
+		if err := kv.GetValue(iface); err != nil {
+			continue
+		}
+		if errorMessage, rejected := agent.rejected[iface.Name]; rejected {
+			agent.db.Put(utils.InterfaceErrorKey(agent.Label, iface.Name), &vppiface.InterfaceErrors_Interface{
+				InterfaceName: iface.Name,
+				ErrorData: []*vppiface.InterfaceErrors_Interface_ErrorData{{
+					ChangeType:   "create",
+					ErrorMessage: errorMessage,
+				}},
+			})
+			continue
+		}
+		stateKey := utils.InterfaceStateKey(agent.Label, iface.Name)
+		if found, _, _ := agent.db.GetValue(stateKey, &vppiface.InterfacesState_Interface{}); found {
+			continue
+		}
+		agent.db.Put(stateKey, &vppiface.InterfacesState_Interface{
+			Name:        iface.Name,
+			AdminStatus: vppiface.InterfacesState_Interface_UP,
+			OperStatus:  vppiface.InterfacesState_Interface_UP,
+		})
+	}
+}
+
+func (agent *FakeAgent) acknowledgeBridgeDomains() {
+	it, err := agent.db.ListValues(utils.L2BridgeDomainKeyPrefix(agent.Label))
+	if err != nil {
+		return
+	}
+	defer it.Close()
+
+	for {
+		kv, stop := it.GetNext()
+		if stop {
+			return
+		}
+		// the l2 fib entries are stored under their bridge domain's key
+		if strings.Contains(strings.TrimPrefix(kv.GetKey(), utils.L2BridgeDomainKeyPrefix(agent.Label)), "/") {
+			continue
+		}
+		bd := &l2.BridgeDomains_BridgeDomain{}
+		if err := kv.GetValue(bd); err != nil {
+			continue
+		}
+		if errorMessage, rejected := agent.rejected[bd.Name]; rejected {
+			agent.db.Put(utils.L2BridgeDomainErrorKey(agent.Label, bd.Name), &l2.BridgeDomainErrors_BridgeDomain{
+				BdName: bd.Name,
+				ErrorData: []*l2.BridgeDomainErrors_BridgeDomain_ErrorData{{
+					ChangeType:   "create",
+					ErrorMessage: errorMessage,
+				}},
+			})
+			continue
+		}
+		stateKey := utils.L2BridgeDomainStateKey(agent.Label, bd.Name)
+		if found, _, _ := agent.db.GetValue(stateKey, &l2.BridgeDomainState_BridgeDomain{}); found {
+			continue
+		}
+		agent.db.Put(stateKey, &l2.BridgeDomainState_BridgeDomain{
+			InternalName:   bd.Name,
+			InterfaceCount: uint32(len(bd.Interfaces)),
+		})
+	}
+}
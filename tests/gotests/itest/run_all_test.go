@@ -32,6 +32,18 @@ func Test(t *testing.T) {
 				suite := &basicTCSuite{T: t}
 				suite.TC02HTTPPost(&sfctestdata.VPP1MEMIF2LoopbackVETH, VPP1MEMIF2LoopbackVETH...)
 			})
+			t.Run("TC03WaitForAgentAcks", func(t *testing.T) {
+				suite := &basicTCSuite{T: t}
+				suite.TC03WaitForAgentAcks(&sfctestdata.VPP1MEMIF2LoopbackVETH, VPP1MEMIF2LoopbackVETH...)
+			})
+			t.Run("TC04ReconcileAfterRestart", func(t *testing.T) {
+				suite := &basicTCSuite{T: t}
+				suite.TC04ReconcileAfterRestart(&sfctestdata.VPP1MEMIF2LoopbackVETH, VPP1MEMIF2LoopbackVETH...)
+			})
+			t.Run("TC05AgentRestartRerender", func(t *testing.T) {
+				suite := &basicTCSuite{T: t}
+				suite.TC05AgentRestartRerender(&sfctestdata.VPP1MEMIF2LoopbackVETH, VPP1MEMIF2LoopbackVETH...)
+			})
 		})
 		doneChan <- struct{}{}
 	}()
@@ -0,0 +1,283 @@
+// Copyright (c) 2017 Cisco and/or its affiliates.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at:
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package membroker is an in-memory keyval.ProtoBroker.  It stands in for
+// etcd when the controller's rendering is exercised in tests: values are
+// serialized to json like the etcd plugin does, brokers created with a
+// prefix share the store and see keys relative to their prefix, and the
+// whole store can be listed in key order to compare what was rendered.
+package membroker
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/ligato/cn-infra/datasync"
+	"github.com/ligato/cn-infra/db/keyval"
+)
+
+var serializer = &keyval.SerializerJSON{}
+
+type memValue struct {
+	data     []byte
+	revision int64
+}
+
+type memStore struct {
+	sync.Mutex
+	revision int64
+	kvs      map[string]memValue
+}
+
+// Broker is an in-memory keyval.ProtoBroker, the brokers created from it with NewBroker share its store
+type Broker struct {
+	prefix string
+	store  *memStore
+}
+
+// New returns an empty broker with no prefix
+func New() *Broker {
+	return &Broker{store: &memStore{kvs: make(map[string]memValue)}}
+}
+
+// NewBroker returns a broker sharing the store, prefix is prepended to the keys of all its calls, it has
+// the signature of the dbFactory the controller's cnp driver is given
+func (b *Broker) NewBroker(prefix string) keyval.ProtoBroker {
+	return &Broker{prefix: b.prefix + prefix, store: b.store}
+}
+
+// Put stores the json of value under the key
+func (b *Broker) Put(key string, value proto.Message, opts ...datasync.PutOption) error {
+	data, err := serializer.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	b.store.Lock()
+	defer b.store.Unlock()
+	b.store.put(b.prefix+key, data)
+
+	return nil
+}
+
+// PutIfRevision stores the json of value under the key if the key is at the revision, 0 if it does not
+// exist, it returns whether the value was stored
+func (b *Broker) PutIfRevision(key string, value proto.Message, revision int64) (bool, error) {
+	data, err := serializer.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+
+	b.store.Lock()
+	defer b.store.Unlock()
+	if b.store.kvs[b.prefix+key].revision != revision {
+		return false, nil
+	}
+	b.store.put(b.prefix+key, data)
+
+	return true, nil
+}
+
+func (s *memStore) put(key string, data []byte) {
+	s.revision++
+	s.kvs[key] = memValue{data: data, revision: s.revision}
+}
+
+// NewTxn returns a transaction whose operations are applied together on Commit
+func (b *Broker) NewTxn() keyval.ProtoTxn {
+	return &txn{broker: b}
+}
+
+// GetValue loads the value stored under the key into reqObj
+func (b *Broker) GetValue(key string, reqObj proto.Message) (found bool, revision int64, err error) {
+	b.store.Lock()
+	value, exists := b.store.kvs[b.prefix+key]
+	b.store.Unlock()
+
+	if !exists {
+		return false, 0, nil
+	}
+	if err := serializer.Unmarshal(value.data, reqObj); err != nil {
+		return true, value.revision, err
+	}
+	return true, value.revision, nil
+}
+
+// ListValues iterates, in key order, over the values stored under keys starting with key
+func (b *Broker) ListValues(key string) (keyval.ProtoKeyValIterator, error) {
+	return &keyValIterator{kvs: b.list(key)}, nil
+}
+
+// ListKeys iterates, in key order, over the keys starting with prefix
+func (b *Broker) ListKeys(prefix string) (keyval.ProtoKeyIterator, error) {
+	return &keyIterator{kvs: b.list(prefix)}, nil
+}
+
+// Delete removes the key, or every key starting with it when datasync.WithPrefix is given
+func (b *Broker) Delete(key string, opts ...datasync.DelOption) (existed bool, err error) {
+
+	withPrefix := false
+	for _, opt := range opts {
+		if _, ok := opt.(*datasync.WithPrefixOpt); ok {
+			withPrefix = true
+		}
+	}
+
+	b.store.Lock()
+	defer b.store.Unlock()
+
+	if !withPrefix {
+		_, existed = b.store.kvs[b.prefix+key]
+		delete(b.store.kvs, b.prefix+key)
+		return existed, nil
+	}
+	for storeKey := range b.store.kvs {
+		if strings.HasPrefix(storeKey, b.prefix+key) {
+			delete(b.store.kvs, storeKey)
+			existed = true
+		}
+	}
+	return existed, nil
+}
+
+// Dump returns the json of every value stored under keys starting with prefix, the keys are relative to the
+// broker's prefix
+func (b *Broker) Dump(prefix string) map[string][]byte {
+	dump := make(map[string][]byte)
+	for _, kv := range b.list(prefix) {
+		dump[kv.key] = kv.data
+	}
+	return dump
+}
+
+// list copies the entries under the prefix, sorted by key, so iterators are not affected by later writes
+func (b *Broker) list(prefix string) []*keyVal {
+	b.store.Lock()
+	defer b.store.Unlock()
+
+	var kvs []*keyVal
+	for storeKey, value := range b.store.kvs {
+		if strings.HasPrefix(storeKey, b.prefix+prefix) {
+			kvs = append(kvs, &keyVal{
+				key:      strings.TrimPrefix(storeKey, b.prefix),
+				data:     value.data,
+				revision: value.revision,
+			})
+		}
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].key < kvs[j].key })
+
+	return kvs
+}
+
+type keyVal struct {
+	key      string
+	data     []byte
+	revision int64
+}
+
+func (kv *keyVal) GetKey() string {
+	return kv.key
+}
+
+func (kv *keyVal) GetValue(msg proto.Message) error {
+	return serializer.Unmarshal(kv.data, msg)
+}
+
+func (kv *keyVal) GetPrevValue(msg proto.Message) (prevValueExist bool, err error) {
+	return false, nil
+}
+
+func (kv *keyVal) GetRevision() int64 {
+	return kv.revision
+}
+
+type keyValIterator struct {
+	kvs []*keyVal
+}
+
+func (it *keyValIterator) GetNext() (kv keyval.ProtoKeyVal, stop bool) {
+	if len(it.kvs) == 0 {
+		return nil, true
+	}
+	kv, it.kvs = it.kvs[0], it.kvs[1:]
+	return kv, false
+}
+
+func (it *keyValIterator) Close() error {
+	return nil
+}
+
+type keyIterator struct {
+	kvs []*keyVal
+}
+
+func (it *keyIterator) GetNext() (key string, rev int64, stop bool) {
+	if len(it.kvs) == 0 {
+		return "", 0, true
+	}
+	kv := it.kvs[0]
+	it.kvs = it.kvs[1:]
+	return kv.key, kv.revision, false
+}
+
+func (it *keyIterator) Close() error {
+	return nil
+}
+
+type txnOp struct {
+	key  string
+	data []byte // nil for a delete
+}
+
+type txn struct {
+	broker *Broker
+	ops    []txnOp
+	err    error
+}
+
+func (t *txn) Put(key string, value proto.Message) keyval.ProtoTxn {
+	data, err := serializer.Marshal(value)
+	if err != nil && t.err == nil {
+		t.err = err
+	}
+	t.ops = append(t.ops, txnOp{key: t.broker.prefix + key, data: data})
+	return t
+}
+
+func (t *txn) Delete(key string) keyval.ProtoTxn {
+	t.ops = append(t.ops, txnOp{key: t.broker.prefix + key})
+	return t
+}
+
+func (t *txn) Commit() error {
+	if t.err != nil {
+		return t.err
+	}
+
+	store := t.broker.store
+	store.Lock()
+	defer store.Unlock()
+
+	for _, op := range t.ops {
+		if op.data == nil {
+			delete(store.kvs, op.key)
+			continue
+		}
+		store.put(op.key, op.data)
+	}
+	return nil
+}